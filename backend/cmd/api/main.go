@@ -3,19 +3,20 @@ package main
 
 import (
 	"context"
+	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"strings"
+	"time"
 
-	"github.com/gin-contrib/cors"
-	"github.com/gin-contrib/sessions"
-	"github.com/gin-contrib/sessions/cookie"
 	"github.com/gin-gonic/gin"
 
-	"github.com/yourusername/paper-forge/internal/auth"
 	"github.com/yourusername/paper-forge/internal/config"
 	"github.com/yourusername/paper-forge/internal/jobs"
+	"github.com/yourusername/paper-forge/internal/metrics"
 	"github.com/yourusername/paper-forge/internal/pdf"
+	"github.com/yourusername/paper-forge/server"
 )
 
 func main() {
@@ -28,39 +29,13 @@ func main() {
 	// Ginのモードを設定
 	gin.SetMode(cfg.GinMode)
 
-	// Ginルーターの初期化（デフォルトミドルウェア: Logger, Recovery）
-	router := gin.Default()
-
-	// セッションストアの設定（クッキー署名鍵は必須）
-	store := cookie.NewStore([]byte(cfg.SessionSecret))
-	store.Options(sessions.Options{
-		Path:     "/",
-		MaxAge:   auth.SessionMaxAgeSeconds(),
-		HttpOnly: true,
-		Secure:   cfg.GinMode == gin.ReleaseMode,
-		SameSite: http.SameSiteStrictMode,
-	})
-	router.Use(sessions.Sessions(auth.SessionCookieName, store))
-
-	// CORSミドルウェアの設定
-	corsConfig := cors.DefaultConfig()
-	// CORS許可オリジンを設定（カンマ区切りの文字列を配列に変換）
-	origins := strings.Split(cfg.CORSAllowedOrigins, ",")
-	corsConfig.AllowOrigins = origins
-	corsConfig.AllowCredentials = true
-	corsConfig.AllowHeaders = []string{
-		"Origin",
-		"Content-Type",
-		"Accept",
-		"Authorization",
-		"X-CSRF-Token", // CSRF保護用ヘッダー
-	}
-	// フロントエンドがレスポンスヘッダーから CSRF トークンを読み取れるように公開
-	corsConfig.ExposeHeaders = []string{"X-CSRF-Token"}
-	router.Use(cors.New(corsConfig))
-
 	pdfService := pdf.NewService(cfg)
-	jobManager, err := setupJobs(cfg, pdfService)
+	if !cfg.LazyInit {
+		warmGhostscript(pdfService)
+		discoverOCRLanguages(pdfService)
+	}
+	metrics.RegisterWorkspaceUsage(pdfService.WorkspaceUsage)
+	jobManager, err := server.SetupJobs(cfg, pdfService)
 	if err != nil {
 		log.Fatalf("Failed to set up jobs: %v", err)
 	}
@@ -68,13 +43,15 @@ func main() {
 		defer func() {
 			_ = jobManager.Shutdown(context.Background())
 		}()
-		jobManager.StartWorkers()
 	} else {
 		log.Printf("Starting without Redis-backed job queue; large PDF処理は同期モードで動作します")
 	}
 
-	// ルーティングの設定
-	setupRoutes(router, cfg, pdfService, jobManager)
+	if cfg.KeepaliveIntervalSec > 0 {
+		go keepalive(cfg, jobManager)
+	}
+
+	router := server.New(cfg, server.Deps{PDF: pdfService, Jobs: jobManager})
 
 	// サーバーの起動
 	addr := ":" + cfg.Port
@@ -84,68 +61,71 @@ func main() {
 	}
 }
 
-// handleHealth はヘルスチェックエンドポイントのハンドラーです。
-func handleHealth(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
-		"status":  "ok",
-		"service": "paper-forge-api",
-		"version": "0.1.0",
-	})
+// warmGhostscript は圧縮処理の初回レイテンシを抑えるため、起動時に Ghostscript を解決・起動しておきます。
+// 失敗しても圧縮以外の機能は利用できるため、警告ログのみ出力します。
+func warmGhostscript(svc *pdf.Service) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	version, err := svc.WarmGhostscript(ctx)
+	if err != nil {
+		log.Printf("Ghostscript warm-up failed; optimize requests will fail until it is available: %v", err)
+		return
+	}
+	log.Printf("Ghostscript %s ready", version)
 }
 
-// setupRoutes は API グループと認証周りの配線を行います。
-func setupRoutes(router *gin.Engine, cfg *config.Config, pdfService *pdf.Service, jobManager *jobs.Manager) {
-	// まずは誰でも叩けるヘルスチェックを登録
-	router.GET("/health", handleHealth)
-
-	authManager := auth.NewManager(cfg)
-
-	// 大きなPDFを扱うため、multipartの読み込み上限を設定値に合わせて引き上げる
-	router.MaxMultipartMemory = pdf.MaxUploadTotalBytes + cfg.MaxFileSize
-
-	api := router.Group("/api")
-	{
-		authRoutes := api.Group("/auth")
-		{
-			// ログイン時はセッション未生成なので CSRF 検証は不要
-			authRoutes.POST("/login", authManager.Login)
-			authRoutes.POST("/logout",
-				authManager.RequireLogin(),
-				authManager.VerifyCSRF(),
-				authManager.Logout,
-			)
-		}
-
-		// 今後追加する API はここにぶら下げる
-		protected := api.Group("")
-		protected.Use(authManager.RequireLogin(), authManager.VerifyCSRF())
-		{
-			var scheduler pdf.JobScheduler
-			if jobManager != nil {
-				scheduler = &pdfJobScheduler{manager: jobManager}
-			}
-			handlerOpts := pdf.HandlerOptions{
-				Scheduler:           scheduler,
-				AsyncThresholdBytes: cfg.AsyncThresholdBytes,
-				AsyncThresholdPages: cfg.AsyncThresholdPages,
-			}
+// discoverOCRLanguages はインストール済みの OCR 言語パックを調べ、/api/capabilities で通知できるようにします。
+// TESSERACT_PATH が未設定の場合は何もしません。失敗しても他の機能には影響しないため、警告ログのみ出力します。
+func discoverOCRLanguages(svc *pdf.Service) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	langs, err := svc.DiscoverOCRLanguages(ctx)
+	if err != nil {
+		log.Printf("OCR language discovery failed; no OCR languages will be advertised: %v", err)
+		return
+	}
+	if len(langs) > 0 {
+		log.Printf("OCR languages available: %s", strings.Join(langs, ", "))
+	}
+}
 
-			pdfRoutes := protected.Group("/pdf")
-			{
-				pdfRoutes.POST("/inspect", pdf.InspectHandler(pdfService))
-				pdfRoutes.POST("/merge", pdf.MergeHandler(pdfService, handlerOpts))
-				pdfRoutes.POST("/reorder", pdf.ReorderHandler(pdfService, handlerOpts))
-				pdfRoutes.POST("/split", pdf.SplitHandler(pdfService, handlerOpts))
-				pdfRoutes.POST("/optimize", pdf.OptimizeHandler(pdfService, handlerOpts))
+// keepalive は KEEPALIVE_INTERVAL_SECONDS ごとに Redis と KEEPALIVE_URL へ ping し、
+// アイドル中に接続が切られて次のリクエストで張り直しになるのを防ぎます。
+// Cloud Run では CPU を常時割り当てている（--no-cpu-throttling）場合にのみ動作します。
+func keepalive(cfg *config.Config, jobManager *jobs.Manager) {
+	interval := time.Duration(cfg.KeepaliveIntervalSec) * time.Second
+	client := &http.Client{Timeout: interval}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		ctx, cancel := context.WithTimeout(context.Background(), interval)
+		if jobManager != nil {
+			if err := jobManager.Ping(ctx); err != nil {
+				log.Printf("keepalive: redis ping failed: %v", err)
 			}
-
-			if jobManager != nil {
-				protected.GET("/jobs/:id", jobStatusHandler(jobManager))
-				protected.GET("/jobs/:id/download", jobDownloadHandler(pdfService))
-			} else {
-				protected.GET("/jobs/:id", jobsUnavailableHandler())
-				protected.GET("/jobs/:id/download", jobsUnavailableHandler())
+		}
+		if cfg.KeepaliveURL != "" {
+			if err := pingURL(ctx, client, cfg.KeepaliveURL); err != nil {
+				log.Printf("keepalive: %v", err)
 			}
 		}
+		cancel()
+	}
+}
+
+func pingURL(ctx context.Context, client *http.Client, url string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("%s responded with %d", url, resp.StatusCode)
 	}
+	return nil
 }
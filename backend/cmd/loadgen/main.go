@@ -0,0 +1,271 @@
+// Package main は PDF サービスに合成PDFで負荷をかけ、スループットとメモリ使用量を計測するツールです。
+//
+// サービスをプロセス内で直接呼び出すため、Redis やログインは不要です。pdfcpu の更新前後で
+// 同じ条件で実行し、結果を比較して性能劣化を検出する用途を想定しています。
+//
+//	go run ./cmd/loadgen -op merge -concurrency 8 -duration 30s
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"os"
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/yourusername/paper-forge/internal/config"
+	"github.com/yourusername/paper-forge/internal/pdf"
+	"github.com/yourusername/paper-forge/internal/pdf/pdftest"
+)
+
+type options struct {
+	op           string
+	concurrency  int
+	duration     time.Duration
+	files        int
+	pages        int
+	bytesPerPage int
+	preset       string
+	ghostscript  string
+}
+
+func main() {
+	var opts options
+	flag.StringVar(&opts.op, "op", "merge", "operation to run: merge, split or optimize")
+	flag.IntVar(&opts.concurrency, "concurrency", runtime.NumCPU(), "number of concurrent workers")
+	flag.DurationVar(&opts.duration, "duration", 10*time.Second, "how long to generate load")
+	flag.IntVar(&opts.files, "files", 4, "number of input files per merge request")
+	flag.IntVar(&opts.pages, "pages", 20, "pages per synthetic input PDF")
+	flag.IntVar(&opts.bytesPerPage, "bytes-per-page", 2048, "approximate content size of each page")
+	flag.StringVar(&opts.preset, "preset", string(pdf.OptimizePresetStandard), "optimize preset")
+	flag.StringVar(&opts.ghostscript, "gs", "gs", "path to the Ghostscript executable")
+	flag.Parse()
+
+	if opts.concurrency < 1 {
+		log.Fatal("concurrency must be at least 1")
+	}
+
+	work, err := buildWork(opts)
+	if err != nil {
+		log.Fatalf("failed to prepare %s workload: %v", opts.op, err)
+	}
+
+	tmpDir, err := os.MkdirTemp("", "paper-forge-loadgen-")
+	if err != nil {
+		log.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	svc := pdf.NewService(&config.Config{
+		WorkDir:          tmpDir,
+		MaxFileSize:      1 << 30,
+		MaxPages:         100000,
+		JobExpireMinutes: 1,
+		GhostscriptPath:  opts.ghostscript,
+	})
+	if opts.op == "optimize" {
+		if _, err := svc.WarmGhostscript(context.Background()); err != nil {
+			log.Fatalf("Ghostscript is not available: %v", err)
+		}
+	}
+
+	report := run(svc, work, opts)
+	report.print(os.Stdout, opts)
+}
+
+type workFunc func(ctx context.Context, svc *pdf.Service) (*pdf.Result, error)
+
+func buildWork(opts options) (workFunc, error) {
+	switch opts.op {
+	case "merge":
+		inputs := make([]pdftest.File, opts.files)
+		order := make([]int, opts.files)
+		for i := range inputs {
+			inputs[i] = pdftest.File{Name: fmt.Sprintf("input-%d.pdf", i), Data: pdftest.Synthetic(opts.pages, opts.bytesPerPage)}
+			order[i] = i
+		}
+		headers, err := pdftest.FileHeaders("files[]", inputs...)
+		if err != nil {
+			return nil, err
+		}
+		return func(ctx context.Context, svc *pdf.Service) (*pdf.Result, error) {
+			return svc.MergeMultipart(ctx, headers, order)
+		}, nil
+	case "split":
+		header, err := singleFile(opts)
+		if err != nil {
+			return nil, err
+		}
+		ranges := fmt.Sprintf("1-%d,%d-%d", max(opts.pages/2, 1), max(opts.pages/2, 1)+1, opts.pages)
+		if opts.pages < 2 {
+			ranges = "1"
+		}
+		return func(ctx context.Context, svc *pdf.Service) (*pdf.Result, error) {
+			return svc.SplitMultipart(ctx, header, ranges)
+		}, nil
+	case "optimize":
+		header, err := singleFile(opts)
+		if err != nil {
+			return nil, err
+		}
+		preset := pdf.OptimizePreset(opts.preset)
+		return func(ctx context.Context, svc *pdf.Service) (*pdf.Result, error) {
+			return svc.OptimizeMultipart(ctx, header, preset)
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown operation %q", opts.op)
+	}
+}
+
+func singleFile(opts options) (*multipart.FileHeader, error) {
+	headers, err := pdftest.FileHeaders("file", pdftest.File{
+		Name: "input.pdf",
+		Data: pdftest.Synthetic(opts.pages, opts.bytesPerPage),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return headers[0], nil
+}
+
+type report struct {
+	elapsed   time.Duration
+	latencies []time.Duration
+	errors    int64
+	firstErr  error
+	peakHeap  uint64
+	allocated uint64
+	gcCycles  uint32
+}
+
+func run(svc *pdf.Service, work workFunc, opts options) *report {
+	ctx, cancel := context.WithTimeout(context.Background(), opts.duration)
+	defer cancel()
+
+	var before runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+
+	var (
+		mu        sync.Mutex
+		latencies []time.Duration
+		errCount  atomic.Int64
+		firstErr  error
+		peakHeap  atomic.Uint64
+		wg        sync.WaitGroup
+	)
+
+	stopSampler := make(chan struct{})
+	samplerDone := make(chan struct{})
+	go func() {
+		defer close(samplerDone)
+		ticker := time.NewTicker(100 * time.Millisecond)
+		defer ticker.Stop()
+		var ms runtime.MemStats
+		for {
+			runtime.ReadMemStats(&ms)
+			if ms.HeapInuse > peakHeap.Load() {
+				peakHeap.Store(ms.HeapInuse)
+			}
+			select {
+			case <-stopSampler:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	start := time.Now()
+	for w := 0; w < opts.concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				began := time.Now()
+				// 実行中のリクエストは計測期間の終了で中断せず、最後まで処理させる
+				result, err := work(context.Background(), svc)
+				took := time.Since(began)
+				if err != nil {
+					if errCount.Add(1) == 1 {
+						mu.Lock()
+						firstErr = err
+						mu.Unlock()
+					}
+					continue
+				}
+				_ = result.Cleanup()
+				mu.Lock()
+				latencies = append(latencies, took)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+	close(stopSampler)
+	<-samplerDone
+
+	var after runtime.MemStats
+	runtime.ReadMemStats(&after)
+
+	return &report{
+		elapsed:   elapsed,
+		latencies: latencies,
+		errors:    errCount.Load(),
+		firstErr:  firstErr,
+		peakHeap:  peakHeap.Load(),
+		allocated: after.TotalAlloc - before.TotalAlloc,
+		gcCycles:  after.NumGC - before.NumGC,
+	}
+}
+
+func (r *report) print(out io.Writer, opts options) {
+	sort.Slice(r.latencies, func(i, j int) bool { return r.latencies[i] < r.latencies[j] })
+	done := len(r.latencies)
+
+	fmt.Fprintf(out, "operation:    %s (concurrency=%d, pages=%d, files=%d)\n", opts.op, opts.concurrency, opts.pages, opts.files)
+	fmt.Fprintf(out, "elapsed:      %s\n", r.elapsed.Round(time.Millisecond))
+	fmt.Fprintf(out, "completed:    %d (errors: %d)\n", done, r.errors)
+	if r.firstErr != nil {
+		fmt.Fprintf(out, "first error:  %v\n", r.firstErr)
+	}
+	if done > 0 {
+		fmt.Fprintf(out, "throughput:   %.2f ops/s\n", float64(done)/r.elapsed.Seconds())
+		fmt.Fprintf(out, "latency:      p50=%s p95=%s p99=%s max=%s\n",
+			percentile(r.latencies, 50), percentile(r.latencies, 95), percentile(r.latencies, 99), r.latencies[done-1])
+		fmt.Fprintf(out, "alloc/op:     %s\n", formatBytes(r.allocated/uint64(done)))
+	}
+	fmt.Fprintf(out, "total alloc:  %s\n", formatBytes(r.allocated))
+	fmt.Fprintf(out, "peak heap:    %s\n", formatBytes(r.peakHeap))
+	fmt.Fprintf(out, "gc cycles:    %d\n", r.gcCycles)
+}
+
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := (len(sorted)*p + 99) / 100
+	if idx < 1 {
+		idx = 1
+	}
+	return sorted[idx-1].Round(time.Microsecond)
+}
+
+func formatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := uint64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
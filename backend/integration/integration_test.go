@@ -0,0 +1,353 @@
+//go:build integration
+
+// Package integration は実際の Redis と Ghostscript を使い、組み立て済みのルーター全体を HTTP 越しに検証します。
+//
+//	go test -tags integration ./integration/...
+//
+// Redis は INTEGRATION_REDIS_URL を使い、未設定の場合は PATH 上の redis-server を起動します。
+// どちらも無い場合、および Ghostscript（gs）が無い場合は該当するテストをスキップします。
+package integration
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/yourusername/paper-forge/internal/config"
+	"github.com/yourusername/paper-forge/internal/pdf"
+	"github.com/yourusername/paper-forge/internal/pdf/pdftest"
+	"github.com/yourusername/paper-forge/internal/tenant"
+	"github.com/yourusername/paper-forge/server"
+)
+
+const (
+	testTenant = "integration"
+	testAPIKey = "integration-test-api-key-0123456789"
+)
+
+// redisURL は TestMain で用意した Redis の接続先です。空の場合は非同期ジョブのテストをスキップします。
+var redisURL string
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	url, stop, err := startRedis()
+	if err != nil {
+		log.Printf("integration: Redis を用意できないため非同期ジョブのテストをスキップします: %v", err)
+	}
+	redisURL = url
+	code := m.Run()
+	stop()
+	os.Exit(code)
+}
+
+// startRedis は INTEGRATION_REDIS_URL、または PATH 上の redis-server を空いているポートで起動して接続先を返します。
+func startRedis() (string, func(), error) {
+	if url := os.Getenv("INTEGRATION_REDIS_URL"); url != "" {
+		return url, func() {}, nil
+	}
+	bin, err := exec.LookPath("redis-server")
+	if err != nil {
+		return "", func() {}, err
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", func() {}, err
+	}
+	port := l.Addr().(*net.TCPAddr).Port
+	l.Close()
+
+	cmd := exec.Command(bin, "--port", strconv.Itoa(port), "--bind", "127.0.0.1", "--save", "", "--appendonly", "no")
+	if err := cmd.Start(); err != nil {
+		return "", func() {}, err
+	}
+	stop := func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	}
+	url := fmt.Sprintf("redis://127.0.0.1:%d/0", port)
+	opt, _ := redis.ParseURL(url)
+	rdb := redis.NewClient(opt)
+	defer rdb.Close()
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		if err := rdb.Ping(context.Background()).Err(); err == nil {
+			return url, stop, nil
+		} else if time.Now().After(deadline) {
+			stop()
+			return "", func() {}, fmt.Errorf("redis-server did not become ready: %w", err)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+type env struct {
+	t   *testing.T
+	srv *httptest.Server
+}
+
+// newEnv は API サーバー全体を起動します。async が true の場合はすべてのジョブを Redis 経由の非同期処理にします。
+func newEnv(t *testing.T, async bool) *env {
+	t.Helper()
+	cfg := &config.Config{
+		SessionSecret:          "integration-session-secret-0123456789",
+		CORSAllowedOrigins:     "http://localhost:5173",
+		TenantAPIKeys:          testTenant + ":" + testAPIKey,
+		MaxFileSize:            50 << 20,
+		MaxPages:               1000,
+		MaxMergePages:          1000,
+		JobExpireMinutes:       5,
+		WorkDir:                t.TempDir(),
+		GhostscriptPath:        "gs",
+		PDFCheckLevel:          "standard",
+		OptimizeKeepOriginal:   true,
+		ExternalToolTimeoutSec: 120,
+		ExternalToolOutputKB:   64,
+		AsyncThresholdBytes:    1 << 40,
+		QueueRedisURL:          redisURL,
+	}
+	if async {
+		if redisURL == "" {
+			t.Skip("Redis is not available")
+		}
+		cfg.AsyncThresholdBytes = 1
+	}
+
+	pdfService := pdf.NewService(cfg)
+	deps := server.Deps{PDF: pdfService}
+	if async {
+		manager, err := server.SetupJobs(cfg, pdfService)
+		if err != nil || manager == nil {
+			t.Fatalf("failed to set up jobs: %v", err)
+		}
+		t.Cleanup(func() { _ = manager.Shutdown(context.Background()) })
+		deps.Jobs = manager
+	}
+	srv := httptest.NewServer(server.New(cfg, deps))
+	t.Cleanup(srv.Close)
+	return &env{t: t, srv: srv}
+}
+
+func requireGhostscript(t *testing.T) {
+	t.Helper()
+	if _, err := exec.LookPath("gs"); err != nil {
+		t.Skip("Ghostscript (gs) is not installed")
+	}
+}
+
+type upload struct {
+	name string
+	data []byte
+}
+
+// post は multipart/form-data でファイルとフォーム項目を送信します。
+func (e *env) post(path, field string, files []upload, form map[string]string) *http.Response {
+	e.t.Helper()
+	body := &bytes.Buffer{}
+	w := multipart.NewWriter(body)
+	for _, f := range files {
+		part, err := w.CreateFormFile(field, f.name)
+		if err != nil {
+			e.t.Fatalf("failed to create form file: %v", err)
+		}
+		part.Write(f.data)
+	}
+	for k, v := range form {
+		_ = w.WriteField(k, v)
+	}
+	if err := w.Close(); err != nil {
+		e.t.Fatalf("failed to close multipart writer: %v", err)
+	}
+	req, _ := http.NewRequest(http.MethodPost, e.srv.URL+"/api"+path, body)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	return e.do(req)
+}
+
+func (e *env) get(path string) *http.Response {
+	e.t.Helper()
+	req, _ := http.NewRequest(http.MethodGet, e.srv.URL+"/api"+path, nil)
+	return e.do(req)
+}
+
+func (e *env) do(req *http.Request) *http.Response {
+	e.t.Helper()
+	req.Header.Set("Authorization", "Bearer "+testAPIKey)
+	req.Header.Set(tenant.Header, testTenant)
+	resp, err := e.srv.Client().Do(req)
+	if err != nil {
+		e.t.Fatalf("%s %s failed: %v", req.Method, req.URL.Path, err)
+	}
+	return resp
+}
+
+// readOK はステータスを確認してボディを読み込みます。
+func (e *env) readOK(resp *http.Response, want int) []byte {
+	e.t.Helper()
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		e.t.Fatalf("failed to read response: %v", err)
+	}
+	if resp.StatusCode != want {
+		e.t.Fatalf("unexpected status %d (want %d): %s", resp.StatusCode, want, data)
+	}
+	return data
+}
+
+// pages は /api/pdf/inspect で PDF のページ数を数えます。
+func (e *env) pages(data []byte) int {
+	e.t.Helper()
+	var result pdf.InspectResult
+	body := e.readOK(e.post("/pdf/inspect", "file", []upload{{"out.pdf", data}}, nil), http.StatusOK)
+	if err := json.Unmarshal(body, &result); err != nil {
+		e.t.Fatalf("failed to parse inspect result: %v", err)
+	}
+	return result.Source.Pages
+}
+
+// runAsync はジョブの受付（202）から終了まで待ち、成果物をダウンロードします。
+func (e *env) runAsync(resp *http.Response) []byte {
+	e.t.Helper()
+	var accepted struct {
+		JobID string `json:"jobId"`
+	}
+	if err := json.Unmarshal(e.readOK(resp, http.StatusAccepted), &accepted); err != nil || accepted.JobID == "" {
+		e.t.Fatalf("unexpected accepted response: %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Minute)
+	for {
+		var status struct {
+			Status       string          `json:"status"`
+			OutputSize   int64           `json:"outputSize"`
+			OutputSHA256 string          `json:"outputSha256"`
+			Error        json.RawMessage `json:"error"`
+		}
+		body := e.readOK(e.get("/jobs/"+accepted.JobID+"?wait=30s"), http.StatusOK)
+		if err := json.Unmarshal(body, &status); err != nil {
+			e.t.Fatalf("failed to parse job status: %v", err)
+		}
+		switch status.Status {
+		case "done":
+			out := e.readOK(e.get("/jobs/"+accepted.JobID+"/download"), http.StatusOK)
+			sum := sha256.Sum256(out)
+			if int64(len(out)) != status.OutputSize || hex.EncodeToString(sum[:]) != status.OutputSHA256 {
+				e.t.Fatalf("download does not match the job record (size %d, sha256 %s): %s", len(out), hex.EncodeToString(sum[:]), body)
+			}
+			return out
+		case "error":
+			e.t.Fatalf("job %s failed: %s", accepted.JobID, body)
+		}
+		if time.Now().After(deadline) {
+			e.t.Fatalf("job %s did not finish: %s", accepted.JobID, body)
+		}
+	}
+}
+
+func zipEntries(t *testing.T, data []byte) map[string][]byte {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("response is not a zip: %v", err)
+	}
+	entries := make(map[string][]byte)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("failed to open %s: %v", f.Name, err)
+		}
+		entries[f.Name], _ = io.ReadAll(rc)
+		rc.Close()
+	}
+	return entries
+}
+
+func TestMergeSync(t *testing.T) {
+	e := newEnv(t, false)
+	resp := e.post("/pdf/merge", "files[]", []upload{
+		{"a.pdf", pdftest.Synthetic(2, 0)},
+		{"b.pdf", pdftest.Synthetic(3, 0)},
+	}, nil)
+	out := e.readOK(resp, http.StatusOK)
+	if got := resp.Header.Get("Content-Type"); got != "application/pdf" {
+		t.Fatalf("unexpected content type: %s", got)
+	}
+	if got := e.pages(out); got != 5 {
+		t.Fatalf("merged PDF has %d pages, want 5", got)
+	}
+}
+
+func TestSplitSync(t *testing.T) {
+	e := newEnv(t, false)
+	resp := e.post("/pdf/split", "file", []upload{{"in.pdf", pdftest.Synthetic(6, 0)}}, map[string]string{"ranges": "1-2,5-"})
+	entries := zipEntries(t, e.readOK(resp, http.StatusOK))
+	for name, want := range map[string]int{"part-01.pdf": 2, "part-02.pdf": 2} {
+		data, ok := entries[name]
+		if !ok {
+			t.Fatalf("zip is missing %s: %v", name, entries)
+		}
+		if got := e.pages(data); got != want {
+			t.Fatalf("%s has %d pages, want %d", name, got, want)
+		}
+	}
+}
+
+func TestOptimizeSync(t *testing.T) {
+	requireGhostscript(t)
+	e := newEnv(t, false)
+	input := pdftest.Synthetic(4, 64*1024)
+	resp := e.post("/pdf/optimize", "file", []upload{{"in.pdf", input}}, map[string]string{"preset": "standard"})
+	out := e.readOK(resp, http.StatusOK)
+	if got := e.pages(out); got != 4 {
+		t.Fatalf("optimized PDF has %d pages, want 4", got)
+	}
+	if len(out) > len(input) {
+		t.Fatalf("optimized output (%d bytes) is larger than the input (%d bytes)", len(out), len(input))
+	}
+}
+
+func TestMergeAsync(t *testing.T) {
+	e := newEnv(t, true)
+	resp := e.post("/pdf/merge", "files[]", []upload{
+		{"a.pdf", pdftest.Synthetic(1, 0)},
+		{"b.pdf", pdftest.Synthetic(4, 0)},
+	}, nil)
+	if got := e.pages(e.runAsync(resp)); got != 5 {
+		t.Fatalf("merged PDF has %d pages, want 5", got)
+	}
+}
+
+func TestSplitAsync(t *testing.T) {
+	e := newEnv(t, true)
+	resp := e.post("/pdf/split", "file", []upload{{"in.pdf", pdftest.Synthetic(3, 0)}}, map[string]string{"ranges": "1,2-3"})
+	entries := zipEntries(t, e.runAsync(resp))
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 parts, got %d", len(entries))
+	}
+}
+
+func TestOptimizeAsync(t *testing.T) {
+	requireGhostscript(t)
+	e := newEnv(t, true)
+	resp := e.post("/pdf/optimize", "file", []upload{{"in.pdf", pdftest.Synthetic(2, 32*1024)}}, map[string]string{"preset": "aggressive"})
+	if got := e.pages(e.runAsync(resp)); got != 2 {
+		t.Fatalf("optimized PDF has %d pages, want 2", got)
+	}
+}
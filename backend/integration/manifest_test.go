@@ -0,0 +1,80 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/yourusername/paper-forge/internal/config"
+	"github.com/yourusername/paper-forge/internal/pdf"
+	"github.com/yourusername/paper-forge/internal/pdf/pdftest"
+)
+
+// TestManifestGoldensExecute は各バージョンのコードが書き出したマニフェスト（internal/pdf/testdata/manifests）を
+// 現在のワーカーで実行し、ローリングアップデート中に処理待ちだったジョブが完了できることを確認します。
+func TestManifestGoldensExecute(t *testing.T) {
+	paths, err := filepath.Glob("../internal/pdf/testdata/manifests/v*/*.json")
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("no golden manifests found: %v", err)
+	}
+	for _, path := range paths {
+		t.Run(path, func(t *testing.T) {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("failed to read golden manifest: %v", err)
+			}
+			var manifest pdf.JobManifest
+			if err := json.Unmarshal(data, &manifest); err != nil {
+				t.Fatalf("failed to parse golden manifest: %v", err)
+			}
+			if manifest.Operation == pdf.OperationOptimize {
+				requireGhostscript(t)
+			}
+
+			root := t.TempDir()
+			jobDir := filepath.Join(root, manifest.JobID)
+			if err := os.MkdirAll(filepath.Join(jobDir, "in"), 0o750); err != nil {
+				t.Fatalf("failed to create workspace: %v", err)
+			}
+			if err := os.WriteFile(filepath.Join(jobDir, "manifest.json"), data, 0o640); err != nil {
+				t.Fatalf("failed to write manifest: %v", err)
+			}
+			for _, f := range manifest.Files {
+				pages := f.Pages
+				if pages == 0 {
+					// ページ数の確認を実行時に行う（pagesDeferred）マニフェスト
+					pages = 3
+				}
+				if err := os.WriteFile(filepath.Join(jobDir, "in", f.StoredName), pdftest.Synthetic(pages, 0), 0o640); err != nil {
+					t.Fatalf("failed to write input: %v", err)
+				}
+			}
+
+			svc := pdf.NewService(&config.Config{
+				WorkDir:                root,
+				MaxFileSize:            50 << 20,
+				MaxPages:               1000,
+				GhostscriptPath:        "gs",
+				OptimizeKeepOriginal:   true,
+				ExternalToolTimeoutSec: 120,
+				ExternalToolOutputKB:   64,
+			})
+			result, err := svc.RunJob(context.Background(), manifest.JobID, nil)
+			if err != nil {
+				t.Fatalf("RunJob returned error: %v", err)
+			}
+			if result.Operation != manifest.Operation || len(result.Artifacts) == 0 {
+				t.Fatalf("unexpected result: %+v", result)
+			}
+			for _, a := range result.Artifacts {
+				if a.Size == 0 {
+					t.Fatalf("artifact %s is empty", a.Name)
+				}
+			}
+		})
+	}
+}
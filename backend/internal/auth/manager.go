@@ -7,6 +7,7 @@ import (
 	"errors"
 	"net/http"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -15,6 +16,7 @@ import (
 	"golang.org/x/crypto/bcrypt"
 
 	"github.com/yourusername/paper-forge/internal/config"
+	"github.com/yourusername/paper-forge/internal/tenant"
 )
 
 const (
@@ -54,13 +56,18 @@ type Manager struct {
 	cfg      *config.Config
 	lock     sync.Mutex
 	attempts map[string]*attemptState
+	// tenantKeys はテナントIDごとの API キーです（TENANT_API_KEYS 未設定の場合は空）。
+	tenantKeys map[string]string
 }
 
 // NewManager は認証マネージャーを作成します。
 func NewManager(cfg *config.Config) *Manager {
+	// 形式は config.Validate で検証済み
+	keys, _ := cfg.TenantKeys()
 	return &Manager{
-		cfg:      cfg,
-		attempts: make(map[string]*attemptState),
+		cfg:        cfg,
+		attempts:   make(map[string]*attemptState),
+		tenantKeys: keys,
 	}
 }
 
@@ -154,8 +161,17 @@ func (m *Manager) Logout(c *gin.Context) {
 }
 
 // RequireLogin はセッションを検証するミドルウェアを返します。
+// TENANT_API_KEYS が設定されている場合は、Authorization: Bearer の API キーと X-Tenant ヘッダーでも認証できます。
+// セッションでログインした利用者のテナントは tenant.Default です。
 func (m *Manager) RequireLogin() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if received, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); ok && len(m.tenantKeys) > 0 {
+			if m.authenticateAPIKey(c, received) {
+				c.Next()
+			}
+			return
+		}
+
 		session := sessions.Default(c)
 		user, ok := session.Get(sessionKeyUser).(string)
 		if !ok || user == "" {
@@ -193,6 +209,7 @@ func (m *Manager) RequireLogin() gin.HandlerFunc {
 		session.Set(sessionKeyLastActive, now.Unix())
 		_ = session.Save()
 		c.Set(ContextUserKey, user)
+		setTenant(c, tenant.Default)
 		c.Next()
 	}
 }
@@ -200,7 +217,7 @@ func (m *Manager) RequireLogin() gin.HandlerFunc {
 // VerifyCSRF は X-CSRF-Token ヘッダーを検証するミドルウェアです。
 func (m *Manager) VerifyCSRF() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		if isSafeMethod(c.Request.Method) {
+		if isSafeMethod(c.Request.Method) || c.GetBool(contextAPIKeyKey) {
 			c.Next()
 			return
 		}
@@ -319,3 +336,19 @@ func isSafeMethod(method string) bool {
 		return false
 	}
 }
+
+// RequireAdminToken は Authorization: Bearer で ADMIN_API_TOKEN を要求するミドルウェアです。
+// 管理用エンドポイントはログインセッションとは別に、運用者だけが持つトークンで保護します。
+func RequireAdminToken(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		received, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(strings.TrimSpace(received))) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"code":    "UNAUTHORIZED",
+				"message": "管理用トークンが正しくありません",
+			})
+			return
+		}
+		c.Next()
+	}
+}
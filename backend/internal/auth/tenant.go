@@ -0,0 +1,104 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yourusername/paper-forge/internal/tenant"
+)
+
+// contextAPIKeyKey は API キーで認証したリクエストであることを示すキーです（CSRF 検証を省略するため）。
+const contextAPIKeyKey = "auth.apiKey"
+
+// authenticateAPIKey は Authorization: Bearer と X-Tenant ヘッダーでテナントの API キーを検証します。
+// API キーは Cookie を使わないため CSRF の対象になりません。
+func (m *Manager) authenticateAPIKey(c *gin.Context, received string) bool {
+	id := strings.TrimSpace(c.GetHeader(tenant.Header))
+	expected, ok := m.tenantKeys[id]
+	if !ok || subtle.ConstantTimeCompare([]byte(expected), []byte(strings.TrimSpace(received))) != 1 {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"code":    "UNAUTHORIZED",
+			"message": "API キーまたはテナントが正しくありません",
+		})
+		return false
+	}
+	c.Set(ContextUserKey, "tenant:"+id)
+	c.Set(contextAPIKeyKey, true)
+	setTenant(c, id)
+	return true
+}
+
+// setTenant はリクエストのテナントをコンテキストに設定します。
+func setTenant(c *gin.Context, id string) {
+	c.Request = c.Request.WithContext(tenant.WithID(c.Request.Context(), id))
+}
+
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+// TenantRateLimiter はテナントごとに1分あたりのリクエスト数を制限します。
+// ログイン試行の制限と同様に、プロセス内のメモリで数えます。
+type TenantRateLimiter struct {
+	limit   int
+	window  time.Duration
+	lock    sync.Mutex
+	windows map[string]*rateWindow
+}
+
+// NewTenantRateLimiter は1分あたり perMinute 件に制限する TenantRateLimiter を作成します。
+// perMinute が0以下の場合は nil を返します。
+func NewTenantRateLimiter(perMinute int) *TenantRateLimiter {
+	if perMinute <= 0 {
+		return nil
+	}
+	return &TenantRateLimiter{
+		limit:   perMinute,
+		window:  time.Minute,
+		windows: make(map[string]*rateWindow),
+	}
+}
+
+// Middleware はテナントの上限を超えたリクエストを 429 で拒否するミドルウェアです。
+// RequireLogin の後に登録してください。nil の TenantRateLimiter は何もしません。
+func (l *TenantRateLimiter) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if l == nil {
+			c.Next()
+			return
+		}
+		if retryAfter := l.take(tenant.FromContext(c.Request.Context()), time.Now()); retryAfter > 0 {
+			c.Header("Retry-After", strconv.FormatInt(int64(retryAfter.Round(time.Second).Seconds()), 10))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+				"code":    "RATE_LIMITED",
+				"message": "リクエストが多すぎます。しばらくしてから再度お試しください",
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// take はテナントのリクエストを1件数え、上限を超えた場合は次の枠までの待ち時間を返します。
+func (l *TenantRateLimiter) take(id string, now time.Time) time.Duration {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	w, ok := l.windows[id]
+	if !ok || now.Sub(w.start) >= l.window {
+		w = &rateWindow{start: now}
+		l.windows[id] = w
+	}
+	if w.count >= l.limit {
+		return w.start.Add(l.window).Sub(now)
+	}
+	w.count++
+	return 0
+}
@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yourusername/paper-forge/internal/config"
+	"github.com/yourusername/paper-forge/internal/tenant"
+)
+
+func TestRequireLoginWithTenantAPIKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	m := NewManager(&config.Config{TenantAPIKeys: "legal:legal-key-0123456789abcdef,hr:hr-key-0123456789abcdefgh"})
+
+	router := gin.New()
+	router.POST("/jobs", m.RequireLogin(), m.VerifyCSRF(), func(c *gin.Context) {
+		c.String(http.StatusOK, tenant.FromContext(c.Request.Context()))
+	})
+
+	cases := []struct {
+		name   string
+		tenant string
+		key    string
+		status int
+	}{
+		{"valid", "legal", "legal-key-0123456789abcdef", http.StatusOK},
+		{"key of another tenant", "hr", "legal-key-0123456789abcdef", http.StatusUnauthorized},
+		{"missing tenant", "", "legal-key-0123456789abcdef", http.StatusUnauthorized},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/jobs", nil)
+		req.Header.Set("Authorization", "Bearer "+tc.key)
+		if tc.tenant != "" {
+			req.Header.Set(tenant.Header, tc.tenant)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != tc.status {
+			t.Fatalf("%s: status=%d body=%s, want %d", tc.name, w.Code, w.Body.String(), tc.status)
+		}
+		if tc.status == http.StatusOK && w.Body.String() != tc.tenant {
+			t.Fatalf("%s: resolved tenant %q", tc.name, w.Body.String())
+		}
+	}
+}
+
+func TestTenantRateLimiter(t *testing.T) {
+	if NewTenantRateLimiter(0) != nil {
+		t.Fatal("zero limit should disable the limiter")
+	}
+	l := NewTenantRateLimiter(2)
+	now := time.Now()
+	for i := 0; i < 2; i++ {
+		if wait := l.take("legal", now); wait != 0 {
+			t.Fatalf("request %d should pass, got wait %v", i, wait)
+		}
+	}
+	if wait := l.take("legal", now.Add(10*time.Second)); wait != 50*time.Second {
+		t.Fatalf("expected 50s wait, got %v", wait)
+	}
+	if wait := l.take("hr", now); wait != 0 {
+		t.Fatal("limits must be isolated per tenant")
+	}
+	if wait := l.take("legal", now.Add(time.Minute)); wait != 0 {
+		t.Fatal("a new window should reset the count")
+	}
+}
@@ -0,0 +1,64 @@
+// Package buildinfo はビルド時に埋め込んだバージョン情報を提供します。
+//
+// リリースビルドでは -ldflags で値を埋め込みます。
+//
+//	go build -ldflags "-X github.com/yourusername/paper-forge/internal/buildinfo.Version=v1.2.3 \
+//	  -X github.com/yourusername/paper-forge/internal/buildinfo.Commit=$(git rev-parse HEAD) \
+//	  -X github.com/yourusername/paper-forge/internal/buildinfo.Date=$(date -u +%Y-%m-%dT%H:%M:%SZ)" ./cmd/api
+//
+// Commit と Date を埋め込まなかった場合は、go build が記録した VCS の情報（vcs.revision / vcs.time）を使います。
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+	"sync"
+)
+
+// ldflags の -X で上書きする値です。
+var (
+	Version = "dev"
+	Commit  = ""
+	Date    = ""
+)
+
+// Info はビルドの情報です。
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"buildDate"`
+	// Modified はコミットされていない変更を含む作業ツリーからビルドしたことを表します（VCS の情報から判定）。
+	Modified  bool   `json:"modified,omitempty"`
+	GoVersion string `json:"goVersion"`
+}
+
+var (
+	once sync.Once
+	info Info
+)
+
+// Get はビルドの情報を返します。
+func Get() Info {
+	once.Do(func() {
+		info = Info{Version: Version, Commit: Commit, BuildDate: Date, GoVersion: runtime.Version()}
+		bi, ok := debug.ReadBuildInfo()
+		if !ok {
+			return
+		}
+		for _, s := range bi.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = s.Value
+				}
+			case "vcs.time":
+				if info.BuildDate == "" {
+					info.BuildDate = s.Value
+				}
+			case "vcs.modified":
+				info.Modified = s.Value == "true"
+			}
+		}
+	})
+	return info
+}
@@ -2,12 +2,18 @@
 package config
 
 import (
+	"encoding/base64"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
+
+	"github.com/yourusername/paper-forge/internal/faults"
+	"github.com/yourusername/paper-forge/internal/tenant"
 )
 
 // Config はアプリケーションの設定を保持する構造体です。
@@ -16,32 +22,157 @@ type Config struct {
 	AppUsername     string // ログイン用ユーザー名
 	AppPasswordHash string // bcryptでハッシュ化されたパスワード
 	SessionSecret   string // セッション署名用の秘密鍵
+	AdminAPIToken   string // 管理用エンドポイント（上書きトークン発行など）の Bearer トークン（空の場合は無効）
+
+	// テナント（部署）設定
+	TenantAPIKeys            string // テナントごとの API キー（"テナントID:APIキー" のカンマ区切り。空の場合は API キー認証を無効）
+	TenantMaxActiveJobs      int    // テナントごとのキュー待ち・処理中の非同期ジョブ数の上限（0で無制限）
+	TenantMaxRunningJobs     int    // テナントごとに同時に処理する非同期ジョブ数の上限。超過分はテナントごとの待ち行列で順番を待つ（0で無制限）
+	TenantQueueWeights       string // テナント専用のキューと処理の重み（"テナントID=重み" のカンマ区切り。空で全テナント共有のキュー）
+	TenantRateLimitPerMinute int    // テナントごとの1分あたりのリクエスト数の上限（0で無制限）
 
 	// サーバー設定
 	Port    string // APIサーバーのポート番号
 	GinMode string // Ginの実行モード (debug, release, test)
 
+	// 起動設定（Cloud Run のコールドスタート対策）
+	LazyInit             bool   // Redis への接続確認・ワーカー起動・外部コマンドの確認を起動時ではなく最初のリクエスト時に行うか
+	KeepaliveIntervalSec int    // Redis 接続などを維持するための定期 ping の間隔（秒、0で無効。CPU 常時割り当てが前提）
+	KeepaliveURL         string // 定期 ping で GET する URL（例: サービス自身の /health。空の場合は Redis のみ）
+
 	// CORS設定
 	CORSAllowedOrigins string // CORS許可オリジン（カンマ区切り）
 
 	// ファイル制限
-	MaxFileSize      int64 // 単一ファイルの最大サイズ（バイト）
-	MaxPages         int   // 単一ファイルの最大ページ数
-	JobExpireMinutes int   // ジョブの有効期限（分）
+	MaxFileSize        int64  // 単一ファイルの最大サイズ（バイト）
+	MaxPages           int    // 単一ファイルの最大ページ数
+	MaxMergePages      int    // 結合する全ファイルの合計ページ数の上限（0で無制限）
+	SoftLimitBytes     int64  // 確認なしで受け付けるジョブの合計入力サイズ（バイト、0で無効）
+	SoftLimitPages     int    // 確認なしで受け付けるジョブの合計ページ数（0で無効）
+	JobExpireMinutes   int    // ジョブの有効期限（分）
+	ResultCacheMinutes int    // 同一入力の成果物を再利用する期間（分、0で無効）
+	WorkDir            string // ジョブの作業ディレクトリ（空の場合は os.TempDir()/app）
+	WorkDirMaxMB       int64  // 作業ディレクトリ全体の使用量上限（MB、0で無制限）
+	SharedInputTenants string // 同じ入力を内容のハッシュで共有するテナント（カンマ区切り、"*" ですべて、空で無効）
 
 	// ジョブ/キュー設定
 	QueueRedisURL       string // Asynq用Redis接続URL
 	AsyncThresholdBytes int64  // 同期処理から非同期へ切り替えるサイズ閾値
 	AsyncThresholdPages int    // 同期処理から非同期へ切り替えるページ閾値
+	MaxConcurrentSync   int    // 同時に実行する同期処理の上限（0以下で無制限）
+	SyncRetryAfterSec   int    // 同期処理の上限到達時に返す Retry-After 秒数
 	JobResultBaseURL    string // 結果ファイル取得用のベースURL（署名URL等を生成する場合に使用）
+	PublicBaseURL       string // 外部へ通知するリンクの生成に使う公開URL（例: https://pdf.example.com）
+	JobDedupEnabled     bool   // 同じ内容のジョブがキュー待ち・処理中の場合に、新たに投入せず既存のジョブIDを返すか
+	JobQueueSLASeconds  int    // キュー待ちの目標時間（秒）。超えたジョブに slaBreached を付ける（0以下で無効）
+
+	// 冗長構成の Redis（QUEUE_REDIS_MODE が sentinel / cluster の場合。QUEUE_REDIS_URL の代わりに使う）
+	QueueRedisMode             string // standalone（既定）/ sentinel / cluster
+	QueueRedisAddrs            string // sentinel: Sentinel のアドレス、cluster: ノードのアドレス（カンマ区切りの host:port）
+	QueueRedisSentinelMaster   string // Sentinel で監視しているマスター名
+	QueueRedisSentinelPassword string // Sentinel 自体の認証パスワード
+	QueueRedisUsername         string // Redis の ACL ユーザー名
+	QueueRedisPassword         string // Redis の認証パスワード
+	QueueRedisDB               int    // 使用するDB番号（sentinel のみ。cluster は常に 0）
+	QueueRedisTLS              bool   // TLS で接続するか
+	QueueRedisCAFile           string // TLS 接続でサーバー証明書の検証に使う CA 証明書（PEM）のパス
+	QueueRedisIAMAuth          bool   // パスワードの代わりにサービスアカウントのアクセストークンで認証するか（Memorystore の IAM 認証）
+	QueueFailoverRedisURL      string // 主 Redis に接続できない間、新しいジョブを投入する待避先の Redis（空の場合は待避しない）
+
+	// 成果物配送設定（メール）
+	MailFrom               string // 送信元メールアドレス
+	SMTPHost               string // SMTPサーバーのホスト名
+	SMTPPort               int    // SMTPサーバーのポート番号
+	SMTPUsername           string // SMTP認証ユーザー名（空の場合は認証なし）
+	SMTPPassword           string // SMTP認証パスワード
+	SendGridAPIKey         string // SendGrid APIキー（設定時はSMTPより優先）
+	MailAttachmentMaxBytes int64  // 添付で送る成果物の最大サイズ（超過時は署名付きリンクを送信）
+	MailAllowedRecipients  string // deliverTo=email で送信できる宛先（カンマ区切り、ドメインまたはメールアドレス。空の場合はメール配送を受け付けない）
+
+	// 成果物配送設定（SFTP）
+	SFTPPath           string // sftp 実行ファイルのパス
+	SFTPHost           string // 配送先SFTPサーバーのホスト名（空の場合はSFTP配送を無効化）
+	SFTPPort           int    // 配送先SFTPサーバーのポート番号
+	SFTPUser           string // 接続ユーザー名
+	SFTPKeyPath        string // 秘密鍵ファイルのパス
+	SFTPKnownHostsPath string // known_hosts ファイルのパス（ホスト鍵検証に使用）
+	SFTPRemoteDir      string // アップロード先ディレクトリ
+
+	// 成果物配送設定（クラウドストレージ）
+	OutputURLAllowedHosts string // 署名付きPUT URLとして受け付けるホスト（カンマ区切り、サブドメイン含む）
+	StorageDeliveryPrefix string // outputPath の書き込み先を限定するプレフィックス（この配下のテナントごとの階層に書き込む）
+
+	// クラウドドライブ連携設定（Google ドライブ / OneDrive）
+	GoogleDriveClientID      string // Google ドライブの OAuth クライアントID（空の場合は連携を無効化）
+	GoogleDriveClientSecret  string // Google ドライブの OAuth クライアントシークレット
+	OneDriveClientID         string // OneDrive（Microsoft Entra ID）のアプリケーションID（空の場合は連携を無効化）
+	OneDriveClientSecret     string // OneDrive のクライアントシークレット
+	OneDriveTenant           string // OneDrive の認可に使うディレクトリ（common / organizations / テナントID）
+	ConnectorRedirectBaseURL string // OAuth のリダイレクト先にする API の公開URL（<URL>/api/connectors/<連携先>/callback）
+	ConnectorReturnURL       string // 接続の完了後に戻るフロントエンドのURL
+	ConnectorMaxFiles        int    // 1回のリクエストで取り込めるクラウドドライブのファイル数
+
+	// ジョブ通知設定（Slack / Teams）
+	NotifyWebhookURL          string // 既定の通知先 Incoming Webhook URL（空の場合は通知しない）
+	NotifyWebhookAllowedHosts string // ジョブ単位で指定できる Webhook のホスト（カンマ区切り、サブドメイン含む）
+
+	// エラー報告設定（ワーカーのパニックなど想定外のエラー）
+	ErrorReportService string // エラー報告に載せるサービス名（Cloud Run では K_SERVICE）
+
+	// 利用量の重み付け・予算アラート設定
+	OperationCostWeights  string // 操作ごとの1ページあたりのコスト単位（"操作=重み" のカンマ区切り、未指定の操作は1）
+	TenantMonthlyBudgets  string // テナントごとの月間予算（"テナントID=単位数" のカンマ区切り、空の場合はアラートなし）
+	BudgetAlertThresholds string // アラートを送る予算消化率（%、カンマ区切り）
+	BudgetAlertWebhookURL string // 予算アラートの投稿先 Incoming Webhook URL（空の場合は NOTIFY_WEBHOOK_URL）
+	BudgetAlertEmail      string // 予算アラートの送信先メールアドレス（カンマ区切り、空の場合は送らない）
 
 	// PDF処理設定
-	GhostscriptPath string // Ghostscript実行ファイルのパス
+	GhostscriptPath        string // Ghostscript実行ファイルのパス
+	ColorProfile           string // 圧縮で色を変換して出力インテントを埋め込む既定の ICC プロファイル（srgb / fogra39。空の場合は変換しない）
+	ColorProfileSRGB       string // sRGB の ICC プロファイルのパス（空の場合は srgb を使えない）
+	ColorProfileFOGRA39    string // FOGRA39（Coated FOGRA39）の ICC プロファイルのパス（空の場合は fogra39 を使えない）
+	TesseractPath          string // OCR に使う tesseract 実行ファイルのパス（空の場合は OCR 言語の検出を行わない）
+	OptimizeKeepOriginal   bool   // 圧縮後の方が大きい場合に元ファイルを返すか（false の場合は警告付きで圧縮結果を返す）
+	ExternalToolTimeoutSec int    // Ghostscript / sftp など外部コマンド1回あたりの最大実行時間（秒、0で無制限）
+	ExternalToolOutputKB   int    // 外部コマンドの出力をメモリに保持する上限（KB、標準出力・標準エラーそれぞれ末尾のみ）
+	PDFCheckLevel          string // アップロードPDFの検証レベル (lenient, standard, strict)
+	OutputPDFVersion       string // 成果物の PDF のバージョン（1.4 / 1.5 / 1.6 / 1.7。空の場合は圧縮は 1.5、それ以外の操作は処理結果のまま）
+	DeferPageCount         bool   // 結合の入力のページ数確認を受付時ではなく実行時に行うか
+	DisabledOperations     string // 無効化する操作（カンマ区切り、例: "optimize"）
+	FaultInjection         string // 障害注入（"障害点[=回数]" のカンマ区切り、例: "storage.write=1"）。テスト・デバッグ用で release モードでは使用不可
+
+	// 成果物の透かし設定
+	WatermarkText       string // 成果物に自動で付ける透かし・フッターの文言（空の場合は付けない）
+	WatermarkOperations string // 透かしを付ける操作（カンマ区切り、空の場合はすべての操作）
+	WatermarkStyle      string // 透かしの書式（pdfcpu の透かし記述。空の場合はページ下部中央の小さな文字）
+
+	// 成果物の処理記録設定
+	AuditRecord           bool   // 成果物の XMP メタデータに処理記録（操作・日時・ツールのバージョン・入力のハッシュ）を埋め込むか
+	AuditRecordOperations string // 処理記録を埋め込む操作（カンマ区切り、空の場合はすべての操作）
+
+	// ワークスペース暗号化設定
+	WorkspaceEncryptionKeys string // 作業ファイルの暗号化鍵（"鍵ID:base64鍵" のカンマ区切り、先頭が暗号化に使う鍵。空の場合は暗号化しない）
+
+	// 成果物のマニフェスト署名設定
+	ResultSigningKeys     string // 成果物のマニフェストの署名鍵（"鍵ID:base64 Ed25519 シード" のカンマ区切り、先頭が署名に使う鍵。空の場合は署名しない）
+	SigningProvider       string // 署名を行う場所（keys / gcpkms / vault / pkcs12。空の場合は RESULT_SIGNING_KEYS があれば keys）
+	SigningKMSKeyVersions string // Cloud KMS の鍵バージョン（"projects/.../cryptoKeyVersions/N" のカンマ区切り、先頭で署名し残りは検証用に公開）
+	VaultAddr             string // HashiCorp Vault のアドレス（例: https://vault.example.com:8200）
+	VaultToken            string // Vault のトークン
+	VaultNamespace        string // Vault Enterprise の名前空間（任意）
+	SigningVaultMount     string // Vault の Transit シークレットエンジンのマウントパス
+	SigningVaultKey       string // Vault の Transit の鍵の名前
+	SigningPKCS12File     string // 開発用の PKCS#12 ファイルのパス（release モードでは使用不可）
+	SigningPKCS12Password string // PKCS#12 ファイルのパスワード
 
 	// GCP設定（本番環境用）
 	GCPProject     string // GCPプロジェクトID
 	GCSBucket      string // Google Cloud Storageバケット名
 	ServiceAccount string // サービスアカウント
+
+	// データ所在地（データレジデンシー）設定
+	DataRegion       string // このデプロイ（テナント）のデータを保存すべきリージョン（例: eu）。空の場合は GCS_BUCKET を使う
+	GCSRegionBuckets string // リージョンごとの保存先（"リージョン=バケット[/プレフィックス]" のカンマ区切り）
 }
 
 // Load は環境変数から設定を読み込みます。
@@ -55,32 +186,155 @@ func Load() (*Config, error) {
 		AppUsername:     getEnv("APP_USERNAME", ""),
 		AppPasswordHash: getEnv("APP_PASSWORD_HASH", ""),
 		SessionSecret:   getEnv("SESSION_SECRET", ""),
+		AdminAPIToken:   getEnv("ADMIN_API_TOKEN", ""),
+
+		// テナント設定
+		TenantAPIKeys:            getEnv("TENANT_API_KEYS", ""),
+		TenantMaxActiveJobs:      getEnvAsInt("TENANT_MAX_ACTIVE_JOBS", 0),
+		TenantMaxRunningJobs:     getEnvAsInt("TENANT_MAX_RUNNING_JOBS", 0),
+		TenantQueueWeights:       getEnv("TENANT_QUEUE_WEIGHTS", ""),
+		TenantRateLimitPerMinute: getEnvAsInt("TENANT_RATE_LIMIT_PER_MINUTE", 0),
 
 		// サーバー設定
 		Port:    getEnv("PORT", "8080"),
 		GinMode: getEnv("GIN_MODE", "debug"),
 
+		// 起動設定
+		LazyInit:             getEnvAsBool("LAZY_INIT", false),
+		KeepaliveIntervalSec: getEnvAsInt("KEEPALIVE_INTERVAL_SECONDS", 0),
+		KeepaliveURL:         getEnv("KEEPALIVE_URL", ""),
+
 		// CORS設定
 		CORSAllowedOrigins: getEnv("CORS_ALLOWED_ORIGINS", "http://localhost:5173"),
 
 		// ファイル制限
-		MaxFileSize:      getEnvAsInt64("MAX_FILE_SIZE", 104857600), // 100MB
-		MaxPages:         getEnvAsInt("MAX_PAGES", 200),
-		JobExpireMinutes: getEnvAsInt("JOB_EXPIRE_MINUTES", 10),
+		MaxFileSize:        getEnvAsInt64("MAX_FILE_SIZE", 104857600), // 100MB
+		MaxPages:           getEnvAsInt("MAX_PAGES", 200),
+		MaxMergePages:      getEnvAsInt("MAX_MERGE_PAGES", 1000),
+		SoftLimitBytes:     getEnvAsInt64("SOFT_LIMIT_BYTES", 0),
+		SoftLimitPages:     getEnvAsInt("SOFT_LIMIT_PAGES", 0),
+		JobExpireMinutes:   getEnvAsInt("JOB_EXPIRE_MINUTES", 10),
+		ResultCacheMinutes: getEnvAsInt("RESULT_CACHE_MINUTES", 0),
+		WorkDir:            getEnv("WORK_DIR", ""),
+		WorkDirMaxMB:       getEnvAsInt64("WORK_DIR_MAX_MB", 0),
+		SharedInputTenants: getEnv("SHARED_INPUT_TENANTS", ""),
 
 		// ジョブ/キュー設定
 		QueueRedisURL:       getEnv("QUEUE_REDIS_URL", "redis://127.0.0.1:6379/0"),
 		AsyncThresholdBytes: getEnvAsInt64("ASYNC_THRESHOLD_BYTES", 50*1024*1024), // 50MB
 		AsyncThresholdPages: getEnvAsInt("ASYNC_THRESHOLD_PAGES", 120),
+		MaxConcurrentSync:   getEnvAsInt("MAX_CONCURRENT_SYNC", 8),
+		SyncRetryAfterSec:   getEnvAsInt("SYNC_RETRY_AFTER_SECONDS", 5),
 		JobResultBaseURL:    getEnv("JOB_RESULT_BASE_URL", ""),
+		PublicBaseURL:       getEnv("PUBLIC_BASE_URL", "http://localhost:8080"),
+		JobDedupEnabled:     getEnvAsBool("JOB_DEDUP_ENABLED", false),
+		JobQueueSLASeconds:  getEnvAsInt("JOB_QUEUE_SLA_SECONDS", 0),
+
+		QueueRedisMode:             getEnv("QUEUE_REDIS_MODE", "standalone"),
+		QueueRedisAddrs:            getEnv("QUEUE_REDIS_ADDRS", ""),
+		QueueRedisSentinelMaster:   getEnv("QUEUE_REDIS_SENTINEL_MASTER", ""),
+		QueueRedisSentinelPassword: getEnv("QUEUE_REDIS_SENTINEL_PASSWORD", ""),
+		QueueRedisUsername:         getEnv("QUEUE_REDIS_USERNAME", ""),
+		QueueRedisPassword:         getEnv("QUEUE_REDIS_PASSWORD", ""),
+		QueueRedisDB:               getEnvAsInt("QUEUE_REDIS_DB", 0),
+		QueueRedisTLS:              getEnvAsBool("QUEUE_REDIS_TLS", false),
+		QueueRedisCAFile:           getEnv("QUEUE_REDIS_CA_FILE", ""),
+		QueueRedisIAMAuth:          getEnvAsBool("QUEUE_REDIS_IAM_AUTH", false),
+		QueueFailoverRedisURL:      getEnv("QUEUE_FAILOVER_REDIS_URL", ""),
+
+		// 成果物配送設定（メール）
+		MailFrom:               getEnv("MAIL_FROM", ""),
+		SMTPHost:               getEnv("SMTP_HOST", ""),
+		SMTPPort:               getEnvAsInt("SMTP_PORT", 587),
+		SMTPUsername:           getEnv("SMTP_USERNAME", ""),
+		SMTPPassword:           getEnv("SMTP_PASSWORD", ""),
+		SendGridAPIKey:         getEnv("SENDGRID_API_KEY", ""),
+		MailAttachmentMaxBytes: getEnvAsInt64("MAIL_ATTACHMENT_MAX_BYTES", 10*1024*1024), // 10MB
+		MailAllowedRecipients:  getEnv("MAIL_ALLOWED_RECIPIENTS", ""),
+
+		// 成果物配送設定（SFTP）
+		SFTPPath:           getEnv("SFTP_PATH", "sftp"),
+		SFTPHost:           getEnv("SFTP_HOST", ""),
+		SFTPPort:           getEnvAsInt("SFTP_PORT", 22),
+		SFTPUser:           getEnv("SFTP_USER", ""),
+		SFTPKeyPath:        getEnv("SFTP_KEY_PATH", ""),
+		SFTPKnownHostsPath: getEnv("SFTP_KNOWN_HOSTS_PATH", ""),
+		SFTPRemoteDir:      getEnv("SFTP_REMOTE_DIR", "."),
+
+		// 成果物配送設定（クラウドストレージ）
+		OutputURLAllowedHosts: getEnv("OUTPUT_URL_ALLOWED_HOSTS", "storage.googleapis.com"),
+		StorageDeliveryPrefix: getEnv("STORAGE_DELIVERY_PREFIX", "deliveries"),
+
+		// クラウドドライブ連携設定（Google ドライブ / OneDrive）
+		GoogleDriveClientID:      getEnv("GOOGLE_DRIVE_CLIENT_ID", ""),
+		GoogleDriveClientSecret:  getEnv("GOOGLE_DRIVE_CLIENT_SECRET", ""),
+		OneDriveClientID:         getEnv("ONEDRIVE_CLIENT_ID", ""),
+		OneDriveClientSecret:     getEnv("ONEDRIVE_CLIENT_SECRET", ""),
+		OneDriveTenant:           getEnv("ONEDRIVE_TENANT", "common"),
+		ConnectorRedirectBaseURL: getEnv("CONNECTOR_REDIRECT_BASE_URL", ""),
+		ConnectorReturnURL:       getEnv("CONNECTOR_RETURN_URL", ""),
+		ConnectorMaxFiles:        getEnvAsInt("CONNECTOR_MAX_FILES", 20),
+
+		// ジョブ通知設定（Slack / Teams）
+		NotifyWebhookURL:          getEnv("NOTIFY_WEBHOOK_URL", ""),
+		NotifyWebhookAllowedHosts: getEnv("NOTIFY_WEBHOOK_ALLOWED_HOSTS", "hooks.slack.com,webhook.office.com,logic.azure.com"),
+
+		ErrorReportService: getEnv("ERROR_REPORT_SERVICE", getEnv("K_SERVICE", "paper-forge")),
+
+		// 利用量の重み付け・予算アラート設定
+		OperationCostWeights:  getEnv("OPERATION_COST_WEIGHTS", ""),
+		TenantMonthlyBudgets:  getEnv("TENANT_MONTHLY_BUDGETS", ""),
+		BudgetAlertThresholds: getEnv("BUDGET_ALERT_THRESHOLDS", "80,100"),
+		BudgetAlertWebhookURL: getEnv("BUDGET_ALERT_WEBHOOK_URL", ""),
+		BudgetAlertEmail:      getEnv("BUDGET_ALERT_EMAIL", ""),
 
 		// PDF処理設定
-		GhostscriptPath: getEnv("GHOSTSCRIPT_PATH", "gs"),
+		GhostscriptPath:        getEnv("GHOSTSCRIPT_PATH", "gs"),
+		ColorProfile:           getEnv("COLOR_PROFILE", ""),
+		ColorProfileSRGB:       getEnv("COLOR_PROFILE_SRGB", ""),
+		ColorProfileFOGRA39:    getEnv("COLOR_PROFILE_FOGRA39", ""),
+		TesseractPath:          getEnv("TESSERACT_PATH", ""),
+		OptimizeKeepOriginal:   getEnvAsBool("OPTIMIZE_KEEP_ORIGINAL_ON_NO_GAIN", true),
+		ExternalToolTimeoutSec: getEnvAsInt("EXTERNAL_TOOL_TIMEOUT_SECONDS", 300),
+		ExternalToolOutputKB:   getEnvAsInt("EXTERNAL_TOOL_OUTPUT_KB", 64),
+		PDFCheckLevel:          getEnv("PDF_CHECK_LEVEL", "standard"),
+		OutputPDFVersion:       getEnv("OUTPUT_PDF_VERSION", ""),
+		DeferPageCount:         getEnvAsBool("DEFER_PAGE_COUNT", false),
+		DisabledOperations:     getEnv("DISABLED_OPERATIONS", ""),
+		FaultInjection:         getEnv("FAULT_INJECTION", ""),
+
+		// 成果物の透かし設定
+		WatermarkText:       getEnv("WATERMARK_TEXT", ""),
+		WatermarkOperations: getEnv("WATERMARK_OPERATIONS", ""),
+		WatermarkStyle:      getEnv("WATERMARK_STYLE", ""),
+
+		// 成果物の処理記録設定
+		AuditRecord:           getEnvAsBool("AUDIT_RECORD", false),
+		AuditRecordOperations: getEnv("AUDIT_RECORD_OPERATIONS", ""),
+
+		// ワークスペース暗号化設定
+		WorkspaceEncryptionKeys: getEnv("WORKSPACE_ENCRYPTION_KEYS", ""),
+
+		// 成果物のマニフェスト署名設定
+		ResultSigningKeys:     getEnv("RESULT_SIGNING_KEYS", ""),
+		SigningProvider:       getEnv("SIGNING_PROVIDER", ""),
+		SigningKMSKeyVersions: getEnv("SIGNING_KMS_KEY_VERSIONS", ""),
+		VaultAddr:             getEnv("VAULT_ADDR", ""),
+		VaultToken:            getEnv("VAULT_TOKEN", ""),
+		VaultNamespace:        getEnv("VAULT_NAMESPACE", ""),
+		SigningVaultMount:     getEnv("SIGNING_VAULT_MOUNT", "transit"),
+		SigningVaultKey:       getEnv("SIGNING_VAULT_KEY", ""),
+		SigningPKCS12File:     getEnv("SIGNING_PKCS12_FILE", ""),
+		SigningPKCS12Password: getEnv("SIGNING_PKCS12_PASSWORD", ""),
 
 		// GCP設定
 		GCPProject:     getEnv("GCP_PROJECT", ""),
 		GCSBucket:      getEnv("GCS_BUCKET", ""),
 		ServiceAccount: getEnv("SERVICE_ACCOUNT", ""),
+
+		// データ所在地設定
+		DataRegion:       getEnv("DATA_REGION", ""),
+		GCSRegionBuckets: getEnv("GCS_REGION_BUCKETS", ""),
 	}
 
 	// 必須設定のバリデーション
@@ -123,7 +377,7 @@ func (c *Config) Validate() error {
 		if c.SessionSecret == "" {
 			return fmt.Errorf("SESSION_SECRET is required in release mode")
 		}
-		if c.QueueRedisURL == "" {
+		if (c.QueueRedisMode == "" || c.QueueRedisMode == RedisModeStandalone) && c.QueueRedisURL == "" {
 			return fmt.Errorf("QUEUE_REDIS_URL is required in release mode")
 		}
 		if c.GhostscriptPath == "" {
@@ -131,9 +385,354 @@ func (c *Config) Validate() error {
 		}
 	}
 
+	switch c.PDFCheckLevel {
+	case "", "lenient", "standard", "strict":
+	default:
+		return fmt.Errorf("PDF_CHECK_LEVEL must be one of lenient, standard, strict")
+	}
+
+	switch c.OutputPDFVersion {
+	case "", "1.4", "1.5", "1.6", "1.7":
+	default:
+		return fmt.Errorf("OUTPUT_PDF_VERSION must be one of 1.4, 1.5, 1.6, 1.7")
+	}
+
+	switch c.ColorProfile {
+	case "":
+	case "srgb":
+		if c.ColorProfileSRGB == "" {
+			return fmt.Errorf("COLOR_PROFILE_SRGB is required when COLOR_PROFILE=srgb")
+		}
+	case "fogra39":
+		if c.ColorProfileFOGRA39 == "" {
+			return fmt.Errorf("COLOR_PROFILE_FOGRA39 is required when COLOR_PROFILE=fogra39")
+		}
+	default:
+		return fmt.Errorf("COLOR_PROFILE must be one of srgb, fogra39")
+	}
+
+	if _, err := c.QueueRedisAddrList(); err != nil {
+		return err
+	}
+	if c.QueueRedisIAMAuth && c.QueueRedisMode == RedisModeSentinel {
+		return fmt.Errorf("QUEUE_REDIS_IAM_AUTH is not supported when QUEUE_REDIS_MODE=sentinel")
+	}
+	if c.QueueFailoverRedisURL != "" && c.QueueFailoverRedisURL == c.QueueRedisURL {
+		return fmt.Errorf("QUEUE_FAILOVER_REDIS_URL must differ from QUEUE_REDIS_URL")
+	}
+
+	if c.FaultInjection != "" {
+		if c.GinMode == "release" {
+			return fmt.Errorf("FAULT_INJECTION must not be set in release mode")
+		}
+		if _, err := faults.Parse(c.FaultInjection); err != nil {
+			return err
+		}
+	}
+
+	if c.KeepaliveURL != "" && c.KeepaliveIntervalSec <= 0 {
+		return fmt.Errorf("KEEPALIVE_URL requires KEEPALIVE_INTERVAL_SECONDS")
+	}
+
+	if _, _, err := c.WorkspaceKeys(); err != nil {
+		return err
+	}
+	if _, err := c.SigningBackend(); err != nil {
+		return err
+	}
+
+	if _, _, err := c.StorageLocation(); err != nil {
+		return err
+	}
+
+	if _, err := c.TenantKeys(); err != nil {
+		return err
+	}
+
+	if err := c.validateConnectors(); err != nil {
+		return err
+	}
+
+	if _, err := c.CostWeights(); err != nil {
+		return err
+	}
+	if _, err := c.TenantBudgets(); err != nil {
+		return err
+	}
+	if _, err := c.BudgetThresholds(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// validateConnectors はクラウドドライブ連携の設定を検証します。
+// OAuth のリダイレクト先とクライアントシークレットが無いと接続を完了できないため、起動時に検出します。
+func (c *Config) validateConnectors() error {
+	connectors := []struct{ name, id, secret string }{
+		{"GOOGLE_DRIVE", c.GoogleDriveClientID, c.GoogleDriveClientSecret},
+		{"ONEDRIVE", c.OneDriveClientID, c.OneDriveClientSecret},
+	}
+	for _, conn := range connectors {
+		if strings.TrimSpace(conn.id) == "" {
+			continue
+		}
+		if strings.TrimSpace(conn.secret) == "" {
+			return fmt.Errorf("%s_CLIENT_SECRET is required when %s_CLIENT_ID is set", conn.name, conn.name)
+		}
+		if strings.TrimSpace(c.ConnectorRedirectBaseURL) == "" {
+			return fmt.Errorf("CONNECTOR_REDIRECT_BASE_URL is required when %s_CLIENT_ID is set", conn.name)
+		}
+	}
 	return nil
 }
 
+// QUEUE_REDIS_MODE の値
+const (
+	RedisModeStandalone = "standalone"
+	RedisModeSentinel   = "sentinel"
+	RedisModeCluster    = "cluster"
+)
+
+// QueueRedisAddrList は QUEUE_REDIS_MODE に応じた QUEUE_REDIS_ADDRS の一覧を返します。
+// standalone の場合は QUEUE_REDIS_URL を使うため nil です。
+func (c *Config) QueueRedisAddrList() ([]string, error) {
+	var addrs []string
+	for _, entry := range strings.Split(c.QueueRedisAddrs, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			addrs = append(addrs, entry)
+		}
+	}
+	switch c.QueueRedisMode {
+	case "", RedisModeStandalone:
+		return nil, nil
+	case RedisModeSentinel:
+		if strings.TrimSpace(c.QueueRedisSentinelMaster) == "" {
+			return nil, fmt.Errorf("QUEUE_REDIS_SENTINEL_MASTER is required when QUEUE_REDIS_MODE=sentinel")
+		}
+	case RedisModeCluster:
+		if c.QueueRedisDB != 0 {
+			return nil, fmt.Errorf("QUEUE_REDIS_DB must be 0 when QUEUE_REDIS_MODE=cluster")
+		}
+	default:
+		return nil, fmt.Errorf("QUEUE_REDIS_MODE must be one of standalone, sentinel, cluster")
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("QUEUE_REDIS_ADDRS is required when QUEUE_REDIS_MODE=%s", c.QueueRedisMode)
+	}
+	return addrs, nil
+}
+
+// minTenantAPIKeyLength は推測されにくい API キーとして求める最低の長さです。
+const minTenantAPIKeyLength = 24
+
+// TenantKeys は TENANT_API_KEYS を解析し、テナントIDごとの API キーを返します。未設定の場合は nil です。
+func (c *Config) TenantKeys() (map[string]string, error) {
+	raw := strings.TrimSpace(c.TenantAPIKeys)
+	if raw == "" {
+		return nil, nil
+	}
+	keys := make(map[string]string)
+	seen := make(map[string]bool)
+	for _, entry := range strings.Split(raw, ",") {
+		id, key, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		id = strings.TrimSpace(id)
+		key = strings.TrimSpace(key)
+		if !ok || !tenant.Valid(id) {
+			return nil, fmt.Errorf("TENANT_API_KEYS must be a comma-separated list of tenantId:apiKey (tenantId: lowercase letters, digits and hyphens)")
+		}
+		// tenant.Default はログインセッションのテナントのため、API キーに割り当てると両者のジョブが混ざる
+		if id == tenant.Default {
+			return nil, fmt.Errorf("TENANT_API_KEYS: tenant %q is reserved for login sessions", id)
+		}
+		if len(key) < minTenantAPIKeyLength {
+			return nil, fmt.Errorf("TENANT_API_KEYS: key for tenant %q must be at least %d characters", id, minTenantAPIKeyLength)
+		}
+		if _, dup := keys[id]; dup {
+			return nil, fmt.Errorf("TENANT_API_KEYS: duplicate tenant %q", id)
+		}
+		if seen[key] {
+			return nil, fmt.Errorf("TENANT_API_KEYS: tenant %q reuses another tenant's key", id)
+		}
+		seen[key] = true
+		keys[id] = key
+	}
+	return keys, nil
+}
+
+// CostWeights は OPERATION_COST_WEIGHTS を解析し、操作ごとの1ページあたりのコスト単位を返します。
+func (c *Config) CostWeights() (map[string]float64, error) {
+	return parseNamedAmounts("OPERATION_COST_WEIGHTS", c.OperationCostWeights, func(string) bool { return true })
+}
+
+// TenantBudgets は TENANT_MONTHLY_BUDGETS を解析し、テナントごとの月間予算（コスト単位）を返します。
+func (c *Config) TenantBudgets() (map[string]float64, error) {
+	return parseNamedAmounts("TENANT_MONTHLY_BUDGETS", c.TenantMonthlyBudgets, tenant.Valid)
+}
+
+// BudgetThresholds は BUDGET_ALERT_THRESHOLDS を解析し、アラートを送る予算消化率（%）を昇順で返します。
+func (c *Config) BudgetThresholds() ([]int, error) {
+	var thresholds []int
+	for _, entry := range strings.Split(c.BudgetAlertThresholds, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		pct, err := strconv.Atoi(entry)
+		if err != nil || pct <= 0 || pct > 1000 {
+			return nil, fmt.Errorf("BUDGET_ALERT_THRESHOLDS must be a comma-separated list of percentages (1-1000)")
+		}
+		thresholds = append(thresholds, pct)
+	}
+	sort.Ints(thresholds)
+	return thresholds, nil
+}
+
+// parseNamedAmounts は "名前=数値" のカンマ区切りを解析します。数値は0以上でなければなりません。
+func parseNamedAmounts(env, raw string, validName func(string) bool) (map[string]float64, error) {
+	amounts := make(map[string]float64)
+	for _, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(entry, "=")
+		name = strings.ToLower(strings.TrimSpace(name))
+		amount, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if !ok || name == "" || !validName(name) || err != nil || amount < 0 {
+			return nil, fmt.Errorf("%s must be a comma-separated list of name=number", env)
+		}
+		if _, dup := amounts[name]; dup {
+			return nil, fmt.Errorf("%s: duplicate entry %q", env, name)
+		}
+		amounts[name] = amount
+	}
+	return amounts, nil
+}
+
+// StorageLocation は DATA_REGION と GCS_REGION_BUCKETS から、成果物を書き込むバケットとオブジェクト名のプレフィックスを返します。
+// DATA_REGION が未設定の場合は GCS_BUCKET を返します。設定されたリージョンの保存先が無い場合は、
+// 別リージョンへ書き込まないようエラーを返します。
+func (c *Config) StorageLocation() (bucket, prefix string, err error) {
+	region := strings.ToLower(strings.TrimSpace(c.DataRegion))
+	locations := make(map[string][2]string)
+	for _, entry := range strings.Split(c.GCSRegionBuckets, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, target, ok := strings.Cut(entry, "=")
+		name = strings.ToLower(strings.TrimSpace(name))
+		target = strings.Trim(strings.TrimSpace(target), "/")
+		if !ok || name == "" || target == "" {
+			return "", "", fmt.Errorf("GCS_REGION_BUCKETS must be a comma-separated list of region=bucket[/prefix]")
+		}
+		if _, dup := locations[name]; dup {
+			return "", "", fmt.Errorf("GCS_REGION_BUCKETS: duplicate region %q", name)
+		}
+		b, p, _ := strings.Cut(target, "/")
+		locations[name] = [2]string{b, strings.Trim(p, "/")}
+	}
+
+	if region == "" {
+		return c.GCSBucket, "", nil
+	}
+	loc, ok := locations[region]
+	if !ok {
+		return "", "", fmt.Errorf("DATA_REGION %q has no storage location in GCS_REGION_BUCKETS", region)
+	}
+	return loc[0], loc[1], nil
+}
+
+// WorkspaceKeys は WORKSPACE_ENCRYPTION_KEYS を解析し、暗号化に使う鍵IDと鍵IDごとの鍵を返します。
+// 未設定の場合は active が空になります。
+func (c *Config) WorkspaceKeys() (active string, keys map[string][]byte, err error) {
+	return parseKeyList("WORKSPACE_ENCRYPTION_KEYS", c.WorkspaceEncryptionKeys)
+}
+
+// SigningKeys は成果物のマニフェストの署名鍵（Ed25519 の32バイトのシード）を解析します。
+// active は署名に使う鍵（先頭の鍵）のIDで、残りは検証用の公開鍵として公開し続ける以前の鍵です。未設定の場合は空です。
+func (c *Config) SigningKeys() (active string, seeds map[string][]byte, err error) {
+	return parseKeyList("RESULT_SIGNING_KEYS", c.ResultSigningKeys)
+}
+
+// 署名を行う場所（SIGNING_PROVIDER）
+const (
+	SigningProviderKeys   = "keys"
+	SigningProviderGCPKMS = "gcpkms"
+	SigningProviderVault  = "vault"
+	SigningProviderPKCS12 = "pkcs12"
+)
+
+// SigningBackend は署名を行う場所を返します。署名しない場合は空です。
+// SIGNING_PROVIDER が空の場合は、従来どおり RESULT_SIGNING_KEYS があれば keys とします。
+func (c *Config) SigningBackend() (string, error) {
+	provider := strings.ToLower(strings.TrimSpace(c.SigningProvider))
+	if provider == "" && strings.TrimSpace(c.ResultSigningKeys) != "" {
+		provider = SigningProviderKeys
+	}
+	switch provider {
+	case "":
+	case SigningProviderKeys:
+		active, _, err := c.SigningKeys()
+		if err != nil {
+			return "", err
+		}
+		if active == "" {
+			return "", fmt.Errorf("RESULT_SIGNING_KEYS is required when SIGNING_PROVIDER=keys")
+		}
+	case SigningProviderGCPKMS:
+		for _, name := range strings.Split(c.SigningKMSKeyVersions, ",") {
+			if !strings.Contains(strings.TrimSpace(name), "/cryptoKeyVersions/") {
+				return "", fmt.Errorf("SIGNING_KMS_KEY_VERSIONS must be a comma-separated list of projects/.../cryptoKeyVersions/N")
+			}
+		}
+	case SigningProviderVault:
+		if strings.TrimSpace(c.VaultAddr) == "" || strings.TrimSpace(c.VaultToken) == "" || strings.TrimSpace(c.SigningVaultKey) == "" {
+			return "", fmt.Errorf("VAULT_ADDR, VAULT_TOKEN and SIGNING_VAULT_KEY are required when SIGNING_PROVIDER=vault")
+		}
+	case SigningProviderPKCS12:
+		// 秘密鍵をディスクに置く構成のため、開発環境に限る
+		if c.GinMode == "release" {
+			return "", fmt.Errorf("SIGNING_PROVIDER=pkcs12 must not be used in release mode; use gcpkms or vault")
+		}
+		if strings.TrimSpace(c.SigningPKCS12File) == "" {
+			return "", fmt.Errorf("SIGNING_PKCS12_FILE is required when SIGNING_PROVIDER=pkcs12")
+		}
+	default:
+		return "", fmt.Errorf("SIGNING_PROVIDER must be one of keys, gcpkms, vault, pkcs12")
+	}
+	return provider, nil
+}
+
+// parseKeyList は "鍵ID:base64鍵" のカンマ区切りの設定 raw を解析します。鍵はいずれも32バイトです。
+func parseKeyList(name, raw string) (active string, keys map[string][]byte, err error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", nil, nil
+	}
+	keys = make(map[string][]byte)
+	for _, entry := range strings.Split(raw, ",") {
+		id, encoded, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		id = strings.TrimSpace(id)
+		if !ok || id == "" {
+			return "", nil, fmt.Errorf("%s must be a comma-separated list of keyId:base64key", name)
+		}
+		key, decodeErr := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if decodeErr != nil || len(key) != 32 {
+			return "", nil, fmt.Errorf("%s: key %q must be 32 bytes encoded in base64", name, id)
+		}
+		if _, dup := keys[id]; dup {
+			return "", nil, fmt.Errorf("%s: duplicate key id %q", name, id)
+		}
+		if active == "" {
+			active = id
+		}
+		keys[id] = key
+	}
+	return active, keys, nil
+}
+
 // getEnv は環境変数を取得し、存在しない場合はデフォルト値を返します。
 func getEnv(key string, defaultValue string) string {
 	value := os.Getenv(key)
@@ -168,3 +767,16 @@ func getEnvAsInt64(key string, defaultValue int64) int64 {
 	}
 	return value
 }
+
+// getEnvAsBool は環境変数を真偽値として取得します。
+func getEnvAsBool(key string, defaultValue bool) bool {
+	valueStr := os.Getenv(key)
+	if valueStr == "" {
+		return defaultValue
+	}
+	value, err := strconv.ParseBool(valueStr)
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
@@ -0,0 +1,57 @@
+package config
+
+import "testing"
+
+func TestStorageLocation(t *testing.T) {
+	tests := []struct {
+		name       string
+		cfg        Config
+		wantBucket string
+		wantPrefix string
+		wantErr    bool
+	}{
+		{name: "no region uses GCS_BUCKET", cfg: Config{GCSBucket: "pdf-tools", GCSRegionBuckets: "eu=pdf-eu"}, wantBucket: "pdf-tools"},
+		{name: "region with bucket only", cfg: Config{GCSBucket: "pdf-tools", DataRegion: "eu", GCSRegionBuckets: "eu=pdf-eu,us=pdf-us"}, wantBucket: "pdf-eu"},
+		{name: "region with prefix", cfg: Config{DataRegion: " EU ", GCSRegionBuckets: " us=pdf-us , eu = pdf-shared/eu/data/ "}, wantBucket: "pdf-shared", wantPrefix: "eu/data"},
+		{name: "region without a location", cfg: Config{GCSBucket: "pdf-tools", DataRegion: "jp", GCSRegionBuckets: "eu=pdf-eu"}, wantErr: true},
+		{name: "region without any mapping", cfg: Config{GCSBucket: "pdf-tools", DataRegion: "eu"}, wantErr: true},
+		{name: "malformed entry", cfg: Config{DataRegion: "eu", GCSRegionBuckets: "eu"}, wantErr: true},
+		{name: "empty bucket", cfg: Config{DataRegion: "eu", GCSRegionBuckets: "eu=/"}, wantErr: true},
+		{name: "duplicate region", cfg: Config{DataRegion: "eu", GCSRegionBuckets: "eu=a,EU=b"}, wantErr: true},
+		{name: "malformed entry without a region", cfg: Config{GCSBucket: "pdf-tools", GCSRegionBuckets: "=pdf-eu"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bucket, prefix, err := tt.cfg.StorageLocation()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if bucket != tt.wantBucket || prefix != tt.wantPrefix {
+				t.Fatalf("StorageLocation() = %q, %q; want %q, %q", bucket, prefix, tt.wantBucket, tt.wantPrefix)
+			}
+		})
+	}
+}
+
+func TestTenantKeys(t *testing.T) {
+	const key = "0123456789abcdef0123456789abcdef"
+	keys, err := (&Config{TenantAPIKeys: " sales:" + key + " , legal:" + key + "x"}).TenantKeys()
+	if err != nil {
+		t.Fatalf("TenantKeys returned error: %v", err)
+	}
+	if len(keys) != 2 || keys["sales"] != key || keys["legal"] != key+"x" {
+		t.Fatalf("unexpected keys: %v", keys)
+	}
+
+	for _, raw := range []string{
+		"default:" + key,
+		"Sales:" + key,
+		"sales:short",
+		"sales:" + key + ",sales:" + key + "x",
+		"sales:" + key + ",legal:" + key,
+	} {
+		if _, err := (&Config{TenantAPIKeys: raw}).TenantKeys(); err == nil {
+			t.Errorf("TenantKeys(%q) should fail", raw)
+		}
+	}
+}
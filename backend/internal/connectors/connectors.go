@@ -0,0 +1,433 @@
+// Package connectors は Google ドライブ / OneDrive と OAuth で接続し、ジョブの入力の取り込みと成果物の書き戻しを行います。
+//
+// アクセストークンは API サーバーのメモリ上に保持し、利用者のセッションには接続を指すIDだけを保存します。
+// トークンがブラウザへ渡らない代わりに、インスタンスが再起動すると接続し直す必要があります。
+package connectors
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yourusername/paper-forge/internal/config"
+)
+
+// Provider は連携先のクラウドドライブです。ジョブの配送方法（deliverTo）と同じ値を使います。
+type Provider string
+
+const (
+	GoogleDrive Provider = "gdrive"
+	OneDrive    Provider = "onedrive"
+)
+
+const (
+	// stateTTL は認可画面へ移ってから戻るまでの有効期限です。
+	stateTTL = 10 * time.Minute
+	// tokenExpirySkew は期限切れの少し前にアクセストークンを更新するための余裕です。
+	tokenExpirySkew = time.Minute
+)
+
+var (
+	// ErrNotConfigured は連携先の OAuth クライアントが設定されていないことを表します。
+	ErrNotConfigured = errors.New("connector is not configured")
+	// ErrNotConnected は利用者が連携先に接続していない（または接続の有効期限が切れた）ことを表します。
+	ErrNotConnected = errors.New("connector is not connected")
+	// ErrStateInvalid は OAuth の state の署名・有効期限が正しくないことを表します。
+	ErrStateInvalid = errors.New("oauth state is invalid or expired")
+	// ErrNotPDF は取り込もうとしたファイルが PDF ではないことを表します。
+	ErrNotPDF = errors.New("file is not a pdf")
+	// ErrFileTooLarge は取り込もうとしたファイルがサイズの上限を超えていることを表します。
+	ErrFileTooLarge = errors.New("file is too large")
+	// ErrFileNotFound はファイルが存在しないか、接続の権限で参照できない場合のエラーです。
+	ErrFileNotFound = errors.New("file is not found")
+)
+
+// File はクラウドドライブ上のファイルの情報です。
+type File struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Size     int64  `json:"size"`
+	MimeType string `json:"mimeType,omitempty"`
+}
+
+// oauthEndpoint は連携先の OAuth 2.0 の認可・トークンのエンドポイントです。
+type oauthEndpoint struct {
+	authURL  string
+	tokenURL string
+	scopes   []string
+	// params は認可URLに付ける連携先固有のパラメータです。
+	params url.Values
+}
+
+// driveAPI は連携先ごとのファイル操作です。
+type driveAPI interface {
+	stat(ctx context.Context, client *http.Client, token, fileID string) (File, error)
+	download(ctx context.Context, client *http.Client, token, fileID string) (io.ReadCloser, error)
+	upload(ctx context.Context, client *http.Client, token, folderID, name, contentType string, body io.Reader, size int64) error
+}
+
+type provider struct {
+	clientID     string
+	clientSecret string
+	endpoint     oauthEndpoint
+	api          driveAPI
+}
+
+// Manager は連携先ごとの OAuth クライアントと、利用者の接続を管理します。
+type Manager struct {
+	providers    map[Provider]*provider
+	redirectBase string
+	returnURL    string
+	maxFiles     int
+	secret       []byte
+	client       *http.Client
+	store        *connectionStore
+	now          func() time.Time
+}
+
+// NewManager は設定済みの連携先を登録した Manager を作成します。
+// 接続はプロセス内で共有するため、API のハンドラーと配送のワーカーで別々に作成しても同じ接続を参照します。
+func NewManager(cfg *config.Config) *Manager {
+	m := &Manager{
+		providers:    make(map[Provider]*provider),
+		redirectBase: strings.TrimRight(strings.TrimSpace(cfg.ConnectorRedirectBaseURL), "/"),
+		returnURL:    strings.TrimSpace(cfg.ConnectorReturnURL),
+		maxFiles:     cfg.ConnectorMaxFiles,
+		secret:       stateSecret(cfg.SessionSecret),
+		client:       &http.Client{Timeout: 10 * time.Minute},
+		store:        sharedConnections,
+		now:          time.Now,
+	}
+	if id := strings.TrimSpace(cfg.GoogleDriveClientID); id != "" {
+		m.providers[GoogleDrive] = &provider{
+			clientID:     id,
+			clientSecret: cfg.GoogleDriveClientSecret,
+			endpoint:     googleEndpoint,
+			api:          newGoogleDrive(),
+		}
+	}
+	if id := strings.TrimSpace(cfg.OneDriveClientID); id != "" {
+		m.providers[OneDrive] = &provider{
+			clientID:     id,
+			clientSecret: cfg.OneDriveClientSecret,
+			endpoint:     oneDriveEndpoint(cfg.OneDriveTenant),
+			api:          newOneDrive(),
+		}
+	}
+	return m
+}
+
+// ParseProvider は連携先の名前を検証します。
+func ParseProvider(raw string) (Provider, bool) {
+	switch p := Provider(strings.ToLower(strings.TrimSpace(raw))); p {
+	case GoogleDrive, OneDrive:
+		return p, true
+	default:
+		return "", false
+	}
+}
+
+// Providers は設定済みの連携先を名前順に返します。
+func (m *Manager) Providers() []Provider {
+	if m == nil {
+		return nil
+	}
+	list := make([]Provider, 0, len(m.providers))
+	for p := range m.providers {
+		list = append(list, p)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i] < list[j] })
+	return list
+}
+
+// Enabled は連携先 p が設定済みかを返します。
+func (m *Manager) Enabled(p Provider) bool {
+	if m == nil {
+		return false
+	}
+	_, ok := m.providers[p]
+	return ok
+}
+
+// MaxFiles は1回のリクエストで取り込めるファイル数です。
+func (m *Manager) MaxFiles() int {
+	return m.maxFiles
+}
+
+// Begin は新しい接続のIDと、利用者を送る連携先の認可画面のURL、認可を始めたブラウザを確かめる nonce を返します。
+// 接続のIDは利用者のセッションに保存し、Complete で認可が完了するまではトークンを持ちません。
+// nonce は認可を始めたブラウザにだけ渡し（短命のクッキー）、戻ってきたときに Complete へ渡します。
+// state は nonce を含めて署名するため、他人が始めた認可の state を別のブラウザで使うことはできません。
+func (m *Manager) Begin(p Provider) (connectionID, authURL, nonce string, err error) {
+	prov, ok := m.providers[p]
+	if !ok {
+		return "", "", "", fmt.Errorf("%w: %s", ErrNotConfigured, p)
+	}
+	connectionID, err = randomID()
+	if err != nil {
+		return "", "", "", err
+	}
+	nonce, err = randomID()
+	if err != nil {
+		return "", "", "", err
+	}
+	query := url.Values{}
+	query.Set("client_id", prov.clientID)
+	query.Set("redirect_uri", m.redirectURL(p))
+	query.Set("response_type", "code")
+	query.Set("scope", strings.Join(prov.endpoint.scopes, " "))
+	query.Set("state", m.signState(p, connectionID, nonce, m.now().Add(stateTTL)))
+	for key, values := range prov.endpoint.params {
+		query[key] = values
+	}
+	return connectionID, prov.endpoint.authURL + "?" + query.Encode(), nonce, nil
+}
+
+// Complete は連携先から戻った認可コードをトークンに交換し、state が指す接続に保存します。
+// nonce は Begin で認可を始めたブラウザに渡したものです。一致しない場合は ErrStateInvalid です。
+func (m *Manager) Complete(ctx context.Context, p Provider, state, nonce, code string) error {
+	prov, ok := m.providers[p]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNotConfigured, p)
+	}
+	connectionID, err := m.verifyState(p, state, nonce)
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(code) == "" {
+		return fmt.Errorf("authorization code is missing")
+	}
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", m.redirectURL(p))
+	token, err := m.requestToken(ctx, prov, form)
+	if err != nil {
+		return err
+	}
+	m.store.put(connectionID, &connection{provider: p, token: token}, m.now())
+	return nil
+}
+
+// Connected は接続 connectionID が連携先 p のトークンを持っているかを返します。
+func (m *Manager) Connected(p Provider, connectionID string) bool {
+	conn := m.store.get(connectionID, m.now())
+	return conn != nil && conn.provider == p
+}
+
+// Disconnect は接続のトークンを破棄します。
+func (m *Manager) Disconnect(connectionID string) {
+	m.store.remove(connectionID)
+}
+
+// DisconnectAll はこのプロセスが保持するすべての接続のトークンを破棄し、破棄した接続の数を返します。
+// 利用者の保存データをまとめて削除する際に使います。
+func (m *Manager) DisconnectAll() int {
+	return m.store.clear()
+}
+
+// Open はファイル fileID の情報と内容を返します。maxSize が正の場合、それを超えるファイルは ErrFileTooLarge です。
+// PDF 以外のファイル（Google ドキュメントなど）は ErrNotPDF です。
+func (m *Manager) Open(ctx context.Context, p Provider, connectionID, fileID string, maxSize int64) (File, io.ReadCloser, error) {
+	prov, token, err := m.accessToken(ctx, p, connectionID)
+	if err != nil {
+		return File{}, nil, err
+	}
+	file, err := prov.api.stat(ctx, m.client, token, fileID)
+	if err != nil {
+		return File{}, nil, err
+	}
+	if file.MimeType != "" && file.MimeType != "application/pdf" {
+		return file, nil, fmt.Errorf("%w: %s (%s)", ErrNotPDF, file.Name, file.MimeType)
+	}
+	if maxSize > 0 && file.Size > maxSize {
+		return file, nil, fmt.Errorf("%w: %s", ErrFileTooLarge, file.Name)
+	}
+	body, err := prov.api.download(ctx, m.client, token, fileID)
+	if err != nil {
+		return file, nil, err
+	}
+	return file, body, nil
+}
+
+// Upload は body を連携先のフォルダ folderID（空の場合はマイドライブ / ルート）へ name として書き込みます。
+// 同じ名前のファイルがある場合も上書きしません。
+func (m *Manager) Upload(ctx context.Context, p Provider, connectionID, folderID, name, contentType string, body io.Reader, size int64) error {
+	prov, token, err := m.accessToken(ctx, p, connectionID)
+	if err != nil {
+		return err
+	}
+	return prov.api.upload(ctx, m.client, token, folderID, name, contentType, body, size)
+}
+
+// ReturnURL は接続の完了後に利用者を戻すフロントエンドのURLです。status は connected / denied / error です。
+func (m *Manager) ReturnURL(p Provider, status string) string {
+	base := m.returnURL
+	if base == "" {
+		base = "/"
+	}
+	sep := "?"
+	if strings.Contains(base, "?") {
+		sep = "&"
+	}
+	query := url.Values{}
+	query.Set("connector", string(p))
+	query.Set("status", status)
+	return base + sep + query.Encode()
+}
+
+func (m *Manager) redirectURL(p Provider) string {
+	return fmt.Sprintf("%s/api/connectors/%s/callback", m.redirectBase, p)
+}
+
+// accessToken は接続の有効なアクセストークンを返します。期限切れの場合はリフレッシュトークンで更新します。
+func (m *Manager) accessToken(ctx context.Context, p Provider, connectionID string) (*provider, string, error) {
+	prov, ok := m.providers[p]
+	if !ok {
+		return nil, "", fmt.Errorf("%w: %s", ErrNotConfigured, p)
+	}
+	conn := m.store.get(connectionID, m.now())
+	if conn == nil || conn.provider != p {
+		return nil, "", fmt.Errorf("%w: %s", ErrNotConnected, p)
+	}
+
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+	if conn.token.expiresAt.IsZero() || m.now().Add(tokenExpirySkew).Before(conn.token.expiresAt) {
+		return prov, conn.token.AccessToken, nil
+	}
+	if conn.token.RefreshToken == "" {
+		m.store.remove(connectionID)
+		return nil, "", fmt.Errorf("%w: %s (token expired)", ErrNotConnected, p)
+	}
+	form := url.Values{}
+	form.Set("grant_type", "refresh_token")
+	form.Set("refresh_token", conn.token.RefreshToken)
+	token, err := m.requestToken(ctx, prov, form)
+	if err != nil {
+		return nil, "", err
+	}
+	// 更新時にリフレッシュトークンを返さない連携先では、以前のものを使い続ける
+	if token.RefreshToken == "" {
+		token.RefreshToken = conn.token.RefreshToken
+	}
+	conn.token = token
+	return prov, token.AccessToken, nil
+}
+
+// oauthToken はトークンエンドポイントの応答です。
+type oauthToken struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+	expiresAt    time.Time
+}
+
+func (m *Manager) requestToken(ctx context.Context, prov *provider, form url.Values) (oauthToken, error) {
+	form.Set("client_id", prov.clientID)
+	form.Set("client_secret", prov.clientSecret)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, prov.endpoint.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return oauthToken{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return oauthToken{}, fmt.Errorf("token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if err := checkResponse(resp, "token endpoint"); err != nil {
+		return oauthToken{}, err
+	}
+	var token oauthToken
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return oauthToken{}, fmt.Errorf("failed to parse token response: %w", err)
+	}
+	if token.AccessToken == "" {
+		return oauthToken{}, fmt.Errorf("token endpoint returned an empty access token")
+	}
+	if token.ExpiresIn > 0 {
+		token.expiresAt = m.now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	}
+	return token, nil
+}
+
+// signState は接続のIDと有効期限を連携先ごとに署名した state を返します。
+// OAuth の戻り（callback）は別サイトからの遷移のためセッションのクッキーが届かないことがあり、
+// どの接続の認可かは state の署名で確認します。
+// signState は接続のIDと有効期限を state にします。nonce は state に含めず、署名の対象にだけ加えます。
+func (m *Manager) signState(p Provider, connectionID, nonce string, expiresAt time.Time) string {
+	expires := strconv.FormatInt(expiresAt.Unix(), 10)
+	return connectionID + "." + expires + "." + m.stateMAC(p, connectionID, nonce, expires)
+}
+
+func (m *Manager) verifyState(p Provider, state, nonce string) (string, error) {
+	parts := strings.Split(state, ".")
+	if len(parts) != 3 || nonce == "" {
+		return "", ErrStateInvalid
+	}
+	connectionID, expires, signature := parts[0], parts[1], parts[2]
+	if !hmac.Equal([]byte(m.stateMAC(p, connectionID, nonce, expires)), []byte(signature)) {
+		return "", ErrStateInvalid
+	}
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || m.now().Unix() > unix {
+		return "", ErrStateInvalid
+	}
+	return connectionID, nil
+}
+
+func (m *Manager) stateMAC(p Provider, connectionID, nonce, expires string) string {
+	mac := hmac.New(sha256.New, m.secret)
+	// セッション用の鍵を流用するため、用途を示す接頭辞を含めて署名対象を分離する
+	mac.Write([]byte("connector:" + string(p) + ":" + connectionID + ":" + nonce + ":" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// stateSecret は state の署名鍵です。SESSION_SECRET が無い開発環境では起動ごとの乱数を使います
+// （接続はメモリ上にしかないため、再起動で署名鍵が変わっても失うものは無い）。
+func stateSecret(sessionSecret string) []byte {
+	if strings.TrimSpace(sessionSecret) != "" {
+		return []byte(sessionSecret)
+	}
+	buf := make([]byte, 32)
+	_, _ = rand.Read(buf)
+	return buf
+}
+
+func randomID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate connection id: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// checkResponse は 2xx 以外の応答をエラーにします。応答の本文は先頭だけをエラーに含めます。
+func checkResponse(resp *http.Response, what string) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	detail, _ := io.ReadAll(io.LimitReader(resp.Body, 2048))
+	switch resp.StatusCode {
+	case http.StatusUnauthorized:
+		return fmt.Errorf("%w: %s responded with 401", ErrNotConnected, what)
+	case http.StatusNotFound:
+		return fmt.Errorf("%w: %s responded with 404", ErrFileNotFound, what)
+	}
+	return fmt.Errorf("%s responded with %d: %s", what, resp.StatusCode, strings.TrimSpace(string(detail)))
+}
@@ -0,0 +1,277 @@
+package connectors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/yourusername/paper-forge/internal/config"
+)
+
+// newTestManager は Google ドライブと OneDrive の API を srv に向けた Manager を作成します。
+func newTestManager(t *testing.T, srv *httptest.Server) *Manager {
+	t.Helper()
+	m := NewManager(&config.Config{
+		SessionSecret:            "test-session-secret",
+		GoogleDriveClientID:      "google-client",
+		GoogleDriveClientSecret:  "google-secret",
+		OneDriveClientID:         "onedrive-client",
+		OneDriveClientSecret:     "onedrive-secret",
+		ConnectorRedirectBaseURL: "https://forge.example.com/",
+		ConnectorReturnURL:       "https://forge.example.com/app",
+		ConnectorMaxFiles:        5,
+	})
+	m.store = newConnectionStore()
+	m.client = srv.Client()
+	for _, prov := range m.providers {
+		prov.endpoint.tokenURL = srv.URL + "/token"
+	}
+	m.providers[GoogleDrive].api = &googleDrive{apiBase: srv.URL + "/drive/v3", uploadBase: srv.URL + "/upload/drive/v3"}
+	m.providers[OneDrive].api = &oneDrive{graphBase: srv.URL + "/graph"}
+	return m
+}
+
+// connect は Begin と Complete で接続を作成し、そのIDを返します。
+func connect(t *testing.T, m *Manager, p Provider) string {
+	t.Helper()
+	connectionID, authURL, nonce, err := m.Begin(p)
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatalf("auth url: %v", err)
+	}
+	if got := u.Query().Get("redirect_uri"); got != "https://forge.example.com/api/connectors/"+string(p)+"/callback" {
+		t.Fatalf("unexpected redirect_uri %q", got)
+	}
+	if err := m.Complete(context.Background(), p, u.Query().Get("state"), nonce, "auth-code"); err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	if !m.Connected(p, connectionID) {
+		t.Fatalf("connection should be stored after Complete")
+	}
+	return connectionID
+}
+
+func TestGoogleDriveOpenDownloadsPDF(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/token":
+			if r.FormValue("code") != "auth-code" || r.FormValue("client_secret") != "google-secret" {
+				http.Error(w, "bad request", http.StatusBadRequest)
+				return
+			}
+			fmt.Fprint(w, `{"access_token":"access-1","refresh_token":"refresh-1","expires_in":3600}`)
+		case r.Header.Get("Authorization") != "Bearer access-1":
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+		case r.URL.Path == "/drive/v3/files/doc" && r.URL.Query().Get("alt") == "media":
+			fmt.Fprint(w, "%PDF-1.7 body")
+		case r.URL.Path == "/drive/v3/files/doc":
+			fmt.Fprint(w, `{"id":"doc","name":"contract.pdf","size":"13","mimeType":"application/pdf"}`)
+		case r.URL.Path == "/drive/v3/files/sheet":
+			fmt.Fprint(w, `{"id":"sheet","name":"budget","mimeType":"application/vnd.google-apps.spreadsheet"}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	m := newTestManager(t, srv)
+	connectionID := connect(t, m, GoogleDrive)
+
+	file, body, err := m.Open(context.Background(), GoogleDrive, connectionID, "doc", 1024)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	data, _ := io.ReadAll(body)
+	body.Close()
+	if file.Name != "contract.pdf" || file.Size != 13 || string(data) != "%PDF-1.7 body" {
+		t.Fatalf("unexpected file %+v %q", file, data)
+	}
+
+	if _, _, err := m.Open(context.Background(), GoogleDrive, connectionID, "doc", 10); !errors.Is(err, ErrFileTooLarge) {
+		t.Fatalf("expected ErrFileTooLarge, got %v", err)
+	}
+	if _, _, err := m.Open(context.Background(), GoogleDrive, connectionID, "sheet", 0); !errors.Is(err, ErrNotPDF) {
+		t.Fatalf("expected ErrNotPDF, got %v", err)
+	}
+	if _, _, err := m.Open(context.Background(), GoogleDrive, connectionID, "missing", 0); !errors.Is(err, ErrFileNotFound) {
+		t.Fatalf("expected ErrFileNotFound, got %v", err)
+	}
+	// 別の連携先の接続としては使えない
+	if _, _, err := m.Open(context.Background(), OneDrive, connectionID, "doc", 0); !errors.Is(err, ErrNotConnected) {
+		t.Fatalf("expected ErrNotConnected, got %v", err)
+	}
+}
+
+func TestAccessTokenRefreshesExpiredToken(t *testing.T) {
+	var refreshes atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.FormValue("grant_type") {
+		case "authorization_code":
+			fmt.Fprint(w, `{"access_token":"access-1","refresh_token":"refresh-1","expires_in":3600}`)
+		case "refresh_token":
+			if r.FormValue("refresh_token") != "refresh-1" {
+				http.Error(w, "invalid_grant", http.StatusBadRequest)
+				return
+			}
+			refreshes.Add(1)
+			// 更新時はリフレッシュトークンを返さない
+			fmt.Fprint(w, `{"access_token":"access-2","expires_in":3600}`)
+		}
+	}))
+	defer srv.Close()
+	m := newTestManager(t, srv)
+	now := time.Now()
+	m.now = func() time.Time { return now }
+	connectionID := connect(t, m, OneDrive)
+
+	if _, token, err := m.accessToken(context.Background(), OneDrive, connectionID); err != nil || token != "access-1" {
+		t.Fatalf("unexpected token %q: %v", token, err)
+	}
+	now = now.Add(2 * time.Hour)
+	for range 2 {
+		if _, token, err := m.accessToken(context.Background(), OneDrive, connectionID); err != nil || token != "access-2" {
+			t.Fatalf("expected refreshed token, got %q: %v", token, err)
+		}
+	}
+	if refreshes.Load() != 1 {
+		t.Fatalf("expected one refresh, got %d", refreshes.Load())
+	}
+	// 以前のリフレッシュトークンを使い続ける
+	now = now.Add(2 * time.Hour)
+	if _, token, err := m.accessToken(context.Background(), OneDrive, connectionID); err != nil || token != "access-2" {
+		t.Fatalf("expected second refresh with the original refresh token, got %q: %v", token, err)
+	}
+}
+
+func TestVerifyStateRejectsTamperedAndExpired(t *testing.T) {
+	m := NewManager(&config.Config{SessionSecret: "test-session-secret", GoogleDriveClientID: "id"})
+	now := time.Now()
+	m.now = func() time.Time { return now }
+	state := m.signState(GoogleDrive, "conn", "nonce", now.Add(stateTTL))
+
+	if id, err := m.verifyState(GoogleDrive, state, "nonce"); err != nil || id != "conn" {
+		t.Fatalf("valid state rejected: %q %v", id, err)
+	}
+	cases := map[string]func() (string, error){
+		"other provider": func() (string, error) { return m.verifyState(OneDrive, state, "nonce") },
+		"tampered":       func() (string, error) { return m.verifyState(GoogleDrive, "other"+state, "nonce") },
+		"malformed":      func() (string, error) { return m.verifyState(GoogleDrive, "conn", "nonce") },
+		"other browser":  func() (string, error) { return m.verifyState(GoogleDrive, state, "other") },
+		"no nonce":       func() (string, error) { return m.verifyState(GoogleDrive, state, "") },
+		"expired": func() (string, error) {
+			now = now.Add(stateTTL + time.Second)
+			return m.verifyState(GoogleDrive, state, "nonce")
+		},
+	}
+	for name, verify := range cases {
+		if _, err := verify(); !errors.Is(err, ErrStateInvalid) {
+			t.Fatalf("%s: expected ErrStateInvalid, got %v", name, err)
+		}
+	}
+}
+
+func TestCompleteRejectsCallbackFromAnotherSession(t *testing.T) {
+	m := NewManager(&config.Config{SessionSecret: "test-session-secret", GoogleDriveClientID: "id"})
+	m.store = newConnectionStore()
+	// 攻撃者が自分で始めた認可の state を、被害者のブラウザ（被害者の nonce）で戻させる
+	_, _, victimNonce, err := m.Begin(GoogleDrive)
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	attackerID, authURL, _, err := m.Begin(GoogleDrive)
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatalf("auth url: %v", err)
+	}
+
+	if err := m.Complete(context.Background(), GoogleDrive, u.Query().Get("state"), victimNonce, "auth-code"); !errors.Is(err, ErrStateInvalid) {
+		t.Fatalf("expected ErrStateInvalid, got %v", err)
+	}
+	if m.Connected(GoogleDrive, attackerID) {
+		t.Fatal("callback from another session should not store a token")
+	}
+}
+
+func TestOneDriveUploadUsesUploadSession(t *testing.T) {
+	var received strings.Builder
+	var srvURL string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/token":
+			fmt.Fprint(w, `{"access_token":"access-1","expires_in":3600}`)
+		case r.URL.Path == "/graph/me/drive/items/folder!1:/result.pdf:/createUploadSession":
+			if r.Header.Get("Authorization") != "Bearer access-1" {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprintf(w, `{"uploadUrl":%q}`, srvURL+"/session")
+		case r.URL.Path == "/session":
+			if r.Header.Get("Authorization") != "" {
+				http.Error(w, "upload url is pre-authenticated", http.StatusBadRequest)
+				return
+			}
+			if r.Header.Get("Content-Range") != "bytes 0-12/13" {
+				http.Error(w, "bad range", http.StatusBadRequest)
+				return
+			}
+			data, _ := io.ReadAll(r.Body)
+			received.Write(data)
+			w.WriteHeader(http.StatusCreated)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	srvURL = srv.URL
+	m := newTestManager(t, srv)
+	connectionID := connect(t, m, OneDrive)
+
+	err := m.Upload(context.Background(), OneDrive, connectionID, "folder!1", "result.pdf", "application/pdf", strings.NewReader("%PDF-1.7 body"), 13)
+	if err != nil {
+		t.Fatalf("Upload: %v", err)
+	}
+	if received.String() != "%PDF-1.7 body" {
+		t.Fatalf("unexpected upload %q", received.String())
+	}
+}
+
+func TestReturnURLKeepsExistingQuery(t *testing.T) {
+	m := NewManager(&config.Config{ConnectorReturnURL: "https://forge.example.com/app?tab=merge"})
+	got := m.ReturnURL(GoogleDrive, "connected")
+	if got != "https://forge.example.com/app?tab=merge&connector=gdrive&status=connected" {
+		t.Fatalf("unexpected return url %q", got)
+	}
+}
+
+func TestDisconnectAllDropsTokens(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"access_token":"access-1","refresh_token":"refresh-1","expires_in":3600}`)
+	}))
+	defer srv.Close()
+	m := newTestManager(t, srv)
+	google := connect(t, m, GoogleDrive)
+	onedrive := connect(t, m, OneDrive)
+
+	if n := m.DisconnectAll(); n != 2 {
+		t.Fatalf("expected 2 connections to be dropped, got %d", n)
+	}
+	if m.Connected(GoogleDrive, google) || m.Connected(OneDrive, onedrive) {
+		t.Fatal("tokens should be discarded")
+	}
+	if n := m.DisconnectAll(); n != 0 {
+		t.Fatalf("nothing should be left to drop, got %d", n)
+	}
+}
@@ -0,0 +1,140 @@
+package connectors
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// googleEndpoint は Google の OAuth 2.0 のエンドポイントです。
+// drive.file はアプリで作成したファイルと、利用者が Google Picker で選んだファイルだけを扱える最小の権限です。
+var googleEndpoint = oauthEndpoint{
+	authURL:  "https://accounts.google.com/o/oauth2/v2/auth",
+	tokenURL: "https://oauth2.googleapis.com/token",
+	scopes:   []string{"https://www.googleapis.com/auth/drive.file"},
+	params: url.Values{
+		// 非同期ジョブの書き戻しはアクセストークンの期限後になることがあるため、リフレッシュトークンを受け取る
+		"access_type": {"offline"},
+		"prompt":      {"consent"},
+	},
+}
+
+// googleDrive は Google Drive API v3 でファイルを読み書きします。
+type googleDrive struct {
+	apiBase    string
+	uploadBase string
+}
+
+func newGoogleDrive() *googleDrive {
+	return &googleDrive{
+		apiBase:    "https://www.googleapis.com/drive/v3",
+		uploadBase: "https://www.googleapis.com/upload/drive/v3",
+	}
+}
+
+func (g *googleDrive) stat(ctx context.Context, client *http.Client, token, fileID string) (File, error) {
+	query := url.Values{}
+	query.Set("fields", "id,name,size,mimeType")
+	query.Set("supportsAllDrives", "true")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, g.fileURL(fileID)+"?"+query.Encode(), nil)
+	if err != nil {
+		return File{}, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := client.Do(req)
+	if err != nil {
+		return File{}, fmt.Errorf("google drive request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if err := checkResponse(resp, "google drive"); err != nil {
+		return File{}, err
+	}
+	var payload struct {
+		ID       string `json:"id"`
+		Name     string `json:"name"`
+		Size     string `json:"size"` // int64 は文字列で返る
+		MimeType string `json:"mimeType"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return File{}, fmt.Errorf("failed to parse google drive file: %w", err)
+	}
+	size, _ := strconv.ParseInt(payload.Size, 10, 64)
+	return File{ID: payload.ID, Name: payload.Name, Size: size, MimeType: payload.MimeType}, nil
+}
+
+func (g *googleDrive) download(ctx context.Context, client *http.Client, token, fileID string) (io.ReadCloser, error) {
+	query := url.Values{}
+	query.Set("alt", "media")
+	query.Set("supportsAllDrives", "true")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, g.fileURL(fileID)+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("google drive download failed: %w", err)
+	}
+	if err := checkResponse(resp, "google drive"); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// upload は再開可能アップロードでファイルを作成します。メタデータ（名前・フォルダ）と内容を分けて送れるため、大きな成果物にも使えます。
+func (g *googleDrive) upload(ctx context.Context, client *http.Client, token, folderID, name, contentType string, body io.Reader, size int64) error {
+	metadata := map[string]any{"name": name}
+	if folderID != "" {
+		metadata["parents"] = []string{folderID}
+	}
+	encoded, err := json.Marshal(metadata)
+	if err != nil {
+		return err
+	}
+	query := url.Values{}
+	query.Set("uploadType", "resumable")
+	query.Set("supportsAllDrives", "true")
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, g.uploadBase+"/files?"+query.Encode(), bytes.NewReader(encoded))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json; charset=UTF-8")
+	req.Header.Set("X-Upload-Content-Type", contentType)
+	req.Header.Set("X-Upload-Content-Length", strconv.FormatInt(size, 10))
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("google drive upload failed: %w", err)
+	}
+	resp.Body.Close()
+	if err := checkResponse(resp, "google drive"); err != nil {
+		return err
+	}
+	session := resp.Header.Get("Location")
+	if session == "" {
+		return fmt.Errorf("google drive did not return an upload session")
+	}
+
+	put, err := http.NewRequestWithContext(ctx, http.MethodPut, session, body)
+	if err != nil {
+		return err
+	}
+	put.ContentLength = size
+	put.Header.Set("Content-Type", contentType)
+	resp, err = client.Do(put)
+	if err != nil {
+		return fmt.Errorf("google drive upload failed: %w", err)
+	}
+	defer resp.Body.Close()
+	return checkResponse(resp, "google drive")
+}
+
+func (g *googleDrive) fileURL(fileID string) string {
+	return g.apiBase + "/files/" + url.PathEscape(fileID)
+}
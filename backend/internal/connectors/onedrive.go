@@ -0,0 +1,163 @@
+package connectors
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// oneDriveChunkSize はアップロードセッションで1回に送る大きさです（Graph API の要件により 320KiB の倍数）。
+const oneDriveChunkSize = 32 * 320 * 1024
+
+// oneDriveEndpoint は Microsoft ID プラットフォームの OAuth 2.0 のエンドポイントです。
+// tenant は common（個人・職場の両方）/ organizations / テナントIDです。
+func oneDriveEndpoint(tenant string) oauthEndpoint {
+	tenant = strings.TrimSpace(tenant)
+	if tenant == "" {
+		tenant = "common"
+	}
+	base := "https://login.microsoftonline.com/" + url.PathEscape(tenant) + "/oauth2/v2.0"
+	return oauthEndpoint{
+		authURL:  base + "/authorize",
+		tokenURL: base + "/token",
+		// offline_access は非同期ジョブの書き戻しに使うリフレッシュトークンのため
+		scopes: []string{"offline_access", "Files.ReadWrite"},
+	}
+}
+
+// oneDrive は Microsoft Graph でサインインした利用者の OneDrive のファイルを読み書きします。
+type oneDrive struct {
+	graphBase string
+}
+
+func newOneDrive() *oneDrive {
+	return &oneDrive{graphBase: "https://graph.microsoft.com/v1.0"}
+}
+
+func (o *oneDrive) stat(ctx context.Context, client *http.Client, token, fileID string) (File, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, o.itemURL(fileID)+"?$select=id,name,size,file", nil)
+	if err != nil {
+		return File{}, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := client.Do(req)
+	if err != nil {
+		return File{}, fmt.Errorf("onedrive request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if err := checkResponse(resp, "onedrive"); err != nil {
+		return File{}, err
+	}
+	var payload struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+		Size int64  `json:"size"`
+		File *struct {
+			MimeType string `json:"mimeType"`
+		} `json:"file"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return File{}, fmt.Errorf("failed to parse onedrive item: %w", err)
+	}
+	if payload.File == nil {
+		// フォルダなどファイル以外の項目
+		return File{ID: payload.ID, Name: payload.Name, MimeType: "inode/directory"}, nil
+	}
+	return File{ID: payload.ID, Name: payload.Name, Size: payload.Size, MimeType: payload.File.MimeType}, nil
+}
+
+// download は内容を取得します。Graph API は事前認証済みのダウンロードURLへリダイレクトし、
+// 別ホストへのリダイレクトでは Authorization ヘッダーは送られません。
+func (o *oneDrive) download(ctx context.Context, client *http.Client, token, fileID string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, o.itemURL(fileID)+"/content", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("onedrive download failed: %w", err)
+	}
+	if err := checkResponse(resp, "onedrive"); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// upload はアップロードセッションで oneDriveChunkSize ずつ送ります。単純なアップロード（PUT）は 250MB までのため使いません。
+func (o *oneDrive) upload(ctx context.Context, client *http.Client, token, folderID, name, contentType string, body io.Reader, size int64) error {
+	if size <= 0 {
+		return fmt.Errorf("onedrive upload requires a non-empty file")
+	}
+	parent := o.graphBase + "/me/drive/root"
+	if folderID != "" {
+		parent = o.itemURL(folderID)
+	}
+	endpoint := parent + ":/" + url.PathEscape(name) + ":/createUploadSession"
+	encoded, err := json.Marshal(map[string]any{
+		"item": map[string]string{"@microsoft.graph.conflictBehavior": "rename"},
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(encoded))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("onedrive upload failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if err := checkResponse(resp, "onedrive"); err != nil {
+		return err
+	}
+	var session struct {
+		UploadURL string `json:"uploadUrl"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&session); err != nil || session.UploadURL == "" {
+		return fmt.Errorf("onedrive did not return an upload session")
+	}
+
+	buf := make([]byte, oneDriveChunkSize)
+	for offset := int64(0); offset < size; {
+		n, err := io.ReadFull(body, buf[:min(int64(len(buf)), size-offset)])
+		if err != nil {
+			return fmt.Errorf("failed to read result: %w", err)
+		}
+		if err := o.putChunk(ctx, client, session.UploadURL, buf[:n], offset, size, contentType); err != nil {
+			return err
+		}
+		offset += int64(n)
+	}
+	return nil
+}
+
+// putChunk はアップロードセッションのURL（事前認証済みのため Authorization は付けない）へ1チャンクを送ります。
+func (o *oneDrive) putChunk(ctx context.Context, client *http.Client, uploadURL string, chunk []byte, offset, size int64, contentType string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, uploadURL, bytes.NewReader(chunk))
+	if err != nil {
+		return err
+	}
+	req.ContentLength = int64(len(chunk))
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", offset, offset+int64(len(chunk))-1, size))
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("onedrive upload failed: %w", err)
+	}
+	defer resp.Body.Close()
+	return checkResponse(resp, "onedrive")
+}
+
+func (o *oneDrive) itemURL(itemID string) string {
+	return o.graphBase + "/me/drive/items/" + url.PathEscape(itemID)
+}
@@ -0,0 +1,78 @@
+package connectors
+
+import (
+	"sync"
+	"time"
+)
+
+// connectionIdleTTL は使われていない接続を破棄するまでの時間です（ログインセッションの最長期間と同じ）。
+const connectionIdleTTL = 12 * time.Hour
+
+// sharedConnections はプロセス内のすべての Manager が参照する接続です。
+var sharedConnections = newConnectionStore()
+
+// connection は利用者1人分の連携先への接続です。
+type connection struct {
+	provider Provider
+	// mu はトークンの更新を1回にまとめるためのロックです。
+	mu       sync.Mutex
+	token    oauthToken
+	lastUsed time.Time
+}
+
+// connectionStore は接続のIDから接続を引く、メモリ上の保存先です。
+type connectionStore struct {
+	mu    sync.Mutex
+	conns map[string]*connection
+}
+
+func newConnectionStore() *connectionStore {
+	return &connectionStore{conns: make(map[string]*connection)}
+}
+
+// put は接続を保存し、しばらく使われていない接続を破棄します。
+func (s *connectionStore) put(id string, conn *connection, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, c := range s.conns {
+		if now.Sub(c.lastUsed) > connectionIdleTTL {
+			delete(s.conns, key)
+		}
+	}
+	conn.lastUsed = now
+	s.conns[id] = conn
+}
+
+// get は接続を返します。無い場合や期限切れの場合は nil です。
+func (s *connectionStore) get(id string, now time.Time) *connection {
+	if id == "" {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	conn, ok := s.conns[id]
+	if !ok {
+		return nil
+	}
+	if now.Sub(conn.lastUsed) > connectionIdleTTL {
+		delete(s.conns, id)
+		return nil
+	}
+	conn.lastUsed = now
+	return conn
+}
+
+func (s *connectionStore) remove(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.conns, id)
+}
+
+// clear はすべての接続を破棄し、破棄した数を返します。
+func (s *connectionStore) clear() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := len(s.conns)
+	s.conns = make(map[string]*connection)
+	return n
+}
@@ -0,0 +1,81 @@
+// Package delivery はジョブ完了時に成果物を外部へ配送する機能を提供します。
+package delivery
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/yourusername/paper-forge/internal/config"
+	"github.com/yourusername/paper-forge/internal/connectors"
+	"github.com/yourusername/paper-forge/internal/pdf"
+)
+
+// ErrNotConfigured は配送方法に必要な設定が行われていないことを表します。
+var ErrNotConfigured = errors.New("delivery method is not configured")
+
+// Request は1件の配送依頼を表します。
+type Request struct {
+	Tenant      string // ジョブを投入したテナント（ストレージのオブジェクト名のプレフィックスに使う）
+	Result      *pdf.Result
+	Target      pdf.DeliveryTarget
+	DownloadURL string    // 署名付きの共有ダウンロードURL（発行できない場合は空）
+	ExpiresAt   time.Time // DownloadURL の有効期限
+}
+
+// Sender は配送方法ごとの送信処理を実装します。
+type Sender interface {
+	Send(ctx context.Context, req *Request) error
+}
+
+// Dispatcher は配送方法に応じて Sender を選択します。
+type Dispatcher struct {
+	senders map[pdf.DeliveryMethod]Sender
+	storage *storageSender
+}
+
+// NewDispatcher は設定済みの配送方法を登録した Dispatcher を作成します。
+func NewDispatcher(cfg *config.Config) *Dispatcher {
+	d := &Dispatcher{
+		senders: make(map[pdf.DeliveryMethod]Sender),
+	}
+	if sender := newEmailSender(cfg); sender != nil {
+		d.senders[pdf.DeliveryMethodEmail] = sender
+	}
+	if sender := newSFTPSender(cfg); sender != nil {
+		d.senders[pdf.DeliveryMethodSFTP] = sender
+	}
+	if sender := newStorageSender(cfg); sender != nil {
+		d.senders[pdf.DeliveryMethodStorage] = sender
+		d.storage = sender
+	}
+	drives := connectors.NewManager(cfg)
+	for _, p := range drives.Providers() {
+		d.senders[pdf.DeliveryMethod(p)] = &driveSender{connectors: drives, provider: p}
+	}
+	return d
+}
+
+// Deliver は依頼された配送方法で成果物を送信します。
+func (d *Dispatcher) Deliver(ctx context.Context, req *Request) error {
+	if req == nil || req.Result == nil {
+		return fmt.Errorf("delivery request is incomplete")
+	}
+	sender, ok := d.senders[req.Target.Method]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNotConfigured, req.Target.Method)
+	}
+	return sender.Send(ctx, req)
+}
+
+// StoreObject は成果物以外のデータ（ジョブ情報のアーカイブなど）を、設定済みバケットの objectPath へ書き込みます。
+// オブジェクト名にはデータ所在地のプレフィックスを付け、テナントの階層は付けません。GCS_BUCKET が未設定の場合は ErrNotConfigured です。
+func (d *Dispatcher) StoreObject(ctx context.Context, objectPath, contentType string, data []byte) error {
+	if d == nil || d.storage == nil || d.storage.bucket == "" {
+		return fmt.Errorf("%w: GCS_BUCKET is not set", ErrNotConfigured)
+	}
+	name := objectName(d.storage.prefix, objectPath)
+	return d.storage.putObject(ctx, name, contentType, bytes.NewReader(data), int64(len(data)))
+}
@@ -0,0 +1,28 @@
+package delivery
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/yourusername/paper-forge/internal/connectors"
+)
+
+// driveSender は成果物をジョブを投入した利用者のクラウドドライブ（Google ドライブ / OneDrive）へ書き戻します。
+type driveSender struct {
+	connectors *connectors.Manager
+	provider   connectors.Provider
+}
+
+func (s *driveSender) Send(ctx context.Context, req *Request) error {
+	if req.Target.Connection == "" {
+		return fmt.Errorf("%w: %s delivery requires a connection", connectors.ErrNotConnected, s.provider)
+	}
+	file, err := req.Result.Open()
+	if err != nil {
+		return fmt.Errorf("failed to open result: %w", err)
+	}
+	defer file.Close()
+
+	return s.connectors.Upload(ctx, s.provider, req.Target.Connection, req.Target.FolderID,
+		req.Result.OutputFilename, contentTypeFor(req.Result.ResultKind), file, req.Result.OutputSize)
+}
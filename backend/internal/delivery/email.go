@@ -0,0 +1,341 @@
+package delivery
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yourusername/paper-forge/internal/config"
+	"github.com/yourusername/paper-forge/internal/pdf"
+)
+
+const sendGridEndpoint = "https://api.sendgrid.com/v3/mail/send"
+
+// ErrRecipientNotAllowed は送信先のメールアドレスが MAIL_ALLOWED_RECIPIENTS で許可されていないことを表します。
+var ErrRecipientNotAllowed = errors.New("recipient is not allowed by MAIL_ALLOWED_RECIPIENTS")
+
+type mailAttachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+type mailMessage struct {
+	From       string
+	To         string
+	Subject    string
+	Body       string
+	Attachment *mailAttachment
+}
+
+type mailTransport interface {
+	send(ctx context.Context, msg *mailMessage) error
+}
+
+// emailSender は成果物をメールで送信します。
+// 添付上限を超える成果物は添付せず、署名付きダウンロードリンクを本文に記載します。
+type emailSender struct {
+	from          string
+	maxAttachment int64
+	transport     mailTransport
+	// policy は送信できる宛先です。受付時にも検証しますが、設定の変更前に投入されたジョブに備えて送信前にも確認します。
+	policy *pdf.DeliveryPolicy
+}
+
+func newEmailSender(cfg *config.Config) *emailSender {
+	transport := newMailTransport(cfg)
+	if transport == nil {
+		return nil
+	}
+	return &emailSender{
+		from:          cfg.MailFrom,
+		maxAttachment: cfg.MailAttachmentMaxBytes,
+		transport:     transport,
+		policy:        pdf.NewDeliveryPolicy(cfg),
+	}
+}
+
+// newMailTransport は設定済みの送信方法を返します。MAIL_FROM または送信方法が未設定の場合は nil です。
+func newMailTransport(cfg *config.Config) mailTransport {
+	if cfg == nil || strings.TrimSpace(cfg.MailFrom) == "" {
+		return nil
+	}
+	switch {
+	case cfg.SendGridAPIKey != "":
+		return &sendGridTransport{
+			apiKey: cfg.SendGridAPIKey,
+			client: &http.Client{Timeout: 30 * time.Second},
+		}
+	case cfg.SMTPHost != "":
+		return &smtpTransport{
+			host:     cfg.SMTPHost,
+			port:     cfg.SMTPPort,
+			username: cfg.SMTPUsername,
+			password: cfg.SMTPPassword,
+		}
+	default:
+		return nil
+	}
+}
+
+// Mailer は成果物を伴わないお知らせメール（予算アラートなど）を送信します。
+type Mailer struct {
+	from      string
+	transport mailTransport
+}
+
+// NewMailer はメール送信の設定から Mailer を作成します。送信方法が未設定の場合は nil を返します。
+func NewMailer(cfg *config.Config) *Mailer {
+	transport := newMailTransport(cfg)
+	if transport == nil {
+		return nil
+	}
+	return &Mailer{from: cfg.MailFrom, transport: transport}
+}
+
+// Send は to へテキストのメールを送信します。
+func (m *Mailer) Send(ctx context.Context, to, subject, body string) error {
+	if m == nil {
+		return ErrNotConfigured
+	}
+	return m.transport.send(ctx, &mailMessage{
+		From:    m.from,
+		To:      to,
+		Subject: subject,
+		Body:    body,
+	})
+}
+
+func (s *emailSender) Send(ctx context.Context, req *Request) error {
+	if !s.policy.AllowsEmailRecipient(req.Target.Email) {
+		return ErrRecipientNotAllowed
+	}
+	result := req.Result
+	msg := &mailMessage{
+		From:    s.from,
+		To:      req.Target.Email,
+		Subject: fmt.Sprintf("[Paper Forge] ジョブが完了しました (%s)", result.OutputFilename),
+	}
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "Paper Forge のジョブが完了しました。\n\n")
+	fmt.Fprintf(&body, "ジョブID: %s\n操作: %s\nファイル: %s (%d bytes)\n\n", result.JobID, result.Operation, result.OutputFilename, result.OutputSize)
+
+	if s.maxAttachment > 0 && result.OutputSize <= s.maxAttachment {
+		data, err := readResult(result)
+		if err != nil {
+			return fmt.Errorf("failed to read result for attachment: %w", err)
+		}
+		msg.Attachment = &mailAttachment{
+			Filename:    result.OutputFilename,
+			ContentType: contentTypeFor(result.ResultKind),
+			Data:        data,
+		}
+		body.WriteString("成果物を添付しています。\n")
+	} else {
+		if req.DownloadURL == "" {
+			return fmt.Errorf("result is too large to attach (%d bytes) and no download link is available", result.OutputSize)
+		}
+		fmt.Fprintf(&body, "成果物のサイズが大きいため、以下のリンクからダウンロードしてください。\n%s\n\n", req.DownloadURL)
+		fmt.Fprintf(&body, "リンクの有効期限: %s\n", req.ExpiresAt.UTC().Format(time.RFC3339))
+	}
+	msg.Body = body.String()
+
+	return s.transport.send(ctx, msg)
+}
+
+func contentTypeFor(kind pdf.ResultKind) string {
+	switch kind {
+	case pdf.ResultKindPDF:
+		return "application/pdf"
+	case pdf.ResultKindZIP:
+		return "application/zip"
+	default:
+		return "application/octet-stream"
+	}
+}
+
+type smtpTransport struct {
+	host     string
+	port     int
+	username string
+	password string
+}
+
+func (t *smtpTransport) send(ctx context.Context, msg *mailMessage) error {
+	raw, err := buildMIMEMessage(msg)
+	if err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if t.username != "" {
+		auth = smtp.PlainAuth("", t.username, t.password, t.host)
+	}
+
+	// net/smtp は context を受け取らないため、キャンセル時は結果を待たずに戻る
+	addr := net.JoinHostPort(t.host, strconv.Itoa(t.port))
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(addr, auth, msg.From, []string{msg.To}, raw)
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("smtp send failed: %w", err)
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func buildMIMEMessage(msg *mailMessage) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+
+	fmt.Fprintf(&buf, "From: %s\r\n", msg.From)
+	fmt.Fprintf(&buf, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.BEncoding.Encode("UTF-8", msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&buf, "Content-Type: multipart/mixed; boundary=%s\r\n\r\n", writer.Boundary())
+
+	bodyHeader := textproto.MIMEHeader{}
+	bodyHeader.Set("Content-Type", "text/plain; charset=UTF-8")
+	bodyHeader.Set("Content-Transfer-Encoding", "base64")
+	part, err := writer.CreatePart(bodyHeader)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeBase64Lines(part, []byte(msg.Body)); err != nil {
+		return nil, err
+	}
+
+	if msg.Attachment != nil {
+		attachHeader := textproto.MIMEHeader{}
+		attachHeader.Set("Content-Type", msg.Attachment.ContentType)
+		attachHeader.Set("Content-Transfer-Encoding", "base64")
+		attachHeader.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": msg.Attachment.Filename}))
+		part, err := writer.CreatePart(attachHeader)
+		if err != nil {
+			return nil, err
+		}
+		if err := writeBase64Lines(part, msg.Attachment.Data); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeBase64Lines は RFC 2045 に従い76文字ごとに改行した base64 を書き込みます。
+func writeBase64Lines(w io.Writer, data []byte) error {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 76 {
+		if _, err := io.WriteString(w, encoded[:76]+"\r\n"); err != nil {
+			return err
+		}
+		encoded = encoded[76:]
+	}
+	_, err := io.WriteString(w, encoded+"\r\n")
+	return err
+}
+
+type sendGridTransport struct {
+	apiKey string
+	client *http.Client
+}
+
+type sendGridAddress struct {
+	Email string `json:"email"`
+}
+
+type sendGridPersonalization struct {
+	To []sendGridAddress `json:"to"`
+}
+
+type sendGridContent struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type sendGridAttachment struct {
+	Content     string `json:"content"`
+	Type        string `json:"type"`
+	Filename    string `json:"filename"`
+	Disposition string `json:"disposition"`
+}
+
+type sendGridPayload struct {
+	Personalizations []sendGridPersonalization `json:"personalizations"`
+	From             sendGridAddress           `json:"from"`
+	Subject          string                    `json:"subject"`
+	Content          []sendGridContent         `json:"content"`
+	Attachments      []sendGridAttachment      `json:"attachments,omitempty"`
+}
+
+func (t *sendGridTransport) send(ctx context.Context, msg *mailMessage) error {
+	payload := sendGridPayload{
+		Personalizations: []sendGridPersonalization{{To: []sendGridAddress{{Email: msg.To}}}},
+		From:             sendGridAddress{Email: msg.From},
+		Subject:          msg.Subject,
+		Content:          []sendGridContent{{Type: "text/plain", Value: msg.Body}},
+	}
+	if msg.Attachment != nil {
+		payload.Attachments = []sendGridAttachment{{
+			Content:     base64.StdEncoding.EncodeToString(msg.Attachment.Data),
+			Type:        msg.Attachment.ContentType,
+			Filename:    msg.Attachment.Filename,
+			Disposition: "attachment",
+		}}
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sendGridEndpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+t.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("sendgrid request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 2048))
+		return fmt.Errorf("sendgrid responded with %d: %s", resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
+
+// readResult は成果物の内容を読み込みます。ワークスペースが暗号化されている場合は復号した内容を返します。
+func readResult(result *pdf.Result) ([]byte, error) {
+	file, err := result.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return io.ReadAll(file)
+}
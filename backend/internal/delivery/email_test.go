@@ -0,0 +1,138 @@
+package delivery
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yourusername/paper-forge/internal/config"
+	"github.com/yourusername/paper-forge/internal/pdf"
+)
+
+type recordingTransport struct {
+	sent []*mailMessage
+}
+
+func (t *recordingTransport) send(_ context.Context, msg *mailMessage) error {
+	t.sent = append(t.sent, msg)
+	return nil
+}
+
+func newTestEmailSender(maxAttachment int64) (*emailSender, *recordingTransport) {
+	transport := &recordingTransport{}
+	return &emailSender{
+		from:          "noreply@example.com",
+		maxAttachment: maxAttachment,
+		transport:     transport,
+		policy:        &pdf.DeliveryPolicy{EmailEnabled: true, EmailRecipients: []string{"example.com"}},
+	}, transport
+}
+
+func testEmailRequest(t *testing.T, email, downloadURL string) *Request {
+	t.Helper()
+	data := []byte("%PDF-1.4\n% delivered\n")
+	path := filepath.Join(t.TempDir(), "merged.pdf")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("failed to write result: %v", err)
+	}
+	return &Request{
+		Result: &pdf.Result{
+			JobID:          "job-1",
+			Operation:      pdf.OperationMerge,
+			OutputPath:     path,
+			OutputFilename: "merged.pdf",
+			OutputSize:     int64(len(data)),
+			ResultKind:     pdf.ResultKindPDF,
+		},
+		Target:      pdf.DeliveryTarget{Method: pdf.DeliveryMethodEmail, Email: email},
+		DownloadURL: downloadURL,
+		ExpiresAt:   time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+}
+
+func TestEmailSenderSend(t *testing.T) {
+	tests := []struct {
+		name          string
+		maxAttachment int64
+		email         string
+		downloadURL   string
+		wantErr       string
+		wantAttached  bool
+		wantBody      string
+	}{
+		{name: "attaches small results", maxAttachment: 1024, email: "user@example.com", wantAttached: true, wantBody: "成果物を添付しています。"},
+		{name: "falls back to a link for large results", maxAttachment: 8, email: "user@example.com", downloadURL: "https://api.example.com/dl?sig=abc", wantBody: "https://api.example.com/dl?sig=abc"},
+		{name: "falls back to a link when attachments are disabled", email: "user@example.com", downloadURL: "https://api.example.com/dl?sig=abc", wantBody: "2025-01-02T03:04:05Z"},
+		{name: "fails without a link for large results", maxAttachment: 8, email: "user@example.com", wantErr: "no download link"},
+		{name: "rejects recipients outside the allowlist", maxAttachment: 1024, email: "attacker@example.net", wantErr: "MAIL_ALLOWED_RECIPIENTS"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender, transport := newTestEmailSender(tt.maxAttachment)
+			err := sender.Send(context.Background(), testEmailRequest(t, tt.email, tt.downloadURL))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				if len(transport.sent) != 0 {
+					t.Fatalf("nothing should be sent on error, got %d messages", len(transport.sent))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Send returned error: %v", err)
+			}
+			if len(transport.sent) != 1 {
+				t.Fatalf("expected one message, got %d", len(transport.sent))
+			}
+			msg := transport.sent[0]
+			if msg.From != "noreply@example.com" || msg.To != tt.email || !strings.Contains(msg.Subject, "merged.pdf") {
+				t.Fatalf("unexpected envelope: %+v", msg)
+			}
+			if (msg.Attachment != nil) != tt.wantAttached {
+				t.Fatalf("attachment = %v, want %v", msg.Attachment != nil, tt.wantAttached)
+			}
+			if tt.wantAttached && (msg.Attachment.ContentType != "application/pdf" || !strings.HasPrefix(string(msg.Attachment.Data), "%PDF-1.4")) {
+				t.Fatalf("unexpected attachment: %s %q", msg.Attachment.ContentType, msg.Attachment.Data)
+			}
+			if !strings.Contains(msg.Body, tt.wantBody) {
+				t.Fatalf("body should contain %q:\n%s", tt.wantBody, msg.Body)
+			}
+		})
+	}
+}
+
+func TestNewEmailSenderRequiresSender(t *testing.T) {
+	if newEmailSender(&config.Config{SMTPHost: "smtp.example.com", MailAllowedRecipients: "example.com"}) != nil {
+		t.Fatal("email delivery should be disabled without MAIL_FROM")
+	}
+	if newEmailSender(&config.Config{MailFrom: "noreply@example.com", MailAllowedRecipients: "example.com"}) != nil {
+		t.Fatal("email delivery should be disabled without a transport")
+	}
+	sender := newEmailSender(&config.Config{MailFrom: "noreply@example.com", SMTPHost: "smtp.example.com", MailAllowedRecipients: "example.com"})
+	if sender == nil || !sender.policy.AllowsEmailRecipient("user@example.com") {
+		t.Fatalf("unexpected sender: %+v", sender)
+	}
+}
+
+func TestBuildMIMEMessageWithAttachment(t *testing.T) {
+	raw, err := buildMIMEMessage(&mailMessage{
+		From:       "noreply@example.com",
+		To:         "user@example.com",
+		Subject:    "[Paper Forge] ジョブが完了しました",
+		Body:       "本文",
+		Attachment: &mailAttachment{Filename: "結合.pdf", ContentType: "application/pdf", Data: []byte("%PDF")},
+	})
+	if err != nil {
+		t.Fatalf("buildMIMEMessage returned error: %v", err)
+	}
+	text := string(raw)
+	for _, want := range []string{"To: user@example.com\r\n", "=?UTF-8?b?", "Content-Type: application/pdf", "filename*=utf-8''%E7%B5%90%E5%90%88.pdf"} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("message should contain %q:\n%s", want, text)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package delivery
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yourusername/paper-forge/internal/config"
+	"github.com/yourusername/paper-forge/internal/execrun"
+)
+
+// sftpSender はデプロイ単位で設定されたSFTPサーバーへ成果物をアップロードします。
+// 多くの文書管理システムはSFTPの取り込みディレクトリを監視しているため、
+// 一時名でアップロードしてから rename し、書き込み途中のファイルを拾われないようにします。
+type sftpSender struct {
+	binary     string
+	host       string
+	port       int
+	user       string
+	keyPath    string
+	knownHosts string
+	remoteDir  string
+	runner     execrun.Runner
+}
+
+func newSFTPSender(cfg *config.Config) *sftpSender {
+	if cfg == nil || strings.TrimSpace(cfg.SFTPHost) == "" {
+		return nil
+	}
+	binary := cfg.SFTPPath
+	if binary == "" {
+		binary = "sftp"
+	}
+	remoteDir := cfg.SFTPRemoteDir
+	if remoteDir == "" {
+		remoteDir = "."
+	}
+	return &sftpSender{
+		binary:     binary,
+		host:       cfg.SFTPHost,
+		port:       cfg.SFTPPort,
+		user:       cfg.SFTPUser,
+		keyPath:    cfg.SFTPKeyPath,
+		knownHosts: cfg.SFTPKnownHostsPath,
+		remoteDir:  remoteDir,
+		runner: &execrun.ExecRunner{
+			Timeout:   time.Duration(cfg.ExternalToolTimeoutSec) * time.Second,
+			MaxOutput: cfg.ExternalToolOutputKB * 1024,
+			// ssh-agent 経由の鍵でも認証できるよう、エージェントのソケットを引き継ぐ
+			AllowEnv: []string{"SSH_AUTH_SOCK"},
+		},
+	}
+}
+
+func (s *sftpSender) Send(ctx context.Context, req *Request) error {
+	result := req.Result
+	remoteName := fmt.Sprintf("%s-%s", result.JobID, result.OutputFilename)
+	finalPath := path.Join(s.remoteDir, remoteName)
+	tempPath := finalPath + ".part"
+
+	// sftp コマンドはローカルのファイルパスを要求するため、暗号化されている場合は一時的に復号する
+	localPath, release, err := result.LocalPath()
+	if err != nil {
+		return fmt.Errorf("failed to prepare result for upload: %w", err)
+	}
+	defer release()
+
+	// 先頭の "-" はコマンド失敗を無視する sftp バッチ構文（既存ディレクトリの mkdir 対策）
+	batch := strings.Join([]string{
+		"-mkdir " + quoteSFTPArg(s.remoteDir),
+		"put " + quoteSFTPArg(localPath) + " " + quoteSFTPArg(tempPath),
+		"rename " + quoteSFTPArg(tempPath) + " " + quoteSFTPArg(finalPath),
+		"bye",
+	}, "\n") + "\n"
+
+	out, err := s.runner.Run(ctx, execrun.Command{
+		Name:  s.binary,
+		Args:  s.args(),
+		Stdin: strings.NewReader(batch),
+	})
+	if err != nil {
+		return fmt.Errorf("sftp upload failed: %w: %s", err, out.Combined())
+	}
+	return nil
+}
+
+func (s *sftpSender) args() []string {
+	args := []string{
+		"-b", "-",
+		"-o", "BatchMode=yes",
+		"-o", "StrictHostKeyChecking=yes",
+	}
+	if s.port > 0 {
+		args = append(args, "-P", strconv.Itoa(s.port))
+	}
+	if s.keyPath != "" {
+		args = append(args, "-i", s.keyPath)
+	}
+	if s.knownHosts != "" {
+		args = append(args, "-o", "UserKnownHostsFile="+s.knownHosts)
+	}
+
+	destination := s.host
+	if s.user != "" {
+		destination = s.user + "@" + s.host
+	}
+	return append(args, destination)
+}
+
+// quoteSFTPArg は sftp バッチファイル用に引数をダブルクォートで囲みます。
+func quoteSFTPArg(v string) string {
+	v = strings.ReplaceAll(v, `\`, `\\`)
+	v = strings.ReplaceAll(v, `"`, `\"`)
+	return `"` + v + `"`
+}
@@ -0,0 +1,104 @@
+package delivery
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/yourusername/paper-forge/internal/config"
+	"github.com/yourusername/paper-forge/internal/execrun"
+	"github.com/yourusername/paper-forge/internal/pdf"
+)
+
+type recordingRunner struct {
+	cmds  []execrun.Command
+	stdin []string
+	out   execrun.Output
+	err   error
+}
+
+func (r *recordingRunner) Run(_ context.Context, cmd execrun.Command) (execrun.Output, error) {
+	r.cmds = append(r.cmds, cmd)
+	if cmd.Stdin != nil {
+		data, _ := io.ReadAll(cmd.Stdin)
+		r.stdin = append(r.stdin, string(data))
+	}
+	return r.out, r.err
+}
+
+func TestNewSFTPSender(t *testing.T) {
+	if newSFTPSender(&config.Config{}) != nil {
+		t.Fatal("SFTP delivery should be disabled without SFTP_HOST")
+	}
+	sender := newSFTPSender(&config.Config{SFTPHost: "drop.example.com"})
+	if sender == nil || sender.binary != "sftp" || sender.remoteDir != "." {
+		t.Fatalf("defaults should apply: %+v", sender)
+	}
+}
+
+func TestSFTPSenderArgs(t *testing.T) {
+	tests := []struct {
+		name   string
+		sender sftpSender
+		want   []string
+	}{
+		{
+			name:   "host only",
+			sender: sftpSender{host: "drop.example.com"},
+			want:   []string{"-b", "-", "-o", "BatchMode=yes", "-o", "StrictHostKeyChecking=yes", "drop.example.com"},
+		},
+		{
+			name:   "all options",
+			sender: sftpSender{host: "drop.example.com", port: 2222, user: "paper", keyPath: "/keys/id_ed25519", knownHosts: "/keys/known_hosts"},
+			want: []string{
+				"-b", "-", "-o", "BatchMode=yes", "-o", "StrictHostKeyChecking=yes",
+				"-P", "2222", "-i", "/keys/id_ed25519", "-o", "UserKnownHostsFile=/keys/known_hosts",
+				"paper@drop.example.com",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.sender.args(); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("args = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSFTPSenderSendUploadsUnderTemporaryName(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "merged.pdf")
+	if err := os.WriteFile(path, []byte("%PDF-1.4\n"), 0o600); err != nil {
+		t.Fatalf("failed to write result: %v", err)
+	}
+	runner := &recordingRunner{}
+	sender := &sftpSender{binary: "sftp", host: "drop.example.com", remoteDir: "inbox/pdf", runner: runner}
+	req := &Request{Result: &pdf.Result{JobID: "job-1", OutputPath: path, OutputFilename: `re"port.pdf`}}
+
+	if err := sender.Send(context.Background(), req); err != nil {
+		t.Fatalf("Send returned error: %v", err)
+	}
+	if len(runner.cmds) != 1 || runner.cmds[0].Name != "sftp" {
+		t.Fatalf("unexpected commands: %+v", runner.cmds)
+	}
+	want := strings.Join([]string{
+		`-mkdir "inbox/pdf"`,
+		`put "` + path + `" "inbox/pdf/job-1-re\"port.pdf.part"`,
+		`rename "inbox/pdf/job-1-re\"port.pdf.part" "inbox/pdf/job-1-re\"port.pdf"`,
+		"bye",
+	}, "\n") + "\n"
+	if runner.stdin[0] != want {
+		t.Fatalf("unexpected batch:\n%s\nwant:\n%s", runner.stdin[0], want)
+	}
+
+	runner.err = errors.New("exit status 1")
+	runner.out = execrun.Output{Stderr: []byte("Permission denied")}
+	if err := sender.Send(context.Background(), req); err == nil || !strings.Contains(err.Error(), "Permission denied") {
+		t.Fatalf("expected upload error with the command output, got %v", err)
+	}
+}
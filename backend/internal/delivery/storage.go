@@ -0,0 +1,228 @@
+package delivery
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/yourusername/paper-forge/internal/config"
+	"github.com/yourusername/paper-forge/internal/tenant"
+)
+
+const (
+	gcsUploadEndpoint = "https://storage.googleapis.com/upload/storage/v1/b/%s/o"
+	gcsMetadataToken  = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+)
+
+// ErrObjectExists は書き込み先に同名のオブジェクトがすでにあることを表します。
+var ErrObjectExists = errors.New("storage object already exists")
+
+// errPreconditionFailed はストレージが書き込みの前提条件（ifGenerationMatch など）を満たさないと応答したことを表します。
+var errPreconditionFailed = errors.New("storage precondition failed")
+
+// storageSender は成果物をクラウドストレージへ直接書き込みます。
+// 設定済みバケットへのオブジェクトパス指定と、クライアント発行の署名付きPUT URLの両方に対応します。
+type storageSender struct {
+	bucket string
+	// prefix はデータ所在地の設定によるオブジェクト名のプレフィックスです。
+	prefix string
+	// region が設定されている場合、保存先リージョンを確認できない署名付きURLへの書き込みを拒否します。
+	region string
+	// deliveryPrefix は outputPath の書き込み先を限定するプレフィックスです（STORAGE_DELIVERY_PREFIX）。
+	deliveryPrefix string
+	allowedHosts   []string
+	client         *http.Client
+	tokenURL       string
+	uploadEndpoint string
+}
+
+func newStorageSender(cfg *config.Config) *storageSender {
+	if cfg == nil {
+		return nil
+	}
+	var hosts []string
+	for _, h := range strings.Split(cfg.OutputURLAllowedHosts, ",") {
+		if h = strings.ToLower(strings.TrimSpace(h)); h != "" {
+			hosts = append(hosts, h)
+		}
+	}
+	bucket, prefix, err := cfg.StorageLocation()
+	if err != nil {
+		// 設定の検証で弾かれるはずだが、保存先を誤らないよう配送自体を無効にする
+		return nil
+	}
+	if bucket == "" && len(hosts) == 0 {
+		return nil
+	}
+	return &storageSender{
+		bucket:         bucket,
+		prefix:         prefix,
+		region:         strings.TrimSpace(cfg.DataRegion),
+		deliveryPrefix: strings.Trim(strings.TrimSpace(cfg.StorageDeliveryPrefix), "/"),
+		allowedHosts:   hosts,
+		client:         &http.Client{Timeout: 10 * time.Minute},
+		tokenURL:       gcsMetadataToken,
+		uploadEndpoint: gcsUploadEndpoint,
+	}
+}
+
+func (s *storageSender) Send(ctx context.Context, req *Request) error {
+	switch {
+	case req.Target.UploadURL != "":
+		return s.putSignedURL(ctx, req)
+	case req.Target.ObjectPath != "":
+		return s.uploadObject(ctx, req)
+	default:
+		return fmt.Errorf("storage delivery requires objectPath or uploadUrl")
+	}
+}
+
+// putSignedURL はクライアントが発行した署名付きURLへ PUT します。
+// 任意のURLへのリクエストを許すとSSRFの踏み台になるため、許可ホストに限定します。
+func (s *storageSender) putSignedURL(ctx context.Context, req *Request) error {
+	if s.region != "" {
+		return fmt.Errorf("%w: upload urls are not accepted when DATA_REGION is set; use objectPath", ErrNotConfigured)
+	}
+	u, err := url.Parse(req.Target.UploadURL)
+	if err != nil || u.Scheme != "https" {
+		return fmt.Errorf("upload url must be https")
+	}
+	if !s.hostAllowed(u.Hostname()) {
+		return fmt.Errorf("%w: upload host %s is not allowed", ErrNotConfigured, u.Hostname())
+	}
+
+	file, err := req.Result.Open()
+	if err != nil {
+		return fmt.Errorf("failed to open result: %w", err)
+	}
+	defer file.Close()
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPut, u.String(), file)
+	if err != nil {
+		return err
+	}
+	httpReq.ContentLength = req.Result.OutputSize
+	httpReq.Header.Set("Content-Type", contentTypeFor(req.Result.ResultKind))
+	return s.do(httpReq)
+}
+
+// uploadObject は設定済みバケットへ GCS JSON API でアップロードします。
+// 認証には Cloud Run 等のメタデータサーバーから取得するアクセストークンを使います。
+func (s *storageSender) uploadObject(ctx context.Context, req *Request) error {
+	if s.bucket == "" {
+		return fmt.Errorf("%w: GCS_BUCKET is not set", ErrNotConfigured)
+	}
+	file, err := req.Result.Open()
+	if err != nil {
+		return fmt.Errorf("failed to open result: %w", err)
+	}
+	defer file.Close()
+
+	// 利用者が指定できるのは STORAGE_DELIVERY_PREFIX 配下のテナントごとの階層だけにする
+	name := tenantObjectName(path.Join(s.prefix, s.deliveryPrefix), req.Tenant, req.Target.ObjectPath)
+	return s.putObject(ctx, name, contentTypeFor(req.Result.ResultKind), file, req.Result.OutputSize)
+}
+
+// putObject は body を設定済みバケットのオブジェクト name として書き込みます。
+// 他のジョブや利用者のオブジェクトを消さないよう、同名のオブジェクトがある場合は上書きせずに ErrObjectExists を返します。
+func (s *storageSender) putObject(ctx context.Context, name, contentType string, body io.Reader, size int64) error {
+	token, err := s.accessToken(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := url.Values{}
+	query.Set("uploadType", "media")
+	query.Set("name", name)
+	query.Set("ifGenerationMatch", "0")
+	endpoint := fmt.Sprintf(s.uploadEndpoint, url.PathEscape(s.bucket)) + "?" + query.Encode()
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, body)
+	if err != nil {
+		return err
+	}
+	httpReq.ContentLength = size
+	httpReq.Header.Set("Content-Type", contentType)
+	httpReq.Header.Set("Authorization", "Bearer "+token)
+	err = s.do(httpReq)
+	if errors.Is(err, errPreconditionFailed) {
+		return fmt.Errorf("%w: %s", ErrObjectExists, name)
+	}
+	return err
+}
+
+// objectName はデータ所在地のプレフィックスを付けたオブジェクト名を返します。
+func objectName(prefix, objectPath string) string {
+	objectPath = strings.TrimLeft(objectPath, "/")
+	if prefix == "" {
+		return objectPath
+	}
+	return prefix + "/" + objectPath
+}
+
+// tenantObjectName は objectName にテナントの階層を付けたオブジェクト名を返します。
+// テナント間で書き込み先が重ならないよう、tenant.Default（テナント未指定）のジョブにも必ずテナントの階層を付けます。
+func tenantObjectName(prefix, tenantID, objectPath string) string {
+	return objectName(prefix, "tenants/"+tenant.Normalize(tenantID)+"/"+strings.TrimLeft(objectPath, "/"))
+}
+
+func (s *storageSender) accessToken(ctx context.Context) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.tokenURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch access token: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("metadata server responded with %d", resp.StatusCode)
+	}
+
+	var payload struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return "", fmt.Errorf("failed to parse access token: %w", err)
+	}
+	if payload.AccessToken == "" {
+		return "", fmt.Errorf("metadata server returned an empty access token")
+	}
+	return payload.AccessToken, nil
+}
+
+func (s *storageSender) do(req *http.Request) error {
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("storage upload failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusPreconditionFailed {
+		return errPreconditionFailed
+	}
+	if resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 2048))
+		return fmt.Errorf("storage responded with %d: %s", resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
+
+func (s *storageSender) hostAllowed(host string) bool {
+	host = strings.ToLower(host)
+	for _, allowed := range s.allowedHosts {
+		if host == allowed || strings.HasSuffix(host, "."+allowed) {
+			return true
+		}
+	}
+	return false
+}
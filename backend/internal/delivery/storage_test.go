@@ -0,0 +1,133 @@
+package delivery
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/yourusername/paper-forge/internal/config"
+	"github.com/yourusername/paper-forge/internal/pdf"
+)
+
+func TestNewStorageSenderUsesRegionLocation(t *testing.T) {
+	sender := newStorageSender(&config.Config{GCSBucket: "pdf-tools", DataRegion: "eu", GCSRegionBuckets: "eu=pdf-eu/residency"})
+	if sender == nil || sender.bucket != "pdf-eu" || sender.prefix != "residency" || sender.region != "eu" {
+		t.Fatalf("unexpected sender: %+v", sender)
+	}
+
+	// 保存先を誤らないよう、リージョンの保存先が無い場合は配送自体を無効にする
+	if sender := newStorageSender(&config.Config{GCSBucket: "pdf-tools", DataRegion: "jp", GCSRegionBuckets: "eu=pdf-eu"}); sender != nil {
+		t.Fatalf("storage delivery should be disabled for an unmapped region: %+v", sender)
+	}
+}
+
+func TestStorageSenderRefusesUploadURLsWithDataRegion(t *testing.T) {
+	sender := newStorageSender(&config.Config{DataRegion: "eu", GCSRegionBuckets: "eu=pdf-eu", OutputURLAllowedHosts: "storage.googleapis.com"})
+	err := sender.Send(context.Background(), &Request{
+		Result: &pdf.Result{OutputFilename: "merged.pdf"},
+		Target: pdf.DeliveryTarget{Method: pdf.DeliveryMethodStorage, UploadURL: "https://storage.googleapis.com/other-bucket/out.pdf?X-Goog-Signature=x"},
+	})
+	if !errors.Is(err, ErrNotConfigured) {
+		t.Fatalf("signed URLs may point outside the region and should be refused, got %v", err)
+	}
+}
+
+func TestObjectNameAddsRegionPrefix(t *testing.T) {
+	tests := []struct {
+		prefix, objectPath string
+		want               string
+	}{
+		{objectPath: "exports/a.pdf", want: "exports/a.pdf"},
+		{prefix: "residency", objectPath: "/exports/a.pdf", want: "residency/exports/a.pdf"},
+	}
+	for _, tt := range tests {
+		if got := objectName(tt.prefix, tt.objectPath); got != tt.want {
+			t.Errorf("objectName(%q, %q) = %q, want %q", tt.prefix, tt.objectPath, got, tt.want)
+		}
+	}
+}
+
+func TestTenantObjectNameAlwaysAddsTenant(t *testing.T) {
+	tests := []struct {
+		prefix, tenantID, objectPath string
+		want                         string
+	}{
+		// テナント未指定のジョブも tenants/default 配下に置き、他のテナントの階層へ書き込めないようにする
+		{objectPath: "exports/a.pdf", want: "tenants/default/exports/a.pdf"},
+		{prefix: "deliveries", tenantID: "default", objectPath: "tenants/sales/a.pdf", want: "deliveries/tenants/default/tenants/sales/a.pdf"},
+		{prefix: "residency/deliveries", tenantID: "sales", objectPath: "/exports/a.pdf", want: "residency/deliveries/tenants/sales/exports/a.pdf"},
+	}
+	for _, tt := range tests {
+		if got := tenantObjectName(tt.prefix, tt.tenantID, tt.objectPath); got != tt.want {
+			t.Errorf("tenantObjectName(%q, %q, %q) = %q, want %q", tt.prefix, tt.tenantID, tt.objectPath, got, tt.want)
+		}
+	}
+}
+
+func newTestStorageServer(t *testing.T, status int) (*storageSender, *[]*http.Request) {
+	t.Helper()
+	var requests []*http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			_, _ = io.WriteString(w, `{"access_token":"token-1"}`)
+			return
+		}
+		_, _ = io.Copy(io.Discard, r.Body)
+		requests = append(requests, r)
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+
+	sender := newStorageSender(&config.Config{GCSBucket: "pdf-tools", StorageDeliveryPrefix: "/deliveries/"})
+	sender.client = server.Client()
+	sender.tokenURL = server.URL + "/token"
+	sender.uploadEndpoint = server.URL + "/b/%s/o"
+	return sender, &requests
+}
+
+func storageRequest(t *testing.T, tenantID, objectPath string) *Request {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "merged.pdf")
+	if err := os.WriteFile(path, []byte("%PDF-1.4\n"), 0o600); err != nil {
+		t.Fatalf("failed to write result: %v", err)
+	}
+	return &Request{
+		Tenant: tenantID,
+		Result: &pdf.Result{OutputPath: path, OutputFilename: "merged.pdf", OutputSize: 9, ResultKind: pdf.ResultKindPDF},
+		Target: pdf.DeliveryTarget{Method: pdf.DeliveryMethodStorage, ObjectPath: objectPath},
+	}
+}
+
+func TestStorageSenderUploadObjectStaysUnderDeliveryPrefix(t *testing.T) {
+	sender, requests := newTestStorageServer(t, http.StatusOK)
+	if err := sender.Send(context.Background(), storageRequest(t, "sales", "exports/report.pdf")); err != nil {
+		t.Fatalf("Send returned error: %v", err)
+	}
+	if len(*requests) != 1 {
+		t.Fatalf("expected one upload, got %d", len(*requests))
+	}
+	req := (*requests)[0]
+	query := req.URL.Query()
+	if req.URL.Path != "/b/pdf-tools/o" || query.Get("name") != "deliveries/tenants/sales/exports/report.pdf" {
+		t.Fatalf("unexpected upload target: %s", req.URL)
+	}
+	if query.Get("ifGenerationMatch") != "0" {
+		t.Fatalf("uploads should refuse to overwrite existing objects: %s", req.URL)
+	}
+	if req.Header.Get("Authorization") != "Bearer token-1" || req.Header.Get("Content-Type") != "application/pdf" {
+		t.Fatalf("unexpected headers: %v", req.Header)
+	}
+}
+
+func TestStorageSenderUploadObjectRefusesOverwrite(t *testing.T) {
+	sender, _ := newTestStorageServer(t, http.StatusPreconditionFailed)
+	err := sender.Send(context.Background(), storageRequest(t, "sales", "exports/report.pdf"))
+	if !errors.Is(err, ErrObjectExists) {
+		t.Fatalf("expected ErrObjectExists, got %v", err)
+	}
+}
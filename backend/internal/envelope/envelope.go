@@ -0,0 +1,192 @@
+// Package envelope は SDK 向けの統一レスポンスエンベロープをコンテントネゴシエーションで提供します。
+//
+// Accept: application/vnd.paperforge+json;v=1 を送ったクライアントには、成功・失敗を問わず
+// { "data": ..., "error": ..., "requestId": "..." } 形式で JSON を返します。
+// それ以外のクライアントには従来どおりのレスポンス形式をそのまま返します。
+package envelope
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	// MediaType はエンベロープ形式を要求するためのメディアタイプです。
+	MediaType = "application/vnd.paperforge+json"
+	// RequestIDHeader はリクエストIDを受け渡すヘッダー名です。
+	RequestIDHeader = "X-Request-Id"
+	// ContextRequestIDKey はハンドラー間でリクエストIDを共有するためのキーです。
+	ContextRequestIDKey = "envelope.requestId"
+
+	currentVersion = "1"
+	maxRequestID   = 128
+)
+
+type body struct {
+	Data      json.RawMessage `json:"data"`
+	Error     json.RawMessage `json:"error"`
+	RequestID string          `json:"requestId"`
+}
+
+// Middleware はリクエストIDの付与と、要求時のエンベロープ変換を行うミドルウェアを返します。
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := sanitizeRequestID(c.GetHeader(RequestIDHeader))
+		if requestID == "" {
+			requestID = uuid.NewString()
+		}
+		c.Set(ContextRequestIDKey, requestID)
+		c.Header(RequestIDHeader, requestID)
+
+		wants, version := negotiate(c.GetHeader("Accept"))
+		if !wants {
+			c.Next()
+			return
+		}
+		if version != currentVersion {
+			c.AbortWithStatusJSON(http.StatusNotAcceptable, gin.H{
+				"code":    "UNSUPPORTED_MEDIA_VERSION",
+				"message": "サポートされていないレスポンス形式のバージョンです。",
+			})
+			return
+		}
+
+		w := &writer{ResponseWriter: c.Writer}
+		c.Writer = w
+		c.Next()
+		w.flush(requestID)
+	}
+}
+
+// negotiate は Accept ヘッダーにエンベロープ形式が含まれるかとそのバージョンを返します。
+// v パラメータ省略時は現行バージョンとして扱います。
+func negotiate(accept string) (bool, string) {
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil || mediaType != MediaType {
+			continue
+		}
+		if v, ok := params["v"]; ok {
+			return true, v
+		}
+		return true, currentVersion
+	}
+	return false, ""
+}
+
+func sanitizeRequestID(v string) string {
+	v = strings.TrimSpace(v)
+	if v == "" || len(v) > maxRequestID {
+		return ""
+	}
+	for _, r := range v {
+		if !(r == '-' || r == '_' || r == '.' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z') {
+			return ""
+		}
+	}
+	return v
+}
+
+// writer は JSON レスポンスをバッファリングし、最後にエンベロープへ包み直します。
+// PDF や ZIP などのバイナリは即座に下位の Writer へ流します。
+type writer struct {
+	gin.ResponseWriter
+	status      int
+	buf         bytes.Buffer
+	passthrough bool
+}
+
+func (w *writer) WriteHeader(code int) {
+	if w.passthrough {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	w.status = code
+}
+
+func (w *writer) WriteHeaderNow() {
+	if w.passthrough {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
+
+func (w *writer) Write(data []byte) (int, error) {
+	if !w.passthrough && !isJSON(w.Header().Get("Content-Type")) {
+		w.startPassthrough()
+	}
+	if w.passthrough {
+		return w.ResponseWriter.Write(data)
+	}
+	return w.buf.Write(data)
+}
+
+func (w *writer) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *writer) Status() int {
+	if !w.passthrough && w.status != 0 {
+		return w.status
+	}
+	return w.ResponseWriter.Status()
+}
+
+func (w *writer) Written() bool {
+	if w.passthrough {
+		return w.ResponseWriter.Written()
+	}
+	return w.buf.Len() > 0
+}
+
+func (w *writer) startPassthrough() {
+	w.passthrough = true
+	if w.status != 0 {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+}
+
+func (w *writer) flush(requestID string) {
+	if w.passthrough {
+		return
+	}
+	if w.status != 0 {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+	if w.buf.Len() == 0 {
+		return
+	}
+
+	payload := json.RawMessage(bytes.TrimSpace(w.buf.Bytes()))
+	out := body{RequestID: requestID, Data: json.RawMessage("null"), Error: json.RawMessage("null")}
+	if w.Status() >= http.StatusBadRequest {
+		out.Error = payload
+	} else {
+		out.Data = payload
+	}
+
+	encoded, err := json.Marshal(out)
+	if err != nil {
+		// 変換できない場合は元のレスポンスをそのまま返す
+		_, _ = w.ResponseWriter.Write(w.buf.Bytes())
+		return
+	}
+	header := w.Header()
+	header.Set("Content-Type", MediaType+"; v="+currentVersion+"; charset=utf-8")
+	header.Set("Content-Length", strconv.Itoa(len(encoded)))
+	_, _ = w.ResponseWriter.Write(encoded)
+}
+
+func isJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
@@ -0,0 +1,103 @@
+package envelope
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Middleware())
+	router.GET("/ok", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"jobId": "job-1"})
+	})
+	router.GET("/fail", func(c *gin.Context) {
+		c.JSON(http.StatusNotFound, gin.H{"code": "JOB_NOT_FOUND", "message": "missing"})
+	})
+	router.GET("/binary", func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/pdf", []byte("%PDF-1.4"))
+	})
+	return router
+}
+
+func TestMiddlewareKeepsDefaultShape(t *testing.T) {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/ok", nil)
+	newRouter().ServeHTTP(rec, req)
+
+	var payload map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if payload["jobId"] != "job-1" {
+		t.Fatalf("unexpected payload: %s", rec.Body.String())
+	}
+	if rec.Header().Get(RequestIDHeader) == "" {
+		t.Fatal("expected X-Request-Id header")
+	}
+}
+
+func TestMiddlewareWrapsSuccessAndError(t *testing.T) {
+	router := newRouter()
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/ok", nil)
+	req.Header.Set("Accept", MediaType+";v=1")
+	req.Header.Set(RequestIDHeader, "req-123")
+	router.ServeHTTP(rec, req)
+
+	var success struct {
+		Data      map[string]string `json:"data"`
+		Error     any               `json:"error"`
+		RequestID string            `json:"requestId"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &success); err != nil {
+		t.Fatalf("failed to parse response: %v body=%s", err, rec.Body.String())
+	}
+	if rec.Code != http.StatusOK || success.Data["jobId"] != "job-1" || success.Error != nil || success.RequestID != "req-123" {
+		t.Fatalf("unexpected envelope: status=%d body=%s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/fail", nil)
+	req.Header.Set("Accept", MediaType)
+	router.ServeHTTP(rec, req)
+
+	var failure struct {
+		Data  any               `json:"data"`
+		Error map[string]string `json:"error"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &failure); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if rec.Code != http.StatusNotFound || failure.Data != nil || failure.Error["code"] != "JOB_NOT_FOUND" {
+		t.Fatalf("unexpected envelope: status=%d body=%s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestMiddlewarePassesBinaryThrough(t *testing.T) {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/binary", nil)
+	req.Header.Set("Accept", MediaType+";v=1")
+	newRouter().ServeHTTP(rec, req)
+
+	if rec.Body.String() != "%PDF-1.4" || rec.Header().Get("Content-Type") != "application/pdf" {
+		t.Fatalf("binary response should not be wrapped: %q", rec.Body.String())
+	}
+}
+
+func TestMiddlewareRejectsUnknownVersion(t *testing.T) {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/ok", nil)
+	req.Header.Set("Accept", MediaType+";v=9")
+	newRouter().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotAcceptable {
+		t.Fatalf("unexpected status: %d", rec.Code)
+	}
+}
@@ -0,0 +1,100 @@
+// Package errreport はワーカーのパニックなど、想定外のエラーを報告します。
+//
+// 報告は Cloud Error Reporting が取り込める形式（ReportedErrorEvent）の JSON を1行ずつ標準エラー出力へ書き出します。
+// Cloud Run では Cloud Logging を経由して Error Reporting にまとめられ、ローカルではそのままログとして読めます。
+package errreport
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yourusername/paper-forge/internal/buildinfo"
+	"github.com/yourusername/paper-forge/internal/config"
+)
+
+// reportedErrorEventType は Error Reporting がログから報告を見分けるための型名です。
+const reportedErrorEventType = "type.googleapis.com/google.devtools.clouderrorreporting.v1beta1.ReportedErrorEvent"
+
+// Event は報告する想定外のエラーです。
+type Event struct {
+	Message string
+	// Stack は runtime/debug.Stack の出力です。Error Reporting は Message に続くスタックからエラーをまとめます。
+	Stack []byte
+	// Labels はジョブIDなど、調査用に添える情報です。
+	Labels map[string]string
+}
+
+// Reporter は想定外のエラーを書き出します。
+type Reporter struct {
+	service string
+	version string
+	out     io.Writer
+	mu      sync.Mutex
+	now     func() time.Time
+}
+
+// New は Reporter を作成します。out が nil の場合は標準エラー出力に書き出します。
+func New(cfg *config.Config, out io.Writer) *Reporter {
+	if out == nil {
+		out = os.Stderr
+	}
+	service := "paper-forge"
+	if cfg != nil && strings.TrimSpace(cfg.ErrorReportService) != "" {
+		service = strings.TrimSpace(cfg.ErrorReportService)
+	}
+	return &Reporter{
+		service: service,
+		version: buildinfo.Get().Version,
+		out:     out,
+		now:     time.Now,
+	}
+}
+
+type serviceContext struct {
+	Service string `json:"service"`
+	Version string `json:"version,omitempty"`
+}
+
+type entry struct {
+	Type           string            `json:"@type"`
+	Severity       string            `json:"severity"`
+	EventTime      string            `json:"eventTime"`
+	Message        string            `json:"message"`
+	ServiceContext serviceContext    `json:"serviceContext"`
+	Labels         map[string]string `json:"logging.googleapis.com/labels,omitempty"`
+}
+
+// Report はエラーを1行の JSON として書き出します。nil の Reporter では何もしません。
+func (r *Reporter) Report(_ context.Context, ev Event) error {
+	if r == nil {
+		return nil
+	}
+	message := ev.Message
+	if len(ev.Stack) > 0 {
+		message += "\n\n" + string(ev.Stack)
+	}
+	line, err := json.Marshal(entry{
+		Type:      reportedErrorEventType,
+		Severity:  "ERROR",
+		EventTime: r.now().UTC().Format(time.RFC3339Nano),
+		Message:   message,
+		ServiceContext: serviceContext{
+			Service: r.service,
+			Version: r.version,
+		},
+		Labels: ev.Labels,
+	})
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, err = r.out.Write(append(line, '\n'))
+	return err
+}
@@ -0,0 +1,54 @@
+package errreport
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yourusername/paper-forge/internal/config"
+)
+
+func TestReportWritesReportedErrorEvent(t *testing.T) {
+	var buf bytes.Buffer
+	r := New(&config.Config{ErrorReportService: "paper-forge-worker"}, &buf)
+	r.now = func() time.Time { return time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC) }
+
+	err := r.Report(context.Background(), Event{
+		Message: "panic: boom",
+		Stack:   []byte("goroutine 1 [running]:\nmain.main()"),
+		Labels:  map[string]string{"jobId": "job-1"},
+	})
+	if err != nil {
+		t.Fatalf("Report returned error: %v", err)
+	}
+	if !strings.HasSuffix(buf.String(), "\n") || strings.Count(buf.String(), "\n") != 1 {
+		t.Fatalf("report should be a single JSON line: %q", buf.String())
+	}
+
+	var got map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("report is not JSON: %v", err)
+	}
+	if got["@type"] != reportedErrorEventType || got["severity"] != "ERROR" || got["eventTime"] != "2026-01-01T09:00:00Z" {
+		t.Fatalf("unexpected envelope: %v", got)
+	}
+	if got["message"] != "panic: boom\n\ngoroutine 1 [running]:\nmain.main()" {
+		t.Fatalf("message should carry the stack: %q", got["message"])
+	}
+	if svc := got["serviceContext"].(map[string]any); svc["service"] != "paper-forge-worker" {
+		t.Fatalf("unexpected service context: %v", svc)
+	}
+	if labels := got["logging.googleapis.com/labels"].(map[string]any); labels["jobId"] != "job-1" {
+		t.Fatalf("unexpected labels: %v", labels)
+	}
+}
+
+func TestNilReporterIsNoop(t *testing.T) {
+	var r *Reporter
+	if err := r.Report(context.Background(), Event{Message: "panic"}); err != nil {
+		t.Fatalf("nil reporter should ignore events, got %v", err)
+	}
+}
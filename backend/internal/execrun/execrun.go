@@ -0,0 +1,242 @@
+// Package execrun は Ghostscript や sftp などの外部コマンド実行を共通化します。
+//
+// 実行はすべて Runner インターフェース経由で行うため、テストではバイナリが無くても
+// 差し替えた Runner で振る舞いを確認できます。既定の ExecRunner はタイムアウト、
+// 環境変数の絞り込み、標準出力・標準エラーの取得サイズ上限をまとめて適用します。
+package execrun
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultMaxOutput は標準出力・標準エラーそれぞれでメモリに保持する最大バイト数の既定値です。
+const DefaultMaxOutput = 64 * 1024
+
+// waitDelay はコマンド停止後に出力の読み取り完了を待つ最大時間です。
+const waitDelay = 2 * time.Second
+
+// baseEnv は外部コマンドへ常に引き継ぐ環境変数です。
+var baseEnv = []string{"PATH", "HOME", "TMPDIR", "LANG", "LC_ALL"}
+
+// Command は実行する外部コマンドです。
+type Command struct {
+	Name  string
+	Args  []string
+	Stdin io.Reader
+	// Env は引き継ぎ対象に加えて設定する環境変数です（"KEY=value" 形式）。
+	Env []string
+	// Log を指定すると、標準出力・標準エラーの全量をここへ書き出します（メモリ上には末尾のみ保持）。
+	Log io.Writer
+}
+
+// Output は外部コマンドの出力です。上限を超えた場合は末尾のみを保持し、Truncated が true になります。
+type Output struct {
+	Stdout    []byte
+	Stderr    []byte
+	Truncated bool
+	// CPU はコマンドが使った CPU 時間（ユーザー + システム）です。取得できない場合は0です。
+	CPU time.Duration
+}
+
+// Combined は標準出力と標準エラーを連結した文字列を返します。
+func (o Output) Combined() string {
+	parts := make([]string, 0, 2)
+	if s := strings.TrimSpace(string(o.Stdout)); s != "" {
+		parts = append(parts, s)
+	}
+	if s := strings.TrimSpace(string(o.Stderr)); s != "" {
+		parts = append(parts, s)
+	}
+	return strings.Join(parts, "\n")
+}
+
+// Runner は外部コマンドを実行します。
+type Runner interface {
+	Run(ctx context.Context, cmd Command) (Output, error)
+}
+
+// ExecRunner は os/exec で外部コマンドを実行する Runner です。
+type ExecRunner struct {
+	// Timeout は1回の実行に許す最大時間です。0 以下の場合は ctx の期限のみに従います。
+	Timeout time.Duration
+	// MaxOutput は標準出力・標準エラーそれぞれでメモリに保持する末尾の最大バイト数です。0 以下の場合は DefaultMaxOutput を使います。
+	MaxOutput int
+	// AllowEnv は baseEnv に加えて親プロセスから引き継ぐ環境変数名です。
+	AllowEnv []string
+}
+
+// ErrTimeout は Runner のタイムアウトによりコマンドが停止されたことを表します。
+var ErrTimeout = errors.New("external command timed out")
+
+// Run はコマンドを実行し、出力を返します。呼び出し元の ctx がキャンセルされた場合は ctx.Err() を返します。
+func (r *ExecRunner) Run(ctx context.Context, c Command) (Output, error) {
+	runCtx := ctx
+	if r.Timeout > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(ctx, r.Timeout)
+		defer cancel()
+	}
+
+	limit := r.MaxOutput
+	if limit <= 0 {
+		limit = DefaultMaxOutput
+	}
+	stdout := &tailBuffer{limit: limit}
+	stderr := &tailBuffer{limit: limit}
+
+	cmd := exec.CommandContext(runCtx, c.Name, c.Args...)
+	cmd.Stdin = c.Stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if c.Log != nil {
+		// 標準出力と標準エラーは別の goroutine から書き込まれるため、ログへの書き込みを直列化する
+		log := &syncWriter{w: c.Log}
+		cmd.Stdout = io.MultiWriter(stdout, log)
+		cmd.Stderr = io.MultiWriter(stderr, log)
+	}
+	cmd.Env = append(r.environ(), c.Env...)
+	// 子プロセスが出力パイプを握ったまま残っても、停止後は待ち続けない
+	cmd.WaitDelay = waitDelay
+
+	err := cmd.Run()
+	out := Output{
+		Stdout:    stdout.Bytes(),
+		Stderr:    stderr.Bytes(),
+		Truncated: stdout.Truncated() || stderr.Truncated(),
+	}
+	if cmd.ProcessState != nil {
+		out.CPU = cmd.ProcessState.UserTime() + cmd.ProcessState.SystemTime()
+		AddCPU(ctx, out.CPU)
+	}
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return out, ctxErr
+		}
+		if errors.Is(runCtx.Err(), context.DeadlineExceeded) {
+			return out, fmt.Errorf("%w after %s: %s", ErrTimeout, r.Timeout, c.Name)
+		}
+		return out, err
+	}
+	return out, nil
+}
+
+// CPUAccount は ctx を共有する外部コマンドの CPU 時間の合計です。ジョブ単位の計測に使います。
+// 同時に実行中の他のジョブの分は含みません。
+type CPUAccount struct {
+	nanos atomic.Int64
+}
+
+// Total はこれまでに加算した CPU 時間の合計を返します。
+func (a *CPUAccount) Total() time.Duration {
+	if a == nil {
+		return 0
+	}
+	return time.Duration(a.nanos.Load())
+}
+
+type cpuAccountKey struct{}
+
+// WithCPUAccount は外部コマンドの CPU 時間を集計する ctx を返します。
+// Runner の実装は AddCPU で、この ctx で実行したコマンドの CPU 時間を加算します。
+func WithCPUAccount(ctx context.Context) (context.Context, *CPUAccount) {
+	account := &CPUAccount{}
+	return context.WithValue(ctx, cpuAccountKey{}, account), account
+}
+
+// AddCPU は ctx の CPUAccount に d を加算します。ctx に CPUAccount が無い場合は何もしません。
+func AddCPU(ctx context.Context, d time.Duration) {
+	if account, ok := ctx.Value(cpuAccountKey{}).(*CPUAccount); ok && d > 0 {
+		account.nanos.Add(int64(d))
+	}
+}
+
+func (r *ExecRunner) environ() []string {
+	names := append(append([]string(nil), baseEnv...), r.AllowEnv...)
+	env := make([]string, 0, len(names))
+	for _, name := range names {
+		if v, ok := os.LookupEnv(name); ok {
+			env = append(env, name+"="+v)
+		}
+	}
+	return env
+}
+
+// tailBuffer は書き込まれたデータの末尾 limit バイトのみを保持する io.Writer です。
+// 書き込みを失敗させるとコマンド側が異常終了するため、常に全量を書き込んだものとして扱います。
+type tailBuffer struct {
+	buf   []byte
+	limit int
+	total int64
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.total += int64(len(p))
+	if len(p) >= b.limit {
+		b.buf = append(b.buf[:0], p[len(p)-b.limit:]...)
+		return len(p), nil
+	}
+	b.buf = append(b.buf, p...)
+	// 毎回詰め直すとコピーが多くなるため、上限の2倍を超えた時点でまとめて切り詰める
+	if len(b.buf) > 2*b.limit {
+		b.buf = append(b.buf[:0], b.buf[len(b.buf)-b.limit:]...)
+	}
+	return len(p), nil
+}
+
+func (b *tailBuffer) Bytes() []byte {
+	if len(b.buf) > b.limit {
+		return b.buf[len(b.buf)-b.limit:]
+	}
+	return b.buf
+}
+
+func (b *tailBuffer) Truncated() bool {
+	return b.total > int64(b.limit)
+}
+
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (s *syncWriter) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.w.Write(p)
+}
+
+// CappedWriter は最大 limit バイトまで w へ書き込み、超過分は読み捨てる io.Writer を返します。
+// ログファイルの肥大化でディスクを使い切らないようにするために使います。
+func CappedWriter(w io.Writer, limit int64) io.Writer {
+	return &cappedWriter{w: w, remaining: limit}
+}
+
+type cappedWriter struct {
+	w         io.Writer
+	remaining int64
+}
+
+func (c *cappedWriter) Write(p []byte) (int, error) {
+	if c.remaining > 0 {
+		chunk := p
+		if int64(len(chunk)) > c.remaining {
+			chunk = chunk[:c.remaining]
+		}
+		n, err := c.w.Write(chunk)
+		c.remaining -= int64(n)
+		if err != nil {
+			// ログの書き込み失敗でコマンドを止めないよう、以降は読み捨てる
+			c.remaining = 0
+		}
+	}
+	return len(p), nil
+}
@@ -0,0 +1,135 @@
+package execrun
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTailBufferKeepsLastBytes(t *testing.T) {
+	b := &tailBuffer{limit: 4}
+	for _, chunk := range []string{"ab", "cd", "ef", "g", "hijklmnop", "q"} {
+		n, err := b.Write([]byte(chunk))
+		if err != nil || n != len(chunk) {
+			t.Fatalf("Write(%q) returned (%d, %v)", chunk, n, err)
+		}
+	}
+	if got := string(b.Bytes()); got != "nopq" {
+		t.Fatalf("buffer = %q, want %q", got, "nopq")
+	}
+	if !b.Truncated() {
+		t.Fatal("expected truncated flag")
+	}
+}
+
+func TestCappedWriter(t *testing.T) {
+	var dst bytes.Buffer
+	w := CappedWriter(&dst, 5)
+	for _, chunk := range []string{"abc", "def", "ghi"} {
+		if n, err := w.Write([]byte(chunk)); err != nil || n != len(chunk) {
+			t.Fatalf("Write(%q) returned (%d, %v)", chunk, n, err)
+		}
+	}
+	if got := dst.String(); got != "abcde" {
+		t.Fatalf("written = %q, want %q", got, "abcde")
+	}
+}
+
+func requireShell(t *testing.T) {
+	t.Helper()
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not available")
+	}
+}
+
+func TestExecRunnerScrubsEnvironment(t *testing.T) {
+	requireShell(t)
+	t.Setenv("PAPERFORGE_SECRET", "should-not-leak")
+	t.Setenv("PAPERFORGE_ALLOWED", "ok")
+
+	r := &ExecRunner{AllowEnv: []string{"PAPERFORGE_ALLOWED"}}
+	out, err := r.Run(context.Background(), Command{
+		Name: "sh",
+		Args: []string{"-c", `echo "secret=$PAPERFORGE_SECRET allowed=$PAPERFORGE_ALLOWED extra=$EXTRA"`},
+		Env:  []string{"EXTRA=1"},
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if got := strings.TrimSpace(string(out.Stdout)); got != "secret= allowed=ok extra=1" {
+		t.Fatalf("unexpected output: %q", got)
+	}
+}
+
+func TestExecRunnerCapsOutput(t *testing.T) {
+	requireShell(t)
+	var log bytes.Buffer
+	input := append(bytes.Repeat([]byte("x"), 1024), []byte("tail")...)
+	r := &ExecRunner{MaxOutput: 8}
+	out, err := r.Run(context.Background(), Command{
+		Name:  "sh",
+		Args:  []string{"-c", "cat; echo oops >&2"},
+		Stdin: bytes.NewReader(input),
+		Log:   &log,
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if got := string(out.Stdout); got != "xxxxtail" || !out.Truncated {
+		t.Fatalf("stdout = %q truncated = %v, want last 8 bytes and true", got, out.Truncated)
+	}
+	if log.Len() != len(input)+len("oops\n") {
+		t.Fatalf("log has %d bytes, want full output", log.Len())
+	}
+	if got := strings.TrimSpace(string(out.Stderr)); got != "oops" {
+		t.Fatalf("stderr = %q", got)
+	}
+}
+
+func TestExecRunnerTimeout(t *testing.T) {
+	requireShell(t)
+	r := &ExecRunner{Timeout: 50 * time.Millisecond}
+	_, err := r.Run(context.Background(), Command{Name: "sh", Args: []string{"-c", "exec sleep 5"}})
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("expected ErrTimeout, got %v", err)
+	}
+}
+
+func TestExecRunnerReturnsContextError(t *testing.T) {
+	requireShell(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := (&ExecRunner{}).Run(ctx, Command{Name: "sh", Args: []string{"-c", "true"}})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestExecRunnerAddsCPUToAccount(t *testing.T) {
+	requireShell(t)
+	ctx, account := WithCPUAccount(context.Background())
+
+	r := &ExecRunner{}
+	out, err := r.Run(ctx, Command{Name: "sh", Args: []string{"-c", "i=0; while [ $i -lt 20000 ]; do i=$((i+1)); done"}})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if out.CPU <= 0 {
+		t.Skip("CPU time is not reported on this platform")
+	}
+	if account.Total() != out.CPU {
+		t.Fatalf("account = %v, want %v", account.Total(), out.CPU)
+	}
+
+	// ctx に CPUAccount が無いコマンドは集計しない
+	if _, err := r.Run(context.Background(), Command{Name: "sh", Args: []string{"-c", "true"}}); err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if account.Total() != out.CPU {
+		t.Fatalf("commands outside the account should not be added: %v", account.Total())
+	}
+}
@@ -0,0 +1,112 @@
+// Package faults はジョブ処理の復旧経路（ワークスペースの削除・MarkFailed・再試行）を検証するための障害注入を提供します。
+// テストでは Injector.Arm で、デバッグ環境では FAULT_INJECTION（release モードでは使用不可）で有効にします。
+package faults
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Point は障害を注入する箇所です。
+type Point string
+
+const (
+	// StorageWrite はワークスペース（マニフェスト・成果物一覧など）への書き込みを失敗させます。
+	StorageWrite Point = "storage.write"
+	// Subprocess は外部コマンド（Ghostscript など）を起動直後に強制終了します。
+	Subprocess Point = "subprocess"
+	// ManifestCorrupt はマニフェストを途中までしか書き込まず、読み込み時に解析できなくします。
+	ManifestCorrupt Point = "manifest.corrupt"
+)
+
+// Always は Arm で指定すると、解除するまで毎回障害を起こします。
+const Always = -1
+
+// ErrInjected は注入した障害によるエラーです。
+var ErrInjected = errors.New("injected fault")
+
+var knownPoints = map[Point]bool{StorageWrite: true, Subprocess: true, ManifestCorrupt: true}
+
+// Injector は障害点ごとに、残りの障害回数を保持します。nil の場合は何もしません。
+type Injector struct {
+	mu    sync.Mutex
+	armed map[Point]int
+	fired map[Point]int
+}
+
+// New は障害を何も設定していない Injector を作成します。
+func New() *Injector {
+	return &Injector{armed: make(map[Point]int), fired: make(map[Point]int)}
+}
+
+// Parse は FAULT_INJECTION の値（"storage.write=1,subprocess" のように 障害点[=回数] のカンマ区切り、
+// 回数を省略した場合は毎回）から Injector を作成します。空の場合は nil を返します。
+func Parse(spec string) (*Injector, error) {
+	if strings.TrimSpace(spec) == "" {
+		return nil, nil
+	}
+	inj := New()
+	for _, entry := range strings.Split(spec, ",") {
+		name, rawTimes, hasTimes := strings.Cut(strings.TrimSpace(entry), "=")
+		p := Point(strings.TrimSpace(name))
+		if !knownPoints[p] {
+			return nil, fmt.Errorf("FAULT_INJECTION: unknown fault point %q", p)
+		}
+		times := Always
+		if hasTimes {
+			n, err := strconv.Atoi(strings.TrimSpace(rawTimes))
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("FAULT_INJECTION: times for %q must be a positive integer", p)
+			}
+			times = n
+		}
+		inj.Arm(p, times)
+	}
+	return inj, nil
+}
+
+// Arm は障害点 p で次の times 回（Always の場合は解除するまで）障害を起こすよう設定します。0 で解除します。
+func (i *Injector) Arm(p Point, times int) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if times == 0 {
+		delete(i.armed, p)
+		return
+	}
+	i.armed[p] = times
+}
+
+// Fire は障害点 p で障害を起こすべきかを返し、残りの回数を1減らします。
+func (i *Injector) Fire(p Point) bool {
+	if i == nil {
+		return false
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	remaining, ok := i.armed[p]
+	if !ok {
+		return false
+	}
+	switch {
+	case remaining == Always:
+	case remaining <= 1:
+		delete(i.armed, p)
+	default:
+		i.armed[p] = remaining - 1
+	}
+	i.fired[p]++
+	return true
+}
+
+// Fired は障害点 p で実際に障害を起こした回数を返します。
+func (i *Injector) Fired(p Point) int {
+	if i == nil {
+		return 0
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.fired[p]
+}
@@ -0,0 +1,44 @@
+package faults
+
+import "testing"
+
+func TestParseAndFire(t *testing.T) {
+	inj, err := Parse("storage.write=2, subprocess")
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if !inj.Fire(StorageWrite) {
+			t.Fatalf("storage.write should fire on call %d", i+1)
+		}
+	}
+	if inj.Fire(StorageWrite) {
+		t.Fatal("storage.write should be disarmed after two faults")
+	}
+	for i := 0; i < 5; i++ {
+		if !inj.Fire(Subprocess) {
+			t.Fatal("subprocess without a count should always fire")
+		}
+	}
+	if inj.Fire(ManifestCorrupt) || inj.Fired(StorageWrite) != 2 || inj.Fired(Subprocess) != 5 {
+		t.Fatalf("unexpected fired counts: %v", inj.fired)
+	}
+
+	inj.Arm(Subprocess, 0)
+	if inj.Fire(Subprocess) {
+		t.Fatal("Arm(p, 0) should disarm the point")
+	}
+
+	if inj, err := Parse(" "); inj != nil || err != nil {
+		t.Fatalf("empty spec should disable injection: %v %v", inj, err)
+	}
+	var disabled *Injector
+	if disabled.Fire(StorageWrite) {
+		t.Fatal("nil injector should never fire")
+	}
+	for _, spec := range []string{"disk.full", "subprocess=0", "subprocess=x"} {
+		if _, err := Parse(spec); err == nil {
+			t.Fatalf("expected error for %q", spec)
+		}
+	}
+}
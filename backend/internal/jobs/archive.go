@@ -0,0 +1,116 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+const (
+	// archiveObjectDir はジョブ情報のアーカイブを書き込むオブジェクトの階層です（job-archive/<YYYY-MM-DD>/<時刻>.jsonl）。
+	archiveObjectDir = "job-archive"
+	// archivedKeyPrefix はアーカイブ済みのジョブを記録するキーの接頭辞です（job-archived:<jobID>）。
+	archivedKeyPrefix = "job-archived:"
+	// archivedMarkerTTL はアーカイブ済みの記録を残す期間です。リーガルホールド中のジョブ情報は期限なく残るため、
+	// 同じジョブを重複して書き出さないよう長めに保持します。
+	archivedMarkerTTL = 400 * 24 * time.Hour
+	// maxArchiveOlderThanDays は olderThanDays に指定できる上限です。
+	maxArchiveOlderThanDays = 3650
+)
+
+// ErrInvalidArchiveAge は olderThanDays が範囲外であることを表します。
+var ErrInvalidArchiveAge = errors.New("olderThanDays is out of range")
+
+// ArchiveReceipt はジョブ情報のアーカイブの結果です。
+type ArchiveReceipt struct {
+	ArchivedAt    time.Time `json:"archivedAt"`
+	OlderThanDays int       `json:"olderThanDays"`
+	// ObjectPath は書き込んだオブジェクトのパスです（データ所在地のプレフィックスを除く）。対象が無い場合は空です。
+	ObjectPath string   `json:"objectPath,omitempty"`
+	Exported   int      `json:"exported"`
+	JobIDs     []string `json:"jobIds"`
+}
+
+// ArchiveJobs は終了してから（最終更新から）olderThanDays 日以上経ったジョブ情報を JSON Lines にまとめ、
+// ストレージ（GCS_BUCKET）に書き込みます。入力・成果物のファイルは含めません。
+// Redis の有効期限で消える前に処理の履歴を長期保存するためのもので、一度書き出したジョブは次回以降書き出しません。
+func (m *Manager) ArchiveJobs(ctx context.Context, olderThanDays int) (*ArchiveReceipt, error) {
+	if olderThanDays < 0 || olderThanDays > maxArchiveOlderThanDays {
+		return nil, ErrInvalidArchiveAge
+	}
+	now := time.Now().UTC()
+	cutoff := now.Add(-time.Duration(olderThanDays) * 24 * time.Hour)
+	receipt := &ArchiveReceipt{ArchivedAt: now, OlderThanDays: olderThanDays, JobIDs: []string{}}
+
+	ids, err := m.store.JobIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var records []*Record
+	for _, id := range ids {
+		record, err := m.store.Get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if record == nil || !record.Status.Terminal() || record.UpdatedAt.After(cutoff) {
+			continue
+		}
+		archived, err := m.store.rdb.Exists(ctx, archivedKey(id)).Result()
+		if err != nil {
+			return nil, err
+		}
+		if archived > 0 {
+			continue
+		}
+		records = append(records, record)
+	}
+	if len(records) == 0 {
+		return receipt, nil
+	}
+
+	data, err := archiveLines(records)
+	if err != nil {
+		return nil, err
+	}
+	objectPath := fmt.Sprintf("%s/%s/%s.jsonl", archiveObjectDir, now.Format("2006-01-02"), now.Format("20060102T150405Z"))
+	if err := m.dispatcher.StoreObject(ctx, objectPath, "application/x-ndjson", data); err != nil {
+		return nil, err
+	}
+
+	// 書き込みが済んだジョブだけを記録する。記録に失敗しても、次回に重複して書き出されるだけで履歴は失われない
+	for _, record := range records {
+		if err := m.store.rdb.Set(ctx, archivedKey(record.JobID), objectPath, archivedMarkerTTL).Err(); err != nil {
+			m.logf("failed to mark job archived job=%s: %v", record.JobID, err)
+		}
+		receipt.JobIDs = append(receipt.JobIDs, record.JobID)
+	}
+	receipt.ObjectPath = objectPath
+	receipt.Exported = len(records)
+	return receipt, nil
+}
+
+// archiveLines はジョブ情報を作成日時順（同時刻はジョブID順）に1行1件の JSON にします。
+func archiveLines(records []*Record) ([]byte, error) {
+	sort.Slice(records, func(i, j int) bool {
+		if !records[i].CreatedAt.Equal(records[j].CreatedAt) {
+			return records[i].CreatedAt.Before(records[j].CreatedAt)
+		}
+		return records[i].JobID < records[j].JobID
+	})
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, record := range records {
+		if err := enc.Encode(record); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+func archivedKey(jobID string) string {
+	return archivedKeyPrefix + jobID
+}
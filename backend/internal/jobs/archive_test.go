@@ -0,0 +1,34 @@
+package jobs
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestArchiveLinesOrdersRecordsOnePerLine(t *testing.T) {
+	base := time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)
+	records := []*Record{
+		{JobID: "c", Operation: "split", Status: StatusFailed, CreatedAt: base.Add(time.Hour)},
+		{JobID: "b", Operation: "merge", Status: StatusSucceeded, CreatedAt: base, Meta: map[string]any{"totalPages": 3}},
+		{JobID: "a", Operation: "merge", Status: StatusSucceeded, CreatedAt: base},
+	}
+	data, err := archiveLines(records)
+	if err != nil {
+		t.Fatalf("archiveLines returned error: %v", err)
+	}
+	lines := bytes.Split(bytes.TrimSuffix(data, []byte("\n")), []byte("\n"))
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 lines, got %d:\n%s", len(lines), data)
+	}
+	for i, want := range []string{"a", "b", "c"} {
+		var got Record
+		if err := json.Unmarshal(lines[i], &got); err != nil {
+			t.Fatalf("line %d is not a JSON record: %v", i, err)
+		}
+		if got.JobID != want {
+			t.Fatalf("line %d: got job %q, want %q", i, got.JobID, want)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package jobs
+
+import "errors"
+
+// pdfQueue は PDF 処理ジョブを投入する asynq のキュー名です。
+const pdfQueue = "pdf"
+
+// QueueStats はワーカーのオートスケール（KEDA / Cloud Run）向けのキューの状態です。
+// 待避先の Redis を使っている場合は、主 Redis と待避先の合計です。
+type QueueStats struct {
+	Queue string `json:"queue"`
+	// Pending はワーカーの空きを待っているジョブ数、Active は処理中のジョブ数です。
+	Pending int `json:"pending"`
+	Active  int `json:"active"`
+	// Backlog は Pending と Active の合計です。ワーカー1台の同時実行数で割るとおおよその必要台数になります。
+	Backlog int `json:"backlog"`
+	// LatencySeconds は最も古い待機中ジョブが投入されてからの経過秒数です。待機中のジョブが無い場合は 0 です。
+	LatencySeconds float64 `json:"queue_latency_seconds"`
+}
+
+// QueueStats は PDF 処理キュー（テナントごとのキューを含む）の待ち状況を返します。
+// 主 Redis に接続できない場合でも、待避先の状況を取得できればそれを返します。
+func (m *Manager) QueueStats() (QueueStats, error) {
+	stats, err := m.queueStats()
+	if m.failover == nil {
+		return stats, err
+	}
+	secondary, ferr := m.failover.queueStats()
+	if ferr != nil {
+		if err != nil {
+			return QueueStats{Queue: pdfQueue}, errors.Join(err, ferr)
+		}
+		m.logf("failed to inspect failover queue: %v", ferr)
+		return stats, nil
+	}
+	if err != nil {
+		m.logf("failed to inspect primary queue: %v", err)
+		return secondary, nil
+	}
+	return mergeQueueStats(stats, secondary), nil
+}
+
+func (m *Manager) queueStats() (QueueStats, error) {
+	stats := QueueStats{Queue: pdfQueue}
+	queues, err := m.inspector.Queues()
+	if err != nil {
+		return stats, err
+	}
+	existing := make(map[string]bool, len(queues))
+	for _, q := range queues {
+		existing[q] = true
+	}
+	// テナントごとのキュー（TENANT_QUEUE_WEIGHTS）を使っている場合は、すべてのキューの合計を返す
+	for _, name := range m.queues.names() {
+		if !existing[name] {
+			// まだ一度もジョブが投入されていない（キューが作られていない）
+			continue
+		}
+		info, err := m.inspector.GetQueueInfo(name)
+		if err != nil {
+			return stats, err
+		}
+		stats = mergeQueueStats(stats, QueueStats{
+			Pending:        info.Pending,
+			Active:         info.Active,
+			Backlog:        info.Pending + info.Active,
+			LatencySeconds: info.Latency.Seconds(),
+		})
+	}
+	return stats, nil
+}
+
+// mergeQueueStats は主 Redis と待避先のキューの状態を合算します。待ち時間は長い方を採用します。
+func mergeQueueStats(a, b QueueStats) QueueStats {
+	merged := QueueStats{
+		Queue:          a.Queue,
+		Pending:        a.Pending + b.Pending,
+		Active:         a.Active + b.Active,
+		Backlog:        a.Backlog + b.Backlog,
+		LatencySeconds: a.LatencySeconds,
+	}
+	if b.LatencySeconds > merged.LatencySeconds {
+		merged.LatencySeconds = b.LatencySeconds
+	}
+	return merged
+}
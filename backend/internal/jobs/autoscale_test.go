@@ -0,0 +1,17 @@
+package jobs
+
+import "testing"
+
+func TestMergeQueueStats(t *testing.T) {
+	primary := QueueStats{Queue: pdfQueue, Pending: 3, Active: 4, Backlog: 7, LatencySeconds: 2.5}
+	secondary := QueueStats{Queue: pdfQueue, Pending: 1, Backlog: 1, LatencySeconds: 40}
+
+	got := mergeQueueStats(primary, secondary)
+	want := QueueStats{Queue: pdfQueue, Pending: 4, Active: 4, Backlog: 8, LatencySeconds: 40}
+	if got != want {
+		t.Fatalf("unexpected merged stats: %+v", got)
+	}
+	if got := mergeQueueStats(secondary, primary); got.LatencySeconds != 40 {
+		t.Fatalf("latency should be the oldest of both queues: %v", got.LatencySeconds)
+	}
+}
@@ -0,0 +1,122 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/yourusername/paper-forge/internal/config"
+	"github.com/yourusername/paper-forge/internal/pdf"
+)
+
+// budgetAlertPrefix は送信済みの予算アラートを記録するキーの接頭辞です（budget-alert:<YYYY-MM>:<tenant>:<%>）。
+const budgetAlertPrefix = "budget-alert:"
+
+// budgetAlertTimeout は予算アラートの送信を待つ最大時間です。
+const budgetAlertTimeout = 30 * time.Second
+
+// budgetPolicy は操作ごとのコストの重みと、テナントの月間予算・アラートの設定です。
+type budgetPolicy struct {
+	weights    map[string]float64
+	budgets    map[string]float64
+	thresholds []int
+	webhook    string
+	emails     []string
+}
+
+// newBudgetPolicy は設定から budgetPolicy を作成します。形式は config.Validate で検証済みです。
+func newBudgetPolicy(cfg *config.Config) *budgetPolicy {
+	weights, _ := cfg.CostWeights()
+	budgets, _ := cfg.TenantBudgets()
+	thresholds, _ := cfg.BudgetThresholds()
+	var emails []string
+	for _, addr := range strings.Split(cfg.BudgetAlertEmail, ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			emails = append(emails, addr)
+		}
+	}
+	return &budgetPolicy{
+		weights:    weights,
+		budgets:    budgets,
+		thresholds: thresholds,
+		webhook:    strings.TrimSpace(cfg.BudgetAlertWebhookURL),
+		emails:     emails,
+	}
+}
+
+// units はジョブ1件分のコスト単位（入力ページ数 × 操作の重み。未指定の操作は1）を返します。
+// 失敗したジョブも処理した分として計上します。
+func (p *budgetPolicy) units(sample pdf.UsageSample) float64 {
+	weight := 1.0
+	if p != nil {
+		if w, ok := p.weights[string(sample.Operation)]; ok {
+			weight = w
+		}
+	}
+	return float64(sample.Pages) * weight
+}
+
+// budgetFor はテナントの月間予算を返します。未設定の場合は0です。
+func (p *budgetPolicy) budgetFor(tenantID string) float64 {
+	if p == nil {
+		return 0
+	}
+	return p.budgets[tenantID]
+}
+
+// checkBudget は今回の加算で新たに超えた消化率の閾値があれば、月・テナント・閾値ごとに1回だけアラートを送ります。
+func (m *Manager) checkBudget(ctx context.Context, tenantID, month string, before, after float64) {
+	budget := m.budget.budgetFor(tenantID)
+	if budget <= 0 {
+		return
+	}
+	for _, pct := range m.budget.thresholds {
+		limit := budget * float64(pct) / 100
+		if after < limit || before >= limit {
+			continue
+		}
+		// 複数のワーカーが同時に閾値を超えても送信が1回になるよう、Redis で送信済みを記録する
+		key := fmt.Sprintf("%s%s:%s:%d", budgetAlertPrefix, month, tenantID, pct)
+		first, err := m.store.rdb.SetNX(ctx, key, time.Now().UTC().Format(time.RFC3339), usageRetention).Result()
+		if err != nil {
+			m.logf("failed to record budget alert tenant=%s pct=%d: %v", tenantID, pct, err)
+			continue
+		}
+		if !first {
+			continue
+		}
+		go m.sendBudgetAlert(context.WithoutCancel(ctx), tenantID, month, pct, after, budget)
+	}
+}
+
+// sendBudgetAlert は予算アラートを Webhook とメールで送ります。送信の失敗はログに残すのみです。
+func (m *Manager) sendBudgetAlert(ctx context.Context, tenantID, month string, pct int, used, budget float64) {
+	ctx, cancel := context.WithTimeout(ctx, budgetAlertTimeout)
+	defer cancel()
+
+	text := formatBudgetAlert(tenantID, month, pct, used, budget)
+	if m.notifier.Enabled(m.budget.webhook) {
+		if err := m.notifier.Send(ctx, m.budget.webhook, text); err != nil {
+			m.logf("failed to post budget alert tenant=%s pct=%d: %v", tenantID, pct, err)
+		}
+	}
+	subject := fmt.Sprintf("[Paper Forge] %s の月間予算の %d%% に達しました (%s)", tenantID, pct, month)
+	for _, to := range m.budget.emails {
+		if err := m.mailer.Send(ctx, to, subject, text); err != nil {
+			m.logf("failed to mail budget alert tenant=%s pct=%d to=%s: %v", tenantID, pct, to, err)
+		}
+	}
+	m.logf("budget alert tenant=%s month=%s pct=%d used=%.1f budget=%.1f", tenantID, month, pct, used, budget)
+}
+
+func formatBudgetAlert(tenantID, month string, pct int, used, budget float64) string {
+	var b strings.Builder
+	icon := ":warning:"
+	if pct >= 100 {
+		icon = ":rotating_light:"
+	}
+	fmt.Fprintf(&b, "%s Paper Forge: テナント %s の %s の利用量が月間予算の %d%% に達しました\n", icon, tenantID, month, pct)
+	fmt.Fprintf(&b, "利用量: %.1f / 予算: %.1f 単位 (%.1f%%)\n", used, budget, used/budget*100)
+	return b.String()
+}
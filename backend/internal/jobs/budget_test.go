@@ -0,0 +1,49 @@
+package jobs
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/yourusername/paper-forge/internal/config"
+	"github.com/yourusername/paper-forge/internal/pdf"
+)
+
+func TestBudgetPolicyUnits(t *testing.T) {
+	p := newBudgetPolicy(&config.Config{
+		OperationCostWeights:  "ocr=5, merge=1, optimize=0.5",
+		TenantMonthlyBudgets:  "default=1000,legal=200",
+		BudgetAlertThresholds: "100,80",
+	})
+
+	cases := []struct {
+		op    pdf.OperationType
+		pages int
+		want  float64
+	}{
+		{"ocr", 4, 20},
+		{pdf.OperationMerge, 4, 4},
+		{pdf.OperationOptimize, 3, 1.5},
+		{pdf.OperationSplit, 4, 4},
+	}
+	for _, tc := range cases {
+		if got := p.units(pdf.UsageSample{Operation: tc.op, Pages: tc.pages}); got != tc.want {
+			t.Fatalf("%s: units=%v, want %v", tc.op, got, tc.want)
+		}
+	}
+	if p.budgetFor("legal") != 200 || p.budgetFor("hr") != 0 {
+		t.Fatalf("unexpected budgets: %v", p.budgets)
+	}
+	if len(p.thresholds) != 2 || p.thresholds[0] != 80 {
+		t.Fatalf("thresholds should be sorted: %v", p.thresholds)
+	}
+
+	var disabled *budgetPolicy
+	if disabled.units(pdf.UsageSample{Operation: "ocr", Pages: 2}) != 2 || disabled.budgetFor("default") != 0 {
+		t.Fatal("nil policy should weight every page as 1 with no budget")
+	}
+
+	msg := formatBudgetAlert("legal", "2025-01", 100, 210, 200)
+	if !strings.Contains(msg, "legal") || !strings.Contains(msg, "105.0%") {
+		t.Fatalf("unexpected alert message: %s", msg)
+	}
+}
@@ -0,0 +1,146 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/yourusername/paper-forge/internal/tenant"
+)
+
+// dedupKeyPrefix は実行中のジョブを内容のキーから引く索引の接頭辞です（job-dedup:<tenant>:<key>）。
+const dedupKeyPrefix = "job-dedup:"
+
+// dedupClaimAttempts は終了したジョブの索引を置き換える際に、他の投入と競合した場合に判定をやり直す回数です。
+const dedupClaimAttempts = 3
+
+// claimDedupScript は索引が無ければジョブIDを登録し、索引が指しているジョブIDを返します（登録した場合は ARGV のジョブID）。
+// KEYS: 索引のキー / ARGV: ジョブID, 有効期限（ミリ秒）
+var claimDedupScript = redis.NewScript(`
+local existing = redis.call('GET', KEYS[1])
+if existing then
+	return existing
+end
+redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+return ARGV[1]
+`)
+
+// replaceDedupScript は索引がまだ ARGV[1] のジョブを指している場合にだけ ARGV[2] のジョブに置き換えて 1 を返します。
+// KEYS: 索引のキー / ARGV: 置き換える前のジョブID, 新しいジョブID, 有効期限（ミリ秒）
+var replaceDedupScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) ~= ARGV[1] then
+	return 0
+end
+redis.call('SET', KEYS[1], ARGV[2], 'PX', ARGV[3])
+return 1
+`)
+
+// ClaimDedup は JOB_DEDUP_ENABLED が有効な場合に、ジョブ jobID と同じ内容のジョブがテナント tenantID で
+// キュー待ち・処理中かを確認します。該当するジョブがあればそのIDと true を返します。
+// 無ければ jobID を以後の重複判定の対象として登録し、false を返します。
+// 索引の登録と置き換えは Redis 上で不可分に行うため、同時に投入された同じ内容のジョブのうち登録できるのは1件だけです。
+func (m *Manager) ClaimDedup(ctx context.Context, jobID, tenantID string) (string, bool, error) {
+	if !m.cfg.JobDedupEnabled {
+		return "", false, nil
+	}
+	key, ok := m.pdfService.DedupKey(jobID)
+	if !ok {
+		return "", false, nil
+	}
+	indexKey := dedupIndexKey(tenantID, key)
+	ttl := m.jobTTL().Milliseconds()
+
+	for range dedupClaimAttempts {
+		existing, err := claimDedupScript.Run(ctx, m.store.rdb, []string{indexKey}, jobID, ttl).Text()
+		if err != nil {
+			return "", false, err
+		}
+		if existing == jobID {
+			return "", false, nil
+		}
+		record, err := m.store.Get(ctx, existing)
+		if err != nil {
+			return "", false, err
+		}
+		// ジョブ情報が無いのは、索引を登録したジョブの投入がまだ終わっていない場合のため、同じ内容のジョブとして扱う。
+		// 投入に失敗したジョブの索引は ReleaseDedup で削除され、それ以外は索引の有効期限まで重複として扱う
+		if record == nil || (!record.Status.Terminal() && record.VisibleTo(tenantID)) {
+			return existing, true, nil
+		}
+		// 登録済みのジョブが終了している場合は今回のジョブで置き換える。他の投入が先に置き換えていた場合はやり直す
+		replaced, err := replaceDedupScript.Run(ctx, m.store.rdb, []string{indexKey}, existing, jobID, ttl).Int()
+		if err != nil {
+			return "", false, err
+		}
+		if replaced == 1 {
+			return "", false, nil
+		}
+	}
+	return "", false, fmt.Errorf("dedup index %s kept changing", indexKey)
+}
+
+// ReleaseDedup は ClaimDedup で登録したジョブ jobID の索引を削除します。
+// ジョブの投入に失敗した場合に、同じ内容の後続のジョブが存在しないジョブの重複として扱われないようにします。
+func (m *Manager) ReleaseDedup(ctx context.Context, jobID, tenantID string) error {
+	if !m.cfg.JobDedupEnabled {
+		return nil
+	}
+	key, ok := m.pdfService.DedupKey(jobID)
+	if !ok {
+		return nil
+	}
+	_, err := m.store.ReleaseDedup(ctx, dedupIndexKey(tenantID, key), jobID)
+	return err
+}
+
+// dedupIndexKey はテナント tenantID の内容のキー key に対応する重複判定の索引のキーです。
+func dedupIndexKey(tenantID, key string) string {
+	return dedupKeyPrefix + tenant.Normalize(tenantID) + ":" + key
+}
+
+// releaseDedupScript は重複判定の索引がジョブを指している場合にだけ削除して 1 を返します。
+// 同じ内容の後続のジョブが索引を置き換えていた場合は削除せず 0 を返します。
+// KEYS: 索引のキー / ARGV: ジョブID
+var releaseDedupScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// ReleaseDedup はジョブ jobID を指している重複判定の索引 indexKey を削除し、削除したかを返します。
+func (s *Store) ReleaseDedup(ctx context.Context, indexKey, jobID string) (bool, error) {
+	deleted, err := releaseDedupScript.Run(ctx, s.rdb, []string{indexKey}, jobID).Int()
+	if err != nil {
+		return false, err
+	}
+	return deleted == 1, nil
+}
+
+// PurgeDedupIndex は重複判定の索引をすべて削除し、削除した索引のキーと指していたジョブIDを返します。
+func (s *Store) PurgeDedupIndex(ctx context.Context) (map[string]string, error) {
+	var (
+		mu      sync.Mutex
+		deleted = make(map[string]string)
+	)
+	err := forEachNode(ctx, s.rdb, func(ctx context.Context, client redis.Cmdable) error {
+		iter := client.Scan(ctx, 0, dedupKeyPrefix+"*", 500).Iterator()
+		for iter.Next(ctx) {
+			jobID, err := client.GetDel(ctx, iter.Val()).Result()
+			if errors.Is(err, redis.Nil) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			mu.Lock()
+			deleted[iter.Val()] = jobID
+			mu.Unlock()
+		}
+		return iter.Err()
+	})
+	return deleted, err
+}
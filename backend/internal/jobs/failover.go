@@ -0,0 +1,157 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/yourusername/paper-forge/internal/config"
+)
+
+// failoverCheckInterval は主 Redis の死活を確認し、待避先のジョブ情報を書き戻す間隔です。
+const failoverCheckInterval = 5 * time.Second
+
+// EnableFailover は QUEUE_FAILOVER_REDIS_URL の Redis を待避先として設定します。
+// 主 Redis に接続できない間は新しいジョブを待避先のキューへ投入して待避先のワーカーで処理し、
+// 主 Redis が復旧したらジョブ情報と利用量を書き戻します。StartWorkers の前に呼び出してください。
+func (m *Manager) EnableFailover(ctx context.Context) error {
+	fcfg := *m.cfg
+	fcfg.QueueRedisMode = config.RedisModeStandalone
+	fcfg.QueueRedisURL = m.cfg.QueueFailoverRedisURL
+	fcfg.QueueRedisAddrs = ""
+	fcfg.QueueFailoverRedisURL = ""
+
+	rdb, err := NewRedisClient(&fcfg)
+	if err != nil {
+		return fmt.Errorf("failed to configure failover redis: %w", err)
+	}
+	if err := rdb.Ping(ctx).Err(); err != nil {
+		_ = rdb.Close()
+		return fmt.Errorf("failover redis is unreachable: %w", err)
+	}
+	secondary, err := NewManager(&fcfg, m.pdfService, NewStore(rdb, m.store.ttl), m.logger)
+	if err != nil {
+		_ = rdb.Close()
+		return err
+	}
+	m.failover = secondary
+	m.primaryDown.Store(m.store.rdb.Ping(ctx).Err() != nil)
+	return nil
+}
+
+// enqueueWithFailover は主 Redis に接続できない場合に、ジョブを待避先へ投入します。
+func (m *Manager) enqueueWithFailover(ctx context.Context, payload *TaskPayload) (string, error) {
+	if m.failover == nil {
+		return m.enqueue(ctx, payload)
+	}
+	if !m.primaryDown.Load() {
+		id, err := m.enqueue(ctx, payload)
+		if err == nil || !queueUnavailable(err) {
+			return id, err
+		}
+		m.primaryDown.Store(true)
+		m.logf("primary redis is unavailable, enqueueing to failover job=%s: %v", payload.JobID, err)
+	}
+	return m.failover.enqueue(ctx, payload)
+}
+
+// failoverRecord は待避先で処理中・書き戻し前のジョブ情報を返します。
+func (m *Manager) failoverRecord(ctx context.Context, jobID string) *Record {
+	if m.failover == nil {
+		return nil
+	}
+	record, err := m.failover.store.Get(ctx, jobID)
+	if err != nil {
+		m.logf("failed to read failover record job=%s: %v", jobID, err)
+		return nil
+	}
+	return record
+}
+
+// monitorPrimary は主 Redis の死活を定期的に確認し、応答している間は待避先のジョブ情報を書き戻します。
+func (m *Manager) monitorPrimary(ctx context.Context) {
+	ticker := time.NewTicker(failoverCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := m.store.rdb.Ping(ctx).Err(); err != nil {
+			if !m.primaryDown.Swap(true) {
+				m.logf("primary redis is unavailable, new jobs go to failover: %v", err)
+			}
+			continue
+		}
+		if m.primaryDown.Swap(false) {
+			m.logf("primary redis recovered, reconciling failover jobs")
+		}
+		if err := m.reconcileFailover(ctx); err != nil {
+			m.logf("failed to reconcile failover jobs: %v", err)
+			if queueUnavailable(err) {
+				m.primaryDown.Store(true)
+			}
+		}
+	}
+}
+
+// reconcileFailover は待避先のジョブ情報を主 Redis へ書き戻します。終了したジョブは待避先から削除し、
+// 処理中のジョブは終了するまで毎回書き戻します。待避先のワーカーが処理を終えるたびに利用量が加算されるため、
+// 利用量も復旧時に限らず毎回書き戻します。
+func (m *Manager) reconcileFailover(ctx context.Context) error {
+	ids, err := m.failover.store.JobIDs(ctx)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		record, err := m.failover.store.Get(ctx, id)
+		if err != nil {
+			return err
+		}
+		if record == nil {
+			continue
+		}
+		if err := m.store.restore(ctx, record); err != nil {
+			return err
+		}
+		if record.Status.Terminal() {
+			if _, err := m.failover.store.Delete(ctx, id); err != nil {
+				return err
+			}
+		}
+	}
+	for _, month := range reconcileMonths(time.Now()) {
+		if err := m.failover.store.moveUsage(ctx, m.store, month); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reconcileMonths は書き戻す利用量の月（今月と前月）を返します。月をまたいで待避していた場合に備えます。
+func reconcileMonths(now time.Time) []string {
+	first := time.Date(now.UTC().Year(), now.UTC().Month(), 1, 0, 0, 0, 0, time.UTC)
+	return []string{
+		first.Format(UsageMonthLayout),
+		first.AddDate(0, -1, 0).Format(UsageMonthLayout),
+	}
+}
+
+// queueUnavailable はエラーが Redis に接続できないことによるものかを返します。
+func queueUnavailable(err error) bool {
+	if err == nil {
+		return false
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, redis.ErrClosed)
+}
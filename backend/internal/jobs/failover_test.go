@@ -0,0 +1,40 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestQueueUnavailable(t *testing.T) {
+	unavailable := []error{
+		&net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED},
+		fmt.Errorf("enqueue: %w", io.EOF),
+		syscall.ECONNREFUSED,
+	}
+	for _, err := range unavailable {
+		if !queueUnavailable(err) {
+			t.Fatalf("%v should be treated as unavailable", err)
+		}
+	}
+	for _, err := range []error{nil, ErrTenantQuotaExceeded, errors.New("payload.JobID is required"), context.Canceled} {
+		if queueUnavailable(err) {
+			t.Fatalf("%v should not trigger failover", err)
+		}
+	}
+}
+
+func TestReconcileMonths(t *testing.T) {
+	got := reconcileMonths(time.Date(2025, 3, 31, 23, 0, 0, 0, time.UTC))
+	if len(got) != 2 || got[0] != "2025-03" || got[1] != "2025-02" {
+		t.Fatalf("unexpected months: %v", got)
+	}
+	if got := reconcileMonths(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)); got[1] != "2024-12" {
+		t.Fatalf("previous month should cross the year: %v", got)
+	}
+}
@@ -0,0 +1,152 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/yourusername/paper-forge/internal/tenant"
+)
+
+const (
+	// tenantKeyPrefix はテナントごとの実行枠と待ち行列のキーの接頭辞です。
+	// テナントIDをハッシュタグ（{}）で囲み、Redis Cluster でも同じスロットに置きます。
+	tenantKeyPrefix = "job-tenant:"
+	// staleSlotAfter は実行枠を保持したまま応答のないジョブ（ワーカーの異常終了など）の枠を解放するまでの時間です。
+	// asynq のタスクの既定のタイムアウト（30分）に合わせています。
+	staleSlotAfter = 30 * time.Minute
+	// tenantKeysTTL は実行枠と待ち行列のキーの有効期限です。投入・解放のたびに延長します。
+	tenantKeysTTL = 24 * time.Hour
+	// waitingMessage は実行枠の空きを待っているジョブの進捗メッセージです。
+	waitingMessage = "同じ利用者の実行中のジョブの完了を待っています。"
+)
+
+// admitScript は実行枠に空きがあり、先に待っているジョブが無ければ枠を確保して 1 を返します。
+// 空きが無い場合はペイロードを待ち行列の末尾に追加して 0 を返します。
+// KEYS: 実行枠（ZSET）, 待ち行列（LIST） / ARGV: 上限, 現在時刻, 期限切れとみなす時刻, ジョブID, ペイロード, キーの有効期限（秒）
+var admitScript = redis.NewScript(`
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', ARGV[3])
+if redis.call('ZCARD', KEYS[1]) < tonumber(ARGV[1]) and redis.call('LLEN', KEYS[2]) == 0 then
+	redis.call('ZADD', KEYS[1], ARGV[2], ARGV[4])
+	redis.call('EXPIRE', KEYS[1], ARGV[6])
+	return 1
+end
+redis.call('RPUSH', KEYS[2], ARGV[5])
+redis.call('EXPIRE', KEYS[2], ARGV[6])
+return 0
+`)
+
+// releaseScript はジョブの実行枠を解放し、空いた枠の分だけ待ち行列の先頭から取り出したジョブに枠を割り当てて
+// そのペイロードを返します。
+// KEYS: 実行枠（ZSET）, 待ち行列（LIST） / ARGV: ジョブID, 上限, 現在時刻, 期限切れとみなす時刻, キーの有効期限（秒）
+var releaseScript = redis.NewScript(`
+redis.call('ZREM', KEYS[1], ARGV[1])
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', ARGV[4])
+local admitted = {}
+while redis.call('ZCARD', KEYS[1]) < tonumber(ARGV[2]) do
+	local payload = redis.call('LPOP', KEYS[2])
+	if not payload then
+		break
+	end
+	redis.call('ZADD', KEYS[1], ARGV[3], cjson.decode(payload)['jobId'])
+	table.insert(admitted, payload)
+end
+if #admitted > 0 then
+	redis.call('EXPIRE', KEYS[1], ARGV[5])
+end
+return admitted
+`)
+
+// tenantQueueKeys はテナント tenantID の実行枠と待ち行列のキーを返します。
+func tenantQueueKeys(tenantID string) []string {
+	prefix := tenantKeyPrefix + "{" + tenant.Normalize(tenantID) + "}:"
+	return []string{prefix + "slots", prefix + "waiting"}
+}
+
+// admitTenantJob は TENANT_MAX_RUNNING_JOBS が有効な場合に、ジョブをすぐ実行できるかを判定します。
+// テナントの実行中のジョブが上限に達しているか先に待っているジョブがある場合は、テナントごとの待ち行列に
+// 投入順で積んで false を返します。待っているジョブは実行中のジョブが終わるたびに releaseTenantSlot で投入します。
+func (m *Manager) admitTenantJob(ctx context.Context, payload *TaskPayload, body []byte) (bool, error) {
+	limit := m.cfg.TenantMaxRunningJobs
+	if limit <= 0 {
+		return true, nil
+	}
+	now := time.Now()
+	admitted, err := admitScript.Run(ctx, m.store.rdb, tenantQueueKeys(payload.Tenant),
+		limit,
+		now.Unix(),
+		now.Add(-staleSlotAfter).Unix(),
+		payload.JobID,
+		body,
+		int64(tenantKeysTTL/time.Second),
+	).Int()
+	if err != nil {
+		return false, err
+	}
+	return admitted == 1, nil
+}
+
+// holdsTenantSlot はジョブ payload がテナントの実行枠を保持しているかを返します。
+func (m *Manager) holdsTenantSlot(ctx context.Context, payload TaskPayload) bool {
+	if m.cfg.TenantMaxRunningJobs <= 0 {
+		return false
+	}
+	err := m.store.rdb.ZScore(ctx, tenantQueueKeys(payload.Tenant)[0], payload.JobID).Err()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		m.logf("failed to check tenant slot job=%s tenant=%s: %v", payload.JobID, payload.Tenant, err)
+	}
+	return err == nil
+}
+
+// willRetry は試行 retried 回目（最初の実行は 0）のタスクが err で終わったときに、Asynq が再試行するかを返します。
+func willRetry(err error, retried, maxRetry int) bool {
+	if err == nil || errors.Is(err, asynq.SkipRetry) {
+		return false
+	}
+	return retried < maxRetry
+}
+
+// releaseTenantSlot はジョブの実行枠を解放し、同じテナントで待っているジョブを投入順にキューへ入れます。
+// 投入に失敗したジョブは失敗として記録し、その枠も次の待ちジョブへ回します。
+func (m *Manager) releaseTenantSlot(ctx context.Context, payload TaskPayload) {
+	limit := m.cfg.TenantMaxRunningJobs
+	if limit <= 0 {
+		return
+	}
+	pending := []string{payload.JobID}
+	for len(pending) > 0 {
+		jobID := pending[0]
+		pending = pending[1:]
+
+		now := time.Now()
+		admitted, err := releaseScript.Run(ctx, m.store.rdb, tenantQueueKeys(payload.Tenant),
+			jobID,
+			limit,
+			now.Unix(),
+			now.Add(-staleSlotAfter).Unix(),
+			int64(tenantKeysTTL/time.Second),
+		).StringSlice()
+		if err != nil {
+			m.logf("failed to release tenant slot job=%s tenant=%s: %v", jobID, payload.Tenant, err)
+			return
+		}
+		for _, body := range admitted {
+			var next TaskPayload
+			if err := json.Unmarshal([]byte(body), &next); err != nil {
+				m.logf("failed to decode waiting job tenant=%s: %v", payload.Tenant, err)
+				continue
+			}
+			if _, err := m.dispatch(ctx, next.Tenant, []byte(body)); err != nil {
+				m.logf("failed to enqueue waiting job=%s: %v", next.JobID, err)
+				if ferr := m.failJobWithError(ctx, next.JobID, err); ferr != nil {
+					m.logf("failed to mark waiting job failed job=%s: %v", next.JobID, ferr)
+				}
+				pending = append(pending, next.JobID)
+			}
+		}
+	}
+}
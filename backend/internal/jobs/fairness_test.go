@@ -0,0 +1,49 @@
+package jobs
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/hibiken/asynq"
+)
+
+func TestTenantQueueKeysShareHashSlot(t *testing.T) {
+	keys := tenantQueueKeys("")
+	if len(keys) != 2 || keys[0] == keys[1] {
+		t.Fatalf("unexpected keys: %v", keys)
+	}
+	for _, key := range keys {
+		// Lua スクリプトで同時に扱うため、Redis Cluster でも同じスロットになるハッシュタグを含める
+		if !strings.HasPrefix(key, tenantKeyPrefix+"{default}:") {
+			t.Fatalf("key %q should be scoped to the default tenant with a hash tag", key)
+		}
+	}
+	if other := tenantQueueKeys("sales"); other[0] == keys[0] || other[1] == keys[1] {
+		t.Fatalf("tenants must not share keys: %v %v", keys, other)
+	}
+}
+
+func TestWillRetryKeepsSlotOnlyForRetryableErrors(t *testing.T) {
+	retryable := errors.New("redis unavailable")
+	cases := []struct {
+		name     string
+		err      error
+		retried  int
+		maxRetry int
+		want     bool
+	}{
+		{"success", nil, 0, 1, false},
+		{"retryable error", retryable, 0, 1, true},
+		{"final attempt", retryable, 1, 1, false},
+		{"skip retry", fmt.Errorf("panic: %w", asynq.SkipRetry), 0, 1, false},
+		// Asynq のワーカー以外から呼ばれた場合は試行回数が分からないため、再試行しないものとして扱う
+		{"outside a worker", retryable, 0, 0, false},
+	}
+	for _, tc := range cases {
+		if got := willRetry(tc.err, tc.retried, tc.maxRetry); got != tc.want {
+			t.Errorf("%s: willRetry = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package jobs
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/yourusername/paper-forge/internal/pdf"
+)
+
+// GCWorkspace は作業領域に残っているワークスペースと、対応するジョブ情報の状態です。
+type GCWorkspace struct {
+	pdf.WorkspaceEntry
+	// Status は対応するジョブ情報の状態です。ジョブ情報が無い場合は空です。
+	Status Status `json:"status,omitempty"`
+	// Orphaned はジョブ情報が無く、有効期限（JOB_EXPIRE_MINUTES）を過ぎても残っているワークスペースです。
+	// 削除の予約はプロセス内のタイマーのため、期限前にインスタンスが再起動すると残ります。
+	Orphaned bool `json:"orphaned"`
+}
+
+// GCRecord はワークスペースがこのインスタンスに無いジョブ情報です。
+type GCRecord struct {
+	JobID     string    `json:"jobId"`
+	Operation string    `json:"operation"`
+	Status    Status    `json:"status"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	Held      bool      `json:"held"`
+}
+
+// GCReport は作業領域とジョブ情報の突き合わせの結果です。
+type GCReport struct {
+	GeneratedAt   time.Time     `json:"generatedAt"`
+	ExpireMinutes int           `json:"expireMinutes"`
+	TotalSize     int64         `json:"totalSize"`
+	Workspaces    []GCWorkspace `json:"workspaces"`
+	// OrphanedRecords はワークスペースが見つからないジョブ情報です。
+	OrphanedRecords []GCRecord `json:"orphanedRecords"`
+}
+
+// GCCleanReceipt は選択したワークスペース・ジョブ情報の強制削除の証跡です。
+type GCCleanReceipt struct {
+	CleanedAt  time.Time        `json:"cleanedAt"`
+	Workspaces []PurgeReceipt   `json:"workspaces"`
+	Records    []pdf.PurgedItem `json:"records"`
+	// Skipped は処理中・リーガルホールド中・IDの形式誤り・既に無いため削除しなかった項目です。
+	Skipped []SkippedJob `json:"skipped"`
+}
+
+// GCReport はこのインスタンスの作業領域に残っているワークスペースの大きさと経過時間を一覧にし、
+// ジョブ情報と突き合わせて、ジョブ情報の無いワークスペースとワークスペースの無いジョブ情報を返します。
+func (m *Manager) GCReport(ctx context.Context) (*GCReport, error) {
+	entries, err := m.pdfService.ListWorkspaces()
+	if err != nil {
+		return nil, err
+	}
+	ttl := m.jobTTL()
+	report := &GCReport{
+		GeneratedAt:     time.Now().UTC(),
+		ExpireMinutes:   int(ttl / time.Minute),
+		Workspaces:      make([]GCWorkspace, 0, len(entries)),
+		OrphanedRecords: []GCRecord{},
+	}
+	for _, entry := range entries {
+		record, err := m.store.Get(ctx, entry.JobID)
+		if err != nil {
+			return nil, err
+		}
+		ws := GCWorkspace{WorkspaceEntry: entry}
+		if record != nil {
+			ws.Status = record.Status
+		}
+		ws.Orphaned = workspaceOrphaned(entry, record, ttl)
+		report.TotalSize += entry.Size
+		report.Workspaces = append(report.Workspaces, ws)
+	}
+
+	ids, err := m.store.JobIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		if m.pdfService.WorkspaceExists(id) {
+			continue
+		}
+		record, err := m.store.Get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if record == nil {
+			continue
+		}
+		report.OrphanedRecords = append(report.OrphanedRecords, GCRecord{
+			JobID:     record.JobID,
+			Operation: record.Operation,
+			Status:    record.Status,
+			UpdatedAt: record.UpdatedAt,
+			Held:      record.Hold != nil,
+		})
+	}
+	return report, nil
+}
+
+// workspaceOrphaned は、ジョブ情報が無く有効期限を過ぎたワークスペースかどうかを返します。
+// 同期処理のワークスペースはジョブ情報を持たないため、期限内のものは処理中とみなします。
+func workspaceOrphaned(entry pdf.WorkspaceEntry, record *Record, ttl time.Duration) bool {
+	if record != nil || entry.Held {
+		return false
+	}
+	return time.Duration(entry.AgeSeconds)*time.Second >= ttl
+}
+
+// CleanGarbage は選択したワークスペース（入力・成果物・マニフェストと同じ入力の結果キャッシュ）と
+// ジョブ情報を強制的に削除します。処理中のジョブとリーガルホールド中のジョブは削除しません。
+func (m *Manager) CleanGarbage(ctx context.Context, workspaceIDs, recordIDs []string) (*GCCleanReceipt, error) {
+	receipt := &GCCleanReceipt{
+		Workspaces: []PurgeReceipt{},
+		Records:    []pdf.PurgedItem{},
+		Skipped:    []SkippedJob{},
+	}
+	ttl := m.jobTTL()
+	for _, id := range workspaceIDs {
+		if !pdf.ValidWorkspaceID(id) {
+			receipt.Skipped = append(receipt.Skipped, SkippedJob{JobID: id, Reason: "invalid_id"})
+			continue
+		}
+		record, err := m.store.Get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		entry, ok := m.pdfService.Workspace(id)
+		if !ok {
+			receipt.Skipped = append(receipt.Skipped, SkippedJob{JobID: id, Reason: "not_found"})
+			continue
+		}
+		if reason := workspaceCleanBlocked(entry, record, ttl); reason != "" {
+			receipt.Skipped = append(receipt.Skipped, SkippedJob{JobID: id, Reason: reason})
+			continue
+		}
+		items, err := m.pdfService.PurgeWorkspace(id)
+		if errors.Is(err, pdf.ErrWorkspaceHeld) {
+			receipt.Skipped = append(receipt.Skipped, SkippedJob{JobID: id, Reason: "legal_hold"})
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		receipt.Workspaces = append(receipt.Workspaces, PurgeReceipt{
+			JobID:    id,
+			PurgedAt: time.Now().UTC(),
+			Items:    items,
+			Verified: !m.pdfService.WorkspaceExists(id),
+		})
+	}
+
+	for _, id := range recordIDs {
+		record, err := m.store.Get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if record == nil {
+			receipt.Skipped = append(receipt.Skipped, SkippedJob{JobID: id, Reason: "not_found"})
+			continue
+		}
+		if reason := cleanBlocked(record); reason != "" {
+			receipt.Skipped = append(receipt.Skipped, SkippedJob{JobID: id, Reason: reason})
+			continue
+		}
+		data, err := m.store.Delete(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if data == nil {
+			receipt.Skipped = append(receipt.Skipped, SkippedJob{JobID: id, Reason: "not_found"})
+			continue
+		}
+		sum := sha256.Sum256(data)
+		receipt.Records = append(receipt.Records, pdf.PurgedItem{
+			Kind:      "record",
+			Name:      jobKey(id),
+			Size:      int64(len(data)),
+			SHA256:    hex.EncodeToString(sum[:]),
+			DeletedAt: time.Now().UTC(),
+		})
+	}
+
+	receipt.CleanedAt = time.Now().UTC()
+	m.logf("cleaned garbage workspaces=%d records=%d skipped=%d", len(receipt.Workspaces), len(receipt.Records), len(receipt.Skipped))
+	return receipt, nil
+}
+
+// workspaceCleanBlocked はワークスペースを強制削除できない理由を返します。削除できる場合は空です。
+// ジョブ情報の無いワークスペースは、期限内のものは同期処理の実行中のため、期限を過ぎた（orphaned の）ものだけを削除します。
+func workspaceCleanBlocked(entry pdf.WorkspaceEntry, record *Record, ttl time.Duration) string {
+	if record != nil {
+		return cleanBlocked(record)
+	}
+	switch {
+	case workspaceOrphaned(entry, nil, ttl):
+		return ""
+	case entry.Held:
+		return "legal_hold"
+	}
+	return "running"
+}
+
+// cleanBlocked はジョブ情報から強制削除できない理由を返します。削除できる場合は空です。
+// ジョブ情報が無い場合は処理中かどうかを判断できないため、削除しません。
+func cleanBlocked(record *Record) string {
+	switch {
+	case record == nil:
+		return "running"
+	case record.Hold != nil:
+		return "legal_hold"
+	case !record.Status.Terminal():
+		return "running"
+	}
+	return ""
+}
@@ -0,0 +1,66 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/yourusername/paper-forge/internal/pdf"
+)
+
+func TestWorkspaceOrphanedRequiresMissingRecordAndExpiry(t *testing.T) {
+	ttl := 10 * time.Minute
+	cases := []struct {
+		name   string
+		entry  pdf.WorkspaceEntry
+		record *Record
+		want   bool
+	}{
+		{"expired without record", pdf.WorkspaceEntry{AgeSeconds: 600}, nil, true},
+		{"in-flight sync request", pdf.WorkspaceEntry{AgeSeconds: 30}, nil, false},
+		{"held", pdf.WorkspaceEntry{AgeSeconds: 6000, Held: true}, nil, false},
+		{"with record", pdf.WorkspaceEntry{AgeSeconds: 6000}, &Record{Status: StatusSucceeded}, false},
+	}
+	for _, tc := range cases {
+		if got := workspaceOrphaned(tc.entry, tc.record, ttl); got != tc.want {
+			t.Fatalf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestCleanBlockedKeepsRunningAndHeldJobs(t *testing.T) {
+	cases := []struct {
+		record *Record
+		want   string
+	}{
+		{nil, "running"},
+		{&Record{Status: StatusFailed}, ""},
+		{&Record{Status: StatusRunning}, "running"},
+		{&Record{Status: StatusSucceeded, Hold: &HoldInfo{}}, "legal_hold"},
+	}
+	for _, tc := range cases {
+		if got := cleanBlocked(tc.record); got != tc.want {
+			t.Fatalf("record %+v: got %q, want %q", tc.record, got, tc.want)
+		}
+	}
+}
+
+func TestWorkspaceCleanBlockedOnlyCleansOrphanedWorkspacesWithoutRecord(t *testing.T) {
+	ttl := 10 * time.Minute
+	cases := []struct {
+		name   string
+		entry  pdf.WorkspaceEntry
+		record *Record
+		want   string
+	}{
+		{"orphaned", pdf.WorkspaceEntry{AgeSeconds: 600}, nil, ""},
+		{"in-flight sync request", pdf.WorkspaceEntry{AgeSeconds: 30}, nil, "running"},
+		{"held without record", pdf.WorkspaceEntry{AgeSeconds: 6000, Held: true}, nil, "legal_hold"},
+		{"finished job", pdf.WorkspaceEntry{AgeSeconds: 30}, &Record{Status: StatusSucceeded}, ""},
+		{"running job", pdf.WorkspaceEntry{AgeSeconds: 6000}, &Record{Status: StatusRunning}, "running"},
+	}
+	for _, tc := range cases {
+		if got := workspaceCleanBlocked(tc.entry, tc.record, ttl); got != tc.want {
+			t.Fatalf("%s: got %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
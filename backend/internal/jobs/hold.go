@@ -0,0 +1,71 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"strings"
+	"time"
+)
+
+// maxHoldReasonLength はリーガルホールドの理由の最大文字数です。
+const maxHoldReasonLength = 500
+
+var (
+	// ErrJobNotFound は指定されたジョブが存在しない（期限切れを含む）ことを表します。
+	ErrJobNotFound = errors.New("job not found")
+	// ErrJobNotFinished は終了していないジョブへの操作を表します。
+	ErrJobNotFinished = errors.New("job is not finished")
+)
+
+// HoldJob はジョブをリーガルホールドにし、ジョブ情報と成果物を有効期限による削除の対象外にします。
+// 処理中のジョブは状態が書き換わるため、終了したジョブのみ対象とします。
+// 成果物が既に削除されている場合も、ジョブ情報はホールドします。
+func (m *Manager) HoldJob(ctx context.Context, jobID, reason string) (*Record, error) {
+	record, err := m.store.Get(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		return nil, ErrJobNotFound
+	}
+	if !record.Status.Terminal() {
+		return nil, ErrJobNotFinished
+	}
+
+	reason = strings.TrimSpace(reason)
+	if r := []rune(reason); len(r) > maxHoldReasonLength {
+		reason = string(r[:maxHoldReasonLength])
+	}
+	if err := m.pdfService.HoldWorkspace(jobID, reason); err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+		m.logf("legal hold set without workspace (already removed) job=%s", jobID)
+	}
+	if err := m.store.SetHold(ctx, jobID, &HoldInfo{Reason: reason, HeldAt: time.Now().UTC()}); err != nil {
+		return nil, err
+	}
+	return m.store.Get(ctx, jobID)
+}
+
+// ReleaseJob はリーガルホールドを解除します。ジョブ情報と成果物は解除した時点から TTL の間だけ保持します。
+func (m *Manager) ReleaseJob(ctx context.Context, jobID string) (*Record, error) {
+	record, err := m.store.Get(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		return nil, ErrJobNotFound
+	}
+	if record.Hold == nil {
+		return record, nil
+	}
+	if err := m.pdfService.ReleaseWorkspace(jobID); err != nil {
+		return nil, err
+	}
+	if err := m.store.SetHold(ctx, jobID, nil); err != nil {
+		return nil, err
+	}
+	return m.store.Get(ctx, jobID)
+}
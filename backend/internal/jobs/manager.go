@@ -7,12 +7,21 @@ import (
 	"fmt"
 	"log"
 	"net/url"
+	"runtime/debug"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/hibiken/asynq"
 
 	"github.com/yourusername/paper-forge/internal/config"
+	"github.com/yourusername/paper-forge/internal/connectors"
+	"github.com/yourusername/paper-forge/internal/delivery"
+	"github.com/yourusername/paper-forge/internal/errreport"
+	"github.com/yourusername/paper-forge/internal/metrics"
+	"github.com/yourusername/paper-forge/internal/notify"
 	"github.com/yourusername/paper-forge/internal/pdf"
+	"github.com/yourusername/paper-forge/internal/tenant"
 )
 
 const (
@@ -25,15 +34,30 @@ type Manager struct {
 	client     *asynq.Client
 	server     *asynq.Server
 	mux        *asynq.ServeMux
+	inspector  *asynq.Inspector
 	store      *Store
 	pdfService *pdf.Service
 	logger     *log.Logger
+	dispatcher *delivery.Dispatcher
+	notifier   *notify.Notifier
+	reporter   *errreport.Reporter
+	mailer     *delivery.Mailer
+	signer     *LinkSigner
+	budget     *budgetPolicy
+	queues     tenantQueues
+
+	// failover は主 Redis に接続できない間にジョブを投入する待避先です（QUEUE_FAILOVER_REDIS_URL）。
+	failover    *Manager
+	primaryDown atomic.Bool
+	stopMonitor context.CancelFunc
 }
 
 // TaskPayload はPDF操作ジョブのペイロードです。
 type TaskPayload struct {
 	JobID     string            `json:"jobId"`
 	Operation pdf.OperationType `json:"operation"`
+	// Tenant はジョブを投入したテナントです。空の場合は投入時のコンテキストから設定します。
+	Tenant string `json:"tenant,omitempty"`
 }
 
 // NewManager は Manager を初期化します。
@@ -47,19 +71,18 @@ func NewManager(cfg *config.Config, pdfService *pdf.Service, store *Store, logge
 	if store == nil {
 		return nil, errors.New("store is nil")
 	}
-	opt, err := asynq.ParseRedisURI(cfg.QueueRedisURL)
+	opt, err := newRedisConn(cfg)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse redis url: %w", err)
+		return nil, err
 	}
 
+	queues := newTenantQueues(cfg.TenantQueueWeights)
 	client := asynq.NewClient(opt)
 	server := asynq.NewServer(
 		opt,
 		asynq.Config{
 			Concurrency: 4,
-			Queues: map[string]int{
-				"pdf": 1,
-			},
+			Queues:      queues.serverQueues(),
 		},
 	)
 
@@ -69,16 +92,31 @@ func NewManager(cfg *config.Config, pdfService *pdf.Service, store *Store, logge
 		client:     client,
 		server:     server,
 		mux:        mux,
+		inspector:  asynq.NewInspector(opt),
 		store:      store,
 		pdfService: pdfService,
 		logger:     logger,
+		dispatcher: delivery.NewDispatcher(cfg),
+		notifier:   notify.New(cfg),
+		reporter:   errreport.New(cfg, nil),
+		mailer:     delivery.NewMailer(cfg),
+		signer:     NewLinkSigner(cfg.SessionSecret),
+		budget:     newBudgetPolicy(cfg),
+		queues:     queues,
 	}
 	mux.HandleFunc(taskTypePDF, manager.handlePDFTask)
 	return manager, nil
 }
 
 // StartWorkers は Asynq サーバーをバックグラウンドで起動します。
+// 待避先が設定されている場合は、待避先のワーカーと主 Redis の死活監視も起動します。
 func (m *Manager) StartWorkers() {
+	if m.failover != nil {
+		m.failover.StartWorkers()
+		ctx, cancel := context.WithCancel(context.Background())
+		m.stopMonitor = cancel
+		go m.monitorPrimary(ctx)
+	}
 	go func() {
 		if err := m.server.Run(m.mux); err != nil && err != asynq.ErrServerClosed {
 			if m.logger != nil {
@@ -92,12 +130,30 @@ func (m *Manager) StartWorkers() {
 
 // Shutdown はサーバーとクライアントを閉じます。
 func (m *Manager) Shutdown(ctx context.Context) error {
+	if m.stopMonitor != nil {
+		m.stopMonitor()
+	}
+	if m.failover != nil {
+		_ = m.failover.Shutdown(ctx)
+	}
 	m.server.Shutdown()
 	m.client.Close()
+	m.inspector.Close()
 	return nil
 }
 
-// Enqueue はジョブをキューに投入します。
+// Ping は Redis（待避先を含む）に接続できるかを確認します。接続を維持する定期 ping にも使います。
+func (m *Manager) Ping(ctx context.Context) error {
+	err := m.store.rdb.Ping(ctx).Err()
+	if m.failover != nil {
+		if ferr := m.failover.Ping(ctx); ferr != nil {
+			err = errors.Join(err, ferr)
+		}
+	}
+	return err
+}
+
+// Enqueue はジョブをキューに投入します。主 Redis に接続できない場合は待避先へ投入します。
 func (m *Manager) Enqueue(ctx context.Context, payload *TaskPayload) (string, error) {
 	if payload == nil {
 		return "", fmt.Errorf("payload is nil")
@@ -105,15 +161,35 @@ func (m *Manager) Enqueue(ctx context.Context, payload *TaskPayload) (string, er
 	if payload.JobID == "" {
 		return "", fmt.Errorf("payload.JobID is required")
 	}
+	if payload.Tenant == "" {
+		payload.Tenant = tenant.FromContext(ctx)
+	}
+	return m.enqueueWithFailover(ctx, payload)
+}
+
+func (m *Manager) enqueue(ctx context.Context, payload *TaskPayload) (string, error) {
+	if err := m.checkTenantQuota(ctx, payload.Tenant); err != nil {
+		return "", err
+	}
+
+	// ラベルなどは投入時にマニフェストへ保存されているため、キュー待ちの間も参照できるよう記録へ写す
+	jobOpts, err := m.pdfService.LoadJobOptions(payload.JobID)
+	if err != nil {
+		m.logf("failed to load job options job=%s: %v", payload.JobID, err)
+	}
 
 	record := &Record{
-		JobID:     payload.JobID,
-		Operation: string(payload.Operation),
-		Status:    StatusQueued,
+		JobID:         payload.JobID,
+		Operation:     string(payload.Operation),
+		Tenant:        payload.Tenant,
+		Label:         jobOpts.Label,
+		ClientContext: jobOpts.ClientContext,
+		Status:        StatusQueued,
 		Progress: ProgressInfo{
-			Percent: 0,
-			Stage:   "queued",
+			Percent: pdf.StageQueued.Percent(0),
+			Stage:   string(pdf.StageQueued),
 		},
+		QueuedAt: time.Now().UTC(),
 	}
 	if err := m.store.Upsert(ctx, record); err != nil {
 		return "", err
@@ -124,7 +200,25 @@ func (m *Manager) Enqueue(ctx context.Context, payload *TaskPayload) (string, er
 		return "", err
 	}
 
-	task := asynq.NewTask(taskTypePDF, body, asynq.Queue("pdf"))
+	ready, err := m.admitTenantJob(ctx, payload, body)
+	if err != nil {
+		return "", err
+	}
+	if !ready {
+		// 実行枠が空くまでキュー待ちのまま、テナントごとの待ち行列で順番を待つ
+		progress := record.Progress
+		progress.Message = waitingMessage
+		if err := m.store.UpdateProgress(ctx, payload.JobID, progress); err != nil {
+			m.logf("failed to update progress job=%s: %v", payload.JobID, err)
+		}
+		return payload.JobID, nil
+	}
+	return m.dispatch(ctx, payload.Tenant, body)
+}
+
+// dispatch はジョブのペイロードを asynq のタスクとしてテナント tenantID のキューへ入れます。
+func (m *Manager) dispatch(ctx context.Context, tenantID string, body []byte) (string, error) {
+	task := asynq.NewTask(taskTypePDF, body, asynq.Queue(m.queues.queueFor(tenantID)))
 	info, err := m.client.EnqueueContext(ctx, task, asynq.MaxRetry(1))
 	if err != nil {
 		return "", err
@@ -142,12 +236,55 @@ func (m *Manager) UpdateProgress(ctx context.Context, jobID string, percent int,
 	}
 }
 
-// GetRecord はジョブ情報を取得します。
+// GetRecord はジョブ情報を取得します。待避先で処理中・書き戻し前のジョブはそちらの情報を返します。
 func (m *Manager) GetRecord(ctx context.Context, jobID string) (*Record, error) {
-	return m.store.Get(ctx, jobID)
+	if record := m.failoverRecord(ctx, jobID); record != nil {
+		return m.flagQueueSLA(record), nil
+	}
+	record, err := m.store.Get(ctx, jobID)
+	return m.flagQueueSLA(record), err
+}
+
+// WaitForTerminal はジョブが終了状態になるか timeout まで待ってからジョブ情報を返します。
+func (m *Manager) WaitForTerminal(ctx context.Context, jobID string, timeout time.Duration) (*Record, error) {
+	store := m.store
+	if record := m.failoverRecord(ctx, jobID); record != nil {
+		store = m.failover.store
+	}
+	record, err := store.WaitForTerminal(ctx, jobID, timeout)
+	return m.flagQueueSLA(record), err
+}
+
+// ListChanges は cursor より後にステータスが変化したジョブを返します。
+func (m *Manager) ListChanges(ctx context.Context, cursor int64, limit int) ([]*Record, int64, bool, error) {
+	records, next, hasMore, err := m.store.Changes(ctx, cursor, limit)
+	for _, record := range records {
+		m.flagQueueSLA(record)
+	}
+	return records, next, hasMore, err
+}
+
+// queueSLA はキュー待ちの目標時間（JOB_QUEUE_SLA_SECONDS）です。0 の場合は判定しません。
+func (m *Manager) queueSLA() time.Duration {
+	if m.cfg == nil || m.cfg.JobQueueSLASeconds <= 0 {
+		return 0
+	}
+	return time.Duration(m.cfg.JobQueueSLASeconds) * time.Second
+}
+
+// flagQueueSLA はキュー待ちのまま目標時間を超えているジョブに slaBreached を付けて返します。
+// 処理を始めたジョブは開始時に判定して保存しているため、ここでは表示用に付けるだけで保存しません。
+func (m *Manager) flagQueueSLA(record *Record) *Record {
+	if record == nil || record.SLABreached || record.Status != StatusQueued {
+		return record
+	}
+	if sla := m.queueSLA(); sla > 0 && record.QueuedFor(time.Now()) > sla {
+		record.SLABreached = true
+	}
+	return record
 }
 
-func (m *Manager) handlePDFTask(ctx context.Context, task *asynq.Task) error {
+func (m *Manager) handlePDFTask(ctx context.Context, task *asynq.Task) (err error) {
 	var payload TaskPayload
 	if err := json.Unmarshal(task.Payload(), &payload); err != nil {
 		return err
@@ -156,42 +293,271 @@ func (m *Manager) handlePDFTask(ctx context.Context, task *asynq.Task) error {
 	if payload.JobID == "" {
 		return fmt.Errorf("missing jobId in payload")
 	}
+	// ワーカーの停止後に再配信されたタスクで、前回の実行でジョブが終了していれば実行も配送・通知もしない
+	if current, err := m.store.Get(ctx, payload.JobID); err == nil && current != nil && current.Status.Terminal() {
+		m.logf("skipping redelivered task for finished job=%s status=%s", payload.JobID, current.Status)
+		// 前回の実行が実行枠を解放する前に停止していた場合に限り、ここで解放する
+		if m.holdsTenantSlot(ctx, payload) {
+			m.releaseTenantSlot(context.WithoutCancel(ctx), payload)
+		}
+		return nil
+	}
+	// 再試行されない結果で終わったら、同じテナントで待っているジョブに実行枠を回す。
+	// 再試行されるエラーで戻る場合は、次の試行が使うため枠を保持したままにする
+	defer func() {
+		retried, _ := asynq.GetRetryCount(ctx)
+		maxRetry, _ := asynq.GetMaxRetry(ctx)
+		if !willRetry(err, retried, maxRetry) {
+			m.releaseTenantSlot(context.WithoutCancel(ctx), payload)
+		}
+	}()
+	// 処理中のパニックでジョブが running のまま残らないよう、失敗にしてから戻る
+	var jobOpts pdf.JobOptions
+	defer func() {
+		if r := recover(); r != nil {
+			err = m.recoverJob(ctx, payload, jobOpts, r, debug.Stack())
+		}
+	}()
 
-	if err := m.store.Upsert(ctx, &Record{
-		JobID:     payload.JobID,
-		Operation: string(payload.Operation),
-		Status:    StatusRunning,
+	// 失敗時はワークスペースごと削除されるため、通知先などのオプションは実行前に読み出しておく
+	jobOpts, err = m.pdfService.LoadJobOptions(payload.JobID)
+	if err != nil {
+		m.logf("failed to load job options job=%s: %v", payload.JobID, err)
+	}
+
+	running := &Record{
+		JobID:         payload.JobID,
+		Operation:     string(payload.Operation),
+		Tenant:        payload.Tenant,
+		Label:         jobOpts.Label,
+		ClientContext: jobOpts.ClientContext,
+		Status:        StatusRunning,
 		Progress: ProgressInfo{
-			Percent: 0,
-			Stage:   "load",
+			Percent: pdf.StageLoad.Percent(0),
+			Stage:   string(pdf.StageLoad),
 		},
-	}); err != nil {
+		StartedAt: time.Now().UTC(),
+	}
+	m.startQueueClock(ctx, payload, running)
+	if err := m.store.Upsert(ctx, running); err != nil {
 		return err
 	}
 
-	result, err := m.pdfService.RunJob(ctx, payload.JobID, func(stage string, percent int) {
+	meter, err := m.pdfService.StartUsage(payload.JobID)
+	if err != nil {
+		m.logf("failed to start usage metering job=%s: %v", payload.JobID, err)
+	}
+
+	result, err := m.pdfService.RunJob(ctx, payload.JobID, func(stage pdf.Stage, percent int) {
 		_ = m.store.UpdateProgress(ctx, payload.JobID, ProgressInfo{
-			Stage:   stage,
+			Stage:   string(stage),
 			Percent: percent,
 		})
 	})
+	// 完了済みの実行を返しただけの再配信は、前回の実行で計上済みのため二重に計上しない
+	if sample, ok := meter.Finish(result, err); ok && (result == nil || !result.Resumed) {
+		m.RecordUsage(tenant.WithID(ctx, payload.Tenant), sample)
+	}
 	if err != nil {
-		return m.failJobWithError(ctx, payload.JobID, err)
+		failErr := m.failJobWithError(ctx, payload.JobID, err)
+		m.notifyFailure(ctx, payload, jobOpts, err)
+		return failErr
+	}
+	if err := m.finishJob(ctx, payload, result, jobOpts); err != nil {
+		if errors.Is(err, ErrJobAlreadyDone) {
+			m.logf("job already done, skipping notification job=%s", payload.JobID)
+			return nil
+		}
+		return err
 	}
-	return m.finishJob(ctx, payload.JobID, result)
+	m.notifySuccess(ctx, payload, jobOpts, result)
+	return nil
 }
 
-func (m *Manager) finishJob(ctx context.Context, jobID string, result *pdf.Result) error {
+// startQueueClock は処理を始めるジョブの記録へキュー待ちの開始時刻を引き継ぎ、目標時間を超えていれば slaBreached を付けます。
+// 再試行の場合は最初の投入からの待ち時間で判定し、超過は一度だけ数えます。
+func (m *Manager) startQueueClock(ctx context.Context, payload TaskPayload, running *Record) {
+	previous, err := m.store.Get(ctx, payload.JobID)
+	if err != nil {
+		m.logf("failed to load queued record job=%s: %v", payload.JobID, err)
+	}
+	if previous == nil {
+		return
+	}
+	running.QueuedAt = previous.QueuedAt
+	if !previous.StartedAt.IsZero() {
+		running.StartedAt = previous.StartedAt
+	}
+	if previous.SLABreached {
+		running.SLABreached = true
+		return
+	}
+	sla := m.queueSLA()
+	if waited := running.QueuedFor(running.StartedAt); sla > 0 && waited > sla {
+		running.SLABreached = true
+		metrics.QueueSLABreaches.Add(string(payload.Operation), 1)
+		m.logf("queue SLA breached job=%s operation=%s tenant=%s waited=%s sla=%s", payload.JobID, payload.Operation, payload.Tenant, waited.Round(time.Second), sla)
+	}
+}
+
+func (m *Manager) finishJob(ctx context.Context, payload TaskPayload, result *pdf.Result, jobOpts pdf.JobOptions) error {
 	if result == nil {
 		return fmt.Errorf("result is nil")
 	}
+	jobID := payload.JobID
 	downloadURL := m.buildDownloadURL(result)
-	if err := m.store.MarkDone(ctx, jobID, downloadURL, result.Meta); err != nil {
+	artifacts := make([]ArtifactInfo, len(result.Artifacts))
+	for i, a := range result.Artifacts {
+		artifacts[i] = ArtifactInfo{
+			Name:        a.Name,
+			Kind:        a.Kind,
+			Size:        a.Size,
+			SHA256:      a.SHA256,
+			DownloadURL: downloadURL,
+		}
+		if i > 0 {
+			artifacts[i].DownloadURL = m.buildArtifactURL(result.JobID, a.Name)
+		}
+	}
+	var inputs []InputInfo
+	if jobOpts.KeepInputs {
+		retained, err := m.pdfService.RetainedInputs(jobID)
+		if err != nil {
+			m.logf("failed to list retained inputs job=%s: %v", jobID, err)
+		}
+		for _, in := range retained {
+			inputs = append(inputs, InputInfo{
+				Name:        in.Name,
+				Size:        in.Size,
+				DownloadURL: fmt.Sprintf("/api/jobs/%s/inputs/%s", jobID, url.PathEscape(in.Name)),
+			})
+		}
+	}
+	if err := m.store.MarkDone(ctx, jobID, downloadURL, artifacts, inputs, result.JobMeta()); err != nil {
 		return err
 	}
+	if jobOpts.Delivery != nil {
+		m.deliver(ctx, payload, result, *jobOpts.Delivery)
+	}
 	return nil
 }
 
+// deliver はジョブ完了後に成果物を配送し、結果をジョブ情報へ記録します。
+// 配送に失敗してもジョブ自体は成功のまま扱い、ダウンロードURLからの取得は可能です。
+func (m *Manager) deliver(ctx context.Context, payload TaskPayload, result *pdf.Result, target pdf.DeliveryTarget) {
+	jobID := payload.JobID
+	req := &delivery.Request{
+		Tenant: tenant.Normalize(payload.Tenant),
+		Result: result,
+		Target: target,
+	}
+	req.DownloadURL, req.ExpiresAt = m.sharedLink(jobID)
+
+	info := &DeliveryInfo{
+		Method: string(target.Method),
+		Status: DeliveryStatusSent,
+	}
+	if err := m.dispatcher.Deliver(ctx, req); err != nil {
+		m.logf("failed to deliver result job=%s method=%s: %v", jobID, target.Method, err)
+		info.Status = DeliveryStatusFailed
+		info.Error = deliveryErrorMessage(err)
+	}
+	info.UpdatedAt = time.Now().UTC()
+	if err := m.store.UpdateDelivery(ctx, jobID, info); err != nil {
+		m.logf("failed to record delivery job=%s: %v", jobID, err)
+	}
+}
+
+// deliveryErrorMessage は配送の失敗を利用者に返す決まった文言にします。
+// 原因のエラーには送信先のホストや応答の本文が含まれるため、ジョブ情報には載せずにログにだけ残します。
+func deliveryErrorMessage(err error) string {
+	switch {
+	case errors.Is(err, delivery.ErrNotConfigured):
+		return "配送方法が設定されていないか、指定された送信先は使えません。"
+	case errors.Is(err, delivery.ErrRecipientNotAllowed):
+		return "送信先のメールアドレスは許可されていません。"
+	case errors.Is(err, delivery.ErrObjectExists):
+		return "保存先に同じ名前のファイルがすでにあります。"
+	case errors.Is(err, connectors.ErrNotConnected):
+		return "連携先に接続していないか、接続の有効期限が切れています。"
+	case errors.Is(err, connectors.ErrFileNotFound):
+		return "保存先のフォルダが見つからないか、アクセスが許可されていません。"
+	default:
+		return "成果物を配送できませんでした。"
+	}
+}
+
+// notifySuccess は完了通知をチャットへ投稿します。通知の失敗はジョブ結果に影響させません。
+func (m *Manager) notifySuccess(ctx context.Context, payload TaskPayload, jobOpts pdf.JobOptions, result *pdf.Result) {
+	if !m.notifier.Enabled(jobOpts.NotifyWebhook) {
+		return
+	}
+	link, _ := m.sharedLink(payload.JobID)
+	ev := notify.Event{
+		JobID:         payload.JobID,
+		Operation:     string(payload.Operation),
+		Label:         jobOpts.Label,
+		ClientContext: jobOpts.ClientContext,
+		Succeeded:     true,
+		Result:        result,
+		DownloadURL:   link,
+	}
+	if err := m.notifier.Notify(ctx, jobOpts.NotifyWebhook, ev); err != nil {
+		m.logf("failed to post job notification job=%s: %v", payload.JobID, err)
+	}
+}
+
+// notifyFailure は失敗通知をチャットへ投稿します。
+func (m *Manager) notifyFailure(ctx context.Context, payload TaskPayload, jobOpts pdf.JobOptions, jobErr error) {
+	if !m.notifier.Enabled(jobOpts.NotifyWebhook) {
+		return
+	}
+	ev := notify.Event{
+		JobID:         payload.JobID,
+		Operation:     string(payload.Operation),
+		Label:         jobOpts.Label,
+		ClientContext: jobOpts.ClientContext,
+	}
+	ev.ErrorCode, ev.ErrorText = pdf.UserMessage(jobErr)
+	if err := m.notifier.Notify(ctx, jobOpts.NotifyWebhook, ev); err != nil {
+		m.logf("failed to post job notification job=%s: %v", payload.JobID, err)
+	}
+}
+
+// sharedLink はログイン不要で成果物を取得できる署名付きURLと有効期限を返します。
+// 署名鍵が未設定の場合は空文字を返します。
+func (m *Manager) sharedLink(jobID string) (string, time.Time) {
+	if m.signer == nil {
+		return "", time.Time{}
+	}
+	expiresAt := time.Now().Add(m.jobTTL())
+	return m.signer.SignedURL(m.cfg.PublicBaseURL, jobID, expiresAt), expiresAt
+}
+
+// VerifySharedLink は共有ダウンロードリンクの署名を検証します。
+func (m *Manager) VerifySharedLink(jobID, expires, signature string) error {
+	if m.signer == nil {
+		return ErrLinkInvalid
+	}
+	return m.signer.Verify(jobID, expires, signature)
+}
+
+func (m *Manager) jobTTL() time.Duration {
+	minutes := m.cfg.JobExpireMinutes
+	if minutes <= 0 {
+		minutes = 10
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+func (m *Manager) logf(format string, args ...any) {
+	if m.logger != nil {
+		m.logger.Printf(format, args...)
+		return
+	}
+	log.Printf(format, args...)
+}
+
 func (m *Manager) failJob(ctx context.Context, jobID, code, message string) error {
 	if err := m.store.MarkFailed(ctx, jobID, &ErrorInfo{
 		Code:    code,
@@ -202,12 +568,44 @@ func (m *Manager) failJob(ctx context.Context, jobID, code, message string) erro
 	return nil
 }
 
+// failJobWithError はジョブを失敗にします。ジョブ情報には利用者向けの文言だけを保存し、原因はログに出します。
 func (m *Manager) failJobWithError(ctx context.Context, jobID string, err error) error {
-	var apiErr *pdf.Error
-	if errors.As(err, &apiErr) {
-		return m.failJob(ctx, jobID, apiErr.Code, apiErr.Message)
+	m.logf("job failed job=%s: %v", jobID, err)
+	code, message := pdf.UserMessage(err)
+	return m.failJob(ctx, jobID, code, message)
+}
+
+// recoverJob はジョブの処理中に発生したパニックの後始末をします。
+// ジョブを INTERNAL_ERROR で失敗にし、作業領域を削除し、スタックをエラー報告に送ります。失敗の通知は通常の失敗と同じです。
+// 同じ入力で再実行しても再びパニックする可能性が高いため、Asynq には再試行しないエラーを返します。
+func (m *Manager) recoverJob(ctx context.Context, payload TaskPayload, jobOpts pdf.JobOptions, recovered any, stack []byte) error {
+	ctx = context.WithoutCancel(ctx)
+	panicErr := fmt.Errorf("panic: %v", recovered)
+	m.logf("job panicked job=%s operation=%s: %v\n%s", payload.JobID, payload.Operation, recovered, stack)
+	metrics.JobPanics.Add(string(payload.Operation), 1)
+
+	if err := m.reporter.Report(ctx, errreport.Event{
+		Message: panicErr.Error(),
+		Stack:   stack,
+		Labels: map[string]string{
+			"jobId":     payload.JobID,
+			"operation": string(payload.Operation),
+			"tenant":    payload.Tenant,
+		},
+	}); err != nil {
+		m.logf("failed to report panic job=%s: %v", payload.JobID, err)
 	}
-	return m.failJob(ctx, jobID, "INTERNAL_ERROR", err.Error())
+	if err := m.pdfService.DiscardWorkspace(payload.JobID); err != nil {
+		m.logf("failed to remove workspace after panic job=%s: %v", payload.JobID, err)
+	}
+	// パニックの内容は内部の詳細のため、利用者には INTERNAL_ERROR と汎用の文言だけを返す
+	code, message := pdf.UserMessage(panicErr)
+	failErr := m.failJob(ctx, payload.JobID, code, message)
+	m.notifyFailure(ctx, payload, jobOpts, panicErr)
+	if failErr != nil {
+		return fmt.Errorf("job %s: %w (ジョブの失敗の記録にも失敗しました: %v)", payload.JobID, panicErr, failErr)
+	}
+	return fmt.Errorf("job %s: %w: %w", payload.JobID, panicErr, asynq.SkipRetry)
 }
 
 func (m *Manager) buildDownloadURL(result *pdf.Result) string {
@@ -217,3 +615,12 @@ func (m *Manager) buildDownloadURL(result *pdf.Result) string {
 	}
 	return fmt.Sprintf("%s/%s/%s", strings.TrimRight(base, "/"), result.JobID, url.PathEscape(result.OutputFilename))
 }
+
+// buildArtifactURL は主成果物以外の成果物のダウンロードURLを組み立てます。
+func (m *Manager) buildArtifactURL(jobID, name string) string {
+	base := m.cfg.JobResultBaseURL
+	if base == "" {
+		return fmt.Sprintf("/api/jobs/%s/download?artifact=%s", jobID, url.QueryEscape(name))
+	}
+	return fmt.Sprintf("%s/%s/%s", strings.TrimRight(base, "/"), jobID, url.PathEscape(name))
+}
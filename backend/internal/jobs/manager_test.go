@@ -0,0 +1,162 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yourusername/paper-forge/internal/config"
+	"github.com/yourusername/paper-forge/internal/connectors"
+	"github.com/yourusername/paper-forge/internal/delivery"
+	"github.com/yourusername/paper-forge/internal/notify"
+	"github.com/yourusername/paper-forge/internal/pdf"
+)
+
+func TestRecordQueuedFor(t *testing.T) {
+	queuedAt := time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+	now := queuedAt.Add(90 * time.Second)
+
+	waiting := &Record{Status: StatusQueued, QueuedAt: queuedAt}
+	if got := waiting.QueuedFor(now); got != 90*time.Second {
+		t.Fatalf("queued jobs should report the wait so far, got %s", got)
+	}
+	started := &Record{Status: StatusSucceeded, QueuedAt: queuedAt, StartedAt: queuedAt.Add(30 * time.Second)}
+	if got := started.QueuedFor(now); got != 30*time.Second {
+		t.Fatalf("started jobs should report the wait until the start, got %s", got)
+	}
+	if got := (&Record{Status: StatusQueued}).QueuedFor(now); got != 0 {
+		t.Fatalf("records without an enqueue time should report zero, got %s", got)
+	}
+}
+
+func TestFlagQueueSLA(t *testing.T) {
+	m := &Manager{cfg: &config.Config{JobQueueSLASeconds: 60}}
+	late := m.flagQueueSLA(&Record{Status: StatusQueued, QueuedAt: time.Now().Add(-2 * time.Minute)})
+	if !late.SLABreached {
+		t.Fatal("jobs queued beyond the SLA should be flagged")
+	}
+	if fresh := m.flagQueueSLA(&Record{Status: StatusQueued, QueuedAt: time.Now()}); fresh.SLABreached {
+		t.Fatal("jobs within the SLA should not be flagged")
+	}
+
+	disabled := &Manager{cfg: &config.Config{}}
+	if record := disabled.flagQueueSLA(&Record{Status: StatusQueued, QueuedAt: time.Now().Add(-time.Hour)}); record.SLABreached {
+		t.Fatal("the SLA check should be off when JOB_QUEUE_SLA_SECONDS is 0")
+	}
+}
+
+func TestNotifyFailurePostsUserFacingError(t *testing.T) {
+	var posted []string
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Text string `json:"text"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		posted = append(posted, r.URL.Path+"|"+payload.Text)
+	}))
+	defer server.Close()
+	// Notifier は既定のトランスポートを使うため、テスト用サーバーの証明書を信頼させる
+	defaultTransport := http.DefaultTransport
+	http.DefaultTransport = server.Client().Transport
+	t.Cleanup(func() { http.DefaultTransport = defaultTransport })
+
+	tests := []struct {
+		name       string
+		defaultURL string
+		override   string
+		jobErr     error
+		wantPath   string
+		want       []string
+		notWant    []string
+	}{
+		{
+			name:       "API errors keep their code and message",
+			defaultURL: server.URL + "/default",
+			jobErr:     &pdf.Error{Code: "INVALID_INPUT", Message: "PDFが壊れています。", Err: errors.New("xref at /tmp/jobs/abc/in.pdf")},
+			wantPath:   "/default",
+			want:       []string{"merge ジョブが失敗しました", "ジョブID: job-1", "ラベル: 月次", "エラー: INVALID_INPUT PDFが壊れています。"},
+			notWant:    []string{"/tmp/jobs", "xref"},
+		},
+		{
+			name:       "internal causes are not posted",
+			defaultURL: server.URL + "/default",
+			override:   server.URL + "/override",
+			jobErr:     errors.New("redis: connection refused"),
+			wantPath:   "/override",
+			want:       []string{"エラー: INTERNAL_ERROR サーバー内部でエラーが発生しました。"},
+			notWant:    []string{"redis"},
+		},
+		{
+			name:   "nothing is posted without a webhook",
+			jobErr: errors.New("boom"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			posted = nil
+			cfg := &config.Config{NotifyWebhookURL: tt.defaultURL, NotifyWebhookAllowedHosts: "127.0.0.1"}
+			m := &Manager{cfg: cfg, notifier: notify.New(cfg), logger: log.New(io.Discard, "", 0)}
+
+			m.notifyFailure(context.Background(), TaskPayload{JobID: "job-1", Operation: pdf.OperationMerge}, pdf.JobOptions{Label: "月次", NotifyWebhook: tt.override}, tt.jobErr)
+
+			if tt.wantPath == "" {
+				if len(posted) != 0 {
+					t.Fatalf("expected no posts, got %q", posted)
+				}
+				return
+			}
+			if len(posted) != 1 || !strings.HasPrefix(posted[0], tt.wantPath+"|") {
+				t.Fatalf("expected one post to %s, got %q", tt.wantPath, posted)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(posted[0], want) {
+					t.Fatalf("post should contain %q: %s", want, posted[0])
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(posted[0], notWant) {
+					t.Fatalf("post should not contain %q: %s", notWant, posted[0])
+				}
+			}
+		})
+	}
+}
+
+func TestStatusTerminal(t *testing.T) {
+	for status, want := range map[Status]bool{
+		StatusQueued:    false,
+		StatusRunning:   false,
+		StatusSucceeded: true,
+		StatusFailed:    true,
+	} {
+		if got := status.Terminal(); got != want {
+			t.Errorf("%s.Terminal() = %v, want %v", status, got, want)
+		}
+	}
+}
+
+func TestDeliveryErrorMessageHidesCause(t *testing.T) {
+	cases := []struct {
+		err  error
+		want string
+	}{
+		{fmt.Errorf("%w: upload host internal.example is not allowed", delivery.ErrNotConfigured), "配送方法が設定されていないか、指定された送信先は使えません。"},
+		{delivery.ErrRecipientNotAllowed, "送信先のメールアドレスは許可されていません。"},
+		{fmt.Errorf("%w: tenants/sales/report.pdf", delivery.ErrObjectExists), "保存先に同じ名前のファイルがすでにあります。"},
+		{fmt.Errorf("%w: gdrive (token expired)", connectors.ErrNotConnected), "連携先に接続していないか、接続の有効期限が切れています。"},
+		{errors.New("sftp: dial tcp 10.0.0.5:22: connection refused"), "成果物を配送できませんでした。"},
+	}
+	for _, tc := range cases {
+		if got := deliveryErrorMessage(tc.err); got != tc.want {
+			t.Fatalf("%v: got %q, want %q", tc.err, got, tc.want)
+		}
+	}
+}
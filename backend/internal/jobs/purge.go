@@ -0,0 +1,214 @@
+package jobs
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/yourusername/paper-forge/internal/pdf"
+	"github.com/yourusername/paper-forge/internal/tenant"
+)
+
+// ErrJobOnHold はリーガルホールド中のため削除できないジョブを表します。
+var ErrJobOnHold = errors.New("job is under legal hold")
+
+// PurgeReceipt はジョブの即時削除の証跡です。削除したデータごとにサイズとハッシュ、削除時刻を記録します。
+type PurgeReceipt struct {
+	JobID    string           `json:"jobId"`
+	PurgedAt time.Time        `json:"purgedAt"`
+	Items    []pdf.PurgedItem `json:"items"`
+	// Retained は削除しなかったデータです（ジョブを特定できない利用量の集計など）。
+	Retained []RetainedItem `json:"retained"`
+	// Verified は削除後にワークスペースとジョブ情報が残っていないことを確認できたかを表します。
+	Verified bool `json:"verified"`
+}
+
+// RetainedItem は削除の対象にしなかったデータと、その理由です。
+type RetainedItem struct {
+	Kind   string `json:"kind"` // usage
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// retainedUsageReason は利用量の集計を削除しない理由です。
+// 集計はテナント・月ごとの件数とサイズの合計のみで、ジョブや入力の内容・ファイル名を含みません。
+const retainedUsageReason = "aggregate_only"
+
+// SkippedJob は一括削除で削除しなかったジョブと、その理由です。
+type SkippedJob struct {
+	JobID  string `json:"jobId"`
+	Reason string `json:"reason"`
+}
+
+// UserPurgeReceipt は利用者単位の一括削除の証跡です。
+type UserPurgeReceipt struct {
+	Username string         `json:"username"`
+	PurgedAt time.Time      `json:"purgedAt"`
+	Jobs     []PurgeReceipt `json:"jobs"`
+	// Uploads と Cache はジョブに紐付かない分割アップロードと結果キャッシュの削除記録です。
+	Uploads []pdf.PurgedItem `json:"uploads"`
+	Cache   []pdf.PurgedItem `json:"cache"`
+	// Index はジョブの削除後に残っていた重複判定の索引の削除記録です。
+	Index []pdf.PurgedItem `json:"index"`
+	// Connections はトークンを破棄したクラウドドライブ連携の接続数です（API を提供するプロセスのメモリ上の接続）。
+	Connections int `json:"connections"`
+	// Retained は削除しなかったデータです。
+	Retained []RetainedItem `json:"retained"`
+	// Skipped はリーガルホールド中・処理中のため削除しなかったジョブです。
+	Skipped []SkippedJob `json:"skipped"`
+}
+
+// PurgeJob はジョブの入力・成果物・マニフェスト・ジョブ情報と重複判定の索引を即時に削除し、削除の証跡を返します。
+// 処理中のジョブ（ErrJobNotFinished）とリーガルホールド中のジョブ（ErrJobOnHold）は削除しません。
+func (m *Manager) PurgeJob(ctx context.Context, jobID string) (*PurgeReceipt, error) {
+	record, err := m.store.Get(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	if record != nil {
+		if !record.Status.Terminal() {
+			return nil, ErrJobNotFinished
+		}
+		if record.Hold != nil {
+			return nil, ErrJobOnHold
+		}
+	}
+
+	// 重複判定の索引のキーはマニフェストから求めるため、ワークスペースを削除する前に控えておく
+	var indexKey string
+	if key, ok := m.pdfService.DedupKey(jobID); ok {
+		owner := ""
+		if record != nil {
+			owner = record.Tenant
+		} else if t, err := m.pdfService.JobTenant(jobID); err == nil {
+			owner = t
+		}
+		indexKey = dedupIndexKey(owner, key)
+	}
+
+	items, err := m.pdfService.PurgeWorkspace(jobID)
+	if err != nil {
+		if errors.Is(err, pdf.ErrWorkspaceHeld) {
+			return nil, ErrJobOnHold
+		}
+		return nil, err
+	}
+	data, err := m.store.Delete(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	if data != nil {
+		sum := sha256.Sum256(data)
+		items = append(items, pdf.PurgedItem{
+			Kind:      "record",
+			Name:      jobKey(jobID),
+			Size:      int64(len(data)),
+			SHA256:    hex.EncodeToString(sum[:]),
+			DeletedAt: now,
+		})
+	}
+	if indexKey != "" {
+		released, err := m.store.ReleaseDedup(ctx, indexKey, jobID)
+		if err != nil {
+			return nil, err
+		}
+		if released {
+			items = append(items, dedupPurgedItem(indexKey, jobID, now))
+		}
+	}
+	if record == nil && len(items) == 0 {
+		return nil, ErrJobNotFound
+	}
+	retained := []RetainedItem{}
+	if record != nil {
+		// 利用量はジョブの終了時に、その月・テナントの集計へ加算している
+		month := record.UpdatedAt.UTC().Format(UsageMonthLayout)
+		retained = append(retained, RetainedItem{Kind: "usage", Name: usageKey(month, tenant.Normalize(record.Tenant)), Reason: retainedUsageReason})
+	}
+
+	remaining, err := m.store.Get(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	m.logf("purged job=%s items=%d", jobID, len(items))
+	return &PurgeReceipt{
+		JobID:    jobID,
+		PurgedAt: now,
+		Items:    items,
+		Retained: retained,
+		Verified: remaining == nil,
+	}, nil
+}
+
+// PurgeAll はこのデプロイに保存されている全ジョブと分割アップロード・結果キャッシュ・重複判定の索引を即時に削除します。
+// クラウドドライブ連携のトークンは API のプロセスが保持するため、呼び出し元で破棄して Connections に記録します。
+// ログインはデプロイ単位の単一アカウントのため、利用者単位の削除は保存データ全体の削除になります。
+func (m *Manager) PurgeAll(ctx context.Context, username string) (*UserPurgeReceipt, error) {
+	ids, err := m.store.JobIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	receipt := &UserPurgeReceipt{
+		Username: username,
+		Jobs:     []PurgeReceipt{},
+		Skipped:  []SkippedJob{},
+	}
+	for _, id := range ids {
+		jobReceipt, err := m.PurgeJob(ctx, id)
+		switch {
+		case errors.Is(err, ErrJobOnHold):
+			receipt.Skipped = append(receipt.Skipped, SkippedJob{JobID: id, Reason: "legal_hold"})
+		case errors.Is(err, ErrJobNotFinished):
+			receipt.Skipped = append(receipt.Skipped, SkippedJob{JobID: id, Reason: "running"})
+		case errors.Is(err, ErrJobNotFound):
+			// 一覧の取得後に期限切れになったジョブ
+		case err != nil:
+			return nil, err
+		default:
+			receipt.Jobs = append(receipt.Jobs, *jobReceipt)
+		}
+	}
+
+	receipt.Uploads = m.pdfService.PurgeUploads()
+	receipt.Cache, err = m.pdfService.PurgeResultCache()
+	if err != nil {
+		return nil, err
+	}
+	// ワークスペースが期限切れで先に消えたジョブの索引は PurgeJob で求められないため、残りをまとめて削除する
+	index, err := m.store.PurgeDedupIndex(ctx)
+	if err != nil {
+		return nil, err
+	}
+	receipt.PurgedAt = time.Now().UTC()
+	receipt.Index = make([]pdf.PurgedItem, 0, len(index))
+	for key, jobID := range index {
+		receipt.Index = append(receipt.Index, dedupPurgedItem(key, jobID, receipt.PurgedAt))
+	}
+	sort.Slice(receipt.Index, func(i, j int) bool { return receipt.Index[i].Name < receipt.Index[j].Name })
+	usageKeys, err := m.store.UsageKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	receipt.Retained = make([]RetainedItem, 0, len(usageKeys))
+	for _, key := range usageKeys {
+		receipt.Retained = append(receipt.Retained, RetainedItem{Kind: "usage", Name: key, Reason: retainedUsageReason})
+	}
+	m.logf("purged all jobs for user=%s jobs=%d skipped=%d", username, len(receipt.Jobs), len(receipt.Skipped))
+	return receipt, nil
+}
+
+// dedupPurgedItem は削除した重複判定の索引の記録です。索引の値（ジョブID）のサイズとハッシュを記録します。
+func dedupPurgedItem(indexKey, jobID string, deletedAt time.Time) pdf.PurgedItem {
+	sum := sha256.Sum256([]byte(jobID))
+	return pdf.PurgedItem{
+		Kind:      "index",
+		Name:      indexKey,
+		Size:      int64(len(jobID)),
+		SHA256:    hex.EncodeToString(sum[:]),
+		DeletedAt: deletedAt,
+	}
+}
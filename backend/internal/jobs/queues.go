@@ -0,0 +1,85 @@
+package jobs
+
+import (
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/yourusername/paper-forge/internal/tenant"
+)
+
+const (
+	// tenantQueuePrefix はテナント専用の asynq のキュー名の接頭辞です（pdf-<tenant>）。
+	tenantQueuePrefix = pdfQueue + "-"
+	// sharedQueueWeight は TENANT_QUEUE_WEIGHTS に無いテナントが共有する pdfQueue の重みです。
+	sharedQueueWeight = 1
+	// maxTenantQueueWeight は指定できる重みの上限です。
+	maxTenantQueueWeight = 100
+)
+
+// tenantQueues は TENANT_QUEUE_WEIGHTS に指定したテナントごとのキューと重みです。
+// ワーカーは重みに比例した確率で各キューからジョブを取り出すため、処理の混み合うテナントがあっても
+// 各テナントの処理量はおおよそ重みの比になります。指定の無いテナントは共有の pdfQueue を使います。
+type tenantQueues struct {
+	weights map[string]int // テナントID -> 重み
+}
+
+// newTenantQueues は "テナントID=重み" のカンマ区切り（例: "sales=3,legal=1"）からキューの設定を作成します。
+// 形式の誤った指定はログに出して無視します。
+func newTenantQueues(raw string) tenantQueues {
+	weights, invalid := parseTenantQueueWeights(raw)
+	if len(invalid) > 0 {
+		log.Printf("TENANT_QUEUE_WEIGHTS contains invalid entries (ignored): %s", strings.Join(invalid, ", "))
+	}
+	return tenantQueues{weights: weights}
+}
+
+func parseTenantQueueWeights(raw string) (weights map[string]int, invalid []string) {
+	weights = make(map[string]int)
+	for _, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		id, value, ok := strings.Cut(entry, "=")
+		id = strings.TrimSpace(id)
+		weight, err := strconv.Atoi(strings.TrimSpace(value))
+		if !ok || !tenant.Valid(id) || err != nil || weight < 1 || weight > maxTenantQueueWeight {
+			invalid = append(invalid, entry)
+			continue
+		}
+		weights[id] = weight
+	}
+	return weights, invalid
+}
+
+// queueFor はテナント tenantID のジョブを投入するキュー名を返します。
+func (q tenantQueues) queueFor(tenantID string) string {
+	id := tenant.Normalize(tenantID)
+	if _, ok := q.weights[id]; ok {
+		return tenantQueuePrefix + id
+	}
+	return pdfQueue
+}
+
+// serverQueues は asynq サーバーがポーリングするキューと重みを返します。
+// 重みの変更前に投入されたジョブも処理できるよう、共有の pdfQueue は常に含めます。
+func (q tenantQueues) serverQueues() map[string]int {
+	queues := map[string]int{pdfQueue: sharedQueueWeight}
+	for id, weight := range q.weights {
+		queues[tenantQueuePrefix+id] = weight
+	}
+	return queues
+}
+
+// names はポーリングするキュー名を整列して返します。
+func (q tenantQueues) names() []string {
+	queues := q.serverQueues()
+	names := make([]string, 0, len(queues))
+	for name := range queues {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
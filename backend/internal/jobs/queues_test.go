@@ -0,0 +1,36 @@
+package jobs
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTenantQueuesWeights(t *testing.T) {
+	weights, invalid := parseTenantQueueWeights(" sales=3, legal = 1,Bad=2,ops=0,hr=x,finance,default=2 ")
+	if want := map[string]int{"sales": 3, "legal": 1, "default": 2}; !reflect.DeepEqual(weights, want) {
+		t.Fatalf("unexpected weights: %v", weights)
+	}
+	if want := []string{"Bad=2", "ops=0", "hr=x", "finance"}; !reflect.DeepEqual(invalid, want) {
+		t.Fatalf("unexpected invalid entries: %v", invalid)
+	}
+
+	q := tenantQueues{weights: weights}
+	if got := q.queueFor("sales"); got != "pdf-sales" {
+		t.Fatalf("unexpected queue for a weighted tenant: %q", got)
+	}
+	if got := q.queueFor(""); got != "pdf-default" {
+		t.Fatalf("empty tenant should use the default tenant's queue: %q", got)
+	}
+	if got := q.queueFor("marketing"); got != pdfQueue {
+		t.Fatalf("tenants without a weight should share %q: %q", pdfQueue, got)
+	}
+	want := map[string]int{pdfQueue: sharedQueueWeight, "pdf-sales": 3, "pdf-legal": 1, "pdf-default": 2}
+	if got := q.serverQueues(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected server queues: %v", got)
+	}
+
+	// 未設定の場合は従来どおり共有のキューだけを使う
+	if got := newTenantQueues("").names(); !reflect.DeepEqual(got, []string{pdfQueue}) {
+		t.Fatalf("unexpected queues without weights: %v", got)
+	}
+}
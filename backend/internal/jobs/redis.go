@@ -0,0 +1,206 @@
+package jobs
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/yourusername/paper-forge/internal/config"
+)
+
+const (
+	// metadataTokenURL は Cloud Run / GCE のメタデータサーバーからサービスアカウントのアクセストークンを取得するURLです。
+	metadataTokenURL = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+	// iamTokenRefreshMargin は期限切れ前にアクセストークンを取り直す余裕です。
+	iamTokenRefreshMargin = 5 * time.Minute
+	// iamConnMaxLifetime は IAM 認証の接続を張り直す間隔です。トークン（1時間）が切れる前に新しいトークンで認証し直します。
+	iamConnMaxLifetime = 45 * time.Minute
+)
+
+// redisConn は Store と asynq で共通の Redis 接続設定です。
+// asynq.RedisConnOpt を実装しており、asynq にも同じ TLS・認証の設定でクライアントを渡します。
+type redisConn struct {
+	mode  string
+	addrs []string
+	// single は standalone の場合に QUEUE_REDIS_URL を解析した設定です。
+	single *redis.Options
+	cfg    *config.Config
+	tls    *tls.Config
+	// token は QUEUE_REDIS_IAM_AUTH が有効な場合にパスワードの代わりに使うアクセストークンです。
+	token *iamTokenSource
+}
+
+// NewRedisClient は QUEUE_REDIS_* の設定から Store 用の Redis クライアントを作成します。
+// QUEUE_REDIS_MODE が sentinel / cluster の場合は QUEUE_REDIS_URL の代わりに QUEUE_REDIS_ADDRS 等を使います。
+func NewRedisClient(cfg *config.Config) (redis.UniversalClient, error) {
+	conn, err := newRedisConn(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return conn.newClient(), nil
+}
+
+func newRedisConn(cfg *config.Config) (*redisConn, error) {
+	addrs, err := cfg.QueueRedisAddrList()
+	if err != nil {
+		return nil, err
+	}
+	conn := &redisConn{mode: cfg.QueueRedisMode, addrs: addrs, cfg: cfg}
+	if cfg.QueueRedisTLS {
+		conn.tls = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	if conn.mode == "" || conn.mode == config.RedisModeStandalone {
+		opt, err := redis.ParseURL(cfg.QueueRedisURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse redis url: %w", err)
+		}
+		if opt.TLSConfig != nil {
+			// rediss:// の場合は接続先のホスト名を検証する設定になっている
+			conn.tls = opt.TLSConfig
+		}
+		conn.single = opt
+	}
+
+	if caFile := cfg.QueueRedisCAFile; caFile != "" {
+		if conn.tls == nil {
+			return nil, fmt.Errorf("QUEUE_REDIS_CA_FILE requires a rediss:// URL or QUEUE_REDIS_TLS=true")
+		}
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read QUEUE_REDIS_CA_FILE: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("QUEUE_REDIS_CA_FILE contains no PEM certificates: %s", caFile)
+		}
+		conn.tls.RootCAs = pool
+	}
+
+	if cfg.QueueRedisIAMAuth {
+		conn.token = newIAMTokenSource(metadataTokenURL)
+	}
+	return conn, nil
+}
+
+// MakeRedisClient は asynq.RedisConnOpt の実装です。
+func (c *redisConn) MakeRedisClient() interface{} {
+	return c.newClient()
+}
+
+func (c *redisConn) newClient() redis.UniversalClient {
+	switch c.mode {
+	case config.RedisModeSentinel:
+		return redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:       c.cfg.QueueRedisSentinelMaster,
+			SentinelAddrs:    c.addrs,
+			SentinelPassword: c.cfg.QueueRedisSentinelPassword,
+			Username:         c.cfg.QueueRedisUsername,
+			Password:         c.cfg.QueueRedisPassword,
+			DB:               c.cfg.QueueRedisDB,
+			TLSConfig:        c.tls,
+		})
+	case config.RedisModeCluster:
+		opt := &redis.ClusterOptions{
+			Addrs:     c.addrs,
+			Username:  c.cfg.QueueRedisUsername,
+			Password:  c.cfg.QueueRedisPassword,
+			TLSConfig: c.tls,
+		}
+		if c.token != nil {
+			opt.CredentialsProviderContext = c.credentials
+			opt.ConnMaxLifetime = iamConnMaxLifetime
+		}
+		return redis.NewClusterClient(opt)
+	default:
+		opt := *c.single
+		opt.TLSConfig = c.tls
+		if c.token != nil {
+			opt.CredentialsProviderContext = c.credentials
+			opt.ConnMaxLifetime = iamConnMaxLifetime
+		}
+		return redis.NewClient(&opt)
+	}
+}
+
+// credentials は IAM 認証で使うユーザー名とパスワード（アクセストークン）を返します。
+func (c *redisConn) credentials(ctx context.Context) (string, string, error) {
+	token, err := c.token.Token(ctx)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to fetch redis IAM token: %w", err)
+	}
+	return "", token, nil
+}
+
+// iamTokenSource はメタデータサーバーから取得したアクセストークンを期限まで使い回します。
+type iamTokenSource struct {
+	url    string
+	client *http.Client
+	now    func() time.Time
+
+	mu     sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+func newIAMTokenSource(url string) *iamTokenSource {
+	return &iamTokenSource{
+		url:    url,
+		client: &http.Client{Timeout: 10 * time.Second},
+		now:    time.Now,
+	}
+}
+
+// Token は有効なアクセストークンを返します。期限が近い場合は取り直します。
+func (s *iamTokenSource) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token != "" && s.now().Add(iamTokenRefreshMargin).Before(s.expiry) {
+		return s.token, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("metadata server responded with %d", resp.StatusCode)
+	}
+	var payload struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return "", fmt.Errorf("failed to parse access token: %w", err)
+	}
+	if payload.AccessToken == "" {
+		return "", fmt.Errorf("metadata server returned an empty access token")
+	}
+	s.token = payload.AccessToken
+	s.expiry = s.now().Add(time.Duration(payload.ExpiresIn) * time.Second)
+	return s.token, nil
+}
+
+// forEachNode は全データを持つノードごとに fn を呼び出します。
+// クラスタ構成ではキーが各マスターに分散しているため、SCAN 等はマスターごとに行う必要があります。
+func forEachNode(ctx context.Context, rdb redis.UniversalClient, fn func(ctx context.Context, client redis.Cmdable) error) error {
+	if cluster, ok := rdb.(*redis.ClusterClient); ok {
+		return cluster.ForEachMaster(ctx, func(ctx context.Context, client *redis.Client) error {
+			return fn(ctx, client)
+		})
+	}
+	return fn(ctx, rdb)
+}
@@ -0,0 +1,104 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/yourusername/paper-forge/internal/config"
+)
+
+func TestNewRedisClientModes(t *testing.T) {
+	cluster, err := NewRedisClient(&config.Config{
+		QueueRedisMode:  config.RedisModeCluster,
+		QueueRedisAddrs: "10.0.0.1:6379, 10.0.0.2:6379",
+		QueueRedisTLS:   true,
+	})
+	if err != nil {
+		t.Fatalf("cluster: %v", err)
+	}
+	defer cluster.Close()
+	if c, ok := cluster.(*redis.ClusterClient); !ok || len(c.Options().Addrs) != 2 || c.Options().TLSConfig == nil {
+		t.Fatalf("unexpected cluster client: %T", cluster)
+	}
+
+	sentinel, err := NewRedisClient(&config.Config{
+		QueueRedisMode:           config.RedisModeSentinel,
+		QueueRedisAddrs:          "10.0.0.1:26379",
+		QueueRedisSentinelMaster: "paperforge",
+		QueueRedisDB:             2,
+	})
+	if err != nil {
+		t.Fatalf("sentinel: %v", err)
+	}
+	defer sentinel.Close()
+	if c, ok := sentinel.(*redis.Client); !ok || c.Options().DB != 2 {
+		t.Fatalf("unexpected sentinel client: %T", sentinel)
+	}
+
+	invalid := []*config.Config{
+		{QueueRedisMode: config.RedisModeSentinel, QueueRedisAddrs: "10.0.0.1:26379"},
+		{QueueRedisMode: config.RedisModeCluster},
+		{QueueRedisMode: config.RedisModeCluster, QueueRedisAddrs: "10.0.0.1:6379", QueueRedisDB: 1},
+		{QueueRedisMode: "replica"},
+	}
+	for _, cfg := range invalid {
+		if _, err := NewRedisClient(cfg); err == nil {
+			t.Fatalf("expected error for %+v", cfg)
+		}
+	}
+}
+
+func TestNewRedisConnTLSOptions(t *testing.T) {
+	conn, err := newRedisConn(&config.Config{QueueRedisURL: "rediss://:secret@redis.internal:6378/1"})
+	if err != nil {
+		t.Fatalf("rediss: %v", err)
+	}
+	if conn.tls == nil || conn.tls.ServerName != "redis.internal" {
+		t.Fatalf("rediss:// should enable TLS with server name verification: %+v", conn.tls)
+	}
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(caFile, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatalf("failed to write CA file: %v", err)
+	}
+	if _, err := newRedisConn(&config.Config{QueueRedisURL: "redis://127.0.0.1:6379/0", QueueRedisCAFile: caFile}); err == nil {
+		t.Fatal("CA file without TLS should be rejected")
+	}
+	if _, err := newRedisConn(&config.Config{QueueRedisURL: "rediss://redis.internal:6378", QueueRedisCAFile: caFile}); err == nil {
+		t.Fatal("CA file without certificates should be rejected")
+	}
+}
+
+func TestIAMTokenSourceCachesUntilExpiry(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata-Flavor") != "Google" {
+			t.Errorf("missing Metadata-Flavor header")
+		}
+		calls++
+		fmt.Fprintf(w, `{"access_token":"token-%d","expires_in":3600}`, calls)
+	}))
+	defer srv.Close()
+
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	src := newIAMTokenSource(srv.URL)
+	src.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if token, err := src.Token(context.Background()); err != nil || token != "token-1" {
+			t.Fatalf("Token() = %q, %v", token, err)
+		}
+	}
+	now = now.Add(56 * time.Minute)
+	if token, err := src.Token(context.Background()); err != nil || token != "token-2" {
+		t.Fatalf("token should be refreshed before expiry: %q, %v", token, err)
+	}
+}
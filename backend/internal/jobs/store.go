@@ -3,24 +3,39 @@ package jobs
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/redis/go-redis/v9"
+
+	"github.com/yourusername/paper-forge/internal/pdf"
 )
 
 const (
 	jobKeyPrefix = "job:"
+
+	// changeIndexKey はステータスが変化したジョブを変更順に並べた sorted set です。
+	// score には changeSeqKey で採番した単調増加の連番を使い、カーソルとして公開します。
+	changeIndexKey = "jobs:changes"
+	changeSeqKey   = "jobs:changes:seq"
+	// changeIndexMax は変更インデックスに保持する最大件数です。
+	changeIndexMax = 10000
+
+	// jobEventsPrefix はジョブのステータス変化を通知する Pub/Sub チャネルの接頭辞です。
+	jobEventsPrefix = "job-events:"
 )
 
 // Store はジョブ状態を Redis に保存します。
 type Store struct {
-	rdb *redis.Client
+	rdb redis.UniversalClient
 	ttl time.Duration
 }
 
 // NewStore は Store を作成します。
-func NewStore(rdb *redis.Client, ttl time.Duration) *Store {
+func NewStore(rdb redis.UniversalClient, ttl time.Duration) *Store {
 	return &Store{
 		rdb: rdb,
 		ttl: ttl,
@@ -64,7 +79,114 @@ func (s *Store) Upsert(ctx context.Context, record *Record) error {
 	if err != nil {
 		return err
 	}
-	return s.rdb.Set(ctx, jobKey(record.JobID), payload, s.ttl).Err()
+	if err := s.rdb.Set(ctx, jobKey(record.JobID), payload, s.recordTTL(record)).Err(); err != nil {
+		return err
+	}
+	return s.recordChange(ctx, record.JobID)
+}
+
+// restore は別の Store から移したジョブ情報を日時を変えずに保存します。
+// 書き戻しを繰り返しても変更履歴が増え続けないよう、ステータスが変わった場合のみ記録します。
+func (s *Store) restore(ctx context.Context, record *Record) error {
+	previous, err := s.Get(ctx, record.JobID)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if err := s.rdb.Set(ctx, jobKey(record.JobID), payload, s.recordTTL(record)).Err(); err != nil {
+		return err
+	}
+	if previous == nil || previous.Status != record.Status {
+		return s.recordChange(ctx, record.JobID)
+	}
+	return nil
+}
+
+// Changes は cursor より後にステータスが変化したジョブを変更順に返します。
+// 戻り値の next は次回の cursor として利用でき、hasMore は続きがあるかを表します。
+func (s *Store) Changes(ctx context.Context, cursor int64, limit int) (records []*Record, next int64, hasMore bool, err error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	entries, err := s.rdb.ZRangeByScoreWithScores(ctx, changeIndexKey, &redis.ZRangeBy{
+		Min:   fmt.Sprintf("(%d", cursor),
+		Max:   "+inf",
+		Count: int64(limit + 1),
+	}).Result()
+	if err != nil {
+		return nil, cursor, false, err
+	}
+
+	if len(entries) > limit {
+		hasMore = true
+		entries = entries[:limit]
+	}
+
+	next = cursor
+	records = make([]*Record, 0, len(entries))
+	for _, entry := range entries {
+		next = int64(entry.Score)
+		jobID, ok := entry.Member.(string)
+		if !ok {
+			continue
+		}
+		record, err := s.Get(ctx, jobID)
+		if err != nil {
+			return nil, cursor, false, err
+		}
+		// TTL で失効済みのジョブはインデックスにだけ残っている場合がある
+		if record == nil {
+			continue
+		}
+		records = append(records, record)
+	}
+	return records, next, hasMore, nil
+}
+
+func (s *Store) recordChange(ctx context.Context, jobID string) error {
+	seq, err := s.rdb.Incr(ctx, changeSeqKey).Result()
+	if err != nil {
+		return err
+	}
+	pipe := s.rdb.TxPipeline()
+	pipe.ZAdd(ctx, changeIndexKey, redis.Z{Score: float64(seq), Member: jobID})
+	pipe.ZRemRangeByRank(ctx, changeIndexKey, 0, -changeIndexMax-1)
+	pipe.Publish(ctx, jobEventsChannel(jobID), seq)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// WaitForTerminal はジョブが終了状態になるか timeout が経過するまで待機し、最新のジョブ情報を返します。
+// ステータス変化は Pub/Sub で受け取るため、待機中に Redis をポーリングしません。
+func (s *Store) WaitForTerminal(ctx context.Context, jobID string, timeout time.Duration) (*Record, error) {
+	// 取りこぼしを防ぐため、現在の状態を読む前に購読を開始する
+	sub := s.rdb.Subscribe(ctx, jobEventsChannel(jobID))
+	defer sub.Close()
+	if _, err := sub.Receive(ctx); err != nil {
+		return nil, err
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	events := sub.Channel()
+
+	for {
+		record, err := s.Get(ctx, jobID)
+		if err != nil || record == nil || record.Status.Terminal() {
+			return record, err
+		}
+
+		select {
+		case <-events:
+		case <-timer.C:
+			return record, nil
+		case <-ctx.Done():
+			return record, nil
+		}
+	}
 }
 
 // UpdateProgress は進捗を更新します。
@@ -74,17 +196,34 @@ func (s *Store) UpdateProgress(ctx context.Context, jobID string, progress Progr
 	})
 }
 
+// ErrJobAlreadyDone は完了済みのジョブを再び完了にしようとしたことを表します（再配信されたタスクなど）。
+var ErrJobAlreadyDone = errors.New("job is already done")
+
 // MarkDone はジョブ完了時の情報を保存します。
-func (s *Store) MarkDone(ctx context.Context, jobID string, downloadURL string, meta any) error {
-	return s.updatePartial(ctx, jobID, func(record *Record) {
+// 終了済み（成功・失敗）のジョブは成果物の情報を書き換えずに ErrJobAlreadyDone を返します。呼び出し元は配送・通知を繰り返しません。
+// 状態の確認は書き込みと同じトランザクションで行うため、同時に再配信されたタスクが二重に完了にすることも、
+// 失敗として記録したジョブを後から成功に書き換えることもありません。
+// inputs は keepInputs=true で入力ファイルを保持している場合のみ指定します。
+func (s *Store) MarkDone(ctx context.Context, jobID string, downloadURL string, artifacts []ArtifactInfo, inputs []InputInfo, meta any) error {
+	return s.updateRecord(ctx, jobID, func(record *Record) error {
+		if record.Status.Terminal() {
+			return ErrJobAlreadyDone
+		}
 		record.Status = StatusSucceeded
 		record.Progress = ProgressInfo{
-			Percent: 100,
-			Stage:   "completed",
+			Percent: pdf.StageCompleted.Percent(1),
+			Stage:   string(pdf.StageCompleted),
 		}
 		record.DownloadURL = downloadURL
+		record.Artifacts = artifacts
+		if len(artifacts) > 0 {
+			record.OutputSize = artifacts[0].Size
+			record.OutputSHA256 = artifacts[0].SHA256
+		}
+		record.Inputs = inputs
 		record.Meta = meta
 		record.Error = nil
+		return nil
 	})
 }
 
@@ -98,36 +237,134 @@ func (s *Store) MarkFailed(ctx context.Context, jobID string, errInfo *ErrorInfo
 	})
 }
 
+// UpdateDelivery は成果物配送の結果を保存します。
+func (s *Store) UpdateDelivery(ctx context.Context, jobID string, info *DeliveryInfo) error {
+	return s.updatePartial(ctx, jobID, func(record *Record) {
+		record.Delivery = info
+	})
+}
+
+// Delete はジョブ情報を削除し、変更履歴からも取り除きます。削除前に保存されていた JSON を返します（無い場合は nil）。
+func (s *Store) Delete(ctx context.Context, jobID string) ([]byte, error) {
+	key := jobKey(jobID)
+	data, err := s.rdb.Get(ctx, key).Bytes()
+	if err == redis.Nil {
+		data = nil
+	} else if err != nil {
+		return nil, err
+	}
+	pipe := s.rdb.TxPipeline()
+	pipe.Del(ctx, key)
+	pipe.ZRem(ctx, changeIndexKey, jobID)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// JobIDs は保存されている全ジョブのIDを返します。
+func (s *Store) JobIDs(ctx context.Context) ([]string, error) {
+	var (
+		mu  sync.Mutex
+		ids []string
+	)
+	err := forEachNode(ctx, s.rdb, func(ctx context.Context, client redis.Cmdable) error {
+		iter := client.Scan(ctx, 0, jobKeyPrefix+"*", 500).Iterator()
+		for iter.Next(ctx) {
+			mu.Lock()
+			ids = append(ids, strings.TrimPrefix(iter.Val(), jobKeyPrefix))
+			mu.Unlock()
+		}
+		return iter.Err()
+	})
+	return ids, err
+}
+
+// SetHold はリーガルホールドを設定します。hold が nil の場合は解除します。
+// ホールド中のジョブ情報は有効期限を持たず、解除した時点から改めて TTL の間だけ保持します。
+// 一覧で状態の変化に気付けるよう、ステータスが変わらなくても変更履歴に記録します。
+func (s *Store) SetHold(ctx context.Context, jobID string, hold *HoldInfo) error {
+	err := s.updatePartial(ctx, jobID, func(record *Record) {
+		record.Hold = hold
+		if hold != nil {
+			record.ExpiresAt = time.Time{}
+		} else if s.ttl > 0 {
+			record.ExpiresAt = time.Now().UTC().Add(s.ttl)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return s.recordChange(ctx, jobID)
+}
+
+// recordTTL はジョブ情報の保存期間を返します。リーガルホールド中は期限を設けません（0）。
+func (s *Store) recordTTL(record *Record) time.Duration {
+	if record.Hold != nil {
+		return 0
+	}
+	return s.ttl
+}
+
 func (s *Store) updatePartial(ctx context.Context, jobID string, mutate func(*Record)) error {
+	return s.updateRecord(ctx, jobID, func(record *Record) error {
+		mutate(record)
+		return nil
+	})
+}
+
+// updateRecord はジョブ情報を WATCH したうえで読み取り、mutate で変更して書き戻します。
+// 読み取りから書き込みまでの間に他の更新があった場合は読み取りからやり直すため、mutate での状態の確認も更新と矛盾しません。
+// mutate がエラーを返した場合は書き込まずにそのエラーを返します。
+func (s *Store) updateRecord(ctx context.Context, jobID string, mutate func(*Record) error) error {
 	key := jobKey(jobID)
 	for {
-		tx := s.rdb.TxPipeline()
-		data, err := s.rdb.Get(ctx, key).Bytes()
-		if err != nil {
-			if err == redis.Nil {
-				return fmt.Errorf("job not found: %s", jobID)
+		var statusChanged bool
+		err := s.rdb.Watch(ctx, func(tx *redis.Tx) error {
+			data, err := tx.Get(ctx, key).Bytes()
+			if err != nil {
+				if err == redis.Nil {
+					return fmt.Errorf("job not found: %s", jobID)
+				}
+				return err
 			}
+			var record Record
+			if err := json.Unmarshal(data, &record); err != nil {
+				return err
+			}
+			previousStatus := record.Status
+			if err := mutate(&record); err != nil {
+				return err
+			}
+			record.UpdatedAt = time.Now().UTC()
+			payload, err := json.Marshal(&record)
+			if err != nil {
+				return err
+			}
+			_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+				pipe.Set(ctx, key, payload, s.recordTTL(&record))
+				return nil
+			})
+			statusChanged = record.Status != previousStatus
 			return err
+		}, key)
+		if err == redis.TxFailedErr {
+			continue
 		}
-		var record Record
-		if err := json.Unmarshal(data, &record); err != nil {
-			return err
-		}
-		mutate(&record)
-		record.UpdatedAt = time.Now().UTC()
-		payload, err := json.Marshal(&record)
 		if err != nil {
 			return err
 		}
-		tx.Set(ctx, key, payload, s.ttl)
-		_, err = tx.Exec(ctx)
-		if err == redis.TxFailedErr {
-			continue
+		if statusChanged {
+			return s.recordChange(ctx, jobID)
 		}
-		return err
+		return nil
 	}
 }
 
 func jobKey(id string) string {
 	return jobKeyPrefix + id
 }
+
+func jobEventsChannel(id string) string {
+	return jobEventsPrefix + id
+}
@@ -0,0 +1,74 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+
+	"github.com/yourusername/paper-forge/internal/tenant"
+)
+
+// ErrTenantQuotaExceeded はテナントのキュー待ち・処理中のジョブ数が TENANT_MAX_ACTIVE_JOBS に達したことを表します。
+var ErrTenantQuotaExceeded = errors.New("tenant active job quota exceeded")
+
+// VisibleTo はジョブ情報をテナント tenantID から参照できるかを返します。
+// テナント導入前のジョブ情報（tenant が空）は tenant.Default のものとして扱います。
+func (r *Record) VisibleTo(tenantID string) bool {
+	return r != nil && tenant.Normalize(r.Tenant) == tenant.Normalize(tenantID)
+}
+
+// JobVisible はジョブ jobID をテナント tenantID から参照できるかを返します。
+// ジョブ情報が無い（期限切れ・同期処理）場合は、ワークスペースのマニフェストに記録したテナントで確認し、
+// どちらでも所有者を確認できないジョブは参照を許可しません。
+func (m *Manager) JobVisible(ctx context.Context, jobID, tenantID string) (bool, error) {
+	record, err := m.GetRecord(ctx, jobID)
+	if err != nil {
+		return false, err
+	}
+	if record != nil {
+		return record.VisibleTo(tenantID), nil
+	}
+	return m.manifestVisible(jobID, tenantID)
+}
+
+// manifestVisible はジョブ情報の無いジョブ jobID を、マニフェストのテナントが tenantID と一致する場合にだけ参照可能とします。
+func (m *Manager) manifestVisible(jobID, tenantID string) (bool, error) {
+	if m.pdfService == nil {
+		return false, nil
+	}
+	owner, err := m.pdfService.JobTenant(jobID)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	return owner == tenant.Normalize(tenantID), nil
+}
+
+// checkTenantQuota はテナントのキュー待ち・処理中のジョブ数が上限に達していないかを確認します。
+// 件数は保存済みのジョブ情報から数えるため、同時に投入されたジョブでわずかに上限を超えることがあります。
+func (m *Manager) checkTenantQuota(ctx context.Context, tenantID string) error {
+	limit := m.cfg.TenantMaxActiveJobs
+	if limit <= 0 {
+		return nil
+	}
+	ids, err := m.store.JobIDs(ctx)
+	if err != nil {
+		return err
+	}
+	active := 0
+	for _, id := range ids {
+		record, err := m.store.Get(ctx, id)
+		if err != nil {
+			return err
+		}
+		if record.VisibleTo(tenantID) && !record.Status.Terminal() {
+			active++
+		}
+	}
+	if active >= limit {
+		return ErrTenantQuotaExceeded
+	}
+	return nil
+}
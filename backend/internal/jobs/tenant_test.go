@@ -0,0 +1,62 @@
+package jobs
+
+import (
+	"context"
+	"testing"
+
+	"github.com/yourusername/paper-forge/internal/config"
+	"github.com/yourusername/paper-forge/internal/pdf"
+	"github.com/yourusername/paper-forge/internal/pdf/pdftest"
+	"github.com/yourusername/paper-forge/internal/tenant"
+)
+
+func TestRecordVisibleTo(t *testing.T) {
+	if !(&Record{}).VisibleTo(tenant.Default) {
+		t.Fatal("records without a tenant should belong to the default tenant")
+	}
+	if (&Record{Tenant: "sales"}).VisibleTo("legal") {
+		t.Fatal("records should not be visible to other tenants")
+	}
+	var missing *Record
+	if missing.VisibleTo(tenant.Default) {
+		t.Fatal("missing records should not be visible")
+	}
+}
+
+func TestManifestVisibleChecksRecordedTenant(t *testing.T) {
+	svc := pdf.NewService(&config.Config{MaxFileSize: 1 << 20, MaxPages: 20, WorkDir: t.TempDir()})
+	headers, err := pdftest.FileHeaders("file", pdftest.File{Name: "a.pdf", Data: pdftest.Synthetic(1, 0)})
+	if err != nil {
+		t.Fatalf("failed to build multipart files: %v", err)
+	}
+	ctx := tenant.WithID(context.Background(), "sales")
+	manifest, err := svc.PrepareJob(ctx, pdf.OperationResize, pdf.PrepareRequest{Files: headers})
+	if err != nil {
+		t.Fatalf("PrepareJob returned error: %v", err)
+	}
+	m := &Manager{pdfService: svc}
+
+	tests := []struct {
+		name     string
+		jobID    string
+		tenantID string
+		want     bool
+	}{
+		{name: "owner", jobID: manifest.JobID, tenantID: "sales", want: true},
+		{name: "other tenant", jobID: manifest.JobID, tenantID: "legal"},
+		// ジョブ情報もワークスペースも無いジョブは、所有者を確認できないため tenant.Default にも見せない
+		{name: "unknown job for default", jobID: "00000000-0000-4000-8000-000000000000", tenantID: tenant.Default},
+		{name: "path-like id", jobID: "../" + manifest.JobID, tenantID: "sales"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := m.manifestVisible(tt.jobID, tt.tenantID)
+			if err != nil {
+				t.Fatalf("manifestVisible returned error: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("manifestVisible(%q, %q) = %v, want %v", tt.jobID, tt.tenantID, got, tt.want)
+			}
+		})
+	}
+}
@@ -1,6 +1,11 @@
 package jobs
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/yourusername/paper-forge/internal/pdf"
+)
 
 // Status はジョブの実行状態を表します。
 type Status string
@@ -12,6 +17,11 @@ const (
 	StatusFailed    Status = "error"
 )
 
+// Terminal はこれ以上状態が変化しない終了状態かを返します。
+func (s Status) Terminal() bool {
+	return s == StatusSucceeded || s == StatusFailed
+}
+
 // ProgressInfo は進捗の補足情報を表します。
 type ProgressInfo struct {
 	Percent int    `json:"percent"`
@@ -25,16 +35,81 @@ type ErrorInfo struct {
 	Message string `json:"message"`
 }
 
+// DeliveryStatus は成果物配送の結果を表します。
+type DeliveryStatus string
+
+const (
+	DeliveryStatusSent   DeliveryStatus = "sent"
+	DeliveryStatusFailed DeliveryStatus = "failed"
+)
+
+// DeliveryInfo はジョブ完了後に行った成果物配送の結果を保持します。
+type DeliveryInfo struct {
+	Method    string         `json:"method"`
+	Status    DeliveryStatus `json:"status"`
+	Error     string         `json:"error,omitempty"`
+	UpdatedAt time.Time      `json:"updatedAt"`
+}
+
+// ArtifactInfo はジョブの成果物1つ分の情報です。先頭が主成果物で、DownloadURL は downloadUrl と同じです。
+type ArtifactInfo struct {
+	Name        string         `json:"name"`
+	Kind        pdf.ResultKind `json:"kind"`
+	Size        int64          `json:"size"`
+	SHA256      string         `json:"sha256,omitempty"`
+	DownloadURL string         `json:"downloadUrl"`
+}
+
+// InputInfo は keepInputs=true で保持している入力ファイル1つ分の情報です。
+type InputInfo struct {
+	Name        string `json:"name"`
+	Size        int64  `json:"size"`
+	DownloadURL string `json:"downloadUrl"`
+}
+
+// HoldInfo はジョブに設定されたリーガルホールドの情報です。
+type HoldInfo struct {
+	Reason string    `json:"reason,omitempty"`
+	HeldAt time.Time `json:"heldAt"`
+}
+
 // Record はジョブの現在状態を表します。
 type Record struct {
-	JobID       string       `json:"jobId"`
-	Operation   string       `json:"operation"`
-	Status      Status       `json:"status"`
-	Progress    ProgressInfo `json:"progress"`
-	DownloadURL string       `json:"downloadUrl,omitempty"`
-	Meta        any          `json:"meta,omitempty"`
-	Error       *ErrorInfo   `json:"error,omitempty"`
-	CreatedAt   time.Time    `json:"createdAt"`
-	UpdatedAt   time.Time    `json:"updatedAt"`
-	ExpiresAt   time.Time    `json:"expiresAt"`
+	JobID         string          `json:"jobId"`
+	Operation     string          `json:"operation"`
+	Tenant        string          `json:"tenant,omitempty"`
+	Label         string          `json:"label,omitempty"`
+	ClientContext json.RawMessage `json:"clientContext,omitempty"`
+	Status        Status          `json:"status"`
+	Progress      ProgressInfo    `json:"progress"`
+	DownloadURL   string          `json:"downloadUrl,omitempty"`
+	OutputSize    int64           `json:"outputSize,omitempty"`   // downloadUrl の主成果物のサイズ（ダウンロードの検証・再開用）
+	OutputSHA256  string          `json:"outputSha256,omitempty"` // downloadUrl の主成果物の SHA-256
+	Artifacts     []ArtifactInfo  `json:"artifacts,omitempty"`
+	Inputs        []InputInfo     `json:"inputs,omitempty"`
+	Meta          any             `json:"meta,omitempty"`
+	Error         *ErrorInfo      `json:"error,omitempty"`
+	Delivery      *DeliveryInfo   `json:"delivery,omitempty"`
+	Hold          *HoldInfo       `json:"hold,omitempty"`
+	QueuedAt      time.Time       `json:"queuedAt,omitempty"`    // キューへ投入した日時
+	StartedAt     time.Time       `json:"startedAt,omitempty"`   // ワーカーが処理を始めた日時
+	SLABreached   bool            `json:"slaBreached,omitempty"` // キュー待ちが JOB_QUEUE_SLA_SECONDS を超えたか
+	CreatedAt     time.Time       `json:"createdAt"`
+	UpdatedAt     time.Time       `json:"updatedAt"`
+	ExpiresAt     time.Time       `json:"expiresAt"`
+}
+
+// QueuedFor はキュー待ちの時間を返します。処理を始めたジョブは投入から開始まで、
+// キュー待ちのジョブは now までの時間です。投入日時が無い場合は 0 です。
+func (r *Record) QueuedFor(now time.Time) time.Duration {
+	if r.QueuedAt.IsZero() {
+		return 0
+	}
+	if !r.StartedAt.IsZero() {
+		return r.StartedAt.Sub(r.QueuedAt)
+	}
+	if r.Status != StatusQueued {
+		return 0
+	}
+	return now.Sub(r.QueuedAt)
 }
@@ -0,0 +1,228 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/yourusername/paper-forge/internal/pdf"
+	"github.com/yourusername/paper-forge/internal/tenant"
+)
+
+const (
+	// usageKeyPrefix は月・テナントごとの利用量を保持する hash の接頭辞です（usage:<YYYY-MM>:<tenant>）。
+	usageKeyPrefix = "usage:"
+	// usageTenantsPrefix は月ごとに利用量のあるテナントIDを保持する set の接頭辞です。
+	usageTenantsPrefix = "usage-tenants:"
+	// usageRetention は利用量を保持する期間です。前年同月と比較できるよう1年強残します。
+	usageRetention = 400 * 24 * time.Hour
+	// UsageMonthLayout は利用量を集計する月の形式です。
+	UsageMonthLayout = "2006-01"
+)
+
+// UsageRow は1か月・1テナント分の利用量の集計です。
+type UsageRow struct {
+	Month      string  `json:"month"`
+	Tenant     string  `json:"tenant"`
+	Jobs       int64   `json:"jobs"`
+	FailedJobs int64   `json:"failedJobs"`
+	Pages      int64   `json:"pages"`
+	Bytes      int64   `json:"bytes"`
+	CPUSeconds float64 `json:"cpuSeconds"`
+	// Units は OPERATION_COST_WEIGHTS で重み付けしたコスト単位の合計です。
+	Units float64 `json:"units"`
+	// Budget はテナントの月間予算（TENANT_MONTHLY_BUDGETS）です。未設定の場合は0です。
+	Budget float64 `json:"budget,omitempty"`
+}
+
+// AddUsage はジョブ1件分の利用量を at の月・テナントの集計に加算し、加算後のコスト単位の合計を返します。
+// units は重み付け済みのコスト単位です（集計は1/1000単位の整数で保持します）。
+func (s *Store) AddUsage(ctx context.Context, tenantID string, at time.Time, sample pdf.UsageSample, units float64) (float64, error) {
+	month := at.UTC().Format(UsageMonthLayout)
+	tenantID = tenant.Normalize(tenantID)
+	key := usageKey(month, tenantID)
+	failed := int64(0)
+	if sample.Failed {
+		failed = 1
+	}
+	var total *redis.IntCmd
+	_, err := s.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HIncrBy(ctx, key, "jobs", 1)
+		pipe.HIncrBy(ctx, key, "failedJobs", failed)
+		pipe.HIncrBy(ctx, key, "pages", int64(sample.Pages))
+		pipe.HIncrBy(ctx, key, "bytes", sample.Bytes)
+		pipe.HIncrBy(ctx, key, "cpuMillis", sample.CPU.Milliseconds())
+		total = pipe.HIncrBy(ctx, key, "unitMillis", int64(math.Round(units*1000)))
+		pipe.Expire(ctx, key, usageRetention)
+		pipe.SAdd(ctx, usageTenantsKey(month), tenantID)
+		pipe.Expire(ctx, usageTenantsKey(month), usageRetention)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return float64(total.Val()) / 1000, nil
+}
+
+// UsageKeys は保存されている利用量の集計のキーをすべて返します。
+func (s *Store) UsageKeys(ctx context.Context) ([]string, error) {
+	var (
+		mu   sync.Mutex
+		keys []string
+	)
+	err := forEachNode(ctx, s.rdb, func(ctx context.Context, client redis.Cmdable) error {
+		iter := client.Scan(ctx, 0, usageKeyPrefix+"*", 500).Iterator()
+		for iter.Next(ctx) {
+			mu.Lock()
+			keys = append(keys, iter.Val())
+			mu.Unlock()
+		}
+		return iter.Err()
+	})
+	sort.Strings(keys)
+	return keys, err
+}
+
+// Usage は month（YYYY-MM）のテナントごとの利用量をテナントID順に返します。
+func (s *Store) Usage(ctx context.Context, month string) ([]UsageRow, error) {
+	tenants, err := s.rdb.SMembers(ctx, usageTenantsKey(month)).Result()
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(tenants)
+
+	rows := make([]UsageRow, 0, len(tenants))
+	for _, id := range tenants {
+		values, err := s.rdb.HGetAll(ctx, usageKey(month, id)).Result()
+		if err != nil {
+			return nil, err
+		}
+		if len(values) == 0 {
+			continue
+		}
+		counter := func(field string) int64 {
+			n, _ := strconv.ParseInt(values[field], 10, 64)
+			return n
+		}
+		rows = append(rows, UsageRow{
+			Month:      month,
+			Tenant:     id,
+			Jobs:       counter("jobs"),
+			FailedJobs: counter("failedJobs"),
+			Pages:      counter("pages"),
+			Bytes:      counter("bytes"),
+			CPUSeconds: float64(counter("cpuMillis")) / 1000,
+			Units:      float64(counter("unitMillis")) / 1000,
+		})
+	}
+	return rows, nil
+}
+
+// RecordUsage はジョブの利用量を ctx のテナントの集計に加算し、予算の消化率に応じてアラートを送ります。
+// 記録の失敗はジョブ結果に影響させません。
+func (m *Manager) RecordUsage(ctx context.Context, sample pdf.UsageSample) {
+	tenantID := tenant.FromContext(ctx)
+	now := time.Now()
+	units := m.budget.units(sample)
+	total, err := m.store.AddUsage(ctx, tenantID, now, sample, units)
+	if err != nil {
+		m.logf("failed to record usage tenant=%s operation=%s: %v", tenantID, sample.Operation, err)
+		return
+	}
+	m.checkBudget(ctx, tenantID, now.UTC().Format(UsageMonthLayout), total-units, total)
+}
+
+// Usage は month（YYYY-MM）のテナントごとの利用量を返します。
+func (m *Manager) Usage(ctx context.Context, month string) ([]UsageRow, error) {
+	if _, err := time.Parse(UsageMonthLayout, month); err != nil {
+		return nil, fmt.Errorf("invalid month %q: %w", month, err)
+	}
+	rows, err := m.store.Usage(ctx, month)
+	if err != nil {
+		return nil, err
+	}
+	for i := range rows {
+		rows[i].Budget = m.budget.budgetFor(rows[i].Tenant)
+	}
+	return rows, nil
+}
+
+// moveUsage は month の利用量を dst の集計に加算し、s からは削除します（待避先の Redis から主 Redis への書き戻し用）。
+// テナントごとに集計の読み取りと削除を1つのトランザクションで行うため、その間に加算された分を失わず、
+// 書き戻しが繰り返されても同じ分を二重に加算しません。dst への加算に失敗した場合は取り出した分を s に戻し、次回に書き戻します。
+func (s *Store) moveUsage(ctx context.Context, dst *Store, month string) error {
+	tenants, err := s.rdb.SMembers(ctx, usageTenantsKey(month)).Result()
+	if err != nil {
+		return err
+	}
+	for _, id := range tenants {
+		counts, err := s.takeUsage(ctx, month, id)
+		if err != nil {
+			return err
+		}
+		if len(counts) == 0 {
+			continue
+		}
+		if err := dst.addUsageCounts(ctx, month, id, counts); err != nil {
+			if restoreErr := s.addUsageCounts(ctx, month, id, counts); restoreErr != nil {
+				return errors.Join(err, restoreErr)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// takeUsage は month・テナントの集計を読み取ると同時に削除し、フィールドごとの値を返します。
+func (s *Store) takeUsage(ctx context.Context, month, tenantID string) (map[string]int64, error) {
+	key := usageKey(month, tenantID)
+	var values *redis.MapStringStringCmd
+	_, err := s.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		values = pipe.HGetAll(ctx, key)
+		pipe.Del(ctx, key)
+		pipe.SRem(ctx, usageTenantsKey(month), tenantID)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int64, len(values.Val()))
+	for field, raw := range values.Val() {
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			continue
+		}
+		counts[field] = n
+	}
+	return counts, nil
+}
+
+// addUsageCounts は takeUsage で取り出した値を month・テナントの集計に加算します。
+func (s *Store) addUsageCounts(ctx context.Context, month, tenantID string, counts map[string]int64) error {
+	key := usageKey(month, tenantID)
+	_, err := s.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for field, n := range counts {
+			pipe.HIncrBy(ctx, key, field, n)
+		}
+		pipe.Expire(ctx, key, usageRetention)
+		pipe.SAdd(ctx, usageTenantsKey(month), tenantID)
+		pipe.Expire(ctx, usageTenantsKey(month), usageRetention)
+		return nil
+	})
+	return err
+}
+
+func usageKey(month, tenantID string) string {
+	return usageKeyPrefix + month + ":" + tenant.Normalize(tenantID)
+}
+
+func usageTenantsKey(month string) string {
+	return usageTenantsPrefix + month
+}
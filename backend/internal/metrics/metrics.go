@@ -0,0 +1,65 @@
+// Package metrics はプロセス内カウンターを expvar で公開します。
+package metrics
+
+import (
+	"expvar"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+var registry = expvar.NewMap("paperforge")
+
+var (
+	// AbandonedRequests はクライアント切断により中断された同期リクエスト数（操作種別ごと）です。
+	AbandonedRequests = newMap("abandoned_requests")
+	// SaturatedRequests は同期処理の実行枠が埋まっていた件数です（rejected: 503 応答, upgraded: 非同期へ切替）。
+	SaturatedRequests = newMap("saturated_requests")
+	// ResultCacheHits は結果キャッシュから成果物を再利用した件数です（操作種別ごと）。
+	ResultCacheHits = newMap("result_cache_hits")
+	// AbortedUploads はクライアントの中断により保存を打ち切ったアップロード数です（multipart: 通常のファイル送信）。
+	AbortedUploads = newMap("aborted_uploads")
+	// QueueSLABreaches はキュー待ちが JOB_QUEUE_SLA_SECONDS を超えてから処理を始めたジョブ数です（操作種別ごと）。
+	QueueSLABreaches = newMap("queue_sla_breaches")
+	// JobPanics は処理中にパニックし、失敗にしたジョブ数です（操作種別ごと）。
+	JobPanics = newMap("job_panics")
+)
+
+func newMap(name string) *expvar.Map {
+	m := new(expvar.Map)
+	registry.Set(name, m)
+	return m
+}
+
+// RegisterWorkspaceUsage は作業領域の使用量（直近の実使用量・予約量・上限、バイト）を返す関数を登録し、
+// workspace_usage として公開します。
+func RegisterWorkspaceUsage(f func() (used, reserved, limit int64)) {
+	registry.Set("workspace_usage", expvar.Func(func() any {
+		used, reserved, limit := f()
+		return map[string]int64{
+			"usedBytes":     used,
+			"reservedBytes": reserved,
+			"limitBytes":    limit,
+		}
+	}))
+}
+
+// RegisterQueueStats はジョブキューの待ち状況を返す関数を登録し、queue として公開します。
+// 取得に失敗した場合は error のみを含めます。
+func RegisterQueueStats(f func() (any, error)) {
+	registry.Set("queue", expvar.Func(func() any {
+		stats, err := f()
+		if err != nil {
+			return map[string]string{"error": err.Error()}
+		}
+		return stats
+	}))
+}
+
+// Handler はアプリケーションのカウンターのみを JSON で返すハンドラーです。
+// expvar 標準のハンドラーはコマンドライン引数なども含むため使用しません。
+func Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/json; charset=utf-8", []byte(registry.String()))
+	}
+}
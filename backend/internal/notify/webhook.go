@@ -0,0 +1,176 @@
+// Package notify はジョブ完了・失敗時のチャット通知（Slack / Microsoft Teams）を提供します。
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/yourusername/paper-forge/internal/config"
+	"github.com/yourusername/paper-forge/internal/pdf"
+)
+
+// Event は通知対象となるジョブの状態変化です。
+type Event struct {
+	JobID     string
+	Operation string
+	Label     string
+	// ClientContext は投入時にクライアントが指定した JSON で、連携先での突き合わせ用にそのまま載せます。
+	ClientContext json.RawMessage
+	Succeeded     bool
+	Result        *pdf.Result // 成功時のみ
+	DownloadURL   string      // 成功時のみ（署名付きリンク）
+	ErrorCode     string      // 失敗時のみ
+	ErrorText     string      // 失敗時のみ
+}
+
+// Notifier はチャットの Incoming Webhook へメッセージを投稿します。
+type Notifier struct {
+	defaultURL   string
+	allowedHosts []string
+	client       *http.Client
+}
+
+// New は Notifier を作成します。
+func New(cfg *config.Config) *Notifier {
+	var hosts []string
+	for _, h := range strings.Split(cfg.NotifyWebhookAllowedHosts, ",") {
+		if h = strings.ToLower(strings.TrimSpace(h)); h != "" {
+			hosts = append(hosts, h)
+		}
+	}
+	return &Notifier{
+		defaultURL:   strings.TrimSpace(cfg.NotifyWebhookURL),
+		allowedHosts: hosts,
+		client:       &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Enabled は override または既定の Webhook が設定されているかを返します。
+func (n *Notifier) Enabled(override string) bool {
+	return n != nil && (override != "" || n.defaultURL != "")
+}
+
+// Notify はイベントを投稿します。override が指定されていれば既定の Webhook より優先します。
+func (n *Notifier) Notify(ctx context.Context, override string, ev Event) error {
+	return n.Send(ctx, override, formatMessage(ev))
+}
+
+// Send は任意のメッセージを投稿します（予算アラートなどジョブ以外の通知に使います）。
+// override が指定されていれば既定の Webhook より優先します。
+func (n *Notifier) Send(ctx context.Context, override, text string) error {
+	target := override
+	if target == "" {
+		target = n.defaultURL
+	}
+	if target == "" {
+		return nil
+	}
+
+	u, err := url.Parse(target)
+	if err != nil || u.Scheme != "https" {
+		return fmt.Errorf("webhook url must be https")
+	}
+	if !n.hostAllowed(u.Hostname()) {
+		return fmt.Errorf("webhook host %s is not allowed", u.Hostname())
+	}
+
+	body, err := json.Marshal(buildPayload(u.Hostname(), text))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("webhook responded with %d: %s", resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
+
+func (n *Notifier) hostAllowed(host string) bool {
+	host = strings.ToLower(host)
+	for _, allowed := range n.allowedHosts {
+		if host == allowed || strings.HasSuffix(host, "."+allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// buildPayload は送信先に応じたペイロードを組み立てます。
+// Teams のコネクタは MessageCard 形式、それ以外（Slack 互換）は text のみを送ります。
+func buildPayload(host, text string) any {
+	host = strings.ToLower(host)
+	if strings.HasSuffix(host, "webhook.office.com") || strings.HasSuffix(host, "logic.azure.com") {
+		return map[string]any{
+			"@type":    "MessageCard",
+			"@context": "https://schema.org/extensions",
+			"summary":  "Paper Forge 通知",
+			"text":     strings.ReplaceAll(text, "\n", "<br>"),
+		}
+	}
+	return map[string]any{"text": text}
+}
+
+func formatMessage(ev Event) string {
+	var b strings.Builder
+	if !ev.Succeeded {
+		fmt.Fprintf(&b, ":x: Paper Forge: %s ジョブが失敗しました\n", ev.Operation)
+		writeJobContext(&b, ev)
+		fmt.Fprintf(&b, "エラー: %s %s\n", ev.ErrorCode, ev.ErrorText)
+		return b.String()
+	}
+
+	fmt.Fprintf(&b, ":white_check_mark: Paper Forge: %s ジョブが完了しました\n", ev.Operation)
+	writeJobContext(&b, ev)
+	if ev.Result != nil {
+		fmt.Fprintf(&b, "ファイル: %s (%s)\n", ev.Result.OutputFilename, humanBytes(ev.Result.OutputSize))
+		if meta, ok := ev.Result.Meta.(*pdf.OptimizeMeta); ok && meta != nil {
+			fmt.Fprintf(&b, "圧縮: %s → %s (%.1f%% 削減)\n", humanBytes(meta.OriginalSize), humanBytes(meta.OutputSize), meta.SavedPercent)
+		}
+	}
+	if ev.DownloadURL != "" {
+		fmt.Fprintf(&b, "ダウンロード: %s\n", ev.DownloadURL)
+	}
+	return b.String()
+}
+
+// writeJobContext はジョブID と、指定されていればラベル・clientContext を書き出します。
+func writeJobContext(b *strings.Builder, ev Event) {
+	fmt.Fprintf(b, "ジョブID: %s\n", ev.JobID)
+	if ev.Label != "" {
+		fmt.Fprintf(b, "ラベル: %s\n", ev.Label)
+	}
+	if len(ev.ClientContext) > 0 {
+		fmt.Fprintf(b, "clientContext: %s\n", ev.ClientContext)
+	}
+}
+
+func humanBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,191 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/yourusername/paper-forge/internal/config"
+	"github.com/yourusername/paper-forge/internal/pdf"
+)
+
+func TestNotifierSend(t *testing.T) {
+	var posted []map[string]any
+	status := http.StatusOK
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]any
+		if r.Header.Get("Content-Type") != "application/json" || json.NewDecoder(r.Body).Decode(&payload) != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		posted = append(posted, payload)
+		w.WriteHeader(status)
+		_, _ = io.WriteString(w, "invalid_token\n")
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name       string
+		defaultURL string
+		override   string
+		hosts      string
+		status     int
+		wantErr    string
+		wantPosts  int
+	}{
+		{name: "posts to the default webhook", defaultURL: server.URL + "/hook", hosts: "127.0.0.1", wantPosts: 1},
+		{name: "override wins over the default", defaultURL: "https://hooks.slack.com/x", override: server.URL + "/hook", hosts: "127.0.0.1", wantPosts: 1},
+		{name: "no webhook is a no-op"},
+		{name: "rejects plain http", override: "http://127.0.0.1/hook", hosts: "127.0.0.1", wantErr: "https"},
+		{name: "rejects hosts outside the allowlist", override: server.URL + "/hook", hosts: "hooks.slack.com", wantErr: "not allowed"},
+		{name: "reports error responses", override: server.URL + "/hook", hosts: "127.0.0.1", status: http.StatusForbidden, wantErr: "403: invalid_token", wantPosts: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			posted = nil
+			status = http.StatusOK
+			if tt.status != 0 {
+				status = tt.status
+			}
+			n := New(&config.Config{NotifyWebhookURL: tt.defaultURL, NotifyWebhookAllowedHosts: tt.hosts})
+			n.client = server.Client()
+
+			err := n.Send(context.Background(), tt.override, "hello")
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("Send returned error: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+			if len(posted) != tt.wantPosts {
+				t.Fatalf("expected %d posts, got %d", tt.wantPosts, len(posted))
+			}
+			if tt.wantPosts > 0 && posted[0]["text"] != "hello" {
+				t.Fatalf("unexpected payload: %v", posted[0])
+			}
+		})
+	}
+}
+
+func TestNotifierHostAllowed(t *testing.T) {
+	n := New(&config.Config{NotifyWebhookAllowedHosts: " Hooks.Slack.com ,webhook.office.com"})
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{host: "hooks.slack.com", want: true},
+		{host: "HOOKS.SLACK.COM", want: true},
+		{host: "contoso.webhook.office.com", want: true},
+		{host: "evilhooks.slack.com"},
+		{host: "hooks.slack.com.evil.example"},
+		{host: "example.com"},
+	}
+	for _, tt := range tests {
+		if got := n.hostAllowed(tt.host); got != tt.want {
+			t.Errorf("hostAllowed(%q) = %v, want %v", tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestBuildPayload(t *testing.T) {
+	tests := []struct {
+		host string
+		want map[string]any
+	}{
+		{host: "hooks.slack.com", want: map[string]any{"text": "a\nb"}},
+		{host: "contoso.webhook.office.com", want: map[string]any{
+			"@type":    "MessageCard",
+			"@context": "https://schema.org/extensions",
+			"summary":  "Paper Forge 通知",
+			"text":     "a<br>b",
+		}},
+		{host: "prod-01.westus.logic.azure.com", want: map[string]any{
+			"@type":    "MessageCard",
+			"@context": "https://schema.org/extensions",
+			"summary":  "Paper Forge 通知",
+			"text":     "a<br>b",
+		}},
+	}
+	for _, tt := range tests {
+		got, _ := buildPayload(tt.host, "a\nb").(map[string]any)
+		if len(got) != len(tt.want) {
+			t.Fatalf("%s: unexpected payload %v", tt.host, got)
+		}
+		for k, v := range tt.want {
+			if got[k] != v {
+				t.Fatalf("%s: %s = %v, want %v", tt.host, k, got[k], v)
+			}
+		}
+	}
+}
+
+func TestFormatMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		ev      Event
+		want    []string
+		notWant []string
+	}{
+		{
+			name: "success with optimize stats and link",
+			ev: Event{
+				JobID: "job-1", Operation: "optimize", Succeeded: true,
+				Result: &pdf.Result{
+					OutputFilename: "optimized.pdf", OutputSize: 1536,
+					Meta: &pdf.OptimizeMeta{OriginalSize: 3 << 20, OutputSize: 1 << 20, SavedPercent: 66.7},
+				},
+				DownloadURL: "https://api.example.com/dl",
+			},
+			want: []string{
+				":white_check_mark: Paper Forge: optimize ジョブが完了しました",
+				"ジョブID: job-1",
+				"ファイル: optimized.pdf (1.5 KB)",
+				"圧縮: 3.0 MB → 1.0 MB (66.7% 削減)",
+				"ダウンロード: https://api.example.com/dl",
+			},
+			notWant: []string{"ラベル:", "clientContext:"},
+		},
+		{
+			name: "failure with label and client context",
+			ev: Event{
+				JobID: "job-2", Operation: "merge", Label: "契約書",
+				ClientContext: json.RawMessage(`{"ticket":42}`),
+				ErrorCode:     "INVALID_INPUT", ErrorText: "PDFが壊れています。",
+			},
+			want: []string{
+				":x: Paper Forge: merge ジョブが失敗しました",
+				"ラベル: 契約書",
+				`clientContext: {"ticket":42}`,
+				"エラー: INVALID_INPUT PDFが壊れています。",
+			},
+			notWant: []string{"ダウンロード:"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := formatMessage(tt.ev)
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Fatalf("message should contain %q:\n%s", want, got)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(got, notWant) {
+					t.Fatalf("message should not contain %q:\n%s", notWant, got)
+				}
+			}
+		})
+	}
+}
+
+func TestHumanBytes(t *testing.T) {
+	for n, want := range map[int64]string{0: "0 B", 1023: "1023 B", 1024: "1.0 KB", 5 << 20: "5.0 MB", 3 << 30: "3.0 GB"} {
+		if got := humanBytes(n); got != want {
+			t.Errorf("humanBytes(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
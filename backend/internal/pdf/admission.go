@@ -0,0 +1,50 @@
+package pdf
+
+import "time"
+
+// Admission は同時に実行する同期処理の数を制限します。
+// 上限に達した場合は新たな処理を待たせず即座に拒否し、goroutine やメモリの際限ない増加を防ぎます。
+type Admission struct {
+	slots      chan struct{}
+	retryAfter time.Duration
+}
+
+// NewAdmission は Admission を作成します。limit が 0 以下の場合は制限なしとして nil を返します。
+func NewAdmission(limit int, retryAfter time.Duration) *Admission {
+	if limit <= 0 {
+		return nil
+	}
+	if retryAfter <= 0 {
+		retryAfter = 5 * time.Second
+	}
+	return &Admission{
+		slots:      make(chan struct{}, limit),
+		retryAfter: retryAfter,
+	}
+}
+
+// TryAcquire は実行枠の確保を試みます。確保できた場合は解放関数と true を返します。
+// nil の Admission は常に成功します。
+func (a *Admission) TryAcquire() (func(), bool) {
+	if a == nil {
+		return func() {}, true
+	}
+	select {
+	case a.slots <- struct{}{}:
+		return func() { <-a.slots }, true
+	default:
+		return nil, false
+	}
+}
+
+// RetryAfter はクライアントに再試行を促すまでの秒数を返します。
+func (a *Admission) RetryAfter() int {
+	if a == nil {
+		return 0
+	}
+	secs := int(a.retryAfter / time.Second)
+	if secs < 1 {
+		secs = 1
+	}
+	return secs
+}
@@ -0,0 +1,219 @@
+package pdf
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	pdfapi "github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+
+	"github.com/yourusername/paper-forge/internal/buildinfo"
+)
+
+// auditNamespace は処理記録の XMP プロパティの名前空間です（接頭辞 pfa）。
+const auditNamespace = "urn:paper-forge:audit:1.0/"
+
+// auditPolicy はデプロイ単位で成果物の XMP メタデータに処理記録を埋め込む設定です。
+type auditPolicy struct {
+	// ops は処理記録を埋め込む操作です。空の場合は全操作に埋め込みます。
+	ops map[OperationType]bool
+}
+
+// newAuditPolicy は AUDIT_RECORD* の設定から処理記録の設定を作成します。無効な場合は nil を返します。
+func newAuditPolicy(enabled bool, operations string) *auditPolicy {
+	if !enabled {
+		return nil
+	}
+	ops, unknown := parseOperationList(operations)
+	if len(unknown) > 0 {
+		log.Printf("AUDIT_RECORD_OPERATIONS contains unknown operations (ignored): %s", strings.Join(unknown, ", "))
+	}
+	return &auditPolicy{ops: ops}
+}
+
+// appliesTo は操作 op の成果物に処理記録を埋め込むかを返します。nil の auditPolicy は常に false です。
+func (p *auditPolicy) appliesTo(op OperationType) bool {
+	if p == nil {
+		return false
+	}
+	return len(p.ops) == 0 || p.ops[op]
+}
+
+// auditRecord は成果物に埋め込む処理記録です。受け取った側が成果物の作られ方を確認できるようにします。
+// 入力のファイル名は受け取った側へ渡すべきでない場合があるため含めず、内容のハッシュで特定します。
+type auditRecord struct {
+	JobID       string
+	Operation   OperationType
+	Steps       []string // 適用した処理（操作と透かし）を適用順に並べたもの
+	Tools       []auditTool
+	Inputs      []auditInput
+	ReceivedAt  time.Time
+	CompletedAt time.Time
+	// Document は文書情報を変更した操作（metadata）の変更後の値です。XMP を置き換えても失われないよう一緒に書き出します。
+	Document *DocumentMetadata
+}
+
+type auditTool struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type auditInput struct {
+	SHA256 string `json:"sha256"`
+	Size   int64  `json:"size"`
+	Pages  int    `json:"pages"`
+}
+
+// newAuditRecord はジョブのマニフェストと現在の設定から処理記録を作成します。
+func (s *Service) newAuditRecord(ctx context.Context, manifest *JobManifest) auditRecord {
+	record := auditRecord{
+		JobID:     manifest.JobID,
+		Operation: manifest.Operation,
+		Steps:     []string{string(manifest.Operation)},
+		Tools: []auditTool{
+			{Name: "paper-forge", Version: buildinfo.Get().Version},
+			{Name: "pdfcpu", Version: model.VersionStr},
+		},
+		ReceivedAt:  manifest.CreatedAt.UTC(),
+		CompletedAt: s.now().UTC(),
+	}
+	if s.watermark.appliesTo(manifest.Operation) {
+		record.Steps = append(record.Steps, "watermark")
+	}
+	if manifest.Operation == OperationOptimize {
+		if version := s.ghostscriptVersion(ctx); version != "" {
+			record.Tools = append(record.Tools, auditTool{Name: "ghostscript", Version: version})
+		}
+	}
+	for _, f := range manifest.Files {
+		record.Inputs = append(record.Inputs, auditInput{SHA256: f.SHA256, Size: f.Size, Pages: f.Pages})
+	}
+	return record
+}
+
+// xmp は処理記録を XMP パケットとして書き出します。
+func (r auditRecord) xmp() []byte {
+	var b bytes.Buffer
+	text := func(tag, value string) {
+		b.WriteString("<" + tag + ">")
+		_ = xml.EscapeText(&b, []byte(value))
+		b.WriteString("</" + tag + ">\n")
+	}
+
+	b.WriteString("<?xpacket begin=\"\uFEFF\" id=\"W5M0MpCehiHzreSzNTczkc9d\"?>\n")
+	b.WriteString("<x:xmpmeta xmlns:x=\"adobe:ns:meta/\">\n")
+	b.WriteString("<rdf:RDF xmlns:rdf=\"http://www.w3.org/1999/02/22-rdf-syntax-ns#\">\n")
+	b.WriteString("<rdf:Description rdf:about=\"\" xmlns:xmp=\"http://ns.adobe.com/xap/1.0/\" xmlns:pfa=\"" + auditNamespace + "\" " + documentXMPNamespaces + ">\n")
+	text("xmp:CreatorTool", "paper-forge")
+	text("xmp:MetadataDate", r.CompletedAt.Format(time.RFC3339))
+	text("pfa:JobID", r.JobID)
+	text("pfa:Operation", string(r.Operation))
+	text("pfa:ReceivedAt", r.ReceivedAt.Format(time.RFC3339))
+	text("pfa:CompletedAt", r.CompletedAt.Format(time.RFC3339))
+	if r.Document != nil {
+		r.Document.writeXMP(&b)
+	}
+
+	b.WriteString("<pfa:Steps><rdf:Seq>\n")
+	for _, step := range r.Steps {
+		text("rdf:li", step)
+	}
+	b.WriteString("</rdf:Seq></pfa:Steps>\n")
+
+	b.WriteString("<pfa:Tools><rdf:Bag>\n")
+	for _, tool := range r.Tools {
+		b.WriteString("<rdf:li rdf:parseType=\"Resource\">\n")
+		text("pfa:Name", tool.Name)
+		text("pfa:Version", tool.Version)
+		b.WriteString("</rdf:li>\n")
+	}
+	b.WriteString("</rdf:Bag></pfa:Tools>\n")
+
+	b.WriteString("<pfa:Inputs><rdf:Seq>\n")
+	for _, in := range r.Inputs {
+		b.WriteString("<rdf:li rdf:parseType=\"Resource\">\n")
+		text("pfa:SHA256", in.SHA256)
+		text("pfa:Size", fmt.Sprint(in.Size))
+		text("pfa:Pages", fmt.Sprint(in.Pages))
+		b.WriteString("</rdf:li>\n")
+	}
+	b.WriteString("</rdf:Seq></pfa:Inputs>\n")
+
+	b.WriteString("</rdf:Description>\n</rdf:RDF>\n</x:xmpmeta>\n")
+	b.WriteString("<?xpacket end=\"w\"?>")
+	return b.Bytes()
+}
+
+// applyAuditRecord は処理パイプラインの最終段（透かしの後）で、処理記録を成果物の PDF の XMP メタデータに埋め込みます。
+// 既存の XMP メタデータは処理記録で置き換えます。ZIP の成果物は処理記録を埋め込んだ PDF から作り直します。
+func (s *Service) applyAuditRecord(ctx context.Context, manifest *JobManifest, result *Result) error {
+	if result == nil || !s.audit.appliesTo(manifest.Operation) {
+		return nil
+	}
+	record := s.newAuditRecord(ctx, manifest)
+	if meta, ok := result.Meta.(*MetadataMeta); ok {
+		record.Document = &meta.Document
+	}
+	packet := record.xmp()
+
+	var pdfPaths []string
+	for i, a := range result.Artifacts {
+		if a.Kind != ResultKindPDF {
+			continue
+		}
+		if err := embedXMP(a.Path, packet); err != nil {
+			return err
+		}
+		if err := refreshArtifact(result, i); err != nil {
+			return err
+		}
+		pdfPaths = append(pdfPaths, a.Path)
+	}
+	return rebuildZipArtifacts(result, pdfPaths)
+}
+
+// embedXMP は PDF のカタログに XMP メタデータのストリームを設定します。
+// 処理記録をそのまま読めるよう、ストリームは圧縮しません。
+func embedXMP(path string, packet []byte) error {
+	pdfCtx, err := pdfapi.ReadContextFile(path)
+	if err != nil {
+		return err
+	}
+	if err := setXMP(pdfCtx, packet); err != nil {
+		return err
+	}
+
+	tmp := path + ".xmp"
+	if err := pdfapi.WriteContextFile(pdfCtx, tmp); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// setXMP は読み込んだ PDF のカタログに XMP メタデータのストリームを設定します。
+func setXMP(pdfCtx *model.Context, packet []byte) error {
+	root, err := pdfCtx.Catalog()
+	if err != nil {
+		return err
+	}
+	sd := types.StreamDict{Dict: types.NewDict(), Content: packet}
+	sd.InsertName("Type", "Metadata")
+	sd.InsertName("Subtype", "XML")
+	if err := sd.Encode(); err != nil {
+		return err
+	}
+	ref, err := pdfCtx.IndRefForNewObject(sd)
+	if err != nil {
+		return err
+	}
+	root.Update("Metadata", *ref)
+	return nil
+}
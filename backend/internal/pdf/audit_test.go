@@ -0,0 +1,122 @@
+package pdf
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yourusername/paper-forge/internal/config"
+	"github.com/yourusername/paper-forge/internal/pdf/pdftest"
+)
+
+func TestAuditRecordXMPIsWellFormed(t *testing.T) {
+	svc := NewService(&config.Config{AuditRecord: true, WatermarkText: "Processed by ACME DMS"})
+	svc.now = func() time.Time { return time.Date(2026, 10, 16, 9, 30, 0, 0, time.UTC) }
+	manifest := &JobManifest{
+		JobID:     "job-1",
+		Operation: OperationMerge,
+		Files: []JobFile{
+			{StoredName: "00.pdf", OriginalName: "secret <draft>.pdf", Size: 1024, Pages: 2, SHA256: "aaa"},
+			{StoredName: "01.pdf", OriginalName: "b.pdf", Size: 2048, Pages: 3, SHA256: "bbb"},
+		},
+		CreatedAt: time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC),
+	}
+
+	packet := svc.newAuditRecord(context.Background(), manifest).xmp()
+	dec := xml.NewDecoder(bytes.NewReader(packet))
+	for {
+		if _, err := dec.Token(); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("XMP packet is not well-formed: %v\n%s", err, packet)
+		}
+	}
+	for _, want := range []string{
+		"<pfa:JobID>job-1</pfa:JobID>",
+		"<pfa:ReceivedAt>2026-10-16T09:00:00Z</pfa:ReceivedAt>",
+		"<pfa:CompletedAt>2026-10-16T09:30:00Z</pfa:CompletedAt>",
+		"<rdf:li>merge</rdf:li>\n<rdf:li>watermark</rdf:li>",
+		"<pfa:Name>pdfcpu</pfa:Name>",
+		"<pfa:SHA256>bbb</pfa:SHA256>",
+	} {
+		if !bytes.Contains(packet, []byte(want)) {
+			t.Fatalf("XMP packet lacks %q:\n%s", want, packet)
+		}
+	}
+	if bytes.Contains(packet, []byte("secret")) {
+		t.Fatal("XMP packet should not include input file names")
+	}
+}
+
+func TestApplyAuditRecordEmbedsIntoOutputs(t *testing.T) {
+	dir := t.TempDir()
+	var partPaths []string
+	for _, name := range []string{"part-01.pdf", "part-02.pdf"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, pdftest.Synthetic(1, 0), 0o640); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+		partPaths = append(partPaths, path)
+	}
+	zipPath := filepath.Join(dir, splitFilename)
+	if err := createZip(zipPath, partPaths, ""); err != nil {
+		t.Fatalf("createZip returned error: %v", err)
+	}
+	var artifacts []Artifact
+	for _, path := range append([]string{zipPath}, partPaths...) {
+		kind := ResultKindPDF
+		if path == zipPath {
+			kind = ResultKindZIP
+		}
+		a, err := newArtifact(path, kind)
+		if err != nil {
+			t.Fatalf("newArtifact returned error: %v", err)
+		}
+		artifacts = append(artifacts, a)
+	}
+	result := newResult(workspace{jobID: "job-1", dir: dir, outDir: dir}, OperationSplit, OSFS{}, append([]Artifact(nil), artifacts...), nil)
+	manifest := &JobManifest{JobID: "job-1", Operation: OperationSplit, Files: []JobFile{{StoredName: "00.pdf", SHA256: "abc"}}}
+
+	disabled := NewService(&config.Config{AuditRecord: true, AuditRecordOperations: "merge"})
+	if err := disabled.applyAuditRecord(context.Background(), manifest, result); err != nil {
+		t.Fatalf("applyAuditRecord returned error: %v", err)
+	}
+	if result.Artifacts[1].SHA256 != artifacts[1].SHA256 {
+		t.Fatal("outputs of operations outside AUDIT_RECORD_OPERATIONS should not be rewritten")
+	}
+
+	svc := NewService(&config.Config{AuditRecord: true, AuditRecordOperations: "split", ResultCacheMinutes: 10})
+	if err := svc.applyAuditRecord(context.Background(), manifest, result); err != nil {
+		t.Fatalf("applyAuditRecord returned error: %v", err)
+	}
+	for i, a := range result.Artifacts {
+		if a.SHA256 == artifacts[i].SHA256 {
+			t.Fatalf("artifact %s was not rewritten", a.Name)
+		}
+	}
+	for _, path := range partPaths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", path, err)
+		}
+		if !strings.Contains(string(data), "<pfa:JobID>job-1</pfa:JobID>") {
+			t.Fatalf("%s does not contain the processing record", path)
+		}
+	}
+	if _, err := os.Stat(partPaths[0] + ".xmp"); !os.IsNotExist(err) {
+		t.Fatalf("temporary file should be removed: %v", err)
+	}
+
+	// 処理記録はジョブごとに異なるため結果キャッシュには保存しない
+	svc.tmpRoot = t.TempDir()
+	svc.storeCachedResult(manifest, result)
+	if _, err := os.Stat(filepath.Join(svc.tmpRoot, cacheDirName)); !os.IsNotExist(err) {
+		t.Fatalf("results with a processing record should not be cached: %v", err)
+	}
+}
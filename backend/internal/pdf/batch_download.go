@@ -0,0 +1,181 @@
+package pdf
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/yourusername/paper-forge/internal/tenant"
+)
+
+// MaxBatchDownloadJobs は一括ダウンロードで1回に指定できるジョブ数の上限です。
+const MaxBatchDownloadJobs = 50
+
+// batchDownloadFilename は一括ダウンロードで返す ZIP のファイル名です。
+const batchDownloadFilename = "paper-forge-results.zip"
+
+// JobResultsService は複数ジョブの成果物をまとめて開くサービスです。
+// ctx にはリクエストのコンテキスト（テナントを含む）が渡されます。
+type JobResultsService interface {
+	OpenJobResults(ctx context.Context, jobIDs []string) (*JobResults, error)
+}
+
+// JobResults は一括ダウンロードのために開いた各ジョブの主成果物です。
+// 使い終わったら Close で全てのファイルを閉じてください。
+type JobResults struct {
+	entries []jobResultEntry
+}
+
+type jobResultEntry struct {
+	name string // ZIP 内のパス（ジョブID/成果物名）
+	file File
+}
+
+// OpenJobResults は jobIDs の主成果物をすべて開きます。
+// 1件でも成果物が見つからなければ何も返さず JOB_RESULT_NOT_FOUND とするため、
+// レスポンスを書き始めた後に欠けが判明して不完全な ZIP を返すことはありません。
+// ctx のテナント以外が受け付けたジョブも、成果物が無い場合と同じく JOB_RESULT_NOT_FOUND とします。
+func (s *Service) OpenJobResults(ctx context.Context, jobIDs []string) (*JobResults, error) {
+	ids, err := normalizeBatchJobIDs(jobIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	owner := tenant.FromContext(ctx)
+	results := &JobResults{entries: make([]jobResultEntry, 0, len(ids))}
+	for _, id := range ids {
+		if err := ctx.Err(); err != nil {
+			results.Close()
+			return nil, err
+		}
+		notFound := func(err error) error {
+			return newError("JOB_RESULT_NOT_FOUND", fmt.Sprintf("ジョブ %s の成果物が見つかりませんでした。完了前か、保存期間を過ぎています。", id), err)
+		}
+		// 他のテナントのジョブは、存在を知られないよう成果物が無い場合と同じエラーにする
+		jobTenant, err := s.JobTenant(id)
+		if err != nil {
+			results.Close()
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil, notFound(err)
+			}
+			return nil, err
+		}
+		if jobTenant != owner {
+			results.Close()
+			return nil, notFound(nil)
+		}
+		result, file, err := s.OpenArtifact(id, "")
+		if err != nil {
+			results.Close()
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil, notFound(err)
+			}
+			return nil, err
+		}
+		// 同じ操作の成果物は同名になるため、ジョブごとのフォルダに分けて衝突を避ける
+		results.entries = append(results.entries, jobResultEntry{
+			name: path.Join(id, path.Base(result.OutputFilename)),
+			file: file,
+		})
+	}
+	return results, nil
+}
+
+// normalizeBatchJobIDs はジョブIDの形式と件数を検証し、重複を除いて指定順に返します。
+func normalizeBatchJobIDs(jobIDs []string) ([]string, error) {
+	seen := make(map[string]bool, len(jobIDs))
+	ids := make([]string, 0, len(jobIDs))
+	for _, raw := range jobIDs {
+		id := strings.TrimSpace(raw)
+		if seen[id] {
+			continue
+		}
+		// ジョブIDはワークスペースのディレクトリ名になるため、UUID 以外はパスとして扱わない
+		if _, err := uuid.Parse(id); err != nil {
+			return nil, newError("INVALID_INPUT", fmt.Sprintf("jobIds に不正なジョブID %q が含まれています。", raw), nil)
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		return nil, newError("INVALID_INPUT", "jobIds にダウンロードするジョブIDを1件以上指定してください。", nil)
+	}
+	if len(ids) > MaxBatchDownloadJobs {
+		return nil, newError("LIMIT_EXCEEDED", fmt.Sprintf("一度にダウンロードできるジョブは%d件までです。", MaxBatchDownloadJobs), nil)
+	}
+	return ids, nil
+}
+
+// WriteZIP は開いた成果物を1つの ZIP として w へ書き出します。
+// 成果物の多くは圧縮済みの PDF / ZIP のため、再圧縮せず無圧縮で格納します。
+func (r *JobResults) WriteZIP(w io.Writer) error {
+	zw := zip.NewWriter(w)
+	modified := time.Now()
+	for _, entry := range r.entries {
+		writer, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     entry.name,
+			Method:   zip.Store,
+			Modified: modified,
+		})
+		if err != nil {
+			return fmt.Errorf("zipヘッダーの書き込みに失敗しました: %w", err)
+		}
+		if _, err := io.Copy(writer, entry.file); err != nil {
+			return fmt.Errorf("zipへの書き込みに失敗しました (%s): %w", entry.name, err)
+		}
+	}
+	return zw.Close()
+}
+
+// Close は開いた成果物をすべて閉じます。
+func (r *JobResults) Close() {
+	for _, entry := range r.entries {
+		entry.file.Close()
+	}
+}
+
+type batchDownloadRequest struct {
+	JobIDs []string `json:"jobIds"`
+}
+
+// JobResultsDownloadHandler は POST /api/jobs/download のハンドラーを返します。
+// 指定したジョブの主成果物を1つの ZIP にまとめ、ディスクへ書き出さずにそのままストリーミングします。
+func JobResultsDownloadHandler(svc JobResultsService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req batchDownloadRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    "INVALID_INPUT",
+				"message": "jobIds にジョブIDの配列を指定してください。",
+			})
+			return
+		}
+
+		results, err := svc.OpenJobResults(c.Request.Context(), req.JobIDs)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+		defer results.Close()
+
+		c.Header("Content-Type", "application/zip")
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", batchDownloadFilename))
+		c.Header("Cache-Control", "no-store")
+		c.Status(http.StatusOK)
+		if err := results.WriteZIP(c.Writer); err != nil {
+			// ヘッダー送信後のためエラーレスポンスは返せない。途中で切れた ZIP はクライアント側で展開に失敗する
+			log.Printf("failed to stream batch download: %v", err)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package pdf
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/yourusername/paper-forge/internal/config"
+	"github.com/yourusername/paper-forge/internal/tenant"
+)
+
+func createFinishedJob(t *testing.T, svc *Service, fsys *memFS, op OperationType, filename string, payload []byte) string {
+	t.Helper()
+	ws, err := svc.createWorkspace()
+	if err != nil {
+		t.Fatalf("createWorkspace returned error: %v", err)
+	}
+	if err := writeManifest(fsys, ws.dir, &JobManifest{JobID: ws.jobID, Operation: op}); err != nil {
+		t.Fatalf("writeManifest returned error: %v", err)
+	}
+	out, err := fsys.OpenFile(filepath.Join(ws.outDir, filename), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o640)
+	if err != nil {
+		t.Fatalf("failed to create output: %v", err)
+	}
+	_, _ = out.Write(payload)
+	_ = out.Close()
+	return ws.jobID
+}
+
+func postBatchDownload(svc JobResultsService, body string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/jobs/download", JobResultsDownloadHandler(svc))
+
+	req := httptest.NewRequest(http.MethodPost, "/jobs/download", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestJobResultsDownloadHandler(t *testing.T) {
+	fsys := newMemFS()
+	svc := NewServiceWithFS(&config.Config{}, fsys)
+
+	first := createFinishedJob(t, svc, fsys, OperationOptimize, optimizedFilename, []byte("%PDF-1.4\n% first\n"))
+	second := createFinishedJob(t, svc, fsys, OperationOptimize, optimizedFilename, []byte("%PDF-1.4\n% second\n"))
+
+	w := postBatchDownload(svc, `{"jobIds":["`+first+`","`+second+`","`+first+`"]}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d body=%s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/zip" {
+		t.Fatalf("unexpected content type: %s", ct)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	if err != nil {
+		t.Fatalf("response is not a zip: %v", err)
+	}
+	want := []struct{ name, data string }{
+		{first + "/" + optimizedFilename, "%PDF-1.4\n% first\n"},
+		{second + "/" + optimizedFilename, "%PDF-1.4\n% second\n"},
+	}
+	if len(zr.File) != len(want) {
+		t.Fatalf("expected %d entries, got %d", len(want), len(zr.File))
+	}
+	for i, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("failed to open entry %s: %v", f.Name, err)
+		}
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		if f.Name != want[i].name || string(data) != want[i].data {
+			t.Fatalf("entry %d = %s %q, want %s %q", i, f.Name, data, want[i].name, want[i].data)
+		}
+	}
+}
+
+func TestJobResultsDownloadHandlerErrors(t *testing.T) {
+	fsys := newMemFS()
+	svc := NewServiceWithFS(&config.Config{}, fsys)
+	done := createFinishedJob(t, svc, fsys, OperationMerge, outputFilename, []byte("%PDF-1.4\n"))
+
+	cases := []struct {
+		name   string
+		body   string
+		status int
+		code   string
+	}{
+		{"missing job", `{"jobIds":["` + done + `","` + uuid.NewString() + `"]}`, http.StatusNotFound, "JOB_RESULT_NOT_FOUND"},
+		{"path traversal", `{"jobIds":["../secrets"]}`, http.StatusBadRequest, "INVALID_INPUT"},
+		{"empty", `{"jobIds":[]}`, http.StatusBadRequest, "INVALID_INPUT"},
+		{"not json", `jobIds=1`, http.StatusBadRequest, "INVALID_INPUT"},
+	}
+	for _, tc := range cases {
+		w := postBatchDownload(svc, tc.body)
+		if w.Code != tc.status || !bytes.Contains(w.Body.Bytes(), []byte(tc.code)) {
+			t.Fatalf("%s: status=%d body=%s, want %d %s", tc.name, w.Code, w.Body.String(), tc.status, tc.code)
+		}
+	}
+
+	ids := make([]string, MaxBatchDownloadJobs+1)
+	for i := range ids {
+		ids[i] = uuid.NewString()
+	}
+	if _, err := normalizeBatchJobIDs(ids); !IsError(err, "LIMIT_EXCEEDED") {
+		t.Fatalf("expected LIMIT_EXCEEDED, got %v", err)
+	}
+}
+
+func TestOpenJobResultsChecksTenant(t *testing.T) {
+	fsys := newMemFS()
+	svc := NewServiceWithFS(&config.Config{}, fsys)
+	id := createFinishedJob(t, svc, fsys, OperationMerge, outputFilename, []byte("%PDF-1.4\n"))
+	dir := svc.workspaceFor(id).dir
+	if err := writeManifest(fsys, dir, &JobManifest{JobID: id, Tenant: "sales", Operation: OperationMerge}); err != nil {
+		t.Fatalf("writeManifest returned error: %v", err)
+	}
+
+	for _, ctx := range []context.Context{context.Background(), tenant.WithID(context.Background(), "legal")} {
+		if _, err := svc.OpenJobResults(ctx, []string{id}); !IsError(err, "JOB_RESULT_NOT_FOUND") {
+			t.Fatalf("results of another tenant should not be opened (%s), got %v", tenant.FromContext(ctx), err)
+		}
+	}
+
+	results, err := svc.OpenJobResults(tenant.WithID(context.Background(), "sales"), []string{id})
+	if err != nil {
+		t.Fatalf("OpenJobResults returned error: %v", err)
+	}
+	results.Close()
+}
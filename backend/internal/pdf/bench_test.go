@@ -0,0 +1,98 @@
+package pdf
+
+import (
+	"context"
+	"mime/multipart"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/yourusername/paper-forge/internal/config"
+	"github.com/yourusername/paper-forge/internal/pdf/pdftest"
+)
+
+func newBenchService(b *testing.B) *Service {
+	b.Helper()
+	svc := NewService(&config.Config{
+		MaxFileSize:      1 << 30,
+		MaxPages:         10000,
+		JobExpireMinutes: 1,
+		GhostscriptPath:  "gs",
+	})
+	svc.tmpRoot = filepath.Join(b.TempDir(), "app")
+	return svc
+}
+
+func benchFiles(b *testing.B, files ...pdftest.File) []*multipart.FileHeader {
+	b.Helper()
+	headers, err := pdftest.FileHeaders("files[]", files...)
+	if err != nil {
+		b.Fatalf("failed to build multipart files: %v", err)
+	}
+	return headers
+}
+
+func BenchmarkMerge(b *testing.B) {
+	for _, tc := range []struct {
+		name  string
+		files int
+		pages int
+	}{
+		{name: "2x10pages", files: 2, pages: 10},
+		{name: "10x50pages", files: 10, pages: 50},
+	} {
+		b.Run(tc.name, func(b *testing.B) {
+			svc := newBenchService(b)
+			inputs := make([]pdftest.File, tc.files)
+			order := make([]int, tc.files)
+			for i := range inputs {
+				inputs[i] = pdftest.File{Name: "input.pdf", Data: pdftest.Synthetic(tc.pages, 2048)}
+				order[i] = i
+			}
+			headers := benchFiles(b, inputs...)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				result, err := svc.MergeMultipart(context.Background(), headers, order)
+				if err != nil {
+					b.Fatalf("merge failed: %v", err)
+				}
+				_ = result.Cleanup()
+			}
+		})
+	}
+}
+
+func BenchmarkSplit(b *testing.B) {
+	svc := newBenchService(b)
+	headers := benchFiles(b, pdftest.File{Name: "input.pdf", Data: pdftest.Synthetic(100, 2048)})
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		result, err := svc.SplitMultipart(context.Background(), headers[0], "1-25,26-50,51-75,76-100")
+		if err != nil {
+			b.Fatalf("split failed: %v", err)
+		}
+		_ = result.Cleanup()
+	}
+}
+
+func BenchmarkOptimize(b *testing.B) {
+	if _, err := exec.LookPath("gs"); err != nil {
+		b.Skip("Ghostscript is not installed")
+	}
+	svc := newBenchService(b)
+	headers := benchFiles(b, pdftest.File{Name: "input.pdf", Data: pdftest.Synthetic(20, 4096)})
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		result, err := svc.OptimizeMultipart(context.Background(), headers[0], OptimizePresetStandard)
+		if err != nil {
+			b.Fatalf("optimize failed: %v", err)
+		}
+		_ = result.Cleanup()
+	}
+}
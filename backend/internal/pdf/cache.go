@@ -0,0 +1,287 @@
+package pdf
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yourusername/paper-forge/internal/metrics"
+)
+
+const (
+	cacheDirName      = ".cache"
+	cacheEntryOutput  = "output-%02d"
+	cacheEntryPayload = "entry.json"
+)
+
+// cachedEntry は結果キャッシュに保存する成果物の付帯情報です。
+// 成果物ファイルは Artifacts の順に cacheEntryOutput の名前で保存します。
+type cachedEntry struct {
+	Artifacts []Artifact `json:"artifacts"`
+	// KeyID は成果物の暗号化に使った鍵のIDです。現在の鍵と異なるエントリは再利用しません。
+	KeyID string `json:"keyId,omitempty"`
+	// Watermark は成果物に付けた透かしの設定です。現在の設定と異なるエントリは再利用しません。
+	Watermark string `json:"watermark,omitempty"`
+	// PDFVersion は成果物を書き出した PDF のバージョンです。現在の設定と異なるエントリは再利用しません。
+	PDFVersion string          `json:"pdfVersion,omitempty"`
+	Meta       json.RawMessage `json:"meta,omitempty"`
+	CreatedAt  time.Time       `json:"createdAt"`
+}
+
+func (s *Service) resultCacheTTL() time.Duration {
+	if s.cfg == nil || s.cfg.ResultCacheMinutes <= 0 {
+		return 0
+	}
+	return time.Duration(s.cfg.ResultCacheMinutes) * time.Minute
+}
+
+// resultCacheKey は入力ファイルの内容ハッシュと正規化した処理オプションからキャッシュキーを生成します。
+// 配信先や通知先など成果物の内容に影響しないオプションはキーに含めません。
+func resultCacheKey(manifest *JobManifest) (string, bool) {
+	if manifest == nil || len(manifest.Files) == 0 {
+		return "", false
+	}
+	h := sha256.New()
+	fmt.Fprintf(h, "op=%s\n", manifest.Operation)
+	for _, f := range manifest.Files {
+		if f.SHA256 == "" {
+			return "", false
+		}
+		fmt.Fprintf(h, "file=%s\n", f.SHA256)
+	}
+	switch manifest.Operation {
+	case OperationMerge, OperationReorder:
+		order := make([]string, len(manifest.Order))
+		for i, v := range manifest.Order {
+			order[i] = strconv.Itoa(v)
+		}
+		fmt.Fprintf(h, "order=%s\n", strings.Join(order, ","))
+		if manifest.Mode != "" {
+			fmt.Fprintf(h, "mode=%s\n", manifest.Mode)
+		}
+		if manifest.TOC {
+			fmt.Fprintln(h, "toc=true")
+		}
+	case OperationSplit, OperationExtract:
+		fmt.Fprintf(h, "ranges=%s\n", strings.Join(strings.Fields(manifest.Ranges), ""))
+		if manifest.Output != "" {
+			fmt.Fprintf(h, "output=%s\n", manifest.Output)
+		}
+	case OperationOptimize:
+		fmt.Fprintf(h, "preset=%s\n", manifest.Preset)
+		fmt.Fprintf(h, "pages=%s\n", strings.Join(strings.Fields(manifest.Ranges), ""))
+		if manifest.ColorProfile != "" {
+			fmt.Fprintf(h, "colorProfile=%s\n", manifest.ColorProfile)
+		}
+	case OperationRotate:
+		for _, r := range manifest.Rotations {
+			fmt.Fprintf(h, "rotate=%s:%d\n", r.Pages, r.Angle)
+		}
+	case OperationWatermark:
+		if manifest.Watermark == nil {
+			return "", false
+		}
+		w := manifest.Watermark
+		fmt.Fprintf(h, "watermark=%q:%s\n", w.Text, w.description())
+		fmt.Fprintf(h, "pages=%s\n", strings.Join(strings.Fields(manifest.Ranges), ""))
+	case OperationStamp:
+		// 画像は入力ファイルのハッシュとしてキーに含まれる
+		if manifest.Stamp == nil {
+			return "", false
+		}
+		fmt.Fprintf(h, "stamp=%s\n", manifest.Stamp.description())
+		fmt.Fprintf(h, "pages=%s\n", strings.Join(strings.Fields(manifest.Ranges), ""))
+	case OperationMetadata:
+		if manifest.Metadata == nil {
+			return "", false
+		}
+		// map のキーは JSON で整列されるため、同じ変更内容は同じ表現になる
+		spec, err := json.Marshal(manifest.Metadata)
+		if err != nil {
+			return "", false
+		}
+		fmt.Fprintf(h, "metadata=%s\n", spec)
+	case OperationCompose:
+		for _, step := range manifest.Recipe {
+			fmt.Fprintf(h, "step=%d:%s\n", step.File, strings.Join(strings.Fields(step.Pages), ""))
+		}
+	case OperationNUp:
+		if manifest.NUp == nil {
+			return "", false
+		}
+		fmt.Fprintf(h, "nup=%d:%s\n", manifest.NUp.PerSheet, manifest.NUp.description())
+	case OperationResize:
+		if manifest.Resize == nil {
+			return "", false
+		}
+		fmt.Fprintf(h, "resize=%s:%s:%s\n", manifest.Resize.Paper, manifest.Resize.Mode, manifest.Resize.Orientation)
+	default:
+		return "", false
+	}
+	return hex.EncodeToString(h.Sum(nil)), true
+}
+
+// lookupCachedResult は有効期間内の同一リクエストの成果物があれば、ジョブのワークスペースへ複製して返します。
+func (s *Service) lookupCachedResult(ws workspace, manifest *JobManifest) (*Result, bool) {
+	ttl := s.resultCacheTTL()
+	// 処理記録・受領書はジョブごとに異なるため、付ける場合はキャッシュを使わない
+	if ttl <= 0 || s.audit.appliesTo(manifest.Operation) || manifest.Options.Receipt != "" {
+		return nil, false
+	}
+	key, ok := resultCacheKey(manifest)
+	if !ok {
+		return nil, false
+	}
+
+	entryDir := filepath.Join(s.tmpRoot, cacheDirName, key)
+	data, err := os.ReadFile(filepath.Join(entryDir, cacheEntryPayload))
+	if err != nil {
+		return nil, false
+	}
+	var entry cachedEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		_ = removeDir(entryDir)
+		return nil, false
+	}
+	if s.now().Sub(entry.CreatedAt) > ttl {
+		_ = removeDir(entryDir)
+		return nil, false
+	}
+
+	if len(entry.Artifacts) == 0 || entry.KeyID != s.activeKeyID() || entry.Watermark != s.watermark.signature(manifest.Operation) ||
+		entry.PDFVersion != s.outputPDFVersion(manifest.Options) {
+		return nil, false
+	}
+
+	artifacts := make([]Artifact, len(entry.Artifacts))
+	for i, a := range entry.Artifacts {
+		a.Path = filepath.Join(ws.outDir, filepath.Base(a.Name))
+		if err := linkOrCopy(filepath.Join(entryDir, fmt.Sprintf(cacheEntryOutput, i)), a.Path); err != nil {
+			return nil, false
+		}
+		artifacts[i] = a
+	}
+
+	s.scheduleCleanup(ws.dir)
+	metrics.ResultCacheHits.Add(string(manifest.Operation), 1)
+
+	result := newResult(ws, manifest.Operation, s.fs, artifacts, decodeResultMeta(manifest.Operation, entry.Meta))
+	result.Cached = true
+	return result, true
+}
+
+// storeCachedResult は成果物を結果キャッシュへ保存します。保存に失敗しても処理結果には影響しません。
+func (s *Service) storeCachedResult(manifest *JobManifest, result *Result) {
+	ttl := s.resultCacheTTL()
+	if ttl <= 0 || result == nil || s.audit.appliesTo(manifest.Operation) || manifest.Options.Receipt != "" {
+		return
+	}
+	key, ok := resultCacheKey(manifest)
+	if !ok {
+		return
+	}
+
+	var meta json.RawMessage
+	if result.Meta != nil {
+		raw, err := json.Marshal(result.Meta)
+		if err != nil {
+			return
+		}
+		meta = raw
+	}
+
+	cacheRoot := filepath.Join(s.tmpRoot, cacheDirName)
+	if err := os.MkdirAll(cacheRoot, 0o750); err != nil {
+		return
+	}
+	// 書き込み途中のエントリを参照しないよう、一時ディレクトリに作成してから差し替える
+	staging, err := os.MkdirTemp(cacheRoot, "tmp-")
+	if err != nil {
+		return
+	}
+	for i, a := range result.Artifacts {
+		if err := linkOrCopy(a.Path, filepath.Join(staging, fmt.Sprintf(cacheEntryOutput, i))); err != nil {
+			_ = removeDir(staging)
+			return
+		}
+	}
+	entry := cachedEntry{
+		Artifacts:  result.Artifacts,
+		KeyID:      s.activeKeyID(),
+		Watermark:  s.watermark.signature(manifest.Operation),
+		PDFVersion: s.outputPDFVersion(manifest.Options),
+		Meta:       meta,
+		CreatedAt:  s.now().UTC(),
+	}
+	if err := writeJSON(OSFS{}, filepath.Join(staging, cacheEntryPayload), entry); err != nil {
+		_ = removeDir(staging)
+		return
+	}
+
+	entryDir := filepath.Join(cacheRoot, key)
+	_ = removeDir(entryDir)
+	if err := os.Rename(staging, entryDir); err != nil {
+		_ = removeDir(staging)
+		return
+	}
+	time.AfterFunc(ttl, func() {
+		s.expireCacheEntry(entryDir, ttl)
+	})
+}
+
+// expireCacheEntry は期限切れのエントリのみを削除します。同じキーで再保存された新しいエントリは残します。
+func (s *Service) expireCacheEntry(entryDir string, ttl time.Duration) {
+	data, err := os.ReadFile(filepath.Join(entryDir, cacheEntryPayload))
+	if err != nil {
+		return
+	}
+	var entry cachedEntry
+	if err := json.Unmarshal(data, &entry); err == nil && s.now().Sub(entry.CreatedAt) < ttl {
+		return
+	}
+	_ = removeDir(entryDir)
+}
+
+// scheduleCleanup はジョブの有効期限経過後にワークスペースを削除します。
+func (s *Service) scheduleCleanup(dir string) {
+	expireMinutes := s.cfg.JobExpireMinutes
+	if expireMinutes <= 0 {
+		expireMinutes = defaultCleanupMin
+	}
+	time.AfterFunc(time.Duration(expireMinutes)*time.Minute, func() {
+		// リーガルホールド中のワークスペースは解除されるまで残す（解除時に改めて削除を予約する）
+		if s.workspaceHeld(dir) {
+			return
+		}
+		_ = removeDir(dir)
+		s.sharedInputs.sweep()
+	})
+}
+
+// linkOrCopy はハードリンクを試み、できない場合は内容をコピーします。
+func linkOrCopy(src, dst string) error {
+	if err := os.Link(src, dst); err == nil {
+		return nil
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o640)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,135 @@
+package pdf
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/yourusername/paper-forge/internal/config"
+)
+
+func TestResultCacheKey(t *testing.T) {
+	base := JobManifest{
+		Operation: OperationOptimize,
+		Files:     []JobFile{{SHA256: "abc"}},
+		Preset:    OptimizePresetStandard,
+	}
+	key, ok := resultCacheKey(&base)
+	if !ok {
+		t.Fatal("optimize should be cacheable")
+	}
+
+	tests := []struct {
+		name      string
+		mutate    func(m *JobManifest)
+		sameKey   bool
+		cacheable bool
+	}{
+		{
+			name: "delivery and notification do not change the output",
+			mutate: func(m *JobManifest) {
+				m.Options = JobOptions{
+					Delivery:      &DeliveryTarget{Method: DeliveryMethodSFTP},
+					NotifyWebhook: "https://hooks.slack.com/x",
+					Label:         "月次",
+				}
+			},
+			sameKey:   true,
+			cacheable: true,
+		},
+		{name: "page ranges", mutate: func(m *JobManifest) { m.Ranges = "1-3" }, cacheable: true},
+		{name: "different input", mutate: func(m *JobManifest) { m.Files = []JobFile{{SHA256: "def"}} }, cacheable: true},
+		{name: "different preset", mutate: func(m *JobManifest) { m.Preset = OptimizePresetAggressive }, cacheable: true},
+		{name: "input without a hash", mutate: func(m *JobManifest) { m.Files = []JobFile{{SHA256: ""}} }},
+		{name: "no inputs", mutate: func(m *JobManifest) { m.Files = nil }},
+		{name: "operation without a key", mutate: func(m *JobManifest) { m.Operation = "unknown" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := base
+			tt.mutate(&m)
+			got, ok := resultCacheKey(&m)
+			if ok != tt.cacheable {
+				t.Fatalf("cacheable = %v, want %v", ok, tt.cacheable)
+			}
+			if ok && (got == key) != tt.sameKey {
+				t.Fatalf("same key = %v, want %v", got == key, tt.sameKey)
+			}
+		})
+	}
+
+	spaced := base
+	spaced.Ranges = " 1 - 3 "
+	compact := base
+	compact.Ranges = "1-3"
+	spacedKey, _ := resultCacheKey(&spaced)
+	compactKey, _ := resultCacheKey(&compact)
+	if spacedKey != compactKey {
+		t.Fatal("page ranges should be normalized before hashing")
+	}
+}
+
+func TestResultCacheStoreAndLookup(t *testing.T) {
+	now := time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+	svc := NewService(&config.Config{MaxFileSize: 1 << 20, MaxPages: 20, ResultCacheMinutes: 10, JobExpireMinutes: 1})
+	svc.tmpRoot = t.TempDir()
+	svc.now = func() time.Time { return now }
+
+	manifest := &JobManifest{Operation: OperationOptimize, Files: []JobFile{{SHA256: "abc"}}, Preset: OptimizePresetStandard}
+	first := svc.workspaceFor("job-1")
+	if err := os.MkdirAll(first.outDir, 0o750); err != nil {
+		t.Fatalf("failed to create out dir: %v", err)
+	}
+	outPath := filepath.Join(first.outDir, optimizedFilename)
+	if err := os.WriteFile(outPath, []byte("%PDF-1.4\n% optimized\n"), 0o640); err != nil {
+		t.Fatalf("failed to write output: %v", err)
+	}
+	artifact, err := newArtifact(outPath, ResultKindPDF)
+	if err != nil {
+		t.Fatalf("newArtifact returned error: %v", err)
+	}
+	artifact.Name = optimizedFilename
+	svc.storeCachedResult(manifest, newResult(first, OperationOptimize, nil, []Artifact{artifact}, &OptimizeMeta{SavedBytes: 10}))
+
+	lookup := func(jobID string, m *JobManifest) (*Result, bool) {
+		ws := svc.workspaceFor(jobID)
+		if err := os.MkdirAll(ws.outDir, 0o750); err != nil {
+			t.Fatalf("failed to create out dir: %v", err)
+		}
+		return svc.lookupCachedResult(ws, m)
+	}
+
+	result, ok := lookup("job-2", manifest)
+	if !ok || !result.Cached {
+		t.Fatal("the same request should reuse the cached result")
+	}
+	data, err := os.ReadFile(result.OutputPath)
+	if err != nil || string(data) != "%PDF-1.4\n% optimized\n" || filepath.Dir(result.OutputPath) != svc.workspaceFor("job-2").outDir {
+		t.Fatalf("cached output should be copied into the new workspace: %s %q %v", result.OutputPath, data, err)
+	}
+	if result.Artifacts[0].SHA256 != artifact.SHA256 {
+		t.Fatalf("artifact digest should be kept: %+v", result.Artifacts[0])
+	}
+	if meta, ok := result.Meta.(*OptimizeMeta); !ok || meta.SavedBytes != 10 {
+		t.Fatalf("cached meta should be decoded into the operation's type, got %T %+v", result.Meta, result.Meta)
+	}
+
+	receipt := *manifest
+	receipt.Options.Receipt = ReceiptModeAttach
+	if _, ok := lookup("job-3", &receipt); ok {
+		t.Fatal("jobs with a receipt should not use the cache")
+	}
+
+	now = now.Add(11 * time.Minute)
+	if _, ok := lookup("job-4", manifest); ok {
+		t.Fatal("expired entries should not be reused")
+	}
+	if _, err := os.Stat(filepath.Join(svc.tmpRoot, cacheDirName)); err != nil {
+		t.Fatalf("cache root should remain: %v", err)
+	}
+	entries, _ := os.ReadDir(filepath.Join(svc.tmpRoot, cacheDirName))
+	if len(entries) != 0 {
+		t.Fatalf("expired entry should be removed, got %d entries", len(entries))
+	}
+}
@@ -0,0 +1,21 @@
+package pdf
+
+import "context"
+
+// runStep は context に対応していない処理（pdfcpu など）を1ステップとして実行します。
+// pdfcpu の処理は途中で止められないため、キャンセルは実行前と実行後にだけ確認し、実行中の処理は完了まで待ちます。
+// 複数のステップからなる処理では、ステップの間でキャンセルが反映されます。
+// 処理の完了を待ってから戻るため、呼び出し元が同時実行数の枠を返したりワークスペースを削除したりした後に、
+// 取り残された処理が動き続けることはありません。
+// 処理は呼び出し元の goroutine で実行するため、処理の中のパニックも呼び出し元へそのまま伝わり、
+// 非同期ジョブではワーカー（handlePDFTask）の recover でジョブの失敗として扱われます。
+func runStep(ctx context.Context, fn func() error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := fn(); err != nil {
+		return err
+	}
+	// 実行中にキャンセルされた場合は、出力を使わずに中断する
+	return ctx.Err()
+}
@@ -0,0 +1,63 @@
+package pdf
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestRunStep(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	stepErr := errors.New("pdfcpu failed")
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		fn      func(cancel context.CancelFunc) error
+		wantRun bool
+		wantErr error
+	}{
+		{name: "runs the step", ctx: context.Background(), fn: func(context.CancelFunc) error { return nil }, wantRun: true},
+		{name: "returns the step error", ctx: context.Background(), fn: func(context.CancelFunc) error { return stepErr }, wantRun: true, wantErr: stepErr},
+		{name: "skips the step after cancellation", ctx: canceled, fn: func(context.CancelFunc) error { return nil }, wantErr: context.Canceled},
+		{
+			name: "waits for the step and reports cancellation during it",
+			ctx:  context.Background(),
+			fn: func(cancel context.CancelFunc) error {
+				cancel()
+				return nil
+			},
+			wantRun: true,
+			wantErr: context.Canceled,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(tt.ctx)
+			defer cancel()
+			ran := false
+			err := runStep(ctx, func() error {
+				ran = true
+				return tt.fn(cancel)
+			})
+			if ran != tt.wantRun || !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Fatalf("ran=%v err=%v; want ran=%v err=%v", ran, err, tt.wantRun, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRunStepPanicReachesCallerRecover(t *testing.T) {
+	// pdfcpu のパニックはワーカーの recover で受け止めるため、別の goroutine でプロセスを落とさず呼び出し元へ伝わる
+	recovered := func() (r any) {
+		defer func() { r = recover() }()
+		_ = runStep(context.Background(), func() error {
+			panic("pdfcpu: index out of range")
+		})
+		return nil
+	}()
+	if recovered != "pdfcpu: index out of range" {
+		t.Fatalf("panic should propagate to the caller, recovered %v", recovered)
+	}
+}
@@ -0,0 +1,130 @@
+package pdf
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OperationCapability は操作1つ分の提供状況です。
+type OperationCapability struct {
+	Type    OperationType `json:"type"`
+	Enabled bool          `json:"enabled"`
+}
+
+// CapabilityLimits はサーバーが受け付ける入力の上限です。0 の項目は無制限を表します。
+type CapabilityLimits struct {
+	MaxFileSize    int64 `json:"maxFileSize"`              // 1ファイルの最大サイズ（バイト）
+	MaxTotalSize   int64 `json:"maxTotalSize"`             // 1リクエストの合計サイズ（バイト）
+	MaxFiles       int   `json:"maxFiles"`                 // 1リクエストのファイル数
+	MaxPages       int   `json:"maxPages"`                 // 1ファイルの最大ページ数
+	MaxMergePages  int   `json:"maxMergePages"`            // 結合する全ファイルの合計ページ数
+	SoftLimitBytes int64 `json:"softLimitBytes,omitempty"` // 確認が必要になるジョブの合計サイズ
+	SoftLimitPages int   `json:"softLimitPages,omitempty"` // 確認が必要になるジョブの合計ページ数
+}
+
+// Capabilities はこのデプロイで利用できる機能と上限の一覧です。
+// クライアントは上限を固定値で持たず、これを元に入力の検証や表示するツールを決めます。
+type Capabilities struct {
+	Operations        []OperationCapability `json:"operations"`
+	Limits            CapabilityLimits      `json:"limits"`
+	AcceptedMIMETypes []string              `json:"acceptedMimeTypes"`
+	OptimizePresets   []OptimizePreset      `json:"optimizePresets"`
+	// ColorProfiles は圧縮の colorProfile で選べる ICC プロファイルです（COLOR_PROFILE_* でパスを設定したもの）。
+	ColorProfiles []ColorProfile `json:"colorProfiles"`
+	// OCRLanguages は OCR で選択できる言語パックです（lang に "jpn+eng" のように + 区切りで指定）。
+	// TESSERACT_PATH が未設定の場合は空です。
+	OCRLanguages []string `json:"ocrLanguages"`
+}
+
+// CapabilitiesService は提供機能の一覧を返すサービスです。
+type CapabilitiesService interface {
+	Capabilities() Capabilities
+}
+
+// OperationGate は操作が有効かを判定します。
+type OperationGate interface {
+	OperationEnabled(op OperationType) bool
+}
+
+// parseOperationList は DISABLED_OPERATIONS などカンマ区切りの操作名の一覧を解析します。
+// 登録されていない操作名は unknown として返します。
+func parseOperationList(raw string) (ops map[OperationType]bool, unknown []string) {
+	ops = make(map[OperationType]bool)
+	for _, name := range strings.Split(raw, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if _, ok := lookupOperation(OperationType(name)); !ok {
+			unknown = append(unknown, name)
+			continue
+		}
+		ops[OperationType(name)] = true
+	}
+	return ops, unknown
+}
+
+func newDisabledOperations(raw string) map[OperationType]bool {
+	disabled, unknown := parseOperationList(raw)
+	if len(unknown) > 0 {
+		log.Printf("DISABLED_OPERATIONS contains unknown operations (ignored): %s", strings.Join(unknown, ", "))
+	}
+	return disabled
+}
+
+// OperationEnabled は操作 op が登録済みで、設定により無効化されていないかを返します。
+func (s *Service) OperationEnabled(op OperationType) bool {
+	if _, ok := lookupOperation(op); !ok {
+		return false
+	}
+	return !s.disabledOps[op]
+}
+
+// Capabilities は登録済みの操作と有効/無効（登録順）、および現在の設定による上限を返します。
+func (s *Service) Capabilities() Capabilities {
+	ops := Operations()
+	caps := Capabilities{
+		Operations: make([]OperationCapability, 0, len(ops)),
+		Limits: CapabilityLimits{
+			MaxFileSize:    s.cfg.MaxFileSize,
+			MaxTotalSize:   MaxUploadTotalBytes,
+			MaxFiles:       maxUploadFiles,
+			MaxPages:       s.cfg.MaxPages,
+			MaxMergePages:  s.cfg.MaxMergePages,
+			SoftLimitBytes: s.cfg.SoftLimitBytes,
+			SoftLimitPages: s.cfg.SoftLimitPages,
+		},
+		AcceptedMIMETypes: []string{"application/pdf"},
+		OptimizePresets:   []OptimizePreset{OptimizePresetStandard, OptimizePresetAggressive},
+		ColorProfiles:     s.ColorProfiles(),
+		OCRLanguages:      s.ocrLanguages(),
+	}
+	for _, op := range ops {
+		caps.Operations = append(caps.Operations, OperationCapability{Type: op, Enabled: s.OperationEnabled(op)})
+	}
+	return caps
+}
+
+// RequireOperation は無効化された操作へのリクエストを 403 OPERATION_DISABLED で拒否するミドルウェアです。
+func RequireOperation(gate OperationGate, op OperationType) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !gate.OperationEnabled(op) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"code":    "OPERATION_DISABLED",
+				"message": "この機能は現在の環境では利用できません。",
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// CapabilitiesHandler は GET /api/capabilities のハンドラーを返します。
+func CapabilitiesHandler(svc CapabilitiesService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, svc.Capabilities())
+	}
+}
@@ -0,0 +1,49 @@
+package pdf
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yourusername/paper-forge/internal/config"
+)
+
+func TestDisabledOperations(t *testing.T) {
+	svc := NewServiceWithFS(&config.Config{DisabledOperations: " Optimize, ocr ,", MaxFileSize: 1 << 20, MaxPages: 50}, newMemFS())
+
+	if svc.OperationEnabled(OperationOptimize) {
+		t.Fatal("optimize should be disabled")
+	}
+	if !svc.OperationEnabled(OperationMerge) {
+		t.Fatal("merge should be enabled")
+	}
+	if svc.OperationEnabled(OperationType("ocr")) {
+		t.Fatal("unregistered operations are never enabled")
+	}
+
+	caps := svc.Capabilities()
+	if len(caps.Operations) != len(Operations()) {
+		t.Fatalf("unexpected capabilities: %+v", caps)
+	}
+	if caps.Limits.MaxFileSize != 1<<20 || caps.Limits.MaxPages != 50 || caps.Limits.MaxFiles != maxUploadFiles {
+		t.Fatalf("unexpected limits: %+v", caps.Limits)
+	}
+	for _, op := range caps.Operations {
+		if op.Enabled != (op.Type != OperationOptimize) {
+			t.Fatalf("unexpected flag for %s: %v", op.Type, op.Enabled)
+		}
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/api/pdf/optimize", RequireOperation(svc, OperationOptimize), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/pdf/optimize", nil))
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("unexpected status: %d body=%s", rec.Code, rec.Body.String())
+	}
+}
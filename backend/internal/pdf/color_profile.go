@@ -0,0 +1,165 @@
+package pdf
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	pdfapi "github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+
+	"github.com/yourusername/paper-forge/internal/config"
+)
+
+// ColorProfile は圧縮で色を変換し、出力インテントとして埋め込む ICC プロファイルです。
+type ColorProfile string
+
+const (
+	// ColorProfileSRGB は画面表示・社内配布向けの sRGB です。
+	ColorProfileSRGB ColorProfile = "srgb"
+	// ColorProfileFOGRA39 は商業印刷（コート紙のオフセット印刷）向けの CMYK です。
+	ColorProfileFOGRA39 ColorProfile = "fogra39"
+	// colorProfileNone はリクエストで既定のプロファイル（COLOR_PROFILE）を使わないことを表します。
+	colorProfileNone = "none"
+)
+
+// colorProfileSpec は ICC プロファイルのファイルと、Ghostscript の色変換・出力インテントに使う値です。
+type colorProfileSpec struct {
+	path string
+	// strategy は Ghostscript の ColorConversionStrategy（RGB / CMYK）です。
+	strategy string
+	// condition は出力インテントの OutputConditionIdentifier です。
+	condition string
+	info      string
+}
+
+// newColorProfiles は COLOR_PROFILE_* でパスを設定した ICC プロファイルを返します。
+func newColorProfiles(cfg *config.Config) map[ColorProfile]colorProfileSpec {
+	profiles := make(map[ColorProfile]colorProfileSpec)
+	if path := strings.TrimSpace(cfg.ColorProfileSRGB); path != "" {
+		profiles[ColorProfileSRGB] = colorProfileSpec{path: path, strategy: "RGB", condition: "sRGB", info: "sRGB IEC61966-2.1"}
+	}
+	if path := strings.TrimSpace(cfg.ColorProfileFOGRA39); path != "" {
+		profiles[ColorProfileFOGRA39] = colorProfileSpec{path: path, strategy: "CMYK", condition: "FOGRA39", info: "Coated FOGRA39 (ISO 12647-2:2004)"}
+	}
+	return profiles
+}
+
+// ColorProfiles はこのデプロイで選べる ICC プロファイルを返します。
+func (s *Service) ColorProfiles() []ColorProfile {
+	names := make([]ColorProfile, 0, len(s.colorProfiles))
+	for name := range s.colorProfiles {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+	return names
+}
+
+// resolveColorProfile は圧縮の colorProfile 項目を検証します。
+// 空の場合は既定のプロファイル（COLOR_PROFILE）を、none の場合は色を変換しないことを表す空を返します。
+func (s *Service) resolveColorProfile(raw string) (ColorProfile, error) {
+	name := strings.ToLower(strings.TrimSpace(raw))
+	switch name {
+	case "":
+		if s.cfg == nil {
+			return "", nil
+		}
+		name = s.cfg.ColorProfile
+	case colorProfileNone:
+		return "", nil
+	}
+	if name == "" {
+		return "", nil
+	}
+	profile := ColorProfile(name)
+	if profile != ColorProfileSRGB && profile != ColorProfileFOGRA39 {
+		return "", newError("INVALID_INPUT", "colorProfile には srgb / fogra39 / none のいずれかを指定してください。", nil)
+	}
+	if _, ok := s.colorProfiles[profile]; !ok {
+		return "", newError("INVALID_INPUT", fmt.Sprintf("この環境では colorProfile=%s は利用できません。", profile), nil)
+	}
+	return profile, nil
+}
+
+// ghostscriptArgs は ICC プロファイルで色を変換する Ghostscript の引数です。
+// Ghostscript は既定で -dSAFER のため、プロファイルのファイルの読み込みを許可します。
+func (p colorProfileSpec) ghostscriptArgs() []string {
+	return []string{
+		"--permit-file-read=" + p.path,
+		"-sColorConversionStrategy=" + p.strategy,
+		"-dProcessColorModel=/Device" + p.strategy,
+		"-sOutputICCProfile=" + p.path,
+	}
+}
+
+// iccComponents は ICC プロファイルのヘッダーの色空間から色成分の数を返します。
+func iccComponents(profile []byte) (int, error) {
+	// ヘッダーは128バイトで、16バイト目から4バイトが色空間のシグネチャ
+	if len(profile) < 128 || string(profile[36:40]) != "acsp" {
+		return 0, fmt.Errorf("ICC プロファイルではありません")
+	}
+	switch string(profile[16:20]) {
+	case "GRAY":
+		return 1, nil
+	case "RGB ":
+		return 3, nil
+	case "CMYK":
+		return 4, nil
+	default:
+		return 0, fmt.Errorf("対応していない色空間の ICC プロファイルです: %q", profile[16:20])
+	}
+}
+
+// embedOutputIntent は PDF のカタログに ICC プロファイルの出力インテントを設定します。既存の出力インテントは置き換えます。
+// 印刷所や検版ツールが、どの印刷条件を前提に色を作ったかを判断できるようにします。
+func embedOutputIntent(path string, p colorProfileSpec) error {
+	profile, err := os.ReadFile(p.path)
+	if err != nil {
+		return fmt.Errorf("ICC プロファイルを読み込めませんでした: %w", err)
+	}
+	components, err := iccComponents(profile)
+	if err != nil {
+		return fmt.Errorf("%s: %w", p.path, err)
+	}
+
+	pdfCtx, err := pdfapi.ReadContextFile(path)
+	if err != nil {
+		return err
+	}
+	root, err := pdfCtx.Catalog()
+	if err != nil {
+		return err
+	}
+	sd := types.StreamDict{Dict: types.NewDict(), Content: profile}
+	sd.Insert("N", types.Integer(components))
+	sd.InsertName("Filter", "FlateDecode")
+	sd.FilterPipeline = []types.PDFFilter{{Name: "FlateDecode"}}
+	if err := sd.Encode(); err != nil {
+		return err
+	}
+	profileRef, err := pdfCtx.IndRefForNewObject(sd)
+	if err != nil {
+		return err
+	}
+
+	intent := types.NewDict()
+	intent.InsertName("Type", "OutputIntent")
+	intent.InsertName("S", "GTS_PDFX")
+	intent.Insert("OutputConditionIdentifier", types.StringLiteral(p.condition))
+	intent.Insert("Info", types.StringLiteral(p.info))
+	intent.Insert("RegistryName", types.StringLiteral("http://www.color.org"))
+	intent.Insert("DestOutputProfile", *profileRef)
+	intentRef, err := pdfCtx.IndRefForNewObject(intent)
+	if err != nil {
+		return err
+	}
+	root.Update("OutputIntents", types.Array{*intentRef})
+
+	tmp := path + ".icc"
+	if err := pdfapi.WriteContextFile(pdfCtx, tmp); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,143 @@
+package pdf
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	pdfapi "github.com/pdfcpu/pdfcpu/pkg/api"
+
+	"github.com/yourusername/paper-forge/internal/config"
+	"github.com/yourusername/paper-forge/internal/execrun"
+	"github.com/yourusername/paper-forge/internal/pdf/pdftest"
+)
+
+// writeTestICC は色空間 space（"RGB " / "CMYK"）のヘッダーだけを持つ ICC プロファイルを書き出します。
+func writeTestICC(t *testing.T, space string) string {
+	t.Helper()
+	profile := make([]byte, 128)
+	copy(profile[16:20], space)
+	copy(profile[36:40], "acsp")
+	profile = append(profile, []byte("test-icc-body")...)
+	path := filepath.Join(t.TempDir(), strings.TrimSpace(space)+".icc")
+	if err := os.WriteFile(path, profile, 0o640); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestResolveColorProfile(t *testing.T) {
+	svc := NewService(&config.Config{WorkDir: t.TempDir(), ColorProfile: "srgb", ColorProfileSRGB: writeTestICC(t, "RGB ")})
+	cases := []struct {
+		raw  string
+		want ColorProfile
+		err  bool
+	}{
+		{raw: "", want: ColorProfileSRGB},
+		{raw: " SRGB ", want: ColorProfileSRGB},
+		{raw: "none", want: ""},
+		{raw: "fogra39", err: true}, // パスが未設定
+		{raw: "adobergb", err: true},
+	}
+	for _, tc := range cases {
+		got, err := svc.resolveColorProfile(tc.raw)
+		var apiErr *Error
+		if tc.err != (err != nil) || (err != nil && (!errors.As(err, &apiErr) || apiErr.Code != "INVALID_INPUT")) || got != tc.want {
+			t.Fatalf("%q: got %q, %v", tc.raw, got, err)
+		}
+	}
+	if profiles := svc.ColorProfiles(); len(profiles) != 1 || profiles[0] != ColorProfileSRGB {
+		t.Fatalf("only configured profiles should be listed: %v", profiles)
+	}
+}
+
+func TestIccComponents(t *testing.T) {
+	for space, want := range map[string]int{"GRAY": 1, "RGB ": 3, "CMYK": 4} {
+		data, _ := os.ReadFile(writeTestICC(t, space))
+		if got, err := iccComponents(data); err != nil || got != want {
+			t.Fatalf("%q: got %d, %v", space, got, err)
+		}
+	}
+	if _, err := iccComponents([]byte("not a profile")); err == nil {
+		t.Fatal("non-ICC data should be rejected")
+	}
+}
+
+func TestExecuteOptimizeConvertsToColorProfile(t *testing.T) {
+	original := []byte("%PDF-1.4\n% original\n")
+	svc := NewService(&config.Config{OptimizeKeepOriginal: true, JobExpireMinutes: 1, ColorProfileFOGRA39: writeTestICC(t, "CMYK")})
+	svc.gs.once.Do(func() { svc.gs.path = "/usr/bin/gs" })
+	// 変換後の出力は元より大きい実際の PDF にし、元のファイルに戻されずに出力インテントが埋め込まれることを確かめる
+	runner := &recordingOutputRunner{outputWritingRunner: outputWritingRunner{output: pdftest.Synthetic(1, 4096)}}
+	svc.runner = runner
+	state := newOptimizeTestState(t, svc, original)
+	state.colorProfile = ColorProfileFOGRA39
+
+	result, err := svc.executeOptimize(context.Background(), state, nil)
+	if err != nil {
+		t.Fatalf("executeOptimize returned error: %v", err)
+	}
+	args := strings.Join(runner.args, " ")
+	for _, want := range []string{"-sColorConversionStrategy=CMYK", "-dProcessColorModel=/DeviceCMYK", "-sOutputICCProfile=", "--permit-file-read="} {
+		if !strings.Contains(args, want) {
+			t.Fatalf("missing %s in %s", want, args)
+		}
+	}
+	meta := result.Meta.(*OptimizeMeta)
+	if meta.NoGainApplied || meta.ColorProfile != ColorProfileFOGRA39 {
+		t.Fatalf("converted output must not be replaced by the original: %+v", meta)
+	}
+	assertOutputIntent(t, result.OutputPath, "CMYK", 4)
+}
+
+// assertOutputIntent は path の PDF のカタログに、writeTestICC で作った色空間 space（成分数 components）のプロファイルを持つ出力インテントがあることを確かめます。
+func assertOutputIntent(t *testing.T, path, space string, components int) {
+	t.Helper()
+	pdfCtx, err := pdfapi.ReadContextFile(path)
+	if err != nil {
+		t.Fatalf("output should be a readable PDF: %v", err)
+	}
+	root, err := pdfCtx.Catalog()
+	if err != nil {
+		t.Fatal(err)
+	}
+	intents, err := pdfCtx.DereferenceArray(root["OutputIntents"])
+	if err != nil || len(intents) != 1 {
+		t.Fatalf("catalog should have one output intent: %v %v", intents, err)
+	}
+	intent, err := pdfCtx.DereferenceDict(intents[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := intent.NameEntry("S"); s == nil || *s != "GTS_PDFX" {
+		t.Fatalf("unexpected output intent subtype: %v", intent)
+	}
+	profile, _, err := pdfCtx.DereferenceStreamDict(intent["DestOutputProfile"])
+	if err != nil || profile == nil {
+		t.Fatalf("output intent should reference the profile stream: %v", err)
+	}
+	if err := profile.Decode(); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(profile.Content[16:20], []byte(space)) || !bytes.HasSuffix(profile.Content, []byte("test-icc-body")) {
+		t.Fatalf("embedded profile should be the configured one: %q", profile.Content)
+	}
+	if n := profile.IntEntry("N"); n == nil || *n != components {
+		t.Fatalf("profile stream should declare its components: %v", n)
+	}
+}
+
+// recordingOutputRunner は outputWritingRunner と同じく出力を書き込み、渡された引数を記録します。
+type recordingOutputRunner struct {
+	outputWritingRunner
+	args []string
+}
+
+func (r *recordingOutputRunner) Run(ctx context.Context, cmd execrun.Command) (execrun.Output, error) {
+	r.args = cmd.Args
+	return r.outputWritingRunner.Run(ctx, cmd)
+}
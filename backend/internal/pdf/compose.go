@@ -0,0 +1,334 @@
+package pdf
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	pdfapi "github.com/pdfcpu/pdfcpu/pkg/api"
+
+	"github.com/yourusername/paper-forge/internal/tenant"
+)
+
+const (
+	composeFilename = "composed.pdf"
+	// maxComposeSteps はレシピの手順の最大数です。ページ選択の画面は1ページずつの手順を送ることがあるため、多めにしています。
+	maxComposeSteps = 1000
+)
+
+// ComposeStep は組み立てるPDFに入力ファイル File（files[] の0始まりの番号）のページ Pages を追加する手順です。
+// Pages は extract と同じ範囲指定で、空の場合は全ページです。
+type ComposeStep struct {
+	File  int    `json:"file"`
+	Pages string `json:"pages,omitempty"`
+}
+
+// checkFile は i 番目の手順のファイル番号が files[] の件数 n の範囲内であることを確認します。
+func (step ComposeStep) checkFile(i, n int) error {
+	if step.File < 0 || step.File >= n {
+		return newError("INVALID_INPUT", fmt.Sprintf("recipe[%d] の file に存在しないファイルの番号が指定されています。", i), nil)
+	}
+	return nil
+}
+
+// parseComposeRecipe はフォーム項目 recipe の JSON（例: [{"file":0,"pages":"1-3"},{"file":2,"pages":"5"}]）を解析します。
+// ファイル番号とページ範囲は入力の保存後に resolveComposeRecipe で確認します。
+func parseComposeRecipe(raw string) ([]ComposeStep, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, newError("INVALID_INPUT", "組み立てる手順を recipe に JSON で指定してください。", nil)
+	}
+	var recipe []ComposeStep
+	dec := json.NewDecoder(strings.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&recipe); err != nil {
+		return nil, newError("INVALID_INPUT", `recipe は JSON の配列で指定してください。例: [{"file":0,"pages":"1-3"}]`, err)
+	}
+	switch {
+	case len(recipe) == 0:
+		return nil, newError("INVALID_INPUT", "recipe に手順を1つ以上指定してください。", nil)
+	case len(recipe) > maxComposeSteps:
+		return nil, newError("LIMIT_EXCEEDED", fmt.Sprintf("recipe の手順は最大%d件までです。", maxComposeSteps), nil)
+	}
+	for i := range recipe {
+		recipe[i].Pages = strings.TrimSpace(recipe[i].Pages)
+	}
+	return recipe, nil
+}
+
+// resolveComposeRecipe は各手順のページ範囲を入力のページ数で解析し、手順ごとの範囲と組み立て後のページ数を返します。
+// 組み立て後のページ数は結合と同じく MAX_MERGE_PAGES で制限します。
+func (s *Service) resolveComposeRecipe(recipe []ComposeStep, files []storedFile) ([][]PageRange, int, error) {
+	ranges := make([][]PageRange, len(recipe))
+	total := 0
+	for i, step := range recipe {
+		if err := step.checkFile(i, len(files)); err != nil {
+			return nil, 0, err
+		}
+		pages := files[step.File].pages
+		if step.Pages == "" {
+			ranges[i] = []PageRange{{Start: 1, End: pages}}
+		} else {
+			parsed, err := parseOrderedPageRanges(step.Pages, pages)
+			if err != nil {
+				var apiErr *Error
+				if errors.As(err, &apiErr) {
+					return nil, 0, newError(apiErr.Code, fmt.Sprintf("recipe[%d]: %s", i, apiErr.Message), err)
+				}
+				return nil, 0, err
+			}
+			ranges[i] = parsed
+		}
+		for _, pr := range ranges[i] {
+			total += pr.count()
+		}
+		if s.cfg.MaxMergePages > 0 && total > s.cfg.MaxMergePages {
+			return nil, 0, newError("LIMIT_EXCEEDED", fmt.Sprintf("組み立てるPDFのページ数が上限(%dページ)を超えています。", s.cfg.MaxMergePages), nil)
+		}
+	}
+	return ranges, total, nil
+}
+
+// ComposeMultipart は複数のPDFから recipe の順にページを取り出し、1つのPDFを組み立てます。
+// 結合・並べ替え・抽出を1回で行う処理で、同じファイルのページを何度でも、どの順序でも使えます。
+func (s *Service) ComposeMultipart(ctx context.Context, files []*multipart.FileHeader, recipe []ComposeStep) (_ *Result, err error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	state, _, err := s.prepareCompose(ctx, files, recipe, JobOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = removeDir(state.ws.dir)
+		}
+	}()
+
+	result, execErr := s.executeCompose(ctx, state, nil)
+	if execErr != nil {
+		return nil, execErr
+	}
+	return result, nil
+}
+
+type composeState struct {
+	ws          workspace
+	storedFiles []storedFile
+	recipe      []ComposeStep
+}
+
+func (s *Service) prepareCompose(ctx context.Context, files []*multipart.FileHeader, recipe []ComposeStep, opts JobOptions) (*composeState, *JobManifest, error) {
+	if err := validateMergeInputs(files, nil); err != nil {
+		return nil, nil, err
+	}
+	if len(recipe) == 0 {
+		return nil, nil, newError("INVALID_INPUT", "recipe に手順を1つ以上指定してください。", nil)
+	}
+
+	release, err := s.reserveWorkspace(files...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer release()
+
+	ws, err := s.createWorkspace()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	storedFiles, err := s.storeMultipartFiles(ctx, files, ws.inDir)
+	if err != nil {
+		_ = removeDir(ws.dir)
+		return nil, nil, err
+	}
+
+	// DEFER_PAGE_COUNT でページ数を確認していない場合は、範囲の確認を実行時に行う
+	if !s.cfg.DeferPageCount {
+		if _, _, err := s.resolveComposeRecipe(recipe, storedFiles); err != nil {
+			_ = removeDir(ws.dir)
+			return nil, nil, err
+		}
+	} else {
+		for i, step := range recipe {
+			if err := step.checkFile(i, len(storedFiles)); err != nil {
+				_ = removeDir(ws.dir)
+				return nil, nil, err
+			}
+		}
+	}
+
+	manifest := &JobManifest{
+		JobID:         ws.jobID,
+		Tenant:        tenant.FromContext(ctx),
+		Operation:     OperationCompose,
+		Files:         toJobFiles(storedFiles),
+		Recipe:        append([]ComposeStep(nil), recipe...),
+		Options:       opts,
+		PagesDeferred: s.cfg.DeferPageCount,
+		StoreMs:       ws.elapsed().Milliseconds(),
+		CreatedAt:     s.now().UTC(),
+	}
+	if err := writeManifest(s.fs, ws.dir, manifest); err != nil {
+		_ = removeDir(ws.dir)
+		return nil, nil, fmt.Errorf("ジョブマニフェストの保存に失敗しました: %w", err)
+	}
+
+	return &composeState{ws: ws, storedFiles: storedFiles, recipe: recipe}, manifest, nil
+}
+
+// executeCompose は手順ごとにページを取り出した一時ファイルを作り、手順の順に結合します。
+func (s *Service) executeCompose(ctx context.Context, state *composeState, progress ProgressReporter) (*Result, error) {
+	ws := state.ws
+	ranges, totalPages, err := s.resolveComposeRecipe(state.recipe, state.storedFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	reportProgress(progress, StageProcess, 0)
+	outputPath := filepath.Join(ws.outDir, composeFilename)
+	parts := make([]string, len(state.recipe))
+	defer func() {
+		for _, part := range parts {
+			if part != "" && part != outputPath {
+				_ = os.Remove(part)
+			}
+		}
+	}()
+	for i, step := range state.recipe {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		var selectedPages []string
+		for _, pr := range ranges[i] {
+			selectedPages = append(selectedPages, buildPageSelection(pr)...)
+		}
+		// 手順が1つの場合は結合せずにそのまま成果物にする
+		part := outputPath
+		if len(state.recipe) > 1 {
+			part = filepath.Join(ws.dir, fmt.Sprintf("compose-%04d.pdf", i+1))
+		}
+		parts[i] = part
+		src := state.storedFiles[step.File].path
+		if err := runStep(ctx, func() error { return pdfapi.CollectFile(src, part, selectedPages, nil) }); err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return nil, ctxErr
+			}
+			return nil, newError("UNSUPPORTED_PDF", fmt.Sprintf("recipe[%d] のページの取り出しに失敗しました。ファイルが破損していないか確認してください。", i), err)
+		}
+		reportProgress(progress, StageProcess, float64(i+1)/float64(len(state.recipe)+1))
+	}
+	if len(parts) > 1 {
+		if err := runStep(ctx, func() error { return mergeCreateFileCompat(parts, outputPath) }); err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return nil, ctxErr
+			}
+			return nil, newError("UNSUPPORTED_PDF", "PDFの組み立てに失敗しました。ファイルが破損していないか確認してください。", err)
+		}
+	}
+	reportProgress(progress, StageWrite, 0)
+
+	sources := make([]SourceFileMeta, len(state.storedFiles))
+	for i, sf := range state.storedFiles {
+		sources[i] = SourceFileMeta{
+			Name:  sf.originalName,
+			Size:  sf.size,
+			Pages: sf.pages,
+		}
+	}
+
+	meta := struct {
+		Type      OperationType    `json:"type"`
+		CreatedAt string           `json:"createdAt"`
+		Files     []SourceFileMeta `json:"files"`
+		Recipe    []ComposeStep    `json:"recipe"`
+		Output    string           `json:"output"`
+		Pages     int              `json:"pages"`
+	}{
+		Type:      OperationCompose,
+		CreatedAt: s.now().UTC().Format(time.RFC3339),
+		Files:     sources,
+		Recipe:    state.recipe,
+		Output:    composeFilename,
+		Pages:     totalPages,
+	}
+
+	metaPath := filepath.Join(ws.dir, "meta.json")
+	if err := writeJSON(s.fs, metaPath, meta); err != nil {
+		return nil, fmt.Errorf("メタデータの保存に失敗しました: %w", err)
+	}
+
+	s.scheduleCleanup(ws.dir)
+
+	artifact, err := newArtifact(outputPath, ResultKindPDF)
+	if err != nil {
+		return nil, fmt.Errorf("成果物の確認に失敗しました: %w", err)
+	}
+
+	reportProgress(progress, StageCompleted, 1)
+
+	return newResult(ws, OperationCompose, s.fs, []Artifact{artifact}, &ComposeMeta{
+		TotalPages: totalPages,
+		Sources:    sources,
+		Recipe:     state.recipe,
+	}), nil
+}
+
+// PrepareComposeJob は非同期ジョブ用に入力を保存します。
+func (s *Service) PrepareComposeJob(ctx context.Context, files []*multipart.FileHeader, recipe []ComposeStep, opts JobOptions) (*JobManifest, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	_, manifest, err := s.prepareCompose(ctx, files, recipe, opts)
+	if err != nil {
+		return nil, err
+	}
+	return s.sealJob(manifest)
+}
+
+func init() {
+	registerOperation(composeOperation{})
+}
+
+type composeOperation struct{}
+
+func (composeOperation) Type() OperationType { return OperationCompose }
+
+func (composeOperation) OutputSpec() OutputSpec {
+	return OutputSpec{Filename: composeFilename, Kind: ResultKindPDF}
+}
+
+func (composeOperation) Prepare(ctx context.Context, s *Service, req PrepareRequest) (*JobManifest, error) {
+	recipe, err := parseComposeRecipe(req.Params.Get("recipe"))
+	if err != nil {
+		return nil, err
+	}
+	return s.PrepareComposeJob(ctx, req.Files, recipe, req.Options)
+}
+
+func (composeOperation) Execute(ctx context.Context, s *Service, job *jobInput, progress ProgressReporter) (*Result, error) {
+	if len(job.manifest.Recipe) == 0 {
+		return nil, newError("INVALID_INPUT", "組み立てる手順がありません。", nil)
+	}
+	state := &composeState{ws: job.ws, storedFiles: job.files, recipe: job.manifest.Recipe}
+	return s.executeCompose(ctx, state, progress)
+}
+
+func (composeOperation) Plan(s *Service, job *jobInput) (*JobPlan, error) {
+	_, total, err := s.resolveComposeRecipe(job.manifest.Recipe, job.files)
+	if err != nil {
+		return nil, err
+	}
+	return &JobPlan{Outputs: []PlannedOutput{{Name: composeFilename, Kind: ResultKindPDF, Pages: total}}}, nil
+}
@@ -0,0 +1,106 @@
+package pdf
+
+import (
+	"context"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/yourusername/paper-forge/internal/config"
+	"github.com/yourusername/paper-forge/internal/pdf/pdftest"
+)
+
+func TestParseComposeRecipe(t *testing.T) {
+	recipe, err := parseComposeRecipe(`[{"file":0,"pages":" 1-3 "},{"file":2,"pages":"5"},{"file":1}]`)
+	if err != nil {
+		t.Fatalf("parseComposeRecipe returned error: %v", err)
+	}
+	want := []ComposeStep{{File: 0, Pages: "1-3"}, {File: 2, Pages: "5"}, {File: 1}}
+	if !reflect.DeepEqual(recipe, want) {
+		t.Fatalf("got %+v, want %+v", recipe, want)
+	}
+
+	for _, raw := range []string{"", "[]", "{}", `[{"file":"0"}]`, `[{"file":0,"range":"1"}]`} {
+		if _, err := parseComposeRecipe(raw); !IsError(err, "INVALID_INPUT") {
+			t.Fatalf("%q: expected INVALID_INPUT, got %v", raw, err)
+		}
+	}
+}
+
+func TestResolveComposeRecipe(t *testing.T) {
+	svc := NewService(&config.Config{MaxMergePages: 10})
+	files := []storedFile{{pages: 4}, {pages: 6}}
+
+	ranges, total, err := svc.resolveComposeRecipe([]ComposeStep{{File: 1, Pages: "6-5"}, {File: 0}, {File: 1, Pages: "1"}}, files)
+	if err != nil {
+		t.Fatalf("resolveComposeRecipe returned error: %v", err)
+	}
+	if total != 7 || !reflect.DeepEqual(ranges[1], []PageRange{{Start: 1, End: 4}}) {
+		t.Fatalf("unexpected resolution: %v, %d", ranges, total)
+	}
+
+	if _, _, err := svc.resolveComposeRecipe([]ComposeStep{{File: 2}}, files); !IsError(err, "INVALID_INPUT") {
+		t.Fatalf("unknown file should be rejected, got %v", err)
+	}
+	if _, _, err := svc.resolveComposeRecipe([]ComposeStep{{File: 0, Pages: "5"}}, files); !IsError(err, "INVALID_INPUT") {
+		t.Fatalf("out-of-range pages should be rejected, got %v", err)
+	}
+	if _, _, err := svc.resolveComposeRecipe([]ComposeStep{{File: 1}, {File: 1}}, files); !IsError(err, "LIMIT_EXCEEDED") {
+		t.Fatalf("MAX_MERGE_PAGES should apply to the composed output, got %v", err)
+	}
+}
+
+func TestPrepareComposeJobThroughRegistry(t *testing.T) {
+	svc := NewService(&config.Config{MaxFileSize: 1 << 20, MaxPages: 20})
+	svc.tmpRoot = t.TempDir()
+	headers, err := pdftest.FileHeaders("files[]",
+		pdftest.File{Name: "a.pdf", Data: pdftest.Synthetic(3, 0)},
+		pdftest.File{Name: "b.pdf", Data: pdftest.Synthetic(5, 0)},
+	)
+	if err != nil {
+		t.Fatalf("failed to build multipart files: %v", err)
+	}
+
+	manifest, err := svc.PrepareJob(context.Background(), OperationCompose, PrepareRequest{
+		Files:  headers,
+		Params: url.Values{"recipe": {`[{"file":1,"pages":"5"},{"file":0,"pages":"1-2"},{"file":1,"pages":"1"}]`}},
+	})
+	if err != nil {
+		t.Fatalf("PrepareJob returned error: %v", err)
+	}
+	want := []ComposeStep{{File: 1, Pages: "5"}, {File: 0, Pages: "1-2"}, {File: 1, Pages: "1"}}
+	if manifest.Operation != OperationCompose || !reflect.DeepEqual(manifest.Recipe, want) {
+		t.Fatalf("unexpected manifest: %+v", manifest)
+	}
+
+	plan, err := svc.PlanJob(context.Background(), manifest.JobID)
+	if err != nil {
+		t.Fatalf("PlanJob returned error: %v", err)
+	}
+	if plan.Outputs[0].Name != composeFilename || plan.Outputs[0].Pages != 4 {
+		t.Fatalf("unexpected plan: %+v", plan.Outputs)
+	}
+
+	_, err = svc.PrepareJob(context.Background(), OperationCompose, PrepareRequest{
+		Files:  headers,
+		Params: url.Values{"recipe": {`[{"file":0,"pages":"4"}]`}},
+	})
+	if !IsError(err, "INVALID_INPUT") {
+		t.Fatalf("pages beyond the source should be rejected, got %v", err)
+	}
+}
+
+func TestComposeCacheKeyFollowsRecipe(t *testing.T) {
+	base := &JobManifest{
+		Operation: OperationCompose,
+		Files:     []JobFile{{SHA256: "a"}, {SHA256: "b"}},
+		Recipe:    []ComposeStep{{File: 0, Pages: "1-2"}, {File: 1}},
+	}
+	swapped := *base
+	swapped.Recipe = []ComposeStep{{File: 1}, {File: 0, Pages: "1-2"}}
+	k1, ok1 := resultCacheKey(base)
+	k2, ok2 := resultCacheKey(&swapped)
+	if !ok1 || !ok2 || k1 == k2 {
+		t.Fatalf("recipe order should change the cache key: %q %q", k1, k2)
+	}
+}
@@ -0,0 +1,79 @@
+package pdf
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+)
+
+// dedupFingerprint はジョブの重複判定に使う内容です。ジョブIDや作成時刻など、送信ごとに変わる値は含めません。
+type dedupFingerprint struct {
+	Operation    OperationType  `json:"operation"`
+	Files        []string       `json:"files"`
+	Order        []int          `json:"order,omitempty"`
+	Mode         ReorderMode    `json:"mode,omitempty"`
+	TOC          bool           `json:"toc,omitempty"`
+	Ranges       string         `json:"ranges,omitempty"`
+	Preset       OptimizePreset `json:"preset,omitempty"`
+	ColorProfile ColorProfile   `json:"colorProfile,omitempty"`
+	Output       SplitOutput    `json:"output,omitempty"`
+	Rotations    []PageRotation `json:"rotations,omitempty"`
+	Watermark    *WatermarkSpec `json:"watermark,omitempty"`
+	Stamp        *StampSpec     `json:"stamp,omitempty"`
+	Metadata     *MetadataSpec  `json:"metadata,omitempty"`
+	Recipe       []ComposeStep  `json:"recipe,omitempty"`
+	NUp          *NUpSpec       `json:"nup,omitempty"`
+	Resize       *ResizeSpec    `json:"resize,omitempty"`
+	Options      JobOptions     `json:"options"`
+}
+
+// DedupKey はジョブ jobID の入力のハッシュとオプション（配送先・ラベル等を含む）から、
+// 同じ内容のジョブを見分けるキーを返します。入力のハッシュが記録されていない場合は false を返します。
+func (s *Service) DedupKey(jobID string) (string, bool) {
+	if strings.TrimSpace(jobID) == "" {
+		return "", false
+	}
+	manifest, err := loadManifest(s.fs, s.workspaceFor(jobID).dir)
+	if err != nil {
+		return "", false
+	}
+	return dedupKey(manifest)
+}
+
+func dedupKey(manifest *JobManifest) (string, bool) {
+	if manifest == nil || len(manifest.Files) == 0 {
+		return "", false
+	}
+	fp := dedupFingerprint{
+		Operation:    manifest.Operation,
+		Files:        make([]string, len(manifest.Files)),
+		Order:        manifest.Order,
+		Mode:         manifest.Mode,
+		TOC:          manifest.TOC,
+		Ranges:       manifest.Ranges,
+		Preset:       manifest.Preset,
+		ColorProfile: manifest.ColorProfile,
+		Output:       manifest.Output,
+		Rotations:    manifest.Rotations,
+		Watermark:    manifest.Watermark,
+		Stamp:        manifest.Stamp,
+		Metadata:     manifest.Metadata,
+		Recipe:       manifest.Recipe,
+		NUp:          manifest.NUp,
+		Resize:       manifest.Resize,
+		Options:      manifest.Options,
+	}
+	for i, f := range manifest.Files {
+		if f.SHA256 == "" {
+			return "", false
+		}
+		fp.Files[i] = f.SHA256
+	}
+	data, err := json.Marshal(fp)
+	if err != nil {
+		return "", false
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), true
+}
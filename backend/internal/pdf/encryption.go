@@ -0,0 +1,493 @@
+package pdf
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/yourusername/paper-forge/internal/config"
+)
+
+// ワークスペース上のファイルは以下の形式で暗号化します（AES-256-GCM、64KiB 単位のチャンク）。
+//
+//	header: magic(4) | keyID長(1) | keyID | nonce接頭辞(8)
+//	chunk : 暗号文（平文最大 sealChunkSize バイト + タグ16バイト）
+//
+// 各チャンクの nonce は「nonce接頭辞 + 連番(4バイト)」で、追加認証データにヘッダー全体と
+// 最終チャンクかどうかのフラグを含めるため、チャンクの入れ替え・切り詰めは復号時に検出されます。
+const (
+	sealMagic       = "PFE1"
+	sealChunkSize   = 64 * 1024
+	sealNoncePrefix = 8
+	sealPlainDir    = "plain"
+)
+
+// ErrUnknownKey は暗号化に使われた鍵が見つからないことを表します。
+var ErrUnknownKey = errors.New("workspace encryption key not found")
+
+// KeyProvider はワークスペース暗号化の鍵を提供します。KMS などから鍵を取得する場合は独自に実装します。
+type KeyProvider interface {
+	// ActiveKey は新たに暗号化する際に使う鍵と、その鍵IDを返します。
+	ActiveKey() (id string, key []byte, err error)
+	// Key は鍵IDに対応する鍵を返します。鍵のローテーション後も、過去の鍵で暗号化したファイルを復号できるようにします。
+	Key(id string) ([]byte, error)
+}
+
+type staticKeyProvider struct {
+	active string
+	keys   map[string][]byte
+}
+
+// NewStaticKeyProvider は固定の鍵一覧から KeyProvider を作成します。active は暗号化に使う鍵IDです。
+func NewStaticKeyProvider(active string, keys map[string][]byte) (KeyProvider, error) {
+	if _, ok := keys[active]; !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, active)
+	}
+	for id, key := range keys {
+		if len(id) == 0 || len(id) > 255 {
+			return nil, fmt.Errorf("invalid key id length: %q", id)
+		}
+		if len(key) != 32 {
+			return nil, fmt.Errorf("key %q must be 32 bytes (AES-256)", id)
+		}
+	}
+	return &staticKeyProvider{active: active, keys: keys}, nil
+}
+
+func (p *staticKeyProvider) ActiveKey() (string, []byte, error) {
+	return p.active, p.keys[p.active], nil
+}
+
+func (p *staticKeyProvider) Key(id string) ([]byte, error) {
+	key, ok := p.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, id)
+	}
+	return key, nil
+}
+
+// keyProviderFromConfig は WORKSPACE_ENCRYPTION_KEYS の設定から KeyProvider を作成します。未設定の場合は nil を返します。
+func keyProviderFromConfig(cfg *config.Config) (KeyProvider, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	active, keys, err := cfg.WorkspaceKeys()
+	if err != nil || active == "" {
+		return nil, err
+	}
+	return NewStaticKeyProvider(active, keys)
+}
+
+// SetKeyProvider はワークスペース暗号化に使う KeyProvider を差し替えます。nil を指定すると暗号化を無効にします。
+func (s *Service) SetKeyProvider(kp KeyProvider) {
+	s.keys = kp
+}
+
+// activeKeyID は暗号化に使う鍵のIDを返します。暗号化が無効な場合は空です。
+func (s *Service) activeKeyID() string {
+	if s.keys == nil {
+		return ""
+	}
+	id, _, err := s.keys.ActiveKey()
+	if err != nil {
+		return ""
+	}
+	return id
+}
+
+// artifactFS は成果物の読み出しに使う FS です。暗号化が有効な場合は読み出し時に透過的に復号します。
+func (s *Service) artifactFS() FS {
+	if s.keys == nil {
+		return s.fs
+	}
+	return sealedFS{FS: s.fs, keys: s.keys}
+}
+
+// sealJob はジョブ入力を暗号化し、使用した鍵IDをマニフェストに記録します。
+func (s *Service) sealJob(manifest *JobManifest) (*JobManifest, error) {
+	if s.keys == nil || manifest == nil {
+		return manifest, nil
+	}
+	ws := s.workspaceFor(manifest.JobID)
+	keyID, key, err := s.keys.ActiveKey()
+	if err != nil {
+		_ = removeDir(ws.dir)
+		return nil, fmt.Errorf("暗号鍵の取得に失敗しました: %w", err)
+	}
+	for _, f := range manifest.Files {
+		if err := sealFileInPlace(filepath.Join(ws.inDir, f.StoredName), keyID, key); err != nil {
+			_ = removeDir(ws.dir)
+			return nil, fmt.Errorf("入力ファイルの暗号化に失敗しました: %w", err)
+		}
+	}
+	manifest.KeyID = keyID
+	if err := writeManifest(s.fs, ws.dir, manifest); err != nil {
+		_ = removeDir(ws.dir)
+		return nil, fmt.Errorf("マニフェストの保存に失敗しました: %w", err)
+	}
+	return manifest, nil
+}
+
+// unsealInputs は暗号化された入力を処理用の平文ディレクトリへ復号し、参照先を差し替えます。
+// 復号したバイト数は load 段階の進捗として progress に通知します。
+// 戻り値の関数で平文ディレクトリを削除します。
+func (s *Service) unsealInputs(ws workspace, manifest *JobManifest, stored []storedFile, progress *byteProgress) (func(), error) {
+	if manifest.KeyID == "" {
+		return func() {}, nil
+	}
+	if s.keys == nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, manifest.KeyID)
+	}
+	plainDir := filepath.Join(ws.dir, sealPlainDir)
+	cleanup := func() { _ = removeDir(plainDir) }
+	if err := os.MkdirAll(plainDir, 0o700); err != nil {
+		return nil, err
+	}
+	for i := range stored {
+		dst := filepath.Join(plainDir, filepath.Base(stored[i].path))
+		if err := unsealFile(s.keys, stored[i].path, dst, progress); err != nil {
+			cleanup()
+			return nil, fmt.Errorf("入力ファイルの復号に失敗しました: %w", err)
+		}
+		stored[i].path = dst
+	}
+	return cleanup, nil
+}
+
+// sealArtifacts は成果物を暗号化し、以後の読み出しで復号されるようにします。
+func (s *Service) sealArtifacts(result *Result) error {
+	if s.keys == nil || result == nil || result.Cached {
+		// キャッシュから複製した成果物は保存時に暗号化済み
+		return nil
+	}
+	keyID, key, err := s.keys.ActiveKey()
+	if err != nil {
+		return err
+	}
+	for _, a := range result.Artifacts {
+		if err := sealFileInPlace(a.Path, keyID, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sealFileInPlace は平文ファイルを暗号化したファイルに置き換えます。
+func sealFileInPlace(path, keyID string, key []byte) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmpPath := path + ".sealing"
+	out, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o640)
+	if err != nil {
+		return err
+	}
+	w, err := newSealWriter(out, keyID, key)
+	if err == nil {
+		_, err = io.Copy(w, in)
+	}
+	if err == nil {
+		err = w.Close()
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// unsealFile は暗号化されたファイル src を復号して dst に書き出します。progress は nil でも構いません。
+func unsealFile(keys KeyProvider, src, dst string, progress *byteProgress) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	r, err := newSealReader(in, keys)
+	if err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	var w io.Writer = out
+	if progress != nil {
+		w = io.MultiWriter(out, progress)
+	}
+	if _, err := io.Copy(w, r); err != nil {
+		out.Close()
+		_ = os.Remove(dst)
+		return err
+	}
+	return out.Close()
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func sealNonce(prefix []byte, counter uint32) []byte {
+	nonce := make([]byte, sealNoncePrefix+4)
+	copy(nonce, prefix)
+	binary.BigEndian.PutUint32(nonce[sealNoncePrefix:], counter)
+	return nonce
+}
+
+func sealAAD(header []byte, final bool) []byte {
+	aad := append([]byte(nil), header...)
+	if final {
+		return append(aad, 1)
+	}
+	return append(aad, 0)
+}
+
+// sealWriter は書き込まれた平文をチャンク単位で暗号化します。Close で最終チャンクを書き出します。
+type sealWriter struct {
+	w       io.Writer
+	aead    cipher.AEAD
+	header  []byte
+	prefix  []byte
+	counter uint32
+	buf     []byte
+}
+
+func newSealWriter(w io.Writer, keyID string, key []byte) (*sealWriter, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	prefix := make([]byte, sealNoncePrefix)
+	if _, err := rand.Read(prefix); err != nil {
+		return nil, err
+	}
+	header := make([]byte, 0, len(sealMagic)+1+len(keyID)+sealNoncePrefix)
+	header = append(header, sealMagic...)
+	header = append(header, byte(len(keyID)))
+	header = append(header, keyID...)
+	header = append(header, prefix...)
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+	return &sealWriter{w: w, aead: aead, header: header, prefix: prefix, buf: make([]byte, 0, sealChunkSize)}, nil
+}
+
+func (s *sealWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		// バッファが満杯でも、続きがあるか分かるまでは最終チャンクか判断できないため書き出しを遅らせる
+		if len(s.buf) == sealChunkSize {
+			if err := s.flush(false); err != nil {
+				return written, err
+			}
+		}
+		n := copy(s.buf[len(s.buf):sealChunkSize], p)
+		s.buf = s.buf[:len(s.buf)+n]
+		p = p[n:]
+		written += n
+	}
+	return written, nil
+}
+
+func (s *sealWriter) flush(final bool) error {
+	sealed := s.aead.Seal(nil, sealNonce(s.prefix, s.counter), s.buf, sealAAD(s.header, final))
+	s.counter++
+	s.buf = s.buf[:0]
+	_, err := s.w.Write(sealed)
+	return err
+}
+
+func (s *sealWriter) Close() error {
+	return s.flush(true)
+}
+
+// sealReader は暗号化されたストリームを復号しながら読み出します。
+type sealReader struct {
+	r       *bufio.Reader
+	aead    cipher.AEAD
+	header  []byte
+	prefix  []byte
+	counter uint32
+	plain   []byte
+	done    bool
+}
+
+// readSealHeader はヘッダーを読み取ります。暗号化されたファイルでない場合は ok=false を返します。
+func readSealHeader(r *bufio.Reader) (header []byte, keyID string, ok bool, err error) {
+	magic, err := r.Peek(len(sealMagic))
+	if err != nil || string(magic) != sealMagic {
+		return nil, "", false, nil
+	}
+	fixed := make([]byte, len(sealMagic)+1)
+	if _, err := io.ReadFull(r, fixed); err != nil {
+		return nil, "", false, err
+	}
+	rest := make([]byte, int(fixed[len(sealMagic)])+sealNoncePrefix)
+	if _, err := io.ReadFull(r, rest); err != nil {
+		return nil, "", false, fmt.Errorf("暗号化ヘッダーが不正です: %w", err)
+	}
+	header = append(fixed, rest...)
+	return header, string(rest[:len(rest)-sealNoncePrefix]), true, nil
+}
+
+func newSealReader(src io.Reader, keys KeyProvider) (*sealReader, error) {
+	r := bufio.NewReader(src)
+	header, keyID, ok, err := readSealHeader(r)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errors.New("暗号化されたファイルではありません")
+	}
+	return newSealReaderWithHeader(r, header, keyID, keys)
+}
+
+func newSealReaderWithHeader(r *bufio.Reader, header []byte, keyID string, keys KeyProvider) (*sealReader, error) {
+	if keys == nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, keyID)
+	}
+	key, err := keys.Key(keyID)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	return &sealReader{
+		r:      r,
+		aead:   aead,
+		header: header,
+		prefix: header[len(header)-sealNoncePrefix:],
+	}, nil
+}
+
+func (s *sealReader) Read(p []byte) (int, error) {
+	for len(s.plain) == 0 {
+		if s.done {
+			return 0, io.EOF
+		}
+		if err := s.next(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, s.plain)
+	s.plain = s.plain[n:]
+	return n, nil
+}
+
+func (s *sealReader) next() error {
+	chunk := make([]byte, sealChunkSize+s.aead.Overhead())
+	n, err := io.ReadFull(s.r, chunk)
+	switch {
+	case err == io.EOF:
+		return io.ErrUnexpectedEOF // 最終チャンクを読む前に終わった（切り詰め）
+	case err == io.ErrUnexpectedEOF:
+		s.done = true
+	case err != nil:
+		return err
+	default:
+		if _, peekErr := s.r.Peek(1); peekErr == io.EOF {
+			s.done = true
+		}
+	}
+	plain, openErr := s.aead.Open(chunk[:0], sealNonce(s.prefix, s.counter), chunk[:n], sealAAD(s.header, s.done))
+	if openErr != nil {
+		return fmt.Errorf("暗号化ファイルの復号に失敗しました: %w", openErr)
+	}
+	s.counter++
+	s.plain = plain
+	return nil
+}
+
+// sealedPlainSize は暗号文のサイズ（ヘッダーを除く）から平文のサイズを求めます。
+func sealedPlainSize(body int64, overhead int) int64 {
+	block := int64(sealChunkSize + overhead)
+	chunks := (body + block - 1) / block
+	if chunks == 0 {
+		chunks = 1
+	}
+	return body - chunks*int64(overhead)
+}
+
+// sealedFS は読み出し時に暗号化ファイルを透過的に復号する FS です。暗号化されていないファイルはそのまま読み出します。
+type sealedFS struct {
+	FS
+	keys KeyProvider
+}
+
+func (f sealedFS) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	file, err := f.FS.OpenFile(name, flag, perm)
+	if err != nil || flag&(os.O_WRONLY|os.O_RDWR) != 0 {
+		return file, err
+	}
+	br := bufio.NewReader(file)
+	header, keyID, ok, err := readSealHeader(br)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if !ok {
+		return &plainFile{File: file, r: br}, nil
+	}
+	r, err := newSealReaderWithHeader(br, header, keyID, f.keys)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &sealedFile{File: file, r: r, headerLen: len(header)}, nil
+}
+
+// plainFile はヘッダー判定のために先読みした内容を含めて読み出します。
+type plainFile struct {
+	File
+	r io.Reader
+}
+
+func (f *plainFile) Read(p []byte) (int, error) { return f.r.Read(p) }
+
+// sealedFile は復号しながら読み出し、Stat では平文のサイズを返します。
+type sealedFile struct {
+	File
+	r         *sealReader
+	headerLen int
+}
+
+func (f *sealedFile) Read(p []byte) (int, error) { return f.r.Read(p) }
+
+func (f *sealedFile) Write([]byte) (int, error) {
+	return 0, errors.New("sealed file is read-only")
+}
+
+func (f *sealedFile) Stat() (os.FileInfo, error) {
+	info, err := f.File.Stat()
+	if err != nil {
+		return nil, err
+	}
+	body := info.Size() - int64(f.headerLen)
+	return plainSizeInfo{FileInfo: info, size: sealedPlainSize(body, f.r.aead.Overhead())}, nil
+}
+
+type plainSizeInfo struct {
+	os.FileInfo
+	size int64
+}
+
+func (i plainSizeInfo) Size() int64 { return i.size }
@@ -0,0 +1,113 @@
+package pdf
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func testKeys(t *testing.T) KeyProvider {
+	t.Helper()
+	kp, err := NewStaticKeyProvider("k1", map[string][]byte{"k1": bytes.Repeat([]byte{7}, 32)})
+	if err != nil {
+		t.Fatalf("NewStaticKeyProvider returned error: %v", err)
+	}
+	return kp
+}
+
+func TestSealRoundTrip(t *testing.T) {
+	keys := testKeys(t)
+	dir := t.TempDir()
+	for _, size := range []int{0, 1, sealChunkSize - 1, sealChunkSize, sealChunkSize + 1, 3*sealChunkSize + 17} {
+		plain := make([]byte, size)
+		_, _ = rand.Read(plain)
+		path := filepath.Join(dir, "data.pdf")
+		if err := os.WriteFile(path, plain, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		id, key, _ := keys.ActiveKey()
+		if err := sealFileInPlace(path, id, key); err != nil {
+			t.Fatalf("size %d: sealFileInPlace returned error: %v", size, err)
+		}
+		onDisk, _ := os.ReadFile(path)
+		if size >= 16 && bytes.Contains(onDisk, plain) {
+			t.Fatalf("size %d: plaintext found on disk", size)
+		}
+
+		file, err := openFS(sealedFS{FS: OSFS{}, keys: keys}, path)
+		if err != nil {
+			t.Fatalf("size %d: open returned error: %v", size, err)
+		}
+		info, err := file.Stat()
+		if err != nil || info.Size() != int64(size) {
+			t.Fatalf("size %d: Stat size = %v, err = %v", size, info.Size(), err)
+		}
+		got, err := io.ReadAll(file)
+		file.Close()
+		if err != nil || !bytes.Equal(got, plain) {
+			t.Fatalf("size %d: round trip mismatch (err=%v, len=%d)", size, err, len(got))
+		}
+	}
+}
+
+func TestSealDetectsTruncation(t *testing.T) {
+	keys := testKeys(t)
+	path := filepath.Join(t.TempDir(), "data.pdf")
+	if err := os.WriteFile(path, bytes.Repeat([]byte("x"), 2*sealChunkSize+10), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	id, key, _ := keys.ActiveKey()
+	if err := sealFileInPlace(path, id, key); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	// 最終チャンクを丸ごと落としても、残りのチャンクは最終チャンクとして認証されない
+	truncated := data[:len(data)-(10+16)]
+	if err := os.WriteFile(path, truncated, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	file, err := openFS(sealedFS{FS: OSFS{}, keys: keys}, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if _, err := io.ReadAll(file); err == nil {
+		t.Fatal("expected truncated file to fail authentication")
+	}
+}
+
+func TestSealedFSPassesThroughPlainFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plain.pdf")
+	payload := []byte("%PDF-1.4\n% plain\n")
+	if err := os.WriteFile(path, payload, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	file, err := openFS(sealedFS{FS: OSFS{}, keys: testKeys(t)}, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	got, _ := io.ReadAll(file)
+	if !bytes.Equal(got, payload) {
+		t.Fatalf("unexpected content: %q", got)
+	}
+}
+
+func TestUnsealWithUnknownKey(t *testing.T) {
+	keys := testKeys(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "data.pdf")
+	if err := os.WriteFile(path, []byte("secret"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := sealFileInPlace(path, "old", bytes.Repeat([]byte{1}, 32)); err != nil {
+		t.Fatal(err)
+	}
+	if err := unsealFile(keys, path, filepath.Join(dir, "out.pdf"), nil); !errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("expected ErrUnknownKey, got %v", err)
+	}
+}
@@ -0,0 +1,94 @@
+package pdf
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxUserMessageLength は利用者に返すエラーの文言の最大文字数です。
+const maxUserMessageLength = 300
+
+// internalErrorMessage は原因を利用者に返せないエラーの文言です。
+const internalErrorMessage = "サーバー内部でエラーが発生しました。"
+
+// Error はAPIレスポンス用のエラー情報を保持します。
+// Code と Message は利用者に返す内容で、Message には外部コマンドの出力やファイルのパスを含めません。
+// Err は原因（ログ・調査用）で、レスポンス・ジョブ情報・通知には載せません。
+type Error struct {
+	Code    string
+	Message string
+	Err     error
+}
+
+// Error はログ向けに、Message に原因を続けた文字列を返します。利用者に返す場合は UserMessage を使います。
+func (e *Error) Error() string {
+	if e == nil {
+		return ""
+	}
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %v", e.Message, e.Err)
+	}
+	return e.Message
+}
+
+// Unwrap は元のエラーを返します。
+func (e *Error) Unwrap() error {
+	if e == nil {
+		return nil
+	}
+	return e.Err
+}
+
+func newError(code, message string, err error) error {
+	return &Error{
+		Code:    code,
+		Message: message,
+		Err:     err,
+	}
+}
+
+// IsError は指定したコードのエラーかどうかを判定します。
+func IsError(err error, code string) bool {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr.Code == code
+	}
+	return false
+}
+
+// UserMessage は err を利用者に返すコードと文言にします。レスポンス・ジョブ情報・通知に載せる前に必ず通します。
+// *Error の場合は Code と、RedactMessage を適用した Message です。原因（Err）は含めません。
+// それ以外のエラーは内部の詳細を含みうるため、INTERNAL_ERROR と汎用の文言に置き換えます。
+func UserMessage(err error) (code, message string) {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr.Code, RedactMessage(apiErr.Message)
+	}
+	return "INTERNAL_ERROR", internalErrorMessage
+}
+
+// absolutePathPattern は文言中の絶対パス（作業領域・外部コマンドのパスなど）です。
+// URL（https://host/...）の一部は対象にしないよう、行頭・空白・括弧・引用符の直後から始まるものだけを扱います。
+// 続く ": no such file" などの説明を残すため、コロンはパスに含めません。
+var absolutePathPattern = regexp.MustCompile(`(^|[\s(="'「（])/[^\s"':)」）]*/[^\s"':)」）]*`)
+
+// RedactMessage は利用者に返す文言から内部の情報を取り除きます（エラーの文言を応答に載せる前の共通の規則）。
+// Message は利用者向けに組み立てる前提ですが、原因の文字列を誤って含めた場合に備えて、
+// 最初の行だけを残し（外部コマンドの出力は複数行になる）、絶対パスを伏せ、長さを制限します。
+func RedactMessage(message string) string {
+	if i := strings.IndexAny(message, "\r\n"); i >= 0 {
+		message = message[:i]
+	}
+	message = absolutePathPattern.ReplaceAllString(message, "${1}[path]")
+	message = strings.TrimSpace(message)
+	if utf8.RuneCountInString(message) > maxUserMessageLength {
+		message = string([]rune(message)[:maxUserMessageLength]) + "…"
+	}
+	if message == "" {
+		return internalErrorMessage
+	}
+	return message
+}
@@ -0,0 +1,67 @@
+package pdf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yourusername/paper-forge/internal/execrun"
+)
+
+func TestUserMessageHidesCause(t *testing.T) {
+	cause := errors.New("open /var/lib/paper-forge/work/4c5d/in/00.pdf: permission denied")
+	code, message := UserMessage(fmt.Errorf("run job: %w", newError("DAMAGED_PDF", "a.pdf は破損しているため読み込めませんでした。", cause)))
+	if code != "DAMAGED_PDF" || message != "a.pdf は破損しているため読み込めませんでした。" {
+		t.Fatalf("got %s %q", code, message)
+	}
+
+	code, message = UserMessage(cause)
+	if code != "INTERNAL_ERROR" || strings.Contains(message, "/var/lib") {
+		t.Fatalf("errors other than *Error must not expose their text: %s %q", code, message)
+	}
+}
+
+func TestRedactMessage(t *testing.T) {
+	cases := map[string]string{
+		"ページ範囲 \"1-3\" は正しくありません。":                             "ページ範囲 \"1-3\" は正しくありません。",
+		"保存に失敗しました: open /tmp/pf/job-1/out/a.pdf: no space":    "保存に失敗しました: open [path]: no space",
+		"Ghostscriptの実行に失敗しました。\nError: /undefined in --run--": "Ghostscriptの実行に失敗しました。",
+		"詳細は https://example.com/docs/errors を参照してください。":       "詳細は https://example.com/docs/errors を参照してください。",
+		"\n": internalErrorMessage,
+	}
+	for in, want := range cases {
+		if got := RedactMessage(in); got != want {
+			t.Fatalf("%q: got %q, want %q", in, got, want)
+		}
+	}
+	if got := RedactMessage(strings.Repeat("あ", maxUserMessageLength+10)); len([]rune(got)) != maxUserMessageLength+1 {
+		t.Fatalf("long messages should be truncated, got %d runes", len([]rune(got)))
+	}
+}
+
+func TestGhostscriptFailureKeepsOutputOutOfResponse(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	runner := &stubRunner{
+		out: execrun.Output{Stderr: []byte("GPL Ghostscript 10.02: Unrecoverable error in /tmp/pf/job-1/in/00.pdf\n")},
+		err: errors.New("exit status 1"),
+	}
+	svc := newGhostscriptTestService(runner)
+	_, err := svc.runGhostscript(context.Background(), "in.pdf", "out.pdf", "", ghostscriptOptions{preset: OptimizePresetStandard})
+	if !strings.Contains(err.Error(), "Unrecoverable error") {
+		t.Fatalf("the command output should be kept for logs: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(rec)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/pdf/optimize", nil)
+	respondWithError(c, err)
+	if rec.Code != http.StatusBadRequest || strings.Contains(rec.Body.String(), "Unrecoverable") || strings.Contains(rec.Body.String(), "/tmp/pf") {
+		t.Fatalf("the response must not include the command output: %d %s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,220 @@
+package pdf
+
+import (
+	"context"
+	"fmt"
+	"mime/multipart"
+	"path/filepath"
+	"strings"
+	"time"
+
+	pdfapi "github.com/pdfcpu/pdfcpu/pkg/api"
+
+	"github.com/yourusername/paper-forge/internal/tenant"
+)
+
+const extractFilename = "extracted.pdf"
+
+// ExtractMultipart は範囲指定したページだけを含む1つのPDFを作成します。
+// split と同じ範囲指定を受け付けますが、範囲ごとに分けず ZIP にもまとめません。
+func (s *Service) ExtractMultipart(ctx context.Context, file *multipart.FileHeader, rangesExpr string) (_ *Result, err error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if file == nil {
+		return nil, newError("INVALID_INPUT", "PDFファイルを選択してください。", nil)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	state, _, err := s.prepareExtract(ctx, file, rangesExpr, JobOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = removeDir(state.ws.dir)
+		}
+	}()
+
+	result, execErr := s.executeExtract(ctx, state, nil)
+	if execErr != nil {
+		return nil, execErr
+	}
+	return result, nil
+}
+
+type extractState struct {
+	ws        workspace
+	file      storedFile
+	ranges    []PageRange
+	rangesRaw string
+}
+
+func (s *Service) prepareExtract(ctx context.Context, file *multipart.FileHeader, rangesExpr string, opts JobOptions) (*extractState, *JobManifest, error) {
+	rangesExpr = strings.TrimSpace(rangesExpr)
+	if rangesExpr == "" {
+		return nil, nil, newError("INVALID_INPUT", "抽出するページ範囲を指定してください。", nil)
+	}
+
+	release, err := s.reserveWorkspace(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer release()
+
+	ws, err := s.createWorkspace()
+	if err != nil {
+		return nil, nil, err
+	}
+	stored, err := s.storeMultipartFile(ctx, file, ws.inDir, 0, false)
+	if err != nil {
+		_ = removeDir(ws.dir)
+		return nil, nil, err
+	}
+
+	rangesParsed, err := parseOrderedPageRanges(rangesExpr, stored.pages)
+	if err != nil {
+		_ = removeDir(ws.dir)
+		return nil, nil, err
+	}
+
+	manifest := &JobManifest{
+		JobID:     ws.jobID,
+		Tenant:    tenant.FromContext(ctx),
+		Operation: OperationExtract,
+		Files:     toJobFiles([]storedFile{stored}),
+		Ranges:    rangesExpr,
+		Options:   opts,
+		StoreMs:   ws.elapsed().Milliseconds(),
+		CreatedAt: s.now().UTC(),
+	}
+	if err := writeManifest(s.fs, ws.dir, manifest); err != nil {
+		_ = removeDir(ws.dir)
+		return nil, nil, fmt.Errorf("ジョブマニフェストの保存に失敗しました: %w", err)
+	}
+
+	return &extractState{ws: ws, file: stored, ranges: rangesParsed, rangesRaw: rangesExpr}, manifest, nil
+}
+
+func (s *Service) executeExtract(ctx context.Context, state *extractState, progress ProgressReporter) (*Result, error) {
+	ws := state.ws
+	stored := state.file
+	ranges := state.ranges
+	if ranges == nil {
+		parsed, err := parseOrderedPageRanges(state.rangesRaw, stored.pages)
+		if err != nil {
+			return nil, err
+		}
+		ranges = parsed
+	}
+
+	var selectedPages []string
+	for _, pr := range ranges {
+		selectedPages = append(selectedPages, buildPageSelection(pr)...)
+	}
+
+	reportProgress(progress, StageProcess, 0)
+	outputPath := filepath.Join(ws.outDir, extractFilename)
+	if err := runStep(ctx, func() error { return pdfapi.CollectFile(stored.path, outputPath, selectedPages, nil) }); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		return nil, newError("UNSUPPORTED_PDF", "PDFのページ抽出に失敗しました。ファイルが破損していないか確認してください。", err)
+	}
+	reportProgress(progress, StageWrite, 0)
+
+	sourceMeta := SourceFileMeta{
+		Name:  stored.originalName,
+		Size:  stored.size,
+		Pages: stored.pages,
+	}
+
+	meta := struct {
+		Type      OperationType  `json:"type"`
+		CreatedAt string         `json:"createdAt"`
+		Source    SourceFileMeta `json:"source"`
+		Ranges    []PageRange    `json:"ranges"`
+		Output    string         `json:"output"`
+		Pages     int            `json:"pages"`
+	}{
+		Type:      OperationExtract,
+		CreatedAt: s.now().UTC().Format(time.RFC3339),
+		Source:    sourceMeta,
+		Ranges:    ranges,
+		Output:    extractFilename,
+		Pages:     len(selectedPages),
+	}
+
+	metaPath := filepath.Join(ws.dir, "meta.json")
+	if err := writeJSON(s.fs, metaPath, meta); err != nil {
+		return nil, fmt.Errorf("メタデータの保存に失敗しました: %w", err)
+	}
+
+	s.scheduleCleanup(ws.dir)
+
+	artifact, err := newArtifact(outputPath, ResultKindPDF)
+	if err != nil {
+		return nil, fmt.Errorf("成果物の確認に失敗しました: %w", err)
+	}
+
+	reportProgress(progress, StageCompleted, 1)
+
+	return newResult(ws, OperationExtract, s.fs, []Artifact{artifact}, &ExtractMeta{
+		Original: sourceMeta,
+		Ranges:   ranges,
+		Pages:    len(selectedPages),
+	}), nil
+}
+
+// PrepareExtractJob は非同期ジョブ用に入力を保存します。
+func (s *Service) PrepareExtractJob(ctx context.Context, file *multipart.FileHeader, rangesExpr string, opts JobOptions) (*JobManifest, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	_, manifest, err := s.prepareExtract(ctx, file, rangesExpr, opts)
+	if err != nil {
+		return nil, err
+	}
+	return s.sealJob(manifest)
+}
+
+func init() {
+	registerOperation(extractOperation{})
+}
+
+type extractOperation struct{}
+
+func (extractOperation) Type() OperationType { return OperationExtract }
+
+func (extractOperation) OutputSpec() OutputSpec {
+	return OutputSpec{Filename: extractFilename, Kind: ResultKindPDF}
+}
+
+func (extractOperation) Prepare(ctx context.Context, s *Service, req PrepareRequest) (*JobManifest, error) {
+	file, err := req.singleFile()
+	if err != nil {
+		return nil, err
+	}
+	return s.PrepareExtractJob(ctx, file, req.Params.Get("ranges"), req.Options)
+}
+
+func (extractOperation) Execute(ctx context.Context, s *Service, job *jobInput, progress ProgressReporter) (*Result, error) {
+	state := &extractState{
+		ws:        job.ws,
+		file:      job.files[0],
+		rangesRaw: job.manifest.Ranges,
+	}
+	return s.executeExtract(ctx, state, progress)
+}
+
+func (extractOperation) Plan(s *Service, job *jobInput) (*JobPlan, error) {
+	ranges, err := parseOrderedPageRanges(job.manifest.Ranges, job.files[0].pages)
+	if err != nil {
+		return nil, err
+	}
+	pages := expandPageRanges(ranges)
+	return &JobPlan{Outputs: []PlannedOutput{{Name: extractFilename, Kind: ResultKindPDF, Pages: len(pages), PageOrder: pages}}}, nil
+}
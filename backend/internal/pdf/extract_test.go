@@ -0,0 +1,42 @@
+package pdf
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"github.com/yourusername/paper-forge/internal/config"
+	"github.com/yourusername/paper-forge/internal/pdf/pdftest"
+)
+
+func TestPrepareExtractJobThroughRegistry(t *testing.T) {
+	svc := NewService(&config.Config{MaxFileSize: 1 << 20, MaxPages: 20})
+	svc.tmpRoot = t.TempDir()
+	headers, err := pdftest.FileHeaders("file",
+		pdftest.File{Name: "a.pdf", Data: pdftest.Synthetic(8, 0)},
+	)
+	if err != nil {
+		t.Fatalf("failed to build multipart files: %v", err)
+	}
+
+	manifest, err := svc.PrepareJob(context.Background(), OperationExtract, PrepareRequest{
+		Files:  headers,
+		Params: url.Values{"ranges": {" 3-7 "}},
+	})
+	if err != nil {
+		t.Fatalf("PrepareJob returned error: %v", err)
+	}
+	if manifest.Operation != OperationExtract || manifest.Ranges != "3-7" {
+		t.Fatalf("unexpected manifest: %+v", manifest)
+	}
+
+	for _, ranges := range []string{"", "7-3,4", "9"} {
+		_, err := svc.PrepareJob(context.Background(), OperationExtract, PrepareRequest{
+			Files:  headers,
+			Params: url.Values{"ranges": {ranges}},
+		})
+		if !IsError(err, "INVALID_INPUT") {
+			t.Fatalf("ranges %q: expected INVALID_INPUT, got %v", ranges, err)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package pdf
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/yourusername/paper-forge/internal/execrun"
+	"github.com/yourusername/paper-forge/internal/faults"
+)
+
+// subprocessKillDelay は Subprocess の障害で外部コマンドを起動してから強制終了するまでの時間です。
+const subprocessKillDelay = 10 * time.Millisecond
+
+// injectFaults はワークスペースの書き込みと外部コマンドの実行に障害を注入するよう設定します（FAULT_INJECTION）。
+func (s *Service) injectFaults(inj *faults.Injector) {
+	if inj == nil {
+		return
+	}
+	s.fs = faultFS{FS: s.fs, faults: inj}
+	s.runner = faultRunner{Runner: s.runner, faults: inj}
+}
+
+func faultsFromConfig(spec string) *faults.Injector {
+	// 値は config.Validate で検証済み
+	inj, _ := faults.Parse(spec)
+	if inj != nil {
+		log.Printf("[WARN] FAULT_INJECTION が有効です。指定した障害点でジョブが失敗します: %s", spec)
+	}
+	return inj
+}
+
+// faultFS は StorageWrite / ManifestCorrupt の障害を注入する FS です。
+type faultFS struct {
+	FS
+	faults *faults.Injector
+}
+
+func (f faultFS) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	if flag&(os.O_WRONLY|os.O_RDWR) == 0 {
+		return f.FS.OpenFile(name, flag, perm)
+	}
+	if f.faults.Fire(faults.StorageWrite) {
+		return nil, fmt.Errorf("%w: %s への書き込みに失敗しました", faults.ErrInjected, name)
+	}
+	file, err := f.FS.OpenFile(name, flag, perm)
+	if err != nil || filepath.Base(name) != manifestFilename || !f.faults.Fire(faults.ManifestCorrupt) {
+		return file, err
+	}
+	return truncatingFile{File: file}, nil
+}
+
+func (f faultFS) Rename(oldpath, newpath string) error {
+	if f.faults.Fire(faults.StorageWrite) {
+		return fmt.Errorf("%w: %s への書き込みに失敗しました", faults.ErrInjected, newpath)
+	}
+	return f.FS.Rename(oldpath, newpath)
+}
+
+// truncatingFile は書き込みを半分で打ち切りつつ、呼び出し元には成功したように見せます。
+type truncatingFile struct {
+	File
+}
+
+func (t truncatingFile) Write(p []byte) (int, error) {
+	if _, err := t.File.Write(p[:len(p)/2]); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// faultRunner は Subprocess の障害を注入する Runner です。
+type faultRunner struct {
+	execrun.Runner
+	faults *faults.Injector
+}
+
+func (r faultRunner) Run(ctx context.Context, cmd execrun.Command) (execrun.Output, error) {
+	if !r.faults.Fire(faults.Subprocess) {
+		return r.Runner.Run(ctx, cmd)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	timer := time.AfterFunc(subprocessKillDelay, cancel)
+	defer timer.Stop()
+	out, err := r.Runner.Run(ctx, cmd)
+	// 強制終了より先に終わった場合も、途中で終了したものとして扱う
+	return out, fmt.Errorf("%w: %s を強制終了しました: %v", faults.ErrInjected, cmd.Name, err)
+}
@@ -0,0 +1,95 @@
+package pdf
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/yourusername/paper-forge/internal/config"
+	"github.com/yourusername/paper-forge/internal/execrun"
+	"github.com/yourusername/paper-forge/internal/faults"
+	"github.com/yourusername/paper-forge/internal/pdf/pdftest"
+)
+
+func newFaultService(t *testing.T) (*Service, *faults.Injector) {
+	t.Helper()
+	svc := NewService(&config.Config{MaxFileSize: 1 << 20, MaxPages: 20})
+	svc.tmpRoot = t.TempDir()
+	inj := faults.New()
+	svc.injectFaults(inj)
+	return svc, inj
+}
+
+func assertNoWorkspaces(t *testing.T, svc *Service) {
+	t.Helper()
+	entries, err := os.ReadDir(svc.tmpRoot)
+	if err != nil {
+		t.Fatalf("failed to list work dir: %v", err)
+	}
+	if len(entries) > 0 {
+		t.Fatalf("workspace was not cleaned up: %v", entries)
+	}
+}
+
+func TestStorageWriteFaultCleansUpPreparedJob(t *testing.T) {
+	svc, inj := newFaultService(t)
+	headers, err := pdftest.FileHeaders("file", pdftest.File{Name: "a.pdf", Data: pdftest.Synthetic(3, 0)})
+	if err != nil {
+		t.Fatalf("failed to build multipart files: %v", err)
+	}
+
+	inj.Arm(faults.StorageWrite, 1)
+	if _, err := svc.PrepareSplitJob(context.Background(), headers[0], "1-2", SplitOutputZIP, JobOptions{}); !errors.Is(err, faults.ErrInjected) {
+		t.Fatalf("expected the injected storage fault, got %v", err)
+	}
+	assertNoWorkspaces(t, svc)
+
+	if _, err := svc.PrepareSplitJob(context.Background(), headers[0], "1-2", SplitOutputZIP, JobOptions{}); err != nil {
+		t.Fatalf("job should be accepted once the fault is spent: %v", err)
+	}
+}
+
+func TestCorruptManifestFailsJobAndCleansUp(t *testing.T) {
+	svc, inj := newFaultService(t)
+	headers, err := pdftest.FileHeaders("file", pdftest.File{Name: "a.pdf", Data: pdftest.Synthetic(3, 0)})
+	if err != nil {
+		t.Fatalf("failed to build multipart files: %v", err)
+	}
+
+	inj.Arm(faults.ManifestCorrupt, 1)
+	manifest, err := svc.PrepareSplitJob(context.Background(), headers[0], "1-2", SplitOutputZIP, JobOptions{})
+	if err != nil {
+		t.Fatalf("corruption should not be noticed when the job is accepted: %v", err)
+	}
+	if _, err := svc.RunJob(context.Background(), manifest.JobID, nil); err == nil {
+		t.Fatal("RunJob should fail for a corrupted manifest")
+	}
+	if inj.Fired(faults.ManifestCorrupt) != 1 {
+		t.Fatal("manifest corruption was not injected")
+	}
+	assertNoWorkspaces(t, svc)
+}
+
+func TestSubprocessFaultKillsCommand(t *testing.T) {
+	sleep, err := exec.LookPath("sleep")
+	if err != nil {
+		t.Skip("sleep is not available")
+	}
+	inj := faults.New()
+	runner := faultRunner{Runner: &execrun.ExecRunner{}, faults: inj}
+
+	inj.Arm(faults.Subprocess, 1)
+	start := time.Now()
+	if _, err := runner.Run(context.Background(), execrun.Command{Name: sleep, Args: []string{"5"}}); !errors.Is(err, faults.ErrInjected) {
+		t.Fatalf("expected the injected subprocess fault, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("command was not killed (ran for %s)", elapsed)
+	}
+	if _, err := runner.Run(context.Background(), execrun.Command{Name: sleep, Args: []string{"0"}}); err != nil {
+		t.Fatalf("command should run normally once the fault is spent: %v", err)
+	}
+}
@@ -0,0 +1,99 @@
+package pdf
+
+import (
+	"io"
+	"os"
+	"strings"
+)
+
+// FS はワークスペースの作成、マニフェストの読み書き、成果物の読み出しに使うファイルシステムです。
+// 既定ではローカルディスク (OSFS) を使用します。テストではメモリ上の実装に差し替えられます。
+//
+// pdfcpu や Ghostscript はファイルパスを直接扱うため、変換処理そのものはローカルディスク上で行われます。
+type FS interface {
+	MkdirAll(path string, perm os.FileMode) error
+	OpenFile(name string, flag int, perm os.FileMode) (File, error)
+	Stat(name string) (os.FileInfo, error)
+	RemoveAll(path string) error
+	// Rename は oldpath を newpath に置き換えます。書き終えたファイルやディレクトリを一度に公開するために使います。
+	Rename(oldpath, newpath string) error
+}
+
+// File は FS が返すファイルハンドルです。
+type File interface {
+	io.Reader
+	io.Writer
+	io.Closer
+	Stat() (os.FileInfo, error)
+}
+
+// OSFS は os パッケージをそのまま利用する FS です。
+type OSFS struct{}
+
+// MkdirAll は os.MkdirAll を呼び出します。
+func (OSFS) MkdirAll(path string, perm os.FileMode) error { return os.MkdirAll(path, perm) }
+
+// OpenFile は os.OpenFile を呼び出します。
+func (OSFS) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	f, err := os.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Stat は os.Stat を呼び出します。
+func (OSFS) Stat(name string) (os.FileInfo, error) { return os.Stat(name) }
+
+// RemoveAll は os.RemoveAll を呼び出します。
+func (OSFS) RemoveAll(path string) error { return os.RemoveAll(path) }
+
+// Rename は os.Rename を呼び出します。
+func (OSFS) Rename(oldpath, newpath string) error { return os.Rename(oldpath, newpath) }
+
+func openFS(fsys FS, name string) (File, error) {
+	return fsys.OpenFile(name, os.O_RDONLY, 0)
+}
+
+func readFileFS(fsys FS, name string) ([]byte, error) {
+	f, err := openFS(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+// writeFileFS は data を一時ファイルに書き出してから name に置き換えます。書きかけの name が読まれることはありません。
+func writeFileFS(fsys FS, name string, data []byte, perm os.FileMode) error {
+	tmp := name + ".tmp"
+	f, err := fsys.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = fsys.Rename(tmp, name)
+	}
+	if err != nil {
+		_ = fsys.RemoveAll(tmp)
+	}
+	return err
+}
+
+func removeDirFS(fsys FS, path string) error {
+	if strings.TrimSpace(path) == "" {
+		return nil
+	}
+	return fsys.RemoveAll(path)
+}
+
+func fsOrDefault(fsys FS) FS {
+	if fsys == nil {
+		return OSFS{}
+	}
+	return fsys
+}
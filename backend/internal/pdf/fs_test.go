@@ -0,0 +1,370 @@
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/yourusername/paper-forge/internal/config"
+)
+
+// memFS はテスト用のメモリ上の FS 実装です。
+type memFS struct {
+	mu    sync.Mutex
+	dirs  map[string]bool
+	files map[string][]byte
+}
+
+func newMemFS() *memFS {
+	return &memFS{dirs: map[string]bool{}, files: map[string][]byte{}}
+}
+
+func (m *memFS) MkdirAll(path string, perm os.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for p := filepath.Clean(path); p != "/" && p != "."; p = filepath.Dir(p) {
+		m.dirs[p] = true
+	}
+	return nil
+}
+
+func (m *memFS) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	name = filepath.Clean(name)
+	data, exists := m.files[name]
+	if flag&(os.O_WRONLY|os.O_RDWR) == 0 {
+		if !exists {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+		}
+		return &memFile{name: name, reader: bytes.NewReader(data), size: int64(len(data))}, nil
+	}
+	if !m.dirs[filepath.Dir(name)] {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	if !exists && flag&os.O_CREATE == 0 {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	f := &memFile{name: name, fs: m}
+	if flag&os.O_TRUNC == 0 {
+		f.buf.Write(data)
+	}
+	return f, nil
+}
+
+func (m *memFS) Stat(name string) (os.FileInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	name = filepath.Clean(name)
+	if data, ok := m.files[name]; ok {
+		return memFileInfo{name: filepath.Base(name), size: int64(len(data))}, nil
+	}
+	if m.dirs[name] {
+		return memFileInfo{name: filepath.Base(name), dir: true}, nil
+	}
+	return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+}
+
+func (m *memFS) RemoveAll(path string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	path = filepath.Clean(path)
+	prefix := path + string(filepath.Separator)
+	for name := range m.files {
+		if name == path || strings.HasPrefix(name, prefix) {
+			delete(m.files, name)
+		}
+	}
+	for name := range m.dirs {
+		if name == path || strings.HasPrefix(name, prefix) {
+			delete(m.dirs, name)
+		}
+	}
+	return nil
+}
+
+func (m *memFS) Rename(oldpath, newpath string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	oldpath, newpath = filepath.Clean(oldpath), filepath.Clean(newpath)
+	if _, ok := m.files[oldpath]; !ok && !m.dirs[oldpath] {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: fs.ErrNotExist}
+	}
+	if !m.dirs[filepath.Dir(newpath)] {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: fs.ErrNotExist}
+	}
+	if data, ok := m.files[oldpath]; ok {
+		delete(m.files, oldpath)
+		m.files[newpath] = data
+		return nil
+	}
+	prefix := oldpath + string(filepath.Separator)
+	for name, data := range m.files {
+		if strings.HasPrefix(name, prefix) {
+			delete(m.files, name)
+			m.files[filepath.Join(newpath, strings.TrimPrefix(name, prefix))] = data
+		}
+	}
+	for name := range m.dirs {
+		if name == oldpath || strings.HasPrefix(name, prefix) {
+			delete(m.dirs, name)
+			m.dirs[filepath.Join(newpath, strings.TrimPrefix(name, oldpath))] = true
+		}
+	}
+	return nil
+}
+
+type memFile struct {
+	name   string
+	fs     *memFS
+	reader *bytes.Reader
+	buf    bytes.Buffer
+	size   int64
+}
+
+func (f *memFile) Read(p []byte) (int, error) {
+	if f.reader == nil {
+		return 0, errors.New("file not opened for reading")
+	}
+	return f.reader.Read(p)
+}
+
+func (f *memFile) Write(p []byte) (int, error) {
+	if f.fs == nil {
+		return 0, errors.New("file not opened for writing")
+	}
+	return f.buf.Write(p)
+}
+
+func (f *memFile) Close() error {
+	if f.fs != nil {
+		f.fs.mu.Lock()
+		f.fs.files[f.name] = append([]byte(nil), f.buf.Bytes()...)
+		f.fs.mu.Unlock()
+	}
+	return nil
+}
+
+func (f *memFile) Stat() (os.FileInfo, error) {
+	size := f.size
+	if f.fs != nil {
+		size = int64(f.buf.Len())
+	}
+	return memFileInfo{name: filepath.Base(f.name), size: size}, nil
+}
+
+type memFileInfo struct {
+	name string
+	size int64
+	dir  bool
+}
+
+func (i memFileInfo) Name() string       { return i.name }
+func (i memFileInfo) Size() int64        { return i.size }
+func (i memFileInfo) Mode() os.FileMode  { return 0o640 }
+func (i memFileInfo) ModTime() time.Time { return time.Time{} }
+func (i memFileInfo) IsDir() bool        { return i.dir }
+func (i memFileInfo) Sys() any           { return nil }
+
+func TestManifestRoundTripInMemory(t *testing.T) {
+	fsys := newMemFS()
+	svc := NewServiceWithFS(&config.Config{}, fsys)
+
+	ws, err := svc.createWorkspace()
+	if err != nil {
+		t.Fatalf("createWorkspace returned error: %v", err)
+	}
+	manifest := &JobManifest{
+		JobID:     ws.jobID,
+		Operation: OperationSplit,
+		Files:     []JobFile{{StoredName: "001.pdf", OriginalName: "a.pdf", Size: 10, Pages: 3}},
+		Ranges:    "1-2",
+	}
+	if err := writeManifest(fsys, ws.dir, manifest); err != nil {
+		t.Fatalf("writeManifest returned error: %v", err)
+	}
+
+	loaded, err := loadManifest(fsys, ws.dir)
+	if err != nil {
+		t.Fatalf("loadManifest returned error: %v", err)
+	}
+	if loaded.Operation != OperationSplit || loaded.Ranges != "1-2" || len(loaded.Files) != 1 {
+		t.Fatalf("unexpected manifest: %#v", loaded)
+	}
+	if _, err := os.Stat(ws.dir); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("workspace should not exist on disk, stat err = %v", err)
+	}
+}
+
+func TestOpenResultFileInMemory(t *testing.T) {
+	fsys := newMemFS()
+	svc := NewServiceWithFS(&config.Config{}, fsys)
+
+	ws, err := svc.createWorkspace()
+	if err != nil {
+		t.Fatalf("createWorkspace returned error: %v", err)
+	}
+	if err := writeManifest(fsys, ws.dir, &JobManifest{JobID: ws.jobID, Operation: OperationMerge}); err != nil {
+		t.Fatalf("writeManifest returned error: %v", err)
+	}
+	out, err := fsys.OpenFile(filepath.Join(ws.outDir, outputFilename), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o640)
+	if err != nil {
+		t.Fatalf("failed to create output: %v", err)
+	}
+	payload := []byte("%PDF-1.4\n% in-memory\n")
+	_, _ = out.Write(payload)
+	_ = out.Close()
+
+	result, file, err := svc.OpenResultFile(ws.jobID)
+	if err != nil {
+		t.Fatalf("OpenResultFile returned error: %v", err)
+	}
+	data, err := io.ReadAll(file)
+	_ = file.Close()
+	if err != nil {
+		t.Fatalf("failed to read result: %v", err)
+	}
+	if !bytes.Equal(data, payload) || result.OutputSize != int64(len(payload)) {
+		t.Fatalf("unexpected result: size=%d data=%q", result.OutputSize, data)
+	}
+
+	if err := result.Cleanup(); err != nil {
+		t.Fatalf("Cleanup returned error: %v", err)
+	}
+	if _, _, err := svc.OpenResultFile(ws.jobID); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected not-exist after cleanup, got %v", err)
+	}
+}
+
+func TestOpenArtifactByName(t *testing.T) {
+	fsys := newMemFS()
+	svc := NewServiceWithFS(&config.Config{}, fsys)
+
+	ws, err := svc.createWorkspace()
+	if err != nil {
+		t.Fatalf("createWorkspace returned error: %v", err)
+	}
+	if err := writeManifest(fsys, ws.dir, &JobManifest{JobID: ws.jobID, Operation: OperationSplit}); err != nil {
+		t.Fatalf("writeManifest returned error: %v", err)
+	}
+	files := map[string][]byte{
+		splitFilename: []byte("PK zip"),
+		"part-01.pdf": []byte("%PDF-1.4\n% part 1\n"),
+	}
+	for name, data := range files {
+		out, err := fsys.OpenFile(filepath.Join(ws.outDir, name), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o640)
+		if err != nil {
+			t.Fatalf("failed to create %s: %v", name, err)
+		}
+		_, _ = out.Write(data)
+		_ = out.Close()
+	}
+	artifacts := []Artifact{
+		{Name: splitFilename, Kind: ResultKindZIP, Size: int64(len(files[splitFilename]))},
+		{Name: "part-01.pdf", Kind: ResultKindPDF, Size: int64(len(files["part-01.pdf"]))},
+	}
+	if err := writeArtifacts(fsys, ws.dir, artifacts); err != nil {
+		t.Fatalf("writeArtifacts returned error: %v", err)
+	}
+
+	result, file, err := svc.OpenArtifact(ws.jobID, "part-01.pdf")
+	if err != nil {
+		t.Fatalf("OpenArtifact returned error: %v", err)
+	}
+	data, _ := io.ReadAll(file)
+	_ = file.Close()
+	if !bytes.Equal(data, files["part-01.pdf"]) || result.ResultKind != ResultKindPDF || result.OutputFilename != "part-01.pdf" {
+		t.Fatalf("unexpected artifact: %#v data=%q", result, data)
+	}
+	if len(result.Artifacts) != 2 {
+		t.Fatalf("expected 2 artifacts, got %d", len(result.Artifacts))
+	}
+
+	primary, file, err := svc.OpenResultFile(ws.jobID)
+	if err != nil {
+		t.Fatalf("OpenResultFile returned error: %v", err)
+	}
+	_ = file.Close()
+	if primary.OutputFilename != splitFilename || primary.ResultKind != ResultKindZIP {
+		t.Fatalf("expected zip as primary artifact, got %#v", primary)
+	}
+
+	if _, _, err := svc.OpenArtifact(ws.jobID, "../manifest.json"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected not-exist for unknown artifact, got %v", err)
+	}
+}
+
+func TestOpenInputRequiresKeepInputs(t *testing.T) {
+	fsys := newMemFS()
+	svc := NewServiceWithFS(&config.Config{}, fsys)
+
+	ws, err := svc.createWorkspace()
+	if err != nil {
+		t.Fatalf("createWorkspace returned error: %v", err)
+	}
+	inputs := map[string][]byte{
+		"00.pdf": []byte("%PDF-1.4\n% a\n"),
+		"01.pdf": []byte("%PDF-1.4\n% b\n"),
+		"02.pdf": []byte("%PDF-1.4\n% c\n"),
+	}
+	for name, data := range inputs {
+		f, err := fsys.OpenFile(filepath.Join(ws.inDir, name), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o640)
+		if err != nil {
+			t.Fatalf("failed to create %s: %v", name, err)
+		}
+		_, _ = f.Write(data)
+		_ = f.Close()
+	}
+	manifest := &JobManifest{
+		JobID:     ws.jobID,
+		Operation: OperationMerge,
+		Files: []JobFile{
+			{StoredName: "00.pdf", OriginalName: "scan.pdf", Size: int64(len(inputs["00.pdf"]))},
+			{StoredName: "01.pdf", OriginalName: "page.pdf", Size: int64(len(inputs["01.pdf"]))},
+			{StoredName: "02.pdf", OriginalName: "page.pdf", Size: int64(len(inputs["02.pdf"]))},
+		},
+	}
+	if err := writeManifest(fsys, ws.dir, manifest); err != nil {
+		t.Fatalf("writeManifest returned error: %v", err)
+	}
+	if _, _, err := svc.OpenInput(ws.jobID, "scan.pdf"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("inputs should not be exposed without keepInputs, got %v", err)
+	}
+
+	manifest.Options.KeepInputs = true
+	if err := writeManifest(fsys, ws.dir, manifest); err != nil {
+		t.Fatalf("writeManifest returned error: %v", err)
+	}
+	retained, err := svc.RetainedInputs(ws.jobID)
+	if err != nil {
+		t.Fatalf("RetainedInputs returned error: %v", err)
+	}
+	var names []string
+	for _, in := range retained {
+		names = append(names, in.Name)
+	}
+	// 元のファイル名が重複する入力は保存名で区別する
+	if strings.Join(names, ",") != "scan.pdf,01.pdf,02.pdf" {
+		t.Fatalf("unexpected retained inputs: %v", names)
+	}
+
+	input, file, err := svc.OpenInput(ws.jobID, "02.pdf")
+	if err != nil {
+		t.Fatalf("OpenInput returned error: %v", err)
+	}
+	data, _ := io.ReadAll(file)
+	_ = file.Close()
+	if !bytes.Equal(data, inputs["02.pdf"]) || input.Size != int64(len(data)) {
+		t.Fatalf("unexpected input: %#v data=%q", input, data)
+	}
+	if _, _, err := svc.OpenInput(ws.jobID, "../manifest.json"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected not-exist for unknown input, got %v", err)
+	}
+}
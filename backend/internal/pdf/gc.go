@@ -0,0 +1,103 @@
+package pdf
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WorkspaceEntry は作業領域（WORK_DIR）に残っているジョブのワークスペース1つ分の情報です。
+type WorkspaceEntry struct {
+	JobID string `json:"jobId"`
+	// Operation はマニフェストの操作です。マニフェストが無い（作成途中・破損）場合は空です。
+	Operation OperationType `json:"operation,omitempty"`
+	// CreatedAt はマニフェストの作成日時です。マニフェストが無い場合はディレクトリの更新日時です。
+	CreatedAt  time.Time `json:"createdAt"`
+	AgeSeconds int64     `json:"ageSeconds"`
+	Size       int64     `json:"size"`
+	Held       bool      `json:"held"`
+}
+
+// ListWorkspaces は作業領域に残っているワークスペースを作成日時の古い順に返します。
+// 結果キャッシュ・分割アップロード・共有入力ストアのディレクトリ（"." で始まる）とジョブIDの形式でない項目は含めません。
+// 作業領域はインスタンスごとのローカルディスクのため、このインスタンスのワークスペースだけが対象です。
+func (s *Service) ListWorkspaces() ([]WorkspaceEntry, error) {
+	dirEntries, err := os.ReadDir(s.tmpRoot)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return []WorkspaceEntry{}, nil
+		}
+		return nil, err
+	}
+	now := s.now()
+	entries := make([]WorkspaceEntry, 0, len(dirEntries))
+	for _, d := range dirEntries {
+		if !d.IsDir() || strings.HasPrefix(d.Name(), ".") || !ValidWorkspaceID(d.Name()) {
+			continue
+		}
+		info, err := d.Info()
+		if err != nil {
+			// 一覧の取得後に削除された
+			continue
+		}
+		entries = append(entries, s.workspaceEntry(d.Name(), info.ModTime(), now))
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if !entries[i].CreatedAt.Equal(entries[j].CreatedAt) {
+			return entries[i].CreatedAt.Before(entries[j].CreatedAt)
+		}
+		return entries[i].JobID < entries[j].JobID
+	})
+	return entries, nil
+}
+
+// Workspace はジョブのワークスペースの情報を返します。このインスタンスの作業領域に無い場合は false を返します。
+func (s *Service) Workspace(jobID string) (WorkspaceEntry, bool) {
+	if !ValidWorkspaceID(jobID) {
+		return WorkspaceEntry{}, false
+	}
+	info, err := os.Stat(s.workspaceFor(jobID).dir)
+	if err != nil || !info.IsDir() {
+		return WorkspaceEntry{}, false
+	}
+	return s.workspaceEntry(jobID, info.ModTime(), s.now()), true
+}
+
+func (s *Service) workspaceEntry(jobID string, modTime, now time.Time) WorkspaceEntry {
+	ws := s.workspaceFor(jobID)
+	entry := WorkspaceEntry{
+		JobID:     ws.jobID,
+		CreatedAt: modTime.UTC(),
+		Size:      diskUsage(ws.dir),
+		Held:      s.workspaceHeld(ws.dir),
+	}
+	if manifest, err := loadManifest(s.fs, ws.dir); err == nil {
+		entry.Operation = manifest.Operation
+		if !manifest.CreatedAt.IsZero() {
+			entry.CreatedAt = manifest.CreatedAt.UTC()
+		}
+	}
+	entry.AgeSeconds = int64(now.Sub(entry.CreatedAt) / time.Second)
+	return entry
+}
+
+// WorkspaceExists はジョブのワークスペースがこのインスタンスの作業領域にあるかを返します。
+func (s *Service) WorkspaceExists(jobID string) bool {
+	if !ValidWorkspaceID(jobID) {
+		return false
+	}
+	_, err := s.fs.Stat(s.workspaceFor(jobID).dir)
+	return err == nil
+}
+
+// ValidWorkspaceID はワークスペースのディレクトリ名として使われるジョブID（UUID）かどうかを返します。
+// 管理操作で受け取ったIDを作業領域の外のパスとして扱わないよう、削除の前にも確認します。
+func ValidWorkspaceID(jobID string) bool {
+	_, err := uuid.Parse(jobID)
+	return err == nil
+}
@@ -0,0 +1,61 @@
+package pdf
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/yourusername/paper-forge/internal/config"
+)
+
+func TestListWorkspacesReportsAgeSizeAndHold(t *testing.T) {
+	svc := NewService(&config.Config{})
+	svc.tmpRoot = t.TempDir()
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	svc.now = func() time.Time { return now }
+
+	const (
+		older = "0b5e4f8a-3c1d-4f6e-9a2b-7c8d9e0f1a2b"
+		newer = "1c6f5a9b-4d2e-4a7f-8b3c-8d9e0f1a2b3c"
+	)
+	for id, created := range map[string]time.Time{older: now.Add(-30 * time.Minute), newer: now.Add(-time.Minute)} {
+		ws := svc.workspaceFor(id)
+		if err := os.MkdirAll(ws.inDir, 0o750); err != nil {
+			t.Fatalf("failed to create workspace: %v", err)
+		}
+		if err := writeManifest(svc.fs, ws.dir, &JobManifest{JobID: id, Operation: OperationMerge, CreatedAt: created}); err != nil {
+			t.Fatalf("failed to write manifest: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(ws.inDir, "00.pdf"), make([]byte, 1000), 0o640); err != nil {
+			t.Fatalf("failed to write input: %v", err)
+		}
+	}
+	if err := svc.HoldWorkspace(older, "audit"); err != nil {
+		t.Fatalf("HoldWorkspace returned error: %v", err)
+	}
+	// 結果キャッシュ・ジョブIDでないディレクトリは含めない
+	for _, dir := range []string{cacheDirName, "not-a-job"} {
+		if err := os.MkdirAll(filepath.Join(svc.tmpRoot, dir), 0o750); err != nil {
+			t.Fatalf("failed to create %s: %v", dir, err)
+		}
+	}
+
+	entries, err := svc.ListWorkspaces()
+	if err != nil {
+		t.Fatalf("ListWorkspaces returned error: %v", err)
+	}
+	if len(entries) != 2 || entries[0].JobID != older || entries[1].JobID != newer {
+		t.Fatalf("unexpected entries: %+v", entries)
+	}
+	if e := entries[0]; e.AgeSeconds != 1800 || !e.Held || e.Operation != OperationMerge || e.Size <= 1000 {
+		t.Fatalf("unexpected entry: %+v", e)
+	}
+	if entries[1].Held {
+		t.Fatalf("workspace should not be held: %+v", entries[1])
+	}
+
+	if !svc.WorkspaceExists(newer) || svc.WorkspaceExists("../"+newer) || svc.WorkspaceExists("2d7a6b0c-5e3f-4b8a-9c4d-9e0f1a2b3c4d") {
+		t.Fatal("unexpected WorkspaceExists result")
+	}
+}
@@ -0,0 +1,132 @@
+package pdf
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/yourusername/paper-forge/internal/execrun"
+)
+
+// ghostscript は Ghostscript 実行ファイルの解決結果を保持します。
+// リクエストごとの PATH 探索を避けるため、初回解決時の絶対パスを再利用します。
+type ghostscript struct {
+	configured string
+
+	once       sync.Once
+	path       string
+	resolveErr error
+
+	// version は確認済みのバージョン文字列です（WarmGhostscript または処理記録の作成時に確認）。
+	versionMu sync.Mutex
+	version   string
+}
+
+func newGhostscript(configured string) *ghostscript {
+	if configured == "" {
+		configured = "gs"
+	}
+	return &ghostscript{configured: configured}
+}
+
+// resolve は設定された Ghostscript のパスを絶対パスへ解決します。
+func (g *ghostscript) resolve() (string, error) {
+	g.once.Do(func() {
+		path, err := exec.LookPath(g.configured)
+		if err != nil {
+			g.resolveErr = fmt.Errorf("Ghostscript が見つかりません (%s): %w", g.configured, err)
+			return
+		}
+		g.path = path
+	})
+	return g.path, g.resolveErr
+}
+
+// WarmGhostscript は Ghostscript の実行ファイルを解決し、一度起動して動作確認を行います。
+// 起動時に呼び出しておくことで、初回の圧縮リクエストで探索と読み込みの待ち時間が発生しないようにします。
+// 戻り値は Ghostscript のバージョン文字列です。
+func (s *Service) WarmGhostscript(ctx context.Context) (string, error) {
+	path, err := s.gs.resolve()
+	if err != nil {
+		return "", err
+	}
+	out, err := s.runner.Run(ctx, execrun.Command{Name: path, Args: []string{"--version"}})
+	if err != nil {
+		return "", fmt.Errorf("Ghostscript の起動確認に失敗しました (%s): %w", path, err)
+	}
+	version := strings.TrimSpace(string(out.Stdout))
+	s.gs.versionMu.Lock()
+	s.gs.version = version
+	s.gs.versionMu.Unlock()
+	return version, nil
+}
+
+// GhostscriptVersion は起動時（WarmGhostscript）に確認した Ghostscript のバージョンを返します。
+// 確認していない場合や起動に失敗した場合は空です。
+func (s *Service) GhostscriptVersion() string {
+	s.gs.versionMu.Lock()
+	defer s.gs.versionMu.Unlock()
+	return s.gs.version
+}
+
+// ghostscriptVersion は Ghostscript のバージョンを返します。まだ確認していない場合は起動して確認します。
+func (s *Service) ghostscriptVersion(ctx context.Context) string {
+	if version := s.GhostscriptVersion(); version != "" {
+		return version
+	}
+	version, _ := s.WarmGhostscript(ctx)
+	return version
+}
+
+// maxGhostscriptWarnings は結果メタに含める警告の最大件数です。
+const maxGhostscriptWarnings = 20
+
+// ghostscriptWarningPrefixes は Ghostscript が回復可能な問題を報告する行の先頭句です（先頭の "****" は除去済み）。
+var ghostscriptWarningPrefixes = []string{"Warning", "Error"}
+
+// ghostscriptWarningPhrases は行中に含まれていれば警告とみなす定型句です。
+var ghostscriptWarningPhrases = []string{
+	"Output may be incorrect",
+	"Substituting font",
+	"Can't find (or can't open) font",
+	"ICC profile",
+}
+
+// parseGhostscriptWarnings は Ghostscript の出力から警告行を抽出します。
+// 同じ警告はページごとに繰り返されることが多いため重複を除き、件数も上限で打ち切ります。
+func parseGhostscriptWarnings(output string) []string {
+	var warnings []string
+	seen := make(map[string]struct{})
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), "*"))
+		if line == "" || !isGhostscriptWarning(line) {
+			continue
+		}
+		if _, ok := seen[line]; ok {
+			continue
+		}
+		seen[line] = struct{}{}
+		if len(warnings) == maxGhostscriptWarnings {
+			warnings = append(warnings, "(以降の警告は省略されました)")
+			break
+		}
+		warnings = append(warnings, line)
+	}
+	return warnings
+}
+
+func isGhostscriptWarning(line string) bool {
+	for _, prefix := range ghostscriptWarningPrefixes {
+		if strings.HasPrefix(line, prefix) {
+			return true
+		}
+	}
+	for _, phrase := range ghostscriptWarningPhrases {
+		if strings.Contains(line, phrase) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,210 @@
+package pdf
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yourusername/paper-forge/internal/config"
+	"github.com/yourusername/paper-forge/internal/execrun"
+)
+
+type stubRunner struct {
+	calls []execrun.Command
+	out   execrun.Output
+	err   error
+}
+
+func (r *stubRunner) Run(ctx context.Context, cmd execrun.Command) (execrun.Output, error) {
+	r.calls = append(r.calls, cmd)
+	if cmd.Log != nil {
+		_, _ = cmd.Log.Write(r.out.Stdout)
+		_, _ = cmd.Log.Write(r.out.Stderr)
+	}
+	return r.out, r.err
+}
+
+func newGhostscriptTestService(runner execrun.Runner) *Service {
+	svc := NewService(&config.Config{GhostscriptPath: "gs"})
+	// PATH 探索を行わず、解決済みとして扱う
+	svc.gs.once.Do(func() { svc.gs.path = "/usr/bin/gs" })
+	svc.runner = runner
+	return svc
+}
+
+func TestRunGhostscriptUsesRunner(t *testing.T) {
+	runner := &stubRunner{}
+	svc := newGhostscriptTestService(runner)
+
+	if _, err := svc.runGhostscript(context.Background(), "in.pdf", "out.pdf", "", ghostscriptOptions{preset: OptimizePresetAggressive}); err != nil {
+		t.Fatalf("runGhostscript returned error: %v", err)
+	}
+	if len(runner.calls) != 1 {
+		t.Fatalf("expected 1 runner call, got %d", len(runner.calls))
+	}
+	call := runner.calls[0]
+	if call.Name != "/usr/bin/gs" {
+		t.Fatalf("unexpected binary: %s", call.Name)
+	}
+	if !strings.Contains(strings.Join(call.Args, " "), "-dPDFSETTINGS=/screen") {
+		t.Fatalf("aggressive preset not applied: %v", call.Args)
+	}
+}
+
+func TestRunGhostscriptFailure(t *testing.T) {
+	runner := &stubRunner{
+		out: execrun.Output{Stderr: []byte("Error: /syntaxerror")},
+		err: errors.New("exit status 1"),
+	}
+	svc := newGhostscriptTestService(runner)
+
+	_, err := svc.runGhostscript(context.Background(), "in.pdf", "out.pdf", "", ghostscriptOptions{preset: OptimizePresetStandard})
+	if !IsError(err, "UNSUPPORTED_PDF") {
+		t.Fatalf("expected UNSUPPORTED_PDF, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "/syntaxerror") {
+		t.Fatalf("expected Ghostscript output in error, got %v", err)
+	}
+}
+
+func TestRunGhostscriptCanceled(t *testing.T) {
+	svc := newGhostscriptTestService(&stubRunner{err: errors.New("signal: killed")})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := svc.runGhostscript(ctx, "in.pdf", "out.pdf", "", ghostscriptOptions{preset: OptimizePresetStandard})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestRunGhostscriptReturnsWarnings(t *testing.T) {
+	runner := &stubRunner{out: execrun.Output{Stdout: []byte(
+		"   **** Warning: Fonts with Subtype = /TrueType should be embedded.\n" +
+			"   **** Warning: Fonts with Subtype = /TrueType should be embedded.\n" +
+			"Substituting font Helvetica for ArialMT.\n" +
+			"   **** This file had errors that were repaired or ignored.\n" +
+			"   **** Error: ICC profile is broken. Output may be incorrect.\n",
+	)}}
+	svc := newGhostscriptTestService(runner)
+
+	warnings, err := svc.runGhostscript(context.Background(), "in.pdf", "out.pdf", "", ghostscriptOptions{preset: OptimizePresetStandard})
+	if err != nil {
+		t.Fatalf("runGhostscript returned error: %v", err)
+	}
+	want := []string{
+		"Warning: Fonts with Subtype = /TrueType should be embedded.",
+		"Substituting font Helvetica for ArialMT.",
+		"Error: ICC profile is broken. Output may be incorrect.",
+	}
+	if strings.Join(warnings, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("warnings = %q, want %q", warnings, want)
+	}
+}
+
+func TestRunGhostscriptWritesLog(t *testing.T) {
+	runner := &stubRunner{out: execrun.Output{Stderr: []byte("GPL Ghostscript 10.0\n")}}
+	svc := newGhostscriptTestService(runner)
+	logPath := filepath.Join(t.TempDir(), ghostscriptLogFilename)
+
+	if _, err := svc.runGhostscript(context.Background(), "in.pdf", "out.pdf", logPath, ghostscriptOptions{preset: OptimizePresetStandard}); err != nil {
+		t.Fatalf("runGhostscript returned error: %v", err)
+	}
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatalf("failed to read log: %v", err)
+	}
+	if string(data) != "GPL Ghostscript 10.0\n" {
+		t.Fatalf("unexpected log contents: %q", data)
+	}
+}
+
+func TestGhostscriptResolve(t *testing.T) {
+	dir := t.TempDir()
+	binary := filepath.Join(dir, "gs")
+	if err := os.WriteFile(binary, []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatalf("failed to write fake gs: %v", err)
+	}
+	t.Setenv("PATH", dir)
+
+	tests := []struct {
+		name       string
+		configured string
+		want       string
+		wantErr    bool
+	}{
+		{name: "default name is looked up on PATH", want: binary},
+		{name: "absolute path", configured: binary, want: binary},
+		{name: "missing binary", configured: filepath.Join(dir, "missing-gs"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gs := newGhostscript(tt.configured)
+			path, err := gs.resolve()
+			if (err != nil) != tt.wantErr || path != tt.want {
+				t.Fatalf("resolve() = %q, %v; want %q, error %v", path, err, tt.want, tt.wantErr)
+			}
+			// 解決結果は再利用し、PATH が変わっても探索し直さない
+			t.Setenv("PATH", "")
+			if again, againErr := gs.resolve(); again != path || (againErr != nil) != tt.wantErr {
+				t.Fatalf("resolve should reuse the first result, got %q, %v", again, againErr)
+			}
+			t.Setenv("PATH", dir)
+		})
+	}
+}
+
+func TestWarmGhostscriptRecordsVersion(t *testing.T) {
+	runner := &stubRunner{out: execrun.Output{Stdout: []byte("10.02.1\n")}}
+	svc := newGhostscriptTestService(runner)
+
+	if got := svc.GhostscriptVersion(); got != "" {
+		t.Fatalf("version should be empty before the warm-up, got %q", got)
+	}
+	version, err := svc.WarmGhostscript(context.Background())
+	if err != nil || version != "10.02.1" {
+		t.Fatalf("WarmGhostscript = %q, %v", version, err)
+	}
+	if len(runner.calls) != 1 || runner.calls[0].Name != "/usr/bin/gs" || strings.Join(runner.calls[0].Args, " ") != "--version" {
+		t.Fatalf("unexpected warm-up command: %+v", runner.calls)
+	}
+	if got := svc.ghostscriptVersion(context.Background()); got != "10.02.1" || len(runner.calls) != 1 {
+		t.Fatalf("the recorded version should be reused, got %q after %d calls", got, len(runner.calls))
+	}
+
+	failing := newGhostscriptTestService(&stubRunner{err: errors.New("exit status 127")})
+	if _, err := failing.WarmGhostscript(context.Background()); err == nil {
+		t.Fatal("expected error when gs fails to start")
+	}
+	if got := failing.ghostscriptVersion(context.Background()); got != "" {
+		t.Fatalf("failed warm-ups should not record a version, got %q", got)
+	}
+}
+
+func TestRunGhostscriptWritesLogThroughFS(t *testing.T) {
+	runner := &stubRunner{out: execrun.Output{Stderr: []byte("page done\n")}}
+	svc := newGhostscriptTestService(runner)
+	mem := newMemFS()
+	svc.fs = mem
+	logPath := filepath.Join("/jobs/job-1", ghostscriptLogFilename)
+	if err := mem.MkdirAll(filepath.Dir(logPath), 0o750); err != nil {
+		t.Fatalf("MkdirAll returned error: %v", err)
+	}
+
+	// ページ範囲ごとの実行ではログに追記する
+	for range 2 {
+		if _, err := svc.runGhostscript(context.Background(), "in.pdf", "out.pdf", logPath, ghostscriptOptions{preset: OptimizePresetStandard}); err != nil {
+			t.Fatalf("runGhostscript returned error: %v", err)
+		}
+	}
+	data, err := readFileFS(mem, logPath)
+	if err != nil {
+		t.Fatalf("log should be written through the service FS: %v", err)
+	}
+	if string(data) != "page done\npage done\n" {
+		t.Fatalf("unexpected log contents: %q", data)
+	}
+}
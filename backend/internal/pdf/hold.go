@@ -0,0 +1,59 @@
+package pdf
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// holdFilename はリーガルホールド中のワークスペースに置く目印のファイル名です。
+const holdFilename = "hold.json"
+
+// WorkspaceHold はワークスペースに記録したリーガルホールドの情報です。
+type WorkspaceHold struct {
+	Reason string    `json:"reason,omitempty"`
+	HeldAt time.Time `json:"heldAt"`
+}
+
+// HoldWorkspace はジョブのワークスペース（入力と成果物）を有効期限による削除の対象外にします。
+// 既に削除されたワークスペースの場合は fs.ErrNotExist を返します。
+func (s *Service) HoldWorkspace(jobID, reason string) error {
+	if strings.TrimSpace(jobID) == "" {
+		return fmt.Errorf("jobID is required")
+	}
+	ws := s.workspaceFor(jobID)
+	if _, err := s.fs.Stat(filepath.Join(ws.dir, manifestFilename)); err != nil {
+		return err
+	}
+	return writeJSON(s.fs, filepath.Join(ws.dir, holdFilename), WorkspaceHold{
+		Reason: reason,
+		HeldAt: s.now().UTC(),
+	})
+}
+
+// ReleaseWorkspace はリーガルホールドを解除します。ワークスペースは解除した時点から
+// 改めて有効期限（JOB_EXPIRE_MINUTES）の間だけ残し、その後削除します。
+func (s *Service) ReleaseWorkspace(jobID string) error {
+	if strings.TrimSpace(jobID) == "" {
+		return fmt.Errorf("jobID is required")
+	}
+	ws := s.workspaceFor(jobID)
+	if !s.workspaceHeld(ws.dir) {
+		return nil
+	}
+	if err := s.fs.RemoveAll(filepath.Join(ws.dir, holdFilename)); err != nil {
+		return err
+	}
+	s.scheduleCleanup(ws.dir)
+	return nil
+}
+
+// workspaceHeld はワークスペースがリーガルホールド中かを返します。
+// 目印の有無を確認できない場合は、誤って削除しないようホールド中として扱います。
+func (s *Service) workspaceHeld(dir string) bool {
+	_, err := s.fs.Stat(filepath.Join(dir, holdFilename))
+	return err == nil || !errors.Is(err, fs.ErrNotExist)
+}
@@ -0,0 +1,49 @@
+package pdf
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/yourusername/paper-forge/internal/config"
+)
+
+func TestHoldAndReleaseWorkspace(t *testing.T) {
+	fsys := newMemFS()
+	svc := NewServiceWithFS(&config.Config{}, fsys)
+
+	ws, err := svc.createWorkspace()
+	if err != nil {
+		t.Fatalf("createWorkspace returned error: %v", err)
+	}
+	if err := writeManifest(fsys, ws.dir, &JobManifest{JobID: ws.jobID, Operation: OperationMerge}); err != nil {
+		t.Fatalf("writeManifest returned error: %v", err)
+	}
+	if svc.workspaceHeld(ws.dir) {
+		t.Fatal("new workspace should not be held")
+	}
+
+	if err := svc.HoldWorkspace(ws.jobID, "case 2025-123"); err != nil {
+		t.Fatalf("HoldWorkspace returned error: %v", err)
+	}
+	if !svc.workspaceHeld(ws.dir) {
+		t.Fatal("workspace should be held")
+	}
+
+	if err := svc.ReleaseWorkspace(ws.jobID); err != nil {
+		t.Fatalf("ReleaseWorkspace returned error: %v", err)
+	}
+	if svc.workspaceHeld(ws.dir) {
+		t.Fatal("workspace should be released")
+	}
+	if _, err := fsys.Stat(filepath.Join(ws.dir, manifestFilename)); err != nil {
+		t.Fatalf("release should keep the workspace until it expires: %v", err)
+	}
+
+	if err := svc.HoldWorkspace(uuid.NewString(), ""); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected not-exist for removed workspace, got %v", err)
+	}
+}
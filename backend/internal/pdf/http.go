@@ -1,18 +1,23 @@
 package pdf
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"log"
 	"mime/multipart"
 	"net/http"
 	"net/url"
-	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+
+	"github.com/yourusername/paper-forge/internal/metrics"
 )
 
 // JobRunner はジョブを実行できるサービスが実装します。
@@ -21,28 +26,64 @@ type JobRunner interface {
 	DiscardJob(jobID string) error
 }
 
+// JobPlanner は準備済みジョブの処理計画（dryRun）を提供します。
+type JobPlanner interface {
+	PlanJob(ctx context.Context, jobID string) (*JobPlan, error)
+}
+
 // MergeService は結合ジョブの準備と実行を提供します。
 type MergeService interface {
 	JobRunner
-	PrepareMergeJob(ctx context.Context, files []*multipart.FileHeader, order []int) (*JobManifest, error)
+	PrepareMergeJob(ctx context.Context, files []*multipart.FileHeader, order []int, toc bool, opts JobOptions) (*JobManifest, error)
 }
 
 // ReorderService はページ順入替ジョブの準備と実行を提供します。
 type ReorderService interface {
 	JobRunner
-	PrepareReorderJob(ctx context.Context, file *multipart.FileHeader, order []int) (*JobManifest, error)
+	PrepareReorderJob(ctx context.Context, file *multipart.FileHeader, order []int, mode ReorderMode, opts JobOptions) (*JobManifest, error)
 }
 
 // SplitService は分割ジョブの準備と実行を提供します。
 type SplitService interface {
 	JobRunner
-	PrepareSplitJob(ctx context.Context, file *multipart.FileHeader, rangesExpr string) (*JobManifest, error)
+	PrepareSplitJob(ctx context.Context, file *multipart.FileHeader, rangesExpr string, output SplitOutput, opts JobOptions) (*JobManifest, error)
+}
+
+// RotateService はページ回転ジョブの準備と実行を提供します。
+type RotateService interface {
+	JobRunner
+	PrepareRotateJob(ctx context.Context, file *multipart.FileHeader, rotations map[string]int, opts JobOptions) (*JobManifest, error)
+}
+
+// WatermarkService は透かし追加ジョブの準備と実行を提供します。
+type WatermarkService interface {
+	JobRunner
+	PrepareWatermarkJob(ctx context.Context, file *multipart.FileHeader, spec WatermarkSpec, pages string, opts JobOptions) (*JobManifest, error)
+}
+
+// StampService は画像スタンプジョブの準備と実行を提供します。
+type StampService interface {
+	JobRunner
+	PrepareStampJob(ctx context.Context, file, image *multipart.FileHeader, spec StampSpec, pages string, opts JobOptions) (*JobManifest, error)
 }
 
 // OptimizeService は圧縮ジョブの準備と実行を提供します。
 type OptimizeService interface {
 	JobRunner
-	PrepareOptimizeJob(ctx context.Context, file *multipart.FileHeader, preset OptimizePreset) (*JobManifest, error)
+	PrepareOptimizeJob(ctx context.Context, file *multipart.FileHeader, preset OptimizePreset, pages, colorProfile string, opts JobOptions) (*JobManifest, error)
+}
+
+// OperationService は登録済みの操作をまとめて扱うサービスです。
+type OperationService interface {
+	JobRunner
+	PrepareJob(ctx context.Context, op OperationType, req PrepareRequest) (*JobManifest, error)
+}
+
+// UploadService は分割アップロードの受信と状態の取得を提供します。
+type UploadService interface {
+	CreateUpload(filename string, size int64) (*UploadStatus, error)
+	AppendUpload(id string, offset int64, r io.Reader) (*UploadStatus, error)
+	UploadStatus(id string) (*UploadStatus, error)
 }
 
 // InspectService はPDFメタデータを取得する機能を提供します。
@@ -50,9 +91,20 @@ type InspectService interface {
 	InspectMultipart(ctx context.Context, file *multipart.FileHeader) (*InspectResult, error)
 }
 
+// PreviewService はジョブの成果物のページを画像にする機能を提供します。
+type PreviewService interface {
+	RenderResultPreview(ctx context.Context, jobID, artifact string, page, width int) ([]byte, error)
+}
+
+// ThumbnailService はページのサムネイルを作成する機能を提供します。
+type ThumbnailService interface {
+	RenderThumbnails(ctx context.Context, file *multipart.FileHeader, pagesExpr string, width int) (*ThumbnailResult, error)
+}
+
 // JobScheduler はジョブを非同期キューに投入するためのインターフェースです。
+// 同じ内容のジョブがキュー待ち・処理中の場合は投入せず、そのジョブIDを返します。それ以外は jobID を返します。
 type JobScheduler interface {
-	Schedule(ctx context.Context, op OperationType, jobID string) error
+	Schedule(ctx context.Context, op OperationType, jobID string) (string, error)
 }
 
 // HandlerOptions は同期/非同期切り替えのための設定です。
@@ -60,6 +112,14 @@ type HandlerOptions struct {
 	Scheduler           JobScheduler
 	AsyncThresholdBytes int64
 	AsyncThresholdPages int
+	// Admission は同期処理の同時実行数を制限します（nil の場合は制限なし）。
+	Admission *Admission
+	// SoftLimits は確認が必要なジョブの規模を判定します（nil の場合はソフトリミットなし）。
+	SoftLimits *SoftLimits
+	// Usage は同期処理したジョブの利用量を記録します（nil の場合は記録しない）。
+	Usage UsageRecorder
+	// Delivery は指定できる配送先の範囲です（nil の場合は deliverTo=email を受け付けない）。
+	Delivery *DeliveryPolicy
 }
 
 // MergeHandler は POST /api/pdf/merge のハンドラーを返します。
@@ -96,34 +156,24 @@ func MergeHandler(svc MergeService, opts HandlerOptions) gin.HandlerFunc {
 			return
 		}
 
-		manifest, err := svc.PrepareMergeJob(c.Request.Context(), files, order)
+		toc, err := parseTOC(c.PostForm("toc"))
 		if err != nil {
 			respondWithError(c, err)
 			return
 		}
 
-		if shouldProcessAsync(manifest, opts) {
-			if err := opts.Scheduler.Schedule(c.Request.Context(), manifest.Operation, manifest.JobID); err != nil {
-				if cleanupErr := svc.DiscardJob(manifest.JobID); cleanupErr != nil {
-					err = fmt.Errorf("%w (cleanup failed: %v)", err, cleanupErr)
-				}
-				respondWithError(c, err)
-				return
-			}
-			c.JSON(http.StatusAccepted, gin.H{"jobId": manifest.JobID})
+		jobOpts, ok := bindJobOptions(c, opts)
+		if !ok {
 			return
 		}
 
-		result, err := svc.RunJob(c.Request.Context(), manifest.JobID, nil)
+		manifest, err := svc.PrepareMergeJob(c.Request.Context(), files, order, toc, jobOpts)
 		if err != nil {
 			respondWithError(c, err)
 			return
 		}
-		defer result.Cleanup()
 
-		if err := streamResult(c, result, "結合結果の読み込みに失敗しました"); err != nil {
-			respondWithError(c, err)
-		}
+		dispatchJob(c, svc, manifest, opts, "結合結果の読み込みに失敗しました")
 	}
 }
 
@@ -158,39 +208,86 @@ func ReorderHandler(svc ReorderService, opts HandlerOptions) gin.HandlerFunc {
 			return
 		}
 
-		manifest, err := svc.PrepareReorderJob(c.Request.Context(), file, order)
+		jobOpts, ok := bindJobOptions(c, opts)
+		if !ok {
+			return
+		}
+
+		manifest, err := svc.PrepareReorderJob(c.Request.Context(), file, order, ReorderMode(c.PostForm("mode")), jobOpts)
 		if err != nil {
 			respondWithError(c, err)
 			return
 		}
 
-		if shouldProcessAsync(manifest, opts) {
-			if err := opts.Scheduler.Schedule(c.Request.Context(), manifest.Operation, manifest.JobID); err != nil {
-				if cleanupErr := svc.DiscardJob(manifest.JobID); cleanupErr != nil {
-					err = fmt.Errorf("%w (cleanup failed: %v)", err, cleanupErr)
-				}
-				respondWithError(c, err)
-				return
-			}
-			c.JSON(http.StatusAccepted, gin.H{"jobId": manifest.JobID})
+		dispatchJob(c, svc, manifest, opts, "ページ順入替結果の読み込みに失敗しました")
+	}
+}
+
+// SplitHandler は POST /api/pdf/split のハンドラーを返します。
+func SplitHandler(svc SplitService, opts HandlerOptions) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		form, err := c.MultipartForm()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    "INVALID_INPUT",
+				"message": "multipart/form-data でPDFファイルを送信してください。",
+			})
+			return
+		}
+		defer form.RemoveAll()
+
+		file, err := extractSingleFile(form)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    "INVALID_INPUT",
+				"message": err.Error(),
+			})
+			return
+		}
+
+		rangesExpr, err := SplitRangesFromForm(c.PostForm("ranges"), c.PostForm("mode"))
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+		if rangesExpr == "" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    "INVALID_INPUT",
+				"message": "分割するページ範囲を指定してください（1ページずつの場合は each）。",
+			})
 			return
 		}
 
-		result, err := svc.RunJob(c.Request.Context(), manifest.JobID, nil)
+		output, err := normalizeSplitOutput(SplitOutput(c.PostForm("output")))
 		if err != nil {
 			respondWithError(c, err)
 			return
 		}
-		defer result.Cleanup()
 
-		if err := streamResult(c, result, "ページ順入替結果の読み込みに失敗しました"); err != nil {
+		jobOpts, ok := bindJobOptions(c, opts)
+		if !ok {
+			return
+		}
+		if output == SplitOutputParts && opts.Scheduler == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"code":    "JOBS_DISABLED",
+				"message": "分割結果を個別に受け取るには非同期ジョブ機能が必要です。Redis を起動してサーバーを再起動してください。",
+			})
+			return
+		}
+
+		manifest, err := svc.PrepareSplitJob(c.Request.Context(), file, rangesExpr, output, jobOpts)
+		if err != nil {
 			respondWithError(c, err)
+			return
 		}
+
+		dispatchJob(c, svc, manifest, opts, "分割結果の読み込みに失敗しました")
 	}
 }
 
-// SplitHandler は POST /api/pdf/split のハンドラーを返します。
-func SplitHandler(svc SplitService, opts HandlerOptions) gin.HandlerFunc {
+// RotateHandler は POST /api/pdf/rotate のハンドラーを返します。
+func RotateHandler(svc RotateService, opts HandlerOptions) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		form, err := c.MultipartForm()
 		if err != nil {
@@ -211,43 +308,128 @@ func SplitHandler(svc SplitService, opts HandlerOptions) gin.HandlerFunc {
 			return
 		}
 
-		rangesExpr := strings.TrimSpace(c.PostForm("ranges"))
-		if rangesExpr == "" {
+		rotations, err := parseRotationsValue(c.PostForm("rotations"))
+		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{
 				"code":    "INVALID_INPUT",
-				"message": "分割するページ範囲を指定してください。",
+				"message": err.Error(),
 			})
 			return
 		}
 
-		manifest, err := svc.PrepareSplitJob(c.Request.Context(), file, rangesExpr)
+		jobOpts, ok := bindJobOptions(c, opts)
+		if !ok {
+			return
+		}
+
+		manifest, err := svc.PrepareRotateJob(c.Request.Context(), file, rotations, jobOpts)
 		if err != nil {
 			respondWithError(c, err)
 			return
 		}
 
-		if shouldProcessAsync(manifest, opts) {
-			if err := opts.Scheduler.Schedule(c.Request.Context(), manifest.Operation, manifest.JobID); err != nil {
-				if cleanupErr := svc.DiscardJob(manifest.JobID); cleanupErr != nil {
-					err = fmt.Errorf("%w (cleanup failed: %v)", err, cleanupErr)
-				}
-				respondWithError(c, err)
-				return
-			}
-			c.JSON(http.StatusAccepted, gin.H{"jobId": manifest.JobID})
+		dispatchJob(c, svc, manifest, opts, "ページ回転結果の読み込みに失敗しました")
+	}
+}
+
+// WatermarkHandler は POST /api/pdf/watermark のハンドラーを返します。
+func WatermarkHandler(svc WatermarkService, opts HandlerOptions) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		form, err := c.MultipartForm()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    "INVALID_INPUT",
+				"message": "multipart/form-data でPDFファイルを送信してください。",
+			})
 			return
 		}
+		defer form.RemoveAll()
 
-		result, err := svc.RunJob(c.Request.Context(), manifest.JobID, nil)
+		file, err := extractSingleFile(form)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    "INVALID_INPUT",
+				"message": err.Error(),
+			})
+			return
+		}
+
+		spec, err := parseWatermarkSpec(url.Values(form.Value))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    "INVALID_INPUT",
+				"message": err.Error(),
+			})
+			return
+		}
+
+		jobOpts, ok := bindJobOptions(c, opts)
+		if !ok {
+			return
+		}
+
+		manifest, err := svc.PrepareWatermarkJob(c.Request.Context(), file, spec, c.PostForm("pages"), jobOpts)
 		if err != nil {
 			respondWithError(c, err)
 			return
 		}
-		defer result.Cleanup()
 
-		if err := streamResult(c, result, "分割結果の読み込みに失敗しました"); err != nil {
+		dispatchJob(c, svc, manifest, opts, "透かし追加結果の読み込みに失敗しました")
+	}
+}
+
+// StampHandler は POST /api/pdf/stamp のハンドラーを返します。
+// PDF を file、重ねる画像（PNG または JPEG）を image で受け取ります。
+func StampHandler(svc StampService, opts HandlerOptions) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		form, err := c.MultipartForm()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    "INVALID_INPUT",
+				"message": "multipart/form-data でPDFファイルと画像を送信してください。",
+			})
+			return
+		}
+		defer form.RemoveAll()
+
+		file, err := extractSingleFile(form)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    "INVALID_INPUT",
+				"message": err.Error(),
+			})
+			return
+		}
+		images := form.File["image"]
+		if len(images) != 1 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    "INVALID_INPUT",
+				"message": "スタンプに使う画像（PNG または JPEG）を image に1つ指定してください。",
+			})
+			return
+		}
+
+		spec, err := parseStampSpec(url.Values(form.Value))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    "INVALID_INPUT",
+				"message": err.Error(),
+			})
+			return
+		}
+
+		jobOpts, ok := bindJobOptions(c, opts)
+		if !ok {
+			return
+		}
+
+		manifest, err := svc.PrepareStampJob(c.Request.Context(), file, images[0], spec, c.PostForm("pages"), jobOpts)
+		if err != nil {
 			respondWithError(c, err)
+			return
 		}
+
+		dispatchJob(c, svc, manifest, opts, "スタンプ追加結果の読み込みに失敗しました")
 	}
 }
 
@@ -274,35 +456,232 @@ func OptimizeHandler(svc OptimizeService, opts HandlerOptions) gin.HandlerFunc {
 		}
 
 		preset := OptimizePreset(strings.TrimSpace(c.PostForm("preset")))
+		// pages を指定した場合はその範囲のみ圧縮し、他のページはそのまま残す（例: スキャンした付録のみ）
+		pages := strings.TrimSpace(c.PostForm("pages"))
+		// colorProfile を指定すると ICC プロファイルで色を変換し、出力インテントとして埋め込む（none で既定値を使わない）
+		colorProfile := c.PostForm("colorProfile")
 
-		manifest, err := svc.PrepareOptimizeJob(c.Request.Context(), file, preset)
+		jobOpts, ok := bindJobOptions(c, opts)
+		if !ok {
+			return
+		}
+
+		manifest, err := svc.PrepareOptimizeJob(c.Request.Context(), file, preset, pages, colorProfile, jobOpts)
 		if err != nil {
 			respondWithError(c, err)
 			return
 		}
 
-		if shouldProcessAsync(manifest, opts) {
-			if err := opts.Scheduler.Schedule(c.Request.Context(), manifest.Operation, manifest.JobID); err != nil {
-				if cleanupErr := svc.DiscardJob(manifest.JobID); cleanupErr != nil {
-					err = fmt.Errorf("%w (cleanup failed: %v)", err, cleanupErr)
-				}
-				respondWithError(c, err)
-				return
+		dispatchJob(c, svc, manifest, opts, "圧縮結果の読み込みに失敗しました")
+	}
+}
+
+// OperationHandler は registerOperation で登録された操作 op の汎用ハンドラーを返します。
+// ファイルは files[] / files / file のいずれかで受け取り、その他のフォーム項目は操作へそのまま渡します。
+func OperationHandler(svc OperationService, op OperationType, opts HandlerOptions) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		form, err := c.MultipartForm()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    "INVALID_INPUT",
+				"message": "multipart/form-data でPDFファイルを送信してください。",
+			})
+			return
+		}
+		defer form.RemoveAll()
+
+		var files []*multipart.FileHeader
+		for _, key := range []string{"files[]", "files", "file", "file[]"} {
+			if len(form.File[key]) > 0 {
+				files = form.File[key]
+				break
 			}
-			c.JSON(http.StatusAccepted, gin.H{"jobId": manifest.JobID})
+		}
+		if len(files) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    "INVALID_INPUT",
+				"message": "アップロードされたPDFファイルが見つかりません。",
+			})
+			return
+		}
+
+		jobOpts, ok := bindJobOptions(c, opts)
+		if !ok {
 			return
 		}
 
-		result, err := svc.RunJob(c.Request.Context(), manifest.JobID, nil)
+		manifest, err := svc.PrepareJob(c.Request.Context(), op, PrepareRequest{
+			Files:   files,
+			Params:  url.Values(form.Value),
+			Options: jobOpts,
+		})
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		dispatchJob(c, svc, manifest, opts, "処理結果の読み込みに失敗しました")
+	}
+}
+
+// UploadCreateHandler は POST /api/uploads のハンドラーを返します。
+// JSON で { "filename": string, "size": number } を受け取り、アップロードIDを含む状態を返します。
+func UploadCreateHandler(svc UploadService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			Filename string `json:"filename"`
+			Size     int64  `json:"size"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    "INVALID_INPUT",
+				"message": "filename と size を JSON で指定してください。",
+			})
+			return
+		}
+		status, err := svc.CreateUpload(req.Filename, req.Size)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, status)
+	}
+}
+
+// UploadChunkHandler は PATCH /api/uploads/:id のハンドラーを返します。
+// リクエストボディをそのままファイルの続きとして受け取り、Upload-Offset ヘッダーで開始位置を指定します。
+func UploadChunkHandler(svc UploadService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		offset, err := strconv.ParseInt(strings.TrimSpace(c.GetHeader("Upload-Offset")), 10, 64)
+		if err != nil || offset < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    "INVALID_INPUT",
+				"message": "Upload-Offset ヘッダーに開始位置を指定してください。",
+			})
+			return
+		}
+		status, err := svc.AppendUpload(c.Param("id"), offset, c.Request.Body)
 		if err != nil {
 			respondWithError(c, err)
 			return
 		}
-		defer result.Cleanup()
+		c.Header("Upload-Offset", strconv.FormatInt(status.ReceivedBytes, 10))
+		c.JSON(http.StatusOK, status)
+	}
+}
 
-		if err := streamResult(c, result, "圧縮結果の読み込みに失敗しました"); err != nil {
+// UploadStatusHandler は GET /api/uploads/:id/status のハンドラーを返します。
+func UploadStatusHandler(svc UploadService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		status, err := svc.UploadStatus(c.Param("id"))
+		if err != nil {
 			respondWithError(c, err)
+			return
 		}
+		c.Header("Cache-Control", "no-store")
+		c.JSON(http.StatusOK, status)
+	}
+}
+
+// OverrideTokenHandler は POST /api/admin/override-tokens のハンドラーを返します。
+// JSON で { "ttlMinutes": number }（任意、既定・上限は24時間）を受け取り、一度だけ使える上書きトークンを発行します。
+func OverrideTokenHandler(tokens *OverrideTokens) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			TTLMinutes int `json:"ttlMinutes"`
+		}
+		if c.Request.ContentLength != 0 {
+			if err := c.ShouldBindJSON(&req); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"code":    "INVALID_INPUT",
+					"message": "ttlMinutes を数値で指定してください。",
+				})
+				return
+			}
+		}
+		if req.TTLMinutes < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    "INVALID_INPUT",
+				"message": "ttlMinutes は0以上で指定してください。",
+			})
+			return
+		}
+
+		token, expiresAt := tokens.Issue(time.Duration(req.TTLMinutes) * time.Minute)
+		log.Printf("override token issued from %s, expires at %s", c.ClientIP(), expiresAt.Format(time.RFC3339))
+		c.Header("Cache-Control", "no-store")
+		c.JSON(http.StatusCreated, gin.H{
+			"token":     token,
+			"expiresAt": expiresAt,
+		})
+	}
+}
+
+// PrecheckHandler は POST /api/pdf/precheck のハンドラーを返します。
+// 各ファイルの先頭 PrecheckMaxBytes バイトのみを受け取り、本アップロード前にPDF形式・バージョン・暗号化を確認します。
+// ファイルの内容に問題があっても 200 を返し、ファイルごとの結果で知らせます。
+func PrecheckHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// 先頭部分のみを受け付けるため、本体全体を送ってきたリクエストは読み込み前に打ち切る
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, int64(maxUploadFiles+1)*PrecheckMaxBytes)
+		form, err := c.MultipartForm()
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+					"code":    "LIMIT_EXCEEDED",
+					"message": fmt.Sprintf("各ファイルの先頭%dKBのみを送信してください。", PrecheckMaxBytes/1024),
+				})
+				return
+			}
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    "INVALID_INPUT",
+				"message": "multipart/form-data でPDFファイルを送信してください。",
+			})
+			return
+		}
+		defer form.RemoveAll()
+
+		files := form.File["files[]"]
+		if len(files) == 0 {
+			files = form.File["files"]
+		}
+		if len(files) == 0 {
+			files = form.File["file"]
+		}
+		if len(files) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    "INVALID_INPUT",
+				"message": "アップロードされたPDFファイルが見つかりません。",
+			})
+			return
+		}
+		if len(files) > maxUploadFiles {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+				"code":    "LIMIT_EXCEEDED",
+				"message": fmt.Sprintf("アップロードできるPDFは最大%d件までです。", maxUploadFiles),
+			})
+			return
+		}
+
+		results := make([]PrecheckResult, 0, len(files))
+		for i, fh := range files {
+			name := safeOriginalName(fh.Filename, i)
+			src, err := fh.Open()
+			if err != nil {
+				respondWithError(c, fmt.Errorf("ファイルを開けませんでした(%s): %w", name, err))
+				return
+			}
+			head, err := io.ReadAll(io.LimitReader(src, PrecheckMaxBytes))
+			src.Close()
+			if err != nil {
+				respondWithError(c, fmt.Errorf("ファイルの読み取りに失敗しました(%s): %w", name, err))
+				return
+			}
+			results = append(results, PrecheckHead(name, head))
+		}
+
+		c.JSON(http.StatusOK, gin.H{"files": results})
 	}
 }
 
@@ -328,6 +707,10 @@ func InspectHandler(svc InspectService) gin.HandlerFunc {
 			return
 		}
 
+		if !bindInputPasswords(c) {
+			return
+		}
+
 		result, err := svc.InspectMultipart(c.Request.Context(), file)
 		if err != nil {
 			respondWithError(c, err)
@@ -338,11 +721,131 @@ func InspectHandler(svc InspectService) gin.HandlerFunc {
 	}
 }
 
+// ThumbnailsHandler は POST /api/pdf/thumbnails のハンドラーを返します。
+// format=json（既定は zip）の場合は画像を base64 にした JSON を返します。
+func ThumbnailsHandler(svc ThumbnailService, admission *Admission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		form, err := c.MultipartForm()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    "INVALID_INPUT",
+				"message": "multipart/form-data でPDFファイルを送信してください。",
+			})
+			return
+		}
+		defer form.RemoveAll()
+
+		file, err := extractSingleFile(form)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    "INVALID_INPUT",
+				"message": err.Error(),
+			})
+			return
+		}
+
+		width := 0
+		if raw := strings.TrimSpace(c.PostForm("width")); raw != "" {
+			width, err = strconv.Atoi(raw)
+			if err != nil || width <= 0 {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"code":    "INVALID_INPUT",
+					"message": "width は正の整数（ピクセル）で指定してください。",
+				})
+				return
+			}
+		}
+		format := strings.ToLower(strings.TrimSpace(c.PostForm("format")))
+		if format != "" && format != "zip" && format != "json" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    "INVALID_INPUT",
+				"message": "format は zip または json を指定してください。",
+			})
+			return
+		}
+
+		if !bindInputPasswords(c) {
+			return
+		}
+
+		release, ok := admission.TryAcquire()
+		if !ok {
+			metrics.SaturatedRequests.Add("rejected", 1)
+			c.Header("Retry-After", strconv.Itoa(admission.RetryAfter()))
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"code":    "SERVER_BUSY",
+				"message": "現在処理が混み合っています。しばらくしてから再度お試しください。",
+			})
+			return
+		}
+		defer release()
+
+		result, err := svc.RenderThumbnails(c.Request.Context(), file, c.PostForm("pages"), width)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		c.Header("Cache-Control", "no-store")
+		if format == "json" {
+			c.JSON(http.StatusOK, result)
+			return
+		}
+		var buf bytes.Buffer
+		if err := result.WriteZIP(&buf); err != nil {
+			respondWithError(c, fmt.Errorf("サムネイルのZIPを作成できませんでした: %w", err))
+			return
+		}
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", thumbnailsFilename))
+		c.Data(http.StatusOK, "application/zip", buf.Bytes())
+	}
+}
+
+// JobPreviewHandler は GET /api/jobs/:id/preview のハンドラーを返します。
+// 成果物をダウンロードする前に確認できるよう、指定したページ（page、既定は1）を PNG で返します。
+func JobPreviewHandler(svc PreviewService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		page, width := 1, 0
+		for _, q := range []struct {
+			name string
+			dst  *int
+		}{{"page", &page}, {"width", &width}} {
+			raw := strings.TrimSpace(c.Query(q.name))
+			if raw == "" {
+				continue
+			}
+			v, err := strconv.Atoi(raw)
+			if err != nil || v <= 0 {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"code":    "INVALID_INPUT",
+					"message": fmt.Sprintf("%s は正の整数で指定してください。", q.name),
+				})
+				return
+			}
+			*q.dst = v
+		}
+
+		data, err := svc.RenderResultPreview(c.Request.Context(), c.Param("id"), c.Query("artifact"), page, width)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+		c.Header("Cache-Control", "no-store")
+		c.Header("X-Job-Id", c.Param("id"))
+		c.Data(http.StatusOK, "image/png", data)
+	}
+}
+
 func shouldProcessAsync(manifest *JobManifest, opts HandlerOptions) bool {
 	if manifest == nil || opts.Scheduler == nil {
 		return false
 	}
 
+	// 個別の成果物は同期レスポンスで返せないため、ジョブとして処理する
+	if manifest.Options.requiresAsync() || manifest.Output == SplitOutputParts {
+		return true
+	}
+
 	if opts.AsyncThresholdBytes > 0 {
 		var total int64
 		for _, f := range manifest.Files {
@@ -366,8 +869,171 @@ func shouldProcessAsync(manifest *JobManifest, opts HandlerOptions) bool {
 	return false
 }
 
+// parseDryRun は dryRun の指定を読み取ります。不正な値の場合は準備したジョブを破棄し、レスポンスを書き込んだうえで false を返します。
+func parseDryRun(c *gin.Context, svc JobRunner, manifest *JobManifest) (bool, bool) {
+	raw := strings.TrimSpace(c.PostForm("dryRun"))
+	if raw == "" {
+		return false, true
+	}
+	dryRun, err := strconv.ParseBool(raw)
+	if err != nil {
+		_ = svc.DiscardJob(manifest.JobID)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    "INVALID_INPUT",
+			"message": "dryRun には true または false を指定してください。",
+		})
+		return false, false
+	}
+	return dryRun, true
+}
+
+// respondWithPlan は処理を行わずに処理計画を返し、準備したジョブを破棄します。
+func respondWithPlan(c *gin.Context, svc JobRunner, manifest *JobManifest) {
+	defer func() { _ = svc.DiscardJob(manifest.JobID) }()
+	planner, ok := svc.(JobPlanner)
+	if !ok {
+		respondWithError(c, newError("INVALID_INPUT", "この処理は dryRun に対応していません。", nil))
+		return
+	}
+	plan, err := planner.PlanJob(c.Request.Context(), manifest.JobID)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"dryRun": true, "plan": plan})
+}
+
+// bindJobOptions は操作共通のフォーム項目を読み取り、JobOptions を組み立てます。
+// 入力エラー時はレスポンスを書き込んだうえで false を返します。
+func bindJobOptions(c *gin.Context, opts HandlerOptions) (JobOptions, bool) {
+	var jobOpts JobOptions
+
+	method := c.PostForm("deliverTo")
+	if method == "" {
+		method = c.PostForm("deliver")
+	}
+	delivery, err := parseDeliveryTarget(deliveryForm{
+		Method:     method,
+		Email:      c.PostForm("deliverEmail"),
+		OutputPath: c.PostForm("outputPath"),
+		OutputURL:  c.PostForm("outputUrl"),
+		FolderID:   c.PostForm("deliverFolderId"),
+		Connection: c.PostForm(DeliverConnectionField),
+	}, opts.Delivery)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    "INVALID_INPUT",
+			"message": err.Error(),
+		})
+		return JobOptions{}, false
+	}
+	jobOpts.Delivery = delivery
+
+	webhook, err := parseNotifyWebhook(c.PostForm("notifyWebhook"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    "INVALID_INPUT",
+			"message": err.Error(),
+		})
+		return JobOptions{}, false
+	}
+	jobOpts.NotifyWebhook = webhook
+
+	label, err := parseJobLabel(c.PostForm("label"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    "INVALID_INPUT",
+			"message": err.Error(),
+		})
+		return JobOptions{}, false
+	}
+	jobOpts.Label = label
+
+	clientContext, err := parseClientContext(c.PostForm("clientContext"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    "INVALID_INPUT",
+			"message": err.Error(),
+		})
+		return JobOptions{}, false
+	}
+	jobOpts.ClientContext = clientContext
+
+	if raw := strings.TrimSpace(c.PostForm("keepInputs")); raw != "" {
+		keep, err := strconv.ParseBool(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    "INVALID_INPUT",
+				"message": "keepInputs には true または false を指定してください。",
+			})
+			return JobOptions{}, false
+		}
+		jobOpts.KeepInputs = keep
+	}
+
+	receipt, err := parseReceiptMode(c.PostForm("receipt"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    "INVALID_INPUT",
+			"message": err.Error(),
+		})
+		return JobOptions{}, false
+	}
+	jobOpts.Receipt = receipt
+
+	pdfVersion, err := parsePDFVersion(c.PostForm("pdfVersion"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    "INVALID_INPUT",
+			"message": err.Error(),
+		})
+		return JobOptions{}, false
+	}
+	jobOpts.PDFVersion = pdfVersion
+
+	if !bindInputPasswords(c) {
+		return JobOptions{}, false
+	}
+
+	if jobOpts.requiresAsync() && opts.Scheduler == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"code":    "JOBS_DISABLED",
+			"message": "成果物の配送・通知・入力ファイルの保持・受領書の別添には非同期ジョブ機能が必要です。Redis を起動してサーバーを再起動してください。",
+		})
+		return JobOptions{}, false
+	}
+
+	return jobOpts, true
+}
+
+// bindInputPasswords は passwords[] を読み取り、入力PDFの復号に使うパスワードとしてリクエストのコンテキストに設定します。
+// パスワードは JobOptions に含めず、マニフェストにも保存しません。入力エラー時はレスポンスを書き込んだうえで false を返します。
+func bindInputPasswords(c *gin.Context) bool {
+	passwords := c.PostFormArray("passwords[]")
+	if len(passwords) == 0 {
+		passwords = c.PostFormArray("passwords")
+	}
+	if len(passwords) == 0 {
+		return true
+	}
+	if err := validateInputPasswords(passwords); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    "INVALID_INPUT",
+			"message": err.Error(),
+		})
+		return false
+	}
+	c.Request = c.Request.WithContext(WithInputPasswords(c.Request.Context(), passwords))
+	return true
+}
+
 func parseOrder(c *gin.Context) ([]int, error) {
-	raw := strings.TrimSpace(c.PostForm("order"))
+	return parseOrderValues(c.PostForm("order"), c.PostFormArray("order[]"))
+}
+
+// parseOrderValues は order（JSON 配列）または order[]（複数値）の指定を整数配列に変換します。
+func parseOrderValues(rawJSON string, values []string) ([]int, error) {
+	raw := strings.TrimSpace(rawJSON)
 	if raw != "" {
 		var order []int
 		if err := json.Unmarshal([]byte(raw), &order); err != nil {
@@ -376,7 +1042,7 @@ func parseOrder(c *gin.Context) ([]int, error) {
 		return order, nil
 	}
 
-	if values := c.PostFormArray("order[]"); len(values) > 0 {
+	if len(values) > 0 {
 		order := make([]int, len(values))
 		for i, v := range values {
 			trimmed := strings.TrimSpace(v)
@@ -395,17 +1061,231 @@ func parseOrder(c *gin.Context) ([]int, error) {
 	return nil, nil
 }
 
+// parseRotationsValue は rotations（ページ範囲から回転角度への JSON オブジェクト）の指定を変換します。
+func parseRotationsValue(rawJSON string) (map[string]int, error) {
+	raw := strings.TrimSpace(rawJSON)
+	if raw == "" {
+		return nil, errors.New("回転するページ範囲と角度を指定してください。")
+	}
+	var rotations map[string]int
+	if err := json.Unmarshal([]byte(raw), &rotations); err != nil {
+		return nil, errors.New(`rotations は JSON 形式でページ範囲と角度を指定してください。例: {"1-3":90,"5":180}`)
+	}
+	if len(rotations) == 0 {
+		return nil, errors.New("回転するページ範囲と角度を指定してください。")
+	}
+	return rotations, nil
+}
+
+// parseSizeValues は sizes（JSON 配列）または sizes[]（複数値）の指定をバイト数の配列に変換します。
+func parseSizeValues(rawJSON string, values []string) ([]int64, error) {
+	raw := strings.TrimSpace(rawJSON)
+	if raw != "" {
+		var sizes []int64
+		if err := json.Unmarshal([]byte(raw), &sizes); err != nil {
+			return nil, errors.New("sizes は JSON 形式の整数配列で指定してください。例: [1024,2048]")
+		}
+		return sizes, nil
+	}
+
+	if len(values) > 0 {
+		sizes := make([]int64, len(values))
+		for i, v := range values {
+			num, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+			if err != nil {
+				return nil, errors.New("sizes[] の値は整数で指定してください。")
+			}
+			sizes[i] = num
+		}
+		return sizes, nil
+	}
+
+	return nil, nil
+}
+
+// checkDeclaredSizes はクライアントが sizes で宣言したファイルサイズと、サーバーが受信したバイト数を比較します。
+// 一致しない場合は途中で切れたアップロードとみなし、壊れた成果物を作る前に UPLOAD_TRUNCATED を返します。
+// sizes の指定は任意で、順序はアップロードしたファイルの順です。
+func checkDeclaredSizes(c *gin.Context, manifest *JobManifest) error {
+	sizes, err := parseSizeValues(c.PostForm("sizes"), c.PostFormArray("sizes[]"))
+	if err != nil {
+		return newError("INVALID_INPUT", err.Error(), nil)
+	}
+	if sizes == nil {
+		return nil
+	}
+	if len(sizes) != len(manifest.Files) {
+		return newError("INVALID_INPUT", fmt.Sprintf("sizes の件数(%d)がファイル数(%d)と一致しません。", len(sizes), len(manifest.Files)), nil)
+	}
+	for i, f := range manifest.Files {
+		if sizes[i] != f.Size {
+			return newError("UPLOAD_TRUNCATED", fmt.Sprintf("%s の受信サイズ(%dバイト)が宣言されたサイズ(%dバイト)と一致しません。アップロードが途中で切れた可能性があるため、再度お試しください。", f.OriginalName, f.Size, sizes[i]), nil)
+		}
+	}
+	return nil
+}
+
+// checkSoftLimits はソフトリミットを超えたジョブを、confirm=true での再送信か上書きトークンがある場合のみ通します。
+// 通さない場合はジョブを破棄して応答を書き込み、false を返します。confirm=true の場合はトークンを消費しません。
+func checkSoftLimits(c *gin.Context, svc JobRunner, manifest *JobManifest, limits *SoftLimits) bool {
+	warnings := limits.warnings(manifest)
+	if len(warnings) == 0 {
+		return true
+	}
+	if confirmed, _ := strconv.ParseBool(c.PostForm("confirm")); confirmed {
+		return true
+	}
+
+	if token := strings.TrimSpace(c.GetHeader(OverrideTokenHeader)); token != "" {
+		err := limits.redeem(token)
+		if err == nil {
+			log.Printf("override token accepted for job %s: %s", manifest.JobID, strings.Join(warnings, " "))
+			return true
+		}
+		_ = svc.DiscardJob(manifest.JobID)
+		message := "上書きトークンが正しくありません。"
+		switch {
+		case errors.Is(err, ErrOverrideTokenExpired):
+			message = "上書きトークンの有効期限が切れています。"
+		case errors.Is(err, ErrOverrideTokenUsed):
+			message = "この上書きトークンは使用済みです。"
+		}
+		c.JSON(http.StatusForbidden, gin.H{
+			"code":    "OVERRIDE_TOKEN_INVALID",
+			"message": message,
+		})
+		return false
+	}
+
+	_ = svc.DiscardJob(manifest.JobID)
+	c.JSON(http.StatusConflict, gin.H{
+		"code":     "CONFIRMATION_REQUIRED",
+		"message":  "処理に時間がかかる可能性がある大きなジョブです。実行する場合は confirm=true を付けて再送信してください。",
+		"warnings": warnings,
+	})
+	return false
+}
+
+// dispatchJob は準備済みジョブを非同期キューへ投入するか、同期実行して結果を返します。
+// 同期処理の実行枠が埋まっている場合は非同期へ切り替え、キューが無ければ 503 を返します。
+func dispatchJob(c *gin.Context, svc JobRunner, manifest *JobManifest, opts HandlerOptions, readErrMsg string) {
+	if err := checkDeclaredSizes(c, manifest); err != nil {
+		_ = svc.DiscardJob(manifest.JobID)
+		respondWithError(c, err)
+		return
+	}
+	dryRun, ok := parseDryRun(c, svc, manifest)
+	if !ok {
+		return
+	}
+	if dryRun {
+		respondWithPlan(c, svc, manifest)
+		return
+	}
+	if !checkSoftLimits(c, svc, manifest, opts.SoftLimits) {
+		return
+	}
+
+	async := shouldProcessAsync(manifest, opts)
+
+	release := func() {}
+	if !async {
+		var ok bool
+		release, ok = opts.Admission.TryAcquire()
+		if !ok {
+			if opts.Scheduler == nil {
+				metrics.SaturatedRequests.Add("rejected", 1)
+				_ = svc.DiscardJob(manifest.JobID)
+				c.Header("Retry-After", strconv.Itoa(opts.Admission.RetryAfter()))
+				c.JSON(http.StatusServiceUnavailable, gin.H{
+					"code":    "SERVER_BUSY",
+					"message": "現在処理が混み合っています。しばらくしてから再度お試しください。",
+				})
+				return
+			}
+			metrics.SaturatedRequests.Add("upgraded", 1)
+			async = true
+		}
+	}
+	defer release()
+
+	if async {
+		scheduledID, err := opts.Scheduler.Schedule(c.Request.Context(), manifest.Operation, manifest.JobID)
+		if err != nil {
+			if cleanupErr := svc.DiscardJob(manifest.JobID); cleanupErr != nil {
+				err = fmt.Errorf("%w (cleanup failed: %v)", err, cleanupErr)
+			}
+			respondWithError(c, err)
+			return
+		}
+		if scheduledID != "" && scheduledID != manifest.JobID {
+			// 二重送信など同じ内容のジョブが実行中のため、今回の入力は破棄して既存のジョブを返す
+			_ = svc.DiscardJob(manifest.JobID)
+			c.JSON(http.StatusAccepted, gin.H{"jobId": scheduledID, "deduplicated": true})
+			return
+		}
+		c.JSON(http.StatusAccepted, gin.H{"jobId": manifest.JobID})
+		return
+	}
+
+	result, err := runSyncJob(c, svc, manifest, opts.Usage)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+	defer result.Cleanup()
+
+	if err := streamResult(c, result, readErrMsg); err != nil {
+		respondWithError(c, err)
+	}
+}
+
+// runSyncJob はリクエストのコンテキストでジョブを同期実行します。
+// クライアントが切断した場合は処理を中断し、中断件数を操作種別ごとに記録します。
+// Ghostscript などの外部コマンドはその場で停止しますが、pdfcpu の処理は実行中のステップが終わってから中断します（runStep）。
+func runSyncJob(c *gin.Context, svc JobRunner, manifest *JobManifest, usage UsageRecorder) (*Result, error) {
+	meter := startUsage(manifest)
+	result, err := svc.RunJob(c.Request.Context(), manifest.JobID, nil)
+	if err != nil && errors.Is(err, context.Canceled) {
+		metrics.AbandonedRequests.Add(string(manifest.Operation), 1)
+	}
+	if sample, ok := meter.Finish(result, err); ok && usage != nil {
+		// 中断されたリクエストでも処理した分は記録する
+		usage.RecordUsage(context.WithoutCancel(c.Request.Context()), sample)
+	}
+	return result, err
+}
+
+// respondWithError はエラーを {code, message} の JSON で返します。
+// 文言は UserMessage で利用者向けにしたものだけを返し、原因（外部コマンドの出力など）はログに出します。
 func respondWithError(c *gin.Context, err error) {
 	var apiErr *Error
 	switch {
 	case errors.As(err, &apiErr):
+		if apiErr.Err != nil {
+			log.Printf("request failed path=%s code=%s: %v", c.FullPath(), apiErr.Code, err)
+		}
+		code, message := UserMessage(err)
 		status := http.StatusBadRequest
-		if apiErr.Code == "LIMIT_EXCEEDED" {
+		switch apiErr.Code {
+		case "LIMIT_EXCEEDED":
 			status = http.StatusRequestEntityTooLarge
+		case "INSUFFICIENT_STORAGE":
+			status = http.StatusInsufficientStorage
+		case "UPLOAD_NOT_FOUND", "JOB_RESULT_NOT_FOUND", "RESULT_MANIFEST_NOT_FOUND", "RESULT_SIGNING_DISABLED":
+			status = http.StatusNotFound
+		case "UPLOAD_OFFSET_MISMATCH":
+			status = http.StatusConflict
+		case "OPERATION_DISABLED":
+			status = http.StatusForbidden
+		case "TENANT_QUOTA_EXCEEDED":
+			status = http.StatusTooManyRequests
+		case "RESULT_SIGNING_UNAVAILABLE":
+			status = http.StatusServiceUnavailable
 		}
 		c.JSON(status, gin.H{
-			"code":    apiErr.Code,
-			"message": apiErr.Message,
+			"code":    code,
+			"message": message,
 		})
 	case errors.Is(err, context.Canceled):
 		c.JSON(http.StatusRequestTimeout, gin.H{
@@ -413,9 +1293,11 @@ func respondWithError(c *gin.Context, err error) {
 			"message": "リクエストがキャンセルされました。",
 		})
 	default:
+		log.Printf("request failed path=%s: %v", c.FullPath(), err)
+		code, message := UserMessage(err)
 		c.JSON(http.StatusInternalServerError, gin.H{
-			"code":    "INTERNAL_ERROR",
-			"message": "サーバー内部でエラーが発生しました。",
+			"code":    code,
+			"message": message,
 		})
 	}
 }
@@ -441,7 +1323,7 @@ func extractSingleFile(form *multipart.Form) (*multipart.FileHeader, error) {
 }
 
 func streamResult(c *gin.Context, result *Result, readErrMsg string) error {
-	file, err := os.Open(result.OutputPath)
+	file, err := result.Open()
 	if err != nil {
 		return fmt.Errorf("%s: %w", readErrMsg, err)
 	}
@@ -460,6 +1342,9 @@ func streamResult(c *gin.Context, result *Result, readErrMsg string) error {
 	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"; filename*=UTF-8''%s", result.OutputFilename, encodedName))
 	c.Header("Cache-Control", "no-store")
 	c.Header("X-Job-Id", result.JobID)
+	if result.Cached {
+		c.Header("X-Result-Cache", "hit")
+	}
 	c.DataFromReader(http.StatusOK, result.OutputSize, contentType, file, nil)
 	return nil
 }
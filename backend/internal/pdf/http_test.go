@@ -11,9 +11,13 @@ import (
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
+
+	"github.com/yourusername/paper-forge/internal/config"
 )
 
 type stubMergeService struct {
@@ -26,7 +30,7 @@ type stubMergeService struct {
 	discardIDs []string
 }
 
-func (s *stubMergeService) PrepareMergeJob(ctx context.Context, files []*multipart.FileHeader, order []int) (*JobManifest, error) {
+func (s *stubMergeService) PrepareMergeJob(ctx context.Context, files []*multipart.FileHeader, order []int, toc bool, opts JobOptions) (*JobManifest, error) {
 	if s.prepareErr != nil {
 		return nil, s.prepareErr
 	}
@@ -54,13 +58,18 @@ type stubScheduler struct {
 	jobID string
 	op    OperationType
 	err   error
+	// existing を設定すると、同じ内容のジョブが実行中として扱います。
+	existing string
 }
 
-func (s *stubScheduler) Schedule(ctx context.Context, op OperationType, jobID string) error {
+func (s *stubScheduler) Schedule(ctx context.Context, op OperationType, jobID string) (string, error) {
 	s.calls++
 	s.jobID = jobID
 	s.op = op
-	return s.err
+	if s.existing != "" {
+		return s.existing, s.err
+	}
+	return jobID, s.err
 }
 
 type stubInspectService struct {
@@ -107,6 +116,21 @@ func TestParseOrderInvalid(t *testing.T) {
 	}
 }
 
+func TestParseRotationsValue(t *testing.T) {
+	rotations, err := parseRotationsValue(`{"1-3":90,"5":180}`)
+	if err != nil {
+		t.Fatalf("parseRotationsValue returned error: %v", err)
+	}
+	if len(rotations) != 2 || rotations["1-3"] != 90 || rotations["5"] != 180 {
+		t.Fatalf("unexpected rotations: %v", rotations)
+	}
+	for _, raw := range []string{"", "{}", "[90]", `{"1":"90"}`} {
+		if _, err := parseRotationsValue(raw); err == nil {
+			t.Fatalf("expected error for %q", raw)
+		}
+	}
+}
+
 func TestMergeHandlerSuccess(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
@@ -380,6 +404,94 @@ func TestMergeHandlerAsync(t *testing.T) {
 	}
 }
 
+func TestMergeHandlerAsyncDeduplicated(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	manifest := &JobManifest{
+		JobID:     "job-second-click",
+		Operation: OperationMerge,
+		Files:     []JobFile{{StoredName: "00.pdf", Size: 200, Pages: 10}},
+	}
+	service := &stubMergeService{manifest: manifest}
+	scheduler := &stubScheduler{existing: "job-first-click"}
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	fileWriter, err := writer.CreateFormFile("files[]", "input1.pdf")
+	if err != nil {
+		t.Fatalf("failed to create form file: %v", err)
+	}
+	_, _ = fileWriter.Write([]byte("dummy"))
+	_ = writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/pdf/merge", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	rec := httptest.NewRecorder()
+
+	router := gin.New()
+	router.POST("/api/pdf/merge", MergeHandler(service, HandlerOptions{Scheduler: scheduler, AsyncThresholdBytes: 100}))
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("unexpected status: %d", rec.Code)
+	}
+	var payload struct {
+		JobID        string `json:"jobId"`
+		Deduplicated bool   `json:"deduplicated"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if payload.JobID != "job-first-click" || !payload.Deduplicated {
+		t.Fatalf("expected the existing job, got %+v", payload)
+	}
+	if len(service.discardIDs) != 1 || service.discardIDs[0] != "job-second-click" {
+		t.Fatalf("duplicate workspace should be discarded: %v", service.discardIDs)
+	}
+}
+
+func TestDedupKey(t *testing.T) {
+	base := func() *JobManifest {
+		return &JobManifest{
+			JobID:     "a",
+			Operation: OperationSplit,
+			Files:     []JobFile{{StoredName: "00.pdf", OriginalName: "a.pdf", SHA256: "abc"}},
+			Ranges:    "1-3",
+			Options:   JobOptions{Label: "契約書"},
+		}
+	}
+	first, ok := dedupKey(base())
+	if !ok {
+		t.Fatal("expected a key")
+	}
+
+	again := base()
+	again.JobID = "b"
+	again.CreatedAt = again.CreatedAt.Add(time.Minute)
+	again.Files[0].StoredName = "01.pdf"
+	if key, _ := dedupKey(again); key != first {
+		t.Fatal("job ID, timestamps and stored names must not affect the key")
+	}
+
+	relabeled := base()
+	relabeled.Options.Label = "請求書"
+	if key, _ := dedupKey(relabeled); key == first {
+		t.Fatal("different options must produce a different key")
+	}
+
+	withTOC := base()
+	withTOC.TOC = true
+	if key, _ := dedupKey(withTOC); key == first {
+		t.Fatal("a table of contents must produce a different key")
+	}
+
+	unhashed := base()
+	unhashed.Files[0].SHA256 = ""
+	if _, ok := dedupKey(unhashed); ok {
+		t.Fatal("inputs without hashes must not be deduplicated")
+	}
+}
+
 func TestMergeHandlerAsyncScheduleFails(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
@@ -431,3 +543,391 @@ func TestMergeHandlerAsyncScheduleFails(t *testing.T) {
 		t.Fatalf("RunJob should not be called when scheduling fails")
 	}
 }
+
+func TestMergeHandlerDeliveryForcesAsync(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	manifest := &JobManifest{
+		JobID:     "job-delivery",
+		Operation: OperationMerge,
+		Files:     []JobFile{{StoredName: "00.pdf", Size: 10, Pages: 1}},
+		Options: JobOptions{
+			Delivery: &DeliveryTarget{Method: DeliveryMethodEmail, Email: "user@example.com"},
+		},
+	}
+
+	service := &stubMergeService{manifest: manifest}
+	scheduler := &stubScheduler{}
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	fileWriter, err := writer.CreateFormFile("files[]", "input1.pdf")
+	if err != nil {
+		t.Fatalf("failed to create form file: %v", err)
+	}
+	if _, err := io.Copy(fileWriter, bytes.NewReader([]byte("dummy"))); err != nil {
+		t.Fatalf("failed to write dummy file: %v", err)
+	}
+	_ = writer.WriteField("deliverTo", "email")
+	_ = writer.WriteField("deliverEmail", "user@example.com")
+	if err := writer.Close(); err != nil {
+		t.Fatalf("failed to close writer: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/pdf/merge", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	rec := httptest.NewRecorder()
+
+	router := gin.New()
+	opts := HandlerOptions{
+		Scheduler:           scheduler,
+		AsyncThresholdBytes: 1 << 40,
+		Delivery:            &DeliveryPolicy{EmailEnabled: true, EmailRecipients: []string{"example.com"}},
+	}
+	router.POST("/api/pdf/merge", MergeHandler(service, opts))
+
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("unexpected status: %d body=%s", rec.Code, rec.Body.String())
+	}
+	if scheduler.calls != 1 {
+		t.Fatalf("delivery jobs should always be scheduled, got %d calls", scheduler.calls)
+	}
+	if service.runCalled {
+		t.Fatalf("RunJob should not be called synchronously when delivery is requested")
+	}
+}
+
+func TestMergeHandlerSaturatedReturnsRetryAfter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	manifest := &JobManifest{
+		JobID:     "job-busy",
+		Operation: OperationMerge,
+		Files:     []JobFile{{StoredName: "00.pdf", Size: 10, Pages: 1}},
+	}
+	service := &stubMergeService{manifest: manifest}
+
+	admission := NewAdmission(1, 7*time.Second)
+	release, ok := admission.TryAcquire()
+	if !ok {
+		t.Fatalf("first acquire should succeed")
+	}
+	defer release()
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	fileWriter, err := writer.CreateFormFile("files[]", "input1.pdf")
+	if err != nil {
+		t.Fatalf("failed to create form file: %v", err)
+	}
+	if _, err := io.Copy(fileWriter, bytes.NewReader([]byte("dummy"))); err != nil {
+		t.Fatalf("failed to write dummy file: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("failed to close writer: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/pdf/merge", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	rec := httptest.NewRecorder()
+
+	router := gin.New()
+	router.POST("/api/pdf/merge", MergeHandler(service, HandlerOptions{Admission: admission}))
+
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("unexpected status: %d body=%s", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("Retry-After"); got != "7" {
+		t.Fatalf("unexpected Retry-After: %q", got)
+	}
+	if service.runCalled {
+		t.Fatalf("RunJob should not be called when saturated")
+	}
+	if len(service.discardIDs) != 1 || service.discardIDs[0] != "job-busy" {
+		t.Fatalf("prepared job should be discarded, got %v", service.discardIDs)
+	}
+}
+
+func TestParseDeliveryTarget(t *testing.T) {
+	policy := &DeliveryPolicy{EmailEnabled: true, EmailRecipients: []string{"example.com", "partner@example.org"}}
+	target, err := parseDeliveryTarget(deliveryForm{Method: "Email", Email: " Taro <Taro@Example.com> "}, policy)
+	if err != nil {
+		t.Fatalf("parseDeliveryTarget returned error: %v", err)
+	}
+	if target == nil || target.Method != DeliveryMethodEmail || target.Email != "Taro@Example.com" {
+		t.Fatalf("unexpected target: %#v", target)
+	}
+
+	if target, err := parseDeliveryTarget(deliveryForm{}, policy); err != nil || target != nil {
+		t.Fatalf("empty method should disable delivery: target=%#v err=%v", target, err)
+	}
+	if _, err := parseDeliveryTarget(deliveryForm{Method: "email"}, policy); err == nil {
+		t.Fatal("expected error when email address is missing")
+	}
+	if _, err := parseDeliveryTarget(deliveryForm{Method: "fax"}, policy); err == nil {
+		t.Fatal("expected error for unsupported method")
+	}
+
+	target, err = parseDeliveryTarget(deliveryForm{Method: "storage", OutputPath: "exports/2025/report.pdf"}, policy)
+	if err != nil || target.ObjectPath != "exports/2025/report.pdf" {
+		t.Fatalf("unexpected storage target: %#v err=%v", target, err)
+	}
+	for _, p := range []string{"../escape.pdf", "/abs.pdf", "a/../../b.pdf", "dir/", "tenants/other/report.pdf", "tenants"} {
+		if _, err := parseDeliveryTarget(deliveryForm{Method: "storage", OutputPath: p}, policy); err == nil {
+			t.Fatalf("expected error for outputPath %q", p)
+		}
+	}
+	if _, err := parseDeliveryTarget(deliveryForm{Method: "storage", OutputURL: "http://example.com/put"}, policy); err == nil {
+		t.Fatal("expected error for non-https outputUrl")
+	}
+}
+
+func TestParseDeliveryTargetRestrictsUploadHosts(t *testing.T) {
+	policy := NewDeliveryPolicy(&config.Config{OutputURLAllowedHosts: "storage.googleapis.com"})
+	tests := []struct {
+		name   string
+		policy *DeliveryPolicy
+		url    string
+		ok     bool
+	}{
+		{name: "allowed host", policy: policy, url: "https://storage.googleapis.com/bucket/out.pdf?X-Goog-Signature=abc", ok: true},
+		{name: "subdomain of an allowed host", policy: policy, url: "https://bucket.storage.googleapis.com/out.pdf", ok: true},
+		{name: "internal address", policy: policy, url: "https://169.254.169.254/computeMetadata/v1/"},
+		{name: "lookalike host", policy: policy, url: "https://storage.googleapis.com.evil.example/out.pdf"},
+		{name: "no policy", url: "https://storage.googleapis.com/bucket/out.pdf"},
+		{name: "data region set", policy: NewDeliveryPolicy(&config.Config{OutputURLAllowedHosts: "storage.googleapis.com", DataRegion: "eu"}), url: "https://storage.googleapis.com/bucket/out.pdf"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target, err := parseDeliveryTarget(deliveryForm{Method: "storage", OutputURL: tt.url}, tt.policy)
+			if tt.ok && (err != nil || target.UploadURL != tt.url) {
+				t.Fatalf("expected %s to be accepted: %#v %v", tt.url, target, err)
+			}
+			if !tt.ok && err == nil {
+				t.Fatalf("expected %s to be rejected", tt.url)
+			}
+		})
+	}
+}
+
+func TestParseDeliveryTargetRestrictsEmailRecipients(t *testing.T) {
+	allowed := &DeliveryPolicy{EmailEnabled: true, EmailRecipients: []string{"example.com", "partner@example.org"}}
+	tests := []struct {
+		name   string
+		policy *DeliveryPolicy
+		email  string
+		ok     bool
+	}{
+		{name: "allowed domain", policy: allowed, email: "user@example.com", ok: true},
+		{name: "allowed address", policy: allowed, email: "Partner@Example.org", ok: true},
+		{name: "other address in allowed address domain", policy: allowed, email: "someone@example.org"},
+		{name: "subdomain", policy: allowed, email: "user@mail.example.com"},
+		{name: "lookalike domain", policy: allowed, email: "user@evilexample.com"},
+		{name: "outside domain", policy: allowed, email: "attacker@example.net"},
+		{name: "no allowlist", policy: &DeliveryPolicy{EmailEnabled: true}, email: "user@example.com"},
+		{name: "no sender", policy: &DeliveryPolicy{EmailRecipients: []string{"example.com"}}, email: "user@example.com"},
+		{name: "no policy", email: "user@example.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseDeliveryTarget(deliveryForm{Method: "email", Email: tt.email}, tt.policy)
+			if tt.ok && err != nil {
+				t.Fatalf("expected %s to be accepted: %v", tt.email, err)
+			}
+			if !tt.ok && err == nil {
+				t.Fatalf("expected %s to be rejected", tt.email)
+			}
+		})
+	}
+}
+
+func TestNewDeliveryPolicy(t *testing.T) {
+	policy := NewDeliveryPolicy(&config.Config{MailFrom: "noreply@example.com", SMTPHost: "smtp.example.com", MailAllowedRecipients: " Example.com, ,ops@example.org "})
+	if !policy.EmailEnabled || len(policy.EmailRecipients) != 2 || policy.EmailRecipients[0] != "example.com" || policy.EmailRecipients[1] != "ops@example.org" {
+		t.Fatalf("unexpected policy: %+v", policy)
+	}
+	if NewDeliveryPolicy(&config.Config{SMTPHost: "smtp.example.com"}).EmailEnabled {
+		t.Fatal("email should be disabled without MAIL_FROM")
+	}
+	if NewDeliveryPolicy(&config.Config{MailFrom: "noreply@example.com"}).EmailEnabled {
+		t.Fatal("email should be disabled without a transport")
+	}
+}
+
+func TestParseJobLabel(t *testing.T) {
+	label, err := parseJobLabel("  contract_v3 merge ")
+	if err != nil || label != "contract_v3 merge" {
+		t.Fatalf("unexpected label: %q err=%v", label, err)
+	}
+	if label, err := parseJobLabel(""); err != nil || label != "" {
+		t.Fatalf("empty label should be allowed: %q err=%v", label, err)
+	}
+	if _, err := parseJobLabel(strings.Repeat("契", maxJobLabelLength)); err != nil {
+		t.Fatalf("label of max length should be allowed: %v", err)
+	}
+	if _, err := parseJobLabel(strings.Repeat("契", maxJobLabelLength+1)); err == nil {
+		t.Fatal("expected error for too long label")
+	}
+	if _, err := parseJobLabel("line1\nline2"); err == nil {
+		t.Fatal("expected error for label with newline")
+	}
+}
+
+func TestParseClientContext(t *testing.T) {
+	ctx, err := parseClientContext(` { "ticket": "OPS-42",  "order": 7 } `)
+	if err != nil {
+		t.Fatalf("parseClientContext returned error: %v", err)
+	}
+	if string(ctx) != `{"ticket":"OPS-42","order":7}` {
+		t.Fatalf("unexpected clientContext: %s", ctx)
+	}
+	if ctx, err := parseClientContext(""); err != nil || ctx != nil {
+		t.Fatalf("empty clientContext should be allowed: %s err=%v", ctx, err)
+	}
+	if _, err := parseClientContext(`{"ticket":`); err == nil {
+		t.Fatal("expected error for invalid JSON")
+	}
+	if _, err := parseClientContext(`"` + strings.Repeat("x", maxClientContextBytes) + `"`); err == nil {
+		t.Fatal("expected error for too large clientContext")
+	}
+}
+
+func TestMergeHandlerUploadTruncated(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	service := &stubMergeService{
+		manifest: &JobManifest{
+			JobID:     "job-truncated",
+			Operation: OperationMerge,
+			Files: []JobFile{
+				{StoredName: "00.pdf", OriginalName: "a.pdf", Size: 100, Pages: 1},
+				{StoredName: "01.pdf", OriginalName: "b.pdf", Size: 60, Pages: 1},
+			},
+		},
+	}
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	for _, name := range []string{"a.pdf", "b.pdf"} {
+		fileWriter, err := writer.CreateFormFile("files[]", name)
+		if err != nil {
+			t.Fatalf("failed to create form file: %v", err)
+		}
+		if _, err := fileWriter.Write([]byte("%PDF-1.4\n")); err != nil {
+			t.Fatalf("failed to write dummy file: %v", err)
+		}
+	}
+	if err := writer.WriteField("sizes", "[100,80]"); err != nil {
+		t.Fatalf("failed to write sizes: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("failed to close writer: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/pdf/merge", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	rec := httptest.NewRecorder()
+
+	router := gin.New()
+	router.POST("/api/pdf/merge", MergeHandler(service, HandlerOptions{AsyncThresholdBytes: 1 << 40}))
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("unexpected status: %d body=%s", rec.Code, rec.Body.String())
+	}
+	var payload map[string]string
+	if err := json.Unmarshal(rec.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if payload["code"] != "UPLOAD_TRUNCATED" {
+		t.Fatalf("unexpected code: %s", payload["code"])
+	}
+	if service.runCalled {
+		t.Fatal("RunJob should not be called for a truncated upload")
+	}
+	if len(service.discardIDs) != 1 || service.discardIDs[0] != "job-truncated" {
+		t.Fatalf("expected job to be discarded, got %v", service.discardIDs)
+	}
+}
+
+func TestMergeHandlerSoftLimitRequiresConfirmation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tokens := NewOverrideTokens("secret")
+	token, _ := tokens.Issue(time.Hour)
+	opts := HandlerOptions{
+		AsyncThresholdPages: 1,
+		Scheduler:           &stubScheduler{},
+		SoftLimits:          NewSoftLimits(0, 10, tokens),
+	}
+
+	send := func(confirm, overrideToken string) (*httptest.ResponseRecorder, *stubMergeService) {
+		service := &stubMergeService{
+			manifest: &JobManifest{
+				JobID:     "job-soft",
+				Operation: OperationMerge,
+				Files:     []JobFile{{StoredName: "00.pdf", OriginalName: "a.pdf", Size: 10, Pages: 30}},
+			},
+		}
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		fileWriter, err := writer.CreateFormFile("files[]", "a.pdf")
+		if err != nil {
+			t.Fatalf("failed to create form file: %v", err)
+		}
+		if _, err := fileWriter.Write([]byte("%PDF-1.4\n")); err != nil {
+			t.Fatalf("failed to write dummy file: %v", err)
+		}
+		if confirm != "" {
+			if err := writer.WriteField("confirm", confirm); err != nil {
+				t.Fatalf("failed to write confirm: %v", err)
+			}
+		}
+		if err := writer.Close(); err != nil {
+			t.Fatalf("failed to close writer: %v", err)
+		}
+
+		req := httptest.NewRequest(http.MethodPost, "/api/pdf/merge", body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		if overrideToken != "" {
+			req.Header.Set(OverrideTokenHeader, overrideToken)
+		}
+		rec := httptest.NewRecorder()
+		router := gin.New()
+		router.POST("/api/pdf/merge", MergeHandler(service, opts))
+		router.ServeHTTP(rec, req)
+		return rec, service
+	}
+
+	rec, service := send("", "")
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("unexpected status: %d body=%s", rec.Code, rec.Body.String())
+	}
+	var payload struct {
+		Code     string   `json:"code"`
+		Warnings []string `json:"warnings"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if payload.Code != "CONFIRMATION_REQUIRED" || len(payload.Warnings) != 1 {
+		t.Fatalf("unexpected payload: %+v", payload)
+	}
+	if len(service.discardIDs) != 1 {
+		t.Fatalf("expected job to be discarded, got %v", service.discardIDs)
+	}
+
+	if rec, _ := send("true", ""); rec.Code != http.StatusAccepted {
+		t.Fatalf("confirmed job should be accepted: %d body=%s", rec.Code, rec.Body.String())
+	}
+	if rec, _ := send("", token); rec.Code != http.StatusAccepted {
+		t.Fatalf("job with override token should be accepted: %d body=%s", rec.Code, rec.Body.String())
+	}
+	if rec, _ := send("", token); rec.Code != http.StatusForbidden {
+		t.Fatalf("reused override token should be rejected: %d body=%s", rec.Code, rec.Body.String())
+	}
+}
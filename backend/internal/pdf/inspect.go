@@ -2,15 +2,52 @@ package pdf
 
 import (
 	"context"
+	"fmt"
+	"math"
 	"mime/multipart"
+	"strings"
+	"time"
+
+	pdfapi "github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
 )
 
 // InspectResult はアップロードされたPDFの基本メタデータを表します。
 type InspectResult struct {
-	Source SourceFileMeta `json:"source"`
+	Source   SourceFileMeta `json:"source"`
+	Document DocumentInfo   `json:"document"`
+	Pages    []PageInfo     `json:"pages"`
+}
+
+// PageInfo はページ1つ分の大きさと向きです。
+// Width / Height は表示される領域（CropBox、無い場合は MediaBox）の回転前の大きさ（ポイント）で、
+// Rotation（時計回り、0 / 90 / 180 / 270）が 90 か 270 の場合は表示上の縦横が入れ替わります。
+type PageInfo struct {
+	Number   int     `json:"number"`
+	Width    float64 `json:"width"`
+	Height   float64 `json:"height"`
+	Rotation int     `json:"rotation"`
 }
 
-// InspectMultipart は単一PDFファイルを受け取り、ページ数などのメタデータを返します。
+// DocumentInfo は PDF の文書情報（/Info）と形式に関する情報です。文書情報の項目は設定されていない場合は省略します。
+type DocumentInfo struct {
+	Title    string `json:"title,omitempty"`
+	Author   string `json:"author,omitempty"`
+	Subject  string `json:"subject,omitempty"`
+	Keywords string `json:"keywords,omitempty"`
+	Creator  string `json:"creator,omitempty"`
+	Producer string `json:"producer,omitempty"`
+	// CreatedAt / ModifiedAt は日付として解釈できない場合も省略します。
+	CreatedAt  *time.Time `json:"createdAt,omitempty"`
+	ModifiedAt *time.Time `json:"modifiedAt,omitempty"`
+	Version    string     `json:"version"`
+	// Encrypted はパスワードで保護されたPDFかどうかです。passwords[] で復号したPDFも true です。
+	Encrypted  bool `json:"encrypted"`
+	Linearized bool `json:"linearized"`
+}
+
+// InspectMultipart は単一PDFファイルを受け取り、ページ数と各ページの大きさ、文書情報を返します。
 func (s *Service) InspectMultipart(ctx context.Context, file *multipart.FileHeader) (*InspectResult, error) {
 	if ctx == nil {
 		ctx = context.Background()
@@ -27,16 +64,97 @@ func (s *Service) InspectMultipart(ctx context.Context, file *multipart.FileHead
 		_ = removeDir(ws.dir)
 	}()
 
-	stored, err := s.storeMultipartFile(ctx, file, ws.inDir, 0)
+	stored, err := s.storeMultipartFile(ctx, file, ws.inDir, 0, false)
 	if err != nil {
 		return nil, err
 	}
 
+	pdfCtx, err := pdfapi.ReadContextFile(stored.path)
+	if err != nil {
+		return nil, newError("DAMAGED_PDF", fmt.Sprintf("%s の文書情報を読み込めませんでした。", stored.originalName), err)
+	}
+	pages, err := readPageInfo(pdfCtx)
+	if err != nil {
+		return nil, newError("DAMAGED_PDF", fmt.Sprintf("%s のページの大きさを読み込めませんでした。", stored.originalName), err)
+	}
+
 	return &InspectResult{
 		Source: SourceFileMeta{
 			Name:  stored.originalName,
 			Size:  stored.size,
 			Pages: stored.pages,
 		},
+		Document: readDocumentInfo(pdfCtx, stored),
+		Pages:    pages,
 	}, nil
 }
+
+// readDocumentInfo は読み込んだ保存済みのPDFから文書情報を取り出します。
+func readDocumentInfo(pdfCtx *model.Context, stored storedFile) DocumentInfo {
+	doc := DocumentInfo{
+		Title:      strings.TrimSpace(pdfCtx.Title),
+		Author:     strings.TrimSpace(pdfCtx.Author),
+		Subject:    strings.TrimSpace(pdfCtx.Subject),
+		Keywords:   strings.TrimSpace(pdfCtx.Keywords),
+		Creator:    strings.TrimSpace(pdfCtx.Creator),
+		Producer:   strings.TrimSpace(pdfCtx.Producer),
+		CreatedAt:  parsePDFDate(pdfCtx.XRefTable.CreationDate),
+		ModifiedAt: parsePDFDate(pdfCtx.ModDate),
+		Version:    pdfCtx.VersionString(),
+		// 所有者パスワードだけのPDFは復号せずに読めるため、暗号化辞書の有無でも判定する
+		Encrypted: stored.encrypted || pdfCtx.Encrypt != nil,
+	}
+	if pdfCtx.Read != nil {
+		doc.Linearized = pdfCtx.Read.Linearized
+	}
+	return doc
+}
+
+// readPageInfo は各ページの表示領域の大きさと回転を、継承された属性（親の /Pages の MediaBox など）を含めて読み取ります。
+func readPageInfo(pdfCtx *model.Context) ([]PageInfo, error) {
+	pages := make([]PageInfo, 0, pdfCtx.PageCount)
+	for i := 1; i <= pdfCtx.PageCount; i++ {
+		_, _, attrs, err := pdfCtx.PageDict(i, false)
+		if err != nil {
+			return nil, err
+		}
+		if attrs == nil {
+			return nil, fmt.Errorf("page %d has no attributes", i)
+		}
+		box := attrs.CropBox
+		if box == nil {
+			box = attrs.MediaBox
+		}
+		if box == nil {
+			return nil, fmt.Errorf("page %d has no media box", i)
+		}
+		pages = append(pages, newPageInfo(i, box, attrs.Rotate))
+	}
+	return pages, nil
+}
+
+// newPageInfo は box の大きさを小数第2位までに丸め、rotate を 0〜270 の90度単位に正規化します。
+func newPageInfo(number int, box *types.Rectangle, rotate int) PageInfo {
+	round := func(v float64) float64 { return math.Round(math.Abs(v)*100) / 100 }
+	rotation := ((rotate/90)%4 + 4) % 4 * 90
+	return PageInfo{
+		Number:   number,
+		Width:    round(box.Width()),
+		Height:   round(box.Height()),
+		Rotation: rotation,
+	}
+}
+
+// parsePDFDate は PDF の日付文字列（例: "D:20240131120000+09'00'"）を解釈します。解釈できない場合は nil です。
+func parsePDFDate(raw string) *time.Time {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil
+	}
+	t, ok := types.DateTime(raw, true)
+	if !ok {
+		return nil
+	}
+	t = t.UTC()
+	return &t
+}
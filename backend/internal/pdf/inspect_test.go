@@ -0,0 +1,89 @@
+package pdf
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+
+	"github.com/yourusername/paper-forge/internal/config"
+	"github.com/yourusername/paper-forge/internal/pdf/pdftest"
+)
+
+func TestInspectMultipartReturnsDocumentInfo(t *testing.T) {
+	svc := NewService(&config.Config{MaxFileSize: 1 << 20, MaxPages: 20})
+	svc.tmpRoot = t.TempDir()
+	headers, err := pdftest.FileHeaders("file",
+		pdftest.File{Name: "report.pdf", Data: pdftest.SyntheticWithInfo(2, map[string]string{
+			"Title":        "Quarterly Report",
+			"Author":       "Finance",
+			"Keywords":     "q3, budget",
+			"Producer":     "paper-forge test",
+			"CreationDate": "D:20240131120000Z",
+		})},
+		pdftest.File{Name: "locked.pdf", Data: encryptedPDF(t, 1, "secret")},
+	)
+	if err != nil {
+		t.Fatalf("failed to build multipart files: %v", err)
+	}
+
+	result, err := svc.InspectMultipart(context.Background(), headers[0])
+	if err != nil {
+		t.Fatalf("InspectMultipart returned error: %v", err)
+	}
+	doc := result.Document
+	if result.Source.Pages != 2 || doc.Title != "Quarterly Report" || doc.Author != "Finance" || doc.Keywords != "q3, budget" || doc.Producer != "paper-forge test" {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	if doc.Version != "1.4" || doc.Encrypted || doc.Linearized {
+		t.Fatalf("unexpected format info: %+v", doc)
+	}
+	if doc.CreatedAt == nil || !doc.CreatedAt.Equal(time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected createdAt: %v", doc.CreatedAt)
+	}
+	if doc.ModifiedAt != nil {
+		t.Fatalf("missing dates should be omitted: %v", doc.ModifiedAt)
+	}
+	wantPages := []PageInfo{{Number: 1, Width: 595, Height: 842}, {Number: 2, Width: 595, Height: 842}}
+	if !reflect.DeepEqual(result.Pages, wantPages) {
+		t.Fatalf("unexpected pages: %+v", result.Pages)
+	}
+
+	locked, err := svc.InspectMultipart(WithInputPasswords(context.Background(), []string{"secret"}), headers[1])
+	if err != nil {
+		t.Fatalf("InspectMultipart returned error for encrypted input: %v", err)
+	}
+	if !locked.Document.Encrypted {
+		t.Fatalf("decrypted input should be reported as encrypted: %+v", locked.Document)
+	}
+}
+
+func TestParsePDFDate(t *testing.T) {
+	if got := parsePDFDate("D:20240131210000+09'00'"); got == nil || !got.Equal(time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC)) || got.Location() != time.UTC {
+		t.Fatalf("unexpected date: %v", got)
+	}
+	// 読み取れない日付は検証では弾かれないこともあるため、省略して返す
+	for _, raw := range []string{"", "  ", "not a date", "D:20241341"} {
+		if got := parsePDFDate(raw); got != nil {
+			t.Fatalf("%q: unparseable dates should be omitted, got %v", raw, got)
+		}
+	}
+}
+
+func TestNewPageInfoNormalizesRotation(t *testing.T) {
+	box := types.NewRectangle(0, 0, 612.004, 792)
+	cases := []struct {
+		rotate int
+		want   int
+	}{
+		{0, 0}, {90, 90}, {-90, 270}, {450, 90}, {-180, 180}, {360, 0},
+	}
+	for _, tc := range cases {
+		got := newPageInfo(3, box, tc.rotate)
+		if got != (PageInfo{Number: 3, Width: 612, Height: 792, Rotation: tc.want}) {
+			t.Fatalf("rotate %d: unexpected page info %+v", tc.rotate, got)
+		}
+	}
+}
@@ -10,6 +10,7 @@ func toJobFiles(stored []storedFile) []JobFile {
 			OriginalName: sf.originalName,
 			Size:         sf.size,
 			Pages:        sf.pages,
+			SHA256:       sf.sha256,
 		}
 	}
 	return files
@@ -26,6 +27,7 @@ func storedFilesFromManifest(jobDir string, manifest *JobManifest) []storedFile
 			originalName: f.OriginalName,
 			size:         f.Size,
 			pages:        f.Pages,
+			sha256:       f.SHA256,
 		}
 	}
 	return stored
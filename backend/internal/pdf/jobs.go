@@ -3,15 +3,40 @@ package pdf
 import (
 	"context"
 	"fmt"
+	"strings"
+
+	"github.com/yourusername/paper-forge/internal/execrun"
 )
 
+// LoadJobOptions はジョブ投入時に指定された付帯オプションを返します。
+// 失敗時はワークスペースが削除されるため、通知などで後から必要な値は実行前に読み出してください。
+func (s *Service) LoadJobOptions(jobID string) (JobOptions, error) {
+	if jobID == "" {
+		return JobOptions{}, fmt.Errorf("jobID is required")
+	}
+	manifest, err := loadManifest(s.fs, s.workspaceFor(jobID).dir)
+	if err != nil {
+		return JobOptions{}, err
+	}
+	return manifest.Options, nil
+}
+
+// DiscardWorkspace はジョブの作業領域（入力・復号した入力・途中の成果物）を削除します。
+// RunJob は失敗時に自身で削除するため、途中でパニックした場合など RunJob が戻らなかったときに使います。
+func (s *Service) DiscardWorkspace(jobID string) error {
+	if strings.TrimSpace(jobID) == "" {
+		return fmt.Errorf("jobID is required")
+	}
+	return removeDir(s.workspaceFor(jobID).dir)
+}
+
 // RunJob はジョブIDに対応するPDF処理を実行します。
 func (s *Service) RunJob(ctx context.Context, jobID string, reporter ProgressReporter) (*Result, error) {
 	if jobID == "" {
 		return nil, fmt.Errorf("jobID is required")
 	}
 	ws := s.workspaceFor(jobID)
-	manifest, err := loadManifest(ws.dir)
+	manifest, err := loadManifest(s.fs, ws.dir)
 	if err != nil {
 		_ = removeDir(ws.dir)
 		return nil, err
@@ -20,6 +45,16 @@ func (s *Service) RunJob(ctx context.Context, jobID string, reporter ProgressRep
 		_ = removeDir(ws.dir)
 		return nil, fmt.Errorf("manifest missing operation")
 	}
+	op, ok := lookupOperation(manifest.Operation)
+	if !ok {
+		_ = removeDir(ws.dir)
+		return nil, fmt.Errorf("unsupported operation: %s", manifest.Operation)
+	}
+	// キュー投入後に設定で無効化された操作は実行しない
+	if !s.OperationEnabled(manifest.Operation) {
+		_ = removeDir(ws.dir)
+		return nil, newError("OPERATION_DISABLED", "この機能は現在の環境では利用できません。", nil)
+	}
 
 	stored := storedFilesFromManifest(ws.dir, manifest)
 	if len(stored) == 0 {
@@ -27,37 +62,78 @@ func (s *Service) RunJob(ctx context.Context, jobID string, reporter ProgressRep
 		return nil, fmt.Errorf("manifest has no input files")
 	}
 
-	var (
-		result *Result
-		runErr error
-	)
+	// 再配信されたタスクで前回の実行が完了していれば、成果物を作り直さずに返す
+	if completed, ok := s.loadCompletedRun(ws, manifest); ok {
+		reportProgress(reporter, StageCompleted, 1)
+		return completed, nil
+	}
+	staged, err := s.stageOutputs(ws)
+	if err != nil {
+		_ = removeDir(ws.dir)
+		return nil, fmt.Errorf("成果物の書き出し先の作成に失敗しました: %w", err)
+	}
 
-	switch manifest.Operation {
-	case OperationMerge:
-		state := &mergeState{ws: ws, storedFiles: stored}
-		result, runErr = s.executeMerge(ctx, state, manifest.Order, reporter)
-	case OperationReorder:
-		state := &reorderState{ws: ws, file: stored[0]}
-		result, runErr = s.executeReorder(ctx, state, manifest.Order, reporter)
-	case OperationSplit:
-		state := &splitState{
-			ws:        ws,
-			file:      stored[0],
-			rangesRaw: manifest.Ranges,
+	// 外部コマンドの CPU 時間はこのジョブの ctx で実行した分だけを集計する
+	ctx, cpu := execrun.WithCPUAccount(ctx)
+	timings := &JobTimings{StoreMs: manifest.StoreMs}
+	watch := startStopwatch()
+
+	if cached, ok := s.lookupCachedResult(staged, manifest); ok {
+		reportProgress(reporter, StageLoad, 1)
+		cached.fs = s.artifactFS()
+		if err := s.commitOutputs(ws, staged, cached); err != nil {
+			_ = removeDir(ws.dir)
+			return nil, fmt.Errorf("成果物の保存に失敗しました: %w", err)
 		}
-		result, runErr = s.executeSplit(ctx, state, reporter)
-	case OperationOptimize:
-		state := &optimizeState{
-			ws:     ws,
-			file:   stored[0],
-			preset: manifest.Preset,
+		if err := writeArtifacts(s.fs, ws.dir, cached.Artifacts); err != nil {
+			_ = removeDir(ws.dir)
+			return nil, fmt.Errorf("成果物一覧の保存に失敗しました: %w", err)
 		}
-		result, runErr = s.executeOptimize(ctx, state, reporter)
-	default:
-		_ = removeDir(ws.dir)
-		return nil, fmt.Errorf("unsupported operation: %s", manifest.Operation)
+		if err := s.writeResultManifest(ctx, ws, manifest, cached); err != nil {
+			_ = removeDir(ws.dir)
+			return nil, fmt.Errorf("成果物のマニフェストの作成に失敗しました: %w", err)
+		}
+		timings.WriteMs = watch.lap()
+		cached.Timings = timings
+		if err := s.markRunCompleted(ws, cached); err != nil {
+			_ = removeDir(ws.dir)
+			return nil, fmt.Errorf("実行の完了の記録に失敗しました: %w", err)
+		}
+		return cached, nil
 	}
 
+	// 大きな入力では復号に時間がかかるため、処理用に書き出したバイト数を load 段階の進捗として通知する
+	var totalInput int64
+	for _, sf := range stored {
+		totalInput += sf.size
+	}
+	releasePlain, err := s.unsealInputs(ws, manifest, stored, newByteProgress(reporter, StageLoad, totalInput))
+	if err != nil {
+		_ = removeDir(ws.dir)
+		return nil, err
+	}
+	if err := s.countDeferredPages(ctx, ws, manifest, stored); err != nil {
+		releasePlain()
+		_ = removeDir(ws.dir)
+		return nil, err
+	}
+	reportProgress(reporter, StageLoad, 1)
+	timings.LoadMs = watch.lap()
+	result, runErr := op.Execute(ctx, s, &jobInput{ws: staged, manifest: manifest, files: stored}, reporter)
+	// 復号した入力は処理が終わり次第削除する
+	releasePlain()
+	timings.ProcessMs = watch.lap()
+	if runErr == nil {
+		if err := s.finishArtifacts(ctx, manifest, result); err != nil {
+			runErr = err
+		} else if err := s.commitOutputs(ws, staged, result); err != nil {
+			runErr = fmt.Errorf("成果物の保存に失敗しました: %w", err)
+		} else if err := writeArtifacts(s.fs, ws.dir, result.Artifacts); err != nil {
+			runErr = fmt.Errorf("成果物一覧の保存に失敗しました: %w", err)
+		} else if err := s.writeResultManifest(ctx, ws, manifest, result); err != nil {
+			runErr = fmt.Errorf("成果物のマニフェストの作成に失敗しました: %w", err)
+		}
+	}
 	if runErr != nil {
 		if cleanupErr := removeDir(ws.dir); cleanupErr != nil {
 			runErr = fmt.Errorf("%w (ワークスペースの削除にも失敗しました: %v)", runErr, cleanupErr)
@@ -65,5 +141,17 @@ func (s *Service) RunJob(ctx context.Context, jobID string, reporter ProgressRep
 		return nil, runErr
 	}
 
+	timings.WriteMs = watch.lap()
+	timings.SubprocessCPUMs = cpu.Total().Milliseconds()
+	result.Timings = timings
+	result.fs = s.artifactFS()
+	for _, sf := range stored {
+		result.inputPages += sf.pages
+	}
+	if err := s.markRunCompleted(ws, result); err != nil {
+		_ = removeDir(ws.dir)
+		return nil, fmt.Errorf("実行の完了の記録に失敗しました: %w", err)
+	}
+	s.storeCachedResult(manifest, result)
 	return result, nil
 }
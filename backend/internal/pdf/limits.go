@@ -0,0 +1,155 @@
+package pdf
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OverrideTokenHeader は上書きトークンを送るリクエストヘッダーです。
+const OverrideTokenHeader = "X-Override-Token"
+
+// MaxOverrideTokenTTL は上書きトークンの有効期間の上限です。
+const MaxOverrideTokenTTL = 24 * time.Hour
+
+var (
+	// ErrOverrideTokenInvalid は上書きトークンの改ざん・形式不正を表します。
+	ErrOverrideTokenInvalid = errors.New("override token is invalid")
+	// ErrOverrideTokenExpired は上書きトークンの有効期限切れを表します。
+	ErrOverrideTokenExpired = errors.New("override token expired")
+	// ErrOverrideTokenUsed は使用済みの上書きトークンを表します。
+	ErrOverrideTokenUsed = errors.New("override token already used")
+)
+
+// SoftLimits は確認なしで受け付けるジョブの規模の上限（ソフトリミット）です。
+//
+// MAX_FILE_SIZE / MAX_PAGES / MAX_MERGE_PAGES などのハードリミットは常に拒否しますが、
+// ソフトリミットを超えたジョブは警告を返し、confirm=true を付けた再送信か、
+// 管理者が発行した上書きトークンがあれば受け付けます。
+type SoftLimits struct {
+	maxBytes  int64
+	maxPages  int
+	overrides *OverrideTokens
+}
+
+// NewSoftLimits は SoftLimits を作成します。maxBytes と maxPages がどちらも 0 以下の場合は nil を返します。
+func NewSoftLimits(maxBytes int64, maxPages int, overrides *OverrideTokens) *SoftLimits {
+	if maxBytes <= 0 && maxPages <= 0 {
+		return nil
+	}
+	return &SoftLimits{maxBytes: maxBytes, maxPages: maxPages, overrides: overrides}
+}
+
+// warnings はジョブがソフトリミットを超えている場合に、その内容を返します。nil の SoftLimits は常に空です。
+func (l *SoftLimits) warnings(manifest *JobManifest) []string {
+	if l == nil || manifest == nil {
+		return nil
+	}
+	var (
+		totalBytes int64
+		totalPages int
+	)
+	for _, f := range manifest.Files {
+		totalBytes += f.Size
+		totalPages += f.Pages
+	}
+
+	var warnings []string
+	if l.maxBytes > 0 && totalBytes > l.maxBytes {
+		warnings = append(warnings, fmt.Sprintf("入力ファイルの合計サイズ(%.1fMB)が推奨上限(%.1fMB)を超えています。", float64(totalBytes)/(1024*1024), float64(l.maxBytes)/(1024*1024)))
+	}
+	if l.maxPages > 0 && totalPages > l.maxPages {
+		warnings = append(warnings, fmt.Sprintf("入力ファイルの合計ページ数(%dページ)が推奨上限(%dページ)を超えています。", totalPages, l.maxPages))
+	}
+	return warnings
+}
+
+// redeem は上書きトークンを検証して使用済みにします。
+func (l *SoftLimits) redeem(token string) error {
+	if l.overrides == nil {
+		return ErrOverrideTokenInvalid
+	}
+	return l.overrides.Redeem(token)
+}
+
+// OverrideTokens は管理者がソフトリミットを一度だけ超えるために発行する上書きトークンを発行・検証します。
+//
+// トークンは "ID.有効期限(Unix秒).署名" の形式で、署名で改ざんを検出するためサーバー側に発行履歴は持ちません。
+// 使用済みのIDは有効期限までメモリ上に保持するため、一度限りの保証は API インスタンス単位です。
+type OverrideTokens struct {
+	secret []byte
+	now    func() time.Time
+
+	mu   sync.Mutex
+	used map[string]time.Time
+}
+
+// NewOverrideTokens は OverrideTokens を作成します。secret が空の場合は nil を返します。
+func NewOverrideTokens(secret string) *OverrideTokens {
+	if strings.TrimSpace(secret) == "" {
+		return nil
+	}
+	return &OverrideTokens{
+		secret: []byte(secret),
+		now:    time.Now,
+		used:   make(map[string]time.Time),
+	}
+}
+
+// Issue は ttl の間だけ有効な上書きトークンを発行します。ttl は MaxOverrideTokenTTL までに切り詰めます。
+func (o *OverrideTokens) Issue(ttl time.Duration) (string, time.Time) {
+	if ttl <= 0 || ttl > MaxOverrideTokenTTL {
+		ttl = MaxOverrideTokenTTL
+	}
+	id := strings.ReplaceAll(uuid.NewString(), "-", "")
+	expiresAt := o.now().Add(ttl).Truncate(time.Second)
+	expires := expiresAt.Unix()
+	return id + "." + strconv.FormatInt(expires, 10) + "." + o.sign(id, expires), expiresAt.UTC()
+}
+
+// Redeem はトークンの署名と有効期限を検証し、使用済みにします。
+func (o *OverrideTokens) Redeem(token string) error {
+	parts := strings.Split(strings.TrimSpace(token), ".")
+	if len(parts) != 3 || parts[0] == "" {
+		return ErrOverrideTokenInvalid
+	}
+	expires, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return ErrOverrideTokenInvalid
+	}
+	if !hmac.Equal([]byte(o.sign(parts[0], expires)), []byte(parts[2])) {
+		return ErrOverrideTokenInvalid
+	}
+	now := o.now()
+	if now.Unix() > expires {
+		return ErrOverrideTokenExpired
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for id, exp := range o.used {
+		if now.After(exp) {
+			delete(o.used, id)
+		}
+	}
+	if _, ok := o.used[parts[0]]; ok {
+		return ErrOverrideTokenUsed
+	}
+	o.used[parts[0]] = time.Unix(expires, 0)
+	return nil
+}
+
+func (o *OverrideTokens) sign(id string, expires int64) string {
+	mac := hmac.New(sha256.New, o.secret)
+	// セッション用の鍵を流用するため、用途を示す接頭辞を含めて署名対象を分離する
+	mac.Write([]byte("override:" + id + ":" + strconv.FormatInt(expires, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
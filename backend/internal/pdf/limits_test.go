@@ -0,0 +1,53 @@
+package pdf
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOverrideTokensRedeemOnce(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	tokens := NewOverrideTokens("secret")
+	tokens.now = func() time.Time { return now }
+
+	token, expiresAt := tokens.Issue(time.Hour)
+	if !expiresAt.Equal(now.Add(time.Hour)) {
+		t.Fatalf("unexpected expiresAt: %v", expiresAt)
+	}
+	if err := tokens.Redeem(token); err != nil {
+		t.Fatalf("Redeem returned error: %v", err)
+	}
+	if err := tokens.Redeem(token); !errors.Is(err, ErrOverrideTokenUsed) {
+		t.Fatalf("expected ErrOverrideTokenUsed, got %v", err)
+	}
+
+	other, _ := tokens.Issue(time.Minute)
+	tampered := strings.Replace(other, ".", "x.", 1)
+	if err := tokens.Redeem(tampered); !errors.Is(err, ErrOverrideTokenInvalid) {
+		t.Fatalf("expected ErrOverrideTokenInvalid, got %v", err)
+	}
+	if err := NewOverrideTokens("another").Redeem(other); !errors.Is(err, ErrOverrideTokenInvalid) {
+		t.Fatalf("expected token signed with another secret to be rejected, got %v", err)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if err := tokens.Redeem(other); !errors.Is(err, ErrOverrideTokenExpired) {
+		t.Fatalf("expected ErrOverrideTokenExpired, got %v", err)
+	}
+}
+
+func TestSoftLimitsWarnings(t *testing.T) {
+	manifest := &JobManifest{Files: []JobFile{{Size: 3 << 20, Pages: 60}, {Size: 3 << 20, Pages: 50}}}
+
+	if w := NewSoftLimits(0, 0, nil).warnings(manifest); len(w) != 0 {
+		t.Fatalf("disabled soft limits should not warn: %v", w)
+	}
+	if w := NewSoftLimits(10<<20, 200, nil).warnings(manifest); len(w) != 0 {
+		t.Fatalf("unexpected warnings: %v", w)
+	}
+	if w := NewSoftLimits(5<<20, 100, nil).warnings(manifest); len(w) != 2 {
+		t.Fatalf("expected size and page warnings, got %v", w)
+	}
+}
@@ -0,0 +1,18 @@
+//go:build !unix
+
+package pdf
+
+import "io/fs"
+
+// hardLinksSupported はリンク数を取得できない環境では false です（共有入力ストアは使いません）。
+const hardLinksSupported = false
+
+// linkCount はリンク数を取得できない環境では常に ok=false を返します。
+func linkCount(fs.FileInfo) (uint64, bool) {
+	return 0, false
+}
+
+// fileKey はファイルの実体を識別できない環境では常に ok=false を返します。
+func fileKey(fs.FileInfo) ([2]uint64, bool) {
+	return [2]uint64{}, false
+}
@@ -0,0 +1,30 @@
+//go:build unix
+
+package pdf
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// hardLinksSupported は共有入力ストア（SHARED_INPUT_TENANTS）が使えるかを表します。
+// 参照数をハードリンクの数で数えるため、リンク数を取得できる環境でのみ有効です。
+const hardLinksSupported = true
+
+// linkCount はファイルのハードリンクの数を返します。
+func linkCount(info fs.FileInfo) (uint64, bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+	return uint64(st.Nlink), true
+}
+
+// fileKey はファイルの実体（デバイスと inode）を識別する値を返します。ハードリンクは同じ値になります。
+func fileKey(info fs.FileInfo) ([2]uint64, bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return [2]uint64{}, false
+	}
+	return [2]uint64{uint64(st.Dev), uint64(st.Ino)}, true
+}
@@ -3,22 +3,56 @@ package pdf
 import (
 	"encoding/json"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/yourusername/paper-forge/internal/tenant"
 )
 
 const manifestFilename = "manifest.json"
 
+// manifestVersion は現在のコードが書き出すマニフェストの形式のバージョンです。
+// API とワーカーを順に入れ替える間は、旧バージョンが書いたマニフェストを新バージョンが実行し、その逆も起こります。
+// フィールドの意味を変える・削除する場合は値を上げ、testdata/manifests/v<N>/ に書き出し例を追加してください。
+const manifestVersion = 1
+
 // JobManifest はジョブに必要な情報を保持します。
 type JobManifest struct {
-	JobID     string         `json:"jobId"`
+	// Version は書き出したコードのマニフェスト形式のバージョンです。0 はバージョンを記録する前の形式です。
+	Version int    `json:"version,omitempty"`
+	JobID   string `json:"jobId"`
+	// Tenant はジョブを受け付けたテナントです。空の場合はテナント導入前のジョブで、tenant.Default として扱います。
+	Tenant    string         `json:"tenant,omitempty"`
 	Operation OperationType  `json:"operation"`
 	Files     []JobFile      `json:"files"`
 	Order     []int          `json:"order,omitempty"`
+	Mode      ReorderMode    `json:"mode,omitempty"` // order の扱い。空の場合は全ページの並べ替え
+	TOC       bool           `json:"toc,omitempty"`  // 結合結果の先頭に目次のページを付けるか（merge のみ）
 	Ranges    string         `json:"ranges,omitempty"`
 	Preset    OptimizePreset `json:"preset,omitempty"`
-	CreatedAt time.Time      `json:"createdAt"`
+	// ColorProfile は圧縮で色を変換して埋め込む ICC プロファイルです（optimize のみ。既定値は受付時に解決済み）。
+	ColorProfile ColorProfile   `json:"colorProfile,omitempty"`
+	Output       SplitOutput    `json:"output,omitempty"` // 分割結果の受け取り方。空の場合は ZIP にまとめる
+	Rotations    []PageRotation `json:"rotations,omitempty"`
+	Watermark    *WatermarkSpec `json:"watermark,omitempty"`
+	Stamp        *StampSpec     `json:"stamp,omitempty"`
+	Metadata     *MetadataSpec  `json:"metadata,omitempty"`
+	Recipe       []ComposeStep  `json:"recipe,omitempty"` // ページを組み立てる手順（compose のみ）
+	NUp          *NUpSpec       `json:"nup,omitempty"`
+	Resize       *ResizeSpec    `json:"resize,omitempty"`
+	Options      JobOptions     `json:"options"`
+	// PagesDeferred は入力のページ数をまだ確認していないことを表します（DEFER_PAGE_COUNT）。
+	// 実行時に確認して Files[].Pages を埋め、false に戻します。
+	PagesDeferred bool `json:"pagesDeferred,omitempty"`
+	// StoreMs は受付時に入力を保存するのにかかった時間（ミリ秒。ページ数の確認・パスワードの復号を含む）です。
+	StoreMs int64 `json:"storeMs,omitempty"`
+	// KeyID は入力ファイルの暗号化に使った鍵のIDです。暗号化していない場合は空です。
+	KeyID     string    `json:"keyId,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
 }
 
 // JobFile はジョブ入力ファイルのメタデータを表します。
@@ -27,14 +61,16 @@ type JobFile struct {
 	OriginalName string `json:"originalName"`
 	Size         int64  `json:"size"`
 	Pages        int    `json:"pages"`
+	SHA256       string `json:"sha256,omitempty"`
 }
 
-func writeManifest(jobDir string, manifest *JobManifest) error {
+func writeManifest(fsys FS, jobDir string, manifest *JobManifest) error {
 	if manifest == nil {
 		return fmt.Errorf("manifest is nil")
 	}
+	manifest.Version = manifestVersion
 	path := filepath.Join(jobDir, manifestFilename)
-	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o640)
+	file, err := fsys.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o640)
 	if err != nil {
 		return fmt.Errorf("failed to open manifest: %w", err)
 	}
@@ -44,9 +80,9 @@ func writeManifest(jobDir string, manifest *JobManifest) error {
 	return enc.Encode(manifest)
 }
 
-func loadManifest(jobDir string) (*JobManifest, error) {
+func loadManifest(fsys FS, jobDir string) (*JobManifest, error) {
 	path := filepath.Join(jobDir, manifestFilename)
-	data, err := os.ReadFile(path)
+	data, err := readFileFS(fsys, path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read manifest: %w", err)
 	}
@@ -56,3 +92,18 @@ func loadManifest(jobDir string) (*JobManifest, error) {
 	}
 	return &manifest, nil
 }
+
+// JobTenant はジョブ jobID を受け付けたテナントを、ワークスペースのマニフェストから返します。
+// ジョブ情報（Redis）が期限切れ・同期処理で無い場合に、所有者を確かめるために使います。
+// ワークスペースが無い、または jobID がジョブIDの形式でない場合は fs.ErrNotExist を返します。
+func (s *Service) JobTenant(jobID string) (string, error) {
+	// ジョブIDはワークスペースのディレクトリ名になるため、UUID 以外はパスとして扱わない
+	if _, err := uuid.Parse(jobID); err != nil {
+		return "", fmt.Errorf("job %q: %w", jobID, fs.ErrNotExist)
+	}
+	manifest, err := loadManifest(s.fs, s.workspaceFor(jobID).dir)
+	if err != nil {
+		return "", err
+	}
+	return tenant.Normalize(manifest.Tenant), nil
+}
@@ -0,0 +1,225 @@
+package pdf
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/yourusername/paper-forge/internal/config"
+	"github.com/yourusername/paper-forge/internal/pdf/pdftest"
+)
+
+// manifestGoldenDir は各バージョンのコードが書き出したマニフェストの例（ゴールデンファイル）の置き場所です。
+// v<N>/ の各ファイルは、バージョン N のまま処理中のジョブが新しいワーカーで実行されうることを表します。
+const manifestGoldenDir = "testdata/manifests"
+
+type manifestGolden struct {
+	version int
+	path    string
+	data    []byte
+}
+
+func loadManifestGoldens(t *testing.T) []manifestGolden {
+	t.Helper()
+	dirs, err := filepath.Glob(filepath.Join(manifestGoldenDir, "v*"))
+	if err != nil {
+		t.Fatalf("failed to list golden manifests: %v", err)
+	}
+	var goldens []manifestGolden
+	for _, dir := range dirs {
+		version, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(dir), "v"))
+		if err != nil {
+			t.Fatalf("unexpected golden directory: %s", dir)
+		}
+		paths, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+		for _, path := range paths {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("failed to read %s: %v", path, err)
+			}
+			goldens = append(goldens, manifestGolden{version: version, path: path, data: data})
+		}
+	}
+	return goldens
+}
+
+// TestManifestGoldensReadByCurrentVersion は過去のバージョンが書き出したマニフェストを、
+// 現在のコードがフィールドを取りこぼさずに読み込み、書き戻せることを確認します。
+func TestManifestGoldensReadByCurrentVersion(t *testing.T) {
+	goldens := loadManifestGoldens(t)
+	seen := map[int]bool{}
+	for _, g := range goldens {
+		seen[g.version] = true
+		t.Run(g.path, func(t *testing.T) {
+			// 現在の JobManifest に無いフィールドがあれば、旧バージョンの情報を読み落としている
+			dec := json.NewDecoder(bytes.NewReader(g.data))
+			dec.DisallowUnknownFields()
+			var manifest JobManifest
+			if err := dec.Decode(&manifest); err != nil {
+				t.Fatalf("current code cannot read the manifest: %v", err)
+			}
+			if manifest.Version != g.version {
+				t.Fatalf("manifest version %d does not match its directory v%d", manifest.Version, g.version)
+			}
+			if _, ok := lookupOperation(manifest.Operation); !ok {
+				t.Fatalf("operation %q is no longer registered", manifest.Operation)
+			}
+			if len(storedFilesFromManifest("job", &manifest)) == 0 {
+				t.Fatal("manifest has no input files")
+			}
+
+			// 実行中の書き戻し（DEFER_PAGE_COUNT など）でバージョン以外の値が変わらないこと
+			fsys := newMemFS()
+			_ = fsys.MkdirAll("job", 0o750)
+			if err := writeManifest(fsys, "job", &manifest); err != nil {
+				t.Fatalf("writeManifest returned error: %v", err)
+			}
+			rewritten, err := readFileFS(fsys, filepath.Join("job", manifestFilename))
+			if err != nil {
+				t.Fatalf("failed to read rewritten manifest: %v", err)
+			}
+			want, got := decodeManifestMap(t, g.data), decodeManifestMap(t, rewritten)
+			delete(want, "version")
+			if got["version"] != float64(manifestVersion) {
+				t.Fatalf("rewritten manifest should be stamped with version %d: %v", manifestVersion, got["version"])
+			}
+			delete(got, "version")
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("rewriting changed the manifest:\n got: %v\nwant: %v", got, want)
+			}
+		})
+	}
+	if !seen[0] || !seen[manifestVersion] {
+		t.Fatalf("golden manifests are required for v0 and the current version v%d", manifestVersion)
+	}
+}
+
+// TestManifestCurrentVersionHasGoldens は現在のコードが書き出すフィールドが、すべて現在のバージョンの
+// ゴールデンファイルに含まれていることを確認します。新しいフィールドを追加した場合はゴールデンファイルも更新してください。
+func TestManifestCurrentVersionHasGoldens(t *testing.T) {
+	golden := map[string]bool{}
+	for _, g := range loadManifestGoldens(t) {
+		if g.version == manifestVersion {
+			collectManifestKeys("", decodeManifestMap(t, g.data), golden)
+		}
+	}
+
+	svc := NewService(&config.Config{MaxFileSize: 1 << 20, MaxPages: 20, DeferPageCount: true})
+	svc.tmpRoot = t.TempDir()
+	headers, err := pdftest.FileHeaders("files[]",
+		pdftest.File{Name: "a.pdf", Data: pdftest.Synthetic(2, 0)},
+		pdftest.File{Name: "b.pdf", Data: pdftest.Synthetic(3, 0)},
+	)
+	if err != nil {
+		t.Fatalf("failed to build multipart files: %v", err)
+	}
+	opts := JobOptions{
+		Delivery:      &DeliveryTarget{Method: DeliveryMethodStorage, ObjectPath: "exports/"},
+		Label:         "contract",
+		ClientContext: json.RawMessage(`{"matter":"2025-0142"}`),
+		KeepInputs:    true,
+	}
+	ctx := context.Background()
+	prepared := map[string]func() (*JobManifest, error){
+		"merge": func() (*JobManifest, error) { return svc.PrepareMergeJob(ctx, headers, []int{1, 0}, false, opts) },
+		"reorder": func() (*JobManifest, error) {
+			return svc.PrepareReorderJob(ctx, headers[1], []int{0, 0, 2}, ReorderModeFree, opts)
+		},
+		"split": func() (*JobManifest, error) {
+			return svc.PrepareSplitJob(ctx, headers[1], "1,2-", SplitOutputParts, opts)
+		},
+		"extract": func() (*JobManifest, error) { return svc.PrepareExtractJob(ctx, headers[1], "2-3", opts) },
+		"rotate": func() (*JobManifest, error) {
+			return svc.PrepareRotateJob(ctx, headers[1], map[string]int{"1": 90, "2-3": 180}, opts)
+		},
+		"watermark": func() (*JobManifest, error) {
+			spec := WatermarkSpec{Text: "CONFIDENTIAL", Opacity: 0.3, Rotation: 45, FontSize: 48, Position: "c"}
+			return svc.PrepareWatermarkJob(ctx, headers[1], spec, "1-2", opts)
+		},
+		"stamp": func() (*JobManifest, error) {
+			image, err := pdftest.FileHeaders("image", pdftest.File{Name: "logo.png", Data: testPNG})
+			if err != nil {
+				return nil, err
+			}
+			spec := StampSpec{Opacity: 1, Rotation: 0, Scale: 0.25, Position: "tr"}
+			return svc.PrepareStampJob(ctx, headers[1], image[0], spec, "1", opts)
+		},
+		"metadata": func() (*JobManifest, error) {
+			spec := MetadataSpec{Set: map[MetadataField]string{MetadataTitle: "Contract"}, Clear: []MetadataField{MetadataAuthor}}
+			return svc.PrepareMetadataJob(ctx, headers[1], spec, opts)
+		},
+		"compose": func() (*JobManifest, error) {
+			return svc.PrepareComposeJob(ctx, headers, []ComposeStep{{File: 1, Pages: "3-1"}, {File: 0}}, opts)
+		},
+		"nup": func() (*JobManifest, error) {
+			return svc.PrepareNUpJob(ctx, headers[1], NUpSpec{PerSheet: 4, Paper: "a3", Margin: 8}, opts)
+		},
+		"resize": func() (*JobManifest, error) {
+			return svc.PrepareResizeJob(ctx, headers[1], ResizeSpec{Paper: "letter", Mode: ResizeModeFill, Orientation: "auto"}, opts)
+		},
+		"optimize": func() (*JobManifest, error) {
+			return svc.PrepareOptimizeJob(ctx, headers[1], OptimizePresetAggressive, "2-3", "", opts)
+		},
+	}
+	for name, prepare := range prepared {
+		manifest, err := prepare()
+		if err != nil {
+			t.Fatalf("%s: prepare returned error: %v", name, err)
+		}
+		saved, err := readFileFS(svc.fs, filepath.Join(svc.workspaceFor(manifest.JobID).dir, manifestFilename))
+		if err != nil {
+			t.Fatalf("%s: failed to read manifest: %v", name, err)
+		}
+		written := map[string]bool{}
+		collectManifestKeys("", decodeManifestMap(t, saved), written)
+		var missing []string
+		for key := range written {
+			if !golden[key] {
+				missing = append(missing, key)
+			}
+		}
+		sort.Strings(missing)
+		if len(missing) > 0 {
+			t.Fatalf("%s writes fields missing from %s/v%d: %v", name, manifestGoldenDir, manifestVersion, missing)
+		}
+	}
+}
+
+func decodeManifestMap(t *testing.T, data []byte) map[string]any {
+	t.Helper()
+	var m map[string]any
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatalf("failed to decode manifest: %v", err)
+	}
+	return m
+}
+
+// collectManifestKeys は JSON のキーを "files[].storedName" のようなパスで集めます。
+// clientContext は利用者が自由に指定する値のため、中身は含めません。
+func collectManifestKeys(prefix string, v any, keys map[string]bool) {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, child := range v {
+			path := k
+			if prefix != "" {
+				path = prefix + "." + k
+			}
+			keys[path] = true
+			if k != "clientContext" {
+				collectManifestKeys(path, child, keys)
+			}
+		}
+	case []any:
+		for _, child := range v {
+			collectManifestKeys(fmt.Sprintf("%s[]", prefix), child, keys)
+		}
+	}
+}
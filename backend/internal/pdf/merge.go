@@ -3,6 +3,8 @@ package pdf
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -12,13 +14,18 @@ import (
 	"path/filepath"
 	"reflect"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/gabriel-vasile/mimetype"
 	"github.com/google/uuid"
 	pdfapi "github.com/pdfcpu/pdfcpu/pkg/api"
 
 	"github.com/yourusername/paper-forge/internal/config"
+	"github.com/yourusername/paper-forge/internal/execrun"
+	"github.com/yourusername/paper-forge/internal/metrics"
+	"github.com/yourusername/paper-forge/internal/signing"
+	"github.com/yourusername/paper-forge/internal/tenant"
 )
 
 const (
@@ -28,6 +35,9 @@ const (
 	maxUploadFiles    = 20
 	outputFilename    = "merged.pdf"
 	defaultCleanupMin = 10
+
+	// maxParallelStores は結合の入力を同時に保存・ページ数確認するファイル数の上限です。
+	maxParallelStores = 4
 )
 
 // Service はPDF結合などの操作をまとめたサービスです。
@@ -35,16 +45,73 @@ type Service struct {
 	cfg     *config.Config
 	tmpRoot string
 	now     func() time.Time
+	gs      *ghostscript
+	fs      FS
+	runner  execrun.Runner
+	// keys はワークスペース暗号化の鍵です。nil の場合は暗号化しません。
+	keys KeyProvider
+	// usage は作業領域全体の使用量の上限を管理します。
+	usage *workspaceUsage
+	// sharedInputs は設定（SHARED_INPUT_TENANTS）のテナントの入力を内容のハッシュで共有します。nil の場合は共有しません。
+	sharedInputs *sharedInputs
+	// disabledOps は設定（DISABLED_OPERATIONS）で無効化された操作です。
+	disabledOps map[OperationType]bool
+	// watermark は成果物に自動で付ける透かしの設定（WATERMARK_*）です。nil の場合は付けません。
+	watermark *watermarkPolicy
+	// audit は成果物に埋め込む処理記録の設定（AUDIT_RECORD*）です。nil の場合は埋め込みません。
+	audit *auditPolicy
+	// signer は成果物のマニフェストに署名します（SIGNING_PROVIDER）。nil の場合はマニフェストを作成しません。
+	signer signing.Signer
+	// colorProfiles は圧縮で選べる ICC プロファイル（COLOR_PROFILE_*）です。
+	colorProfiles map[ColorProfile]colorProfileSpec
+
+	// ocrLangs は DiscoverOCRLanguages で調べたインストール済みの OCR 言語パックです。
+	// LAZY_INIT が有効な場合は、最初に参照したときに ocrOnce で調べます。
+	ocrMu    sync.RWMutex
+	ocrLangs []string
+	ocrOnce  sync.Once
+
+	uploadsMu sync.Mutex
+	uploads   map[string]*uploadSession
 }
 
 // NewService は Service を作成します。
 func NewService(cfg *config.Config) *Service {
-	root := filepath.Join(os.TempDir(), "app")
-	return &Service{
+	return NewServiceWithFS(cfg, OSFS{})
+}
+
+// NewServiceWithFS はワークスペースの読み書きに fsys を使う Service を作成します。
+func NewServiceWithFS(cfg *config.Config, fsys FS) *Service {
+	root := cfg.WorkDir
+	if root == "" {
+		root = filepath.Join(os.TempDir(), "app")
+	}
+	// 鍵の設定は config.Validate で検証済みのため、ここでの失敗は暗号化なしとして扱う
+	keys, _ := keyProviderFromConfig(cfg)
+	s := &Service{
 		cfg:     cfg,
 		tmpRoot: root,
 		now:     time.Now,
-	}
+		gs:      newGhostscript(cfg.GhostscriptPath),
+		fs:      fsOrDefault(fsys),
+		runner: &execrun.ExecRunner{
+			Timeout:   time.Duration(cfg.ExternalToolTimeoutSec) * time.Second,
+			MaxOutput: cfg.ExternalToolOutputKB * 1024,
+			// フォントやリソース・言語パックの探索パスは Ghostscript / tesseract の動作に必要なため引き継ぐ
+			AllowEnv: []string{"GS_LIB", "GS_FONTPATH", "TESSDATA_PREFIX"},
+		},
+		keys:          keys,
+		usage:         newWorkspaceUsage(root, cfg.WorkDirMaxMB*1024*1024),
+		sharedInputs:  sharedInputsUnlessEncrypted(keys, root, cfg.SharedInputTenants),
+		disabledOps:   newDisabledOperations(cfg.DisabledOperations),
+		watermark:     newWatermarkPolicy(cfg.WatermarkText, cfg.WatermarkStyle, cfg.WatermarkOperations),
+		audit:         newAuditPolicy(cfg.AuditRecord, cfg.AuditRecordOperations),
+		signer:        newManifestSigner(cfg),
+		colorProfiles: newColorProfiles(cfg),
+		uploads:       make(map[string]*uploadSession),
+	}
+	s.injectFaults(faultsFromConfig(cfg.FaultInjection))
+	return s
 }
 
 func (s *Service) createWorkspace() (workspace, error) {
@@ -53,17 +120,18 @@ func (s *Service) createWorkspace() (workspace, error) {
 	inDir := filepath.Join(jobDir, "in")
 	outDir := filepath.Join(jobDir, "out")
 
-	if err := os.MkdirAll(inDir, 0o750); err != nil {
+	if err := s.fs.MkdirAll(inDir, 0o750); err != nil {
 		return workspace{}, fmt.Errorf("入力ディレクトリの作成に失敗しました: %w", err)
 	}
-	if err := os.MkdirAll(outDir, 0o750); err != nil {
+	if err := s.fs.MkdirAll(outDir, 0o750); err != nil {
 		return workspace{}, fmt.Errorf("出力ディレクトリの作成に失敗しました: %w", err)
 	}
 	return workspace{
-		jobID:  jobID,
-		dir:    jobDir,
-		inDir:  inDir,
-		outDir: outDir,
+		jobID:     jobID,
+		dir:       jobDir,
+		inDir:     inDir,
+		outDir:    outDir,
+		createdAt: time.Now(),
 	}, nil
 }
 
@@ -84,45 +152,14 @@ type SourceFileMeta struct {
 	Pages int    `json:"pages"`
 }
 
-// Error はAPIレスポンス用のエラー情報を保持します。
-type Error struct {
-	Code    string
-	Message string
-	Err     error
-}
-
-// Error 実装。
-func (e *Error) Error() string {
-	if e == nil {
-		return ""
-	}
-	if e.Err != nil {
-		return fmt.Sprintf("%s: %v", e.Message, e.Err)
-	}
-	return e.Message
-}
-
-// Unwrap は元のエラーを返します。
-func (e *Error) Unwrap() error {
-	if e == nil {
-		return nil
-	}
-	return e.Err
-}
-
-func newError(code, message string, err error) error {
-	return &Error{
-		Code:    code,
-		Message: message,
-		Err:     err,
-	}
-}
-
 type storedFile struct {
 	path         string
 	originalName string
 	size         int64
 	pages        int
+	sha256       string
+	// encrypted はパスワードで保護されたPDFを受付時に復号したことを表します（path の内容は復号済み）。
+	encrypted bool
 }
 
 func validateMergeInputs(files []*multipart.FileHeader, order []int) error {
@@ -166,7 +203,7 @@ func (s *Service) MergeMultipart(ctx context.Context, files []*multipart.FileHea
 		return nil, err
 	}
 
-	state, _, err := s.prepareMerge(ctx, files, order)
+	state, _, err := s.prepareMerge(ctx, files, order, false, JobOptions{})
 	if err != nil {
 		return nil, err
 	}
@@ -176,7 +213,7 @@ func (s *Service) MergeMultipart(ctx context.Context, files []*multipart.FileHea
 		}
 	}()
 
-	result, execErr := s.executeMerge(ctx, state, order, nil)
+	result, execErr := s.executeMerge(ctx, state, order, false, nil)
 	if execErr != nil {
 		return nil, execErr
 	}
@@ -188,44 +225,48 @@ type mergeState struct {
 	storedFiles []storedFile
 }
 
-func (s *Service) prepareMerge(ctx context.Context, files []*multipart.FileHeader, order []int) (*mergeState, *JobManifest, error) {
-	ws, err := s.createWorkspace()
+func (s *Service) prepareMerge(ctx context.Context, files []*multipart.FileHeader, order []int, toc bool, opts JobOptions) (*mergeState, *JobManifest, error) {
+	release, err := s.reserveWorkspace(files...)
 	if err != nil {
 		return nil, nil, err
 	}
+	defer release()
 
-	var (
-		storedFiles []storedFile
-		totalUpload int64
-	)
+	ws, err := s.createWorkspace()
+	if err != nil {
+		return nil, nil, err
+	}
 
-	for i, fh := range files {
-		if err := ctx.Err(); err != nil {
-			return nil, nil, err
-		}
-		sf, storeErr := s.storeMultipartFile(ctx, fh, ws.inDir, i)
-		if storeErr != nil {
-			_ = removeDir(ws.dir)
-			return nil, nil, storeErr
-		}
+	storedFiles, err := s.storeMultipartFiles(ctx, files, ws.inDir)
+	if err != nil {
+		_ = removeDir(ws.dir)
+		return nil, nil, err
+	}
 
-		totalUpload += sf.size
-		if totalUpload > MaxUploadTotalBytes {
+	// 合計サイズの上限は storeMultipartFiles が保存しながら確認している
+	var totalPages int
+	for _, sf := range storedFiles {
+		// 1ファイルごとの MaxPages とは別に、結合結果のページ数が膨らみすぎないよう合計でも制限する
+		totalPages += sf.pages
+		if s.cfg.MaxMergePages > 0 && totalPages > s.cfg.MaxMergePages {
 			_ = removeDir(ws.dir)
-			return nil, nil, newError("LIMIT_EXCEEDED", "アップロードされたファイル全体のサイズが上限(300MB)を超えています。", nil)
+			return nil, nil, newError("LIMIT_EXCEEDED", fmt.Sprintf("結合するファイルの合計ページ数が上限(%dページ)を超えています。ファイルを分けて結合してください。", s.cfg.MaxMergePages), nil)
 		}
-
-		storedFiles = append(storedFiles, sf)
 	}
 
 	manifest := &JobManifest{
-		JobID:     ws.jobID,
-		Operation: OperationMerge,
-		Files:     toJobFiles(storedFiles),
-		Order:     append([]int(nil), order...),
-		CreatedAt: s.now().UTC(),
-	}
-	if err := writeManifest(ws.dir, manifest); err != nil {
+		JobID:         ws.jobID,
+		Tenant:        tenant.FromContext(ctx),
+		Operation:     OperationMerge,
+		Files:         toJobFiles(storedFiles),
+		Order:         append([]int(nil), order...),
+		TOC:           toc,
+		Options:       opts,
+		PagesDeferred: s.cfg.DeferPageCount,
+		StoreMs:       ws.elapsed().Milliseconds(),
+		CreatedAt:     s.now().UTC(),
+	}
+	if err := writeManifest(s.fs, ws.dir, manifest); err != nil {
 		_ = removeDir(ws.dir)
 		return nil, nil, fmt.Errorf("ジョブマニフェストの保存に失敗しました: %w", err)
 	}
@@ -233,7 +274,8 @@ func (s *Service) prepareMerge(ctx context.Context, files []*multipart.FileHeade
 	return &mergeState{ws: ws, storedFiles: storedFiles}, manifest, nil
 }
 
-func (s *Service) executeMerge(ctx context.Context, state *mergeState, order []int, progress ProgressReporter) (*Result, error) {
+// toc が true の場合は、各入力の題名と開始ページを並べた目次のページを先頭に付けて結合します。
+func (s *Service) executeMerge(ctx context.Context, state *mergeState, order []int, toc bool, progress ProgressReporter) (*Result, error) {
 	ws := state.ws
 	storedFiles := state.storedFiles
 
@@ -255,12 +297,24 @@ func (s *Service) executeMerge(ctx context.Context, state *mergeState, order []i
 		return nil, err
 	}
 
+	if toc {
+		tocPath := filepath.Join(ws.dir, tocFilename)
+		if err := writeTOCPDF(tocPath, tocEntries(ordered)); err != nil {
+			return nil, fmt.Errorf("目次の作成に失敗しました: %w", err)
+		}
+		defer os.Remove(tocPath)
+		inputPaths = append([]string{tocPath}, inputPaths...)
+	}
+
 	outputPath := filepath.Join(ws.outDir, outputFilename)
-	reportProgress(progress, "process", 40)
-	if err := mergeCreateFileCompat(inputPaths, outputPath); err != nil {
+	reportProgress(progress, StageProcess, 0)
+	if err := runStep(ctx, func() error { return mergeCreateFileCompat(inputPaths, outputPath) }); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
 		return nil, newError("UNSUPPORTED_PDF", "PDFの結合に失敗しました。ファイルが破損していないか確認してください。", err)
 	}
-	reportProgress(progress, "write", 80)
+	reportProgress(progress, StageWrite, 0)
 
 	outInfo, err := os.Stat(outputPath)
 	if err != nil {
@@ -277,53 +331,51 @@ func (s *Service) executeMerge(ctx context.Context, state *mergeState, order []i
 		}
 		totalPages += sf.pages
 	}
+	tocPageCount := 0
+	if toc {
+		tocPageCount = tocPages
+		totalPages += tocPages
+	}
 
 	meta := struct {
 		Type      string           `json:"type"`
 		CreatedAt time.Time        `json:"createdAt"`
 		Files     []SourceFileMeta `json:"files"`
+		TOCPages  int              `json:"tocPages,omitempty"`
 		Pages     int              `json:"pages"`
 		Size      int64            `json:"size"`
 	}{
 		Type:      "merge",
 		CreatedAt: s.now().UTC(),
 		Files:     sources,
+		TOCPages:  tocPageCount,
 		Pages:     totalPages,
 		Size:      outInfo.Size(),
 	}
 
 	metaPath := filepath.Join(ws.dir, "meta.json")
-	if err := writeJSON(metaPath, meta); err != nil {
+	if err := writeJSON(s.fs, metaPath, meta); err != nil {
 		return nil, fmt.Errorf("メタデータの保存に失敗しました: %w", err)
 	}
 
-	expireMinutes := s.cfg.JobExpireMinutes
-	if expireMinutes <= 0 {
-		expireMinutes = defaultCleanupMin
-	}
-	time.AfterFunc(time.Duration(expireMinutes)*time.Minute, func() {
-		_ = removeDir(ws.dir)
-	})
+	s.scheduleCleanup(ws.dir)
 
-	result := &Result{
-		JobID:          ws.jobID,
-		Operation:      OperationMerge,
-		OutputPath:     outputPath,
-		OutputFilename: outputFilename,
-		OutputSize:     outInfo.Size(),
-		ResultKind:     ResultKindPDF,
-		Meta: &MergeMeta{
-			TotalPages: totalPages,
-			Sources:    sources,
-		},
-		jobDir: ws.dir,
+	artifact, err := newArtifact(outputPath, ResultKindPDF)
+	if err != nil {
+		return nil, fmt.Errorf("成果物の確認に失敗しました: %w", err)
 	}
-	reportProgress(progress, "completed", 100)
+	result := newResult(ws, OperationMerge, s.fs, []Artifact{artifact}, &MergeMeta{
+		TotalPages: totalPages,
+		TOCPages:   tocPageCount,
+		Sources:    sources,
+	})
+	reportProgress(progress, StageCompleted, 1)
 	return result, nil
 }
 
 // PrepareMergeJob は非同期処理用に入力ファイルを保存し、マニフェストを返します。
-func (s *Service) PrepareMergeJob(ctx context.Context, files []*multipart.FileHeader, order []int) (*JobManifest, error) {
+// toc が true の場合は、結合結果の先頭に目次のページを付けます。
+func (s *Service) PrepareMergeJob(ctx context.Context, files []*multipart.FileHeader, order []int, toc bool, opts JobOptions) (*JobManifest, error) {
 	if ctx == nil {
 		ctx = context.Background()
 	}
@@ -331,16 +383,19 @@ func (s *Service) PrepareMergeJob(ctx context.Context, files []*multipart.FileHe
 	if err := validateMergeInputs(files, order); err != nil {
 		return nil, err
 	}
-	state, manifest, err := s.prepareMerge(ctx, files, order)
+	state, manifest, err := s.prepareMerge(ctx, files, order, toc, opts)
 	if err != nil {
 		return nil, err
 	}
 	// stateは将来の実行で使用されるため、ここではクリーンアップしない
 	_ = state
-	return manifest, nil
+	return s.sealJob(manifest)
 }
 
-func (s *Service) storeMultipartFile(ctx context.Context, fh *multipart.FileHeader, dir string, index int) (storedFile, error) {
+// storeMultipartFile はアップロードされたファイルを dir に保存し、ページ数を確認します。
+// deferPages が true の場合は PDF の読み込み（ページ数の確認と上限の判定）を省き、pages を 0 のまま返します。
+// ctx に index 番目のファイルのパスワード（WithInputPasswords）がある場合は、deferPages にかかわらず保存後に復号します。
+func (s *Service) storeMultipartFile(ctx context.Context, fh *multipart.FileHeader, dir string, index int, deferPages bool) (storedFile, error) {
 	if fh == nil {
 		return storedFile{}, newError("INVALID_INPUT", fmt.Sprintf("files[%d] が空です。", index), nil)
 	}
@@ -364,6 +419,26 @@ func (s *Service) storeMultipartFile(ctx context.Context, fh *multipart.FileHead
 	if err != nil {
 		return storedFile{}, fmt.Errorf("一時ファイルを作成できませんでした: %w", err)
 	}
+	// 途中で失敗・中断した場合は、有効期限を待たずに書きかけのファイルをすぐに消す
+	stored := false
+	defer func() {
+		if !stored {
+			_ = os.Remove(tempPath)
+		}
+	}()
+
+	// 結果キャッシュのキーに使うため、保存と同時に内容のハッシュを計算する
+	hasher := sha256.New()
+	writers := []io.Writer{dst, hasher}
+	// 複数の入力をまとめて保存する場合は、合計サイズが上限を超えた時点でディスクへ書く前に止める
+	if budget := uploadBudgetFrom(ctx); budget != nil {
+		writers = append([]io.Writer{budget}, writers...)
+	}
+	// 大きな入力では保存に時間がかかるため、書き込んだバイト数を進捗として通知する
+	if progress := storeProgress(ctx, fh.Size); progress != nil {
+		writers = append(writers, progress)
+	}
+	out := io.MultiWriter(writers...)
 
 	var totalWritten int64
 	sniffBuf := make([]byte, 4096)
@@ -378,23 +453,33 @@ func (s *Service) storeMultipartFile(ctx context.Context, fh *multipart.FileHead
 		return storedFile{}, newError("INVALID_INPUT", fmt.Sprintf("%s は空のPDFです。", fh.Filename), nil)
 	}
 
-	mime := mimetype.Detect(sniffBuf[:n])
-	if mime == nil || !mime.Is("application/pdf") {
+	level := s.pdfCheckLevel()
+	if rejectBySniff(sniffBuf[:n], level) {
 		dst.Close()
 		return storedFile{}, newError("UNSUPPORTED_PDF", fmt.Sprintf("%s はPDF形式ではありません。", fh.Filename), nil)
 	}
 
-	written, err := dst.Write(sniffBuf[:n])
+	written, err := out.Write(sniffBuf[:n])
 	if err != nil {
 		dst.Close()
+		if errors.Is(err, errUploadTotalExceeded) {
+			return storedFile{}, uploadTotalExceededError()
+		}
 		return storedFile{}, fmt.Errorf("一時ファイルへの書き込みに失敗しました(%s): %w", fh.Filename, err)
 	}
 	totalWritten += int64(written)
 
 	if readErr != io.EOF {
-		copied, err := io.Copy(dst, src)
+		copied, err := io.Copy(out, contextReader{ctx: ctx, r: src})
 		if err != nil {
 			dst.Close()
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				metrics.AbortedUploads.Add("multipart", 1)
+				return storedFile{}, ctxErr
+			}
+			if errors.Is(err, errUploadTotalExceeded) {
+				return storedFile{}, uploadTotalExceededError()
+			}
 			return storedFile{}, fmt.Errorf("ファイルのコピーに失敗しました(%s): %w", fh.Filename, err)
 		}
 		totalWritten += copied
@@ -412,23 +497,136 @@ func (s *Service) storeMultipartFile(ctx context.Context, fh *multipart.FileHead
 		return storedFile{}, newError("LIMIT_EXCEEDED", fmt.Sprintf("%s のサイズが上限(%dMB)を超えています。", fh.Filename, s.cfg.MaxFileSize/(1024*1024)), nil)
 	}
 
-	pages, err := pdfapi.PageCountFile(tempPath)
-	if err != nil {
-		return storedFile{}, newError("UNSUPPORTED_PDF", fmt.Sprintf("%s のページ数を取得できませんでした。", fh.Filename), err)
+	var decrypted bool
+	if password := inputPassword(ctx, index); password != "" {
+		decrypted, err = decryptStoredPDF(tempPath, fh.Filename, password)
+		if err != nil {
+			return storedFile{}, err
+		}
 	}
 
-	if s.cfg.MaxPages > 0 && pages > s.cfg.MaxPages {
-		return storedFile{}, newError("LIMIT_EXCEEDED", fmt.Sprintf("%s のページ数が上限(%dページ)を超えています。", fh.Filename, s.cfg.MaxPages), nil)
+	var pages int
+	if !deferPages {
+		pages, err = s.countStoredPages(tempPath, fh.Filename, sniffPDF(sniffBuf[:n], level))
+		if err != nil {
+			return storedFile{}, err
+		}
+	}
+
+	digest := hex.EncodeToString(hasher.Sum(nil))
+	// 復号した入力はアップロードされた内容（ハッシュ）と異なるため共有しない
+	if !decrypted {
+		s.sharedInputs.share(tenant.FromContext(ctx), tempPath, digest, totalWritten)
 	}
 
+	stored = true
 	return storedFile{
 		path:         tempPath,
 		originalName: safeOriginalName(fh.Filename, index),
 		size:         totalWritten,
 		pages:        pages,
+		sha256:       digest,
+		encrypted:    decrypted,
 	}, nil
 }
 
+// storeMultipartFiles は複数の入力ファイルを最大 maxParallelStores 件ずつ並行して保存します。
+// DEFER_PAGE_COUNT が有効な場合はページ数を確認しません（実行時に countDeferredPages で確認します）。
+// 結果は files と同じ順序で返します。いずれかが失敗した場合は未着手のファイルを保存せず、
+// 先頭に近いファイルのエラーを返します。
+// 合計サイズが MaxUploadTotalBytes を超える場合は、宣言されたサイズで保存を始める前に断り、
+// 宣言と実際の内容が異なる場合も、保存したバイト数の合計が上限を超えた時点で保存中のファイルを止めます。
+func (s *Service) storeMultipartFiles(ctx context.Context, files []*multipart.FileHeader, dir string) ([]storedFile, error) {
+	var declared int64
+	for _, fh := range files {
+		if fh != nil {
+			declared += fh.Size
+		}
+	}
+	if declared > MaxUploadTotalBytes {
+		return nil, uploadTotalExceededError()
+	}
+
+	var (
+		results = make([]storedFile, len(files))
+		errs    = make([]error, len(files))
+		slots   = make(chan struct{}, maxParallelStores)
+		failed  atomic.Bool
+		wg      sync.WaitGroup
+	)
+	ctx = withSharedStoreProgress(ctx, files)
+	ctx = context.WithValue(ctx, uploadBudgetKey{}, &uploadBudget{limit: MaxUploadTotalBytes})
+	for i, fh := range files {
+		slots <- struct{}{}
+		if failed.Load() {
+			<-slots
+			break
+		}
+		if err := ctx.Err(); err != nil {
+			<-slots
+			errs[i] = err
+			break
+		}
+		wg.Add(1)
+		go func(i int, fh *multipart.FileHeader) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			results[i], errs[i] = s.storeMultipartFile(ctx, fh, dir, i, s.cfg.DeferPageCount)
+			if errs[i] != nil {
+				failed.Store(true)
+			}
+		}(i, fh)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+// errUploadTotalExceeded は保存したバイト数の合計が uploadBudget の上限を超えたことを表します。
+var errUploadTotalExceeded = errors.New("upload total size exceeded")
+
+func uploadTotalExceededError() error {
+	return newError("LIMIT_EXCEEDED", "アップロードされたファイル全体のサイズが上限(300MB)を超えています。", nil)
+}
+
+type uploadBudgetKey struct{}
+
+// uploadBudget は並行して保存する入力のバイト数を合計し、上限を超えた書き込みを失敗させる io.Writer です。
+type uploadBudget struct {
+	limit int64
+	used  atomic.Int64
+}
+
+func (b *uploadBudget) Write(p []byte) (int, error) {
+	if b.used.Add(int64(len(p))) > b.limit {
+		return 0, errUploadTotalExceeded
+	}
+	return len(p), nil
+}
+
+func uploadBudgetFrom(ctx context.Context) *uploadBudget {
+	budget, _ := ctx.Value(uploadBudgetKey{}).(*uploadBudget)
+	return budget
+}
+
+// contextReader は読み取りのたびに ctx を確認し、リクエストが中断されたらそれ以上読まないようにします。
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
+
 func safeOriginalName(name string, index int) string {
 	base := filepath.Base(name)
 	if base == "." || base == string(os.PathSeparator) || base == "" {
@@ -437,8 +635,8 @@ func safeOriginalName(name string, index int) string {
 	return base
 }
 
-func writeJSON(path string, v any) error {
-	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o640)
+func writeJSON(fsys FS, path string, v any) error {
+	file, err := fsys.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o640)
 	if err != nil {
 		return err
 	}
@@ -458,7 +656,7 @@ func (s *Service) DiscardJob(jobID string) error {
 		return nil
 	}
 	ws := s.workspaceFor(jobID)
-	return removeDir(ws.dir)
+	return removeDirFS(s.fs, ws.dir)
 }
 
 func removeDir(path string) error {
@@ -468,15 +666,6 @@ func removeDir(path string) error {
 	return os.RemoveAll(path)
 }
 
-// IsError は指定したコードのエラーかどうかを判定します。
-func IsError(err error, code string) bool {
-	var apiErr *Error
-	if errors.As(err, &apiErr) {
-		return apiErr.Code == code
-	}
-	return false
-}
-
 // mergeCreateFileCompat は pdfcpu の MergeCreateFile のシグネチャ差異に対応します。
 func mergeCreateFileCompat(inputs []string, output string) error {
 	fn := reflect.ValueOf(pdfapi.MergeCreateFile)
@@ -510,3 +699,54 @@ func mergeCreateFileCompat(inputs []string, output string) error {
 		return fmt.Errorf("unsupported MergeCreateFile signature with %d parameters", fnType.NumIn())
 	}
 }
+
+func init() {
+	registerOperation(mergeOperation{})
+}
+
+type mergeOperation struct{}
+
+func (mergeOperation) Type() OperationType { return OperationMerge }
+
+func (mergeOperation) OutputSpec() OutputSpec {
+	return OutputSpec{Filename: outputFilename, Kind: ResultKindPDF}
+}
+
+func (mergeOperation) Prepare(ctx context.Context, s *Service, req PrepareRequest) (*JobManifest, error) {
+	order, err := parseOrderValues(req.Params.Get("order"), req.Params["order[]"])
+	if err != nil {
+		return nil, newError("INVALID_INPUT", err.Error(), nil)
+	}
+	toc, err := parseTOC(req.Params.Get("toc"))
+	if err != nil {
+		return nil, err
+	}
+	return s.PrepareMergeJob(ctx, req.Files, order, toc, req.Options)
+}
+
+func (mergeOperation) Execute(ctx context.Context, s *Service, job *jobInput, progress ProgressReporter) (*Result, error) {
+	state := &mergeState{ws: job.ws, storedFiles: job.files}
+	return s.executeMerge(ctx, state, job.manifest.Order, job.manifest.TOC, progress)
+}
+
+func (mergeOperation) Plan(s *Service, job *jobInput) (*JobPlan, error) {
+	order := job.manifest.Order
+	if len(order) == 0 {
+		order = make([]int, len(job.files))
+		for i := range order {
+			order[i] = i
+		}
+	}
+	out := PlannedOutput{Name: outputFilename, Kind: ResultKindPDF}
+	if job.manifest.TOC {
+		out.Pages = tocPages
+	}
+	for _, idx := range order {
+		if idx < 0 || idx >= len(job.files) {
+			return nil, newError("INVALID_INPUT", "order配列に不正な番号が含まれています。", nil)
+		}
+		out.Pages += job.files[idx].pages
+		out.Files = append(out.Files, job.files[idx].originalName)
+	}
+	return &JobPlan{Outputs: []PlannedOutput{out}}, nil
+}
@@ -0,0 +1,224 @@
+package pdf
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/yourusername/paper-forge/internal/config"
+	"github.com/yourusername/paper-forge/internal/metrics"
+	"github.com/yourusername/paper-forge/internal/pdf/pdftest"
+)
+
+func TestPrepareMergeJobRejectsTooManyTotalPages(t *testing.T) {
+	svc := NewService(&config.Config{MaxFileSize: 1 << 20, MaxPages: 20, MaxMergePages: 15})
+	svc.tmpRoot = t.TempDir()
+
+	headers, err := pdftest.FileHeaders("files[]",
+		pdftest.File{Name: "a.pdf", Data: pdftest.Synthetic(10, 0)},
+		pdftest.File{Name: "b.pdf", Data: pdftest.Synthetic(10, 0)},
+	)
+	if err != nil {
+		t.Fatalf("failed to build multipart files: %v", err)
+	}
+
+	if _, err := svc.PrepareMergeJob(context.Background(), headers, []int{0, 1}, false, JobOptions{}); !IsError(err, "LIMIT_EXCEEDED") {
+		t.Fatalf("expected LIMIT_EXCEEDED, got %v", err)
+	}
+	entries, err := os.ReadDir(svc.tmpRoot)
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatalf("failed to read work dir: %v", err)
+	}
+	for _, e := range entries {
+		if _, statErr := os.Stat(filepath.Join(svc.tmpRoot, e.Name(), manifestFilename)); statErr == nil {
+			t.Fatalf("workspace %s should have been removed", e.Name())
+		}
+	}
+}
+
+// abortAfterContext は Err が指定回数呼ばれた後にキャンセル済みを返すコンテキストです（アップロード途中の切断を再現）。
+type abortAfterContext struct {
+	context.Context
+	remaining int
+}
+
+func (c *abortAfterContext) Err() error {
+	if c.remaining <= 0 {
+		return context.Canceled
+	}
+	c.remaining--
+	return nil
+}
+
+func TestStoreMultipartFileRemovesPartialFileOnAbort(t *testing.T) {
+	svc := NewService(&config.Config{MaxFileSize: 1 << 20})
+	dir := t.TempDir()
+
+	headers, err := pdftest.FileHeaders("files[]", pdftest.File{Name: "a.pdf", Data: pdftest.Synthetic(2, 8192)})
+	if err != nil {
+		t.Fatalf("failed to build multipart files: %v", err)
+	}
+
+	before := abortedUploads()
+	ctx := &abortAfterContext{Context: context.Background(), remaining: 1}
+	if _, err := svc.storeMultipartFile(ctx, headers[0], dir, 0, false); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "00.pdf")); !os.IsNotExist(err) {
+		t.Fatalf("partial file should have been removed: %v", err)
+	}
+	if got := abortedUploads(); got != before+1 {
+		t.Fatalf("aborted uploads = %d, want %d", got, before+1)
+	}
+}
+
+func abortedUploads() int64 {
+	if v, ok := metrics.AbortedUploads.Get("multipart").(*expvar.Int); ok {
+		return v.Value()
+	}
+	return 0
+}
+
+func TestStoreMultipartFilesKeepsOrder(t *testing.T) {
+	svc := NewService(&config.Config{MaxFileSize: 1 << 20, MaxPages: 20})
+	dir := t.TempDir()
+
+	var files []pdftest.File
+	for i := 1; i <= maxParallelStores*2+1; i++ {
+		files = append(files, pdftest.File{Name: fmt.Sprintf("%02d.pdf", i), Data: pdftest.Synthetic(i, 0)})
+	}
+	headers, err := pdftest.FileHeaders("files[]", files...)
+	if err != nil {
+		t.Fatalf("failed to build multipart files: %v", err)
+	}
+
+	stored, err := svc.storeMultipartFiles(context.Background(), headers, dir)
+	if err != nil {
+		t.Fatalf("storeMultipartFiles returned error: %v", err)
+	}
+	for i, sf := range stored {
+		if sf.originalName != files[i].Name || sf.pages != i+1 {
+			t.Fatalf("stored[%d] = %s (%d pages), want %s (%d pages)", i, sf.originalName, sf.pages, files[i].Name, i+1)
+		}
+	}
+
+	headers[3] = nil
+	if _, err := svc.storeMultipartFiles(context.Background(), headers, dir); !IsError(err, "INVALID_INPUT") {
+		t.Fatalf("expected INVALID_INPUT, got %v", err)
+	}
+}
+
+func TestStoreMultipartFilesEnforcesTotalSize(t *testing.T) {
+	svc := NewService(&config.Config{MaxFileSize: 1 << 20, MaxPages: 20})
+	dir := t.TempDir()
+	headers, err := pdftest.FileHeaders("files[]",
+		pdftest.File{Name: "a.pdf", Data: pdftest.Synthetic(2, 8<<10)},
+		pdftest.File{Name: "b.pdf", Data: pdftest.Synthetic(2, 8<<10)},
+	)
+	if err != nil {
+		t.Fatalf("failed to build multipart files: %v", err)
+	}
+
+	// 宣言されたサイズの合計が上限を超える場合は、保存を始める前に断る
+	declared := *headers[1]
+	declared.Size = MaxUploadTotalBytes
+	if _, err := svc.storeMultipartFiles(context.Background(), []*multipart.FileHeader{headers[0], &declared}, dir); !IsError(err, "LIMIT_EXCEEDED") {
+		t.Fatalf("expected LIMIT_EXCEEDED, got %v", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Fatalf("nothing should be stored before the total is checked: %d entries", len(entries))
+	}
+
+	// 保存したバイト数の合計が上限を超えた時点で、書きかけのファイルを消して止める
+	ctx := context.WithValue(context.Background(), uploadBudgetKey{}, &uploadBudget{limit: headers[0].Size + 1024})
+	if _, err := svc.storeMultipartFile(ctx, headers[0], dir, 0, false); err != nil {
+		t.Fatalf("storeMultipartFile within the budget returned error: %v", err)
+	}
+	if _, err := svc.storeMultipartFile(ctx, headers[1], dir, 1, false); !IsError(err, "LIMIT_EXCEEDED") {
+		t.Fatalf("expected LIMIT_EXCEEDED, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "01.pdf")); !os.IsNotExist(err) {
+		t.Fatalf("partial file should have been removed: %v", err)
+	}
+}
+
+func TestStoreMultipartFilesReportsBytesCopied(t *testing.T) {
+	svc := NewService(&config.Config{MaxFileSize: 1 << 20, MaxPages: 20})
+	headers, err := pdftest.FileHeaders("files[]",
+		pdftest.File{Name: "a.pdf", Data: pdftest.Synthetic(3, 8<<10)},
+		pdftest.File{Name: "b.pdf", Data: pdftest.Synthetic(5, 8<<10)},
+	)
+	if err != nil {
+		t.Fatalf("failed to build multipart files: %v", err)
+	}
+
+	var (
+		mu      sync.Mutex
+		reports []int
+	)
+	ctx := WithStoreProgress(context.Background(), func(stage Stage, percent int) {
+		if stage != StageLoad {
+			t.Errorf("unexpected stage: %s", stage)
+		}
+		mu.Lock()
+		reports = append(reports, percent)
+		mu.Unlock()
+	})
+	if _, err := svc.storeMultipartFiles(ctx, headers, t.TempDir()); err != nil {
+		t.Fatalf("storeMultipartFiles returned error: %v", err)
+	}
+	// 暗号化の有無にかかわらず、2つの入力の合計に対する保存済みのバイト数で load 段階が進む
+	if len(reports) < 3 || reports[len(reports)-1] != StageLoad.Percent(1) {
+		t.Fatalf("expected gradual load progress up to the end of the stage, got %v", reports)
+	}
+	for i := 1; i < len(reports); i++ {
+		if reports[i] <= reports[i-1] {
+			t.Fatalf("progress should only move forward: %v", reports)
+		}
+	}
+}
+
+func TestPrepareMergeJobDefersPageCount(t *testing.T) {
+	svc := NewService(&config.Config{MaxFileSize: 1 << 20, MaxPages: 20, MaxMergePages: 15, DeferPageCount: true})
+	svc.tmpRoot = t.TempDir()
+
+	headers, err := pdftest.FileHeaders("files[]",
+		pdftest.File{Name: "a.pdf", Data: pdftest.Synthetic(10, 0)},
+		pdftest.File{Name: "b.pdf", Data: pdftest.Synthetic(10, 0)},
+	)
+	if err != nil {
+		t.Fatalf("failed to build multipart files: %v", err)
+	}
+
+	manifest, err := svc.PrepareMergeJob(context.Background(), headers, []int{0, 1}, false, JobOptions{})
+	if err != nil {
+		t.Fatalf("PrepareMergeJob should accept the job before counting pages: %v", err)
+	}
+	if !manifest.PagesDeferred || manifest.Files[0].Pages != 0 {
+		t.Fatalf("pages should be deferred: %+v", manifest)
+	}
+
+	ws := svc.workspaceFor(manifest.JobID)
+	stored := storedFilesFromManifest(ws.dir, manifest)
+	if err := svc.countDeferredPages(context.Background(), ws, manifest, stored); !IsError(err, "LIMIT_EXCEEDED") {
+		t.Fatalf("expected LIMIT_EXCEEDED at execution, got %v", err)
+	}
+
+	svc.cfg.MaxMergePages = 0
+	if err := svc.countDeferredPages(context.Background(), ws, manifest, stored); err != nil {
+		t.Fatalf("countDeferredPages returned error: %v", err)
+	}
+	saved, err := loadManifest(svc.fs, ws.dir)
+	if err != nil {
+		t.Fatalf("loadManifest returned error: %v", err)
+	}
+	if saved.PagesDeferred || saved.Files[0].Pages != 10 || stored[1].pages != 10 {
+		t.Fatalf("counted pages were not saved: %+v", saved)
+	}
+}
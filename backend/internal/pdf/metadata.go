@@ -0,0 +1,445 @@
+package pdf
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	pdfapi "github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+
+	"github.com/yourusername/paper-forge/internal/tenant"
+)
+
+const (
+	metadataFilename = "metadata.pdf"
+	// maxMetadataValueLength は文書情報の各項目の最大文字数です。
+	maxMetadataValueLength = 1000
+)
+
+// MetadataField は編集できる文書情報の項目です。
+type MetadataField string
+
+const (
+	MetadataTitle    MetadataField = "title"
+	MetadataAuthor   MetadataField = "author"
+	MetadataSubject  MetadataField = "subject"
+	MetadataKeywords MetadataField = "keywords"
+)
+
+// metadataFields は編集できる項目と、文書情報辞書（/Info）のキーです。
+var metadataFields = []struct {
+	field MetadataField
+	key   string
+}{
+	{MetadataTitle, "Title"},
+	{MetadataAuthor, "Author"},
+	{MetadataSubject, "Subject"},
+	{MetadataKeywords, "Keywords"},
+}
+
+// MetadataSpec は文書情報の変更内容です。Set の項目は値を設定し、Clear の項目は削除します。
+// どちらにも含まれない項目は元のPDFの値のままにします。
+type MetadataSpec struct {
+	Set   map[MetadataField]string `json:"set,omitempty"`
+	Clear []MetadataField          `json:"clear,omitempty"`
+}
+
+// DocumentMetadata は編集後のPDFの文書情報です。設定されていない項目は省略します。
+type DocumentMetadata struct {
+	Title    string `json:"title,omitempty"`
+	Author   string `json:"author,omitempty"`
+	Subject  string `json:"subject,omitempty"`
+	Keywords string `json:"keywords,omitempty"`
+}
+
+// set は項目 field の値を value にします。
+func (d *DocumentMetadata) set(field MetadataField, value string) {
+	switch field {
+	case MetadataTitle:
+		d.Title = value
+	case MetadataAuthor:
+		d.Author = value
+	case MetadataSubject:
+		d.Subject = value
+	case MetadataKeywords:
+		d.Keywords = value
+	}
+}
+
+// parseMetadataSpec はフォーム項目 metadata の JSON（例: {"title":"月次報告","author":null}）を解析します。
+// 文字列の項目は値を設定し、null または空文字列の項目は削除します。
+func parseMetadataSpec(raw string) (MetadataSpec, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return MetadataSpec{}, newError("INVALID_INPUT", "変更する文書情報を metadata に JSON で指定してください。", nil)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(raw), &fields); err != nil {
+		return MetadataSpec{}, newError("INVALID_INPUT", "metadata は JSON のオブジェクトで指定してください。", err)
+	}
+	if len(fields) == 0 {
+		return MetadataSpec{}, newError("INVALID_INPUT", "変更する文書情報を metadata に1つ以上指定してください。", nil)
+	}
+
+	spec := MetadataSpec{}
+	for name, value := range fields {
+		field, ok := lookupMetadataField(name)
+		if !ok {
+			return MetadataSpec{}, newError("INVALID_INPUT", fmt.Sprintf("metadata の %s は変更できません。title, author, subject, keywords のいずれかを指定してください。", name), nil)
+		}
+		if string(bytes.TrimSpace(value)) == "null" {
+			spec.Clear = append(spec.Clear, field)
+			continue
+		}
+		var s string
+		if err := json.Unmarshal(value, &s); err != nil {
+			return MetadataSpec{}, newError("INVALID_INPUT", fmt.Sprintf("metadata の %s は文字列または null で指定してください。", name), nil)
+		}
+		s = strings.TrimSpace(s)
+		if s == "" {
+			spec.Clear = append(spec.Clear, field)
+			continue
+		}
+		if spec.Set == nil {
+			spec.Set = make(map[MetadataField]string)
+		}
+		spec.Set[field] = s
+	}
+	sort.Slice(spec.Clear, func(i, j int) bool { return spec.Clear[i] < spec.Clear[j] })
+	return spec, validateMetadataSpec(spec)
+}
+
+// lookupMetadataField は JSON のキー name（大文字小文字を区別しない）に対応する項目を返します。
+func lookupMetadataField(name string) (MetadataField, bool) {
+	for _, f := range metadataFields {
+		if strings.EqualFold(strings.TrimSpace(name), string(f.field)) {
+			return f.field, true
+		}
+	}
+	return "", false
+}
+
+// validateMetadataSpec は設定する値の長さと文字を確認します。
+func validateMetadataSpec(spec MetadataSpec) error {
+	if len(spec.Set) == 0 && len(spec.Clear) == 0 {
+		return newError("INVALID_INPUT", "変更する文書情報を metadata に1つ以上指定してください。", nil)
+	}
+	for field, value := range spec.Set {
+		if _, ok := lookupMetadataField(string(field)); !ok {
+			return newError("INVALID_INPUT", fmt.Sprintf("metadata の %s は変更できません。", field), nil)
+		}
+		switch {
+		case utf8.RuneCountInString(value) > maxMetadataValueLength:
+			return newError("INVALID_INPUT", fmt.Sprintf("metadata の %s は%d文字以内で指定してください。", field, maxMetadataValueLength), nil)
+		case !utf8.ValidString(value) || strings.IndexFunc(value, unicode.IsControl) >= 0:
+			return newError("INVALID_INPUT", fmt.Sprintf("metadata の %s に改行などの制御文字は使用できません。", field), nil)
+		}
+	}
+	return nil
+}
+
+// MetadataMultipart は単一PDFの文書情報（/Info と XMP メタデータ）を変更します。
+func (s *Service) MetadataMultipart(ctx context.Context, file *multipart.FileHeader, spec MetadataSpec) (_ *Result, err error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if file == nil {
+		return nil, newError("INVALID_INPUT", "PDFファイルを選択してください。", nil)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	state, _, err := s.prepareMetadata(ctx, file, spec, JobOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = removeDir(state.ws.dir)
+		}
+	}()
+
+	result, execErr := s.executeMetadata(ctx, state, nil)
+	if execErr != nil {
+		return nil, execErr
+	}
+	return result, nil
+}
+
+type metadataState struct {
+	ws   workspace
+	file storedFile
+	spec MetadataSpec
+}
+
+func (s *Service) prepareMetadata(ctx context.Context, file *multipart.FileHeader, spec MetadataSpec, opts JobOptions) (*metadataState, *JobManifest, error) {
+	if err := validateMetadataSpec(spec); err != nil {
+		return nil, nil, err
+	}
+
+	release, err := s.reserveWorkspace(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer release()
+
+	ws, err := s.createWorkspace()
+	if err != nil {
+		return nil, nil, err
+	}
+	stored, err := s.storeMultipartFile(ctx, file, ws.inDir, 0, false)
+	if err != nil {
+		_ = removeDir(ws.dir)
+		return nil, nil, err
+	}
+
+	manifest := &JobManifest{
+		JobID:     ws.jobID,
+		Tenant:    tenant.FromContext(ctx),
+		Operation: OperationMetadata,
+		Files:     toJobFiles([]storedFile{stored}),
+		Metadata:  &spec,
+		Options:   opts,
+		StoreMs:   ws.elapsed().Milliseconds(),
+		CreatedAt: s.now().UTC(),
+	}
+	if err := writeManifest(s.fs, ws.dir, manifest); err != nil {
+		_ = removeDir(ws.dir)
+		return nil, nil, fmt.Errorf("ジョブマニフェストの保存に失敗しました: %w", err)
+	}
+
+	return &metadataState{ws: ws, file: stored, spec: spec}, manifest, nil
+}
+
+func (s *Service) executeMetadata(ctx context.Context, state *metadataState, progress ProgressReporter) (*Result, error) {
+	ws := state.ws
+	stored := state.file
+
+	reportProgress(progress, StageProcess, 0)
+	outputPath := filepath.Join(ws.outDir, metadataFilename)
+	var doc DocumentMetadata
+	if err := runStep(ctx, func() error {
+		var err error
+		doc, err = writeDocumentMetadata(stored.path, outputPath, state.spec, s.now().UTC())
+		return err
+	}); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		return nil, newError("UNSUPPORTED_PDF", "PDFの文書情報の変更に失敗しました。ファイルが破損していないか確認してください。", err)
+	}
+	reportProgress(progress, StageWrite, 0)
+
+	sourceMeta := SourceFileMeta{
+		Name:  stored.originalName,
+		Size:  stored.size,
+		Pages: stored.pages,
+	}
+
+	meta := struct {
+		Type      OperationType    `json:"type"`
+		CreatedAt string           `json:"createdAt"`
+		Source    SourceFileMeta   `json:"source"`
+		Changes   MetadataSpec     `json:"changes"`
+		Document  DocumentMetadata `json:"document"`
+		Output    string           `json:"output"`
+	}{
+		Type:      OperationMetadata,
+		CreatedAt: s.now().UTC().Format(time.RFC3339),
+		Source:    sourceMeta,
+		Changes:   state.spec,
+		Document:  doc,
+		Output:    metadataFilename,
+	}
+
+	metaPath := filepath.Join(ws.dir, "meta.json")
+	if err := writeJSON(s.fs, metaPath, meta); err != nil {
+		return nil, fmt.Errorf("メタデータの保存に失敗しました: %w", err)
+	}
+
+	s.scheduleCleanup(ws.dir)
+
+	artifact, err := newArtifact(outputPath, ResultKindPDF)
+	if err != nil {
+		return nil, fmt.Errorf("成果物の確認に失敗しました: %w", err)
+	}
+
+	reportProgress(progress, StageCompleted, 1)
+
+	return newResult(ws, OperationMetadata, s.fs, []Artifact{artifact}, &MetadataMeta{
+		Original: sourceMeta,
+		Changes:  state.spec,
+		Document: doc,
+	}), nil
+}
+
+// writeDocumentMetadata は inPath の文書情報辞書を spec のとおりに変更して outPath に書き出し、
+// 変更後の値を XMP メタデータにも書き込みます。XMP は /Info と食い違わないよう、変更後の値だけで置き換えます。
+func writeDocumentMetadata(inPath, outPath string, spec MetadataSpec, now time.Time) (DocumentMetadata, error) {
+	pdfCtx, err := pdfapi.ReadContextFile(inPath)
+	if err != nil {
+		return DocumentMetadata{}, err
+	}
+	info, err := documentInfoDict(pdfCtx)
+	if err != nil {
+		return DocumentMetadata{}, err
+	}
+	doc := DocumentMetadata{
+		Title:    strings.TrimSpace(pdfCtx.Title),
+		Author:   strings.TrimSpace(pdfCtx.Author),
+		Subject:  strings.TrimSpace(pdfCtx.Subject),
+		Keywords: strings.TrimSpace(pdfCtx.Keywords),
+	}
+	doc = applyMetadataSpec(info, doc, spec)
+
+	tmp := outPath + ".info"
+	if err := pdfapi.WriteContextFile(pdfCtx, tmp); err != nil {
+		_ = os.Remove(tmp)
+		return DocumentMetadata{}, err
+	}
+	if err := os.Rename(tmp, outPath); err != nil {
+		return DocumentMetadata{}, err
+	}
+	return doc, embedXMP(outPath, doc.xmp(now))
+}
+
+// documentInfoDict は文書情報辞書を返します。無い場合は作成してトレーラーから参照します。
+func documentInfoDict(pdfCtx *model.Context) (types.Dict, error) {
+	if pdfCtx.Info != nil {
+		d, err := pdfCtx.DereferenceDict(*pdfCtx.Info)
+		if err != nil || d != nil {
+			return d, err
+		}
+	}
+	d := types.NewDict()
+	ref, err := pdfCtx.IndRefForNewObject(d)
+	if err != nil {
+		return nil, err
+	}
+	pdfCtx.Info = ref
+	return d, nil
+}
+
+// applyMetadataSpec は文書情報辞書 info と現在の値 doc に spec を適用し、変更後の値を返します。
+// 日本語を含む値も扱えるよう、値は UTF-16BE（BOM 付き）の16進文字列で書き込みます。
+func applyMetadataSpec(info types.Dict, doc DocumentMetadata, spec MetadataSpec) DocumentMetadata {
+	for _, f := range metadataFields {
+		if value, ok := spec.Set[f.field]; ok {
+			info.Update(f.key, types.NewHexLiteral([]byte(types.EncodeUTF16String(value))))
+			doc.set(f.field, value)
+		}
+	}
+	for _, field := range spec.Clear {
+		for _, f := range metadataFields {
+			if f.field == field {
+				delete(info, f.key)
+				doc.set(f.field, "")
+			}
+		}
+	}
+	return doc
+}
+
+// xmp は文書情報を Dublin Core と Adobe PDF のプロパティとして XMP パケットに書き出します。
+func (d DocumentMetadata) xmp(now time.Time) []byte {
+	var b bytes.Buffer
+	b.WriteString("<?xpacket begin=\"\uFEFF\" id=\"W5M0MpCehiHzreSzNTczkc9d\"?>\n")
+	b.WriteString("<x:xmpmeta xmlns:x=\"adobe:ns:meta/\">\n")
+	b.WriteString("<rdf:RDF xmlns:rdf=\"http://www.w3.org/1999/02/22-rdf-syntax-ns#\">\n")
+	b.WriteString("<rdf:Description rdf:about=\"\" xmlns:xmp=\"http://ns.adobe.com/xap/1.0/\" " + documentXMPNamespaces + ">\n")
+	b.WriteString("<xmp:MetadataDate>" + now.Format(time.RFC3339) + "</xmp:MetadataDate>\n")
+	d.writeXMP(&b)
+	b.WriteString("</rdf:Description>\n</rdf:RDF>\n</x:xmpmeta>\n")
+	b.WriteString("<?xpacket end=\"w\"?>")
+	return b.Bytes()
+}
+
+// documentXMPNamespaces は writeXMP が使う名前空間の宣言です。
+const documentXMPNamespaces = `xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:pdf="http://ns.adobe.com/pdf/1.3/"`
+
+// writeXMP は設定されている項目を rdf:Description の子要素として書き出します。
+func (d DocumentMetadata) writeXMP(b *bytes.Buffer) {
+	escape := func(value string) string {
+		var e bytes.Buffer
+		_ = xml.EscapeText(&e, []byte(value))
+		return e.String()
+	}
+	if d.Title != "" {
+		b.WriteString("<dc:title><rdf:Alt><rdf:li xml:lang=\"x-default\">" + escape(d.Title) + "</rdf:li></rdf:Alt></dc:title>\n")
+	}
+	if d.Author != "" {
+		b.WriteString("<dc:creator><rdf:Seq><rdf:li>" + escape(d.Author) + "</rdf:li></rdf:Seq></dc:creator>\n")
+	}
+	if d.Subject != "" {
+		b.WriteString("<dc:description><rdf:Alt><rdf:li xml:lang=\"x-default\">" + escape(d.Subject) + "</rdf:li></rdf:Alt></dc:description>\n")
+	}
+	if d.Keywords != "" {
+		b.WriteString("<pdf:Keywords>" + escape(d.Keywords) + "</pdf:Keywords>\n")
+	}
+}
+
+// PrepareMetadataJob は非同期ジョブ用に入力を保存します。
+func (s *Service) PrepareMetadataJob(ctx context.Context, file *multipart.FileHeader, spec MetadataSpec, opts JobOptions) (*JobManifest, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	_, manifest, err := s.prepareMetadata(ctx, file, spec, opts)
+	if err != nil {
+		return nil, err
+	}
+	return s.sealJob(manifest)
+}
+
+func init() {
+	registerOperation(metadataOperation{})
+}
+
+type metadataOperation struct{}
+
+func (metadataOperation) Type() OperationType { return OperationMetadata }
+
+func (metadataOperation) OutputSpec() OutputSpec {
+	return OutputSpec{Filename: metadataFilename, Kind: ResultKindPDF}
+}
+
+func (metadataOperation) Prepare(ctx context.Context, s *Service, req PrepareRequest) (*JobManifest, error) {
+	file, err := req.singleFile()
+	if err != nil {
+		return nil, err
+	}
+	spec, err := parseMetadataSpec(req.Params.Get("metadata"))
+	if err != nil {
+		return nil, err
+	}
+	return s.PrepareMetadataJob(ctx, file, spec, req.Options)
+}
+
+func (metadataOperation) Execute(ctx context.Context, s *Service, job *jobInput, progress ProgressReporter) (*Result, error) {
+	if job.manifest.Metadata == nil {
+		return nil, newError("INVALID_INPUT", "文書情報の変更内容がありません。", nil)
+	}
+	state := &metadataState{
+		ws:   job.ws,
+		file: job.files[0],
+		spec: *job.manifest.Metadata,
+	}
+	return s.executeMetadata(ctx, state, progress)
+}
+
+func (metadataOperation) Plan(s *Service, job *jobInput) (*JobPlan, error) {
+	return singleOutputPlan(metadataFilename, job.files[0], "")
+}
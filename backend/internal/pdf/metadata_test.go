@@ -0,0 +1,135 @@
+package pdf
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+
+	"github.com/yourusername/paper-forge/internal/config"
+	"github.com/yourusername/paper-forge/internal/pdf/pdftest"
+)
+
+func TestParseMetadataSpec(t *testing.T) {
+	spec, err := parseMetadataSpec(`{"Title":" 月次報告 ","author":null,"keywords":"","subject":"監査用"}`)
+	if err != nil {
+		t.Fatalf("parseMetadataSpec returned error: %v", err)
+	}
+	want := MetadataSpec{
+		Set:   map[MetadataField]string{MetadataTitle: "月次報告", MetadataSubject: "監査用"},
+		Clear: []MetadataField{MetadataAuthor, MetadataKeywords},
+	}
+	if !reflect.DeepEqual(spec, want) {
+		t.Fatalf("got %+v, want %+v", spec, want)
+	}
+
+	for _, raw := range []string{
+		"",
+		"{}",
+		"[]",
+		`{"creator":"x"}`,
+		`{"title":1}`,
+		`{"title":"line\nbreak"}`,
+		`{"title":"` + strings.Repeat("a", maxMetadataValueLength+1) + `"}`,
+	} {
+		if _, err := parseMetadataSpec(raw); !IsError(err, "INVALID_INPUT") {
+			t.Fatalf("%q: expected INVALID_INPUT, got %v", raw, err)
+		}
+	}
+}
+
+func TestApplyMetadataSpecKeepsUnchangedFields(t *testing.T) {
+	info := types.NewDict()
+	info.Update("Title", types.StringLiteral("old"))
+	info.Update("Author", types.StringLiteral("someone"))
+	info.Update("Producer", types.StringLiteral("scanner"))
+	doc := DocumentMetadata{Title: "old", Author: "someone", Keywords: "scan"}
+
+	got := applyMetadataSpec(info, doc, MetadataSpec{
+		Set:   map[MetadataField]string{MetadataTitle: "契約書"},
+		Clear: []MetadataField{MetadataAuthor},
+	})
+	if want := (DocumentMetadata{Title: "契約書", Keywords: "scan"}); got != want {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+	if _, ok := info["Author"]; ok {
+		t.Fatal("cleared field should be removed from the info dictionary")
+	}
+	if info["Producer"] != types.StringLiteral("scanner") {
+		t.Fatal("fields outside the spec should be kept")
+	}
+	if _, ok := info["Title"].(types.HexLiteral); !ok {
+		t.Fatalf("title should be written as a UTF-16 hex string: %#v", info["Title"])
+	}
+}
+
+func TestDocumentMetadataXMPIsWellFormed(t *testing.T) {
+	doc := DocumentMetadata{Title: "R&D <draft>", Author: "経理部", Keywords: "q3, budget"}
+	packet := doc.xmp(time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC))
+	dec := xml.NewDecoder(bytes.NewReader(packet))
+	for {
+		if _, err := dec.Token(); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("XMP packet is not well-formed: %v\n%s", err, packet)
+		}
+	}
+	for _, want := range []string{
+		`<rdf:li xml:lang="x-default">R&amp;D &lt;draft&gt;</rdf:li>`,
+		"<rdf:li>経理部</rdf:li>",
+		"<pdf:Keywords>q3, budget</pdf:Keywords>",
+	} {
+		if !bytes.Contains(packet, []byte(want)) {
+			t.Fatalf("XMP packet lacks %q:\n%s", want, packet)
+		}
+	}
+	if bytes.Contains(packet, []byte("dc:description")) {
+		t.Fatal("unset fields should be omitted")
+	}
+}
+
+func TestMetadataMultipartRewritesInfoAndXMP(t *testing.T) {
+	svc := NewService(&config.Config{MaxFileSize: 1 << 20, MaxPages: 20, AuditRecord: true, AuditRecordOperations: "metadata"})
+	svc.tmpRoot = t.TempDir()
+	headers, err := pdftest.FileHeaders("file",
+		pdftest.File{Name: "scan.pdf", Data: pdftest.SyntheticWithInfo(1, map[string]string{"Title": "scan0001", "Author": "MFP"})},
+	)
+	if err != nil {
+		t.Fatalf("failed to build multipart files: %v", err)
+	}
+
+	spec := MetadataSpec{Set: map[MetadataField]string{MetadataTitle: "契約書 2026-10"}, Clear: []MetadataField{MetadataKeywords}}
+	result, err := svc.MetadataMultipart(context.Background(), headers[0], spec)
+	if err != nil {
+		t.Fatalf("MetadataMultipart returned error: %v", err)
+	}
+	defer result.Cleanup()
+	meta, ok := result.Meta.(*MetadataMeta)
+	if !ok || meta.Document != (DocumentMetadata{Title: "契約書 2026-10", Author: "MFP"}) {
+		t.Fatalf("unexpected meta: %+v", result.Meta)
+	}
+
+	// 処理記録で XMP を置き換えても、変更後の文書情報は残す
+	manifest := &JobManifest{JobID: result.JobID, Operation: OperationMetadata}
+	if err := svc.applyAuditRecord(context.Background(), manifest, result); err != nil {
+		t.Fatalf("applyAuditRecord returned error: %v", err)
+	}
+	data, err := os.ReadFile(result.OutputPath)
+	if err != nil {
+		t.Fatalf("failed to read output: %v", err)
+	}
+	packets := strings.Split(string(data), "<?xpacket begin=")
+	last := packets[len(packets)-1]
+	for _, want := range []string{"<pfa:JobID>", `<rdf:li xml:lang="x-default">契約書 2026-10</rdf:li>`, "<rdf:li>MFP</rdf:li>"} {
+		if !strings.Contains(last, want) {
+			t.Fatalf("final XMP packet lacks %q:\n%s", want, last)
+		}
+	}
+}
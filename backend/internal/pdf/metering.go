@@ -0,0 +1,65 @@
+package pdf
+
+import (
+	"context"
+	"time"
+)
+
+// UsageSample は1ジョブ分の利用量です（部署ごとの費用按分に使います）。
+type UsageSample struct {
+	Operation OperationType
+	// Pages と Bytes は入力ファイルの合計ページ数・合計サイズです。
+	Pages int
+	Bytes int64
+	// CPU はジョブの実行中にこのプロセスと子プロセス（Ghostscript 等）が使った CPU 時間です。
+	// 同時に実行中の他のジョブの分も含むため、並列度が高いほど多めに計上される近似値です。
+	CPU    time.Duration
+	Failed bool
+}
+
+// UsageRecorder はジョブの利用量を記録します。テナントは ctx から判別します。
+type UsageRecorder interface {
+	RecordUsage(ctx context.Context, sample UsageSample)
+}
+
+// UsageMeter は1ジョブ分の利用量を計測します。nil の UsageMeter は何も計測しません。
+type UsageMeter struct {
+	sample   UsageSample
+	startCPU time.Duration
+}
+
+// StartUsage はジョブ jobID の利用量の計測を開始します。RunJob の前に呼び出してください
+// （失敗したジョブはワークスペースごと削除され、入力量を読めなくなるため）。
+func (s *Service) StartUsage(jobID string) (*UsageMeter, error) {
+	manifest, err := loadManifest(s.fs, s.workspaceFor(jobID).dir)
+	if err != nil {
+		return nil, err
+	}
+	return startUsage(manifest), nil
+}
+
+func startUsage(manifest *JobManifest) *UsageMeter {
+	sample := UsageSample{Operation: manifest.Operation}
+	for _, f := range manifest.Files {
+		sample.Pages += f.Pages
+		sample.Bytes += f.Size
+	}
+	return &UsageMeter{sample: sample, startCPU: cpuTime()}
+}
+
+// Finish は計測を終了し、jobErr を失敗として反映した利用量を返します。
+// ページ数の確認を実行時に行ったジョブ（DEFER_PAGE_COUNT）は、result のページ数で補います。
+func (u *UsageMeter) Finish(result *Result, jobErr error) (UsageSample, bool) {
+	if u == nil {
+		return UsageSample{}, false
+	}
+	sample := u.sample
+	if result != nil && result.inputPages > sample.Pages {
+		sample.Pages = result.inputPages
+	}
+	if cpu := cpuTime() - u.startCPU; cpu > 0 {
+		sample.CPU = cpu
+	}
+	sample.Failed = jobErr != nil
+	return sample, true
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package pdf
+
+import "time"
+
+// cpuTime は CPU 時間を取得できない環境では常に0を返します。
+func cpuTime() time.Duration {
+	return 0
+}
@@ -0,0 +1,36 @@
+package pdf
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestUsageMeter(t *testing.T) {
+	meter := startUsage(&JobManifest{
+		Operation: OperationMerge,
+		Files: []JobFile{
+			{StoredName: "00.pdf", Size: 100, Pages: 3},
+			{StoredName: "01.pdf", Size: 250, Pages: 7},
+		},
+	})
+
+	sample, ok := meter.Finish(nil, nil)
+	if !ok {
+		t.Fatal("expected a sample")
+	}
+	if sample.Operation != OperationMerge || sample.Pages != 10 || sample.Bytes != 350 || sample.Failed {
+		t.Fatalf("unexpected sample: %+v", sample)
+	}
+	if sample.CPU < 0 {
+		t.Fatalf("cpu time must not be negative: %v", sample.CPU)
+	}
+
+	if failed, _ := meter.Finish(nil, errors.New("boom")); !failed.Failed {
+		t.Fatal("error should mark the sample as failed")
+	}
+
+	var missing *UsageMeter
+	if _, ok := missing.Finish(nil, nil); ok {
+		t.Fatal("nil meter should not produce a sample")
+	}
+}
@@ -0,0 +1,21 @@
+//go:build unix
+
+package pdf
+
+import (
+	"syscall"
+	"time"
+)
+
+// cpuTime はこのプロセスと終了済みの子プロセスが使った CPU 時間（ユーザー + システム）の合計を返します。
+func cpuTime() time.Duration {
+	var total time.Duration
+	for _, who := range []int{syscall.RUSAGE_SELF, syscall.RUSAGE_CHILDREN} {
+		var ru syscall.Rusage
+		if err := syscall.Getrusage(who, &ru); err != nil {
+			continue
+		}
+		total += time.Duration(ru.Utime.Nano()) + time.Duration(ru.Stime.Nano())
+	}
+	return total
+}
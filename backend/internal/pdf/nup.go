@@ -0,0 +1,302 @@
+package pdf
+
+import (
+	"context"
+	"fmt"
+	"mime/multipart"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	pdfapi "github.com/pdfcpu/pdfcpu/pkg/api"
+
+	"github.com/yourusername/paper-forge/internal/tenant"
+)
+
+const nupFilename = "nup.pdf"
+
+// 面付けの指定を省略した項目の既定値です。配布資料向けに A4 の1枚に2ページを並べます。
+const (
+	defaultNUpPerSheet = 2
+	defaultNUpPaper    = "a4"
+	defaultNUpMargin   = 5.0
+	// maxNUpMargin は余白（mm）の上限です。これより広いとページがほとんど見えなくなります。
+	maxNUpMargin = 30.0
+)
+
+// nupPapers は指定できる用紙サイズと pdfcpu の用紙名です。
+var nupPapers = map[string]string{
+	"a3":     "A3",
+	"a4":     "A4",
+	"a5":     "A5",
+	"letter": "Letter",
+	"legal":  "Legal",
+}
+
+// NUpSpec は面付け（1枚の用紙に複数のページを並べる）の指定です。
+type NUpSpec struct {
+	PerSheet int     `json:"perSheet"` // 1枚に並べるページ数（2 / 4）
+	Paper    string  `json:"paper"`    // 用紙サイズ（a3 / a4 / a5 / letter / legal）
+	Margin   float64 `json:"margin"`   // 並べた各ページの周囲の余白（mm）
+}
+
+// sheets は pages ページを面付けした用紙の枚数です。
+func (n NUpSpec) sheets(pages int) int {
+	return (pages + n.PerSheet - 1) / n.PerSheet
+}
+
+// description は pdfcpu の面付け記述を返します。
+// 2ページは横向きの用紙に左右、4ページは縦向きの用紙に2行2列で並べます。余白は pdfcpu の単位（ポイント）に換算します。
+func (n NUpSpec) description() string {
+	paper := nupPapers[n.Paper]
+	if n.PerSheet == 2 {
+		paper += "L"
+	}
+	margin := n.Margin * 72 / 25.4
+	return fmt.Sprintf("formsize:%s, margin:%s, border:off", paper, strconv.FormatFloat(margin, 'f', 2, 64))
+}
+
+// validateNUpSpec は面付けの指定が範囲内であることを確認します。
+func validateNUpSpec(n NUpSpec) error {
+	if n.PerSheet != 2 && n.PerSheet != 4 {
+		return newError("INVALID_INPUT", "perSheet は 2 または 4 で指定してください。", nil)
+	}
+	if _, ok := nupPapers[n.Paper]; !ok {
+		papers := make([]string, 0, len(nupPapers))
+		for name := range nupPapers {
+			papers = append(papers, name)
+		}
+		sort.Strings(papers)
+		return newError("INVALID_INPUT", fmt.Sprintf("paper は %s のいずれかで指定してください。", strings.Join(papers, ", ")), nil)
+	}
+	if n.Margin < 0 || n.Margin > maxNUpMargin {
+		return newError("INVALID_INPUT", fmt.Sprintf("margin は 0〜%g（mm）の範囲で指定してください。", maxNUpMargin), nil)
+	}
+	return nil
+}
+
+// parseNUpSpec はフォーム項目（perSheet, paper, margin）から面付けの指定を作成します。
+// 省略した項目は既定値を使います。値の範囲は validateNUpSpec で確認します。
+func parseNUpSpec(values url.Values) (NUpSpec, error) {
+	spec := NUpSpec{
+		PerSheet: defaultNUpPerSheet,
+		Paper:    defaultNUpPaper,
+		Margin:   defaultNUpMargin,
+	}
+	if raw := strings.TrimSpace(values.Get("perSheet")); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil {
+			return NUpSpec{}, newError("INVALID_INPUT", "perSheet は整数で指定してください。", nil)
+		}
+		spec.PerSheet = v
+	}
+	if raw := strings.TrimSpace(values.Get("paper")); raw != "" {
+		spec.Paper = strings.ToLower(raw)
+	}
+	if raw := strings.TrimSpace(values.Get("margin")); raw != "" {
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return NUpSpec{}, newError("INVALID_INPUT", "margin は数値（mm）で指定してください。", nil)
+		}
+		spec.Margin = v
+	}
+	return spec, nil
+}
+
+// NUpMultipart は単一PDFのページを、1枚の用紙に2ページまたは4ページずつ並べたPDFを作成します。
+func (s *Service) NUpMultipart(ctx context.Context, file *multipart.FileHeader, spec NUpSpec) (_ *Result, err error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if file == nil {
+		return nil, newError("INVALID_INPUT", "PDFファイルを選択してください。", nil)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	state, _, err := s.prepareNUp(ctx, file, spec, JobOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = removeDir(state.ws.dir)
+		}
+	}()
+
+	result, execErr := s.executeNUp(ctx, state, nil)
+	if execErr != nil {
+		return nil, execErr
+	}
+	return result, nil
+}
+
+type nupState struct {
+	ws   workspace
+	file storedFile
+	spec NUpSpec
+}
+
+func (s *Service) prepareNUp(ctx context.Context, file *multipart.FileHeader, spec NUpSpec, opts JobOptions) (*nupState, *JobManifest, error) {
+	if err := validateNUpSpec(spec); err != nil {
+		return nil, nil, err
+	}
+
+	release, err := s.reserveWorkspace(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer release()
+
+	ws, err := s.createWorkspace()
+	if err != nil {
+		return nil, nil, err
+	}
+	stored, err := s.storeMultipartFile(ctx, file, ws.inDir, 0, false)
+	if err != nil {
+		_ = removeDir(ws.dir)
+		return nil, nil, err
+	}
+
+	manifest := &JobManifest{
+		JobID:     ws.jobID,
+		Tenant:    tenant.FromContext(ctx),
+		Operation: OperationNUp,
+		Files:     toJobFiles([]storedFile{stored}),
+		NUp:       &spec,
+		Options:   opts,
+		StoreMs:   ws.elapsed().Milliseconds(),
+		CreatedAt: s.now().UTC(),
+	}
+	if err := writeManifest(s.fs, ws.dir, manifest); err != nil {
+		_ = removeDir(ws.dir)
+		return nil, nil, fmt.Errorf("ジョブマニフェストの保存に失敗しました: %w", err)
+	}
+
+	return &nupState{ws: ws, file: stored, spec: spec}, manifest, nil
+}
+
+func (s *Service) executeNUp(ctx context.Context, state *nupState, progress ProgressReporter) (*Result, error) {
+	ws := state.ws
+	stored := state.file
+
+	reportProgress(progress, StageProcess, 0)
+	outputPath := filepath.Join(ws.outDir, nupFilename)
+	if err := runStep(ctx, func() error {
+		nup, err := pdfapi.PDFNUpConfig(state.spec.PerSheet, state.spec.description(), nil)
+		if err != nil {
+			return err
+		}
+		return pdfapi.NUpFile([]string{stored.path}, outputPath, nil, nup, nil)
+	}); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		return nil, newError("UNSUPPORTED_PDF", "PDFの面付けに失敗しました。ファイルが破損していないか確認してください。", err)
+	}
+	reportProgress(progress, StageWrite, 0)
+
+	sourceMeta := SourceFileMeta{
+		Name:  stored.originalName,
+		Size:  stored.size,
+		Pages: stored.pages,
+	}
+	sheets := state.spec.sheets(stored.pages)
+
+	meta := struct {
+		Type      OperationType  `json:"type"`
+		CreatedAt string         `json:"createdAt"`
+		Source    SourceFileMeta `json:"source"`
+		NUp       NUpSpec        `json:"nup"`
+		Output    string         `json:"output"`
+		Sheets    int            `json:"sheets"`
+	}{
+		Type:      OperationNUp,
+		CreatedAt: s.now().UTC().Format(time.RFC3339),
+		Source:    sourceMeta,
+		NUp:       state.spec,
+		Output:    nupFilename,
+		Sheets:    sheets,
+	}
+
+	metaPath := filepath.Join(ws.dir, "meta.json")
+	if err := writeJSON(s.fs, metaPath, meta); err != nil {
+		return nil, fmt.Errorf("メタデータの保存に失敗しました: %w", err)
+	}
+
+	s.scheduleCleanup(ws.dir)
+
+	artifact, err := newArtifact(outputPath, ResultKindPDF)
+	if err != nil {
+		return nil, fmt.Errorf("成果物の確認に失敗しました: %w", err)
+	}
+
+	reportProgress(progress, StageCompleted, 1)
+
+	return newResult(ws, OperationNUp, s.fs, []Artifact{artifact}, &NUpMeta{
+		Original: sourceMeta,
+		NUp:      state.spec,
+		Sheets:   sheets,
+	}), nil
+}
+
+// PrepareNUpJob は非同期ジョブ用に入力を保存します。
+func (s *Service) PrepareNUpJob(ctx context.Context, file *multipart.FileHeader, spec NUpSpec, opts JobOptions) (*JobManifest, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	_, manifest, err := s.prepareNUp(ctx, file, spec, opts)
+	if err != nil {
+		return nil, err
+	}
+	return s.sealJob(manifest)
+}
+
+func init() {
+	registerOperation(nupOperation{})
+}
+
+type nupOperation struct{}
+
+func (nupOperation) Type() OperationType { return OperationNUp }
+
+func (nupOperation) OutputSpec() OutputSpec {
+	return OutputSpec{Filename: nupFilename, Kind: ResultKindPDF}
+}
+
+func (nupOperation) Prepare(ctx context.Context, s *Service, req PrepareRequest) (*JobManifest, error) {
+	file, err := req.singleFile()
+	if err != nil {
+		return nil, err
+	}
+	spec, err := parseNUpSpec(req.Params)
+	if err != nil {
+		return nil, err
+	}
+	return s.PrepareNUpJob(ctx, file, spec, req.Options)
+}
+
+func (nupOperation) Execute(ctx context.Context, s *Service, job *jobInput, progress ProgressReporter) (*Result, error) {
+	if job.manifest.NUp == nil {
+		return nil, newError("INVALID_INPUT", "面付けの指定がありません。", nil)
+	}
+	state := &nupState{
+		ws:   job.ws,
+		file: job.files[0],
+		spec: *job.manifest.NUp,
+	}
+	return s.executeNUp(ctx, state, progress)
+}
+
+func (nupOperation) Plan(s *Service, job *jobInput) (*JobPlan, error) {
+	if job.manifest.NUp == nil {
+		return nil, newError("INVALID_INPUT", "面付けの指定がありません。", nil)
+	}
+	sheets := job.manifest.NUp.sheets(job.files[0].pages)
+	return &JobPlan{Outputs: []PlannedOutput{{Name: nupFilename, Kind: ResultKindPDF, Pages: sheets}}}, nil
+}
@@ -0,0 +1,112 @@
+package pdf
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"github.com/yourusername/paper-forge/internal/config"
+	"github.com/yourusername/paper-forge/internal/pdf/pdftest"
+)
+
+func TestParseNUpSpec(t *testing.T) {
+	spec, err := parseNUpSpec(url.Values{})
+	if err != nil || spec != (NUpSpec{PerSheet: 2, Paper: "a4", Margin: 5}) {
+		t.Fatalf("defaults should apply: %+v, %v", spec, err)
+	}
+	spec, err = parseNUpSpec(url.Values{"perSheet": {"4"}, "paper": {" Letter "}, "margin": {"12.5"}})
+	if err != nil || spec != (NUpSpec{PerSheet: 4, Paper: "letter", Margin: 12.5}) {
+		t.Fatalf("unexpected spec: %+v, %v", spec, err)
+	}
+
+	for _, values := range []url.Values{
+		{"perSheet": {"two"}},
+		{"margin": {"1cm"}},
+	} {
+		if _, err := parseNUpSpec(values); !IsError(err, "INVALID_INPUT") {
+			t.Fatalf("%v: expected INVALID_INPUT, got %v", values, err)
+		}
+	}
+	for _, spec := range []NUpSpec{
+		{PerSheet: 3, Paper: "a4"},
+		{PerSheet: 2, Paper: "b4"},
+		{PerSheet: 4, Paper: "a4", Margin: -1},
+		{PerSheet: 4, Paper: "a4", Margin: maxNUpMargin + 1},
+	} {
+		if err := validateNUpSpec(spec); !IsError(err, "INVALID_INPUT") {
+			t.Fatalf("%+v: expected INVALID_INPUT, got %v", spec, err)
+		}
+	}
+}
+
+func TestNUpSpecDescription(t *testing.T) {
+	// 2ページは横向き、4ページは縦向きの用紙に並べ、余白は mm からポイントに換算する
+	if got := (NUpSpec{PerSheet: 2, Paper: "a4", Margin: 10}).description(); got != "formsize:A4L, margin:28.35, border:off" {
+		t.Fatalf("unexpected 2-up description: %q", got)
+	}
+	if got := (NUpSpec{PerSheet: 4, Paper: "letter"}).description(); got != "formsize:Letter, margin:0.00, border:off" {
+		t.Fatalf("unexpected 4-up description: %q", got)
+	}
+	if got := (NUpSpec{PerSheet: 4}).sheets(9); got != 3 {
+		t.Fatalf("9 pages on 4-up should take 3 sheets, got %d", got)
+	}
+}
+
+func TestPrepareNUpJobThroughRegistry(t *testing.T) {
+	svc := NewService(&config.Config{MaxFileSize: 1 << 20, MaxPages: 20})
+	svc.tmpRoot = t.TempDir()
+	headers, err := pdftest.FileHeaders("file", pdftest.File{Name: "handout.pdf", Data: pdftest.Synthetic(5, 0)})
+	if err != nil {
+		t.Fatalf("failed to build multipart files: %v", err)
+	}
+
+	manifest, err := svc.PrepareJob(context.Background(), OperationNUp, PrepareRequest{
+		Files:  headers,
+		Params: url.Values{"perSheet": {"4"}, "paper": {"a3"}},
+	})
+	if err != nil {
+		t.Fatalf("PrepareJob returned error: %v", err)
+	}
+	if manifest.NUp == nil || *manifest.NUp != (NUpSpec{PerSheet: 4, Paper: "a3", Margin: defaultNUpMargin}) {
+		t.Fatalf("unexpected manifest: %+v", manifest.NUp)
+	}
+
+	plan, err := svc.PlanJob(context.Background(), manifest.JobID)
+	if err != nil {
+		t.Fatalf("PlanJob returned error: %v", err)
+	}
+	if plan.Outputs[0].Name != nupFilename || plan.Outputs[0].Pages != 2 {
+		t.Fatalf("unexpected plan: %+v", plan.Outputs)
+	}
+
+	_, err = svc.PrepareJob(context.Background(), OperationNUp, PrepareRequest{
+		Files:  headers,
+		Params: url.Values{"perSheet": {"8"}},
+	})
+	if !IsError(err, "INVALID_INPUT") {
+		t.Fatalf("unsupported page counts per sheet should be rejected, got %v", err)
+	}
+}
+
+func TestNUpCacheKeyFollowsSpec(t *testing.T) {
+	base := &JobManifest{
+		Operation: OperationNUp,
+		Files:     []JobFile{{SHA256: "abc"}},
+		NUp:       &NUpSpec{PerSheet: 2, Paper: "a4", Margin: 5},
+	}
+	key, ok := resultCacheKey(base)
+	if !ok {
+		t.Fatal("nup should be cacheable")
+	}
+	for _, spec := range []NUpSpec{
+		{PerSheet: 4, Paper: "a4", Margin: 5},
+		{PerSheet: 2, Paper: "a3", Margin: 5},
+		{PerSheet: 2, Paper: "a4", Margin: 10},
+	} {
+		other := *base
+		other.NUp = &spec
+		if got, _ := resultCacheKey(&other); got == key {
+			t.Fatalf("%+v should change the cache key", spec)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package pdf
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/yourusername/paper-forge/internal/execrun"
+)
+
+// ocrNonLanguageModels は tesseract --list-langs に含まれるが、言語として選択させないモデルです。
+var ocrNonLanguageModels = map[string]bool{
+	"osd": true, // ページの向き・文字種の判定用
+	"equ": true, // 数式検出用
+}
+
+// DiscoverOCRLanguages は TESSERACT_PATH の tesseract にインストールされている言語パックを調べ、
+// 結果を Capabilities と言語指定の検証に使えるよう保持します。TESSERACT_PATH が未設定の場合は何もしません。
+// 起動時に一度呼び出す想定で、言語パックを追加した場合は再起動で反映されます。
+// LAZY_INIT が有効な場合は呼び出さなくても、最初に言語パックを参照したときに調べます。
+func (s *Service) DiscoverOCRLanguages(ctx context.Context) ([]string, error) {
+	if s.cfg.TesseractPath == "" {
+		return nil, nil
+	}
+	path, err := exec.LookPath(s.cfg.TesseractPath)
+	if err != nil {
+		return nil, fmt.Errorf("tesseract が見つかりません (%s): %w", s.cfg.TesseractPath, err)
+	}
+	out, err := s.runner.Run(ctx, execrun.Command{Name: path, Args: []string{"--list-langs"}})
+	if err != nil {
+		return nil, fmt.Errorf("tesseract の言語パック一覧の取得に失敗しました (%s): %w", path, err)
+	}
+	// バージョンによって一覧を標準エラーへ出力するものがあるため両方を見る
+	langs := parseTesseractLanguages(string(out.Stdout) + "\n" + string(out.Stderr))
+
+	s.ocrMu.Lock()
+	s.ocrLangs = langs
+	s.ocrMu.Unlock()
+	return append([]string(nil), langs...), nil
+}
+
+// parseTesseractLanguages は tesseract --list-langs の出力から言語コードを取り出します。
+// 1行目の見出し（List of available languages ...）と言語以外のモデルは除きます。
+func parseTesseractLanguages(output string) []string {
+	seen := make(map[string]bool)
+	var langs []string
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.ContainsAny(line, " \t:\"") || ocrNonLanguageModels[line] || seen[line] {
+			continue
+		}
+		seen[line] = true
+		langs = append(langs, line)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// ocrLanguages はインストール済みの言語パックを返します。未調査または OCR が無効の場合は空です。
+func (s *Service) ocrLanguages() []string {
+	if s.cfg.LazyInit {
+		s.ocrOnce.Do(s.discoverOCRLanguagesOnFirstUse)
+	}
+	s.ocrMu.RLock()
+	defer s.ocrMu.RUnlock()
+	return append([]string{}, s.ocrLangs...)
+}
+
+// ValidateOCRLanguages は lang パラメータ（"jpn+eng" のように + 区切りで複数指定可）を
+// インストール済みの言語パックと照合し、重複を除いた tesseract へ渡す形式の文字列を返します。
+func (s *Service) ValidateOCRLanguages(raw string) (string, error) {
+	installed := s.ocrLanguages()
+	if len(installed) == 0 {
+		return "", newError("OCR_UNAVAILABLE", "OCR に使用できる言語パックがインストールされていません。", nil)
+	}
+	available := make(map[string]bool, len(installed))
+	for _, lang := range installed {
+		available[lang] = true
+	}
+
+	var (
+		langs   []string
+		unknown []string
+		seen    = make(map[string]bool)
+	)
+	for _, part := range strings.Split(raw, "+") {
+		// 言語パックは chi_sim のように小文字の名前で配布されるため、指定は大文字小文字を区別しない
+		lang := strings.ToLower(strings.TrimSpace(part))
+		if lang == "" || seen[lang] {
+			continue
+		}
+		seen[lang] = true
+		if !available[lang] {
+			unknown = append(unknown, lang)
+			continue
+		}
+		langs = append(langs, lang)
+	}
+	if len(unknown) > 0 {
+		return "", newError("UNSUPPORTED_OCR_LANGUAGE", fmt.Sprintf("OCR 言語 %s は利用できません。利用できる言語: %s", strings.Join(unknown, ", "), strings.Join(installed, ", ")), nil)
+	}
+	if len(langs) == 0 {
+		return "", newError("INVALID_INPUT", "OCR の言語を指定してください。例: jpn または jpn+eng", nil)
+	}
+	return strings.Join(langs, "+"), nil
+}
+
+// discoverOCRLanguagesOnFirstUse は LAZY_INIT が有効な場合に、起動時の代わりに言語パックを調べます。
+func (s *Service) discoverOCRLanguagesOnFirstUse() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := s.DiscoverOCRLanguages(ctx); err != nil {
+		log.Printf("OCR language discovery failed; no OCR languages will be advertised: %v", err)
+	}
+}
@@ -0,0 +1,56 @@
+package pdf
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/yourusername/paper-forge/internal/config"
+	"github.com/yourusername/paper-forge/internal/execrun"
+)
+
+func TestParseTesseractLanguages(t *testing.T) {
+	output := "List of available languages in \"/usr/share/tesseract-ocr/5/tessdata/\" (4):\neng\njpn\nosd\nchi_sim\n"
+	want := []string{"chi_sim", "eng", "jpn"}
+	if got := parseTesseractLanguages(output); !reflect.DeepEqual(got, want) {
+		t.Fatalf("parseTesseractLanguages = %v, want %v", got, want)
+	}
+}
+
+func TestValidateOCRLanguages(t *testing.T) {
+	svc := NewServiceWithFS(&config.Config{}, newMemFS())
+	if _, err := svc.ValidateOCRLanguages("eng"); !IsError(err, "OCR_UNAVAILABLE") {
+		t.Fatalf("expected OCR_UNAVAILABLE without language packs, got %v", err)
+	}
+
+	svc.ocrLangs = []string{"eng", "jpn"}
+	got, err := svc.ValidateOCRLanguages(" JPN+eng+jpn ")
+	if err != nil {
+		t.Fatalf("ValidateOCRLanguages returned error: %v", err)
+	}
+	if got != "jpn+eng" {
+		t.Fatalf("unexpected languages: %q", got)
+	}
+	if _, err := svc.ValidateOCRLanguages("jpn+fra"); !IsError(err, "UNSUPPORTED_OCR_LANGUAGE") {
+		t.Fatalf("expected UNSUPPORTED_OCR_LANGUAGE, got %v", err)
+	}
+	if _, err := svc.ValidateOCRLanguages("+"); !IsError(err, "INVALID_INPUT") {
+		t.Fatalf("expected INVALID_INPUT, got %v", err)
+	}
+	if langs := svc.Capabilities().OCRLanguages; !reflect.DeepEqual(langs, []string{"eng", "jpn"}) {
+		t.Fatalf("capabilities should advertise installed languages: %v", langs)
+	}
+}
+
+func TestOCRLanguagesDiscoveredOnFirstUseWhenLazy(t *testing.T) {
+	runner := &stubRunner{out: execrun.Output{Stdout: []byte("List of available languages (2):\neng\njpn\n")}}
+	svc := NewServiceWithFS(&config.Config{LazyInit: true, TesseractPath: "sh"}, newMemFS())
+	svc.runner = runner
+
+	if langs := svc.Capabilities().OCRLanguages; !reflect.DeepEqual(langs, []string{"eng", "jpn"}) {
+		t.Fatalf("languages should be discovered on first use: %v", langs)
+	}
+	svc.ocrLanguages()
+	if len(runner.calls) != 1 {
+		t.Fatalf("tesseract should run once, ran %d times", len(runner.calls))
+	}
+}
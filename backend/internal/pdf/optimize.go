@@ -1,18 +1,29 @@
 package pdf
 
 import (
-	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"log"
 	"mime/multipart"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 	"time"
+
+	pdfapi "github.com/pdfcpu/pdfcpu/pkg/api"
+
+	"github.com/yourusername/paper-forge/internal/execrun"
+	"github.com/yourusername/paper-forge/internal/tenant"
 )
 
-const optimizedFilename = "optimized.pdf"
+const (
+	optimizedFilename      = "optimized.pdf"
+	ghostscriptLogFilename = "ghostscript.log"
+	// maxToolLogBytes はワークスペースに書き出す外部コマンドのログの最大サイズです。
+	maxToolLogBytes = 16 * 1024 * 1024
+)
 
 // OptimizeMultipart は Ghostscript を利用してPDFを圧縮します。
 func (s *Service) OptimizeMultipart(ctx context.Context, file *multipart.FileHeader, preset OptimizePreset) (_ *Result, err error) {
@@ -32,7 +43,7 @@ func (s *Service) OptimizeMultipart(ctx context.Context, file *multipart.FileHea
 		return nil, err
 	}
 
-	state, _, err := s.prepareOptimize(ctx, file, preset)
+	state, _, err := s.prepareOptimize(ctx, file, preset, "", "", JobOptions{})
 	if err != nil {
 		return nil, err
 	}
@@ -53,59 +64,149 @@ type optimizeState struct {
 	ws     workspace
 	file   storedFile
 	preset OptimizePreset
+	// pagesRaw は圧縮対象のページ範囲指定です。空の場合は全ページを圧縮します。
+	pagesRaw string
+	// level は Ghostscript の -dCompatibilityLevel です（pdfVersion / OUTPUT_PDF_VERSION）。空の場合は 1.5 です。
+	level string
+	// colorProfile は色を変換して埋め込む ICC プロファイルです。空の場合は変換しません。
+	colorProfile ColorProfile
+}
+
+// ghostscriptOptions は圧縮の設定から Ghostscript の実行時の設定を作ります。
+func (s *Service) ghostscriptOptions(state *optimizeState) ghostscriptOptions {
+	opts := ghostscriptOptions{preset: state.preset, level: state.level}
+	if spec, ok := s.colorProfiles[state.colorProfile]; ok {
+		opts.color = &spec
+	}
+	return opts
 }
 
-func (s *Service) prepareOptimize(ctx context.Context, file *multipart.FileHeader, preset OptimizePreset) (*optimizeState, *JobManifest, error) {
+func (s *Service) prepareOptimize(ctx context.Context, file *multipart.FileHeader, preset OptimizePreset, pages, colorProfile string, opts JobOptions) (*optimizeState, *JobManifest, error) {
+	profile, err := s.resolveColorProfile(colorProfile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	release, err := s.reserveWorkspace(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer release()
+
 	ws, err := s.createWorkspace()
 	if err != nil {
 		return nil, nil, err
 	}
 
-	stored, err := s.storeMultipartFile(ctx, file, ws.inDir, 0)
+	stored, err := s.storeMultipartFile(ctx, file, ws.inDir, 0, false)
 	if err != nil {
 		_ = removeDir(ws.dir)
 		return nil, nil, err
 	}
 
+	pages = strings.TrimSpace(pages)
+	if pages != "" {
+		if _, err := parsePageRanges(pages, stored.pages); err != nil {
+			_ = removeDir(ws.dir)
+			return nil, nil, err
+		}
+	}
+
 	manifest := &JobManifest{
-		JobID:     ws.jobID,
-		Operation: OperationOptimize,
-		Files:     toJobFiles([]storedFile{stored}),
-		Preset:    preset,
-		CreatedAt: s.now().UTC(),
+		JobID:        ws.jobID,
+		Tenant:       tenant.FromContext(ctx),
+		Operation:    OperationOptimize,
+		Files:        toJobFiles([]storedFile{stored}),
+		Ranges:       pages,
+		Preset:       preset,
+		ColorProfile: profile,
+		Options:      opts,
+		StoreMs:      ws.elapsed().Milliseconds(),
+		CreatedAt:    s.now().UTC(),
 	}
-	if err := writeManifest(ws.dir, manifest); err != nil {
+	if err := writeManifest(s.fs, ws.dir, manifest); err != nil {
 		_ = removeDir(ws.dir)
 		return nil, nil, fmt.Errorf("ジョブマニフェストの保存に失敗しました: %w", err)
 	}
 
-	return &optimizeState{ws: ws, file: stored, preset: preset}, manifest, nil
+	return &optimizeState{ws: ws, file: stored, preset: preset, pagesRaw: pages, level: s.outputPDFVersion(opts), colorProfile: profile}, manifest, nil
 }
 
 func (s *Service) executeOptimize(ctx context.Context, state *optimizeState, progress ProgressReporter) (*Result, error) {
 	ws := state.ws
 	stored := state.file
 
-	reportProgress(progress, "process", 40)
+	reportProgress(progress, StageProcess, 0)
+
+	var ranges []PageRange
+	if state.pagesRaw != "" {
+		parsed, err := parsePageRanges(state.pagesRaw, stored.pages)
+		if err != nil {
+			return nil, err
+		}
+		ranges = parsed
+	}
 
 	outputPath := filepath.Join(ws.outDir, optimizedFilename)
-	if err := s.runGhostscript(ctx, stored.path, outputPath, state.preset); err != nil {
+	logPath := filepath.Join(ws.dir, ghostscriptLogFilename)
+	var (
+		warnings []string
+		err      error
+	)
+	if coversAllPages(ranges, stored.pages) {
+		warnings, err = s.runGhostscript(ctx, stored.path, outputPath, logPath, s.ghostscriptOptions(state))
+	} else {
+		warnings, err = s.optimizePageRanges(ctx, state, ranges, outputPath, logPath)
+	}
+	if err != nil {
 		return nil, err
 	}
+	if spec, ok := s.colorProfiles[state.colorProfile]; ok {
+		if err := embedOutputIntent(outputPath, spec); err != nil {
+			return nil, fmt.Errorf("出力インテントの埋め込みに失敗しました: %w", err)
+		}
+	}
+	if len(warnings) > 0 {
+		log.Printf("ghostscript reported %d warning(s) for job %s: %s", len(warnings), ws.jobID, strings.Join(warnings, " | "))
+	}
 
-	reportProgress(progress, "write", 80)
+	reportProgress(progress, StageWrite, 0)
 
 	outInfo, err := os.Stat(outputPath)
 	if err != nil {
 		return nil, fmt.Errorf("圧縮後ファイルの確認に失敗しました: %w", err)
 	}
 
+	// 設定によっては圧縮後の方が大きくなることがあるため、悪化したファイルを黙って返さない
+	// 色を変換した場合は、元のファイルに戻すと変換されないため差し戻さない
+	noGainApplied := false
+	if outInfo.Size() >= stored.size {
+		if s.cfg.OptimizeKeepOriginal && state.colorProfile == "" {
+			if err := replaceWithOriginal(stored.path, outputPath); err != nil {
+				return nil, fmt.Errorf("元ファイルへの差し戻しに失敗しました: %w", err)
+			}
+			if outInfo, err = os.Stat(outputPath); err != nil {
+				return nil, fmt.Errorf("圧縮後ファイルの確認に失敗しました: %w", err)
+			}
+			noGainApplied = true
+		} else {
+			warnings = append([]string{fmt.Sprintf(
+				"圧縮後のファイル (%d バイト) が元のファイル (%d バイト) より大きくなりました。",
+				outInfo.Size(), stored.size,
+			)}, warnings...)
+		}
+	}
+
 	meta := &OptimizeMeta{
-		OriginalSize: stored.size,
-		OutputSize:   outInfo.Size(),
-		SavedBytes:   stored.size - outInfo.Size(),
-		SavedPercent: computeSavedPercent(stored.size, outInfo.Size()),
-		Preset:       state.preset,
+		OriginalSize:  stored.size,
+		OutputSize:    outInfo.Size(),
+		SavedBytes:    stored.size - outInfo.Size(),
+		SavedPercent:  computeSavedPercent(stored.size, outInfo.Size()),
+		Preset:        state.preset,
+		ColorProfile:  state.colorProfile,
+		Warnings:      warnings,
+		NoGainApplied: noGainApplied,
+		Pages:         ranges,
 		Source: SourceFileMeta{
 			Name:  stored.originalName,
 			Size:  stored.size,
@@ -114,16 +215,20 @@ func (s *Service) executeOptimize(ctx context.Context, state *optimizeState, pro
 	}
 
 	metaPayload := struct {
-		Type      OperationType `json:"type"`
-		CreatedAt string        `json:"createdAt"`
-		Preset    OptimizePreset
-		Sizes     struct {
+		Type         OperationType `json:"type"`
+		CreatedAt    string        `json:"createdAt"`
+		Preset       OptimizePreset
+		ColorProfile ColorProfile `json:"colorProfile,omitempty"`
+		Sizes        struct {
 			Before int64   `json:"before"`
 			After  int64   `json:"after"`
 			Saved  int64   `json:"saved"`
 			Ratio  float64 `json:"ratio"`
 		} `json:"sizes"`
-		Source SourceFileMeta `json:"source"`
+		Source        SourceFileMeta `json:"source"`
+		Warnings      []string       `json:"warnings,omitempty"`
+		NoGainApplied bool           `json:"noGainApplied,omitempty"`
+		Pages         []PageRange    `json:"pages,omitempty"`
 	}{
 		Type:      OperationOptimize,
 		CreatedAt: s.now().UTC().Format(time.RFC3339),
@@ -134,36 +239,30 @@ func (s *Service) executeOptimize(ctx context.Context, state *optimizeState, pro
 	metaPayload.Sizes.Saved = meta.SavedBytes
 	metaPayload.Sizes.Ratio = meta.SavedPercent
 	metaPayload.Source = meta.Source
+	metaPayload.Warnings = warnings
+	metaPayload.NoGainApplied = noGainApplied
+	metaPayload.Pages = ranges
+	metaPayload.ColorProfile = state.colorProfile
 
 	metaPath := filepath.Join(ws.dir, "meta.json")
-	if err := writeJSON(metaPath, metaPayload); err != nil {
+	if err := writeJSON(s.fs, metaPath, metaPayload); err != nil {
 		return nil, fmt.Errorf("メタデータの保存に失敗しました: %w", err)
 	}
 
-	expireMinutes := s.cfg.JobExpireMinutes
-	if expireMinutes <= 0 {
-		expireMinutes = defaultCleanupMin
+	s.scheduleCleanup(ws.dir)
+
+	artifact, err := newArtifact(outputPath, ResultKindPDF)
+	if err != nil {
+		return nil, fmt.Errorf("成果物の確認に失敗しました: %w", err)
 	}
-	time.AfterFunc(time.Duration(expireMinutes)*time.Minute, func() {
-		_ = removeDir(ws.dir)
-	})
 
-	reportProgress(progress, "completed", 100)
+	reportProgress(progress, StageCompleted, 1)
 
-	return &Result{
-		JobID:          ws.jobID,
-		Operation:      OperationOptimize,
-		OutputPath:     outputPath,
-		OutputFilename: optimizedFilename,
-		OutputSize:     outInfo.Size(),
-		ResultKind:     ResultKindPDF,
-		Meta:           meta,
-		jobDir:         ws.dir,
-	}, nil
+	return newResult(ws, OperationOptimize, s.fs, []Artifact{artifact}, meta), nil
 }
 
-// PrepareOptimizeJob は非同期ジョブを準備します。
-func (s *Service) PrepareOptimizeJob(ctx context.Context, file *multipart.FileHeader, preset OptimizePreset) (*JobManifest, error) {
+// PrepareOptimizeJob は非同期ジョブを準備します。colorProfile が空の場合は既定の ICC プロファイル（COLOR_PROFILE）を使います。
+func (s *Service) PrepareOptimizeJob(ctx context.Context, file *multipart.FileHeader, preset OptimizePreset, pages, colorProfile string, opts JobOptions) (*JobManifest, error) {
 	if ctx == nil {
 		ctx = context.Background()
 	}
@@ -171,11 +270,11 @@ func (s *Service) PrepareOptimizeJob(ctx context.Context, file *multipart.FileHe
 	if err != nil {
 		return nil, err
 	}
-	_, manifest, err := s.prepareOptimize(ctx, file, preset)
+	_, manifest, err := s.prepareOptimize(ctx, file, preset, pages, colorProfile, opts)
 	if err != nil {
 		return nil, err
 	}
-	return manifest, nil
+	return s.sealJob(manifest)
 }
 
 func normalizePreset(p OptimizePreset) (OptimizePreset, error) {
@@ -189,36 +288,246 @@ func normalizePreset(p OptimizePreset) (OptimizePreset, error) {
 	}
 }
 
-func (s *Service) runGhostscript(ctx context.Context, inputPath, outputPath string, preset OptimizePreset) error {
-	args := ghostscriptArgs(outputPath, inputPath, preset)
+// runGhostscript は Ghostscript で圧縮を行い、成功時に出力された警告を返します。
+// 出力はメモリ上には末尾のみ保持し、全量は logPath へ上限付きで書き出します。
+func (s *Service) runGhostscript(ctx context.Context, inputPath, outputPath, logPath string, opts ghostscriptOptions) ([]string, error) {
+	return s.runGhostscriptCommand(ctx, execrun.Command{Args: ghostscriptArgs(outputPath, inputPath, opts)}, logPath)
+}
+
+// runGhostscriptPiped は write が書き出す PDF を標準入力から渡して Ghostscript で圧縮します。
+// 入力を一時ファイルに書き出さずに済みます。Ghostscript は標準入力の PDF を一時ファイルに読み込んでから処理するため、
+// その一時ファイルはワークスペース内の tmpDir に作らせます（ジョブの削除とともに消え、ワークスペースの外に内容を残さない）。
+func (s *Service) runGhostscriptPiped(ctx context.Context, write func(w io.Writer) error, outputPath, logPath, tmpDir string, opts ghostscriptOptions) ([]string, error) {
+	pr, pw := io.Pipe()
+	type ghostscriptRun struct {
+		warnings []string
+		err      error
+	}
+	done := make(chan ghostscriptRun, 1)
+	go func() {
+		warnings, err := s.runGhostscriptCommand(ctx, execrun.Command{
+			Args:  ghostscriptArgs(outputPath, "-", opts),
+			Stdin: pr,
+			Env:   []string{"TMPDIR=" + tmpDir},
+		}, logPath)
+		// 入力を読み終える前に Ghostscript が終了した場合に、書き込み側を待たせたままにしない
+		_ = pr.CloseWithError(errGhostscriptExited)
+		done <- ghostscriptRun{warnings: warnings, err: err}
+	}()
+
+	waited := false
+	defer func() {
+		// 書き出しの途中でパニックした場合も、Ghostscript の終了を待ってから呼び出し元へ伝える
+		if !waited {
+			_ = pw.CloseWithError(errGhostscriptInputAborted)
+			<-done
+		}
+	}()
+	writeErr := runStep(ctx, func() error { return write(pw) })
+	_ = pw.CloseWithError(writeErr)
+	run := <-done
+	waited = true
+
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return nil, ctxErr
+	}
+	if writeErr != nil && !errors.Is(writeErr, errGhostscriptExited) {
+		return nil, newError("UNSUPPORTED_PDF", "圧縮対象ページの切り出しに失敗しました。", writeErr)
+	}
+	if run.err != nil {
+		return nil, run.err
+	}
+	if writeErr != nil {
+		return nil, newError("UNSUPPORTED_PDF", "Ghostscriptによる圧縮に失敗しました。ファイルが破損していないか確認してください。", writeErr)
+	}
+	return run.warnings, nil
+}
 
-	cmd := exec.CommandContext(ctx, s.cfg.GhostscriptPath, args...)
-	var stderr bytes.Buffer
-	cmd.Stdout = &stderr
-	cmd.Stderr = &stderr
+var (
+	errGhostscriptExited       = errors.New("Ghostscript が入力を読み終える前に終了しました")
+	errGhostscriptInputAborted = errors.New("Ghostscript への入力の書き出しが中断されました")
+)
 
-	if err := cmd.Run(); err != nil {
-		return newError("UNSUPPORTED_PDF", fmt.Sprintf("Ghostscriptによる圧縮に失敗しました: %s", stderr.String()), err)
+// runGhostscriptCommand は cmd の実行ファイルを解決済みの Ghostscript にして実行します。
+func (s *Service) runGhostscriptCommand(ctx context.Context, cmd execrun.Command, logPath string) ([]string, error) {
+	gsPath, err := s.gs.resolve()
+	if err != nil {
+		return nil, err
+	}
+	cmd.Name = gsPath
+	if logPath != "" {
+		// ページ範囲ごとに複数回実行する場合もあるため追記する
+		logFile, err := s.fs.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o640)
+		if err != nil {
+			return nil, fmt.Errorf("Ghostscriptのログファイルを作成できませんでした: %w", err)
+		}
+		defer logFile.Close()
+		cmd.Log = execrun.CappedWriter(logFile, maxToolLogBytes)
 	}
-	return nil
+	out, err := s.runner.Run(ctx, cmd)
+	if err != nil {
+		// クライアント切断などでキャンセルされた場合は変換失敗ではなくキャンセルとして返す
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		return nil, newError("UNSUPPORTED_PDF", "Ghostscriptによる圧縮に失敗しました。ファイルが破損していないか確認してください。", fmt.Errorf("%w: %s", err, out.Combined()))
+	}
+	return parseGhostscriptWarnings(out.Combined()), nil
 }
 
-func ghostscriptArgs(outputPath, inputPath string, preset OptimizePreset) []string {
+// ghostscriptOptions は Ghostscript の実行ごとの設定です。
+type ghostscriptOptions struct {
+	preset OptimizePreset
+	// level は -dCompatibilityLevel です。空の場合は 1.5 です。
+	level string
+	// color は色の変換に使う ICC プロファイルです。nil の場合は変換しません。
+	color *colorProfileSpec
+}
+
+func ghostscriptArgs(outputPath, inputPath string, opts ghostscriptOptions) []string {
 	setting := "/printer"
-	if preset == OptimizePresetAggressive {
+	if opts.preset == OptimizePresetAggressive {
 		setting = "/screen"
 	}
+	level := opts.level
+	if level == "" {
+		level = defaultCompatibilityLevel
+	}
 
-	return []string{
+	args := []string{
 		"-sDEVICE=pdfwrite",
-		"-dCompatibilityLevel=1.5",
+		"-dCompatibilityLevel=" + level,
 		"-dNOPAUSE",
 		"-dQUIET",
 		"-dBATCH",
 		fmt.Sprintf("-dPDFSETTINGS=%s", setting),
+	}
+	if opts.color != nil {
+		args = append(args, opts.color.ghostscriptArgs()...)
+	}
+	return append(args,
 		fmt.Sprintf("-sOutputFile=%s", outputPath),
 		inputPath,
+	)
+}
+
+// optimizeSegment は圧縮時に切り出すページ範囲と、その範囲を圧縮するかどうかを表します。
+type optimizeSegment struct {
+	PageRange
+	optimize bool
+}
+
+// coversAllPages は範囲指定が無いか、全ページを対象としているかを返します。
+func coversAllPages(ranges []PageRange, pageCount int) bool {
+	if len(ranges) == 0 {
+		return true
+	}
+	covered := 0
+	for _, r := range ranges {
+		covered += r.End - r.Start + 1
+	}
+	return covered >= pageCount
+}
+
+// buildOptimizeSegments は圧縮対象の範囲と対象外の範囲を、ページ順に隙間なく並べます。
+func buildOptimizeSegments(ranges []PageRange, pageCount int) []optimizeSegment {
+	segments := make([]optimizeSegment, 0, len(ranges)*2+1)
+	next := 1
+	for _, r := range ranges {
+		if r.Start > next {
+			segments = append(segments, optimizeSegment{PageRange: PageRange{Start: next, End: r.Start - 1}})
+		}
+		segments = append(segments, optimizeSegment{PageRange: r, optimize: true})
+		next = r.End + 1
+	}
+	if next <= pageCount {
+		segments = append(segments, optimizeSegment{PageRange: PageRange{Start: next, End: pageCount}})
+	}
+	return segments
+}
+
+// optimizePageRanges は指定範囲のページのみを Ghostscript で圧縮します。
+// ページ順に範囲ごとに切り出し、対象範囲だけを圧縮してから元の順序で結合し直します。
+// 圧縮する範囲は切り出した PDF をそのまま Ghostscript の標準入力へ流し、ファイルに書き出しません。
+// 圧縮しない範囲と圧縮後の範囲は、結合（ファイルを入力とする）のためにワークスペースへ書き出します。
+func (s *Service) optimizePageRanges(ctx context.Context, state *optimizeState, ranges []PageRange, outputPath, logPath string) ([]string, error) {
+	workDir := filepath.Join(state.ws.dir, "segments")
+	if err := os.MkdirAll(workDir, 0o750); err != nil {
+		return nil, fmt.Errorf("作業ディレクトリの作成に失敗しました: %w", err)
+	}
+	source, err := os.Open(state.file.path)
+	if err != nil {
+		return nil, fmt.Errorf("入力ファイルを開けませんでした: %w", err)
+	}
+	defer source.Close()
+
+	var (
+		warnings []string
+		parts    []string
+	)
+	for i, seg := range buildOptimizeSegments(ranges, state.file.pages) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		selection := buildPageSelection(seg.PageRange)
+		collect := func(w io.Writer) error {
+			if _, err := source.Seek(0, io.SeekStart); err != nil {
+				return err
+			}
+			return pdfapi.Collect(source, w, selection, nil)
+		}
+		if !seg.optimize {
+			partPath := filepath.Join(workDir, fmt.Sprintf("part_%03d.pdf", i+1))
+			if err := runStep(ctx, func() error { return writeLocalFile(partPath, collect) }); err != nil {
+				if ctxErr := ctx.Err(); ctxErr != nil {
+					return nil, ctxErr
+				}
+				return nil, newError("UNSUPPORTED_PDF", "圧縮対象ページの切り出しに失敗しました。", err)
+			}
+			parts = append(parts, partPath)
+			continue
+		}
+
+		optimizedPath := filepath.Join(workDir, fmt.Sprintf("part_%03d_optimized.pdf", i+1))
+		segWarnings, err := s.runGhostscriptPiped(ctx, collect, optimizedPath, logPath, workDir, s.ghostscriptOptions(state))
+		if err != nil {
+			return nil, err
+		}
+		warnings = append(warnings, segWarnings...)
+		parts = append(parts, optimizedPath)
+	}
+
+	if err := runStep(ctx, func() error { return mergeCreateFileCompat(parts, outputPath) }); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		return nil, newError("UNSUPPORTED_PDF", "圧縮したページの結合に失敗しました。", err)
+	}
+	return warnings, nil
+}
+
+// writeLocalFile は write の出力でローカルディスク上の path を作成します。失敗した場合は書きかけのファイルを削除します。
+func writeLocalFile(path string, write func(w io.Writer) error) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o640)
+	if err != nil {
+		return err
+	}
+	err = write(f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
 	}
+	if err != nil {
+		_ = os.Remove(path)
+	}
+	return err
+}
+
+// replaceWithOriginal は圧縮結果を元ファイルの内容で置き換えます。
+func replaceWithOriginal(originalPath, outputPath string) error {
+	if err := os.Remove(outputPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return linkOrCopy(originalPath, outputPath)
 }
 
 func computeSavedPercent(before, after int64) float64 {
@@ -228,3 +537,40 @@ func computeSavedPercent(before, after int64) float64 {
 	diff := float64(before-after) / float64(before) * 100
 	return diff
 }
+
+func init() {
+	registerOperation(optimizeOperation{})
+}
+
+type optimizeOperation struct{}
+
+func (optimizeOperation) Type() OperationType { return OperationOptimize }
+
+func (optimizeOperation) OutputSpec() OutputSpec {
+	return OutputSpec{Filename: optimizedFilename, Kind: ResultKindPDF}
+}
+
+func (optimizeOperation) Prepare(ctx context.Context, s *Service, req PrepareRequest) (*JobManifest, error) {
+	file, err := req.singleFile()
+	if err != nil {
+		return nil, err
+	}
+	preset := OptimizePreset(strings.TrimSpace(req.Params.Get("preset")))
+	return s.PrepareOptimizeJob(ctx, file, preset, req.Params.Get("pages"), req.Params.Get("colorProfile"), req.Options)
+}
+
+func (optimizeOperation) Execute(ctx context.Context, s *Service, job *jobInput, progress ProgressReporter) (*Result, error) {
+	state := &optimizeState{
+		ws:           job.ws,
+		file:         job.files[0],
+		preset:       job.manifest.Preset,
+		pagesRaw:     job.manifest.Ranges,
+		level:        s.outputPDFVersion(job.manifest.Options),
+		colorProfile: job.manifest.ColorProfile,
+	}
+	return s.executeOptimize(ctx, state, progress)
+}
+
+func (optimizeOperation) Plan(s *Service, job *jobInput) (*JobPlan, error) {
+	return singleOutputPlan(optimizedFilename, job.files[0], job.manifest.Ranges)
+}
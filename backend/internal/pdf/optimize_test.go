@@ -0,0 +1,210 @@
+package pdf
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	pdfapi "github.com/pdfcpu/pdfcpu/pkg/api"
+
+	"github.com/yourusername/paper-forge/internal/config"
+	"github.com/yourusername/paper-forge/internal/execrun"
+	"github.com/yourusername/paper-forge/internal/pdf/pdftest"
+)
+
+// outputWritingRunner は Ghostscript の代わりに -sOutputFile で指定されたパスへ固定の内容を書き込みます。
+type outputWritingRunner struct {
+	output []byte
+}
+
+func (r *outputWritingRunner) Run(ctx context.Context, cmd execrun.Command) (execrun.Output, error) {
+	// 標準入力から渡された入力は、Ghostscript と同じく最後まで読み込む
+	if cmd.Stdin != nil {
+		_, _ = io.Copy(io.Discard, cmd.Stdin)
+	}
+	for _, arg := range cmd.Args {
+		if path, ok := strings.CutPrefix(arg, "-sOutputFile="); ok {
+			return execrun.Output{}, os.WriteFile(path, r.output, 0o640)
+		}
+	}
+	return execrun.Output{}, nil
+}
+
+func newOptimizeTestState(t *testing.T, svc *Service, original []byte) *optimizeState {
+	t.Helper()
+	svc.tmpRoot = t.TempDir()
+	ws, err := svc.createWorkspace()
+	if err != nil {
+		t.Fatalf("createWorkspace returned error: %v", err)
+	}
+	inputPath := filepath.Join(ws.inDir, "001.pdf")
+	if err := os.WriteFile(inputPath, original, 0o640); err != nil {
+		t.Fatalf("failed to write input: %v", err)
+	}
+	return &optimizeState{
+		ws:     ws,
+		file:   storedFile{path: inputPath, originalName: "scan.pdf", size: int64(len(original)), pages: 1},
+		preset: OptimizePresetAggressive,
+	}
+}
+
+func TestExecuteOptimizeKeepsOriginalWhenLarger(t *testing.T) {
+	original := []byte("%PDF-1.4\n% original\n")
+	svc := NewService(&config.Config{OptimizeKeepOriginal: true, JobExpireMinutes: 1})
+	svc.gs.once.Do(func() { svc.gs.path = "/usr/bin/gs" })
+	svc.runner = &outputWritingRunner{output: []byte("%PDF-1.4\n% a much larger optimized output\n")}
+	state := newOptimizeTestState(t, svc, original)
+
+	result, err := svc.executeOptimize(context.Background(), state, nil)
+	if err != nil {
+		t.Fatalf("executeOptimize returned error: %v", err)
+	}
+	meta := result.Meta.(*OptimizeMeta)
+	if !meta.NoGainApplied || meta.SavedBytes != 0 || result.OutputSize != int64(len(original)) {
+		t.Fatalf("unexpected meta: %+v (output size %d)", meta, result.OutputSize)
+	}
+	data, err := os.ReadFile(result.OutputPath)
+	if err != nil {
+		t.Fatalf("failed to read output: %v", err)
+	}
+	if string(data) != string(original) {
+		t.Fatalf("output should be the original file, got %q", data)
+	}
+}
+
+func TestExecuteOptimizeWarnsWhenLarger(t *testing.T) {
+	original := []byte("%PDF-1.4\n% original\n")
+	larger := []byte("%PDF-1.4\n% a much larger optimized output\n")
+	svc := NewService(&config.Config{OptimizeKeepOriginal: false, JobExpireMinutes: 1})
+	svc.gs.once.Do(func() { svc.gs.path = "/usr/bin/gs" })
+	svc.runner = &outputWritingRunner{output: larger}
+	state := newOptimizeTestState(t, svc, original)
+
+	result, err := svc.executeOptimize(context.Background(), state, nil)
+	if err != nil {
+		t.Fatalf("executeOptimize returned error: %v", err)
+	}
+	meta := result.Meta.(*OptimizeMeta)
+	if meta.NoGainApplied || result.OutputSize != int64(len(larger)) {
+		t.Fatalf("unexpected meta: %+v (output size %d)", meta, result.OutputSize)
+	}
+	if len(meta.Warnings) == 0 || !strings.Contains(meta.Warnings[0], "大きくなりました") {
+		t.Fatalf("expected size warning first, got %q", meta.Warnings)
+	}
+}
+
+func TestBuildOptimizeSegments(t *testing.T) {
+	segments := buildOptimizeSegments([]PageRange{{Start: 3, End: 4}, {Start: 7, End: 7}}, 10)
+	want := []optimizeSegment{
+		{PageRange: PageRange{Start: 1, End: 2}},
+		{PageRange: PageRange{Start: 3, End: 4}, optimize: true},
+		{PageRange: PageRange{Start: 5, End: 6}},
+		{PageRange: PageRange{Start: 7, End: 7}, optimize: true},
+		{PageRange: PageRange{Start: 8, End: 10}},
+	}
+	if len(segments) != len(want) {
+		t.Fatalf("segments = %+v, want %+v", segments, want)
+	}
+	for i := range want {
+		if segments[i] != want[i] {
+			t.Fatalf("segments[%d] = %+v, want %+v", i, segments[i], want[i])
+		}
+	}
+}
+
+func TestCoversAllPages(t *testing.T) {
+	if !coversAllPages(nil, 5) {
+		t.Fatal("no ranges should mean the whole document")
+	}
+	if !coversAllPages([]PageRange{{Start: 1, End: 2}, {Start: 3, End: 5}}, 5) {
+		t.Fatal("ranges covering every page should be treated as the whole document")
+	}
+	if coversAllPages([]PageRange{{Start: 4, End: 5}}, 5) {
+		t.Fatal("partial range reported as whole document")
+	}
+}
+
+// pipingRunner は標準入力で受け取った PDF をそのまま -sOutputFile へ書き出し、受け取ったページ数を記録します。
+type pipingRunner struct {
+	calls []execrun.Command
+	pages []int
+}
+
+func (r *pipingRunner) Run(ctx context.Context, cmd execrun.Command) (execrun.Output, error) {
+	r.calls = append(r.calls, cmd)
+	if cmd.Stdin == nil {
+		return execrun.Output{}, errors.New("input should be piped through stdin")
+	}
+	data, err := io.ReadAll(cmd.Stdin)
+	if err != nil {
+		return execrun.Output{}, err
+	}
+	pages, err := pdfapi.PageCount(bytes.NewReader(data), nil)
+	if err != nil {
+		return execrun.Output{}, err
+	}
+	r.pages = append(r.pages, pages)
+	for _, arg := range cmd.Args {
+		if path, ok := strings.CutPrefix(arg, "-sOutputFile="); ok {
+			return execrun.Output{}, os.WriteFile(path, data, 0o640)
+		}
+	}
+	return execrun.Output{}, nil
+}
+
+func TestOptimizePageRangesPipesSegmentsToGhostscript(t *testing.T) {
+	svc := NewService(&config.Config{JobExpireMinutes: 1})
+	svc.gs.once.Do(func() { svc.gs.path = "/usr/bin/gs" })
+	runner := &pipingRunner{}
+	svc.runner = runner
+	state := newOptimizeTestState(t, svc, pdftest.Synthetic(5, 0))
+	state.file.pages = 5
+	state.pagesRaw = "2-3"
+
+	result, err := svc.executeOptimize(context.Background(), state, nil)
+	if err != nil {
+		t.Fatalf("executeOptimize returned error: %v", err)
+	}
+	if len(runner.calls) != 1 || !slices.Equal(runner.pages, []int{2}) {
+		t.Fatalf("only the selected pages should be piped to ghostscript: calls=%d pages=%v", len(runner.calls), runner.pages)
+	}
+	cmd := runner.calls[0]
+	segments := filepath.Join(state.ws.dir, "segments")
+	if cmd.Args[len(cmd.Args)-1] != "-" || !slices.Contains(cmd.Env, "TMPDIR="+segments) {
+		t.Fatalf("ghostscript should read stdin and spool inside the workspace: args=%v env=%v", cmd.Args, cmd.Env)
+	}
+	if _, err := os.Stat(filepath.Join(segments, "part_002.pdf")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("the compressed segment should not be written to disk before ghostscript: %v", err)
+	}
+	for _, name := range []string{"part_001.pdf", "part_003.pdf"} {
+		if _, err := os.Stat(filepath.Join(segments, name)); err != nil {
+			t.Fatalf("segments kept as-is should fall back to files for the merge: %v", err)
+		}
+	}
+	pages, err := pdfapi.PageCountFile(result.OutputPath)
+	if err != nil || pages != 5 {
+		t.Fatalf("merged output should keep every page: pages=%d err=%v", pages, err)
+	}
+}
+
+func TestOptimizePageRangesReportsGhostscriptFailureWithoutReadingInput(t *testing.T) {
+	svc := NewService(&config.Config{JobExpireMinutes: 1})
+	svc.gs.once.Do(func() { svc.gs.path = "/usr/bin/gs" })
+	// 入力を読まずに終了しても、切り出しの書き込みで止まらずに失敗を返す
+	svc.runner = &stubRunner{err: errors.New("exit status 1")}
+	state := newOptimizeTestState(t, svc, pdftest.Synthetic(3, 4096))
+	state.file.pages = 3
+	state.pagesRaw = "2"
+
+	_, err := svc.executeOptimize(context.Background(), state, nil)
+	var pdfErr *Error
+	if !errors.As(err, &pdfErr) || pdfErr.Code != "UNSUPPORTED_PDF" || !strings.Contains(pdfErr.Message, "Ghostscript") {
+		t.Fatalf("expected the ghostscript failure, got %v", err)
+	}
+}
@@ -0,0 +1,296 @@
+package pdf
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"path"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/yourusername/paper-forge/internal/config"
+)
+
+const (
+	// maxJobLabelLength はジョブのラベルの最大文字数です。
+	maxJobLabelLength = 100
+	// maxClientContextBytes は clientContext の最大サイズ（整形を除いたバイト数）です。
+	maxClientContextBytes = 2048
+	// maxDriveFolderIDLength はクラウドドライブの書き戻し先フォルダIDの最大文字数です。
+	maxDriveFolderIDLength = 256
+	// DeliverConnectionField は書き戻しに使う接続のIDを渡すフォーム項目です。
+	// クライアントが送った値は使わず、API サーバーがセッションの接続で上書きします。
+	DeliverConnectionField = "deliverConnection"
+)
+
+// JobOptions はジョブ投入時に操作種別を問わず指定できる付帯設定です。
+type JobOptions struct {
+	Delivery *DeliveryTarget `json:"delivery,omitempty"`
+	// NotifyWebhook はジョブ単位で通知先を上書きするチャット Webhook URL です。
+	NotifyWebhook string `json:"notifyWebhook,omitempty"`
+	// Label は履歴でジョブを見分けるためにクライアントが付ける任意の名前です（例: "contract_v3 merge"）。
+	Label string `json:"label,omitempty"`
+	// ClientContext は連携システムがチケット番号などを対応付けるための任意の JSON です。
+	// サーバーは中身を解釈せず、ジョブ情報と完了通知にそのまま含めます。
+	ClientContext json.RawMessage `json:"clientContext,omitempty"`
+	// KeepInputs はアップロードされた入力ファイルをジョブの保持期間中残し、後からダウンロードできるようにします。
+	KeepInputs bool `json:"keepInputs,omitempty"`
+	// Receipt は処理受領書（ジョブID・処理内容・入出力のハッシュ・日時を1ページにまとめた PDF）の付け方です。空の場合は付けません。
+	Receipt ReceiptMode `json:"receipt,omitempty"`
+	// PDFVersion は成果物の PDF のバージョン（1.4〜1.7）です。空の場合はデプロイの設定（OUTPUT_PDF_VERSION）に従います。
+	PDFVersion string `json:"pdfVersion,omitempty"`
+}
+
+// DeliveryMethod はジョブ完了時の成果物配送方法を表します。
+type DeliveryMethod string
+
+const (
+	DeliveryMethodEmail   DeliveryMethod = "email"
+	DeliveryMethodSFTP    DeliveryMethod = "sftp"
+	DeliveryMethodStorage DeliveryMethod = "storage"
+	// DeliveryMethodGoogleDrive / DeliveryMethodOneDrive は利用者が接続したクラウドドライブへ書き戻します。
+	DeliveryMethodGoogleDrive DeliveryMethod = "gdrive"
+	DeliveryMethodOneDrive    DeliveryMethod = "onedrive"
+)
+
+// DeliveryTarget はジョブ完了時に成果物を届ける先を表します。
+type DeliveryTarget struct {
+	Method DeliveryMethod `json:"method"`
+	Email  string         `json:"email,omitempty"`
+	// ObjectPath は設定済みバケット内の出力先オブジェクトパスです（storage 配送時）。
+	ObjectPath string `json:"objectPath,omitempty"`
+	// UploadURL はクライアントが発行した署名付きPUT URLです（storage 配送時）。
+	UploadURL string `json:"uploadUrl,omitempty"`
+	// FolderID は書き戻し先のフォルダのIDです（gdrive / onedrive 配送時。空の場合はマイドライブ / ルート）。
+	FolderID string `json:"folderId,omitempty"`
+	// Connection はジョブを投入した利用者のクラウドドライブへの接続のIDです（gdrive / onedrive 配送時）。
+	// サーバーがセッションから設定し、クライアントからは指定できません。
+	Connection string `json:"connection,omitempty"`
+}
+
+// DeliveryPolicy はクライアントが配送先として指定できる範囲です。
+// メールの宛先を任意に指定できると、このサービスが任意の宛先へファイルを送る踏み台になるため、設定した宛先に限ります。
+type DeliveryPolicy struct {
+	// EmailEnabled はメールの送信元と送信方法が設定されているかです。false の場合は deliverTo=email を受け付けません。
+	EmailEnabled bool
+	// EmailRecipients は deliverTo=email で送信できる宛先です（小文字のドメインまたはメールアドレス）。
+	// ドメインはサブドメインを含みません。空の場合は deliverTo=email を受け付けません。
+	EmailRecipients []string
+	// UploadHosts は outputUrl として受け付ける署名付きURLのホストです（サブドメインを含む）。
+	// 任意のURLを受け付けるとSSRFの踏み台になるため、受付時に限定します。空の場合は outputUrl を受け付けません。
+	UploadHosts []string
+}
+
+// NewDeliveryPolicy は設定から DeliveryPolicy を作成します。
+func NewDeliveryPolicy(cfg *config.Config) *DeliveryPolicy {
+	if cfg == nil {
+		return &DeliveryPolicy{}
+	}
+	policy := &DeliveryPolicy{
+		EmailEnabled: strings.TrimSpace(cfg.MailFrom) != "" && (cfg.SendGridAPIKey != "" || cfg.SMTPHost != ""),
+	}
+	for _, entry := range strings.Split(cfg.MailAllowedRecipients, ",") {
+		if entry = strings.ToLower(strings.TrimSpace(entry)); entry != "" {
+			policy.EmailRecipients = append(policy.EmailRecipients, entry)
+		}
+	}
+	// データ所在地を指定したデプロイでは、書き込み先のリージョンを確認できない署名付きURLを受け付けない
+	if strings.TrimSpace(cfg.DataRegion) == "" {
+		for _, h := range strings.Split(cfg.OutputURLAllowedHosts, ",") {
+			if h = strings.ToLower(strings.TrimSpace(h)); h != "" {
+				policy.UploadHosts = append(policy.UploadHosts, h)
+			}
+		}
+	}
+	return policy
+}
+
+// AllowsUploadHost は host の署名付きURLへ成果物を書き込めるかを返します。nil の DeliveryPolicy は常に false です。
+func (p *DeliveryPolicy) AllowsUploadHost(host string) bool {
+	if p == nil {
+		return false
+	}
+	host = strings.ToLower(host)
+	for _, allowed := range p.UploadHosts {
+		if host == allowed || strings.HasSuffix(host, "."+allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsEmailRecipient は addr へ成果物をメールで送信できるかを返します。nil の DeliveryPolicy は常に false です。
+func (p *DeliveryPolicy) AllowsEmailRecipient(addr string) bool {
+	if p == nil || !p.EmailEnabled {
+		return false
+	}
+	addr = strings.ToLower(strings.TrimSpace(addr))
+	at := strings.LastIndex(addr, "@")
+	if at <= 0 {
+		return false
+	}
+	domain := addr[at+1:]
+	for _, allowed := range p.EmailRecipients {
+		if allowed == addr || (!strings.Contains(allowed, "@") && allowed == domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// deliveryForm は配送先指定に関するフォーム入力です。
+type deliveryForm struct {
+	Method     string
+	Email      string
+	OutputPath string
+	OutputURL  string
+	FolderID   string
+	Connection string
+}
+
+// requiresAsync は同期レスポンスでは満たせないオプションが含まれるかを返します。
+// 配送や通知はジョブ完了後にワーカーが行うため、非同期キューを経由させる必要があります。
+// 入力の保持は、同期レスポンスの送信後にワークスペースが削除されるため非同期でのみ扱えます。
+// 別添の受領書は主成果物以外の成果物になるため、同期レスポンスでは返せません。
+func (o JobOptions) requiresAsync() bool {
+	return o.Delivery != nil || o.NotifyWebhook != "" || o.KeepInputs || o.Receipt == ReceiptModeAttach
+}
+
+func parseNotifyWebhook(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", nil
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return "", errors.New("notifyWebhook には https の Webhook URL を指定してください。")
+	}
+	return raw, nil
+}
+
+// parseJobLabel はジョブのラベルを検証します。前後の空白は除き、改行などの制御文字は受け付けません。
+func parseJobLabel(raw string) (string, error) {
+	label := strings.TrimSpace(raw)
+	if utf8.RuneCountInString(label) > maxJobLabelLength {
+		return "", fmt.Errorf("label は%d文字以内で指定してください。", maxJobLabelLength)
+	}
+	if !utf8.ValidString(label) || strings.IndexFunc(label, unicode.IsControl) >= 0 {
+		return "", errors.New("label に改行などの制御文字は使用できません。")
+	}
+	return label, nil
+}
+
+// parseClientContext は clientContext が JSON として正しく、上限以内のサイズであることを検証し、
+// 空白を除いた形で返します。
+func parseClientContext(raw string) (json.RawMessage, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, []byte(raw)); err != nil {
+		return nil, errors.New("clientContext は JSON 形式で指定してください。")
+	}
+	if buf.Len() > maxClientContextBytes {
+		return nil, fmt.Errorf("clientContext は%dバイト以内で指定してください。", maxClientContextBytes)
+	}
+	return json.RawMessage(buf.Bytes()), nil
+}
+
+func parseDeliveryTarget(form deliveryForm, policy *DeliveryPolicy) (*DeliveryTarget, error) {
+	method := strings.ToLower(strings.TrimSpace(form.Method))
+	if method == "" {
+		return nil, nil
+	}
+
+	switch DeliveryMethod(method) {
+	case DeliveryMethodEmail:
+		if policy == nil || !policy.EmailEnabled {
+			return nil, errors.New("このサーバーではメール配送を利用できません。")
+		}
+		email := strings.TrimSpace(form.Email)
+		if email == "" {
+			return nil, errors.New("deliverTo=email の場合は deliverEmail に送信先メールアドレスを指定してください。")
+		}
+		addr, err := mail.ParseAddress(email)
+		if err != nil {
+			return nil, errors.New("deliverEmail のメールアドレス形式が正しくありません。")
+		}
+		if !policy.AllowsEmailRecipient(addr.Address) {
+			return nil, errors.New("deliverEmail には許可された宛先のメールアドレスを指定してください。")
+		}
+		return &DeliveryTarget{Method: DeliveryMethodEmail, Email: addr.Address}, nil
+	case DeliveryMethodSFTP:
+		// 送信先はデプロイ単位で設定されるため、クライアントからは指定させない
+		return &DeliveryTarget{Method: DeliveryMethodSFTP}, nil
+	case DeliveryMethodStorage:
+		return parseStorageTarget(form.OutputPath, form.OutputURL, policy)
+	case DeliveryMethodGoogleDrive, DeliveryMethodOneDrive:
+		return parseDriveTarget(DeliveryMethod(method), form.FolderID, form.Connection)
+	default:
+		return nil, errors.New("deliverTo には email / sftp / storage / gdrive / onedrive のいずれかを指定してください。")
+	}
+}
+
+// parseDriveTarget はクラウドドライブへの書き戻し先を検証します。
+func parseDriveTarget(method DeliveryMethod, folderID, connection string) (*DeliveryTarget, error) {
+	if strings.TrimSpace(connection) == "" {
+		return nil, fmt.Errorf("deliverTo=%s の場合は先に GET /api/connectors/%s/authorize で接続してください。", method, method)
+	}
+	folderID = strings.TrimSpace(folderID)
+	if len(folderID) > maxDriveFolderIDLength || strings.IndexFunc(folderID, invalidDriveIDRune) >= 0 {
+		return nil, errors.New("deliverFolderId の形式が正しくありません。")
+	}
+	return &DeliveryTarget{Method: method, FolderID: folderID, Connection: connection}, nil
+}
+
+// invalidDriveIDRune はクラウドドライブのフォルダIDに使われない文字かを返します（Google ドライブは英数字・-・_、OneDrive は ! も使う）。
+func invalidDriveIDRune(r rune) bool {
+	switch {
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		return false
+	case r == '-', r == '_', r == '!', r == '.':
+		return false
+	}
+	return true
+}
+
+// parseStorageTarget は保存先を検証します。outputPath はサーバー側で設定したプレフィックスとテナントの階層の下に書き込むため、
+// ここではバケット内の相対パスであることだけを確認します。
+func parseStorageTarget(outputPath, outputURL string, policy *DeliveryPolicy) (*DeliveryTarget, error) {
+	outputPath = strings.TrimSpace(outputPath)
+	outputURL = strings.TrimSpace(outputURL)
+
+	switch {
+	case outputPath != "" && outputURL != "":
+		return nil, errors.New("outputPath と outputUrl はどちらか一方のみ指定してください。")
+	case outputURL != "":
+		u, err := url.Parse(outputURL)
+		if err != nil || u.Scheme != "https" || u.Host == "" {
+			return nil, errors.New("outputUrl には https の署名付きURLを指定してください。")
+		}
+		if !policy.AllowsUploadHost(u.Hostname()) {
+			return nil, errors.New("outputUrl には許可されたストレージの署名付きURLを指定してください。")
+		}
+		return &DeliveryTarget{Method: DeliveryMethodStorage, UploadURL: outputURL}, nil
+	case outputPath != "":
+		if strings.HasPrefix(outputPath, "/") || strings.HasSuffix(outputPath, "/") {
+			return nil, errors.New("outputPath はバケット内の相対オブジェクトパスで指定してください。")
+		}
+		cleaned := path.Clean(outputPath)
+		if cleaned != outputPath || cleaned == "." || strings.HasPrefix(cleaned, "..") {
+			return nil, errors.New("outputPath に不正なパスが含まれています。")
+		}
+		// テナントの階層は配送時に付けるため、利用者が指定するパスには含めさせない
+		if cleaned == "tenants" || strings.HasPrefix(cleaned, "tenants/") {
+			return nil, errors.New("outputPath の先頭に tenants/ は指定できません。")
+		}
+		return &DeliveryTarget{Method: DeliveryMethodStorage, ObjectPath: cleaned}, nil
+	default:
+		return nil, errors.New("deliverTo=storage の場合は outputPath または outputUrl を指定してください。")
+	}
+}
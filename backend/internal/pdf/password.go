@@ -0,0 +1,60 @@
+package pdf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	pdfapi "github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+)
+
+// maxInputPasswordLength は入力PDFのパスワードの最大バイト数です（PDF の仕様上の上限）。
+const maxInputPasswordLength = 127
+
+type inputPasswordsKey struct{}
+
+// WithInputPasswords はパスワードで保護された入力PDFを復号するためのパスワードを ctx に設定します。
+// passwords[i] は i 番目の入力ファイルに対応し、空文字列はパスワードなしを表します。
+// パスワードはマニフェストや作業ディレクトリには保存せず、受付時（storeMultipartFile）にだけ使います。
+func WithInputPasswords(ctx context.Context, passwords []string) context.Context {
+	return context.WithValue(ctx, inputPasswordsKey{}, append([]string(nil), passwords...))
+}
+
+// inputPassword は index 番目の入力ファイルのパスワードを返します。指定がない場合は空文字列です。
+func inputPassword(ctx context.Context, index int) string {
+	passwords, _ := ctx.Value(inputPasswordsKey{}).([]string)
+	if index < 0 || index >= len(passwords) {
+		return ""
+	}
+	return passwords[index]
+}
+
+// validateInputPasswords はパスワードの長さを確認します。
+func validateInputPasswords(passwords []string) error {
+	for i, pw := range passwords {
+		if len(pw) > maxInputPasswordLength {
+			return fmt.Errorf("passwords[%d] は%dバイト以内で指定してください。", i, maxInputPasswordLength)
+		}
+	}
+	return nil
+}
+
+// decryptStoredPDF は保存済みのファイル path を password で復号し、同じパスに書き戻します。
+// 以降の処理（ページ数の確認と各操作の実行）は復号済みの内容に対して行うため、パスワードを実行時まで持ち回りません。
+// 復号した場合は true を返します。パスワードが正しくない場合は ENCRYPTED_PDF を返します。暗号化されていないファイルはそのままにします。
+func decryptStoredPDF(path, name, password string) (bool, error) {
+	conf := model.NewDefaultConfiguration()
+	conf.UserPW = password
+	conf.OwnerPW = password
+	err := pdfapi.DecryptFile(path, "", conf)
+	if err == nil {
+		return true, nil
+	}
+	if errors.Is(err, pdfcpu.ErrWrongPassword) {
+		return false, newError("ENCRYPTED_PDF", fmt.Sprintf("%s のパスワードが正しくありません。", name), err)
+	}
+	// 暗号化されていないファイルや読み込めないファイルは、続くページ数の確認で判定する
+	return false, nil
+}
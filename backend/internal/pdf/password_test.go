@@ -0,0 +1,82 @@
+package pdf
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	pdfapi "github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+
+	"github.com/yourusername/paper-forge/internal/config"
+	"github.com/yourusername/paper-forge/internal/pdf/pdftest"
+)
+
+// encryptedPDF は userPW で保護した pages ページのPDFを返します。
+func encryptedPDF(t *testing.T, pages int, userPW string) []byte {
+	t.Helper()
+	dir := t.TempDir()
+	plain := filepath.Join(dir, "plain.pdf")
+	if err := os.WriteFile(plain, pdftest.Synthetic(pages, 0), 0o600); err != nil {
+		t.Fatalf("failed to write plain PDF: %v", err)
+	}
+	conf := model.NewDefaultConfiguration()
+	conf.UserPW = userPW
+	conf.OwnerPW = userPW + "-owner"
+	encrypted := filepath.Join(dir, "encrypted.pdf")
+	if err := pdfapi.EncryptFile(plain, encrypted, conf); err != nil {
+		t.Fatalf("failed to encrypt PDF: %v", err)
+	}
+	data, err := os.ReadFile(encrypted)
+	if err != nil {
+		t.Fatalf("failed to read encrypted PDF: %v", err)
+	}
+	return data
+}
+
+func TestStoreMultipartFileDecryptsWithPassword(t *testing.T) {
+	svc := NewService(&config.Config{MaxFileSize: 1 << 20, MaxPages: 20})
+	svc.tmpRoot = t.TempDir()
+	headers, err := pdftest.FileHeaders("file",
+		pdftest.File{Name: "locked.pdf", Data: encryptedPDF(t, 3, "secret")},
+	)
+	if err != nil {
+		t.Fatalf("failed to build multipart files: %v", err)
+	}
+	spec := WatermarkSpec{Text: "DRAFT", Opacity: 0.3, Rotation: 45, FontSize: 48, Position: "c"}
+
+	if _, err := svc.PrepareWatermarkJob(context.Background(), headers[0], spec, "", JobOptions{}); !IsError(err, "ENCRYPTED_PDF") {
+		t.Fatalf("expected ENCRYPTED_PDF without password, got %v", err)
+	}
+	wrong := WithInputPasswords(context.Background(), []string{"guess"})
+	if _, err := svc.PrepareWatermarkJob(wrong, headers[0], spec, "", JobOptions{}); !IsError(err, "ENCRYPTED_PDF") {
+		t.Fatalf("expected ENCRYPTED_PDF for wrong password, got %v", err)
+	}
+
+	ctx := WithInputPasswords(context.Background(), []string{"secret"})
+	manifest, err := svc.PrepareWatermarkJob(ctx, headers[0], spec, "", JobOptions{})
+	if err != nil {
+		t.Fatalf("PrepareWatermarkJob returned error: %v", err)
+	}
+	if manifest.Files[0].Pages != 3 {
+		t.Fatalf("expected 3 pages after decryption, got %d", manifest.Files[0].Pages)
+	}
+	raw, err := os.ReadFile(filepath.Join(svc.workspaceFor(manifest.JobID).dir, manifestFilename))
+	if err != nil {
+		t.Fatalf("failed to read manifest: %v", err)
+	}
+	if bytes.Contains(raw, []byte("secret")) {
+		t.Fatalf("manifest must not contain the password: %s", raw)
+	}
+}
+
+func TestValidateInputPasswordsRejectsLong(t *testing.T) {
+	if err := validateInputPasswords([]string{"", "secret"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := validateInputPasswords([]string{string(make([]byte, maxInputPasswordLength+1))}); err == nil {
+		t.Fatal("expected error for a password longer than the limit")
+	}
+}
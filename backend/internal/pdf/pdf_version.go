@@ -0,0 +1,155 @@
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	pdfapi "github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+)
+
+// defaultCompatibilityLevel は PDF のバージョンの指定が無い場合に Ghostscript（圧縮）で使う -dCompatibilityLevel です。
+const defaultCompatibilityLevel = "1.5"
+
+// parsePDFVersion はジョブの pdfVersion 項目を検証します。空の場合はデプロイの設定（OUTPUT_PDF_VERSION）に従います。
+func parsePDFVersion(raw string) (string, error) {
+	switch version := strings.TrimSpace(raw); version {
+	case "", "1.4", "1.5", "1.6", "1.7":
+		return version, nil
+	default:
+		return "", errors.New("pdfVersion には 1.4 / 1.5 / 1.6 / 1.7 のいずれかを指定してください。")
+	}
+}
+
+// outputPDFVersion はジョブの成果物の PDF のバージョンを返します。
+// リクエストの指定が無い場合は OUTPUT_PDF_VERSION で、どちらも無い場合は空（処理結果のまま）です。
+func (s *Service) outputPDFVersion(opts JobOptions) string {
+	if opts.PDFVersion != "" {
+		return opts.PDFVersion
+	}
+	if s.cfg == nil {
+		return ""
+	}
+	return s.cfg.OutputPDFVersion
+}
+
+// applyPDFVersion は処理パイプラインの最終段（受領書の後）で、成果物の PDF を指定のバージョンで書き出し直します。
+// 1.4 の場合は、1.5 で導入されたオブジェクトストリーム・相互参照ストリームを使わずに書き出します。
+// 画像の圧縮方式など内容に使われている機能は変換しないため、内容を作り直す必要がある場合は圧縮（Ghostscript）を使います。
+func (s *Service) applyPDFVersion(manifest *JobManifest, result *Result) error {
+	version := s.outputPDFVersion(manifest.Options)
+	if result == nil || version == "" {
+		return nil
+	}
+
+	var pdfPaths []string
+	for i, a := range result.Artifacts {
+		if a.Kind != ResultKindPDF {
+			continue
+		}
+		if err := rewritePDFVersion(a.Path, version); err != nil {
+			return err
+		}
+		if err := refreshArtifact(result, i); err != nil {
+			return err
+		}
+		pdfPaths = append(pdfPaths, a.Path)
+	}
+	return rebuildZipArtifacts(result, pdfPaths)
+}
+
+// rewritePDFVersion は path の PDF を version（"1.4" など）で書き出し直します。
+func rewritePDFVersion(path, version string) error {
+	pdfCtx, err := pdfapi.ReadContextFile(path)
+	if err != nil {
+		return err
+	}
+	if err := prepareVersionWrite(pdfCtx, version); err != nil {
+		return err
+	}
+
+	tmp := path + ".version"
+	if err := pdfapi.WriteContextFile(pdfCtx, tmp); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	if err := setHeaderVersion(tmp, version); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// prepareVersionWrite は pdfCtx を version で書き出せるよう設定します。ヘッダーのバージョンは書き出した後に書き換えます。
+func prepareVersionWrite(pdfCtx *model.Context, version string) error {
+	streams := version >= "1.5"
+	pdfCtx.WriteObjectStream = streams
+	pdfCtx.WriteXRefStream = streams
+	// カタログの /Version はヘッダーより優先されるため削除し、ヘッダーのバージョンだけにする
+	root, err := pdfCtx.Catalog()
+	if err != nil {
+		return err
+	}
+	root.Delete("Version")
+	return nil
+}
+
+// setHeaderVersion はファイル先頭の "%PDF-x.y" のバージョンを書き換えます。
+// 同じ長さで書き換えるため、相互参照表のオフセットは変わりません。
+func setHeaderVersion(path, version string) error {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	header := make([]byte, len("%PDF-1.7"))
+	if _, err := io.ReadFull(f, header); err != nil || !bytes.HasPrefix(header, []byte("%PDF-")) {
+		return fmt.Errorf("PDF のヘッダーが見つかりません: %s", path)
+	}
+	if _, err := f.WriteAt([]byte(version), int64(len("%PDF-"))); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// headerVersionWriter は書き出す PDF の先頭の "%PDF-x.y" のバージョンを書き換えながら w へ渡します（setHeaderVersion のストリーム版）。
+type headerVersionWriter struct {
+	w       io.Writer
+	version string
+	// head はヘッダーを書き換えるまで保留している先頭部分です。
+	head []byte
+	done bool
+}
+
+func (h *headerVersionWriter) Write(p []byte) (int, error) {
+	if h.done {
+		return h.w.Write(p)
+	}
+	h.head = append(h.head, p...)
+	if len(h.head) < len("%PDF-1.7") {
+		return len(p), nil
+	}
+	if !bytes.HasPrefix(h.head, []byte("%PDF-")) {
+		return 0, errors.New("PDF のヘッダーが見つかりません")
+	}
+	copy(h.head[len("%PDF-"):], h.version)
+	h.done = true
+	if _, err := h.w.Write(h.head); err != nil {
+		return 0, err
+	}
+	h.head = nil
+	return len(p), nil
+}
+
+// Close はヘッダーまで書き出されたかを確認します。
+func (h *headerVersionWriter) Close() error {
+	if !h.done {
+		return errors.New("PDF のヘッダーが見つかりません")
+	}
+	return nil
+}
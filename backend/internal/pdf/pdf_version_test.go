@@ -0,0 +1,85 @@
+package pdf
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yourusername/paper-forge/internal/config"
+)
+
+func TestParsePDFVersion(t *testing.T) {
+	for _, raw := range []string{"", "1.4", " 1.7 "} {
+		if _, err := parsePDFVersion(raw); err != nil {
+			t.Fatalf("%q should be accepted: %v", raw, err)
+		}
+	}
+	for _, raw := range []string{"1.3", "2.0", "14", "latest"} {
+		if _, err := parsePDFVersion(raw); err == nil {
+			t.Fatalf("%q should be rejected", raw)
+		}
+	}
+}
+
+func TestOutputPDFVersionPrefersRequest(t *testing.T) {
+	svc := NewService(&config.Config{WorkDir: t.TempDir(), OutputPDFVersion: "1.7"})
+	if got := svc.outputPDFVersion(JobOptions{}); got != "1.7" {
+		t.Fatalf("deployment default should apply, got %q", got)
+	}
+	if got := svc.outputPDFVersion(JobOptions{PDFVersion: "1.4"}); got != "1.4" {
+		t.Fatalf("request should override the default, got %q", got)
+	}
+}
+
+func TestApplyPDFVersionRewritesHeader(t *testing.T) {
+	svc := NewService(&config.Config{WorkDir: t.TempDir(), OutputPDFVersion: "1.6"})
+	path := filepath.Join(t.TempDir(), "merged.pdf")
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 595 842] >>",
+		"<< /Producer (test) >>",
+	}
+	if err := writePDFObjects(path, objects); err != nil {
+		t.Fatal(err)
+	}
+	before, err := newArtifact(path, ResultKindPDF)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := &Result{Artifacts: []Artifact{before}}
+
+	if err := svc.applyPDFVersion(&JobManifest{Operation: OperationMerge}, result); err != nil {
+		t.Fatalf("applyPDFVersion: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(data, []byte("%PDF-1.6\n")) {
+		t.Fatalf("header should be rewritten: %q", data[:12])
+	}
+	if result.Artifacts[0].SHA256 == before.SHA256 {
+		t.Fatal("artifact hash should be refreshed after the rewrite")
+	}
+	if _, err := os.Stat(path + ".version"); !os.IsNotExist(err) {
+		t.Fatalf("temporary file should be removed: %v", err)
+	}
+}
+
+func TestGhostscriptUsesRequestedCompatibilityLevel(t *testing.T) {
+	runner := &stubRunner{}
+	svc := newGhostscriptTestService(runner)
+	if _, err := svc.runGhostscript(context.Background(), "in.pdf", "out.pdf", "", ghostscriptOptions{preset: OptimizePresetStandard, level: "1.4"}); err != nil {
+		t.Fatal(err)
+	}
+	if args := strings.Join(runner.calls[0].Args, " "); !strings.Contains(args, "-dCompatibilityLevel=1.4") {
+		t.Fatalf("compatibility level not applied: %s", args)
+	}
+	if args := strings.Join(ghostscriptArgs("out.pdf", "in.pdf", ghostscriptOptions{preset: OptimizePresetStandard}), " "); !strings.Contains(args, "-dCompatibilityLevel=1.5") {
+		t.Fatalf("default compatibility level should be 1.5: %s", args)
+	}
+}
@@ -0,0 +1,128 @@
+// Package pdftest はベンチマークや負荷試験で使う合成PDFとアップロード用データを生成します。
+package pdftest
+
+import (
+	"bytes"
+	"fmt"
+	"mime/multipart"
+	"sort"
+	"strings"
+)
+
+// File はアップロードとして扱うファイルの名前と内容です。
+type File struct {
+	Name string
+	Data []byte
+}
+
+// Synthetic は指定したページ数の単純なテキストPDFを生成します。
+// bytesPerPage に正の値を指定すると、各ページのコンテンツストリームをおおよそその大きさまで水増しします。
+func Synthetic(pages, bytesPerPage int) []byte {
+	return synthetic(pages, bytesPerPage, nil)
+}
+
+// SyntheticWithInfo は文書情報辞書（/Info）に info の項目（Title, Author, CreationDate など）を持つ Synthetic のPDFを生成します。
+// 値は PDF の文字列としてそのまま書き込むため、括弧やバックスラッシュを含めないでください。
+func SyntheticWithInfo(pages int, info map[string]string) []byte {
+	return synthetic(pages, 0, info)
+}
+
+func synthetic(pages, bytesPerPage int, info map[string]string) []byte {
+	if pages < 1 {
+		pages = 1
+	}
+
+	// オブジェクト番号: 1=Catalog, 2=Pages, 3=Font, 以降はページごとに Page と Contents の組。info があれば最後に Info
+	objCount := 3 + pages*2
+	if len(info) > 0 {
+		objCount++
+	}
+	offsets := make([]int, objCount+1)
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	writeObj := func(num int, body string) {
+		offsets[num] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", num, body)
+	}
+
+	kids := make([]string, pages)
+	for i := 0; i < pages; i++ {
+		kids[i] = fmt.Sprintf("%d 0 R", 4+i*2)
+	}
+	writeObj(1, "<< /Type /Catalog /Pages 2 0 R >>")
+	writeObj(2, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), pages))
+	writeObj(3, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>")
+
+	for i := 0; i < pages; i++ {
+		pageNum := 4 + i*2
+		contentNum := pageNum + 1
+		writeObj(pageNum, fmt.Sprintf(
+			"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 595 842] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>",
+			contentNum,
+		))
+		content := pageContent(i+1, bytesPerPage)
+		writeObj(contentNum, fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content))
+	}
+
+	trailerInfo := ""
+	if len(info) > 0 {
+		keys := make([]string, 0, len(info))
+		for k := range info {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		var entries strings.Builder
+		for _, k := range keys {
+			fmt.Fprintf(&entries, " /%s (%s)", k, info[k])
+		}
+		writeObj(objCount, fmt.Sprintf("<<%s >>", entries.String()))
+		trailerInfo = fmt.Sprintf(" /Info %d 0 R", objCount)
+	}
+
+	xrefOffset := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", objCount+1)
+	for i := 1; i <= objCount; i++ {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offsets[i])
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R%s >>\nstartxref\n%d\n%%%%EOF\n", objCount+1, trailerInfo, xrefOffset)
+	return buf.Bytes()
+}
+
+func pageContent(page, size int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "BT /F1 24 Tf 72 760 Td (Page %d) Tj ET", page)
+	for line := 0; b.Len() < size; line++ {
+		fmt.Fprintf(&b, "\nBT /F1 8 Tf 72 %d Td (paper-forge synthetic filler line %d) Tj ET", 740-(line%90)*8, line)
+	}
+	return b.String()
+}
+
+// FileHeaders は files を multipart/form-data としてエンコードし、fieldName で受け取った場合のファイルヘッダーを返します。
+// ハンドラーを経由せずにサービスを直接呼び出す際に使用します。
+func FileHeaders(fieldName string, files ...File) ([]*multipart.FileHeader, error) {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	for _, f := range files {
+		part, err := w.CreateFormFile(fieldName, f.Name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := part.Write(f.Data); err != nil {
+			return nil, err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	form, err := multipart.NewReader(&body, w.Boundary()).ReadForm(int64(body.Len()) + 1)
+	if err != nil {
+		return nil, err
+	}
+	headers := form.File[fieldName]
+	if len(headers) != len(files) {
+		return nil, fmt.Errorf("expected %d files, got %d", len(files), len(headers))
+	}
+	return headers, nil
+}
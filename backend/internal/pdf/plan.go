@@ -0,0 +1,103 @@
+package pdf
+
+import (
+	"context"
+	"fmt"
+)
+
+// JobPlan は dryRun で返す処理計画です。入力の検証とページ数の確認までを行い、PDF の処理は行いません。
+type JobPlan struct {
+	Operation OperationType    `json:"operation"`
+	Inputs    []SourceFileMeta `json:"inputs"`
+	// Outputs は生成される成果物です。先頭が主成果物です。
+	Outputs []PlannedOutput `json:"outputs"`
+	// TargetPages は処理の対象になるページ（1-based）です。全ページが対象の場合や、ページ単位の処理でない場合は省略します。
+	TargetPages []int `json:"targetPages,omitempty"`
+	// Rotations は回転角度ごとの対象ページ（1-based）です（rotate のみ）。
+	Rotations map[int][]int `json:"rotations,omitempty"`
+}
+
+// PlannedOutput は処理計画上の成果物です。
+type PlannedOutput struct {
+	Name  string     `json:"name"`
+	Kind  ResultKind `json:"kind"`
+	Pages int        `json:"pages,omitempty"`
+	// PageOrder は成果物のページ順を入力のページ番号（1-based）で表したものです。入力と同じ順序の場合は省略します。
+	PageOrder []int `json:"pageOrder,omitempty"`
+	// Files は成果物に含める入力ファイル名を結合順に並べたものです（merge のみ）。
+	Files []string `json:"files,omitempty"`
+}
+
+// planner は dryRun に対応する操作が実装します。
+// job.files のページ数は確認済みです。Operation と Inputs は呼び出し側で設定します。
+type planner interface {
+	Plan(s *Service, job *jobInput) (*JobPlan, error)
+}
+
+// PlanJob は準備済みのジョブ jobID の処理計画を返します。ジョブは実行せず、ワークスペースも削除しません。
+// DEFER_PAGE_COUNT で確認を後回しにしたページ数は、実行時と同じくここで確認します。
+func (s *Service) PlanJob(ctx context.Context, jobID string) (*JobPlan, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ws := s.workspaceFor(jobID)
+	manifest, err := loadManifest(s.fs, ws.dir)
+	if err != nil {
+		return nil, err
+	}
+	op, ok := lookupOperation(manifest.Operation)
+	if !ok {
+		return nil, fmt.Errorf("unsupported operation: %s", manifest.Operation)
+	}
+	p, ok := op.(planner)
+	if !ok {
+		return nil, newError("INVALID_INPUT", "この処理は dryRun に対応していません。", nil)
+	}
+
+	stored := storedFilesFromManifest(ws.dir, manifest)
+	if manifest.PagesDeferred {
+		releasePlain, err := s.unsealInputs(ws, manifest, stored, nil)
+		if err != nil {
+			return nil, err
+		}
+		err = s.countDeferredPages(ctx, ws, manifest, stored)
+		releasePlain()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	plan, err := p.Plan(s, &jobInput{ws: ws, manifest: manifest, files: stored})
+	if err != nil {
+		return nil, err
+	}
+	plan.Operation = manifest.Operation
+	plan.Inputs = make([]SourceFileMeta, len(stored))
+	for i, sf := range stored {
+		plan.Inputs[i] = SourceFileMeta{Name: sf.originalName, Size: sf.size, Pages: sf.pages}
+	}
+	return plan, nil
+}
+
+// singleOutputPlan は入力と同じページ数の PDF を1つ生成する操作の処理計画を返します。
+// pagesRaw が空でない場合は、その範囲を TargetPages とします。
+func singleOutputPlan(name string, file storedFile, pagesRaw string) (*JobPlan, error) {
+	plan := &JobPlan{Outputs: []PlannedOutput{{Name: name, Kind: ResultKindPDF, Pages: file.pages}}}
+	if pagesRaw != "" {
+		ranges, err := parsePageRanges(pagesRaw, file.pages)
+		if err != nil {
+			return nil, err
+		}
+		plan.TargetPages = expandPageRanges(ranges)
+	}
+	return plan, nil
+}
+
+// expandPageRanges はページ範囲を並び順のまま1ページずつのページ番号に展開します。
+func expandPageRanges(ranges []PageRange) []int {
+	var pages []int
+	for _, pr := range ranges {
+		pages = append(pages, pr.pages()...)
+	}
+	return pages
+}
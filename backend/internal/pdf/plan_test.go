@@ -0,0 +1,177 @@
+package pdf
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yourusername/paper-forge/internal/config"
+	"github.com/yourusername/paper-forge/internal/pdf/pdftest"
+)
+
+func TestPlanJobDescribesOutputs(t *testing.T) {
+	svc := NewService(&config.Config{MaxFileSize: 1 << 20, MaxPages: 20, DeferPageCount: true})
+	svc.tmpRoot = t.TempDir()
+	headers, err := pdftest.FileHeaders("files[]",
+		pdftest.File{Name: "a.pdf", Data: pdftest.Synthetic(2, 0)},
+		pdftest.File{Name: "b.pdf", Data: pdftest.Synthetic(3, 0)},
+	)
+	if err != nil {
+		t.Fatalf("failed to build multipart files: %v", err)
+	}
+	ctx := context.Background()
+
+	plan := func(manifest *JobManifest, err error) *JobPlan {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("prepare returned error: %v", err)
+		}
+		p, err := svc.PlanJob(ctx, manifest.JobID)
+		if err != nil {
+			t.Fatalf("PlanJob returned error: %v", err)
+		}
+		return p
+	}
+
+	// ページ数の確認を後回しにした merge も、計画の作成時にページ数を確認する
+	merged := plan(svc.PrepareMergeJob(ctx, headers, []int{1, 0}, false, JobOptions{}))
+	if want := []PlannedOutput{{Name: outputFilename, Kind: ResultKindPDF, Pages: 5, Files: []string{"b.pdf", "a.pdf"}}}; !reflect.DeepEqual(merged.Outputs, want) {
+		t.Fatalf("unexpected merge plan: %+v", merged.Outputs)
+	}
+	if merged.Operation != OperationMerge || len(merged.Inputs) != 2 || merged.Inputs[1].Pages != 3 {
+		t.Fatalf("unexpected merge inputs: %+v", merged)
+	}
+
+	// 目次を付ける場合は先頭の1ページを加える
+	withTOC := plan(svc.PrepareMergeJob(ctx, headers, nil, true, JobOptions{}))
+	if withTOC.Outputs[0].Pages != 5+tocPages {
+		t.Fatalf("merge plan should include the table of contents page: %+v", withTOC.Outputs)
+	}
+
+	reordered := plan(svc.PrepareReorderJob(ctx, headers[1], []int{2, 0, 1}, "", JobOptions{}))
+	if got := reordered.Outputs[0].PageOrder; !reflect.DeepEqual(got, []int{3, 1, 2}) {
+		t.Fatalf("unexpected reorder page order: %v", got)
+	}
+
+	split := plan(svc.PrepareSplitJob(ctx, headers[1], "1,2-", SplitOutputParts, JobOptions{}))
+	wantParts := []PlannedOutput{
+		{Name: "part-01.pdf", Kind: ResultKindPDF, Pages: 1, PageOrder: []int{1}},
+		{Name: "part-02.pdf", Kind: ResultKindPDF, Pages: 2, PageOrder: []int{2, 3}},
+	}
+	if !reflect.DeepEqual(split.Outputs, wantParts) {
+		t.Fatalf("unexpected split plan: %+v", split.Outputs)
+	}
+
+	rotated := plan(svc.PrepareRotateJob(ctx, headers[1], map[string]int{"1": 90, "2-3": 180}, JobOptions{}))
+	if !reflect.DeepEqual(rotated.Rotations, map[int][]int{90: {1}, 180: {2, 3}}) || !reflect.DeepEqual(rotated.TargetPages, []int{1, 2, 3}) {
+		t.Fatalf("unexpected rotate plan: %+v", rotated)
+	}
+}
+
+func TestSplitHandlerDryRunReturnsPlan(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	svc := NewService(&config.Config{MaxFileSize: 1 << 20, MaxPages: 20})
+	svc.tmpRoot = t.TempDir()
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	fileWriter, err := writer.CreateFormFile("file", "a.pdf")
+	if err != nil {
+		t.Fatalf("failed to create form file: %v", err)
+	}
+	if _, err := fileWriter.Write(pdftest.Synthetic(4, 0)); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	_ = writer.WriteField("ranges", "1-2,3-")
+	_ = writer.WriteField("dryRun", "true")
+	if err := writer.Close(); err != nil {
+		t.Fatalf("failed to close writer: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/pdf/split", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	rec := httptest.NewRecorder()
+	router := gin.New()
+	router.POST("/api/pdf/split", SplitHandler(svc, HandlerOptions{}))
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d body=%s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		DryRun bool    `json:"dryRun"`
+		Plan   JobPlan `json:"plan"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if !resp.DryRun || len(resp.Plan.Outputs) != 3 || resp.Plan.Outputs[0].Kind != ResultKindZIP || resp.Plan.Outputs[2].Pages != 2 {
+		t.Fatalf("unexpected plan: %s", rec.Body.String())
+	}
+
+	// 処理は行わないため、準備したワークスペースは残さない
+	entries, err := os.ReadDir(svc.tmpRoot)
+	if err != nil {
+		t.Fatalf("failed to read tmp root: %v", err)
+	}
+	for _, e := range entries {
+		if e.IsDir() && e.Name() != cacheDirName {
+			t.Fatalf("workspace %s should be discarded after a dry run", e.Name())
+		}
+	}
+}
+
+func TestSplitHandlerBurstModePlansOneFilePerPage(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	svc := NewService(&config.Config{MaxFileSize: 1 << 20, MaxPages: 20})
+	svc.tmpRoot = t.TempDir()
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	fileWriter, err := writer.CreateFormFile("file", "a.pdf")
+	if err != nil {
+		t.Fatalf("failed to create form file: %v", err)
+	}
+	if _, err := fileWriter.Write(pdftest.Synthetic(3, 0)); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	_ = writer.WriteField("mode", "burst")
+	_ = writer.WriteField("dryRun", "true")
+	if err := writer.Close(); err != nil {
+		t.Fatalf("failed to close writer: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/pdf/split", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	rec := httptest.NewRecorder()
+	router := gin.New()
+	router.POST("/api/pdf/split", SplitHandler(svc, HandlerOptions{}))
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d body=%s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Plan JobPlan `json:"plan"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	want := []PlannedOutput{
+		{Name: splitFilename, Kind: ResultKindZIP},
+		{Name: "page-0001.pdf", Kind: ResultKindPDF, Pages: 1, PageOrder: []int{1}},
+		{Name: "page-0002.pdf", Kind: ResultKindPDF, Pages: 1, PageOrder: []int{2}},
+		{Name: "page-0003.pdf", Kind: ResultKindPDF, Pages: 1, PageOrder: []int{3}},
+	}
+	if !reflect.DeepEqual(resp.Plan.Outputs, want) {
+		t.Fatalf("unexpected plan: %+v", resp.Plan.Outputs)
+	}
+}
@@ -0,0 +1,243 @@
+package pdf
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+
+	pdfapi "github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// 成果物の後処理は 透かし → 処理記録 → 受領書 → PDF のバージョン → 暗号化 の順に行います。
+// 透かし・処理記録・バージョンはいずれも PDF を読み込んで書き換える処理のため、段階ごとに読み込み直して
+// 書き出し直すと、同じ内容をワークスペースへ何度も書くことになります。
+// finishArtifacts はこれらを読み込んだ PDF（pdfcpu の Context）に続けて適用し、成果物ごとに一度だけ書き出します。
+// 暗号化が有効な場合はその書き出しを暗号化しながら行うため、平文の中間ファイルも作りません。
+// ファイルを入力とする段階（受領書の結合、PDF から作り直す ZIP）がある場合は、段階ごとに一時ファイルを経由します。
+
+// pdfRewrite は読み込んだ成果物の PDF を書き換える後処理の段階です。
+type pdfRewrite struct {
+	// failure は失敗したときのエラーの文言です。
+	failure string
+	apply   func(pdfCtx *model.Context) error
+}
+
+// pdfRewritePlan は成果物の PDF に続けて適用する後処理です。
+type pdfRewritePlan struct {
+	steps []pdfRewrite
+	// watermark は透かしを付ける場合に true です。pdfcpu の透かしは最適化済みの Context を前提とするため、読み込み方を変えます。
+	watermark bool
+	// version は書き出す PDF のヘッダーのバージョンです。空の場合は書き換えません。
+	version string
+}
+
+// finishArtifacts は RunJob で実行した操作の成果物に後処理を適用します。
+func (s *Service) finishArtifacts(ctx context.Context, manifest *JobManifest, result *Result) error {
+	plan := s.pdfRewritePlan(ctx, manifest, result)
+	if len(plan.steps) == 0 || !streamableArtifacts(manifest, result) {
+		return s.finishArtifactFiles(ctx, manifest, result)
+	}
+	seal, err := s.artifactSealer()
+	if err != nil {
+		return fmt.Errorf("成果物の暗号化に失敗しました: %w", err)
+	}
+	for i := range result.Artifacts {
+		if err := rewriteArtifact(&result.Artifacts[i], plan, seal); err != nil {
+			return err
+		}
+	}
+	if primary := result.Artifacts[0]; primary.Path == result.OutputPath {
+		result.OutputSize = primary.Size
+	}
+	return nil
+}
+
+// finishArtifactFiles は後処理を段階ごとに適用します。各段階は成果物のファイルを書き換えます。
+func (s *Service) finishArtifactFiles(ctx context.Context, manifest *JobManifest, result *Result) error {
+	if err := s.applyWatermark(manifest.Operation, result); err != nil {
+		return fmt.Errorf("透かしの追加に失敗しました: %w", err)
+	}
+	if err := s.applyAuditRecord(ctx, manifest, result); err != nil {
+		return fmt.Errorf("処理記録の埋め込みに失敗しました: %w", err)
+	}
+	if err := s.applyReceipt(ctx, manifest, result); err != nil {
+		return fmt.Errorf("受領書の作成に失敗しました: %w", err)
+	}
+	if err := s.applyPDFVersion(manifest, result); err != nil {
+		return fmt.Errorf("PDF のバージョンの変更に失敗しました: %w", err)
+	}
+	if err := s.sealArtifacts(result); err != nil {
+		return fmt.Errorf("成果物の暗号化に失敗しました: %w", err)
+	}
+	return nil
+}
+
+// streamableArtifacts は後処理をファイルを経由せずに適用できるかを返します。
+// 受領書は成果物のファイルと結合し、ZIP は書き換えた PDF のファイルから作り直すため、どちらかがある場合はファイルを経由します。
+func streamableArtifacts(manifest *JobManifest, result *Result) bool {
+	if result == nil || len(result.Artifacts) == 0 || manifest.Options.Receipt != "" {
+		return false
+	}
+	for _, a := range result.Artifacts {
+		if a.Kind != ResultKindPDF {
+			return false
+		}
+	}
+	return true
+}
+
+// pdfRewritePlan はジョブの成果物の PDF に適用する後処理を、finishArtifactFiles と同じ順に並べます。
+func (s *Service) pdfRewritePlan(ctx context.Context, manifest *JobManifest, result *Result) pdfRewritePlan {
+	var plan pdfRewritePlan
+	if s.watermark.appliesTo(manifest.Operation) {
+		plan.watermark = true
+		text, style := s.watermark.text, s.watermark.style
+		plan.steps = append(plan.steps, pdfRewrite{failure: "透かしの追加に失敗しました", apply: func(pdfCtx *model.Context) error {
+			// 透かしの設定は追加先の PDF のリソースを記録するため、成果物ごとに作り直す
+			wm, err := pdfapi.TextWatermark(text, style, true, false, types.POINTS)
+			if err != nil {
+				return err
+			}
+			return pdfcpu.AddWatermarks(pdfCtx, nil, wm)
+		}})
+	}
+	if result != nil && s.audit.appliesTo(manifest.Operation) {
+		record := s.newAuditRecord(ctx, manifest)
+		if meta, ok := result.Meta.(*MetadataMeta); ok {
+			record.Document = &meta.Document
+		}
+		packet := record.xmp()
+		plan.steps = append(plan.steps, pdfRewrite{failure: "処理記録の埋め込みに失敗しました", apply: func(pdfCtx *model.Context) error {
+			return setXMP(pdfCtx, packet)
+		}})
+	}
+	if version := s.outputPDFVersion(manifest.Options); version != "" {
+		plan.version = version
+		plan.steps = append(plan.steps, pdfRewrite{failure: "PDF のバージョンの変更に失敗しました", apply: func(pdfCtx *model.Context) error {
+			return prepareVersionWrite(pdfCtx, version)
+		}})
+	}
+	return plan
+}
+
+// artifactSealer は暗号化が有効な場合に、書き出しを暗号化する関数を返します。無効な場合は nil です。
+func (s *Service) artifactSealer() (func(w io.Writer) (io.WriteCloser, error), error) {
+	if s.keys == nil {
+		return nil, nil
+	}
+	keyID, key, err := s.keys.ActiveKey()
+	if err != nil {
+		return nil, err
+	}
+	return func(w io.Writer) (io.WriteCloser, error) {
+		return newSealWriter(w, keyID, key)
+	}, nil
+}
+
+// rewriteArtifact は成果物 a の PDF を一度だけ読み込んで plan の後処理を適用し、seal が指定されていれば暗号化しながら書き出します。
+// a のサイズと SHA-256 は、他の成果物と同じく平文の内容で更新します。
+func rewriteArtifact(a *Artifact, plan pdfRewritePlan, seal func(w io.Writer) (io.WriteCloser, error)) error {
+	pdfCtx, err := readRewriteContext(a.Path, plan.watermark)
+	if err != nil {
+		return fmt.Errorf("%s: %w", plan.steps[0].failure, err)
+	}
+	for _, step := range plan.steps {
+		if err := step.apply(pdfCtx); err != nil {
+			return fmt.Errorf("%s: %w", step.failure, err)
+		}
+	}
+
+	tmp := a.Path + ".rewrite"
+	size, digest, err := writeRewrittenPDF(pdfCtx, tmp, plan.version, seal)
+	if err == nil {
+		err = os.Rename(tmp, a.Path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		if seal != nil {
+			return fmt.Errorf("成果物の暗号化に失敗しました: %w", err)
+		}
+		return fmt.Errorf("成果物の書き出しに失敗しました: %w", err)
+	}
+	a.Size = size
+	a.SHA256 = digest
+	return nil
+}
+
+// readRewriteContext は path の PDF を読み込みます。透かしを付ける場合は pdfcpu の透かしの追加と同じく最適化してから返します。
+func readRewriteContext(path string, watermark bool) (*model.Context, error) {
+	if !watermark {
+		return pdfapi.ReadContextFile(path)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	conf := model.NewDefaultConfiguration()
+	conf.Cmd = model.ADDWATERMARKS
+	conf.OptimizeDuplicateContentStreams = false
+	return pdfapi.ReadValidateAndOptimize(f, conf)
+}
+
+// writeRewrittenPDF は pdfCtx を path に書き出し、平文のサイズと SHA-256 を返します。
+func writeRewrittenPDF(pdfCtx *model.Context, path, version string, seal func(w io.Writer) (io.WriteCloser, error)) (int64, string, error) {
+	out, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o640)
+	if err != nil {
+		return 0, "", err
+	}
+	defer out.Close()
+
+	var (
+		dst     io.Writer = out
+		closers []io.Closer
+	)
+	if seal != nil {
+		sealed, err := seal(out)
+		if err != nil {
+			return 0, "", err
+		}
+		dst = sealed
+		closers = append(closers, sealed)
+	}
+	hasher := sha256.New()
+	counter := &countingWriter{w: io.MultiWriter(dst, hasher)}
+	dst = counter
+	if version != "" {
+		header := &headerVersionWriter{w: dst, version: version}
+		dst = header
+		// 暗号化の終端より先に、ヘッダーまで書き出されたかを確認する
+		closers = append([]io.Closer{header}, closers...)
+	}
+
+	if err := pdfapi.WriteContext(pdfCtx, dst); err != nil {
+		return 0, "", err
+	}
+	for _, c := range closers {
+		if err := c.Close(); err != nil {
+			return 0, "", err
+		}
+	}
+	if err := out.Close(); err != nil {
+		return 0, "", err
+	}
+	return counter.n, hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// countingWriter は書き込んだバイト数を数えます。
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,109 @@
+package pdf
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	pdfapi "github.com/pdfcpu/pdfcpu/pkg/api"
+
+	"github.com/yourusername/paper-forge/internal/config"
+	"github.com/yourusername/paper-forge/internal/pdf/pdftest"
+)
+
+func TestFinishArtifactsRewritesEachPDFOnceAndSealsWhileWriting(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, outputFilename)
+	if err := os.WriteFile(path, pdftest.Synthetic(2, 0), 0o640); err != nil {
+		t.Fatalf("failed to write output: %v", err)
+	}
+	artifact, err := newArtifact(path, ResultKindPDF)
+	if err != nil {
+		t.Fatalf("newArtifact returned error: %v", err)
+	}
+	result := newResult(workspace{jobID: "job-1", dir: dir, outDir: dir}, OperationMerge, OSFS{}, []Artifact{artifact}, &MergeMeta{})
+	manifest := &JobManifest{JobID: "job-1", Operation: OperationMerge, Options: JobOptions{PDFVersion: "1.4"}}
+
+	svc := NewService(&config.Config{AuditRecord: true, WatermarkText: "Processed by ACME DMS"})
+	svc.keys = testKeys(t)
+	if !streamableArtifacts(manifest, result) {
+		t.Fatal("a single PDF without a receipt should be rewritten without intermediate files")
+	}
+	if err := svc.finishArtifacts(context.Background(), manifest, result); err != nil {
+		t.Fatalf("finishArtifacts returned error: %v", err)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Fatalf("no intermediate files should be left: %v", entries)
+	}
+	onDisk, _ := os.ReadFile(path)
+	if bytes.HasPrefix(onDisk, []byte("%PDF-")) {
+		t.Fatal("the output should be sealed while it is written")
+	}
+	file, err := openFS(svc.artifactFS(), path)
+	if err != nil {
+		t.Fatalf("failed to open sealed output: %v", err)
+	}
+	plain, err := io.ReadAll(file)
+	file.Close()
+	if err != nil {
+		t.Fatalf("failed to decrypt output: %v", err)
+	}
+
+	sum := sha256.Sum256(plain)
+	got := result.Artifacts[0]
+	if got.Size != int64(len(plain)) || got.SHA256 != hex.EncodeToString(sum[:]) || result.OutputSize != got.Size {
+		t.Fatalf("size and digest should describe the plaintext: %+v (plain %d bytes)", got, len(plain))
+	}
+	if !bytes.HasPrefix(plain, []byte("%PDF-1.4")) {
+		t.Fatalf("header should be rewritten to the requested version: %q", plain[:8])
+	}
+	if !strings.Contains(string(plain), "<pfa:JobID>job-1</pfa:JobID>") {
+		t.Fatal("the processing record should be embedded")
+	}
+	if pages, err := pdfapi.PageCount(bytes.NewReader(plain), nil); err != nil || pages != 2 {
+		t.Fatalf("rewritten output should be a readable PDF with every page: pages=%d err=%v", pages, err)
+	}
+	if !bytes.Contains(plain, []byte("/OCProperties")) {
+		t.Fatal("the watermark should be added")
+	}
+}
+
+func TestFinishArtifactsFallsBackToFilesForReceiptsAndZips(t *testing.T) {
+	pdfArtifact := Artifact{Name: outputFilename, Kind: ResultKindPDF}
+	zipArtifact := Artifact{Name: splitFilename, Kind: ResultKindZIP}
+
+	if streamableArtifacts(&JobManifest{Options: JobOptions{Receipt: ReceiptModeAppend}}, &Result{Artifacts: []Artifact{pdfArtifact}}) {
+		t.Fatal("receipts are merged with the output file and need the file-based steps")
+	}
+	if streamableArtifacts(&JobManifest{}, &Result{Artifacts: []Artifact{zipArtifact, pdfArtifact}}) {
+		t.Fatal("zips are rebuilt from the rewritten PDF files and need the file-based steps")
+	}
+}
+
+func TestFinishArtifactsReportsTheFailingStep(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, outputFilename)
+	if err := os.WriteFile(path, []byte("not a pdf"), 0o640); err != nil {
+		t.Fatalf("failed to write output: %v", err)
+	}
+	result := newResult(workspace{jobID: "job-1", dir: dir, outDir: dir}, OperationMerge, OSFS{},
+		[]Artifact{{Name: outputFilename, Kind: ResultKindPDF, Path: path}}, nil)
+	manifest := &JobManifest{JobID: "job-1", Operation: OperationMerge, Options: JobOptions{PDFVersion: "1.4"}}
+
+	err := NewService(&config.Config{}).finishArtifacts(context.Background(), manifest, result)
+	if err == nil || !strings.Contains(err.Error(), "PDF のバージョンの変更に失敗しました") {
+		t.Fatalf("expected the version step to be reported, got %v", err)
+	}
+	if _, statErr := os.Stat(path + ".rewrite"); !errors.Is(statErr, os.ErrNotExist) {
+		t.Fatalf("no partial output should be left: %v", statErr)
+	}
+}
@@ -0,0 +1,71 @@
+package pdf
+
+import (
+	"bytes"
+	"regexp"
+)
+
+// PrecheckMaxBytes は事前チェックで1ファイルあたりに読み取る先頭部分の最大バイト数です。
+const PrecheckMaxBytes = 64 * 1024
+
+// precheckHeaderWindow は PDF ヘッダーを探す範囲です。仕様上、ヘッダーの前に任意のデータが付く場合があります。
+const precheckHeaderWindow = 1024
+
+var pdfHeaderPattern = regexp.MustCompile(`%PDF-(\d)\.(\d)`)
+
+// 暗号化の判定結果
+const (
+	EncryptionNone      = "none"      // 暗号化されていない（ファイル全体を確認済み）
+	EncryptionEncrypted = "encrypted" // 暗号化されている
+	EncryptionUnknown   = "unknown"   // 先頭部分からは判定できない（暗号化辞書はファイル末尾にあることが多い）
+)
+
+// PrecheckResult はファイル先頭部分だけで行う簡易チェックの結果です。
+type PrecheckResult struct {
+	Name       string       `json:"name"`
+	OK         bool         `json:"ok"`
+	Version    string       `json:"version,omitempty"`
+	Encryption string       `json:"encryption"`
+	Error      *UploadError `json:"error,omitempty"`
+}
+
+// PrecheckHead はファイルの先頭部分 head から、PDF ヘッダー・バージョン・暗号化の有無を判定します。
+// head がファイル全体の場合（PrecheckMaxBytes 未満で %%EOF で終わる場合）は暗号化なしと判定できます。
+func PrecheckHead(name string, head []byte) PrecheckResult {
+	result := PrecheckResult{Name: name, Encryption: EncryptionUnknown}
+
+	window := head
+	if len(window) > precheckHeaderWindow {
+		window = window[:precheckHeaderWindow]
+	}
+	m := pdfHeaderPattern.FindSubmatch(window)
+	if m == nil {
+		result.Error = &UploadError{Code: "UNSUPPORTED_PDF", Message: name + " はPDF形式ではありません。"}
+		return result
+	}
+	result.Version = string(m[1]) + "." + string(m[2])
+	if !supportedPDFVersion(result.Version) {
+		result.Error = &UploadError{Code: "UNSUPPORTED_PDF", Message: name + " は対応していないPDFバージョン(" + result.Version + ")です。"}
+		return result
+	}
+
+	switch {
+	case bytes.Contains(head, []byte("/Encrypt")):
+		result.Encryption = EncryptionEncrypted
+		result.Error = &UploadError{Code: "ENCRYPTED_PDF", Message: name + " はパスワードで保護されているため処理できません。"}
+		return result
+	case len(head) < PrecheckMaxBytes && bytes.HasSuffix(bytes.TrimRight(head, "\r\n \t"), []byte("%%EOF")):
+		result.Encryption = EncryptionNone
+	}
+
+	result.OK = true
+	return result
+}
+
+func supportedPDFVersion(v string) bool {
+	switch v {
+	case "1.0", "1.1", "1.2", "1.3", "1.4", "1.5", "1.6", "1.7", "2.0":
+		return true
+	}
+	return false
+}
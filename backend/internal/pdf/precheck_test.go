@@ -0,0 +1,40 @@
+package pdf
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPrecheckHead(t *testing.T) {
+	large := append([]byte("%PDF-1.7\n"), bytes.Repeat([]byte("x"), PrecheckMaxBytes)...)
+	cases := []struct {
+		name       string
+		head       []byte
+		ok         bool
+		version    string
+		encryption string
+		code       string
+	}{
+		{name: "small complete", head: []byte("%PDF-1.4\n1 0 obj\n<<>>\nendobj\ntrailer\n<<>>\n%%EOF\n"), ok: true, version: "1.4", encryption: EncryptionNone},
+		{name: "leading garbage", head: []byte("\xef\xbb\xbf%PDF-2.0\n"), ok: true, version: "2.0", encryption: EncryptionUnknown},
+		{name: "truncated head", head: large[:PrecheckMaxBytes], ok: true, version: "1.7", encryption: EncryptionUnknown},
+		{name: "encrypted", head: []byte("%PDF-1.6\ntrailer\n<< /Encrypt 5 0 R >>\n%%EOF"), version: "1.6", encryption: EncryptionEncrypted, code: "ENCRYPTED_PDF"},
+		{name: "not pdf", head: []byte("\x89PNG\r\n\x1a\n"), encryption: EncryptionUnknown, code: "UNSUPPORTED_PDF"},
+		{name: "unknown version", head: []byte("%PDF-3.1\n"), version: "3.1", encryption: EncryptionUnknown, code: "UNSUPPORTED_PDF"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := PrecheckHead("a.pdf", tc.head)
+			if got.OK != tc.ok || got.Version != tc.version || got.Encryption != tc.encryption {
+				t.Fatalf("unexpected result: %#v", got)
+			}
+			code := ""
+			if got.Error != nil {
+				code = got.Error.Code
+			}
+			if code != tc.code {
+				t.Fatalf("error code = %q, want %q", code, tc.code)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package pdf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	pdfapi "github.com/pdfcpu/pdfcpu/pkg/api"
+)
+
+const (
+	// DefaultPreviewWidth は成果物のプレビューの幅（ピクセル）の既定値です。
+	DefaultPreviewWidth = 800
+	// minPreviewWidth / maxPreviewWidth は指定できるプレビューの幅の範囲です。
+	minPreviewWidth = 100
+	maxPreviewWidth = 2000
+	// previewDirName は描画したプレビューを保存するワークスペース内のディレクトリです。
+	// 成果物と同じくワークスペースの有効期限で削除されます。
+	previewDirName = "preview"
+)
+
+// RenderResultPreview はジョブの成果物 artifact（空の場合は主成果物）の page ページ目を幅 width ピクセルの PNG にします。
+// width が 0 の場合は DefaultPreviewWidth です。描画した画像はワークスペースに保存し、同じページ・幅の要求には保存した画像を返します。
+func (s *Service) RenderResultPreview(ctx context.Context, jobID, artifact string, page, width int) ([]byte, error) {
+	if width == 0 {
+		width = DefaultPreviewWidth
+	}
+	if width < minPreviewWidth || width > maxPreviewWidth {
+		return nil, newError("INVALID_INPUT", fmt.Sprintf("width は%d〜%dの範囲で指定してください。", minPreviewWidth, maxPreviewWidth), nil)
+	}
+	if page < 1 {
+		return nil, newError("INVALID_INPUT", "page は1以上のページ番号で指定してください。", nil)
+	}
+	if !ValidWorkspaceID(jobID) {
+		return nil, previewNotFound()
+	}
+
+	result, file, err := s.OpenArtifact(jobID, artifact)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, previewNotFound()
+		}
+		return nil, err
+	}
+	defer file.Close()
+	if result.ResultKind != ResultKindPDF {
+		return nil, newError("INVALID_INPUT", "プレビューできるのはPDFの成果物のみです。artifact でPDFの成果物を指定してください。", nil)
+	}
+
+	ws := s.workspaceFor(jobID)
+	cachePath := filepath.Join(ws.dir, previewDirName, previewFilename(result.OutputFilename, page, width))
+	if data, err := readFileFS(s.artifactFS(), cachePath); err == nil {
+		return data, nil
+	}
+
+	// 同じページの同時要求で書きかけの画像を返さないよう、一時ディレクトリで描画してから置き換える
+	tmpDir, err := os.MkdirTemp(ws.dir, ".preview-")
+	if err != nil {
+		return nil, fmt.Errorf("プレビューの作業ディレクトリを作成できませんでした: %w", err)
+	}
+	defer func() {
+		_ = removeDir(tmpDir)
+	}()
+
+	inputPath := result.OutputPath
+	if s.keys != nil {
+		// 保存時に暗号化された成果物は、Ghostscript に渡すため平文に復号する
+		inputPath = filepath.Join(tmpDir, "result.pdf")
+		if err := copyToFile(file, inputPath); err != nil {
+			return nil, fmt.Errorf("成果物の復号に失敗しました: %w", err)
+		}
+	}
+
+	pdfCtx, err := pdfapi.ReadContextFile(inputPath)
+	if err != nil {
+		return nil, newError("DAMAGED_PDF", fmt.Sprintf("%s を読み込めませんでした。", result.OutputFilename), err)
+	}
+	pages, err := readPageInfo(pdfCtx)
+	if err != nil {
+		return nil, newError("DAMAGED_PDF", fmt.Sprintf("%s のページの大きさを読み込めませんでした。", result.OutputFilename), err)
+	}
+	if page > len(pages) {
+		return nil, newError("INVALID_RANGE", fmt.Sprintf("page は1〜%dの範囲で指定してください（%s は%dページです）。", len(pages), result.OutputFilename, len(pages)), nil)
+	}
+	batches, err := thumbnailBatches(pages[page-1:page], width)
+	if err != nil {
+		return nil, newError("DAMAGED_PDF", fmt.Sprintf("%s のページの大きさが正しくありません。", result.OutputFilename), err)
+	}
+
+	if err := s.renderer().renderPNG(ctx, inputPath, filepath.Join(tmpDir, "page-%03d.png"), page, page, batches[0].dpi); err != nil {
+		return nil, err
+	}
+	rendered := filepath.Join(tmpDir, "page-001.png")
+	data, err := os.ReadFile(rendered)
+	if err != nil {
+		return nil, fmt.Errorf("プレビューの画像を読み込めませんでした: %w", err)
+	}
+
+	// 保存に失敗しても描画した画像は返す（次の要求で描画し直す）
+	if err := s.storePreview(rendered, cachePath); err != nil {
+		log.Printf("failed to store preview job=%s page=%d: %v", jobID, page, err)
+	}
+	return data, nil
+}
+
+// storePreview は描画した画像を保存先へ移します。保存時の暗号化が有効な場合は成果物と同じく暗号化します。
+func (s *Service) storePreview(rendered, cachePath string) error {
+	if s.keys != nil {
+		keyID, key, err := s.keys.ActiveKey()
+		if err != nil {
+			return err
+		}
+		if err := sealFileInPlace(rendered, keyID, key); err != nil {
+			return err
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(cachePath), 0o750); err != nil {
+		return err
+	}
+	return os.Rename(rendered, cachePath)
+}
+
+// previewFilename は成果物 name の page ページ目を幅 width で描画した画像の保存名です。
+func previewFilename(name string, page, width int) string {
+	base := strings.TrimSuffix(filepath.Base(name), filepath.Ext(name))
+	return fmt.Sprintf("%s-p%d-w%d.png", base, page, width)
+}
+
+func previewNotFound() error {
+	return newError("JOB_RESULT_NOT_FOUND", "ジョブの成果物が見つかりませんでした。完了前か、保存期間を過ぎています。", nil)
+}
+
+func copyToFile(src io.Reader, path string) error {
+	out, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, src); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
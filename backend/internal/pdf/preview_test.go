@@ -0,0 +1,97 @@
+package pdf
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yourusername/paper-forge/internal/pdf/pdftest"
+)
+
+func TestRenderResultPreviewCachesRenderedPages(t *testing.T) {
+	runner := &pngRunner{}
+	svc := newGhostscriptTestService(runner)
+	svc.tmpRoot = t.TempDir()
+
+	ws, err := svc.createWorkspace()
+	if err != nil {
+		t.Fatalf("createWorkspace returned error: %v", err)
+	}
+	if err := writeManifest(svc.fs, ws.dir, &JobManifest{JobID: ws.jobID, Operation: OperationSplit}); err != nil {
+		t.Fatalf("writeManifest returned error: %v", err)
+	}
+	for name, data := range map[string][]byte{splitFilename: []byte("PK zip"), "part-01.pdf": pdftest.Synthetic(3, 0)} {
+		if err := os.WriteFile(filepath.Join(ws.outDir, name), data, 0o640); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	artifacts := []Artifact{{Name: splitFilename, Kind: ResultKindZIP}, {Name: "part-01.pdf", Kind: ResultKindPDF}}
+	if err := writeArtifacts(svc.fs, ws.dir, artifacts); err != nil {
+		t.Fatalf("writeArtifacts returned error: %v", err)
+	}
+
+	ctx := context.Background()
+	data, err := svc.RenderResultPreview(ctx, ws.jobID, "part-01.pdf", 2, 0)
+	if err != nil {
+		t.Fatalf("RenderResultPreview returned error: %v", err)
+	}
+	if len(data) == 0 || len(runner.calls) != 1 {
+		t.Fatalf("expected one render, got %d calls", len(runner.calls))
+	}
+	if args := strings.Join(runner.calls[0].Args, " "); !strings.Contains(args, "-dFirstPage=2 -dLastPage=2") {
+		t.Fatalf("unexpected Ghostscript args: %s", args)
+	}
+	if _, err := os.Stat(filepath.Join(ws.dir, previewDirName, "part-01-p2-w800.png")); err != nil {
+		t.Fatalf("preview should be stored in the workspace: %v", err)
+	}
+	if _, err := svc.RenderResultPreview(ctx, ws.jobID, "part-01.pdf", 2, 0); err != nil || len(runner.calls) != 1 {
+		t.Fatalf("second request should use the stored preview: calls=%d err=%v", len(runner.calls), err)
+	}
+
+	items, err := svc.PurgeWorkspace(ws.jobID)
+	if err != nil {
+		t.Fatalf("PurgeWorkspace returned error: %v", err)
+	}
+	found := false
+	for _, item := range items {
+		found = found || (item.Kind == "preview" && item.Name == "part-01-p2-w800.png")
+	}
+	if !found {
+		t.Fatalf("purge receipt should list stored previews: %+v", items)
+	}
+}
+
+func TestRenderResultPreviewRejectsInvalidRequests(t *testing.T) {
+	svc := newGhostscriptTestService(&pngRunner{})
+	svc.tmpRoot = t.TempDir()
+	ws, err := svc.createWorkspace()
+	if err != nil {
+		t.Fatalf("createWorkspace returned error: %v", err)
+	}
+	if err := writeManifest(svc.fs, ws.dir, &JobManifest{JobID: ws.jobID, Operation: OperationSplit}); err != nil {
+		t.Fatalf("writeManifest returned error: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(ws.outDir, splitFilename), []byte("PK zip"), 0o640); err != nil {
+		t.Fatalf("failed to write zip: %v", err)
+	}
+
+	ctx := context.Background()
+	cases := []struct {
+		jobID, artifact string
+		page, width     int
+		code            string
+	}{
+		{ws.jobID, "", 1, 0, "INVALID_INPUT"},    // ZIP の成果物
+		{ws.jobID, "", 0, 0, "INVALID_INPUT"},    // ページ番号
+		{ws.jobID, "", 1, 5000, "INVALID_INPUT"}, // 幅
+		{ws.jobID, "part-09.pdf", 1, 0, "JOB_RESULT_NOT_FOUND"},
+		{"../" + ws.jobID, "", 1, 0, "JOB_RESULT_NOT_FOUND"},
+	}
+	for _, tc := range cases {
+		if _, err := svc.RenderResultPreview(ctx, tc.jobID, tc.artifact, tc.page, tc.width); !IsError(err, tc.code) {
+			t.Fatalf("%+v: expected %s, got %v", tc, tc.code, err)
+		}
+	}
+}
@@ -1,17 +1,140 @@
 package pdf
 
+import (
+	"context"
+	"mime/multipart"
+	"sync"
+)
+
+// Stage はジョブ進捗の段階を表します。ジョブ状態レスポンスの progress.stage にそのまま出力されます。
+type Stage string
+
+const (
+	StageQueued    Stage = "queued"    // キュー投入済み・未実行
+	StageLoad      Stage = "load"      // 入力ファイルとマニフェストの読み込み
+	StageProcess   Stage = "process"   // PDF処理本体（pdfcpu / Ghostscript）
+	StageWrite     Stage = "write"     // 成果物とメタデータの書き出し
+	StageCompleted Stage = "completed" // 完了
+)
+
+// StageSpan は段階ごとに割り当てた全体進捗（%）の範囲です。
+type StageSpan struct {
+	Stage Stage `json:"stage"`
+	Start int   `json:"start"`
+	End   int   `json:"end"`
+}
+
+// stageSpans は全操作で共通の段階の順序と重み付けです。段階の進み具合は Start〜End の間に線形に割り当てます。
+var stageSpans = []StageSpan{
+	{Stage: StageQueued, Start: 0, End: 0},
+	{Stage: StageLoad, Start: 0, End: 20},
+	{Stage: StageProcess, Start: 20, End: 80},
+	{Stage: StageWrite, Start: 80, End: 100},
+	{Stage: StageCompleted, Start: 100, End: 100},
+}
+
+// StageSpans は段階の順序と重み付けの一覧を返します。
+func StageSpans() []StageSpan {
+	return append([]StageSpan(nil), stageSpans...)
+}
+
+// Percent は段階内の進み具合 fraction（0〜1）を全体の進捗（%）に換算します。
+func (s Stage) Percent(fraction float64) int {
+	if fraction < 0 {
+		fraction = 0
+	}
+	if fraction > 1 {
+		fraction = 1
+	}
+	for _, span := range stageSpans {
+		if span.Stage == s {
+			return span.Start + int(float64(span.End-span.Start)*fraction)
+		}
+	}
+	return 0
+}
+
 // ProgressReporter は進捗更新用コールバックです。
-type ProgressReporter func(stage string, percent int)
+type ProgressReporter func(stage Stage, percent int)
 
-func reportProgress(cb ProgressReporter, stage string, percent int) {
+// reportProgress は段階内の進み具合 fraction（0〜1）を全体進捗に換算して通知します。
+func reportProgress(cb ProgressReporter, stage Stage, fraction float64) {
 	if cb == nil {
 		return
 	}
-	if percent < 0 {
-		percent = 0
+	cb(stage, stage.Percent(fraction))
+}
+
+// byteProgress はファイルのコピー量（バイト）を段階内の進み具合として通知する io.Writer です。
+// 通知先はジョブ状態の更新（Redis への書き込み）なので、全体進捗の % が変わったときだけ通知します。
+// 複数の入力を並行して保存する場合も1つの byteProgress で合計を数えられるよう、加算はロックして行います。
+type byteProgress struct {
+	cb    ProgressReporter
+	stage Stage
+	total int64
+
+	mu   sync.Mutex
+	done int64
+	last int
+}
+
+func newByteProgress(cb ProgressReporter, stage Stage, total int64) *byteProgress {
+	return &byteProgress{cb: cb, stage: stage, total: total, last: -1}
+}
+
+func (p *byteProgress) Write(b []byte) (int, error) {
+	p.add(int64(len(b)))
+	return len(b), nil
+}
+
+func (p *byteProgress) add(n int64) {
+	if p == nil || p.cb == nil || p.total <= 0 {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.done += n
+	percent := p.stage.Percent(float64(p.done) / float64(p.total))
+	if percent == p.last {
+		return
+	}
+	p.last = percent
+	p.cb(p.stage, percent)
+}
+
+type storeProgressKey struct{}
+
+// WithStoreProgress は入力ファイルをワークスペースへ保存する間の進み具合を cb に通知するよう ctx に設定します。
+// 保存したバイト数をアップロードで宣言されたサイズの合計に対する割合として、load 段階の進捗で通知します。
+func WithStoreProgress(ctx context.Context, cb ProgressReporter) context.Context {
+	return context.WithValue(ctx, storeProgressKey{}, cb)
+}
+
+// storeProgress は ctx に設定された保存の進捗の通知先を、合計 total バイトの byteProgress として返します。
+// 複数の入力をまとめて保存する storeMultipartFiles は、合計を数えた byteProgress を ctx に設定して各ファイルの保存に渡します。
+// 通知先が無い場合は nil です。
+func storeProgress(ctx context.Context, total int64) *byteProgress {
+	switch v := ctx.Value(storeProgressKey{}).(type) {
+	case *byteProgress:
+		return v
+	case ProgressReporter:
+		if v != nil {
+			return newByteProgress(v, StageLoad, total)
+		}
+	}
+	return nil
+}
+
+// withSharedStoreProgress は files の宣言サイズの合計で数える byteProgress を ctx に設定します。
+func withSharedStoreProgress(ctx context.Context, files []*multipart.FileHeader) context.Context {
+	var total int64
+	for _, fh := range files {
+		if fh != nil {
+			total += fh.Size
+		}
 	}
-	if percent > 100 {
-		percent = 100
+	if p := storeProgress(ctx, total); p != nil {
+		return context.WithValue(ctx, storeProgressKey{}, p)
 	}
-	cb(stage, percent)
+	return ctx
 }
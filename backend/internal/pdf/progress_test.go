@@ -0,0 +1,55 @@
+package pdf
+
+import "testing"
+
+func TestStagePercent(t *testing.T) {
+	cases := []struct {
+		stage    Stage
+		fraction float64
+		want     int
+	}{
+		{StageQueued, 0, 0},
+		{StageLoad, 0, 0},
+		{StageLoad, 1, 20},
+		{StageProcess, 0, 20},
+		{StageProcess, 0.5, 50},
+		{StageProcess, 2, 80},
+		{StageWrite, 0, 80},
+		{StageCompleted, 1, 100},
+		{Stage("unknown"), 1, 0},
+	}
+	for _, tc := range cases {
+		if got := tc.stage.Percent(tc.fraction); got != tc.want {
+			t.Errorf("%s.Percent(%v) = %d, want %d", tc.stage, tc.fraction, got, tc.want)
+		}
+	}
+}
+
+func TestStageSpansAreOrdered(t *testing.T) {
+	spans := StageSpans()
+	for i := 1; i < len(spans); i++ {
+		if spans[i].Start < spans[i-1].End {
+			t.Fatalf("stage %s starts at %d before %s ends at %d", spans[i].Stage, spans[i].Start, spans[i-1].Stage, spans[i-1].End)
+		}
+	}
+}
+
+func TestByteProgressReportsOnlyChanges(t *testing.T) {
+	var got []int
+	p := newByteProgress(func(stage Stage, percent int) {
+		if stage != StageLoad {
+			t.Fatalf("unexpected stage: %s", stage)
+		}
+		got = append(got, percent)
+	}, StageLoad, 100)
+
+	for i := 0; i < 100; i++ {
+		_, _ = p.Write([]byte{0})
+	}
+	if len(got) != 21 || got[0] != 0 || got[len(got)-1] != 20 {
+		t.Fatalf("unexpected reports: %v", got)
+	}
+
+	var nilProgress *byteProgress
+	nilProgress.add(10)
+}
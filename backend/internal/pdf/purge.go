@@ -0,0 +1,214 @@
+package pdf
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrWorkspaceHeld はリーガルホールド中のため削除できないワークスペースを表します。
+var ErrWorkspaceHeld = errors.New("workspace is under legal hold")
+
+// PurgedItem は即時削除したデータ1件分の記録です。
+// SHA256 は削除直前に保存されていたバイト列（暗号化が有効な場合は暗号文）のハッシュです。
+type PurgedItem struct {
+	Kind      string    `json:"kind"` // input / output / manifest / metadata / preview / cache / upload / record
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	SHA256    string    `json:"sha256,omitempty"`
+	DeletedAt time.Time `json:"deletedAt"`
+}
+
+type purgeTarget struct {
+	kind string
+	name string
+	path string
+}
+
+// PurgeWorkspace はジョブの入力・成果物・マニフェストと、同じ入力から作られた結果キャッシュを即時に削除し、
+// 削除したファイルの一覧を返します。削除後にワークスペースが残っていないことを確認します。
+// ワークスペースが既に無い場合は空の一覧を返します。
+func (s *Service) PurgeWorkspace(jobID string) ([]PurgedItem, error) {
+	if strings.TrimSpace(jobID) == "" {
+		return nil, fmt.Errorf("jobID is required")
+	}
+	ws := s.workspaceFor(jobID)
+	manifest, err := loadManifest(s.fs, ws.dir)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+		// 有効期限で削除済み。作成途中の残骸があれば消しておく
+		return nil, s.removeVerified(ws.dir)
+	}
+	if s.workspaceHeld(ws.dir) {
+		return nil, ErrWorkspaceHeld
+	}
+
+	targets := []purgeTarget{
+		{kind: "manifest", name: manifestFilename, path: filepath.Join(ws.dir, manifestFilename)},
+		{kind: "metadata", name: artifactsFilename, path: filepath.Join(ws.dir, artifactsFilename)},
+		{kind: "metadata", name: resultManifestFilename, path: filepath.Join(ws.dir, resultManifestFilename)},
+		{kind: "metadata", name: "meta.json", path: filepath.Join(ws.dir, "meta.json")},
+	}
+	for _, f := range manifest.Files {
+		targets = append(targets, purgeTarget{kind: "input", name: f.OriginalName, path: filepath.Join(ws.inDir, f.StoredName)})
+	}
+	if artifacts, err := s.loadArtifacts(ws, manifest); err == nil {
+		for _, a := range artifacts {
+			targets = append(targets, purgeTarget{kind: "output", name: a.Name, path: a.Path})
+		}
+	}
+
+	var items []PurgedItem
+	for _, t := range targets {
+		item, ok, err := hashPurgeTarget(s.fs, t)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			items = append(items, item)
+		}
+	}
+	previews, err := hashLocalDir(filepath.Join(ws.dir, previewDirName), "preview")
+	if err != nil {
+		return nil, err
+	}
+	items = append(items, previews...)
+	cacheItems, err := s.purgeCacheEntry(manifest)
+	if err != nil {
+		return nil, err
+	}
+	items = append(items, cacheItems...)
+
+	if err := s.removeVerified(ws.dir); err != nil {
+		return nil, err
+	}
+	// 他のジョブが参照していない共有入力も残さない
+	s.sharedInputs.sweep()
+	return stampDeleted(items, s.now()), nil
+}
+
+// purgeCacheEntry は manifest と同じ入力・オプションの結果キャッシュを削除します。
+func (s *Service) purgeCacheEntry(manifest *JobManifest) ([]PurgedItem, error) {
+	key, ok := resultCacheKey(manifest)
+	if !ok {
+		return nil, nil
+	}
+	entryDir := filepath.Join(s.tmpRoot, cacheDirName, key)
+	items, err := hashLocalDir(entryDir, "cache")
+	if err != nil {
+		return nil, err
+	}
+	if err := removeDir(entryDir); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// PurgeResultCache は結果キャッシュをすべて削除し、削除したファイルの一覧を返します。
+func (s *Service) PurgeResultCache() ([]PurgedItem, error) {
+	cacheRoot := filepath.Join(s.tmpRoot, cacheDirName)
+	items, err := hashLocalDir(cacheRoot, "cache")
+	if err != nil {
+		return nil, err
+	}
+	if err := removeDir(cacheRoot); err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(cacheRoot); !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("結果キャッシュの削除を確認できませんでした: %s", cacheRoot)
+	}
+	return stampDeleted(items, s.now()), nil
+}
+
+// PurgeUploads は受信中・検証済みの分割アップロードをすべて破棄し、その一覧を返します。
+func (s *Service) PurgeUploads() []PurgedItem {
+	s.uploadsMu.Lock()
+	ids := make([]string, 0, len(s.uploads))
+	statuses := make([]UploadStatus, 0, len(s.uploads))
+	for id, session := range s.uploads {
+		session.mu.Lock()
+		statuses = append(statuses, session.status)
+		session.mu.Unlock()
+		ids = append(ids, id)
+	}
+	s.uploadsMu.Unlock()
+
+	items := make([]PurgedItem, 0, len(ids))
+	for i, id := range ids {
+		s.discardUpload(id)
+		items = append(items, PurgedItem{Kind: "upload", Name: statuses[i].Filename, Size: statuses[i].ReceivedBytes})
+	}
+	return stampDeleted(items, s.now())
+}
+
+// removeVerified はディレクトリを削除し、残っていないことを確認します。
+func (s *Service) removeVerified(dir string) error {
+	if err := removeDirFS(s.fs, dir); err != nil {
+		return err
+	}
+	if _, err := s.fs.Stat(dir); !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("ワークスペースの削除を確認できませんでした: %s", dir)
+	}
+	return nil
+}
+
+// hashPurgeTarget は削除対象のサイズとハッシュを計算します。ファイルが無い場合は ok=false を返します。
+func hashPurgeTarget(fsys FS, t purgeTarget) (PurgedItem, bool, error) {
+	f, err := openFS(fsys, t.path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return PurgedItem{}, false, nil
+		}
+		return PurgedItem{}, false, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return PurgedItem{}, false, err
+	}
+	return PurgedItem{Kind: t.kind, Name: t.name, Size: size, SHA256: hex.EncodeToString(h.Sum(nil))}, true, nil
+}
+
+// hashLocalDir はローカルディスク上のディレクトリ配下のファイルをすべてハッシュします（結果キャッシュ用）。
+func hashLocalDir(dir, kind string) ([]PurgedItem, error) {
+	items := []PurgedItem{}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, _ := filepath.Rel(dir, path)
+		item, ok, err := hashPurgeTarget(OSFS{}, purgeTarget{kind: kind, name: filepath.ToSlash(rel), path: path})
+		if err != nil {
+			return err
+		}
+		if ok {
+			items = append(items, item)
+		}
+		return nil
+	})
+	return items, err
+}
+
+func stampDeleted(items []PurgedItem, now time.Time) []PurgedItem {
+	deletedAt := now.UTC()
+	for i := range items {
+		items[i].DeletedAt = deletedAt
+	}
+	return items
+}
@@ -0,0 +1,90 @@
+package pdf
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/yourusername/paper-forge/internal/config"
+)
+
+func TestPurgeWorkspace(t *testing.T) {
+	fsys := newMemFS()
+	svc := NewServiceWithFS(&config.Config{}, fsys)
+
+	ws, err := svc.createWorkspace()
+	if err != nil {
+		t.Fatalf("createWorkspace returned error: %v", err)
+	}
+	input := []byte("%PDF-1.4\n% input\n")
+	in, err := fsys.OpenFile(filepath.Join(ws.inDir, "00.pdf"), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o640)
+	if err != nil {
+		t.Fatalf("failed to create input: %v", err)
+	}
+	_, _ = in.Write(input)
+	_ = in.Close()
+	if err := writeManifest(fsys, ws.dir, &JobManifest{
+		JobID:     ws.jobID,
+		Operation: OperationOptimize,
+		Files:     []JobFile{{StoredName: "00.pdf", OriginalName: "scan.pdf", Size: int64(len(input))}},
+	}); err != nil {
+		t.Fatalf("writeManifest returned error: %v", err)
+	}
+	out, err := fsys.OpenFile(filepath.Join(ws.outDir, optimizedFilename), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o640)
+	if err != nil {
+		t.Fatalf("failed to create output: %v", err)
+	}
+	_, _ = out.Write([]byte("%PDF-1.4\n% output\n"))
+	_ = out.Close()
+
+	items, err := svc.PurgeWorkspace(ws.jobID)
+	if err != nil {
+		t.Fatalf("PurgeWorkspace returned error: %v", err)
+	}
+	kinds := map[string]PurgedItem{}
+	for _, item := range items {
+		if item.SHA256 == "" || item.DeletedAt.IsZero() {
+			t.Fatalf("item %s/%s has no hash or timestamp", item.Kind, item.Name)
+		}
+		kinds[item.Kind] = item
+	}
+	sum := sha256.Sum256(input)
+	if got := kinds["input"]; got.Name != "scan.pdf" || got.SHA256 != hex.EncodeToString(sum[:]) {
+		t.Fatalf("unexpected input item: %+v", got)
+	}
+	if _, ok := kinds["output"]; !ok {
+		t.Fatalf("output was not reported: %+v", items)
+	}
+	if _, ok := kinds["manifest"]; !ok {
+		t.Fatalf("manifest was not reported: %+v", items)
+	}
+	if _, err := fsys.Stat(ws.dir); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("workspace should be removed, got %v", err)
+	}
+
+	if items, err := svc.PurgeWorkspace(uuid.NewString()); err != nil || len(items) != 0 {
+		t.Fatalf("purging a missing workspace should be a no-op: %v %v", items, err)
+	}
+}
+
+func TestPurgeWorkspaceKeepsHeldWorkspace(t *testing.T) {
+	fsys := newMemFS()
+	svc := NewServiceWithFS(&config.Config{}, fsys)
+	jobID := createFinishedJob(t, svc, fsys, OperationMerge, outputFilename, []byte("%PDF-1.4\n"))
+	if err := svc.HoldWorkspace(jobID, "litigation"); err != nil {
+		t.Fatalf("HoldWorkspace returned error: %v", err)
+	}
+
+	if _, err := svc.PurgeWorkspace(jobID); !errors.Is(err, ErrWorkspaceHeld) {
+		t.Fatalf("expected ErrWorkspaceHeld, got %v", err)
+	}
+	if _, err := fsys.Stat(filepath.Join(svc.workspaceFor(jobID).dir, manifestFilename)); err != nil {
+		t.Fatalf("held workspace should remain: %v", err)
+	}
+}
@@ -0,0 +1,130 @@
+package pdf
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// quickDefaultFilename は Content-Disposition も filename も無い場合の入力ファイル名です。
+const quickDefaultFilename = "document.pdf"
+
+// quickDefaults は簡易 API で操作に適用する既定のパラメータです。クエリで上書きできます。
+var quickDefaults = map[OperationType]url.Values{
+	// 分割の範囲は共有メニューからは指定しにくいため、1ページずつに分ける
+	OperationSplit: {"ranges": {SplitRangesEach}},
+}
+
+// QuickHandler は POST /api/quick/<op> のハンドラーを返します。
+// 本文に PDF をそのまま（application/pdf）受け取り、既定のパラメータとクエリの値を
+// フォームの項目として OperationHandler に渡します。共有メニューやショートカットなど、multipart を組み立てにくいクライアント向けです。
+func QuickHandler(svc OperationService, op OperationType, opts HandlerOptions) gin.HandlerFunc {
+	next := OperationHandler(svc, op, opts)
+	return func(c *gin.Context) {
+		if c.ContentType() != "application/pdf" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    "INVALID_INPUT",
+				"message": "本文に Content-Type: application/pdf でPDFファイルを送信してください。",
+			})
+			return
+		}
+		if c.Request.ContentLength == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    "INVALID_INPUT",
+				"message": "PDFファイルを選択してください。",
+			})
+			return
+		}
+
+		body := http.MaxBytesReader(c.Writer, c.Request.Body, MaxUploadTotalBytes)
+		form, err := SpoolFormFile("file", quickFilename(c), "application/pdf", body)
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+					"code":    "LIMIT_EXCEEDED",
+					"message": "ファイルサイズが上限を超えています。",
+				})
+				return
+			}
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    "INVALID_INPUT",
+				"message": "PDFファイルの受信に失敗しました。",
+			})
+			return
+		}
+
+		values := url.Values{}
+		for key, v := range quickDefaults[op] {
+			values[key] = v
+		}
+		for key, v := range c.Request.URL.Query() {
+			values[key] = v
+		}
+		delete(values, "filename")
+		// クラウドドライブへの接続はセッションからのみ設定する
+		delete(values, DeliverConnectionField)
+
+		form.Value = values
+		c.Request.MultipartForm = form
+		c.Request.Form = values
+		c.Request.PostForm = values
+		next(c)
+	}
+}
+
+// quickFilename は入力のファイル名を、クエリの filename、Content-Disposition の filename の順に決めます。
+func quickFilename(c *gin.Context) string {
+	name := strings.TrimSpace(c.Query("filename"))
+	if name == "" {
+		if _, params, err := mime.ParseMediaType(c.GetHeader("Content-Disposition")); err == nil {
+			name = strings.TrimSpace(params["filename"])
+		}
+	}
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+	if name == "" || name == "." || name == "/" {
+		return quickDefaultFilename
+	}
+	return name
+}
+
+// SpoolFormFile は r の内容を一時ファイルに書き出し、フィールド field にファイル filename として持つフォームを返します。
+// multipart 以外で受け取った入力を、アップロードされたファイルと同じ *multipart.FileHeader として扱うために使います。
+// 呼び出し側は使い終わったフォームを RemoveAll で削除します。
+func SpoolFormFile(field, filename, contentType string, r io.Reader) (*multipart.Form, error) {
+	pr, pw := io.Pipe()
+	writer := multipart.NewWriter(pw)
+	go func() {
+		header := make(textproto.MIMEHeader)
+		header.Set("Content-Disposition", mime.FormatMediaType("form-data", map[string]string{"name": field, "filename": filename}))
+		header.Set("Content-Type", contentType)
+		part, err := writer.CreatePart(header)
+		if err == nil {
+			_, err = io.Copy(part, r)
+		}
+		if err == nil {
+			err = writer.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+	// maxMemory を 0 にして、内容は常に一時ファイルへ書き出す
+	form, err := multipart.NewReader(pr, writer.Boundary()).ReadForm(0)
+	pr.CloseWithError(io.ErrClosedPipe)
+	if err != nil {
+		return nil, err
+	}
+	if len(form.File[field]) == 0 {
+		_ = form.RemoveAll()
+		return nil, fmt.Errorf("spooled form has no file")
+	}
+	return form, nil
+}
@@ -0,0 +1,110 @@
+package pdf
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yourusername/paper-forge/internal/config"
+	"github.com/yourusername/paper-forge/internal/pdf/pdftest"
+)
+
+func newQuickRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	svc := NewService(&config.Config{MaxFileSize: 1 << 20, MaxPages: 20})
+	svc.tmpRoot = t.TempDir()
+	router := gin.New()
+	router.POST("/api/quick/split", QuickHandler(svc, OperationSplit, HandlerOptions{}))
+	return router
+}
+
+func TestQuickHandlerAppliesDefaultsAndQuery(t *testing.T) {
+	router := newQuickRouter(t)
+
+	cases := []struct {
+		query string
+		want  []string
+	}{
+		// 既定では1ページずつに分割する
+		{query: "dryRun=true&filename=scan.pdf", want: []string{splitFilename, "page-0001.pdf", "page-0002.pdf", "page-0003.pdf"}},
+		{query: "dryRun=true&ranges=1-2,3", want: []string{splitFilename, "part-01.pdf", "part-02.pdf"}},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/api/quick/split?"+tc.query, bytes.NewReader(pdftest.Synthetic(3, 0)))
+		req.Header.Set("Content-Type", "application/pdf")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: unexpected status: %d body=%s", tc.query, rec.Code, rec.Body.String())
+		}
+		var resp struct {
+			Plan JobPlan `json:"plan"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		var names []string
+		for _, out := range resp.Plan.Outputs {
+			names = append(names, out.Name)
+		}
+		if !reflect.DeepEqual(names, tc.want) {
+			t.Fatalf("%s: unexpected outputs %v", tc.query, names)
+		}
+	}
+}
+
+func TestQuickHandlerRejectsNonPDFBody(t *testing.T) {
+	router := newQuickRouter(t)
+
+	for _, contentType := range []string{"multipart/form-data; boundary=x", "application/json", ""} {
+		req := httptest.NewRequest(http.MethodPost, "/api/quick/split", strings.NewReader("%PDF-1.7"))
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("content type %q: expected 400, got %d", contentType, rec.Code)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/quick/split", http.NoBody)
+	req.Header.Set("Content-Type", "application/pdf")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("empty body: expected 400, got %d", rec.Code)
+	}
+}
+
+func TestQuickFilename(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cases := []struct {
+		target      string
+		disposition string
+		want        string
+	}{
+		{target: "/?filename=scan.pdf", disposition: `attachment; filename="other.pdf"`, want: "scan.pdf"},
+		{target: "/", disposition: `attachment; filename="receipt.pdf"`, want: "receipt.pdf"},
+		{target: "/?filename=..%2F..%2Fetc%2Fpasswd", want: "passwd"},
+		{target: "/", want: quickDefaultFilename},
+	}
+	for _, tc := range cases {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodPost, tc.target, nil)
+		if tc.disposition != "" {
+			c.Request.Header.Set("Content-Disposition", tc.disposition)
+		}
+		if got := quickFilename(c); got != tc.want {
+			t.Fatalf("%s: got %q, want %q", tc.target, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,271 @@
+package pdf
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ReceiptMode は処理受領書（ジョブの処理内容を1ページにまとめた PDF）の付け方です。
+type ReceiptMode string
+
+const (
+	// ReceiptModeAppend は成果物の PDF の末尾に受領書のページを追加します。ZIP の成果物には receipt.pdf を同梱します。
+	ReceiptModeAppend ReceiptMode = "append"
+	// ReceiptModeAttach は受領書を別の成果物 receipt.pdf として追加します。
+	// 主成果物以外は同期レスポンスで返せないため、非同期ジョブでのみ扱います。
+	ReceiptModeAttach ReceiptMode = "attach"
+)
+
+const (
+	// receiptFilename は受領書の成果物・ZIP のエントリの名前です。
+	receiptFilename = "receipt.pdf"
+	// maxReceiptLines は1ページに収まる本文の行数です。入力・成果物が多い場合は残りの件数だけを記載します。
+	maxReceiptLines = 62
+
+	receiptFontSize = 8
+	receiptLeading  = 11
+	receiptMargin   = 50
+)
+
+func parseReceiptMode(raw string) (ReceiptMode, error) {
+	switch mode := ReceiptMode(strings.ToLower(strings.TrimSpace(raw))); mode {
+	case "":
+		return "", nil
+	case ReceiptModeAppend, ReceiptModeAttach:
+		return mode, nil
+	default:
+		return "", errors.New("receipt には append または attach を指定してください。")
+	}
+}
+
+// applyReceipt は処理パイプラインの最終段（処理記録の後）で、受領書を成果物に付けます。
+// 受領書に記載する成果物のハッシュは受領書を付ける前の値です。ジョブごとに内容が異なるため、結果のキャッシュは使いません。
+func (s *Service) applyReceipt(ctx context.Context, manifest *JobManifest, result *Result) error {
+	mode := manifest.Options.Receipt
+	if result == nil || mode == "" || len(result.Artifacts) == 0 {
+		return nil
+	}
+	record := s.newAuditRecord(ctx, manifest)
+	primary := result.Artifacts[0]
+	path := filepath.Join(filepath.Dir(primary.Path), receiptFilename)
+	if err := writeReceiptPDF(path, receiptLines(record, manifest.Options.Label, result.Artifacts)); err != nil {
+		return err
+	}
+
+	switch {
+	case mode == ReceiptModeAppend && primary.Kind == ResultKindZIP:
+		defer os.Remove(path)
+		if err := appendToZip(primary.Path, path, receiptFilename); err != nil {
+			return err
+		}
+		return refreshArtifact(result, 0)
+	case mode == ReceiptModeAppend && len(result.Artifacts) == 1:
+		defer os.Remove(path)
+		merged := primary.Path + ".receipt.pdf"
+		if err := mergeCreateFileCompat([]string{primary.Path, path}, merged); err != nil {
+			_ = os.Remove(merged)
+			return err
+		}
+		if err := os.Rename(merged, primary.Path); err != nil {
+			_ = os.Remove(merged)
+			return err
+		}
+		return refreshArtifact(result, 0)
+	default:
+		// 分割の output=parts など、ページを追加する1つの PDF が無い場合も別の成果物にする
+		artifact, err := newArtifact(path, ResultKindPDF)
+		if err != nil {
+			return err
+		}
+		artifact.Name = receiptFilename
+		result.Artifacts = append(result.Artifacts, artifact)
+		return nil
+	}
+}
+
+// receiptLines は受領書の本文を1行ずつ返します。フォントを埋め込まないため、ASCII で書きます。
+func receiptLines(record auditRecord, label string, artifacts []Artifact) []string {
+	lines := []string{
+		"Job ID:        " + record.JobID,
+	}
+	if label != "" && isPrintableASCII(label) {
+		lines = append(lines, "Label:         "+label)
+	}
+	lines = append(lines,
+		"Operations:    "+strings.Join(record.Steps, " > "),
+		"Received at:   "+record.ReceivedAt.Format(time.RFC3339),
+		"Completed at:  "+record.CompletedAt.Format(time.RFC3339),
+	)
+	tools := make([]string, 0, len(record.Tools))
+	for _, tool := range record.Tools {
+		tools = append(tools, tool.Name+" "+tool.Version)
+	}
+	lines = append(lines, "Tools:         "+strings.Join(tools, ", "), "")
+
+	inputs := make([]string, 0, len(record.Inputs))
+	for i, in := range record.Inputs {
+		inputs = append(inputs, fmt.Sprintf("%3d. sha256:%s  %d bytes, %d pages", i+1, in.SHA256, in.Size, in.Pages))
+	}
+	outputs := make([]string, 0, len(artifacts))
+	for _, a := range artifacts {
+		outputs = append(outputs, fmt.Sprintf("     %s  %d bytes", asciiOnly(a.Name), a.Size))
+		outputs = append(outputs, "     sha256:"+a.SHA256)
+	}
+
+	// 見出し2行と空行1行を除いた残りを入力・成果物で分ける。入力を優先する
+	room := maxReceiptLines - len(lines) - 3
+	inputs = truncateReceiptLines(inputs, min(len(inputs), room/2))
+	outputs = truncateReceiptLines(outputs, room-len(inputs))
+
+	lines = append(lines, fmt.Sprintf("Inputs (%d)", len(record.Inputs)))
+	lines = append(lines, inputs...)
+	lines = append(lines, "", fmt.Sprintf("Outputs (%d, hashes before this receipt was added)", len(artifacts)))
+	return append(lines, outputs...)
+}
+
+// truncateReceiptLines は lines を n 行に収めます。収まらない場合は最後の行を残りの行数の案内にします。
+func truncateReceiptLines(lines []string, n int) []string {
+	if len(lines) <= n {
+		return lines
+	}
+	if n <= 0 {
+		return nil
+	}
+	rest := len(lines) - (n - 1)
+	return append(lines[:n-1:n-1], fmt.Sprintf("     ... %d more lines omitted", rest))
+}
+
+func isPrintableASCII(s string) bool {
+	for _, r := range s {
+		if r < 0x20 || r > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// asciiOnly は ASCII 以外の文字を ? に置き換えます。
+func asciiOnly(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || r > 0x7e {
+			return '?'
+		}
+		return r
+	}, s)
+}
+
+// writeReceiptPDF は A4 縦1ページの受領書を書き出します。標準14フォント（Helvetica / Courier）だけを使い、フォントは埋め込みません。
+func writeReceiptPDF(path string, lines []string) error {
+	var content bytes.Buffer
+	fmt.Fprintf(&content, "BT /F2 16 Tf %d 792 Td (Processing Receipt) Tj ET\n", receiptMargin)
+	fmt.Fprintf(&content, "BT /F1 %d Tf %d Tl %d 770 Td\n", receiptFontSize, receiptLeading, receiptMargin)
+	for _, line := range lines {
+		fmt.Fprintf(&content, "(%s) Tj T*\n", escapePDFString(asciiOnly(line)))
+	}
+	content.WriteString("ET\n")
+	fmt.Fprintf(&content, "BT /F1 7 Tf %d 30 Td (Generated by paper-forge. Verify outputs by comparing their SHA-256 with the values above.) Tj ET", receiptMargin)
+
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 595 842] /Resources << /Font << /F1 5 0 R /F2 6 0 R >> >> /Contents 4 0 R >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", content.Len(), content.String()),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Courier >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold >>",
+		"<< /Title (Processing Receipt) /Producer (paper-forge) >>",
+	}
+	return writePDFObjects(path, objects)
+}
+
+// writePDFObjects は objects を1番から順に番号を付けたオブジェクトとして、相互参照表付きの PDF に書き出します。
+// 1番をカタログ、最後を文書情報辞書とします。
+func writePDFObjects(path string, objects []string) error {
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, len(objects), xref)
+	return os.WriteFile(path, buf.Bytes(), 0o640)
+}
+
+func escapePDFString(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "(", `\(`, ")", `\)`).Replace(s)
+}
+
+// appendToZip は ZIP の既存のエントリとコメントをそのまま残し、file を name として追加します。
+func appendToZip(zipPath, file, name string) (err error) {
+	reader, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return fmt.Errorf("zipファイルの読み込みに失敗しました: %w", err)
+	}
+	defer reader.Close()
+
+	tmp := zipPath + ".receipt"
+	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o640)
+	if err != nil {
+		return fmt.Errorf("zipファイルの作成に失敗しました: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			out.Close()
+			_ = os.Remove(tmp)
+		}
+	}()
+
+	writer := zip.NewWriter(out)
+	if reader.Comment != "" {
+		if err := writer.SetComment(reader.Comment); err != nil {
+			return fmt.Errorf("zipのコメントの設定に失敗しました: %w", err)
+		}
+	}
+	for _, f := range reader.File {
+		if err := writer.Copy(f); err != nil {
+			return fmt.Errorf("zipのエントリのコピーに失敗しました: %w", err)
+		}
+	}
+	src, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+	header, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return err
+	}
+	header.Name = name
+	header.Method = zip.Deflate
+	dst, err := writer.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("zipの中央ディレクトリの書き込みに失敗しました: %w", err)
+	}
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("zipファイルの書き込みに失敗しました: %w", err)
+	}
+	return os.Rename(tmp, zipPath)
+}
@@ -0,0 +1,175 @@
+package pdf
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yourusername/paper-forge/internal/config"
+)
+
+func receiptManifest(inputs int, mode ReceiptMode) *JobManifest {
+	manifest := &JobManifest{
+		JobID:     "job-1",
+		Operation: OperationSplit,
+		CreatedAt: time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC),
+		Options:   JobOptions{Label: "contract (v3)", Receipt: mode},
+	}
+	for i := range inputs {
+		manifest.Files = append(manifest.Files, JobFile{
+			StoredName: fmt.Sprintf("%02d.pdf", i),
+			Size:       1024,
+			Pages:      2,
+			SHA256:     strings.Repeat(strconv.Itoa(i%10), 64),
+		})
+	}
+	return manifest
+}
+
+func TestReceiptLinesFitOnePage(t *testing.T) {
+	svc := NewService(&config.Config{})
+	svc.now = func() time.Time { return time.Date(2026, 10, 16, 9, 30, 0, 0, time.UTC) }
+	record := svc.newAuditRecord(context.Background(), receiptManifest(1, ReceiptModeAppend))
+
+	lines := receiptLines(record, "contract (v3)", []Artifact{{Name: "split.zip", Size: 4096, SHA256: "ccc"}})
+	text := strings.Join(lines, "\n")
+	for _, want := range []string{
+		"Job ID:        job-1",
+		"Label:         contract (v3)",
+		"Received at:   2026-10-16T09:00:00Z",
+		"Completed at:  2026-10-16T09:30:00Z",
+		"sha256:" + strings.Repeat("0", 64),
+		"split.zip  4096 bytes",
+		"sha256:ccc",
+	} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("receipt lacks %q:\n%s", want, text)
+		}
+	}
+
+	// 1ページずつの分割のように成果物が多い場合も1ページに収める
+	record = svc.newAuditRecord(context.Background(), receiptManifest(20, ReceiptModeAppend))
+	artifacts := make([]Artifact, 200)
+	for i := range artifacts {
+		artifacts[i] = Artifact{Name: fmt.Sprintf("page-%04d.pdf", i+1), SHA256: "ddd"}
+	}
+	lines = receiptLines(record, "契約書", artifacts)
+	if len(lines) > maxReceiptLines {
+		t.Fatalf("receipt has %d lines, want at most %d", len(lines), maxReceiptLines)
+	}
+	text = strings.Join(lines, "\n")
+	if !strings.Contains(text, "Outputs (200") || !strings.Contains(text, "more lines omitted") {
+		t.Fatalf("truncated receipt should report the omitted outputs:\n%s", text)
+	}
+	if strings.Contains(text, "Label:") {
+		t.Fatalf("labels that cannot be rendered without embedded fonts should be left out:\n%s", text)
+	}
+}
+
+func TestWriteReceiptPDFHasValidXref(t *testing.T) {
+	path := filepath.Join(t.TempDir(), receiptFilename)
+	if err := writeReceiptPDF(path, []string{`Label: a (b) \ c`, "日本語"}); err != nil {
+		t.Fatalf("writeReceiptPDF: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(data, []byte(`(Label: a \(b\) \\ c) Tj`)) || !bytes.Contains(data, []byte("(???) Tj")) {
+		t.Fatalf("text should be escaped and limited to ASCII:\n%s", data)
+	}
+	idx := bytes.LastIndex(data, []byte("startxref\n"))
+	offset, err := strconv.Atoi(strings.TrimSpace(strings.SplitN(string(data[idx+len("startxref\n"):]), "\n", 2)[0]))
+	if err != nil || !bytes.HasPrefix(data[offset:], []byte("xref\n")) {
+		t.Fatalf("startxref does not point to the xref table: %v", err)
+	}
+	// 各オブジェクトの位置が xref と一致する
+	table := strings.Split(string(data[offset:]), "\n")
+	for i := 1; i <= 7; i++ {
+		off, _ := strconv.Atoi(table[2+i][:10])
+		if !bytes.HasPrefix(data[off:], []byte(fmt.Sprintf("%d 0 obj", i))) {
+			t.Fatalf("xref entry %d points to the wrong offset %d", i, off)
+		}
+	}
+}
+
+func TestApplyReceiptAddsToZipOrAttaches(t *testing.T) {
+	svc := NewService(&config.Config{})
+	dir := t.TempDir()
+	zipPath := filepath.Join(dir, splitFilename)
+	partPath := filepath.Join(dir, "part-01.pdf")
+	if err := os.WriteFile(partPath, []byte("%PDF-1.4 part"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := createZip(zipPath, []string{partPath}, "parts"); err != nil {
+		t.Fatal(err)
+	}
+	zipArtifact, err := newArtifact(zipPath, ResultKindZIP)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zipArtifact.Name = splitFilename
+
+	result := &Result{Artifacts: []Artifact{zipArtifact}, OutputPath: zipPath}
+	if err := svc.applyReceipt(context.Background(), receiptManifest(1, ReceiptModeAppend), result); err != nil {
+		t.Fatalf("applyReceipt: %v", err)
+	}
+	reader, err := zip.OpenReader(zipPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, f := range reader.File {
+		names = append(names, f.Name)
+	}
+	comment := reader.Comment
+	reader.Close()
+	if strings.Join(names, ",") != "part-01.pdf,"+receiptFilename || comment != "parts" {
+		t.Fatalf("unexpected zip entries %v (comment %q)", names, comment)
+	}
+	if result.Artifacts[0].SHA256 == zipArtifact.SHA256 || result.OutputSize != result.Artifacts[0].Size {
+		t.Fatalf("zip artifact should be refreshed: %+v", result.Artifacts[0])
+	}
+	if _, err := os.Stat(filepath.Join(dir, receiptFilename)); !os.IsNotExist(err) {
+		t.Fatalf("receipt added to the zip should not remain as a file: %v", err)
+	}
+
+	result = &Result{Artifacts: []Artifact{result.Artifacts[0]}, OutputPath: zipPath}
+	if err := svc.applyReceipt(context.Background(), receiptManifest(1, ReceiptModeAttach), result); err != nil {
+		t.Fatalf("applyReceipt: %v", err)
+	}
+	if len(result.Artifacts) != 2 || result.Artifacts[1].Name != receiptFilename || result.Artifacts[1].Kind != ResultKindPDF {
+		t.Fatalf("receipt should be attached as a separate artifact: %+v", result.Artifacts)
+	}
+	file, err := os.Open(result.Artifacts[1].Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	head, _ := io.ReadAll(io.LimitReader(file, 8))
+	if string(head) != "%PDF-1.4" {
+		t.Fatalf("attached receipt is not a PDF: %q", head)
+	}
+}
+
+func TestParseReceiptMode(t *testing.T) {
+	for raw, want := range map[string]ReceiptMode{"": "", " Append ": ReceiptModeAppend, "attach": ReceiptModeAttach} {
+		if got, err := parseReceiptMode(raw); err != nil || got != want {
+			t.Fatalf("parseReceiptMode(%q) = %q, %v", raw, got, err)
+		}
+	}
+	if _, err := parseReceiptMode("email"); err == nil {
+		t.Fatal("unknown receipt modes should be rejected")
+	}
+	if !(JobOptions{Receipt: ReceiptModeAttach}).requiresAsync() || (JobOptions{Receipt: ReceiptModeAppend}).requiresAsync() {
+		t.Fatal("only attached receipts should require an asynchronous job")
+	}
+}
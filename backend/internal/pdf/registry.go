@@ -0,0 +1,92 @@
+package pdf
+
+import (
+	"context"
+	"fmt"
+	"mime/multipart"
+	"net/url"
+	"sync"
+)
+
+// OutputSpec は操作が生成する成果物のファイル名と種別です。
+type OutputSpec struct {
+	Filename string
+	Kind     ResultKind
+}
+
+// PrepareRequest は操作の準備（入力の保存とマニフェスト作成）に必要なリクエスト内容です。
+type PrepareRequest struct {
+	Files   []*multipart.FileHeader
+	Params  url.Values // 操作固有のフォーム項目（order, ranges, preset など）
+	Options JobOptions
+}
+
+// singleFile は単一ファイルを受け取る操作向けに、先頭のファイルを返します。
+func (r PrepareRequest) singleFile() (*multipart.FileHeader, error) {
+	switch len(r.Files) {
+	case 0:
+		return nil, newError("INVALID_INPUT", "PDFファイルを選択してください。", nil)
+	case 1:
+		return r.Files[0], nil
+	default:
+		return nil, newError("INVALID_INPUT", "PDFファイルは1つだけ指定してください。", nil)
+	}
+}
+
+// jobInput は保存済みジョブの実行に必要な情報です。
+type jobInput struct {
+	ws       workspace
+	manifest *JobManifest
+	files    []storedFile
+}
+
+// operation は PDF 操作の実装です。各操作は自身のファイルで registerOperation により登録します。
+// 登録された操作はジョブ実行、成果物の取得、汎用ハンドラーから自動的に利用されます。
+type operation interface {
+	Type() OperationType
+	OutputSpec() OutputSpec
+	Prepare(ctx context.Context, s *Service, req PrepareRequest) (*JobManifest, error)
+	Execute(ctx context.Context, s *Service, job *jobInput, progress ProgressReporter) (*Result, error)
+}
+
+var (
+	registryMu    sync.RWMutex
+	registry      = map[OperationType]operation{}
+	registryOrder []OperationType
+)
+
+func registerOperation(op operation) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, exists := registry[op.Type()]; exists {
+		panic(fmt.Sprintf("pdf: operation %q registered twice", op.Type()))
+	}
+	registry[op.Type()] = op
+	registryOrder = append(registryOrder, op.Type())
+}
+
+func lookupOperation(t OperationType) (operation, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	op, ok := registry[t]
+	return op, ok
+}
+
+// Operations は登録済みの操作種別を登録順に返します。
+func Operations() []OperationType {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	return append([]OperationType(nil), registryOrder...)
+}
+
+// PrepareJob は登録済みの操作 op のジョブを準備し、マニフェストを返します。
+func (s *Service) PrepareJob(ctx context.Context, op OperationType, req PrepareRequest) (*JobManifest, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	impl, ok := lookupOperation(op)
+	if !ok {
+		return nil, newError("INVALID_INPUT", fmt.Sprintf("未対応の操作です: %s", op), nil)
+	}
+	return impl.Prepare(ctx, s, req)
+}
@@ -0,0 +1,56 @@
+package pdf
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/yourusername/paper-forge/internal/config"
+)
+
+func TestBuiltinOperationsAreRegistered(t *testing.T) {
+	want := map[OperationType]OutputSpec{
+		OperationMerge:     {Filename: outputFilename, Kind: ResultKindPDF},
+		OperationReorder:   {Filename: reorderFilename, Kind: ResultKindPDF},
+		OperationSplit:     {Filename: splitFilename, Kind: ResultKindZIP},
+		OperationOptimize:  {Filename: optimizedFilename, Kind: ResultKindPDF},
+		OperationRotate:    {Filename: rotateFilename, Kind: ResultKindPDF},
+		OperationExtract:   {Filename: extractFilename, Kind: ResultKindPDF},
+		OperationWatermark: {Filename: watermarkFilename, Kind: ResultKindPDF},
+		OperationStamp:     {Filename: stampFilename, Kind: ResultKindPDF},
+	}
+	registered := map[OperationType]bool{}
+	for _, op := range Operations() {
+		registered[op] = true
+	}
+	for op, spec := range want {
+		if !registered[op] {
+			t.Fatalf("operation %q is not registered", op)
+		}
+		impl, ok := lookupOperation(op)
+		if !ok {
+			t.Fatalf("lookupOperation(%q) failed", op)
+		}
+		if got := impl.OutputSpec(); got != spec {
+			t.Fatalf("OutputSpec(%q) = %#v, want %#v", op, got, spec)
+		}
+	}
+}
+
+func TestPrepareJobRejectsUnknownOperation(t *testing.T) {
+	svc := NewServiceWithFS(&config.Config{}, newMemFS())
+	_, err := svc.PrepareJob(context.Background(), OperationType("ocr"), PrepareRequest{})
+	var pdfErr *Error
+	if !errors.As(err, &pdfErr) || pdfErr.Code != "INVALID_INPUT" {
+		t.Fatalf("expected INVALID_INPUT error, got %v", err)
+	}
+}
+
+func TestPrepareJobRequiresSingleFile(t *testing.T) {
+	svc := NewServiceWithFS(&config.Config{}, newMemFS())
+	_, err := svc.PrepareJob(context.Background(), OperationSplit, PrepareRequest{})
+	var pdfErr *Error
+	if !errors.As(err, &pdfErr) || pdfErr.Code != "INVALID_INPUT" {
+		t.Fatalf("expected INVALID_INPUT error, got %v", err)
+	}
+}
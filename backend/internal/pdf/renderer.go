@@ -0,0 +1,55 @@
+package pdf
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/yourusername/paper-forge/internal/execrun"
+)
+
+// renderer は Ghostscript でページを PNG 画像に変換します。
+type renderer struct {
+	gs     *ghostscript
+	runner execrun.Runner
+}
+
+func (s *Service) renderer() *renderer {
+	return &renderer{gs: s.gs, runner: s.runner}
+}
+
+// renderPNG は inputPath の firstPage〜lastPage を解像度 dpi で PNG に変換します。
+// outputPattern には Ghostscript のページ番号の書式（例: "p-%03d.png"）を含め、番号は firstPage を 1 として振られます。
+func (r *renderer) renderPNG(ctx context.Context, inputPath, outputPattern string, firstPage, lastPage int, dpi float64) error {
+	gsPath, err := r.gs.resolve()
+	if err != nil {
+		return err
+	}
+	out, err := r.runner.Run(ctx, execrun.Command{Name: gsPath, Args: renderArgs(inputPath, outputPattern, firstPage, lastPage, dpi)})
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		return newError("UNSUPPORTED_PDF", "Ghostscriptによる画像の作成に失敗しました。ファイルが破損していないか確認してください。", fmt.Errorf("%w: %s", err, out.Combined()))
+	}
+	return nil
+}
+
+func renderArgs(inputPath, outputPattern string, firstPage, lastPage int, dpi float64) []string {
+	return []string{
+		"-sDEVICE=png16m",
+		"-dNOPAUSE",
+		"-dQUIET",
+		"-dBATCH",
+		"-dSAFER",
+		// 表示される領域（CropBox）を描画し、文字と図形は縮小しても読めるようにアンチエイリアスをかける
+		"-dUseCropBox",
+		"-dTextAlphaBits=4",
+		"-dGraphicsAlphaBits=4",
+		fmt.Sprintf("-dFirstPage=%d", firstPage),
+		fmt.Sprintf("-dLastPage=%d", lastPage),
+		"-r" + strconv.FormatFloat(dpi, 'f', 4, 64),
+		fmt.Sprintf("-sOutputFile=%s", outputPattern),
+		inputPath,
+	}
+}
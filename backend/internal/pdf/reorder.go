@@ -4,18 +4,21 @@ import (
 	"context"
 	"fmt"
 	"mime/multipart"
-	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
 	pdfapi "github.com/pdfcpu/pdfcpu/pkg/api"
+
+	"github.com/yourusername/paper-forge/internal/tenant"
 )
 
 const reorderFilename = "reordered.pdf"
 
 // ReorderMultipart は単一PDFのページ順入替を実行します。
-func (s *Service) ReorderMultipart(ctx context.Context, file *multipart.FileHeader, order []int) (_ *Result, err error) {
+// mode に ReorderModeFree を指定すると、同じページの繰り返しやページの省略を許します。
+func (s *Service) ReorderMultipart(ctx context.Context, file *multipart.FileHeader, order []int, mode ReorderMode) (_ *Result, err error) {
 	if ctx == nil {
 		ctx = context.Background()
 	}
@@ -30,7 +33,7 @@ func (s *Service) ReorderMultipart(ctx context.Context, file *multipart.FileHead
 		return nil, err
 	}
 
-	state, _, err := s.prepareReorder(ctx, file, order)
+	state, _, err := s.prepareReorder(ctx, file, order, mode, JobOptions{})
 	if err != nil {
 		return nil, err
 	}
@@ -52,31 +55,49 @@ type reorderState struct {
 	file storedFile
 }
 
-func (s *Service) prepareReorder(ctx context.Context, file *multipart.FileHeader, order []int) (*reorderState, *JobManifest, error) {
+func (s *Service) prepareReorder(ctx context.Context, file *multipart.FileHeader, order []int, mode ReorderMode, opts JobOptions) (*reorderState, *JobManifest, error) {
+	mode, err := normalizeReorderMode(mode)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	release, err := s.reserveWorkspace(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer release()
+
 	ws, err := s.createWorkspace()
 	if err != nil {
 		return nil, nil, err
 	}
 
-	stored, err := s.storeMultipartFile(ctx, file, ws.inDir, 0)
+	stored, err := s.storeMultipartFile(ctx, file, ws.inDir, 0, false)
 	if err != nil {
 		_ = removeDir(ws.dir)
 		return nil, nil, err
 	}
 
-	if err := validateOrder(order, stored.pages); err != nil {
+	if err := s.validateReorder(order, stored.pages, mode); err != nil {
 		_ = removeDir(ws.dir)
 		return nil, nil, err
 	}
 
 	manifest := &JobManifest{
 		JobID:     ws.jobID,
+		Tenant:    tenant.FromContext(ctx),
 		Operation: OperationReorder,
 		Files:     toJobFiles([]storedFile{stored}),
 		Order:     append([]int(nil), order...),
+		Options:   opts,
+		StoreMs:   ws.elapsed().Milliseconds(),
 		CreatedAt: s.now().UTC(),
 	}
-	if err := writeManifest(ws.dir, manifest); err != nil {
+	// 既定の並べ替えはマニフェストに書かず、従来のマニフェストと同じ内容にする
+	if mode == ReorderModeFree {
+		manifest.Mode = mode
+	}
+	if err := writeManifest(s.fs, ws.dir, manifest); err != nil {
 		_ = removeDir(ws.dir)
 		return nil, nil, fmt.Errorf("ジョブマニフェストの保存に失敗しました: %w", err)
 	}
@@ -93,17 +114,15 @@ func (s *Service) executeReorder(ctx context.Context, state *reorderState, order
 		selectedPages[i] = strconv.Itoa(idx + 1)
 	}
 
-	reportProgress(progress, "process", 40)
+	reportProgress(progress, StageProcess, 0)
 	outputPath := filepath.Join(ws.outDir, reorderFilename)
-	if err := pdfapi.CollectFile(stored.path, outputPath, selectedPages, nil); err != nil {
+	if err := runStep(ctx, func() error { return pdfapi.CollectFile(stored.path, outputPath, selectedPages, nil) }); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
 		return nil, newError("UNSUPPORTED_PDF", "PDFのページ入替に失敗しました。ファイルが破損していないか確認してください。", err)
 	}
-	reportProgress(progress, "write", 80)
-
-	outInfo, err := os.Stat(outputPath)
-	if err != nil {
-		return nil, fmt.Errorf("出力ファイルの確認に失敗しました: %w", err)
-	}
+	reportProgress(progress, StageWrite, 0)
 
 	sourceMeta := SourceFileMeta{
 		Name:  stored.originalName,
@@ -128,7 +147,7 @@ func (s *Service) executeReorder(ctx context.Context, state *reorderState, order
 	}
 
 	metaPath := filepath.Join(ws.dir, "meta.json")
-	if err := writeJSON(metaPath, meta); err != nil {
+	if err := writeJSON(s.fs, metaPath, meta); err != nil {
 		return nil, fmt.Errorf("メタデータの保存に失敗しました: %w", err)
 	}
 
@@ -140,33 +159,61 @@ func (s *Service) executeReorder(ctx context.Context, state *reorderState, order
 		_ = removeDir(ws.dir)
 	})
 
-	reportProgress(progress, "completed", 100)
-
-	return &Result{
-		JobID:          ws.jobID,
-		Operation:      OperationReorder,
-		OutputPath:     outputPath,
-		OutputFilename: reorderFilename,
-		OutputSize:     outInfo.Size(),
-		ResultKind:     ResultKindPDF,
-		Meta: &ReorderMeta{
-			Original: sourceMeta,
-			Order:    append([]int(nil), order...),
-		},
-		jobDir: ws.dir,
-	}, nil
+	artifact, err := newArtifact(outputPath, ResultKindPDF)
+	if err != nil {
+		return nil, fmt.Errorf("成果物の確認に失敗しました: %w", err)
+	}
+
+	reportProgress(progress, StageCompleted, 1)
+
+	return newResult(ws, OperationReorder, s.fs, []Artifact{artifact}, &ReorderMeta{
+		Original: sourceMeta,
+		Order:    append([]int(nil), order...),
+	}), nil
 }
 
 // PrepareReorderJob は非同期ジョブ用に入力を保存します。
-func (s *Service) PrepareReorderJob(ctx context.Context, file *multipart.FileHeader, order []int) (*JobManifest, error) {
+func (s *Service) PrepareReorderJob(ctx context.Context, file *multipart.FileHeader, order []int, mode ReorderMode, opts JobOptions) (*JobManifest, error) {
 	if ctx == nil {
 		ctx = context.Background()
 	}
-	_, manifest, err := s.prepareReorder(ctx, file, order)
+	_, manifest, err := s.prepareReorder(ctx, file, order, mode, opts)
 	if err != nil {
 		return nil, err
 	}
-	return manifest, nil
+	return s.sealJob(manifest)
+}
+
+// normalizeReorderMode は order の扱いを検証します。空の場合は全ページの並べ替えです。
+func normalizeReorderMode(m ReorderMode) (ReorderMode, error) {
+	switch ReorderMode(strings.ToLower(strings.TrimSpace(string(m)))) {
+	case "", ReorderModePermutation:
+		return ReorderModePermutation, nil
+	case ReorderModeFree:
+		return ReorderModeFree, nil
+	default:
+		return "", newError("INVALID_INPUT", fmt.Sprintf("modeには permutation または free を指定してください (received: %s)", m), nil)
+	}
+}
+
+// validateReorder は mode に応じて order を検証します。
+// free では各番号がページ数の範囲内であることだけを確認し、成果物のページ数は MAX_PAGES までとします。
+func (s *Service) validateReorder(order []int, pageCount int, mode ReorderMode) error {
+	if mode != ReorderModeFree {
+		return validateOrder(order, pageCount)
+	}
+	if len(order) == 0 {
+		return newError("INVALID_INPUT", "ページの順序を指定してください。", nil)
+	}
+	if s.cfg.MaxPages > 0 && len(order) > s.cfg.MaxPages {
+		return newError("LIMIT_EXCEEDED", fmt.Sprintf("入替後のページ数が上限(%dページ)を超えています。", s.cfg.MaxPages), nil)
+	}
+	for _, idx := range order {
+		if idx < 0 || idx >= pageCount {
+			return newError("INVALID_INPUT", "order配列に不正なページ番号が含まれています。", nil)
+		}
+	}
+	return nil
 }
 
 func validateOrder(order []int, pageCount int) error {
@@ -187,3 +234,44 @@ func validateOrder(order []int, pageCount int) error {
 
 	return nil
 }
+
+func init() {
+	registerOperation(reorderOperation{})
+}
+
+type reorderOperation struct{}
+
+func (reorderOperation) Type() OperationType { return OperationReorder }
+
+func (reorderOperation) OutputSpec() OutputSpec {
+	return OutputSpec{Filename: reorderFilename, Kind: ResultKindPDF}
+}
+
+func (reorderOperation) Prepare(ctx context.Context, s *Service, req PrepareRequest) (*JobManifest, error) {
+	file, err := req.singleFile()
+	if err != nil {
+		return nil, err
+	}
+	order, err := parseOrderValues(req.Params.Get("order"), req.Params["order[]"])
+	if err != nil {
+		return nil, newError("INVALID_INPUT", err.Error(), nil)
+	}
+	return s.PrepareReorderJob(ctx, file, order, ReorderMode(req.Params.Get("mode")), req.Options)
+}
+
+func (reorderOperation) Execute(ctx context.Context, s *Service, job *jobInput, progress ProgressReporter) (*Result, error) {
+	state := &reorderState{ws: job.ws, file: job.files[0]}
+	return s.executeReorder(ctx, state, job.manifest.Order, progress)
+}
+
+func (reorderOperation) Plan(s *Service, job *jobInput) (*JobPlan, error) {
+	file := job.files[0]
+	if err := s.validateReorder(job.manifest.Order, file.pages, job.manifest.Mode); err != nil {
+		return nil, err
+	}
+	pageOrder := make([]int, len(job.manifest.Order))
+	for i, idx := range job.manifest.Order {
+		pageOrder[i] = idx + 1
+	}
+	return &JobPlan{Outputs: []PlannedOutput{{Name: reorderFilename, Kind: ResultKindPDF, Pages: len(pageOrder), PageOrder: pageOrder}}}, nil
+}
@@ -0,0 +1,66 @@
+package pdf
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/yourusername/paper-forge/internal/config"
+	"github.com/yourusername/paper-forge/internal/pdf/pdftest"
+)
+
+func TestValidateReorderModes(t *testing.T) {
+	svc := NewService(&config.Config{MaxPages: 5})
+
+	// 1ページ目を2回、5ページ目を除く
+	order := []int{0, 0, 1, 2, 3}
+	if err := svc.validateReorder(order, 5, ReorderModePermutation); !IsError(err, "INVALID_INPUT") {
+		t.Fatalf("permutation mode should reject repeated pages, got %v", err)
+	}
+	if err := svc.validateReorder(order, 5, ReorderModeFree); err != nil {
+		t.Fatalf("free mode should accept repeated and omitted pages: %v", err)
+	}
+
+	cases := map[string]struct {
+		order []int
+		code  string
+	}{
+		"out of range": {[]int{0, 5}, "INVALID_INPUT"},
+		"negative":     {[]int{-1}, "INVALID_INPUT"},
+		"empty":        {nil, "INVALID_INPUT"},
+		"too many":     {[]int{0, 0, 0, 0, 0, 0}, "LIMIT_EXCEEDED"},
+	}
+	for name, tc := range cases {
+		if err := svc.validateReorder(tc.order, 5, ReorderModeFree); !IsError(err, tc.code) {
+			t.Fatalf("%s: expected %s, got %v", name, tc.code, err)
+		}
+	}
+
+	if _, err := normalizeReorderMode("shuffle"); !IsError(err, "INVALID_INPUT") {
+		t.Fatalf("expected INVALID_INPUT for unknown mode, got %v", err)
+	}
+}
+
+func TestPrepareReorderJobFreeMode(t *testing.T) {
+	svc := NewService(&config.Config{MaxFileSize: 1 << 20, MaxPages: 20})
+	svc.tmpRoot = t.TempDir()
+	headers, err := pdftest.FileHeaders("file", pdftest.File{Name: "a.pdf", Data: pdftest.Synthetic(3, 0)})
+	if err != nil {
+		t.Fatalf("failed to build multipart files: %v", err)
+	}
+
+	manifest, err := svc.PrepareReorderJob(context.Background(), headers[0], []int{2, 2, 0}, "FREE", JobOptions{})
+	if err != nil {
+		t.Fatalf("PrepareReorderJob returned error: %v", err)
+	}
+	if manifest.Mode != ReorderModeFree {
+		t.Fatalf("unexpected mode: %q", manifest.Mode)
+	}
+	plan, err := svc.PlanJob(context.Background(), manifest.JobID)
+	if err != nil {
+		t.Fatalf("PlanJob returned error: %v", err)
+	}
+	if out := plan.Outputs[0]; out.Pages != 3 || !reflect.DeepEqual(out.PageOrder, []int{3, 3, 1}) {
+		t.Fatalf("unexpected plan: %+v", out)
+	}
+}
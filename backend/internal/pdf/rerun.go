@@ -0,0 +1,118 @@
+package pdf
+
+import (
+	"encoding/json"
+	"path/filepath"
+)
+
+// Asynq はワーカーが処理の途中で停止したタスクを再配信するため、同じジョブの RunJob が同じワークスペースで再び呼ばれることがあります。
+// 成果物は stagingOutDirName に書き出し、すべての後処理が終わってから out と置き換え、最後に completedRunFilename を作成します。
+// 再実行時は completedRunFilename があれば前回の成果物を返し、無ければ前回の途中の成果物を捨てて最初からやり直します。
+const (
+	stagingOutDirName    = "out.partial"
+	completedRunFilename = "completed.json"
+)
+
+// completedRun は完了した実行の結果です。再実行時に Result を組み立て直すために保存します。
+// Meta は Operation のメタデータの型に戻して Result に設定します。
+type completedRun struct {
+	Operation  OperationType   `json:"operation"`
+	Artifacts  []Artifact      `json:"artifacts"`
+	Meta       json.RawMessage `json:"meta,omitempty"`
+	Cached     bool            `json:"cached,omitempty"`
+	Timings    *JobTimings     `json:"timings,omitempty"`
+	InputPages int             `json:"inputPages,omitempty"`
+}
+
+// stageOutputs は今回の実行の成果物を書き出すワークスペースを返します（outDir だけを差し替えたもの）。
+// 前回の実行が途中で停止していた場合に備え、書きかけの成果物・成果物一覧・復号した入力を削除してから作成します。
+func (s *Service) stageOutputs(ws workspace) (workspace, error) {
+	staged := ws
+	staged.outDir = filepath.Join(ws.dir, stagingOutDirName)
+	for _, path := range []string{
+		staged.outDir,
+		filepath.Join(ws.dir, sealPlainDir),
+		filepath.Join(ws.dir, artifactsFilename),
+		filepath.Join(ws.dir, resultManifestFilename),
+	} {
+		if err := removeDirFS(s.fs, path); err != nil {
+			return workspace{}, err
+		}
+	}
+	if err := s.fs.MkdirAll(staged.outDir, 0o750); err != nil {
+		return workspace{}, err
+	}
+	return staged, nil
+}
+
+// commitOutputs は staged に書き出した成果物で ws の out を置き換え、result の成果物のパスを付け替えます。
+func (s *Service) commitOutputs(ws, staged workspace, result *Result) error {
+	if err := removeDirFS(s.fs, ws.outDir); err != nil {
+		return err
+	}
+	if err := s.fs.Rename(staged.outDir, ws.outDir); err != nil {
+		return err
+	}
+	for i := range result.Artifacts {
+		result.Artifacts[i].Path = filepath.Join(ws.outDir, filepath.Base(result.Artifacts[i].Path))
+	}
+	if len(result.Artifacts) > 0 {
+		result.OutputPath = result.Artifacts[0].Path
+	}
+	return nil
+}
+
+// markRunCompleted は実行が完了したことを記録します。書きかけのファイルを完了と誤認しないよう、一時ファイルから名前を変えて作成します。
+func (s *Service) markRunCompleted(ws workspace, result *Result) error {
+	run := completedRun{
+		Operation:  result.Operation,
+		Artifacts:  result.Artifacts,
+		Cached:     result.Cached,
+		Timings:    result.Timings,
+		InputPages: result.inputPages,
+	}
+	if result.Meta != nil {
+		meta, err := json.Marshal(result.Meta)
+		if err != nil {
+			return err
+		}
+		run.Meta = meta
+	}
+	data, err := json.Marshal(run)
+	if err != nil {
+		return err
+	}
+	return writeFileFS(s.fs, filepath.Join(ws.dir, completedRunFilename), data, 0o640)
+}
+
+// loadCompletedRun は前回の実行が完了していれば、その結果を返します。
+// 記録が無い・読めない・成果物が欠けている場合は false を返し、呼び出し元は最初から実行し直します。
+func (s *Service) loadCompletedRun(ws workspace, manifest *JobManifest) (*Result, bool) {
+	data, err := readFileFS(s.fs, filepath.Join(ws.dir, completedRunFilename))
+	if err != nil {
+		return nil, false
+	}
+	var run completedRun
+	if err := json.Unmarshal(data, &run); err != nil || len(run.Artifacts) == 0 {
+		return nil, false
+	}
+	for i := range run.Artifacts {
+		run.Artifacts[i].Path = filepath.Join(ws.outDir, filepath.Base(run.Artifacts[i].Name))
+		if _, err := s.fs.Stat(run.Artifacts[i].Path); err != nil {
+			return nil, false
+		}
+	}
+
+	if run.Operation == "" {
+		run.Operation = manifest.Operation
+	}
+	result := newResult(ws, manifest.Operation, s.fs, run.Artifacts, decodeResultMeta(run.Operation, run.Meta))
+	result.Cached = run.Cached
+	result.Timings = run.Timings
+	result.inputPages = run.InputPages
+	result.Resumed = true
+	result.fs = s.artifactFS()
+	// 前回の実行で予約した削除は、ワーカーの停止とともに失われている
+	s.scheduleCleanup(ws.dir)
+	return result, true
+}
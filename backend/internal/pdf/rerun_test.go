@@ -0,0 +1,120 @@
+package pdf
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/yourusername/paper-forge/internal/config"
+	"github.com/yourusername/paper-forge/internal/pdf/pdftest"
+)
+
+func TestRunJobRestartsAfterInterruptedRunAndResumesCompletedRun(t *testing.T) {
+	svc := NewService(&config.Config{MaxFileSize: 1 << 20, MaxPages: 20, JobExpireMinutes: 1})
+	svc.tmpRoot = t.TempDir()
+	svc.gs.once.Do(func() { svc.gs.path = "/usr/bin/gs" })
+	svc.runner = &outputWritingRunner{output: []byte("%PDF-1.4\n% optimized\n")}
+	headers, err := pdftest.FileHeaders("file", pdftest.File{Name: "a.pdf", Data: pdftest.Synthetic(3, 0)})
+	if err != nil {
+		t.Fatalf("failed to build multipart files: %v", err)
+	}
+	manifest, err := svc.PrepareOptimizeJob(context.Background(), headers[0], OptimizePresetStandard, "", "", JobOptions{})
+	if err != nil {
+		t.Fatalf("PrepareOptimizeJob returned error: %v", err)
+	}
+
+	// 前回の実行が書き出しの途中で停止した状態
+	ws := svc.workspaceFor(manifest.JobID)
+	staging := filepath.Join(ws.dir, stagingOutDirName)
+	if err := os.MkdirAll(staging, 0o750); err != nil {
+		t.Fatalf("failed to create staging dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(staging, "stale.pdf"), []byte("%PDF-1.4\n% trunc"), 0o640); err != nil {
+		t.Fatalf("failed to write stale output: %v", err)
+	}
+
+	result, err := svc.RunJob(context.Background(), manifest.JobID, nil)
+	if err != nil {
+		t.Fatalf("RunJob returned error: %v", err)
+	}
+	if result.OutputPath != filepath.Join(ws.outDir, optimizedFilename) {
+		t.Fatalf("outputs should be committed to the out dir: %s", result.OutputPath)
+	}
+	if _, err := os.Stat(staging); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("staging dir should be gone after the commit: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(ws.outDir, "stale.pdf")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatal("outputs of the interrupted run should be discarded")
+	}
+
+	// 完了後に再配信された場合は処理し直さずに同じ成果物を返す
+	failing := &stubRunner{err: errors.New("ghostscript should not run again")}
+	svc.runner = failing
+	resumed, err := svc.RunJob(context.Background(), manifest.JobID, nil)
+	if err != nil {
+		t.Fatalf("RunJob on a completed job returned error: %v", err)
+	}
+	defer resumed.Cleanup()
+	if len(failing.calls) != 0 {
+		t.Fatalf("completed jobs should not be processed again, got %d calls", len(failing.calls))
+	}
+	if !resumed.Resumed || result.Resumed {
+		t.Fatalf("only the result returned from the completed run should be marked resumed: first=%v resumed=%v", result.Resumed, resumed.Resumed)
+	}
+	if resumed.OutputPath != result.OutputPath || resumed.Artifacts[0].SHA256 != result.Artifacts[0].SHA256 {
+		t.Fatalf("resumed result should match the completed run: %+v", resumed.Artifacts)
+	}
+	if resumed.Timings == nil || resumed.Timings.StoreMs != manifest.StoreMs {
+		t.Fatalf("timings of the completed run should be kept: %+v", resumed.Timings)
+	}
+	meta, ok := resumed.Meta.(*OptimizeMeta)
+	if !ok || meta.Preset != OptimizePresetStandard {
+		t.Fatalf("meta should be decoded into the operation's type, got %T %+v", resumed.Meta, resumed.Meta)
+	}
+}
+
+func TestCompletedRunRoundTripInMemory(t *testing.T) {
+	fsys := newMemFS()
+	svc := NewServiceWithFS(&config.Config{JobExpireMinutes: 1}, fsys)
+	ws, err := svc.createWorkspace()
+	if err != nil {
+		t.Fatalf("createWorkspace returned error: %v", err)
+	}
+	manifest := &JobManifest{JobID: ws.jobID, Operation: OperationMetadata}
+
+	staged, err := svc.stageOutputs(ws)
+	if err != nil {
+		t.Fatalf("stageOutputs returned error: %v", err)
+	}
+	out, err := fsys.OpenFile(filepath.Join(staged.outDir, outputFilename), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o640)
+	if err != nil {
+		t.Fatalf("failed to create output: %v", err)
+	}
+	_, _ = out.Write([]byte("%PDF-1.4\n"))
+	_ = out.Close()
+
+	artifact := Artifact{Name: outputFilename, Kind: ResultKindPDF, Size: 9, Path: filepath.Join(staged.outDir, outputFilename)}
+	result := newResult(staged, OperationMetadata, fsys, []Artifact{artifact}, &MetadataMeta{Document: DocumentMetadata{Title: "new"}})
+	if err := svc.commitOutputs(ws, staged, result); err != nil {
+		t.Fatalf("commitOutputs returned error: %v", err)
+	}
+	if err := svc.markRunCompleted(ws, result); err != nil {
+		t.Fatalf("markRunCompleted returned error: %v", err)
+	}
+	if _, err := os.Stat(ws.dir); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("workspace should not exist on disk, stat err = %v", err)
+	}
+
+	resumed, ok := svc.loadCompletedRun(ws, manifest)
+	if !ok {
+		t.Fatal("loadCompletedRun should find the completed run")
+	}
+	if resumed.OutputPath != filepath.Join(ws.outDir, outputFilename) {
+		t.Fatalf("unexpected output path: %s", resumed.OutputPath)
+	}
+	if meta, ok := resumed.Meta.(*MetadataMeta); !ok || meta.Document.Title != "new" {
+		t.Fatalf("meta should be decoded into *MetadataMeta, got %T %+v", resumed.Meta, resumed.Meta)
+	}
+}
@@ -0,0 +1,431 @@
+package pdf
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"mime/multipart"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	pdfapi "github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+
+	"github.com/yourusername/paper-forge/internal/tenant"
+)
+
+const resizeFilename = "resized.pdf"
+
+// ResizeMode は用紙サイズを揃えるときの元のページの拡大縮小の方法です。
+type ResizeMode string
+
+const (
+	// ResizeModeFit は縦横比を保ち、ページ全体が用紙に収まるように拡大縮小します（既定）。余白ができます。
+	ResizeModeFit ResizeMode = "fit"
+	// ResizeModeFill は縦横比を保ち、用紙全体を覆うように拡大縮小します。はみ出した部分は切り取られます。
+	ResizeModeFill ResizeMode = "fill"
+	// ResizeModeCenter は拡大縮小せずに用紙の中央に置きます。用紙より大きいページははみ出した部分が切り取られます。
+	ResizeModeCenter ResizeMode = "center-no-scale"
+)
+
+// 用紙の向きの指定です。auto は元のページ（回転を反映した表示上の向き）に合わせます。
+const (
+	ResizeOrientationAuto      = "auto"
+	ResizeOrientationPortrait  = "portrait"
+	ResizeOrientationLandscape = "landscape"
+)
+
+// 用紙サイズを揃える指定を省略した項目の既定値です。
+const (
+	defaultResizePaper       = "a4"
+	defaultResizeMode        = ResizeModeFit
+	defaultResizeOrientation = ResizeOrientationAuto
+)
+
+// paperSizes は指定できる用紙サイズの縦向きの幅と高さ（ポイント）です。
+var paperSizes = map[string][2]float64{
+	"a3":     {841.89, 1190.55},
+	"a4":     {595.28, 841.89},
+	"a5":     {419.53, 595.28},
+	"letter": {612, 792},
+	"legal":  {612, 1008},
+}
+
+// ResizeSpec はすべてのページを同じ用紙サイズに揃える指定です。
+type ResizeSpec struct {
+	Paper       string     `json:"paper"`       // 用紙サイズ（a3 / a4 / a5 / letter / legal）
+	Mode        ResizeMode `json:"mode"`        // fit / fill / center-no-scale
+	Orientation string     `json:"orientation"` // auto / portrait / landscape
+}
+
+// pageLayout は1ページを用紙に配置した結果です。Width と Height はページの回転を適用する前の座標系での用紙の大きさで、
+// 元の内容は Scale 倍して (TX, TY) だけ移動します。
+type pageLayout struct {
+	Width, Height float64
+	Scale         float64
+	TX, TY        float64
+}
+
+// layout は表示領域 box・回転 rotate のページを、指定の用紙の中央に配置する変換を求めます。
+func (r ResizeSpec) layout(box types.Rectangle, rotate int) (pageLayout, error) {
+	w, h := math.Abs(box.Width()), math.Abs(box.Height())
+	if w == 0 || h == 0 {
+		return pageLayout{}, fmt.Errorf("page box is empty")
+	}
+	llx, lly := math.Min(box.LL.X, box.UR.X), math.Min(box.LL.Y, box.UR.Y)
+
+	// 90度・270度回転したページは、表示上の幅と高さが入れ替わる
+	turned := ((rotate/90)%2+2)%2 == 1
+	displayLandscape := w > h
+	if turned {
+		displayLandscape = h > w
+	}
+
+	size := paperSizes[r.Paper]
+	tw, th := size[0], size[1]
+	switch r.Orientation {
+	case ResizeOrientationLandscape:
+		tw, th = th, tw
+	case ResizeOrientationAuto:
+		if displayLandscape {
+			tw, th = th, tw
+		}
+	}
+	// 用紙の向きは表示上の向きなので、回転前の座標系に戻す
+	if turned {
+		tw, th = th, tw
+	}
+
+	var scale float64
+	switch r.Mode {
+	case ResizeModeFill:
+		scale = math.Max(tw/w, th/h)
+	case ResizeModeCenter:
+		scale = 1
+	default:
+		scale = math.Min(tw/w, th/h)
+	}
+	return pageLayout{
+		Width:  tw,
+		Height: th,
+		Scale:  scale,
+		TX:     (tw-w*scale)/2 - llx*scale,
+		TY:     (th-h*scale)/2 - lly*scale,
+	}, nil
+}
+
+// validateResizeSpec は用紙サイズを揃える指定が一覧にあることを確認します。
+func validateResizeSpec(r ResizeSpec) error {
+	if _, ok := paperSizes[r.Paper]; !ok {
+		papers := make([]string, 0, len(paperSizes))
+		for name := range paperSizes {
+			papers = append(papers, name)
+		}
+		sort.Strings(papers)
+		return newError("INVALID_INPUT", fmt.Sprintf("paper は %s のいずれかで指定してください。", strings.Join(papers, ", ")), nil)
+	}
+	switch r.Mode {
+	case ResizeModeFit, ResizeModeFill, ResizeModeCenter:
+	default:
+		return newError("INVALID_INPUT", "mode は fit, fill, center-no-scale のいずれかで指定してください。", nil)
+	}
+	switch r.Orientation {
+	case ResizeOrientationAuto, ResizeOrientationPortrait, ResizeOrientationLandscape:
+	default:
+		return newError("INVALID_INPUT", "orientation は auto, portrait, landscape のいずれかで指定してください。", nil)
+	}
+	return nil
+}
+
+// parseResizeSpec はフォーム項目（paper, mode, orientation）から用紙サイズを揃える指定を作成します。
+// 省略した項目は既定値を使います。値は validateResizeSpec で確認します。
+func parseResizeSpec(values url.Values) ResizeSpec {
+	spec := ResizeSpec{
+		Paper:       defaultResizePaper,
+		Mode:        defaultResizeMode,
+		Orientation: defaultResizeOrientation,
+	}
+	if raw := strings.TrimSpace(values.Get("paper")); raw != "" {
+		spec.Paper = strings.ToLower(raw)
+	}
+	if raw := strings.TrimSpace(values.Get("mode")); raw != "" {
+		spec.Mode = ResizeMode(strings.ToLower(raw))
+	}
+	if raw := strings.TrimSpace(values.Get("orientation")); raw != "" {
+		spec.Orientation = strings.ToLower(raw)
+	}
+	return spec
+}
+
+// resizePages は各ページの用紙（MediaBox）を指定のサイズに置き換え、内容を拡大縮小して中央に配置します。
+// 内容は既存のコンテンツストリームの前後に変換行列（cm）を設定するストリームを追加して移動し、元のストリームは書き換えません。
+// 注釈（リンクなど）の位置は変換しません。
+func resizePages(pdfCtx *model.Context, spec ResizeSpec) error {
+	for i := 1; i <= pdfCtx.PageCount; i++ {
+		page, _, attrs, err := pdfCtx.PageDict(i, false)
+		if err != nil {
+			return err
+		}
+		if page == nil || attrs == nil {
+			return fmt.Errorf("page %d has no attributes", i)
+		}
+		box := attrs.CropBox
+		if box == nil {
+			box = attrs.MediaBox
+		}
+		if box == nil {
+			return fmt.Errorf("page %d has no media box", i)
+		}
+		layout, err := spec.layout(*box, attrs.Rotate)
+		if err != nil {
+			return fmt.Errorf("page %d: %w", i, err)
+		}
+		if err := wrapPageContents(pdfCtx, page, layout); err != nil {
+			return fmt.Errorf("page %d: %w", i, err)
+		}
+
+		mediaBox := types.Array{types.Float(0), types.Float(0), types.Float(layout.Width), types.Float(layout.Height)}
+		page.Update("MediaBox", mediaBox)
+		// 親の /Pages から CropBox を継承しないよう、用紙と同じ領域を明示する。その他の領域は元の座標系のため削除する
+		page.Update("CropBox", append(types.Array{}, mediaBox...))
+		for _, key := range []string{"BleedBox", "TrimBox", "ArtBox"} {
+			page.Delete(key)
+		}
+	}
+	return nil
+}
+
+// wrapPageContents はページのコンテンツストリームを、変換行列を設定する q ... cm と Q で囲みます。
+func wrapPageContents(pdfCtx *model.Context, page types.Dict, layout pageLayout) error {
+	prefix := fmt.Sprintf("q %.4f 0 0 %.4f %.4f %.4f cm\n", layout.Scale, layout.Scale, layout.TX, layout.TY)
+	before, err := newContentStream(pdfCtx, prefix)
+	if err != nil {
+		return err
+	}
+	after, err := newContentStream(pdfCtx, "\nQ\n")
+	if err != nil {
+		return err
+	}
+
+	contents := types.Array{*before}
+	if obj, ok := page.Find("Contents"); ok && obj != nil {
+		// Contents はストリームへの参照か、その配列（配列自体が参照の場合もある）
+		resolved, err := pdfCtx.Dereference(obj)
+		if err != nil {
+			return err
+		}
+		if arr, ok := resolved.(types.Array); ok {
+			contents = append(contents, arr...)
+		} else {
+			contents = append(contents, obj)
+		}
+	}
+	page.Update("Contents", append(contents, *after))
+	return nil
+}
+
+func newContentStream(pdfCtx *model.Context, content string) (*types.IndirectRef, error) {
+	sd := types.StreamDict{Dict: types.NewDict(), Content: []byte(content)}
+	if err := sd.Encode(); err != nil {
+		return nil, err
+	}
+	return pdfCtx.IndRefForNewObject(sd)
+}
+
+// ResizeMultipart は単一PDFのすべてのページを同じ用紙サイズに揃えたPDFを作成します。
+func (s *Service) ResizeMultipart(ctx context.Context, file *multipart.FileHeader, spec ResizeSpec) (_ *Result, err error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if file == nil {
+		return nil, newError("INVALID_INPUT", "PDFファイルを選択してください。", nil)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	state, _, err := s.prepareResize(ctx, file, spec, JobOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = removeDir(state.ws.dir)
+		}
+	}()
+
+	result, execErr := s.executeResize(ctx, state, nil)
+	if execErr != nil {
+		return nil, execErr
+	}
+	return result, nil
+}
+
+type resizeState struct {
+	ws   workspace
+	file storedFile
+	spec ResizeSpec
+}
+
+func (s *Service) prepareResize(ctx context.Context, file *multipart.FileHeader, spec ResizeSpec, opts JobOptions) (*resizeState, *JobManifest, error) {
+	if err := validateResizeSpec(spec); err != nil {
+		return nil, nil, err
+	}
+
+	release, err := s.reserveWorkspace(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer release()
+
+	ws, err := s.createWorkspace()
+	if err != nil {
+		return nil, nil, err
+	}
+	stored, err := s.storeMultipartFile(ctx, file, ws.inDir, 0, false)
+	if err != nil {
+		_ = removeDir(ws.dir)
+		return nil, nil, err
+	}
+
+	manifest := &JobManifest{
+		JobID:     ws.jobID,
+		Tenant:    tenant.FromContext(ctx),
+		Operation: OperationResize,
+		Files:     toJobFiles([]storedFile{stored}),
+		Resize:    &spec,
+		Options:   opts,
+		StoreMs:   ws.elapsed().Milliseconds(),
+		CreatedAt: s.now().UTC(),
+	}
+	if err := writeManifest(s.fs, ws.dir, manifest); err != nil {
+		_ = removeDir(ws.dir)
+		return nil, nil, fmt.Errorf("ジョブマニフェストの保存に失敗しました: %w", err)
+	}
+
+	return &resizeState{ws: ws, file: stored, spec: spec}, manifest, nil
+}
+
+func (s *Service) executeResize(ctx context.Context, state *resizeState, progress ProgressReporter) (*Result, error) {
+	ws := state.ws
+	stored := state.file
+
+	reportProgress(progress, StageProcess, 0)
+	outputPath := filepath.Join(ws.outDir, resizeFilename)
+	if err := runStep(ctx, func() error {
+		pdfCtx, err := pdfapi.ReadContextFile(stored.path)
+		if err != nil {
+			return err
+		}
+		if err := resizePages(pdfCtx, state.spec); err != nil {
+			return err
+		}
+		if err := pdfapi.WriteContextFile(pdfCtx, outputPath); err != nil {
+			_ = os.Remove(outputPath)
+			return err
+		}
+		return nil
+	}); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		return nil, newError("UNSUPPORTED_PDF", "PDFの用紙サイズの変更に失敗しました。ファイルが破損していないか確認してください。", err)
+	}
+	reportProgress(progress, StageWrite, 0)
+
+	sourceMeta := SourceFileMeta{
+		Name:  stored.originalName,
+		Size:  stored.size,
+		Pages: stored.pages,
+	}
+
+	meta := struct {
+		Type      OperationType  `json:"type"`
+		CreatedAt string         `json:"createdAt"`
+		Source    SourceFileMeta `json:"source"`
+		Resize    ResizeSpec     `json:"resize"`
+		Output    string         `json:"output"`
+	}{
+		Type:      OperationResize,
+		CreatedAt: s.now().UTC().Format(time.RFC3339),
+		Source:    sourceMeta,
+		Resize:    state.spec,
+		Output:    resizeFilename,
+	}
+
+	metaPath := filepath.Join(ws.dir, "meta.json")
+	if err := writeJSON(s.fs, metaPath, meta); err != nil {
+		return nil, fmt.Errorf("メタデータの保存に失敗しました: %w", err)
+	}
+
+	s.scheduleCleanup(ws.dir)
+
+	artifact, err := newArtifact(outputPath, ResultKindPDF)
+	if err != nil {
+		return nil, fmt.Errorf("成果物の確認に失敗しました: %w", err)
+	}
+
+	reportProgress(progress, StageCompleted, 1)
+
+	return newResult(ws, OperationResize, s.fs, []Artifact{artifact}, &ResizeMeta{
+		Original: sourceMeta,
+		Resize:   state.spec,
+	}), nil
+}
+
+// PrepareResizeJob は非同期ジョブ用に入力を保存します。
+func (s *Service) PrepareResizeJob(ctx context.Context, file *multipart.FileHeader, spec ResizeSpec, opts JobOptions) (*JobManifest, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	_, manifest, err := s.prepareResize(ctx, file, spec, opts)
+	if err != nil {
+		return nil, err
+	}
+	return s.sealJob(manifest)
+}
+
+func init() {
+	registerOperation(resizeOperation{})
+}
+
+type resizeOperation struct{}
+
+func (resizeOperation) Type() OperationType { return OperationResize }
+
+func (resizeOperation) OutputSpec() OutputSpec {
+	return OutputSpec{Filename: resizeFilename, Kind: ResultKindPDF}
+}
+
+func (resizeOperation) Prepare(ctx context.Context, s *Service, req PrepareRequest) (*JobManifest, error) {
+	file, err := req.singleFile()
+	if err != nil {
+		return nil, err
+	}
+	return s.PrepareResizeJob(ctx, file, parseResizeSpec(req.Params), req.Options)
+}
+
+func (resizeOperation) Execute(ctx context.Context, s *Service, job *jobInput, progress ProgressReporter) (*Result, error) {
+	if job.manifest.Resize == nil {
+		return nil, newError("INVALID_INPUT", "用紙サイズの指定がありません。", nil)
+	}
+	state := &resizeState{
+		ws:   job.ws,
+		file: job.files[0],
+		spec: *job.manifest.Resize,
+	}
+	return s.executeResize(ctx, state, progress)
+}
+
+func (resizeOperation) Plan(s *Service, job *jobInput) (*JobPlan, error) {
+	if job.manifest.Resize == nil {
+		return nil, newError("INVALID_INPUT", "用紙サイズの指定がありません。", nil)
+	}
+	return &JobPlan{Outputs: []PlannedOutput{{Name: resizeFilename, Kind: ResultKindPDF, Pages: job.files[0].pages}}}, nil
+}
@@ -0,0 +1,130 @@
+package pdf
+
+import (
+	"context"
+	"math"
+	"net/url"
+	"testing"
+
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+
+	"github.com/yourusername/paper-forge/internal/config"
+	"github.com/yourusername/paper-forge/internal/pdf/pdftest"
+)
+
+func TestParseResizeSpec(t *testing.T) {
+	if spec := parseResizeSpec(url.Values{}); spec != (ResizeSpec{Paper: "a4", Mode: ResizeModeFit, Orientation: "auto"}) {
+		t.Fatalf("defaults should apply: %+v", spec)
+	}
+	spec := parseResizeSpec(url.Values{"paper": {" Letter "}, "mode": {"Center-No-Scale"}, "orientation": {"landscape"}})
+	if spec != (ResizeSpec{Paper: "letter", Mode: ResizeModeCenter, Orientation: "landscape"}) {
+		t.Fatalf("unexpected spec: %+v", spec)
+	}
+
+	for _, spec := range []ResizeSpec{
+		{Paper: "b5", Mode: ResizeModeFit, Orientation: "auto"},
+		{Paper: "a4", Mode: "stretch", Orientation: "auto"},
+		{Paper: "a4", Mode: ResizeModeFit, Orientation: "sideways"},
+	} {
+		if err := validateResizeSpec(spec); !IsError(err, "INVALID_INPUT") {
+			t.Fatalf("%+v: expected INVALID_INPUT, got %v", spec, err)
+		}
+	}
+}
+
+func TestResizeLayout(t *testing.T) {
+	near := func(a, b float64) bool { return math.Abs(a-b) < 0.01 }
+	a5 := *types.NewRectangle(0, 0, 419.53, 595.28)
+
+	// fit: A5 を A4 に拡大して収める（寸法の丸めにより左右に1ポイント未満の余白が残る）
+	got, err := ResizeSpec{Paper: "a4", Mode: ResizeModeFit, Orientation: "auto"}.layout(a5, 0)
+	if err != nil {
+		t.Fatalf("layout returned error: %v", err)
+	}
+	if !near(got.Width, 595.28) || !near(got.Height, 841.89) || !near(got.Scale, 841.89/595.28) || got.TX < 0 || got.TX > 1 || !near(got.TY, 0) {
+		t.Fatalf("unexpected fit layout: %+v", got)
+	}
+
+	// center-no-scale: 大きさを変えずに中央へ。表示領域の原点のずれも打ち消す
+	shifted := *types.NewRectangle(10, 20, 429.53, 615.28)
+	got, _ = ResizeSpec{Paper: "a4", Mode: ResizeModeCenter, Orientation: "auto"}.layout(shifted, 0)
+	if got.Scale != 1 || !near(got.TX, (595.28-419.53)/2-10) || !near(got.TY, (841.89-595.28)/2-20) {
+		t.Fatalf("unexpected center layout: %+v", got)
+	}
+
+	// auto: 横長のページは横向きの用紙に置く。fill は用紙全体を覆う
+	wide := *types.NewRectangle(0, 0, 800, 400)
+	got, _ = ResizeSpec{Paper: "letter", Mode: ResizeModeFill, Orientation: "auto"}.layout(wide, 0)
+	if got.Width != 792 || got.Height != 612 || !near(got.Scale, 612.0/400) {
+		t.Fatalf("unexpected fill layout: %+v", got)
+	}
+
+	// 90度回転したページは回転前の座標系で用紙の幅と高さが入れ替わる
+	got, _ = ResizeSpec{Paper: "letter", Mode: ResizeModeFit, Orientation: "portrait"}.layout(wide, 90)
+	if got.Width != 792 || got.Height != 612 {
+		t.Fatalf("rotated page should get a turned sheet: %+v", got)
+	}
+
+	if _, err := (ResizeSpec{Paper: "a4", Mode: ResizeModeFit}).layout(types.Rectangle{}, 0); err == nil {
+		t.Fatal("empty page boxes should be rejected")
+	}
+}
+
+func TestPrepareResizeJobThroughRegistry(t *testing.T) {
+	svc := NewService(&config.Config{MaxFileSize: 1 << 20, MaxPages: 20})
+	svc.tmpRoot = t.TempDir()
+	headers, err := pdftest.FileHeaders("file", pdftest.File{Name: "mixed.pdf", Data: pdftest.Synthetic(3, 0)})
+	if err != nil {
+		t.Fatalf("failed to build multipart files: %v", err)
+	}
+
+	manifest, err := svc.PrepareJob(context.Background(), OperationResize, PrepareRequest{
+		Files:  headers,
+		Params: url.Values{"paper": {"letter"}, "mode": {"fill"}},
+	})
+	if err != nil {
+		t.Fatalf("PrepareJob returned error: %v", err)
+	}
+	if manifest.Resize == nil || *manifest.Resize != (ResizeSpec{Paper: "letter", Mode: ResizeModeFill, Orientation: "auto"}) {
+		t.Fatalf("unexpected manifest: %+v", manifest.Resize)
+	}
+
+	plan, err := svc.PlanJob(context.Background(), manifest.JobID)
+	if err != nil {
+		t.Fatalf("PlanJob returned error: %v", err)
+	}
+	if plan.Outputs[0].Name != resizeFilename || plan.Outputs[0].Pages != 3 {
+		t.Fatalf("unexpected plan: %+v", plan.Outputs)
+	}
+
+	_, err = svc.PrepareJob(context.Background(), OperationResize, PrepareRequest{
+		Files:  headers,
+		Params: url.Values{"mode": {"stretch"}},
+	})
+	if !IsError(err, "INVALID_INPUT") {
+		t.Fatalf("unknown modes should be rejected, got %v", err)
+	}
+}
+
+func TestResizeCacheKeyFollowsSpec(t *testing.T) {
+	base := &JobManifest{
+		Operation: OperationResize,
+		Files:     []JobFile{{SHA256: "abc"}},
+		Resize:    &ResizeSpec{Paper: "a4", Mode: ResizeModeFit, Orientation: "auto"},
+	}
+	key, ok := resultCacheKey(base)
+	if !ok {
+		t.Fatal("resize should be cacheable")
+	}
+	for _, spec := range []ResizeSpec{
+		{Paper: "letter", Mode: ResizeModeFit, Orientation: "auto"},
+		{Paper: "a4", Mode: ResizeModeCenter, Orientation: "auto"},
+		{Paper: "a4", Mode: ResizeModeFit, Orientation: "portrait"},
+	} {
+		other := *base
+		other.Resize = &spec
+		if got, _ := resultCacheKey(&other); got == key {
+			t.Fatalf("%+v should change the cache key", spec)
+		}
+	}
+}
@@ -1,6 +1,12 @@
 package pdf
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
 	"sync"
 )
 
@@ -8,10 +14,18 @@ import (
 type OperationType string
 
 const (
-	OperationMerge    OperationType = "merge"
-	OperationReorder  OperationType = "reorder"
-	OperationSplit    OperationType = "split"
-	OperationOptimize OperationType = "optimize"
+	OperationMerge     OperationType = "merge"
+	OperationReorder   OperationType = "reorder"
+	OperationSplit     OperationType = "split"
+	OperationOptimize  OperationType = "optimize"
+	OperationRotate    OperationType = "rotate"
+	OperationExtract   OperationType = "extract"
+	OperationWatermark OperationType = "watermark"
+	OperationStamp     OperationType = "stamp"
+	OperationMetadata  OperationType = "metadata"
+	OperationCompose   OperationType = "compose"
+	OperationNUp       OperationType = "nup"
+	OperationResize    OperationType = "resize"
 )
 
 // OptimizePreset は圧縮プリセットの種類を表します。
@@ -22,6 +36,27 @@ const (
 	OptimizePresetAggressive OptimizePreset = "aggressive"
 )
 
+// ReorderMode はページ順入替の order の扱いを表します。
+type ReorderMode string
+
+const (
+	// ReorderModePermutation は全ページを1回ずつ並べ替えます（既定）。
+	ReorderModePermutation ReorderMode = "permutation"
+	// ReorderModeFree は同じページの繰り返しやページの省略を許します（例: 1ページ目を2回印刷し、5ページ目を除く）。
+	ReorderModeFree ReorderMode = "free"
+)
+
+// SplitOutput は分割結果の受け取り方を表します。
+type SplitOutput string
+
+const (
+	// SplitOutputZIP は各パートを1つの ZIP にまとめて主成果物とします（既定）。
+	SplitOutputZIP SplitOutput = "zip"
+	// SplitOutputParts は ZIP を作らず、各パートの PDF を個別の成果物として返します。
+	// 全パートを取得するには成果物ごとのダウンロードが必要なため、非同期ジョブでのみ扱います。
+	SplitOutputParts SplitOutput = "parts"
+)
+
 // ResultKind は生成される成果物の種別を表します。
 type ResultKind string
 
@@ -30,7 +65,41 @@ const (
 	ResultKindZIP ResultKind = "zip"
 )
 
+// Artifact はジョブが生成した成果物ファイル1つ分の情報です。
+type Artifact struct {
+	Name   string     `json:"name"`
+	Kind   ResultKind `json:"kind"`
+	Size   int64      `json:"size"`
+	SHA256 string     `json:"sha256,omitempty"`
+	// Path はワークスペース内の実ファイルのパスです。外部には公開しません。
+	Path string `json:"-"`
+}
+
+// newArtifact はローカルディスク上の成果物ファイルのサイズとハッシュを計算します。
+func newArtifact(path string, kind ResultKind) (Artifact, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Artifact{}, err
+	}
+	defer file.Close()
+
+	hasher := sha256.New()
+	size, err := io.Copy(hasher, file)
+	if err != nil {
+		return Artifact{}, err
+	}
+	return Artifact{
+		Name:   filepath.Base(path),
+		Kind:   kind,
+		Size:   size,
+		SHA256: hex.EncodeToString(hasher.Sum(nil)),
+		Path:   path,
+	}, nil
+}
+
 // Result はPDF処理の成果を表します。
+// 成果物は Artifacts に複数保持でき、先頭が主成果物です。OutputPath などの Output* と ResultKind は
+// 主成果物の情報で、単一ファイルを前提とする既存の利用箇所のために残しています。
 type Result struct {
 	JobID          string        `json:"jobId"`
 	Operation      OperationType `json:"operation"`
@@ -38,28 +107,103 @@ type Result struct {
 	OutputFilename string        `json:"outputFilename"`
 	OutputSize     int64         `json:"outputSize"`
 	ResultKind     ResultKind    `json:"resultKind"`
+	Artifacts      []Artifact    `json:"artifacts"`
 	Meta           any           `json:"meta,omitempty"`
+	// Cached は同一入力・同一オプションの過去の成果物を再利用した場合に true になります。
+	Cached bool `json:"cached,omitempty"`
+	// Timings はジョブの段階ごとの所要時間です。RunJob で実行した場合に設定します。
+	Timings *JobTimings `json:"timings,omitempty"`
+	// Resumed はタスクの再配信で、完了済みの前回の実行の成果物をそのまま返した場合に true になります。
+	// 利用量は前回の実行で計上済みのため、呼び出し元は改めて計上しません。
+	Resumed bool `json:"-"`
 
+	// inputPages は実行時に確認した入力の合計ページ数です（利用量の計測用）。
+	inputPages  int
 	jobDir      string
+	fs          FS
 	cleanupOnce sync.Once
 	cleanupErr  error
 }
 
+// newResult は成果物一覧から Result を組み立てます。artifacts の先頭を主成果物として扱います。
+func newResult(ws workspace, op OperationType, fsys FS, artifacts []Artifact, meta any) *Result {
+	result := &Result{
+		JobID:     ws.jobID,
+		Operation: op,
+		Artifacts: artifacts,
+		Meta:      meta,
+		jobDir:    ws.dir,
+		fs:        fsys,
+	}
+	if len(artifacts) > 0 {
+		primary := artifacts[0]
+		result.OutputPath = primary.Path
+		result.OutputFilename = primary.Name
+		result.OutputSize = primary.Size
+		result.ResultKind = primary.Kind
+	}
+	return result
+}
+
 // Cleanup は作業ディレクトリを削除します。
 func (r *Result) Cleanup() error {
 	if r == nil {
 		return nil
 	}
 	r.cleanupOnce.Do(func() {
-		r.cleanupErr = removeDir(r.jobDir)
+		r.cleanupErr = removeDirFS(fsOrDefault(r.fs), r.jobDir)
 	})
 	return r.cleanupErr
 }
 
+// Open は主成果物のファイルを読み出し用に開きます。
+func (r *Result) Open() (File, error) {
+	return openFS(fsOrDefault(r.fs), r.OutputPath)
+}
+
+// LocalPath は主成果物の平文を読み出せるローカルディスク上のパスを返します。
+// 外部コマンドへ渡す場合に使います。暗号化されている場合は作業ディレクトリへ一時的に復号し、
+// 戻り値の関数で削除します。
+func (r *Result) LocalPath() (string, func(), error) {
+	sealed, ok := r.fs.(sealedFS)
+	if !ok {
+		return r.OutputPath, func() {}, nil
+	}
+	plainDir := filepath.Join(r.jobDir, sealPlainDir)
+	if err := os.MkdirAll(plainDir, 0o700); err != nil {
+		return "", nil, err
+	}
+	tmp, err := os.CreateTemp(plainDir, "artifact-*"+filepath.Ext(r.OutputFilename))
+	if err != nil {
+		return "", nil, err
+	}
+	release := func() { _ = os.Remove(tmp.Name()) }
+	src, err := openFS(sealed, r.OutputPath)
+	if err == nil {
+		_, err = io.Copy(tmp, src)
+		src.Close()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		release()
+		return "", nil, err
+	}
+	return tmp.Name(), release, nil
+}
+
+// OpenArtifact は成果物 a のファイルを読み出し用に開きます。
+func (r *Result) OpenArtifact(a Artifact) (File, error) {
+	return openFS(fsOrDefault(r.fs), a.Path)
+}
+
 // MergeMeta は結合処理のメタデータです。
 type MergeMeta struct {
-	TotalPages int              `json:"totalPages"`
-	Sources    []SourceFileMeta `json:"sources"`
+	TotalPages int `json:"totalPages"`
+	// TOCPages は先頭に付けた目次のページ数です（TotalPages に含みます）。
+	TOCPages int              `json:"tocPages,omitempty"`
+	Sources  []SourceFileMeta `json:"sources"`
 }
 
 // ReorderMeta はページ順入替処理のメタデータです。
@@ -68,6 +212,12 @@ type ReorderMeta struct {
 	Order    []int          `json:"order"`
 }
 
+// RotateMeta はページ回転処理のメタデータです。
+type RotateMeta struct {
+	Original  SourceFileMeta `json:"original"`
+	Rotations []PageRotation `json:"rotations"`
+}
+
 // SplitMeta は分割処理のメタデータです。
 type SplitMeta struct {
 	Original SourceFileMeta `json:"original"`
@@ -75,12 +225,91 @@ type SplitMeta struct {
 	Parts    []SplitPart    `json:"parts"`
 }
 
-// PageRange は分割対象のページ範囲を表します（Start/Endは1-based, End>=Start）。
+// ExtractMeta はページ抽出処理のメタデータです。
+type ExtractMeta struct {
+	Original SourceFileMeta `json:"original"`
+	Ranges   []PageRange    `json:"ranges"`
+	Pages    int            `json:"pages"`
+}
+
+// WatermarkMeta は透かし追加処理のメタデータです。
+type WatermarkMeta struct {
+	Original  SourceFileMeta `json:"original"`
+	Watermark WatermarkSpec  `json:"watermark"`
+	Pages     []PageRange    `json:"pages,omitempty"`
+}
+
+// StampMeta は画像スタンプ処理のメタデータです。
+type StampMeta struct {
+	Original SourceFileMeta `json:"original"`
+	Image    SourceFileMeta `json:"image"`
+	Stamp    StampSpec      `json:"stamp"`
+	Pages    []PageRange    `json:"pages,omitempty"`
+}
+
+// MetadataMeta は文書情報の変更処理のメタデータです。
+type MetadataMeta struct {
+	Original SourceFileMeta   `json:"original"`
+	Changes  MetadataSpec     `json:"changes"`
+	Document DocumentMetadata `json:"document"`
+}
+
+// ComposeMeta は複数のPDFからページを組み立てる処理のメタデータです。
+type ComposeMeta struct {
+	TotalPages int              `json:"totalPages"`
+	Sources    []SourceFileMeta `json:"sources"`
+	Recipe     []ComposeStep    `json:"recipe"`
+}
+
+// NUpMeta は面付け処理のメタデータです。Sheets は成果物の用紙の枚数（ページ数）です。
+type NUpMeta struct {
+	Original SourceFileMeta `json:"original"`
+	NUp      NUpSpec        `json:"nup"`
+	Sheets   int            `json:"sheets"`
+}
+
+// ResizeMeta は用紙サイズを揃える処理のメタデータです。
+type ResizeMeta struct {
+	Original SourceFileMeta `json:"original"`
+	Resize   ResizeSpec     `json:"resize"`
+}
+
+// PageRange は分割対象のページ範囲を表します（Start/Endは1-based）。
+// split / extract では Start > End の逆順の範囲（End に向かって1ページずつ戻る）も使います。
 type PageRange struct {
 	Start int `json:"start"`
 	End   int `json:"end"`
 }
 
+// bounds は範囲に含まれるページの最小値と最大値を返します。
+func (pr PageRange) bounds() (lo, hi int) {
+	if pr.Start > pr.End {
+		return pr.End, pr.Start
+	}
+	return pr.Start, pr.End
+}
+
+// count は範囲に含まれるページ数を返します。
+func (pr PageRange) count() int {
+	lo, hi := pr.bounds()
+	return hi - lo + 1
+}
+
+// pages は範囲のページ番号を Start から End への並び順で返します。
+func (pr PageRange) pages() []int {
+	pages := make([]int, 0, pr.count())
+	step := 1
+	if pr.Start > pr.End {
+		step = -1
+	}
+	for p := pr.Start; ; p += step {
+		pages = append(pages, p)
+		if p == pr.End {
+			return pages
+		}
+	}
+}
+
 // SplitPart は分割で生成された各PDFの情報です。
 type SplitPart struct {
 	Filename string `json:"filename"`
@@ -88,6 +317,7 @@ type SplitPart struct {
 	ToPage   int    `json:"toPage"`
 	Pages    int    `json:"pages"`
 	Size     int64  `json:"size"`
+	SHA256   string `json:"sha256"`
 }
 
 // OptimizeMeta は圧縮処理のメタデータです。
@@ -97,5 +327,58 @@ type OptimizeMeta struct {
 	SavedBytes   int64          `json:"savedBytes"`
 	SavedPercent float64        `json:"savedPercent"`
 	Preset       OptimizePreset `json:"preset"`
+	// ColorProfile は色を変換して出力インテントとして埋め込んだ ICC プロファイルです。
+	ColorProfile ColorProfile   `json:"colorProfile,omitempty"`
 	Source       SourceFileMeta `json:"source"`
+	// Warnings は Ghostscript が処理を継続しつつ報告した警告です（フォントの代替、ICC プロファイルの問題など）。
+	Warnings []string `json:"warnings,omitempty"`
+	// NoGainApplied は圧縮しても小さくならなかったため、元のファイルをそのまま返したことを表します。
+	NoGainApplied bool `json:"noGainApplied,omitempty"`
+	// Pages は圧縮対象としたページ範囲です。全ページを圧縮した場合は空になります。
+	Pages []PageRange `json:"pages,omitempty"`
+}
+
+// newResultMeta は操作 op が Result.Meta に設定するメタデータの値（ポインタ）を返します。
+func newResultMeta(op OperationType) any {
+	switch op {
+	case OperationMerge:
+		return &MergeMeta{}
+	case OperationReorder:
+		return &ReorderMeta{}
+	case OperationSplit:
+		return &SplitMeta{}
+	case OperationOptimize:
+		return &OptimizeMeta{}
+	case OperationRotate:
+		return &RotateMeta{}
+	case OperationExtract:
+		return &ExtractMeta{}
+	case OperationWatermark:
+		return &WatermarkMeta{}
+	case OperationStamp:
+		return &StampMeta{}
+	case OperationMetadata:
+		return &MetadataMeta{}
+	case OperationCompose:
+		return &ComposeMeta{}
+	case OperationNUp:
+		return &NUpMeta{}
+	case OperationResize:
+		return &ResizeMeta{}
+	default:
+		return nil
+	}
+}
+
+// decodeResultMeta は保存しておいたメタデータを、操作 op が Execute で返すのと同じ型に戻します。
+// 通知や監査ログは型でメタデータを判別するため、json.RawMessage のままでは扱えません。
+func decodeResultMeta(op OperationType, raw json.RawMessage) any {
+	if len(raw) == 0 {
+		return nil
+	}
+	meta := newResultMeta(op)
+	if meta == nil || json.Unmarshal(raw, meta) != nil {
+		return raw
+	}
+	return meta
 }
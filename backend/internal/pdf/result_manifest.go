@@ -0,0 +1,197 @@
+package pdf
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yourusername/paper-forge/internal/config"
+	"github.com/yourusername/paper-forge/internal/signing"
+)
+
+const (
+	// resultManifestFilename はジョブ完了時に署名付きマニフェストを保存するファイル名です。
+	resultManifestFilename = "result-manifest.json"
+	// resultManifestVersion はマニフェストの形式のバージョンです。項目の意味を変える場合に上げます。
+	resultManifestVersion = 1
+)
+
+// resultManifest は成果物の受け取り側が、処理後に改変されていないことを確かめるための記録です。
+// 処理記録（auditRecord）と同じく、入力のファイル名は含めず内容のハッシュで特定します。
+type resultManifest struct {
+	Version     int           `json:"version"`
+	JobID       string        `json:"jobId"`
+	Operation   OperationType `json:"operation"`
+	Steps       []string      `json:"steps"`
+	Tools       []auditTool   `json:"tools"`
+	Inputs      []auditInput  `json:"inputs"`
+	Outputs     []Artifact    `json:"outputs"`
+	ReceivedAt  time.Time     `json:"receivedAt"`
+	CompletedAt time.Time     `json:"completedAt"`
+}
+
+// SignedManifest は GET /api/jobs/:id/manifest で返す署名付きマニフェストです。
+// Payload は base64 でエンコードしたマニフェストの JSON で、Signature はそのバイト列に対する署名です。
+// JSON の整形の差で署名が検証できなくならないよう、署名したバイト列をそのまま運びます。
+type SignedManifest struct {
+	Algorithm string `json:"algorithm"`
+	KeyID     string `json:"keyId"`
+	Payload   string `json:"payload"`
+	Signature string `json:"signature"`
+}
+
+// ManifestKey はマニフェストの検証に使う公開鍵です。
+// PublicKeyPEM は SubjectPublicKeyInfo の PEM で、どの署名方式でも設定します。
+// PublicKey は Ed25519 の鍵の場合のみ、32バイトの公開鍵を base64 で設定します。
+type ManifestKey struct {
+	KeyID        string `json:"keyId"`
+	Algorithm    string `json:"algorithm"`
+	PublicKey    string `json:"publicKey,omitempty"`
+	PublicKeyPEM string `json:"publicKeyPem"`
+	// Active は現在の署名に使っている鍵の場合に true です。
+	Active bool `json:"active"`
+}
+
+// newManifestSigner は設定（SIGNING_PROVIDER）から署名に使う Signer を作成します。署名しない設定の場合は nil を返します。
+func newManifestSigner(cfg *config.Config) signing.Signer {
+	// 設定は config.Validate で検証済み。PKCS#12 ファイルが読めない場合などは署名しない
+	signer, err := signing.New(cfg)
+	if err != nil {
+		log.Printf("Result manifests are not signed: %v", err)
+		return nil
+	}
+	return signer
+}
+
+// writeResultManifest はジョブ完了時に成果物のマニフェストを作成して署名し、ワークスペースに保存します。
+// 署名鍵が設定されていない場合は何もしません。成果物のハッシュは受領書などをすべて付けた後の最終的な値です。
+func (s *Service) writeResultManifest(ctx context.Context, ws workspace, manifest *JobManifest, result *Result) error {
+	if s.signer == nil || result == nil {
+		return nil
+	}
+	record := s.newAuditRecord(ctx, manifest)
+	steps := record.Steps
+	if manifest.Options.Receipt != "" {
+		steps = append(steps, "receipt")
+	}
+	payload, err := json.Marshal(resultManifest{
+		Version:     resultManifestVersion,
+		JobID:       record.JobID,
+		Operation:   record.Operation,
+		Steps:       steps,
+		Tools:       record.Tools,
+		Inputs:      record.Inputs,
+		Outputs:     result.Artifacts,
+		ReceivedAt:  record.ReceivedAt,
+		CompletedAt: record.CompletedAt,
+	})
+	if err != nil {
+		return err
+	}
+	sig, err := s.signer.Sign(ctx, payload)
+	if err != nil {
+		return fmt.Errorf("マニフェストの署名に失敗しました: %w", err)
+	}
+	return writeJSON(s.fs, filepath.Join(ws.dir, resultManifestFilename), SignedManifest{
+		Algorithm: sig.Algorithm,
+		KeyID:     sig.KeyID,
+		Payload:   base64.StdEncoding.EncodeToString(payload),
+		Signature: base64.StdEncoding.EncodeToString(sig.Value),
+	})
+}
+
+// SignedResultManifest はジョブ完了時に保存した署名付きマニフェストを返します。
+func (s *Service) SignedResultManifest(jobID string) ([]byte, error) {
+	if s.signer == nil {
+		return nil, resultSigningDisabled()
+	}
+	if !ValidWorkspaceID(jobID) {
+		return nil, resultManifestNotFound()
+	}
+	data, err := readFileFS(s.fs, filepath.Join(s.workspaceFor(jobID).dir, resultManifestFilename))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, resultManifestNotFound()
+		}
+		return nil, err
+	}
+	return data, nil
+}
+
+// ManifestKeys はマニフェストの検証に使う公開鍵の一覧を、署名に使う鍵を先頭にして返します。
+// KMS や Vault の鍵は公開鍵をそこから取得します。
+func (s *Service) ManifestKeys(ctx context.Context) ([]ManifestKey, error) {
+	if s.signer == nil {
+		return nil, resultSigningDisabled()
+	}
+	publicKeys, err := s.signer.PublicKeys(ctx)
+	if err != nil {
+		return nil, newError("RESULT_SIGNING_UNAVAILABLE", "署名鍵の公開鍵を取得できませんでした。時間をおいて再度お試しください。", err)
+	}
+	keys := make([]ManifestKey, 0, len(publicKeys))
+	for _, pk := range publicKeys {
+		pemKey, err := pk.PEM()
+		if err != nil {
+			return nil, newError("RESULT_SIGNING_UNAVAILABLE", "署名鍵の公開鍵を取得できませんでした。時間をおいて再度お試しください。", err)
+		}
+		key := ManifestKey{KeyID: pk.KeyID, Algorithm: pk.Algorithm, PublicKeyPEM: pemKey, Active: pk.Active}
+		if raw, ok := pk.Key.(ed25519.PublicKey); ok {
+			key.PublicKey = base64.StdEncoding.EncodeToString(raw)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+func resultSigningDisabled() error {
+	return newError("RESULT_SIGNING_DISABLED", "この環境では成果物のマニフェストに署名していません。", nil)
+}
+
+func resultManifestNotFound() error {
+	return newError("RESULT_MANIFEST_NOT_FOUND", "ジョブのマニフェストが見つかりませんでした。完了前か、保存期間を過ぎているか、署名鍵の設定前に完了したジョブです。", nil)
+}
+
+// ResultManifestService は成果物の署名付きマニフェストを提供します。
+type ResultManifestService interface {
+	SignedResultManifest(jobID string) ([]byte, error)
+	ManifestKeys(ctx context.Context) ([]ManifestKey, error)
+}
+
+// JobManifestHandler は GET /api/jobs/:id/manifest のハンドラーです。署名付きマニフェストを JSON ファイルとして返します。
+func JobManifestHandler(svc ResultManifestService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		jobID := c.Param("id")
+		data, err := svc.SignedResultManifest(jobID)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+		c.Header("Content-Disposition", `attachment; filename="`+jobID+`.manifest.json"`)
+		c.Header("Cache-Control", "no-store")
+		c.Header("X-Job-Id", jobID)
+		c.Data(http.StatusOK, "application/json; charset=utf-8", data)
+	}
+}
+
+// ManifestKeysHandler は GET /api/manifest-keys のハンドラーです。
+// 成果物を受け取った側がログインせずに検証できるよう、認証の外で公開鍵を返します。
+func ManifestKeysHandler(svc ResultManifestService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		keys, err := svc.ManifestKeys(c.Request.Context())
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"keys": keys})
+	}
+}
@@ -0,0 +1,109 @@
+package pdf
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yourusername/paper-forge/internal/config"
+)
+
+func signingConfig(t *testing.T) *config.Config {
+	t.Helper()
+	seed := func(b byte) string {
+		return base64.StdEncoding.EncodeToString([]byte(strings.Repeat(string(rune(b)), ed25519.SeedSize)))
+	}
+	return &config.Config{WorkDir: t.TempDir(), ResultSigningKeys: "2026-10:" + seed('b') + ",2026-04:" + seed('a')}
+}
+
+func TestResultManifestIsSignedWithActiveKey(t *testing.T) {
+	svc := NewService(signingConfig(t))
+	svc.now = func() time.Time { return time.Date(2026, 10, 16, 9, 30, 0, 0, time.UTC) }
+	ws, err := svc.createWorkspace()
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifest := receiptManifest(2, ReceiptModeAppend)
+	manifest.JobID = ws.jobID
+	result := &Result{Artifacts: []Artifact{{Name: "split.zip", Kind: ResultKindZIP, Size: 4096, SHA256: "ccc", Path: "/secret/path"}}}
+	if err := svc.writeResultManifest(context.Background(), ws, manifest, result); err != nil {
+		t.Fatalf("writeResultManifest: %v", err)
+	}
+
+	data, err := svc.SignedResultManifest(ws.jobID)
+	if err != nil {
+		t.Fatalf("SignedResultManifest: %v", err)
+	}
+	var signed SignedManifest
+	if err := json.Unmarshal(data, &signed); err != nil {
+		t.Fatal(err)
+	}
+	keys, err := svc.ManifestKeys(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 || keys[0].KeyID != "2026-10" || !keys[0].Active || keys[1].Active {
+		t.Fatalf("active key should be listed first: %+v", keys)
+	}
+	if signed.KeyID != "2026-10" || signed.Algorithm != "Ed25519" {
+		t.Fatalf("unexpected signature header: %+v", signed)
+	}
+	public, _ := base64.StdEncoding.DecodeString(keys[0].PublicKey)
+	payload, _ := base64.StdEncoding.DecodeString(signed.Payload)
+	signature, _ := base64.StdEncoding.DecodeString(signed.Signature)
+	if !ed25519.Verify(public, payload, signature) {
+		t.Fatal("signature does not verify with the published key")
+	}
+	if tampered := strings.Replace(string(payload), `"ccc"`, `"ddd"`, 1); ed25519.Verify(public, []byte(tampered), signature) {
+		t.Fatal("signature should not verify after the payload is altered")
+	}
+
+	var got resultManifest
+	if err := json.Unmarshal(payload, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.JobID != ws.jobID || strings.Join(got.Steps, ",") != "split,receipt" || len(got.Inputs) != 2 || got.Outputs[0].SHA256 != "ccc" {
+		t.Fatalf("unexpected manifest: %+v", got)
+	}
+	if strings.Contains(string(payload), "/secret/path") {
+		t.Fatalf("manifest must not expose workspace paths: %s", payload)
+	}
+}
+
+func TestResultManifestHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	disabled := NewService(&config.Config{WorkDir: t.TempDir()})
+	enabled := NewService(signingConfig(t))
+
+	cases := []struct {
+		name string
+		svc  *Service
+		path string
+		code int
+		want string
+	}{
+		{name: "keys", svc: enabled, path: "/api/manifest-keys", code: http.StatusOK, want: `"keyId":"2026-10"`},
+		{name: "keys disabled", svc: disabled, path: "/api/manifest-keys", code: http.StatusNotFound, want: "RESULT_SIGNING_DISABLED"},
+		{name: "manifest disabled", svc: disabled, path: "/api/jobs/6f1c2a3b-0000-4000-8000-000000000000/manifest", code: http.StatusNotFound, want: "RESULT_SIGNING_DISABLED"},
+		{name: "manifest missing", svc: enabled, path: "/api/jobs/6f1c2a3b-0000-4000-8000-000000000000/manifest", code: http.StatusNotFound, want: "RESULT_MANIFEST_NOT_FOUND"},
+		{name: "invalid job id", svc: enabled, path: "/api/jobs/not-a-job/manifest", code: http.StatusNotFound, want: "RESULT_MANIFEST_NOT_FOUND"},
+	}
+	for _, tc := range cases {
+		router := gin.New()
+		router.GET("/api/manifest-keys", ManifestKeysHandler(tc.svc))
+		router.GET("/api/jobs/:id/manifest", JobManifestHandler(tc.svc))
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.path, nil))
+		if rec.Code != tc.code || !strings.Contains(rec.Body.String(), tc.want) {
+			t.Fatalf("%s: got %d %s", tc.name, rec.Code, rec.Body.String())
+		}
+	}
+}
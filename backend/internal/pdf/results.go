@@ -1,59 +1,164 @@
 package pdf
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
-	"os"
+	"io/fs"
 	"path/filepath"
 	"strings"
 )
 
-var operationOutput = map[OperationType]struct {
-	filename string
-	kind     ResultKind
-}{
-	OperationMerge:    {filename: outputFilename, kind: ResultKindPDF},
-	OperationReorder:  {filename: reorderFilename, kind: ResultKindPDF},
-	OperationSplit:    {filename: splitFilename, kind: ResultKindZIP},
-	OperationOptimize: {filename: optimizedFilename, kind: ResultKindPDF},
+// artifactsFilename はジョブ完了時に成果物一覧を保存するファイル名です。
+const artifactsFilename = "artifacts.json"
+
+func writeArtifacts(fsys FS, jobDir string, artifacts []Artifact) error {
+	return writeJSON(fsys, filepath.Join(jobDir, artifactsFilename), artifacts)
+}
+
+// loadArtifacts はワークスペースに保存された成果物一覧を読み込みます。
+// 一覧が無いワークスペース（成果物一覧の導入前に完了したジョブ）は、操作の主成果物のみとして扱います。
+func (s *Service) loadArtifacts(ws workspace, manifest *JobManifest) ([]Artifact, error) {
+	data, err := readFileFS(s.fs, filepath.Join(ws.dir, artifactsFilename))
+	if err == nil {
+		var artifacts []Artifact
+		if err := json.Unmarshal(data, &artifacts); err != nil {
+			return nil, err
+		}
+		for i := range artifacts {
+			artifacts[i].Path = filepath.Join(ws.outDir, filepath.Base(artifacts[i].Name))
+		}
+		return artifacts, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	op, ok := lookupOperation(manifest.Operation)
+	if !ok {
+		return nil, fmt.Errorf("unsupported operation for result download: %s", manifest.Operation)
+	}
+	output := op.OutputSpec()
+	return []Artifact{{
+		Name: output.Filename,
+		Kind: output.Kind,
+		Path: filepath.Join(ws.outDir, output.Filename),
+	}}, nil
+}
+
+// OpenResultFile はジョブIDに対応する主成果物のファイルを開き、Result 情報とファイルハンドルを返します。
+func (s *Service) OpenResultFile(jobID string) (*Result, File, error) {
+	return s.OpenArtifact(jobID, "")
 }
 
-// OpenResultFile はジョブIDに対応する成果物ファイルを開き、Result 情報とファイルハンドルを返します。
-func (s *Service) OpenResultFile(jobID string) (*Result, *os.File, error) {
+// OpenArtifact はジョブIDに対応する成果物のうち name のファイルを開きます。name が空の場合は主成果物を開きます。
+// 戻り値の Result の Output* は開いた成果物の情報です。
+func (s *Service) OpenArtifact(jobID, name string) (*Result, File, error) {
 	if strings.TrimSpace(jobID) == "" {
 		return nil, nil, fmt.Errorf("jobID is required")
 	}
 
 	ws := s.workspaceFor(jobID)
-	manifest, err := loadManifest(ws.dir)
+	manifest, err := loadManifest(s.fs, ws.dir)
 	if err != nil {
 		return nil, nil, err
 	}
-	output, ok := operationOutput[manifest.Operation]
-	if !ok {
-		return nil, nil, fmt.Errorf("unsupported operation for result download: %s", manifest.Operation)
+	artifacts, err := s.loadArtifacts(ws, manifest)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(artifacts) == 0 {
+		return nil, nil, fs.ErrNotExist
 	}
 
-	outputPath := filepath.Join(ws.outDir, output.filename)
-	file, err := os.Open(outputPath)
+	target := artifacts[0]
+	if name != "" {
+		found := false
+		for _, a := range artifacts {
+			if a.Name == name {
+				target, found = a, true
+				break
+			}
+		}
+		if !found {
+			return nil, nil, fmt.Errorf("artifact %q: %w", name, fs.ErrNotExist)
+		}
+	}
+
+	fsys := s.artifactFS()
+	file, err := openFS(fsys, target.Path)
 	if err != nil {
 		return nil, nil, err
 	}
-
 	info, err := file.Stat()
 	if err != nil {
 		file.Close()
 		return nil, nil, err
 	}
+	target.Size = info.Size()
 
-	result := &Result{
-		JobID:          jobID,
-		Operation:      manifest.Operation,
-		OutputPath:     outputPath,
-		OutputFilename: output.filename,
-		OutputSize:     info.Size(),
-		ResultKind:     output.kind,
-		jobDir:         ws.dir,
+	result := newResult(ws, manifest.Operation, fsys, artifacts, nil)
+	result.OutputPath = target.Path
+	result.OutputFilename = target.Name
+	result.OutputSize = target.Size
+	result.ResultKind = target.Kind
+	return result, file, nil
+}
+
+// RetainedInput は keepInputs=true で保持している入力ファイル1つ分の情報です。
+type RetainedInput struct {
+	// Name は GET /api/jobs/:id/inputs/:name で指定する名前です。元のファイル名が重複する場合は保存名になります。
+	Name       string
+	Size       int64
+	storedName string
+}
+
+// RetainedInputs はジョブ投入時に keepInputs=true が指定されていれば、保持している入力ファイルの一覧を返します。
+// 指定されていない場合は空です。
+func (s *Service) RetainedInputs(jobID string) ([]RetainedInput, error) {
+	if strings.TrimSpace(jobID) == "" {
+		return nil, fmt.Errorf("jobID is required")
+	}
+	manifest, err := loadManifest(s.fs, s.workspaceFor(jobID).dir)
+	if err != nil {
+		return nil, err
+	}
+	if !manifest.Options.KeepInputs {
+		return nil, nil
 	}
 
-	return result, file, nil
+	counts := make(map[string]int, len(manifest.Files))
+	for _, f := range manifest.Files {
+		counts[f.OriginalName]++
+	}
+	inputs := make([]RetainedInput, len(manifest.Files))
+	for i, f := range manifest.Files {
+		name := f.OriginalName
+		if name == "" || counts[name] > 1 {
+			name = f.StoredName
+		}
+		inputs[i] = RetainedInput{Name: name, Size: f.Size, storedName: f.StoredName}
+	}
+	return inputs, nil
+}
+
+// OpenInput は keepInputs=true で保持している入力ファイルのうち name のファイルを開きます。
+// 入力を保持していないジョブや、存在しない name の場合は fs.ErrNotExist を返します。
+func (s *Service) OpenInput(jobID, name string) (*RetainedInput, File, error) {
+	inputs, err := s.RetainedInputs(jobID)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range inputs {
+		if inputs[i].Name != name {
+			continue
+		}
+		// 暗号化が有効な場合、入力も保存時に暗号化されているため成果物と同じく復号しながら読み出す
+		file, err := openFS(s.artifactFS(), filepath.Join(s.workspaceFor(jobID).inDir, inputs[i].storedName))
+		if err != nil {
+			return nil, nil, err
+		}
+		return &inputs[i], file, nil
+	}
+	return nil, nil, fmt.Errorf("input %q: %w", name, fs.ErrNotExist)
 }
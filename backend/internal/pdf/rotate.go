@@ -0,0 +1,311 @@
+package pdf
+
+import (
+	"context"
+	"fmt"
+	"mime/multipart"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	pdfapi "github.com/pdfcpu/pdfcpu/pkg/api"
+
+	"github.com/yourusername/paper-forge/internal/tenant"
+)
+
+const rotateFilename = "rotated.pdf"
+
+// rotateAngles は指定できる回転角度（時計回り）です。
+var rotateAngles = []int{90, 180, 270}
+
+// PageRotation はページ範囲と回転角度の組です。Pages は split と同じ範囲指定（例: "1-3,7"）です。
+type PageRotation struct {
+	Pages string `json:"pages"`
+	Angle int    `json:"angle"`
+}
+
+// RotateMultipart は単一PDFの指定ページを回転します。rotations はページ範囲から回転角度への対応です。
+func (s *Service) RotateMultipart(ctx context.Context, file *multipart.FileHeader, rotations map[string]int) (_ *Result, err error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if file == nil {
+		return nil, newError("INVALID_INPUT", "PDFファイルを選択してください。", nil)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	state, _, err := s.prepareRotate(ctx, file, rotations, JobOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = removeDir(state.ws.dir)
+		}
+	}()
+
+	result, execErr := s.executeRotate(ctx, state, nil)
+	if execErr != nil {
+		return nil, execErr
+	}
+	return result, nil
+}
+
+type rotateState struct {
+	ws        workspace
+	file      storedFile
+	rotations []PageRotation
+}
+
+func (s *Service) prepareRotate(ctx context.Context, file *multipart.FileHeader, rotations map[string]int, opts JobOptions) (*rotateState, *JobManifest, error) {
+	normalized, err := normalizeRotations(rotations)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	release, err := s.reserveWorkspace(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer release()
+
+	ws, err := s.createWorkspace()
+	if err != nil {
+		return nil, nil, err
+	}
+	stored, err := s.storeMultipartFile(ctx, file, ws.inDir, 0, false)
+	if err != nil {
+		_ = removeDir(ws.dir)
+		return nil, nil, err
+	}
+
+	if _, err := resolveRotations(normalized, stored.pages); err != nil {
+		_ = removeDir(ws.dir)
+		return nil, nil, err
+	}
+
+	manifest := &JobManifest{
+		JobID:     ws.jobID,
+		Tenant:    tenant.FromContext(ctx),
+		Operation: OperationRotate,
+		Files:     toJobFiles([]storedFile{stored}),
+		Rotations: normalized,
+		Options:   opts,
+		StoreMs:   ws.elapsed().Milliseconds(),
+		CreatedAt: s.now().UTC(),
+	}
+	if err := writeManifest(s.fs, ws.dir, manifest); err != nil {
+		_ = removeDir(ws.dir)
+		return nil, nil, fmt.Errorf("ジョブマニフェストの保存に失敗しました: %w", err)
+	}
+
+	return &rotateState{ws: ws, file: stored, rotations: normalized}, manifest, nil
+}
+
+func (s *Service) executeRotate(ctx context.Context, state *rotateState, progress ProgressReporter) (*Result, error) {
+	ws := state.ws
+	stored := state.file
+
+	byAngle, err := resolveRotations(state.rotations, stored.pages)
+	if err != nil {
+		return nil, err
+	}
+
+	// 角度ごとに1回ずつ回転する。2回目以降は出力ファイルをその場で書き換える
+	outputPath := filepath.Join(ws.outDir, rotateFilename)
+	inputPath := stored.path
+	done := 0
+	for _, angle := range rotateAngles {
+		pages, ok := byAngle[angle]
+		if !ok {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		out := outputPath
+		if inputPath == outputPath {
+			out = ""
+		}
+		if err := runStep(ctx, func() error { return pdfapi.RotateFile(inputPath, out, angle, pages, nil) }); err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return nil, ctxErr
+			}
+			return nil, newError("UNSUPPORTED_PDF", "PDFのページ回転に失敗しました。ファイルが破損していないか確認してください。", err)
+		}
+		inputPath = outputPath
+		done++
+		reportProgress(progress, StageProcess, float64(done)/float64(len(byAngle)))
+	}
+	reportProgress(progress, StageWrite, 0)
+
+	sourceMeta := SourceFileMeta{
+		Name:  stored.originalName,
+		Size:  stored.size,
+		Pages: stored.pages,
+	}
+
+	meta := struct {
+		Type      OperationType  `json:"type"`
+		CreatedAt string         `json:"createdAt"`
+		Source    SourceFileMeta `json:"source"`
+		Rotations []PageRotation `json:"rotations"`
+		Output    string         `json:"output"`
+		Pages     int            `json:"pages"`
+	}{
+		Type:      OperationRotate,
+		CreatedAt: s.now().UTC().Format(time.RFC3339),
+		Source:    sourceMeta,
+		Rotations: state.rotations,
+		Output:    rotateFilename,
+		Pages:     stored.pages,
+	}
+
+	metaPath := filepath.Join(ws.dir, "meta.json")
+	if err := writeJSON(s.fs, metaPath, meta); err != nil {
+		return nil, fmt.Errorf("メタデータの保存に失敗しました: %w", err)
+	}
+
+	s.scheduleCleanup(ws.dir)
+
+	artifact, err := newArtifact(outputPath, ResultKindPDF)
+	if err != nil {
+		return nil, fmt.Errorf("成果物の確認に失敗しました: %w", err)
+	}
+
+	reportProgress(progress, StageCompleted, 1)
+
+	return newResult(ws, OperationRotate, s.fs, []Artifact{artifact}, &RotateMeta{
+		Original:  sourceMeta,
+		Rotations: append([]PageRotation(nil), state.rotations...),
+	}), nil
+}
+
+// PrepareRotateJob は非同期ジョブ用に入力を保存します。
+func (s *Service) PrepareRotateJob(ctx context.Context, file *multipart.FileHeader, rotations map[string]int, opts JobOptions) (*JobManifest, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	_, manifest, err := s.prepareRotate(ctx, file, rotations, opts)
+	if err != nil {
+		return nil, err
+	}
+	return s.sealJob(manifest)
+}
+
+// normalizeRotations は回転指定を検証し、ページ範囲の文字列順に並べた一覧にします。
+// マニフェストやキャッシュキーが指定の順序によらず同じになるようにします。
+func normalizeRotations(rotations map[string]int) ([]PageRotation, error) {
+	if len(rotations) == 0 {
+		return nil, newError("INVALID_INPUT", "回転するページ範囲と角度を指定してください。", nil)
+	}
+	normalized := make([]PageRotation, 0, len(rotations))
+	for pages, angle := range rotations {
+		pages = strings.Join(strings.Fields(pages), "")
+		if pages == "" {
+			return nil, newError("INVALID_INPUT", "空のページ範囲が含まれています。", nil)
+		}
+		if !validRotateAngle(angle) {
+			return nil, newError("INVALID_INPUT", fmt.Sprintf("回転角度は 90, 180, 270 のいずれかで指定してください（%s: %d）。", pages, angle), nil)
+		}
+		normalized = append(normalized, PageRotation{Pages: pages, Angle: angle})
+	}
+	sort.Slice(normalized, func(i, j int) bool { return normalized[i].Pages < normalized[j].Pages })
+	return normalized, nil
+}
+
+// resolveRotations はページ範囲を展開し、角度ごとの pdfcpu のページ指定にまとめます。
+// 同じページを複数の範囲で指定している場合はエラーにします。
+func resolveRotations(rotations []PageRotation, pageCount int) (map[int][]string, error) {
+	if len(rotations) == 0 {
+		return nil, newError("INVALID_INPUT", "回転するページ範囲と角度を指定してください。", nil)
+	}
+	seen := make(map[int]bool)
+	byAngle := make(map[int][]string)
+	for _, r := range rotations {
+		if !validRotateAngle(r.Angle) {
+			return nil, newError("INVALID_INPUT", fmt.Sprintf("回転角度は 90, 180, 270 のいずれかで指定してください（%s: %d）。", r.Pages, r.Angle), nil)
+		}
+		ranges, err := parsePageRanges(r.Pages, pageCount)
+		if err != nil {
+			return nil, err
+		}
+		for _, pr := range ranges {
+			for p := pr.Start; p <= pr.End; p++ {
+				if seen[p] {
+					return nil, newError("INVALID_INPUT", fmt.Sprintf("ページ %d の回転が重複して指定されています。", p), nil)
+				}
+				seen[p] = true
+			}
+			byAngle[r.Angle] = append(byAngle[r.Angle], buildPageSelection(pr)...)
+		}
+	}
+	return byAngle, nil
+}
+
+func validRotateAngle(angle int) bool {
+	for _, a := range rotateAngles {
+		if angle == a {
+			return true
+		}
+	}
+	return false
+}
+
+func init() {
+	registerOperation(rotateOperation{})
+}
+
+type rotateOperation struct{}
+
+func (rotateOperation) Type() OperationType { return OperationRotate }
+
+func (rotateOperation) OutputSpec() OutputSpec {
+	return OutputSpec{Filename: rotateFilename, Kind: ResultKindPDF}
+}
+
+func (rotateOperation) Prepare(ctx context.Context, s *Service, req PrepareRequest) (*JobManifest, error) {
+	file, err := req.singleFile()
+	if err != nil {
+		return nil, err
+	}
+	rotations, err := parseRotationsValue(req.Params.Get("rotations"))
+	if err != nil {
+		return nil, newError("INVALID_INPUT", err.Error(), nil)
+	}
+	return s.PrepareRotateJob(ctx, file, rotations, req.Options)
+}
+
+func (rotateOperation) Execute(ctx context.Context, s *Service, job *jobInput, progress ProgressReporter) (*Result, error) {
+	state := &rotateState{ws: job.ws, file: job.files[0], rotations: job.manifest.Rotations}
+	return s.executeRotate(ctx, state, progress)
+}
+
+func (rotateOperation) Plan(s *Service, job *jobInput) (*JobPlan, error) {
+	file := job.files[0]
+	byAngle, err := resolveRotations(job.manifest.Rotations, file.pages)
+	if err != nil {
+		return nil, err
+	}
+	plan := &JobPlan{
+		Outputs:   []PlannedOutput{{Name: rotateFilename, Kind: ResultKindPDF, Pages: file.pages}},
+		Rotations: make(map[int][]int, len(byAngle)),
+	}
+	for angle, pages := range byAngle {
+		for _, p := range pages {
+			n, err := strconv.Atoi(p)
+			if err != nil {
+				return nil, err
+			}
+			plan.Rotations[angle] = append(plan.Rotations[angle], n)
+			plan.TargetPages = append(plan.TargetPages, n)
+		}
+	}
+	sort.Ints(plan.TargetPages)
+	return plan, nil
+}
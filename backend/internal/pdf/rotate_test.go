@@ -0,0 +1,75 @@
+package pdf
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/yourusername/paper-forge/internal/config"
+	"github.com/yourusername/paper-forge/internal/pdf/pdftest"
+)
+
+func TestResolveRotationsGroupsPagesByAngle(t *testing.T) {
+	rotations, err := normalizeRotations(map[string]int{"5": 90, " 1-2 ": 90, "3": 270})
+	if err != nil {
+		t.Fatalf("normalizeRotations returned error: %v", err)
+	}
+	want := []PageRotation{{Pages: "1-2", Angle: 90}, {Pages: "3", Angle: 270}, {Pages: "5", Angle: 90}}
+	if !reflect.DeepEqual(rotations, want) {
+		t.Fatalf("unexpected normalized rotations: %+v", rotations)
+	}
+
+	byAngle, err := resolveRotations(rotations, 5)
+	if err != nil {
+		t.Fatalf("resolveRotations returned error: %v", err)
+	}
+	if !reflect.DeepEqual(byAngle, map[int][]string{90: {"1", "2", "5"}, 270: {"3"}}) {
+		t.Fatalf("unexpected pages by angle: %v", byAngle)
+	}
+}
+
+func TestResolveRotationsRejectsInvalid(t *testing.T) {
+	if _, err := normalizeRotations(map[string]int{"1": 45}); !IsError(err, "INVALID_INPUT") {
+		t.Fatalf("expected INVALID_INPUT for 45 degrees, got %v", err)
+	}
+	if _, err := normalizeRotations(nil); !IsError(err, "INVALID_INPUT") {
+		t.Fatalf("expected INVALID_INPUT for empty rotations, got %v", err)
+	}
+	cases := map[string][]PageRotation{
+		"overlap":      {{Pages: "1-3", Angle: 90}, {Pages: "3", Angle: 180}},
+		"out of range": {{Pages: "4", Angle: 90}},
+	}
+	for name, rotations := range cases {
+		if _, err := resolveRotations(rotations, 3); !IsError(err, "INVALID_INPUT") {
+			t.Fatalf("%s: expected INVALID_INPUT, got %v", name, err)
+		}
+	}
+}
+
+func TestPrepareRotateJobStoresRotations(t *testing.T) {
+	svc := NewService(&config.Config{MaxFileSize: 1 << 20, MaxPages: 20})
+	svc.tmpRoot = t.TempDir()
+	headers, err := pdftest.FileHeaders("file",
+		pdftest.File{Name: "a.pdf", Data: pdftest.Synthetic(3, 0)},
+	)
+	if err != nil {
+		t.Fatalf("failed to build multipart files: %v", err)
+	}
+
+	manifest, err := svc.PrepareRotateJob(context.Background(), headers[0], map[string]int{"2-3": 180, "1": 90}, JobOptions{})
+	if err != nil {
+		t.Fatalf("PrepareRotateJob returned error: %v", err)
+	}
+	saved, err := loadManifest(svc.fs, svc.workspaceFor(manifest.JobID).dir)
+	if err != nil {
+		t.Fatalf("failed to load manifest: %v", err)
+	}
+	want := []PageRotation{{Pages: "1", Angle: 90}, {Pages: "2-3", Angle: 180}}
+	if saved.Operation != OperationRotate || !reflect.DeepEqual(saved.Rotations, want) {
+		t.Fatalf("unexpected manifest: %+v", saved)
+	}
+
+	if _, err := svc.PrepareRotateJob(context.Background(), headers[0], map[string]int{"1-4": 90}, JobOptions{}); !IsError(err, "INVALID_INPUT") {
+		t.Fatalf("expected INVALID_INPUT for pages beyond the document, got %v", err)
+	}
+}
@@ -0,0 +1,180 @@
+package pdf
+
+import (
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yourusername/paper-forge/internal/tenant"
+)
+
+const (
+	// sharedInputsDirName は内容のハッシュで入力を保存する共有入力ストアのディレクトリです（.inputs/<テナント>/<sha256>.pdf）。
+	sharedInputsDirName = ".inputs"
+	// sharedInputsSweepInterval は入力の保存時に参照されなくなった入力を削除する最短の間隔です。
+	sharedInputsSweepInterval = time.Minute
+)
+
+// sharedInputs は SHARED_INPUT_TENANTS に指定したテナントの入力を内容のハッシュ（SHA-256）で1つだけ保存します。
+//
+// 各ジョブのワークスペースの入力はストアの入力へのハードリンクにするため、同じファイルを複数のジョブで
+// 処理してもディスクは1つ分しか使いません。参照数はハードリンクの数で数え、ワークスペースが削除されて
+// ストアだけが参照する（リンク数が1の）入力は sweep で削除します。
+// 同じ内容を他のテナントと共有しないよう、ストアはテナントごとに分けます。
+type sharedInputs struct {
+	root    string
+	all     bool
+	tenants map[string]struct{}
+
+	mu        sync.Mutex
+	sweptAt   time.Time
+	now       func() time.Time
+	linkInput func(oldname, newname string) error
+}
+
+// newSharedInputs は "テナントID" のカンマ区切り（"*" はすべてのテナント）から共有入力ストアを作成します。
+// 指定が無い場合やハードリンクの数を数えられない環境では nil を返します。
+func newSharedInputs(workRoot, raw string) *sharedInputs {
+	si := &sharedInputs{
+		root:      filepath.Join(workRoot, sharedInputsDirName),
+		tenants:   make(map[string]struct{}),
+		now:       time.Now,
+		linkInput: os.Link,
+	}
+	var invalid []string
+	for _, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		switch {
+		case entry == "":
+		case entry == "*":
+			si.all = true
+		case tenant.Valid(entry):
+			si.tenants[entry] = struct{}{}
+		default:
+			invalid = append(invalid, entry)
+		}
+	}
+	if len(invalid) > 0 {
+		log.Printf("SHARED_INPUT_TENANTS contains invalid tenants (ignored): %s", strings.Join(invalid, ", "))
+	}
+	if !si.all && len(si.tenants) == 0 {
+		return nil
+	}
+	if !hardLinksSupported {
+		log.Printf("SHARED_INPUT_TENANTS is not supported on this platform (ignored)")
+		return nil
+	}
+	return si
+}
+
+// sharedInputsUnlessEncrypted は保存時の暗号化が無効な場合に共有入力ストアを作成します。
+// 入力はジョブのワークスペースごとに暗号化するため、暗号化が有効な場合は共有しません。
+func sharedInputsUnlessEncrypted(keys KeyProvider, workRoot, raw string) *sharedInputs {
+	if keys != nil {
+		if strings.TrimSpace(raw) != "" {
+			log.Printf("SHARED_INPUT_TENANTS is ignored because WORKSPACE_ENCRYPTION_KEYS is set")
+		}
+		return nil
+	}
+	return newSharedInputs(workRoot, raw)
+}
+
+// enabled はテナント tenantID の入力を共有入力ストアに保存するかを返します。
+func (si *sharedInputs) enabled(tenantID string) bool {
+	if si == nil {
+		return false
+	}
+	if si.all {
+		return true
+	}
+	_, ok := si.tenants[tenant.Normalize(tenantID)]
+	return ok
+}
+
+// share は保存済みの入力 path を、テナントの共有入力ストアにある同じ内容（ハッシュ digest と大きさ size）の入力へのハードリンクに置き換えます。
+// ストアに無い場合は path をストアに追加します。既存の入力を使った場合は true を返します。
+// ハードリンクを作れない場合（別のファイルシステムなど）は path をそのまま使い、エラーにはしません。
+func (si *sharedInputs) share(tenantID, path, digest string, size int64) bool {
+	if !si.enabled(tenantID) || digest == "" {
+		return false
+	}
+	dir := filepath.Join(si.root, tenant.Normalize(tenantID))
+	blob := filepath.Join(dir, digest+".pdf")
+
+	// sweep と同時に実行して、リンクする直前の入力を削除しないようにする
+	si.mu.Lock()
+	defer si.mu.Unlock()
+	si.sweepLocked()
+
+	if info, err := os.Stat(blob); err == nil && info.Size() == size {
+		tmp := path + ".shared"
+		if err := si.linkInput(blob, tmp); err != nil {
+			log.Printf("failed to link shared input %s: %v", digest, err)
+			return false
+		}
+		if err := os.Rename(tmp, path); err != nil {
+			_ = os.Remove(tmp)
+			log.Printf("failed to replace input with shared input %s: %v", digest, err)
+			return false
+		}
+		return true
+	}
+
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		log.Printf("failed to create shared input store: %v", err)
+		return false
+	}
+	// 同じハッシュで大きさの異なる入力（書きかけなど）は置き換える
+	_ = os.Remove(blob)
+	if err := si.linkInput(path, blob); err != nil {
+		log.Printf("failed to add shared input %s: %v", digest, err)
+	}
+	return false
+}
+
+// sweep はどのワークスペースからも参照されなくなった入力を削除します。
+func (si *sharedInputs) sweep() {
+	if si == nil {
+		return
+	}
+	si.mu.Lock()
+	defer si.mu.Unlock()
+	si.sweptAt = time.Time{}
+	si.sweepLocked()
+}
+
+// sweepLocked は前回から sharedInputsSweepInterval 以上経っていれば、リンク数が1の入力を削除します。
+func (si *sharedInputs) sweepLocked() {
+	now := si.now()
+	if !si.sweptAt.IsZero() && now.Sub(si.sweptAt) < sharedInputsSweepInterval {
+		return
+	}
+	si.sweptAt = now
+	err := filepath.WalkDir(si.root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		if n, ok := linkCount(info); ok && n <= 1 {
+			_ = os.Remove(path)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("failed to sweep shared inputs: %v", err)
+	}
+}
@@ -0,0 +1,147 @@
+//go:build unix
+
+package pdf
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/yourusername/paper-forge/internal/config"
+	"github.com/yourusername/paper-forge/internal/pdf/pdftest"
+	"github.com/yourusername/paper-forge/internal/tenant"
+)
+
+func newSharedInputsTestService(t *testing.T, tenants string) *Service {
+	t.Helper()
+	root := t.TempDir()
+	svc := NewService(&config.Config{MaxFileSize: 1 << 20, WorkDir: root, SharedInputTenants: tenants})
+	if svc.sharedInputs == nil {
+		t.Fatal("shared inputs should be enabled")
+	}
+	return svc
+}
+
+func storeSharedInput(t *testing.T, svc *Service, ctx context.Context, data []byte) (workspace, storedFile) {
+	t.Helper()
+	headers, err := pdftest.FileHeaders("file", pdftest.File{Name: "a.pdf", Data: data})
+	if err != nil {
+		t.Fatalf("failed to build multipart files: %v", err)
+	}
+	ws, err := svc.createWorkspace()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored, err := svc.storeMultipartFile(ctx, headers[0], ws.inDir, 0, false)
+	if err != nil {
+		t.Fatalf("storeMultipartFile returned error: %v", err)
+	}
+	return ws, stored
+}
+
+func TestSharedInputsStoresIdenticalInputOnce(t *testing.T) {
+	svc := newSharedInputsTestService(t, "legal")
+	ctx := tenant.WithID(context.Background(), "legal")
+	data := pdftest.Synthetic(3, 8192)
+
+	wsA, a := storeSharedInput(t, svc, ctx, data)
+	wsB, b := storeSharedInput(t, svc, ctx, data)
+
+	infoA, err := os.Stat(a.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	infoB, err := os.Stat(b.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !os.SameFile(infoA, infoB) {
+		t.Fatal("identical inputs should share one file")
+	}
+	if n, _ := linkCount(infoA); n != 3 {
+		t.Fatalf("link count = %d, want 3 (store + 2 workspaces)", n)
+	}
+	if got, want := diskUsage(svc.tmpRoot), int64(len(data)); got != want {
+		t.Fatalf("diskUsage = %d, want %d", got, want)
+	}
+	if b.pages != 3 || b.sha256 != a.sha256 {
+		t.Fatalf("unexpected stored file: %+v", b)
+	}
+
+	// 参照するワークスペースが残っている間は削除しない
+	blob := filepath.Join(svc.tmpRoot, sharedInputsDirName, "legal", a.sha256+".pdf")
+	if err := removeDir(wsA.dir); err != nil {
+		t.Fatal(err)
+	}
+	svc.sharedInputs.sweep()
+	if _, err := os.Stat(blob); err != nil {
+		t.Fatalf("shared input referenced by another job was removed: %v", err)
+	}
+
+	if err := removeDir(wsB.dir); err != nil {
+		t.Fatal(err)
+	}
+	svc.sharedInputs.sweep()
+	if _, err := os.Stat(blob); !os.IsNotExist(err) {
+		t.Fatalf("unreferenced shared input should be removed: %v", err)
+	}
+}
+
+func TestSharedInputsOnlyForListedTenants(t *testing.T) {
+	svc := newSharedInputsTestService(t, "legal")
+	data := pdftest.Synthetic(1, 0)
+
+	_, a := storeSharedInput(t, svc, tenant.WithID(context.Background(), "sales"), data)
+	_, b := storeSharedInput(t, svc, tenant.WithID(context.Background(), "sales"), data)
+	infoA, _ := os.Stat(a.path)
+	infoB, _ := os.Stat(b.path)
+	if os.SameFile(infoA, infoB) {
+		t.Fatal("inputs of an unlisted tenant should not be shared")
+	}
+	if _, err := os.Stat(filepath.Join(svc.tmpRoot, sharedInputsDirName)); !os.IsNotExist(err) {
+		t.Fatalf("shared input store should not be created: %v", err)
+	}
+}
+
+func TestSharedInputsSeparatesTenants(t *testing.T) {
+	svc := newSharedInputsTestService(t, "*")
+	data := pdftest.Synthetic(1, 0)
+
+	_, a := storeSharedInput(t, svc, tenant.WithID(context.Background(), "legal"), data)
+	_, b := storeSharedInput(t, svc, tenant.WithID(context.Background(), "sales"), data)
+	infoA, _ := os.Stat(a.path)
+	infoB, _ := os.Stat(b.path)
+	if os.SameFile(infoA, infoB) {
+		t.Fatal("inputs should not be shared across tenants")
+	}
+}
+
+func TestSharedInputsDisabledWithEncryption(t *testing.T) {
+	if si := sharedInputsUnlessEncrypted(testKeys(t), t.TempDir(), "*"); si != nil {
+		t.Fatal("shared inputs should be disabled when workspace encryption is enabled")
+	}
+	if si := newSharedInputs(t.TempDir(), " , INVALID "); si != nil {
+		t.Fatal("shared inputs should be disabled without valid tenants")
+	}
+}
+
+func TestPurgeWorkspaceRemovesUnreferencedSharedInput(t *testing.T) {
+	svc := newSharedInputsTestService(t, "*")
+	ctx := context.Background()
+	data := pdftest.Synthetic(2, 0)
+
+	ws, stored := storeSharedInput(t, svc, ctx, data)
+	manifest := &JobManifest{JobID: ws.jobID, Operation: OperationMerge, Files: []JobFile{{OriginalName: "a.pdf", StoredName: filepath.Base(stored.path), Size: stored.size, SHA256: stored.sha256}}}
+	if err := writeManifest(svc.fs, ws.dir, manifest); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := svc.PurgeWorkspace(ws.jobID); err != nil {
+		t.Fatalf("PurgeWorkspace returned error: %v", err)
+	}
+	blob := filepath.Join(svc.tmpRoot, sharedInputsDirName, tenant.Default, stored.sha256+".pdf")
+	if _, err := os.Stat(blob); !os.IsNotExist(err) {
+		t.Fatalf("shared input should be removed with its last workspace: %v", err)
+	}
+}
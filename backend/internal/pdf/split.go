@@ -3,6 +3,7 @@ package pdf
 import (
 	"archive/zip"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"mime/multipart"
@@ -14,9 +15,17 @@ import (
 	"time"
 
 	pdfapi "github.com/pdfcpu/pdfcpu/pkg/api"
+
+	"github.com/yourusername/paper-forge/internal/tenant"
 )
 
-const splitFilename = "split.zip"
+const (
+	splitFilename = "split.zip"
+	// SplitRangesEach は全ページを1ページずつのファイルに分割する（バースト）範囲指定です。
+	SplitRangesEach = "each"
+	// SplitModeBurst は mode フォーム項目で1ページずつの分割を指定する値です（ranges=each と同じ）。
+	SplitModeBurst = "burst"
+)
 
 // SplitMultipart は範囲指定によるPDF分割を行います。
 func (s *Service) SplitMultipart(ctx context.Context, file *multipart.FileHeader, rangesExpr string) (_ *Result, err error) {
@@ -35,7 +44,7 @@ func (s *Service) SplitMultipart(ctx context.Context, file *multipart.FileHeader
 		return nil, err
 	}
 
-	state, _, err := s.prepareSplit(ctx, file, rangesExpr)
+	state, _, err := s.prepareSplit(ctx, file, rangesExpr, SplitOutputZIP, JobOptions{})
 	if err != nil {
 		return nil, err
 	}
@@ -57,20 +66,32 @@ type splitState struct {
 	file      storedFile
 	ranges    []PageRange
 	rangesRaw string
+	output    SplitOutput
 }
 
-func (s *Service) prepareSplit(ctx context.Context, file *multipart.FileHeader, rangesExpr string) (*splitState, *JobManifest, error) {
+func (s *Service) prepareSplit(ctx context.Context, file *multipart.FileHeader, rangesExpr string, output SplitOutput, opts JobOptions) (*splitState, *JobManifest, error) {
+	output, err := normalizeSplitOutput(output)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	release, err := s.reserveWorkspace(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer release()
+
 	ws, err := s.createWorkspace()
 	if err != nil {
 		return nil, nil, err
 	}
-	stored, err := s.storeMultipartFile(ctx, file, ws.inDir, 0)
+	stored, err := s.storeMultipartFile(ctx, file, ws.inDir, 0, false)
 	if err != nil {
 		_ = removeDir(ws.dir)
 		return nil, nil, err
 	}
 
-	rangesParsed, err := parsePageRanges(rangesExpr, stored.pages)
+	rangesParsed, err := parseSplitRanges(rangesExpr, stored.pages)
 	if err != nil {
 		_ = removeDir(ws.dir)
 		return nil, nil, err
@@ -78,33 +99,42 @@ func (s *Service) prepareSplit(ctx context.Context, file *multipart.FileHeader,
 
 	manifest := &JobManifest{
 		JobID:     ws.jobID,
+		Tenant:    tenant.FromContext(ctx),
 		Operation: OperationSplit,
 		Files:     toJobFiles([]storedFile{stored}),
 		Ranges:    rangesExpr,
+		Options:   opts,
+		StoreMs:   ws.elapsed().Milliseconds(),
 		CreatedAt: s.now().UTC(),
 	}
-	if err := writeManifest(ws.dir, manifest); err != nil {
+	// 既定の ZIP はマニフェストに書かず、従来のマニフェストと同じ内容にする
+	if output == SplitOutputParts {
+		manifest.Output = output
+	}
+	if err := writeManifest(s.fs, ws.dir, manifest); err != nil {
 		_ = removeDir(ws.dir)
 		return nil, nil, fmt.Errorf("ジョブマニフェストの保存に失敗しました: %w", err)
 	}
 
-	return &splitState{ws: ws, file: stored, ranges: rangesParsed, rangesRaw: rangesExpr}, manifest, nil
+	return &splitState{ws: ws, file: stored, ranges: rangesParsed, rangesRaw: rangesExpr, output: output}, manifest, nil
 }
 
 func (s *Service) executeSplit(ctx context.Context, state *splitState, progress ProgressReporter) (*Result, error) {
 	ws := state.ws
-	stored := state.file
 	ranges := state.ranges
 	if ranges == nil {
-		parsed, err := parsePageRanges(state.rangesRaw, stored.pages)
+		parsed, err := s.runTimePageRanges(&state.file, state.rangesRaw)
 		if err != nil {
 			return nil, err
 		}
 		ranges = parsed
 	}
+	stored := state.file
+	burst := burstSplit(state.rangesRaw)
 
 	partsMeta := make([]SplitPart, 0, len(ranges))
 	partPaths := make([]string, 0, len(ranges))
+	partArtifacts := make([]Artifact, 0, len(ranges))
 
 	for i, pr := range ranges {
 		select {
@@ -114,40 +144,51 @@ func (s *Service) executeSplit(ctx context.Context, state *splitState, progress
 		}
 
 		pageSelection := buildPageSelection(pr)
-		partName := fmt.Sprintf("part-%02d.pdf", i+1)
+		partName := splitPartName(i, pr, burst)
 		partPath := filepath.Join(ws.outDir, partName)
 
-		reportProgress(progress, "process", 20+(60*(i+1))/len(ranges))
+		reportProgress(progress, StageProcess, float64(i+1)/float64(len(ranges)))
 
-		if err := pdfapi.CollectFile(stored.path, partPath, pageSelection, nil); err != nil {
+		if err := runStep(ctx, func() error { return pdfapi.CollectFile(stored.path, partPath, pageSelection, nil) }); err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return nil, ctxErr
+			}
 			return nil, newError("UNSUPPORTED_PDF", fmt.Sprintf("ページ範囲 %d の生成に失敗しました。", i+1), err)
 		}
 
-		info, statErr := os.Stat(partPath)
-		if statErr != nil {
-			return nil, fmt.Errorf("partファイルの確認に失敗しました: %w", statErr)
+		artifact, err := newArtifact(partPath, ResultKindPDF)
+		if err != nil {
+			return nil, fmt.Errorf("partファイルの確認に失敗しました: %w", err)
 		}
 
 		partsMeta = append(partsMeta, SplitPart{
 			Filename: partName,
 			FromPage: pr.Start,
 			ToPage:   pr.End,
-			Pages:    pr.End - pr.Start + 1,
-			Size:     info.Size(),
+			Pages:    pr.count(),
+			Size:     artifact.Size,
+			SHA256:   artifact.SHA256,
 		})
 		partPaths = append(partPaths, partPath)
+		partArtifacts = append(partArtifacts, artifact)
 	}
 
-	outputPath := filepath.Join(ws.outDir, splitFilename)
-	if err := createZip(outputPath, partPaths); err != nil {
-		return nil, err
-	}
-	reportProgress(progress, "write", 90)
-
-	outInfo, err := os.Stat(outputPath)
-	if err != nil {
-		return nil, fmt.Errorf("zipファイルの確認に失敗しました: %w", err)
+	// ZIP を主成果物とし、分割した各PDFも個別の成果物として取得できるようにする。
+	// parts の場合は ZIP を作らず、各パートの PDF だけを成果物とする（先頭のパートが主成果物）
+	artifacts := partArtifacts
+	if state.output != SplitOutputParts {
+		outputPath := filepath.Join(ws.outDir, splitFilename)
+		comment := splitZipComment(partsMeta)
+		if err := runStep(ctx, func() error { return createZip(outputPath, partPaths, comment) }); err != nil {
+			return nil, err
+		}
+		zipArtifact, err := newArtifact(outputPath, ResultKindZIP)
+		if err != nil {
+			return nil, fmt.Errorf("成果物の確認に失敗しました: %w", err)
+		}
+		artifacts = append([]Artifact{zipArtifact}, partArtifacts...)
 	}
+	reportProgress(progress, StageWrite, 0)
 
 	sourceMeta := SourceFileMeta{
 		Name:  stored.originalName,
@@ -170,50 +211,134 @@ func (s *Service) executeSplit(ctx context.Context, state *splitState, progress
 	}
 
 	metaPath := filepath.Join(ws.dir, "meta.json")
-	if err := writeJSON(metaPath, meta); err != nil {
+	if err := writeJSON(s.fs, metaPath, meta); err != nil {
 		return nil, fmt.Errorf("メタデータの保存に失敗しました: %w", err)
 	}
 
-	expireMinutes := s.cfg.JobExpireMinutes
-	if expireMinutes <= 0 {
-		expireMinutes = defaultCleanupMin
-	}
-	time.AfterFunc(time.Duration(expireMinutes)*time.Minute, func() {
-		_ = removeDir(ws.dir)
-	})
-
-	reportProgress(progress, "completed", 100)
-
-	return &Result{
-		JobID:          ws.jobID,
-		Operation:      OperationSplit,
-		OutputPath:     outputPath,
-		OutputFilename: splitFilename,
-		OutputSize:     outInfo.Size(),
-		ResultKind:     ResultKindZIP,
-		Meta: &SplitMeta{
-			Original: sourceMeta,
-			Ranges:   ranges,
-			Parts:    partsMeta,
-		},
-		jobDir: ws.dir,
-	}, nil
+	s.scheduleCleanup(ws.dir)
+
+	reportProgress(progress, StageCompleted, 1)
+
+	return newResult(ws, OperationSplit, s.fs, artifacts, &SplitMeta{
+		Original: sourceMeta,
+		Ranges:   ranges,
+		Parts:    partsMeta,
+	}), nil
 }
 
 // PrepareSplitJob は非同期ジョブ用に入力を保存します。
-func (s *Service) PrepareSplitJob(ctx context.Context, file *multipart.FileHeader, rangesExpr string) (*JobManifest, error) {
+// output に SplitOutputParts を指定すると ZIP を作らず、各パートを個別の成果物として返します。
+func (s *Service) PrepareSplitJob(ctx context.Context, file *multipart.FileHeader, rangesExpr string, output SplitOutput, opts JobOptions) (*JobManifest, error) {
 	if ctx == nil {
 		ctx = context.Background()
 	}
-	_, manifest, err := s.prepareSplit(ctx, file, rangesExpr)
+	_, manifest, err := s.prepareSplit(ctx, file, rangesExpr, output, opts)
 	if err != nil {
 		return nil, err
 	}
-	return manifest, nil
+	return s.sealJob(manifest)
 }
 
-// parsePageRanges 以下の関数は従来実装を再利用
+// normalizeSplitOutput は分割結果の受け取り方を検証します。空の場合は ZIP です。
+func normalizeSplitOutput(o SplitOutput) (SplitOutput, error) {
+	switch SplitOutput(strings.ToLower(strings.TrimSpace(string(o)))) {
+	case "", SplitOutputZIP:
+		return SplitOutputZIP, nil
+	case SplitOutputParts:
+		return SplitOutputParts, nil
+	default:
+		return "", newError("INVALID_INPUT", fmt.Sprintf("outputには zip または parts を指定してください (received: %s)", o), nil)
+	}
+}
+
+// runTimePageRanges はジョブの実行時に範囲指定を入力の実際のページ数で検証し直します。
+// 受付時にページ数が分からない入力（pages が 0）はここで数え、file に反映します。
+// "10-" や last などの相対的な指定は実行時のページ数で解決し、範囲外の場合はジョブに記録できるよう INVALID_RANGE を返します。
+func (s *Service) runTimePageRanges(file *storedFile, raw string) ([]PageRange, error) {
+	if file.pages == 0 {
+		pages, err := s.countStoredPages(file.path, file.originalName, true)
+		if err != nil {
+			return nil, err
+		}
+		file.pages = pages
+	}
+	ranges, err := parseSplitRanges(raw, file.pages)
+	if err != nil {
+		var apiErr *Error
+		if errors.As(err, &apiErr) && apiErr.Code == "INVALID_INPUT" {
+			return nil, newError("INVALID_RANGE", fmt.Sprintf("ページ範囲 %q は %s（%dページ）に対して正しくありません: %s", raw, file.originalName, file.pages, apiErr.Message), err)
+		}
+		return nil, err
+	}
+	return ranges, nil
+}
+
+// SplitRangesFromForm は分割のフォーム項目 ranges と mode から範囲指定を決めます。
+// mode=burst の場合は SplitRangesEach を返します。ranges に each 以外を同時に指定した場合はエラーです。
+func SplitRangesFromForm(ranges, mode string) (string, error) {
+	ranges = strings.TrimSpace(ranges)
+	switch strings.ToLower(strings.TrimSpace(mode)) {
+	case "":
+		return ranges, nil
+	case SplitModeBurst:
+		if ranges != "" && !burstSplit(ranges) {
+			return "", newError("INVALID_INPUT", "mode=burst の場合は ranges を指定しないでください。", nil)
+		}
+		return SplitRangesEach, nil
+	default:
+		return "", newError("INVALID_INPUT", fmt.Sprintf("modeには burst を指定してください (received: %s)", mode), nil)
+	}
+}
+
+// burstSplit は範囲指定が1ページずつの分割（each）かを返します。
+func burstSplit(expr string) bool {
+	return strings.EqualFold(strings.TrimSpace(expr), SplitRangesEach)
+}
+
+// parseSplitRanges は分割の範囲指定を解析します。each の場合は全ページを1ページずつの範囲にします。
+func parseSplitRanges(expr string, pageCount int) ([]PageRange, error) {
+	if !burstSplit(expr) {
+		return parseOrderedPageRanges(expr, pageCount)
+	}
+	if pageCount < 1 {
+		return nil, newError("INVALID_INPUT", "有効なページ範囲が指定されていません。", nil)
+	}
+	ranges := make([]PageRange, 0, pageCount)
+	for p := 1; p <= pageCount; p++ {
+		ranges = append(ranges, PageRange{Start: p, End: p})
+	}
+	return ranges, nil
+}
+
+// splitPartName は i 番目の範囲 pr のパートのファイル名です。
+// 1ページずつの分割では元のページ番号（page-0001.pdf）、それ以外は範囲の順番（part-01.pdf）です。
+func splitPartName(i int, pr PageRange, burst bool) string {
+	if burst {
+		return fmt.Sprintf("page-%04d.pdf", pr.Start)
+	}
+	return fmt.Sprintf("part-%02d.pdf", i+1)
+}
+
+// parsePageRanges はページ範囲指定（例: "1-3,5,8-"）を解析します。
+// 範囲は昇順に重複なく並べる必要があります。odd / even / last / last-N の省略記法も使えます（parsePageRangeList を参照）。
 func parsePageRanges(expr string, pageCount int) ([]PageRange, error) {
+	return parsePageRangeList(expr, pageCount, false)
+}
+
+// parseOrderedPageRanges は parsePageRanges に加えて、"10-1" のような逆順の範囲を受け付けます。
+// 成果物のページ順が範囲指定の順になる split / extract で使います。
+func parseOrderedPageRanges(expr string, pageCount int) ([]PageRange, error) {
+	return parsePageRangeList(expr, pageCount, true)
+}
+
+// parsePageRangeList はカンマ区切りの範囲指定を解析します。各要素には次を指定できます。
+//   - "5"、"3-7"、"3-"（最終ページまで）
+//   - "last"（最終ページ）、"last-N"（最終ページの N ページ前）。範囲の端にも使えます（例: "last-3-last"）
+//   - "odd" / "even"（奇数 / 偶数ページ。1ページずつの範囲に展開します）
+//   - allowReverse が true の場合は "10-1" のような逆順の範囲（Start > End）
+//
+// 範囲どうしは昇順に重複なく並べる必要があります（逆順の範囲は含むページの最小値と最大値で判定します）。
+func parsePageRangeList(expr string, pageCount int, allowReverse bool) ([]PageRange, error) {
 	segments := strings.Split(expr, ",")
 	if len(segments) == 0 {
 		return nil, newError("INVALID_INPUT", "範囲指定の形式が正しくありません。", nil)
@@ -224,31 +349,33 @@ func parsePageRanges(expr string, pageCount int) ([]PageRange, error) {
 	lastEnd := 0
 
 	for i, seg := range segments {
-		seg = strings.TrimSpace(seg)
+		seg = strings.ToLower(strings.TrimSpace(seg))
 		if seg == "" {
 			return nil, newError("INVALID_INPUT", "空の範囲指定が含まれています。", nil)
 		}
 
-		start, end, err := parseSingleRange(seg, pageCount)
+		segRanges, err := parseRangeSegment(seg, pageCount, allowReverse)
 		if err != nil {
 			return nil, err
 		}
 
-		if start <= lastEnd {
-			return nil, newError("INVALID_INPUT", "ページ範囲は昇順で指定してください。", nil)
-		}
-		lastEnd = end
+		for _, pr := range segRanges {
+			lo, hi := pr.bounds()
+			if lo <= lastEnd {
+				return nil, newError("INVALID_INPUT", "ページ範囲は昇順で指定してください。", nil)
+			}
+			lastEnd = hi
 
-		for p := start; p <= end; p++ {
-			if _, exists := usedPages[p]; exists {
-				return nil, newError("INVALID_INPUT", fmt.Sprintf("ページ %d が重複しています。", p), nil)
+			for p := lo; p <= hi; p++ {
+				if _, exists := usedPages[p]; exists {
+					return nil, newError("INVALID_INPUT", fmt.Sprintf("ページ %d が重複しています。", p), nil)
+				}
+				usedPages[p] = struct{}{}
 			}
-			usedPages[p] = struct{}{}
+			ranges = append(ranges, pr)
 		}
 
-		ranges = append(ranges, PageRange{Start: start, End: end})
-
-		if end == pageCount && i != len(segments)-1 {
+		if lastEnd == pageCount && i != len(segments)-1 {
 			return nil, newError("INVALID_INPUT", "最終ページ指定の後に追加の範囲を指定することはできません。", nil)
 		}
 	}
@@ -260,59 +387,178 @@ func parsePageRanges(expr string, pageCount int) ([]PageRange, error) {
 	return ranges, nil
 }
 
+// parseRangeSegment は範囲指定の1要素を解析します。odd / even は1ページずつの範囲に展開します。
+func parseRangeSegment(seg string, pageCount int, allowReverse bool) ([]PageRange, error) {
+	switch seg {
+	case "odd", "even":
+		first := 1
+		if seg == "even" {
+			first = 2
+		}
+		if first > pageCount {
+			return nil, newError("INVALID_INPUT", "偶数ページがありません。", nil)
+		}
+		ranges := make([]PageRange, 0, (pageCount-first)/2+1)
+		for p := first; p <= pageCount; p += 2 {
+			ranges = append(ranges, PageRange{Start: p, End: p})
+		}
+		return ranges, nil
+	}
+
+	start, end, err := parseSingleRange(seg, pageCount)
+	if err != nil {
+		return nil, err
+	}
+	if start > end && !allowReverse {
+		return nil, newError("INVALID_INPUT", "範囲指定がページ数の範囲外です。", nil)
+	}
+	return []PageRange{{Start: start, End: end}}, nil
+}
+
+// parseSingleRange は "5"、"3-7"、"3-"、"last-2-last" などの範囲を解析し、開始と終了のページ番号を返します。
+// 開始が終了より大きい（逆順の）範囲もそのまま返します。
 func parseSingleRange(seg string, pageCount int) (int, int, error) {
-	if strings.Contains(seg, "-") {
-		parts := strings.SplitN(seg, "-", 2)
-		if len(parts) != 2 {
-			return 0, 0, newError("INVALID_INPUT", "範囲指定が正しくありません。", nil)
+	startRef, rest := splitPageRef(seg)
+	if startRef == "" {
+		if !strings.Contains(seg, "-") {
+			return 0, 0, newError("INVALID_INPUT", "ページ番号が整数ではありません。", nil)
 		}
-		start, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+		return 0, 0, newError("INVALID_INPUT", "範囲開始が整数ではありません。", nil)
+	}
+	start, err := resolvePageRef(startRef, pageCount)
+	if err != nil {
+		return 0, 0, err
+	}
+	if rest == "" {
+		if start < 1 || start > pageCount {
+			return 0, 0, newError("INVALID_INPUT", "ページ番号がページ数の範囲外です。", nil)
+		}
+		return start, start, nil
+	}
+	if !strings.HasPrefix(rest, "-") {
+		return 0, 0, newError("INVALID_INPUT", "範囲指定が正しくありません。", nil)
+	}
+
+	end := pageCount
+	if endRef := strings.TrimSpace(rest[1:]); endRef != "" {
+		ref, tail := splitPageRef(endRef)
+		if ref == "" || tail != "" {
+			return 0, 0, newError("INVALID_INPUT", "範囲終了が整数ではありません。", nil)
+		}
+		end, err = resolvePageRef(ref, pageCount)
 		if err != nil {
-			return 0, 0, newError("INVALID_INPUT", "範囲開始が整数ではありません。", nil)
+			return 0, 0, err
 		}
-		var end int
-		if strings.TrimSpace(parts[1]) == "" {
-			end = pageCount
-		} else {
-			end, err = strconv.Atoi(strings.TrimSpace(parts[1]))
-			if err != nil {
-				return 0, 0, newError("INVALID_INPUT", "範囲終了が整数ではありません。", nil)
+	}
+
+	if start < 1 || end < 1 || start > pageCount || end > pageCount {
+		return 0, 0, newError("INVALID_INPUT", "範囲指定がページ数の範囲外です。", nil)
+	}
+	return start, end, nil
+}
+
+// splitPageRef は s の先頭からページ番号の指定（整数、last、last-N）を切り出し、残りとともに返します。
+// 先頭がページ番号の指定でない場合は ref が空になります。
+func splitPageRef(s string) (ref, rest string) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "last") {
+		n := len("last")
+		// "last-N" の N まで含める。"last-" の後が数字でなければ範囲の区切りとして残す
+		if tail := strings.TrimLeft(s[n:], " "); strings.HasPrefix(tail, "-") {
+			digits := strings.TrimLeft(tail[1:], " ")
+			if d := leadingDigits(digits); d > 0 {
+				consumed := len(s) - len(digits) + d
+				return s[:consumed], strings.TrimSpace(s[consumed:])
 			}
 		}
+		return s[:n], strings.TrimSpace(s[n:])
+	}
+	d := leadingDigits(s)
+	return s[:d], strings.TrimSpace(s[d:])
+}
 
-		if start < 1 || end < start || end > pageCount {
-			return 0, 0, newError("INVALID_INPUT", "範囲指定がページ数の範囲外です。", nil)
-		}
-		return start, end, nil
+func leadingDigits(s string) int {
+	i := 0
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
 	}
+	return i
+}
 
-	page, err := strconv.Atoi(seg)
-	if err != nil {
-		return 0, 0, newError("INVALID_INPUT", "ページ番号が整数ではありません。", nil)
+// resolvePageRef はページ番号の指定（整数、last、last-N）を1-based のページ番号にします。範囲の確認は呼び出し側で行います。
+func resolvePageRef(ref string, pageCount int) (int, error) {
+	if strings.HasPrefix(ref, "last") {
+		offset := 0
+		if tail := strings.TrimSpace(ref[len("last"):]); tail != "" {
+			n, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(tail, "-")))
+			if err != nil {
+				return 0, newError("INVALID_INPUT", "last-N の N は整数で指定してください。", nil)
+			}
+			offset = n
+		}
+		return pageCount - offset, nil
 	}
-	if page < 1 || page > pageCount {
-		return 0, 0, newError("INVALID_INPUT", "ページ番号がページ数の範囲外です。", nil)
+	page, err := strconv.Atoi(ref)
+	if err != nil {
+		return 0, newError("INVALID_INPUT", "ページ番号が整数ではありません。", nil)
 	}
-	return page, page, nil
+	return page, nil
 }
 
+// buildPageSelection は範囲のページを並び順に pdfcpu のページ選択として返します。
 func buildPageSelection(pr PageRange) []string {
-	pages := make([]string, 0, pr.End-pr.Start+1)
-	for p := pr.Start; p <= pr.End; p++ {
-		pages = append(pages, strconv.Itoa(p))
+	pages := pr.pages()
+	selection := make([]string, len(pages))
+	for i, p := range pages {
+		selection[i] = strconv.Itoa(p)
+	}
+	return selection
+}
+
+// maxZipComment は ZIP のコメントの最大バイト数です（ZIP の仕様上の上限）。
+const maxZipComment = 65535
+
+// splitZipComment は各パートのページ範囲と SHA-256 を ZIP のコメントとして返します。
+// 配布先でパートごとに内容を検証できるよう、1行に1パート（"part-01.pdf pages=1-3 sha256=..."）を並べます。
+// パートが多くコメントの上限を超える場合は、ジョブ情報の meta.parts を参照するよう案内する1行だけにします。
+func splitZipComment(parts []SplitPart) string {
+	var b strings.Builder
+	b.WriteString("paper-forge split parts\n")
+	for _, p := range parts {
+		fmt.Fprintf(&b, "%s pages=%d-%d sha256=%s\n", p.Filename, p.FromPage, p.ToPage, p.SHA256)
 	}
-	return pages
+	if b.Len() > maxZipComment {
+		return "paper-forge split parts: see meta.parts of the job for per-part sha256\n"
+	}
+	return b.String()
 }
 
-func createZip(outputPath string, files []string) error {
+// createZip は files を1つの ZIP にまとめます。comment が空でなければ ZIP のコメントとして書き込みます。
+// 4GB を超えるエントリやアーカイブ、65535 件を超えるエントリは archive/zip が自動で ZIP64 形式にします。
+// ZIP64 のレコードを含む中央ディレクトリは Close で書き込まれるため、Close の失敗もエラーとして返します。
+func createZip(outputPath string, files []string, comment string) (err error) {
 	outFile, err := os.OpenFile(outputPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o640)
 	if err != nil {
 		return fmt.Errorf("zipファイルの作成に失敗しました: %w", err)
 	}
-	defer outFile.Close()
+	defer func() {
+		if closeErr := outFile.Close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("zipファイルの書き込みに失敗しました: %w", closeErr)
+		}
+	}()
 
 	zipWriter := zip.NewWriter(outFile)
-	defer zipWriter.Close()
+	defer func() {
+		if closeErr := zipWriter.Close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("zipの中央ディレクトリの書き込みに失敗しました: %w", closeErr)
+		}
+	}()
+
+	if comment != "" {
+		if err := zipWriter.SetComment(comment); err != nil {
+			return fmt.Errorf("zipのコメントの設定に失敗しました: %w", err)
+		}
+	}
 
 	sort.Strings(files)
 
@@ -351,3 +597,58 @@ func createZip(outputPath string, files []string) error {
 
 	return nil
 }
+
+func init() {
+	registerOperation(splitOperation{})
+}
+
+type splitOperation struct{}
+
+func (splitOperation) Type() OperationType { return OperationSplit }
+
+func (splitOperation) OutputSpec() OutputSpec {
+	return OutputSpec{Filename: splitFilename, Kind: ResultKindZIP}
+}
+
+func (splitOperation) Prepare(ctx context.Context, s *Service, req PrepareRequest) (*JobManifest, error) {
+	file, err := req.singleFile()
+	if err != nil {
+		return nil, err
+	}
+	rangesExpr, err := SplitRangesFromForm(req.Params.Get("ranges"), req.Params.Get("mode"))
+	if err != nil {
+		return nil, err
+	}
+	return s.PrepareSplitJob(ctx, file, rangesExpr, SplitOutput(req.Params.Get("output")), req.Options)
+}
+
+func (splitOperation) Execute(ctx context.Context, s *Service, job *jobInput, progress ProgressReporter) (*Result, error) {
+	state := &splitState{
+		ws:        job.ws,
+		file:      job.files[0],
+		rangesRaw: job.manifest.Ranges,
+		output:    job.manifest.Output,
+	}
+	return s.executeSplit(ctx, state, progress)
+}
+
+func (splitOperation) Plan(s *Service, job *jobInput) (*JobPlan, error) {
+	ranges, err := parseSplitRanges(job.manifest.Ranges, job.files[0].pages)
+	if err != nil {
+		return nil, err
+	}
+	burst := burstSplit(job.manifest.Ranges)
+	plan := &JobPlan{}
+	if job.manifest.Output != SplitOutputParts {
+		plan.Outputs = append(plan.Outputs, PlannedOutput{Name: splitFilename, Kind: ResultKindZIP})
+	}
+	for i, pr := range ranges {
+		plan.Outputs = append(plan.Outputs, PlannedOutput{
+			Name:      splitPartName(i, pr, burst),
+			Kind:      ResultKindPDF,
+			Pages:     pr.count(),
+			PageOrder: expandPageRanges([]PageRange{pr}),
+		})
+	}
+	return plan, nil
+}
@@ -0,0 +1,246 @@
+package pdf
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/yourusername/paper-forge/internal/config"
+	"github.com/yourusername/paper-forge/internal/pdf/pdftest"
+)
+
+func TestCreateZipWritesReadableArchive(t *testing.T) {
+	dir := t.TempDir()
+	var paths []string
+	for _, name := range []string{"part-02.pdf", "part-01.pdf"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, pdftest.Synthetic(1, 0), 0o640); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+		paths = append(paths, path)
+	}
+
+	zipPath := filepath.Join(dir, splitFilename)
+	if err := createZip(zipPath, paths, ""); err != nil {
+		t.Fatalf("createZip returned error: %v", err)
+	}
+	r, err := zip.OpenReader(zipPath)
+	if err != nil {
+		t.Fatalf("failed to open zip: %v", err)
+	}
+	defer r.Close()
+	if len(r.File) != 2 || r.File[0].Name != "part-01.pdf" || r.File[1].Name != "part-02.pdf" {
+		t.Fatalf("unexpected zip entries: %+v", r.File)
+	}
+
+	if err := createZip(filepath.Join(dir, "missing", splitFilename), paths, ""); err == nil {
+		t.Fatal("expected error when the zip cannot be created")
+	}
+}
+
+func TestSplitZipCommentListsPartChecksums(t *testing.T) {
+	parts := []SplitPart{
+		{Filename: "part-01.pdf", FromPage: 1, ToPage: 3, SHA256: "aa"},
+		{Filename: "part-02.pdf", FromPage: 9, ToPage: 4, SHA256: "bb"},
+	}
+	want := "paper-forge split parts\npart-01.pdf pages=1-3 sha256=aa\npart-02.pdf pages=9-4 sha256=bb\n"
+	if got := splitZipComment(parts); got != want {
+		t.Fatalf("unexpected comment: %q", got)
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "part-01.pdf")
+	if err := os.WriteFile(path, pdftest.Synthetic(1, 0), 0o640); err != nil {
+		t.Fatalf("failed to write part: %v", err)
+	}
+	zipPath := filepath.Join(dir, splitFilename)
+	if err := createZip(zipPath, []string{path}, want); err != nil {
+		t.Fatalf("createZip returned error: %v", err)
+	}
+	r, err := zip.OpenReader(zipPath)
+	if err != nil {
+		t.Fatalf("failed to open zip: %v", err)
+	}
+	defer r.Close()
+	if r.Comment != want {
+		t.Fatalf("unexpected zip comment: %q", r.Comment)
+	}
+
+	many := make([]SplitPart, 1000)
+	for i := range many {
+		many[i] = SplitPart{Filename: fmt.Sprintf("part-%02d.pdf", i+1), FromPage: i + 1, ToPage: i + 1, SHA256: strings.Repeat("0", 64)}
+	}
+	if got := splitZipComment(many); len(got) > maxZipComment || strings.Contains(got, "part-01.pdf") {
+		t.Fatalf("oversized comment should fall back to a short notice: %d bytes", len(got))
+	}
+}
+
+func TestPrepareSplitJobPartsOutput(t *testing.T) {
+	svc := NewService(&config.Config{MaxFileSize: 1 << 20, MaxPages: 20})
+	svc.tmpRoot = t.TempDir()
+	headers, err := pdftest.FileHeaders("file",
+		pdftest.File{Name: "a.pdf", Data: pdftest.Synthetic(3, 0)},
+	)
+	if err != nil {
+		t.Fatalf("failed to build multipart files: %v", err)
+	}
+
+	manifest, err := svc.PrepareSplitJob(context.Background(), headers[0], "1,2-", "Parts", JobOptions{})
+	if err != nil {
+		t.Fatalf("PrepareSplitJob returned error: %v", err)
+	}
+	if manifest.Output != SplitOutputParts {
+		t.Fatalf("unexpected output: %q", manifest.Output)
+	}
+	if !shouldProcessAsync(manifest, HandlerOptions{Scheduler: &stubScheduler{}}) {
+		t.Fatal("split jobs with individual parts should be processed asynchronously")
+	}
+
+	zipped, err := svc.PrepareSplitJob(context.Background(), headers[0], "1,2-", "", JobOptions{})
+	if err != nil {
+		t.Fatalf("PrepareSplitJob returned error: %v", err)
+	}
+	if zipped.Output != "" {
+		t.Fatalf("default output should not be written to the manifest: %q", zipped.Output)
+	}
+
+	if _, err := svc.PrepareSplitJob(context.Background(), headers[0], "1", "tar", JobOptions{}); !IsError(err, "INVALID_INPUT") {
+		t.Fatalf("expected INVALID_INPUT for unknown output, got %v", err)
+	}
+}
+
+func TestParsePageRangesShorthands(t *testing.T) {
+	cases := []struct {
+		expr string
+		want []int
+	}{
+		{"1-3,5,8-", []int{1, 2, 3, 5, 8, 9, 10}},
+		{"odd", []int{1, 3, 5, 7, 9}},
+		{" EVEN ", []int{2, 4, 6, 8, 10}},
+		{"last", []int{10}},
+		{"last-3", []int{7}},
+		{"2,last-3-last", []int{2, 7, 8, 9, 10}},
+		{"4-last-5", []int{4, 5}},
+		{"last - 1 -", []int{9, 10}},
+	}
+	for _, tc := range cases {
+		ranges, err := parsePageRanges(tc.expr, 10)
+		if err != nil {
+			t.Fatalf("%q: unexpected error: %v", tc.expr, err)
+		}
+		if got := expandPageRanges(ranges); !reflect.DeepEqual(got, tc.want) {
+			t.Fatalf("%q: got pages %v, want %v", tc.expr, got, tc.want)
+		}
+	}
+}
+
+func TestParseOrderedPageRangesAllowsReverse(t *testing.T) {
+	cases := []struct {
+		expr string
+		want []PageRange
+	}{
+		{"10-1", []PageRange{{Start: 10, End: 1}}},
+		{"last-2-1", []PageRange{{Start: 8, End: 1}}},
+		{"3-1,6-4,last", []PageRange{{Start: 3, End: 1}, {Start: 6, End: 4}, {Start: 10, End: 10}}},
+	}
+	for _, tc := range cases {
+		got, err := parseOrderedPageRanges(tc.expr, 10)
+		if err != nil {
+			t.Fatalf("%q: unexpected error: %v", tc.expr, err)
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Fatalf("%q: got %+v, want %+v", tc.expr, got, tc.want)
+		}
+	}
+	if got := expandPageRanges([]PageRange{{Start: 4, End: 2}}); !reflect.DeepEqual(got, []int{4, 3, 2}) {
+		t.Fatalf("unexpected reverse expansion: %v", got)
+	}
+	if _, err := parsePageRanges("10-1", 10); !IsError(err, "INVALID_INPUT") {
+		t.Fatalf("reverse ranges must be rejected outside split/extract, got %v", err)
+	}
+}
+
+func TestParsePageRangesRejectsInvalid(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"0",
+		"11",
+		"-3",
+		"abc",
+		"3x",
+		"2-11",
+		"last-10",
+		"last-x",
+		"3-5,4",
+		"5,2",
+		"odd,2",
+		"odd,even",
+		"last,1",
+		"1-2-3",
+	} {
+		if _, err := parseOrderedPageRanges(expr, 10); !IsError(err, "INVALID_INPUT") {
+			t.Fatalf("%q: expected INVALID_INPUT, got %v", expr, err)
+		}
+	}
+	if _, err := parsePageRanges("even", 1); !IsError(err, "INVALID_INPUT") {
+		t.Fatalf("expected INVALID_INPUT for even on a single page, got %v", err)
+	}
+}
+
+func TestRunTimePageRangesUsesActualPageCount(t *testing.T) {
+	svc := NewService(&config.Config{MaxFileSize: 1 << 20, MaxPages: 20})
+	path := filepath.Join(t.TempDir(), "00.pdf")
+	if err := os.WriteFile(path, pdftest.Synthetic(3, 0), 0o600); err != nil {
+		t.Fatalf("failed to write PDF: %v", err)
+	}
+
+	// 受付時にページ数が分からなかった入力は実行時に数える
+	file := storedFile{path: path, originalName: "a.pdf"}
+	ranges, err := svc.runTimePageRanges(&file, "2-last")
+	if err != nil {
+		t.Fatalf("runTimePageRanges returned error: %v", err)
+	}
+	if file.pages != 3 || !reflect.DeepEqual(ranges, []PageRange{{Start: 2, End: 3}}) {
+		t.Fatalf("unexpected result: pages=%d ranges=%+v", file.pages, ranges)
+	}
+
+	_, err = svc.runTimePageRanges(&file, "2-5")
+	if !IsError(err, "INVALID_RANGE") {
+		t.Fatalf("expected INVALID_RANGE, got %v", err)
+	}
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || !strings.Contains(apiErr.Message, "3ページ") {
+		t.Fatalf("error message should mention the actual page count: %v", err)
+	}
+}
+
+func TestSplitRangesFromForm(t *testing.T) {
+	cases := []struct {
+		ranges, mode, want string
+		wantErr            bool
+	}{
+		{ranges: "1-3", want: "1-3"},
+		{mode: "burst", want: SplitRangesEach},
+		{ranges: "each", mode: "BURST", want: SplitRangesEach},
+		{ranges: "1-3", mode: "burst", wantErr: true},
+		{ranges: "1-3", mode: "chunks", wantErr: true},
+	}
+	for _, tc := range cases {
+		got, err := SplitRangesFromForm(tc.ranges, tc.mode)
+		if tc.wantErr {
+			if !IsError(err, "INVALID_INPUT") {
+				t.Errorf("ranges=%q mode=%q: expected INVALID_INPUT, got %v", tc.ranges, tc.mode, err)
+			}
+			continue
+		}
+		if err != nil || got != tc.want {
+			t.Errorf("ranges=%q mode=%q: got %q, %v; want %q", tc.ranges, tc.mode, got, err, tc.want)
+		}
+	}
+}
@@ -0,0 +1,399 @@
+package pdf
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gabriel-vasile/mimetype"
+	pdfapi "github.com/pdfcpu/pdfcpu/pkg/api"
+
+	"github.com/yourusername/paper-forge/internal/tenant"
+)
+
+const (
+	stampFilename = "stamped.pdf"
+	// maxStampImageSize はスタンプに使う画像の最大サイズです。ロゴやレターヘッドを想定しています。
+	maxStampImageSize = 10 << 20
+)
+
+// スタンプの指定を省略した項目の既定値です。ページ中央に、ページ幅の 1/4 の大きさで重ねます。
+const (
+	defaultStampOpacity  = 1.0
+	defaultStampRotation = 0
+	defaultStampScale    = 0.25
+	defaultStampPosition = "c"
+)
+
+// stampImageExts はスタンプに使える画像の MIME タイプと保存時の拡張子です。
+// pdfcpu は拡張子で画像の透かしかどうかを判定するため、保存名の拡張子を実際の形式に合わせます。
+var stampImageExts = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+}
+
+// StampSpec はページに重ねる画像（ロゴやレターヘッド）の指定です。
+type StampSpec struct {
+	Opacity  float64 `json:"opacity"`  // 不透明度（0 より大きく 1 以下）
+	Rotation float64 `json:"rotation"` // 回転角度（反時計回り、-180〜180）
+	Scale    float64 `json:"scale"`    // ページ幅に対する画像の幅の割合（0 より大きく 1 以下）
+	Position string  `json:"position"` // tl / tc / tr / l / c / r / bl / bc / br
+}
+
+// description は pdfcpu の透かし記述を返します。
+func (st StampSpec) description() string {
+	return fmt.Sprintf("position:%s, scalefactor:%s rel, rotation:%s, opacity:%s",
+		st.Position,
+		strconv.FormatFloat(st.Scale, 'f', -1, 64),
+		strconv.FormatFloat(st.Rotation, 'f', -1, 64),
+		strconv.FormatFloat(st.Opacity, 'f', -1, 64))
+}
+
+// validateStampSpec はスタンプの指定が範囲内であることを確認します。
+func validateStampSpec(st StampSpec) error {
+	switch {
+	case st.Opacity <= 0 || st.Opacity > 1:
+		return newError("INVALID_INPUT", "opacity は 0 より大きく 1 以下で指定してください。", nil)
+	case st.Rotation < -180 || st.Rotation > 180:
+		return newError("INVALID_INPUT", "rotation は -180〜180 の範囲で指定してください。", nil)
+	case st.Scale <= 0 || st.Scale > 1:
+		return newError("INVALID_INPUT", "scale は 0 より大きく 1 以下で指定してください。", nil)
+	}
+	for _, p := range watermarkPositions {
+		if st.Position == p {
+			return nil
+		}
+	}
+	return newError("INVALID_INPUT", fmt.Sprintf("position は %s のいずれかで指定してください。", strings.Join(watermarkPositions, ", ")), nil)
+}
+
+// parseStampSpec はフォーム項目（opacity, rotation, scale, position）からスタンプの指定を作成します。
+// 省略した項目は既定値を使います。値の範囲は validateStampSpec で確認します。
+func parseStampSpec(values url.Values) (StampSpec, error) {
+	spec := StampSpec{
+		Opacity:  defaultStampOpacity,
+		Rotation: defaultStampRotation,
+		Scale:    defaultStampScale,
+		Position: defaultStampPosition,
+	}
+	for _, f := range []struct {
+		key string
+		dst *float64
+	}{
+		{"opacity", &spec.Opacity},
+		{"rotation", &spec.Rotation},
+		{"scale", &spec.Scale},
+	} {
+		raw := strings.TrimSpace(values.Get(f.key))
+		if raw == "" {
+			continue
+		}
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return StampSpec{}, fmt.Errorf("%s は数値で指定してください。", f.key)
+		}
+		*f.dst = v
+	}
+	if raw := strings.TrimSpace(values.Get("position")); raw != "" {
+		spec.Position = strings.ToLower(raw)
+	}
+	return spec, nil
+}
+
+// StampMultipart は単一PDFの指定ページ（pages が空の場合は全ページ）に PNG/JPEG 画像を重ねます。
+func (s *Service) StampMultipart(ctx context.Context, file, image *multipart.FileHeader, spec StampSpec, pages string) (_ *Result, err error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if file == nil {
+		return nil, newError("INVALID_INPUT", "PDFファイルを選択してください。", nil)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	state, _, err := s.prepareStamp(ctx, file, image, spec, pages, JobOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = removeDir(state.ws.dir)
+		}
+	}()
+
+	result, execErr := s.executeStamp(ctx, state, nil)
+	if execErr != nil {
+		return nil, execErr
+	}
+	return result, nil
+}
+
+type stampState struct {
+	ws    workspace
+	file  storedFile
+	image storedFile
+	spec  StampSpec
+	// pagesRaw はスタンプを重ねるページ範囲指定です。空の場合は全ページに重ねます。
+	pagesRaw string
+}
+
+func (s *Service) prepareStamp(ctx context.Context, file, image *multipart.FileHeader, spec StampSpec, pages string, opts JobOptions) (*stampState, *JobManifest, error) {
+	if image == nil {
+		return nil, nil, newError("INVALID_INPUT", "スタンプに使う画像（PNG または JPEG）を選択してください。", nil)
+	}
+	if err := validateStampSpec(spec); err != nil {
+		return nil, nil, err
+	}
+
+	release, err := s.reserveWorkspace(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer release()
+
+	ws, err := s.createWorkspace()
+	if err != nil {
+		return nil, nil, err
+	}
+	stored, err := s.storeMultipartFile(ctx, file, ws.inDir, 0, false)
+	if err != nil {
+		_ = removeDir(ws.dir)
+		return nil, nil, err
+	}
+	storedImage, err := storeStampImage(ctx, image, ws.inDir, 1)
+	if err != nil {
+		_ = removeDir(ws.dir)
+		return nil, nil, err
+	}
+
+	pages = strings.TrimSpace(pages)
+	if pages != "" {
+		if _, err := parsePageRanges(pages, stored.pages); err != nil {
+			_ = removeDir(ws.dir)
+			return nil, nil, err
+		}
+	}
+
+	// 画像も入力ファイルとしてマニフェストに記録し、暗号化・入力の保持・キャッシュのキーを PDF と同じく扱う
+	manifest := &JobManifest{
+		JobID:     ws.jobID,
+		Tenant:    tenant.FromContext(ctx),
+		Operation: OperationStamp,
+		Files:     toJobFiles([]storedFile{stored, storedImage}),
+		Ranges:    pages,
+		Stamp:     &spec,
+		Options:   opts,
+		StoreMs:   ws.elapsed().Milliseconds(),
+		CreatedAt: s.now().UTC(),
+	}
+	if err := writeManifest(s.fs, ws.dir, manifest); err != nil {
+		_ = removeDir(ws.dir)
+		return nil, nil, fmt.Errorf("ジョブマニフェストの保存に失敗しました: %w", err)
+	}
+
+	return &stampState{ws: ws, file: stored, image: storedImage, spec: spec, pagesRaw: pages}, manifest, nil
+}
+
+// storeStampImage はスタンプに使う画像を dir に保存します。
+// 先頭部分を mimetype で判定し、PNG と JPEG 以外は受け付けません。画像のページ数は 0 として扱います。
+func storeStampImage(ctx context.Context, fh *multipart.FileHeader, dir string, index int) (storedFile, error) {
+	if fh.Size > maxStampImageSize {
+		return storedFile{}, newError("LIMIT_EXCEEDED", fmt.Sprintf("%s のサイズが上限(%dMB)を超えています。", fh.Filename, maxStampImageSize/(1024*1024)), nil)
+	}
+
+	src, err := fh.Open()
+	if err != nil {
+		return storedFile{}, fmt.Errorf("ファイルを開けませんでした(%s): %w", fh.Filename, err)
+	}
+	defer src.Close()
+
+	head := make([]byte, 3072)
+	n, err := io.ReadFull(src, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return storedFile{}, fmt.Errorf("ファイルの読み取りに失敗しました(%s): %w", fh.Filename, err)
+	}
+	head = head[:n]
+	ext, ok := stampImageExts[mimetype.Detect(head).String()]
+	if n == 0 || !ok {
+		return storedFile{}, newError("INVALID_INPUT", fmt.Sprintf("%s はPNGまたはJPEG形式の画像ではありません。", fh.Filename), nil)
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("%02d%s", index, ext))
+	dst, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o640)
+	if err != nil {
+		return storedFile{}, fmt.Errorf("一時ファイルを作成できませんでした: %w", err)
+	}
+	hasher := sha256.New()
+	// 上限を1バイト超えて読み、申告サイズと異なる大きな画像も拒否する
+	written, err := io.Copy(io.MultiWriter(dst, hasher), io.LimitReader(io.MultiReader(bytes.NewReader(head), contextReader{ctx: ctx, r: src}), maxStampImageSize+1))
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(path)
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return storedFile{}, ctxErr
+		}
+		return storedFile{}, fmt.Errorf("画像の保存に失敗しました(%s): %w", fh.Filename, err)
+	}
+	if written > maxStampImageSize {
+		_ = os.Remove(path)
+		return storedFile{}, newError("LIMIT_EXCEEDED", fmt.Sprintf("%s のサイズが上限(%dMB)を超えています。", fh.Filename, maxStampImageSize/(1024*1024)), nil)
+	}
+
+	return storedFile{
+		path:         path,
+		originalName: safeOriginalName(fh.Filename, index),
+		size:         written,
+		sha256:       hex.EncodeToString(hasher.Sum(nil)),
+	}, nil
+}
+
+func (s *Service) executeStamp(ctx context.Context, state *stampState, progress ProgressReporter) (*Result, error) {
+	ws := state.ws
+	stored := state.file
+
+	var (
+		ranges        []PageRange
+		selectedPages []string
+	)
+	if state.pagesRaw != "" {
+		parsed, err := parsePageRanges(state.pagesRaw, stored.pages)
+		if err != nil {
+			return nil, err
+		}
+		ranges = parsed
+		for _, pr := range ranges {
+			selectedPages = append(selectedPages, buildPageSelection(pr)...)
+		}
+	}
+
+	reportProgress(progress, StageProcess, 0)
+	outputPath := filepath.Join(ws.outDir, stampFilename)
+	spec := state.spec
+	if err := runStep(ctx, func() error {
+		return pdfapi.AddImageWatermarksFile(stored.path, outputPath, selectedPages, true, state.image.path, spec.description(), nil)
+	}); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		return nil, newError("UNSUPPORTED_PDF", "PDFへの画像の追加に失敗しました。PDFと画像が破損していないか確認してください。", err)
+	}
+	reportProgress(progress, StageWrite, 0)
+
+	sourceMeta := SourceFileMeta{
+		Name:  stored.originalName,
+		Size:  stored.size,
+		Pages: stored.pages,
+	}
+	imageMeta := SourceFileMeta{
+		Name: state.image.originalName,
+		Size: state.image.size,
+	}
+
+	meta := struct {
+		Type      OperationType  `json:"type"`
+		CreatedAt string         `json:"createdAt"`
+		Source    SourceFileMeta `json:"source"`
+		Image     SourceFileMeta `json:"image"`
+		Stamp     StampSpec      `json:"stamp"`
+		Pages     []PageRange    `json:"pages,omitempty"`
+		Output    string         `json:"output"`
+	}{
+		Type:      OperationStamp,
+		CreatedAt: s.now().UTC().Format(time.RFC3339),
+		Source:    sourceMeta,
+		Image:     imageMeta,
+		Stamp:     spec,
+		Pages:     ranges,
+		Output:    stampFilename,
+	}
+
+	metaPath := filepath.Join(ws.dir, "meta.json")
+	if err := writeJSON(s.fs, metaPath, meta); err != nil {
+		return nil, fmt.Errorf("メタデータの保存に失敗しました: %w", err)
+	}
+
+	s.scheduleCleanup(ws.dir)
+
+	artifact, err := newArtifact(outputPath, ResultKindPDF)
+	if err != nil {
+		return nil, fmt.Errorf("成果物の確認に失敗しました: %w", err)
+	}
+
+	reportProgress(progress, StageCompleted, 1)
+
+	return newResult(ws, OperationStamp, s.fs, []Artifact{artifact}, &StampMeta{
+		Original: sourceMeta,
+		Image:    imageMeta,
+		Stamp:    spec,
+		Pages:    ranges,
+	}), nil
+}
+
+// PrepareStampJob は非同期ジョブ用に入力を保存します。
+func (s *Service) PrepareStampJob(ctx context.Context, file, image *multipart.FileHeader, spec StampSpec, pages string, opts JobOptions) (*JobManifest, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	_, manifest, err := s.prepareStamp(ctx, file, image, spec, pages, opts)
+	if err != nil {
+		return nil, err
+	}
+	return s.sealJob(manifest)
+}
+
+func init() {
+	registerOperation(stampOperation{})
+}
+
+type stampOperation struct{}
+
+func (stampOperation) Type() OperationType { return OperationStamp }
+
+func (stampOperation) OutputSpec() OutputSpec {
+	return OutputSpec{Filename: stampFilename, Kind: ResultKindPDF}
+}
+
+// Prepare は Files の1件目を PDF、2件目をスタンプの画像として受け取ります。
+func (stampOperation) Prepare(ctx context.Context, s *Service, req PrepareRequest) (*JobManifest, error) {
+	if len(req.Files) != 2 {
+		return nil, newError("INVALID_INPUT", "PDFファイルとスタンプに使う画像を1つずつ指定してください。", nil)
+	}
+	spec, err := parseStampSpec(req.Params)
+	if err != nil {
+		return nil, newError("INVALID_INPUT", err.Error(), nil)
+	}
+	return s.PrepareStampJob(ctx, req.Files[0], req.Files[1], spec, req.Params.Get("pages"), req.Options)
+}
+
+func (stampOperation) Execute(ctx context.Context, s *Service, job *jobInput, progress ProgressReporter) (*Result, error) {
+	if job.manifest.Stamp == nil || len(job.files) != 2 {
+		return nil, newError("INVALID_INPUT", "スタンプの指定がありません。", nil)
+	}
+	state := &stampState{
+		ws:       job.ws,
+		file:     job.files[0],
+		image:    job.files[1],
+		spec:     *job.manifest.Stamp,
+		pagesRaw: job.manifest.Ranges,
+	}
+	return s.executeStamp(ctx, state, progress)
+}
+
+func (stampOperation) Plan(s *Service, job *jobInput) (*JobPlan, error) {
+	return singleOutputPlan(stampFilename, job.files[0], job.manifest.Ranges)
+}
@@ -0,0 +1,88 @@
+package pdf
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/yourusername/paper-forge/internal/config"
+	"github.com/yourusername/paper-forge/internal/pdf/pdftest"
+)
+
+// testPNG はスタンプの画像として使う 1x1 の PNG です。
+var testPNG = func() []byte {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 1, 1))); err != nil {
+		panic(err)
+	}
+	return buf.Bytes()
+}()
+
+func TestParseStampSpec(t *testing.T) {
+	spec, err := parseStampSpec(url.Values{"scale": {"0.5"}, "position": {"TR"}})
+	if err != nil {
+		t.Fatalf("parseStampSpec returned error: %v", err)
+	}
+	want := StampSpec{Opacity: defaultStampOpacity, Rotation: defaultStampRotation, Scale: 0.5, Position: "tr"}
+	if spec != want {
+		t.Fatalf("unexpected spec: %+v", spec)
+	}
+	if got := spec.description(); got != "position:tr, scalefactor:0.5 rel, rotation:0, opacity:1" {
+		t.Fatalf("unexpected description: %s", got)
+	}
+
+	if _, err := parseStampSpec(url.Values{"opacity": {"half"}}); err == nil {
+		t.Fatal("expected error for non-numeric opacity")
+	}
+	for _, invalid := range []StampSpec{
+		{Opacity: 1, Scale: 0, Position: "c"},
+		{Opacity: 1, Scale: 1.5, Position: "c"},
+		{Opacity: 0, Scale: 0.5, Position: "c"},
+		{Opacity: 1, Scale: 0.5, Position: "center"},
+	} {
+		if err := validateStampSpec(invalid); !IsError(err, "INVALID_INPUT") {
+			t.Fatalf("%+v: expected INVALID_INPUT, got %v", invalid, err)
+		}
+	}
+}
+
+func TestPrepareStampJobStoresImage(t *testing.T) {
+	svc := NewService(&config.Config{MaxFileSize: 1 << 20, MaxPages: 20})
+	svc.tmpRoot = t.TempDir()
+	pdfs, err := pdftest.FileHeaders("file", pdftest.File{Name: "a.pdf", Data: pdftest.Synthetic(3, 0)})
+	if err != nil {
+		t.Fatalf("failed to build multipart files: %v", err)
+	}
+	images, err := pdftest.FileHeaders("image",
+		pdftest.File{Name: "logo.png", Data: testPNG},
+		pdftest.File{Name: "logo.gif", Data: []byte("GIF89a\x01\x00\x01\x00\x00\x00\x00;")},
+	)
+	if err != nil {
+		t.Fatalf("failed to build multipart files: %v", err)
+	}
+
+	spec := StampSpec{Opacity: 0.8, Rotation: 0, Scale: 0.2, Position: "tr"}
+	manifest, err := svc.PrepareStampJob(context.Background(), pdfs[0], images[0], spec, "1", JobOptions{})
+	if err != nil {
+		t.Fatalf("PrepareStampJob returned error: %v", err)
+	}
+	saved, err := loadManifest(svc.fs, svc.workspaceFor(manifest.JobID).dir)
+	if err != nil {
+		t.Fatalf("failed to load manifest: %v", err)
+	}
+	if saved.Operation != OperationStamp || saved.Stamp == nil || *saved.Stamp != spec || len(saved.Files) != 2 {
+		t.Fatalf("unexpected manifest: %+v", saved)
+	}
+	if img := saved.Files[1]; img.StoredName != "01.png" || img.OriginalName != "logo.png" || img.Pages != 0 || img.SHA256 == "" {
+		t.Fatalf("unexpected image entry: %+v", img)
+	}
+
+	_, err = svc.PrepareStampJob(context.Background(), pdfs[0], images[1], spec, "", JobOptions{})
+	if !IsError(err, "INVALID_INPUT") || !strings.Contains(err.Error(), "logo.gif") {
+		t.Fatalf("expected INVALID_INPUT for a GIF image, got %v", err)
+	}
+}
@@ -0,0 +1,186 @@
+package pdf
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"fmt"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"strings"
+
+	pdfapi "github.com/pdfcpu/pdfcpu/pkg/api"
+)
+
+const (
+	// DefaultThumbnailWidth はサムネイルの幅（ピクセル）の既定値です。
+	DefaultThumbnailWidth = 200
+	// minThumbnailWidth / maxThumbnailWidth は指定できるサムネイルの幅の範囲です。
+	minThumbnailWidth = 32
+	maxThumbnailWidth = 1000
+	// maxThumbnailPages は1回のリクエストで作成できるサムネイルの最大枚数です。
+	// 同期処理で画像をメモリ上に持つため、多いページは pages で分けて要求してもらいます。
+	maxThumbnailPages = 100
+	// thumbnailsFilename は ZIP で返す場合のファイル名です。
+	thumbnailsFilename = "thumbnails.zip"
+)
+
+// ThumbnailResult は選択したページのサムネイル（PNG）です。
+type ThumbnailResult struct {
+	Source     SourceFileMeta `json:"source"`
+	Thumbnails []Thumbnail    `json:"thumbnails"`
+}
+
+// Thumbnail はページ1つ分のサムネイルです。Data は JSON では base64 になります。
+// Width / Height は回転（/Rotate）を反映した表示上の向きでの画像の大きさ（ピクセル）です。
+type Thumbnail struct {
+	Page   int    `json:"page"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	Data   []byte `json:"data"`
+}
+
+// thumbnailBatch は1回の Ghostscript の実行でまとめて描画するページの範囲と解像度です。
+type thumbnailBatch struct {
+	first, last int
+	dpi         float64
+}
+
+// RenderThumbnails は単一PDFファイルの pagesExpr のページ（空の場合は全ページ）を幅 width ピクセルの PNG に変換します。
+// width が 0 の場合は DefaultThumbnailWidth です。
+func (s *Service) RenderThumbnails(ctx context.Context, file *multipart.FileHeader, pagesExpr string, width int) (*ThumbnailResult, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if file == nil {
+		return nil, newError("INVALID_INPUT", "PDFファイルを選択してください。", nil)
+	}
+	if width == 0 {
+		width = DefaultThumbnailWidth
+	}
+	if width < minThumbnailWidth || width > maxThumbnailWidth {
+		return nil, newError("INVALID_INPUT", fmt.Sprintf("width は%d〜%dの範囲で指定してください。", minThumbnailWidth, maxThumbnailWidth), nil)
+	}
+
+	ws, err := s.createWorkspace()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = removeDir(ws.dir)
+	}()
+
+	stored, err := s.storeMultipartFile(ctx, file, ws.inDir, 0, false)
+	if err != nil {
+		return nil, err
+	}
+	pdfCtx, err := pdfapi.ReadContextFile(stored.path)
+	if err != nil {
+		return nil, newError("DAMAGED_PDF", fmt.Sprintf("%s を読み込めませんでした。", stored.originalName), err)
+	}
+	pageInfo, err := readPageInfo(pdfCtx)
+	if err != nil {
+		return nil, newError("DAMAGED_PDF", fmt.Sprintf("%s のページの大きさを読み込めませんでした。", stored.originalName), err)
+	}
+
+	selected := pageInfo
+	if expr := strings.TrimSpace(pagesExpr); expr != "" {
+		ranges, err := parsePageRanges(expr, len(pageInfo))
+		if err != nil {
+			return nil, err
+		}
+		selected = selected[:0:0]
+		for _, page := range expandPageRanges(ranges) {
+			selected = append(selected, pageInfo[page-1])
+		}
+	}
+	if len(selected) > maxThumbnailPages {
+		return nil, newError("LIMIT_EXCEEDED", fmt.Sprintf("一度に作成できるサムネイルは最大%dページです。pages でページを分けて指定してください。", maxThumbnailPages), nil)
+	}
+	batches, err := thumbnailBatches(selected, width)
+	if err != nil {
+		return nil, newError("DAMAGED_PDF", fmt.Sprintf("%s のページの大きさが正しくありません。", stored.originalName), err)
+	}
+
+	result := &ThumbnailResult{
+		Source: SourceFileMeta{
+			Name:  stored.originalName,
+			Size:  stored.size,
+			Pages: stored.pages,
+		},
+		Thumbnails: make([]Thumbnail, 0, len(selected)),
+	}
+	r := s.renderer()
+	for _, batch := range batches {
+		pattern := filepath.Join(ws.outDir, fmt.Sprintf("p%04d-%%03d.png", batch.first))
+		if err := r.renderPNG(ctx, stored.path, pattern, batch.first, batch.last, batch.dpi); err != nil {
+			return nil, err
+		}
+		for page := batch.first; page <= batch.last; page++ {
+			thumb, err := readThumbnail(filepath.Join(ws.outDir, fmt.Sprintf("p%04d-%03d.png", batch.first, page-batch.first+1)), page)
+			if err != nil {
+				return nil, err
+			}
+			result.Thumbnails = append(result.Thumbnails, thumb)
+		}
+	}
+	return result, nil
+}
+
+// thumbnailBatches は選択したページ（昇順）を、番号が連続し表示上の幅が同じページごとにまとめます。
+// 幅が同じページは同じ解像度で描画できるため、Ghostscript の起動回数を減らせます。
+func thumbnailBatches(pages []PageInfo, width int) ([]thumbnailBatch, error) {
+	var batches []thumbnailBatch
+	for _, page := range pages {
+		displayWidth := page.Width
+		if page.Rotation == 90 || page.Rotation == 270 {
+			displayWidth = page.Height
+		}
+		if displayWidth <= 0 {
+			return nil, fmt.Errorf("page %d has no width", page.Number)
+		}
+		dpi := float64(width) * 72 / displayWidth
+		if n := len(batches); n > 0 && batches[n-1].last == page.Number-1 && batches[n-1].dpi == dpi {
+			batches[n-1].last = page.Number
+			continue
+		}
+		batches = append(batches, thumbnailBatch{first: page.Number, last: page.Number, dpi: dpi})
+	}
+	return batches, nil
+}
+
+// readThumbnail は描画した PNG を読み込み、画像の大きさを添えて返します。
+func readThumbnail(path string, page int) (Thumbnail, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Thumbnail{}, fmt.Errorf("%dページ目の画像を読み込めませんでした: %w", page, err)
+	}
+	cfg, err := png.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return Thumbnail{}, fmt.Errorf("%dページ目の画像が正しくありません: %w", page, err)
+	}
+	return Thumbnail{Page: page, Width: cfg.Width, Height: cfg.Height, Data: data}, nil
+}
+
+// WriteZIP はサムネイルを page-<ページ番号>.png として ZIP に書き出します。
+func (r *ThumbnailResult) WriteZIP(w io.Writer) error {
+	zw := zip.NewWriter(w)
+	for _, thumb := range r.Thumbnails {
+		// PNG は圧縮済みのため、展開の手間を省いて無圧縮で格納する
+		entry, err := zw.CreateHeader(&zip.FileHeader{Name: thumbnailFilename(thumb.Page), Method: zip.Store})
+		if err != nil {
+			return err
+		}
+		if _, err := entry.Write(thumb.Data); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+func thumbnailFilename(page int) string {
+	return fmt.Sprintf("page-%03d.png", page)
+}
@@ -0,0 +1,128 @@
+package pdf
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/yourusername/paper-forge/internal/config"
+	"github.com/yourusername/paper-forge/internal/execrun"
+	"github.com/yourusername/paper-forge/internal/pdf/pdftest"
+)
+
+// pngRunner は Ghostscript の代わりに、指定されたページ数分の空の PNG を -sOutputFile の書式で書き出します。
+type pngRunner struct {
+	calls []execrun.Command
+}
+
+func (r *pngRunner) Run(ctx context.Context, cmd execrun.Command) (execrun.Output, error) {
+	r.calls = append(r.calls, cmd)
+	var first, last int
+	var pattern string
+	for _, arg := range cmd.Args {
+		switch {
+		case strings.HasPrefix(arg, "-dFirstPage="):
+			fmt.Sscanf(arg, "-dFirstPage=%d", &first)
+		case strings.HasPrefix(arg, "-dLastPage="):
+			fmt.Sscanf(arg, "-dLastPage=%d", &last)
+		case strings.HasPrefix(arg, "-sOutputFile="):
+			pattern = strings.TrimPrefix(arg, "-sOutputFile=")
+		}
+	}
+	for i := 1; i <= last-first+1; i++ {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 200, 283))); err != nil {
+			return execrun.Output{}, err
+		}
+		if err := os.WriteFile(fmt.Sprintf(pattern, i), buf.Bytes(), 0o640); err != nil {
+			return execrun.Output{}, err
+		}
+	}
+	return execrun.Output{}, nil
+}
+
+func TestRenderThumbnailsRendersSelectedPages(t *testing.T) {
+	runner := &pngRunner{}
+	svc := newGhostscriptTestService(runner)
+	svc.cfg = &config.Config{MaxFileSize: 1 << 20, MaxPages: 20}
+	svc.tmpRoot = t.TempDir()
+	headers, err := pdftest.FileHeaders("file", pdftest.File{Name: "a.pdf", Data: pdftest.Synthetic(4, 0)})
+	if err != nil {
+		t.Fatalf("failed to build multipart files: %v", err)
+	}
+
+	result, err := svc.RenderThumbnails(context.Background(), headers[0], "1,3-4", 0)
+	if err != nil {
+		t.Fatalf("RenderThumbnails returned error: %v", err)
+	}
+	var pages []int
+	for _, thumb := range result.Thumbnails {
+		if thumb.Width != 200 || thumb.Height != 283 || len(thumb.Data) == 0 {
+			t.Fatalf("unexpected thumbnail: page=%d %dx%d", thumb.Page, thumb.Width, thumb.Height)
+		}
+		pages = append(pages, thumb.Page)
+	}
+	if !reflect.DeepEqual(pages, []int{1, 3, 4}) || result.Source.Pages != 4 {
+		t.Fatalf("unexpected result: pages=%v source=%+v", pages, result.Source)
+	}
+	// 連続したページ（3-4）は1回の実行で描画する。A4 の幅 595pt を 200px にする解像度
+	if len(runner.calls) != 2 || !strings.Contains(strings.Join(runner.calls[1].Args, " "), "-dFirstPage=3 -dLastPage=4 -r24.2017") {
+		t.Fatalf("unexpected Ghostscript calls: %+v", runner.calls)
+	}
+
+	for _, width := range []int{-1, 10, 2000} {
+		if _, err := svc.RenderThumbnails(context.Background(), headers[0], "", width); !IsError(err, "INVALID_INPUT") {
+			t.Fatalf("width %d: expected INVALID_INPUT, got %v", width, err)
+		}
+	}
+	if _, err := svc.RenderThumbnails(context.Background(), headers[0], "5", 0); !IsError(err, "INVALID_INPUT") {
+		t.Fatalf("expected INVALID_INPUT for pages out of range, got %v", err)
+	}
+}
+
+func TestThumbnailBatchesGroupsContiguousPagesOfSameWidth(t *testing.T) {
+	pages := []PageInfo{
+		{Number: 1, Width: 600, Height: 800},
+		{Number: 2, Width: 600, Height: 800},
+		{Number: 3, Width: 800, Height: 600, Rotation: 90},
+		{Number: 4, Width: 800, Height: 600},
+		{Number: 6, Width: 800, Height: 600},
+	}
+	got, err := thumbnailBatches(pages, 300)
+	if err != nil {
+		t.Fatalf("thumbnailBatches returned error: %v", err)
+	}
+	want := []thumbnailBatch{
+		{first: 1, last: 3, dpi: 36},
+		{first: 4, last: 4, dpi: 27},
+		{first: 6, last: 6, dpi: 27},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+	if _, err := thumbnailBatches([]PageInfo{{Number: 1}}, 200); err == nil {
+		t.Fatal("expected error for a page without width")
+	}
+}
+
+func TestThumbnailResultWriteZIP(t *testing.T) {
+	result := &ThumbnailResult{Thumbnails: []Thumbnail{{Page: 2, Data: []byte("a")}, {Page: 10, Data: []byte("b")}}}
+	var buf bytes.Buffer
+	if err := result.WriteZIP(&buf); err != nil {
+		t.Fatalf("WriteZIP returned error: %v", err)
+	}
+	r, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("failed to read zip: %v", err)
+	}
+	if len(r.File) != 2 || r.File[0].Name != "page-002.png" || r.File[1].Name != "page-010.png" {
+		t.Fatalf("unexpected zip entries: %+v", r.File)
+	}
+}
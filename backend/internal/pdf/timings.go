@@ -0,0 +1,66 @@
+package pdf
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// JobTimings はジョブの段階ごとの所要時間（ミリ秒）です。遅いジョブがどこで時間を使ったかを、
+// ログを調べずにジョブ情報の meta.timings で確認できるようにします。
+type JobTimings struct {
+	// StoreMs は受付時に入力を保存するのにかかった時間です（ページ数の確認・パスワードの復号を含む）。
+	StoreMs int64 `json:"storeMs"`
+	// LoadMs は実行前に入力を読み込むのにかかった時間です（暗号化した入力の復号、DEFER_PAGE_COUNT のページ数の確認）。
+	LoadMs int64 `json:"loadMs"`
+	// ProcessMs は操作そのもの（結合・圧縮など）にかかった時間です。
+	ProcessMs int64 `json:"processMs"`
+	// WriteMs は後処理（透かし・処理記録・受領書・PDF のバージョン・暗号化）と成果物の書き出しにかかった時間です。
+	WriteMs int64 `json:"writeMs"`
+	// SubprocessCPUMs はこのジョブで実行した外部コマンド（Ghostscript など）の CPU 時間の合計です。
+	SubprocessCPUMs int64 `json:"subprocessCpuMs"`
+}
+
+// stopwatch は段階の開始時刻を保持し、経過時間をミリ秒で返します。
+type stopwatch struct {
+	start time.Time
+}
+
+func startStopwatch() *stopwatch {
+	return &stopwatch{start: time.Now()}
+}
+
+// lap は前回の lap（または開始）からの経過時間をミリ秒で返し、次の段階の計測を始めます。
+func (w *stopwatch) lap() int64 {
+	now := time.Now()
+	d := now.Sub(w.start)
+	w.start = now
+	return d.Milliseconds()
+}
+
+// JobMeta はジョブ情報の meta として保存する値を返します。操作ごとのメタデータに timings を加えます。
+// Meta の型で処理を分ける呼び出し元（通知など）があるため、Meta 自体は変更しません。
+func (r *Result) JobMeta() any {
+	if r == nil {
+		return nil
+	}
+	if r.Timings == nil {
+		return r.Meta
+	}
+	fields := map[string]json.RawMessage{}
+	if r.Meta != nil {
+		data, err := json.Marshal(r.Meta)
+		if err != nil {
+			return r.Meta
+		}
+		// 操作のメタデータがオブジェクトでない場合は timings を加えずにそのまま返す
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return r.Meta
+		}
+	}
+	timings, err := json.Marshal(r.Timings)
+	if err != nil {
+		return r.Meta
+	}
+	fields["timings"] = timings
+	return fields
+}
@@ -0,0 +1,78 @@
+package pdf
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/yourusername/paper-forge/internal/config"
+	"github.com/yourusername/paper-forge/internal/execrun"
+	"github.com/yourusername/paper-forge/internal/pdf/pdftest"
+)
+
+func TestResultJobMetaAddsTimings(t *testing.T) {
+	result := &Result{
+		Meta:    &ExtractMeta{Pages: 2},
+		Timings: &JobTimings{StoreMs: 5, ProcessMs: 120},
+	}
+	data, err := json.Marshal(result.JobMeta())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got struct {
+		Pages   int        `json:"pages"`
+		Timings JobTimings `json:"timings"`
+	}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Pages != 2 || got.Timings.StoreMs != 5 || got.Timings.ProcessMs != 120 {
+		t.Fatalf("operation meta and timings should be merged: %s", data)
+	}
+	if _, ok := result.Meta.(*ExtractMeta); !ok {
+		t.Fatal("Meta itself must keep its type")
+	}
+
+	untimed := &Result{Meta: &ExtractMeta{}}
+	if _, ok := untimed.JobMeta().(*ExtractMeta); !ok {
+		t.Fatal("results without timings should return Meta unchanged")
+	}
+}
+
+// cpuReportingRunner は outputWritingRunner と同じく出力を書き込み、外部コマンドが cpu だけ CPU を使ったことにします。
+type cpuReportingRunner struct {
+	outputWritingRunner
+	cpu time.Duration
+}
+
+func (r *cpuReportingRunner) Run(ctx context.Context, cmd execrun.Command) (execrun.Output, error) {
+	execrun.AddCPU(ctx, r.cpu)
+	out, err := r.outputWritingRunner.Run(ctx, cmd)
+	out.CPU = r.cpu
+	return out, err
+}
+
+func TestRunJobRecordsTimings(t *testing.T) {
+	svc := NewService(&config.Config{MaxFileSize: 1 << 20, MaxPages: 20, JobExpireMinutes: 1})
+	svc.tmpRoot = t.TempDir()
+	svc.gs.once.Do(func() { svc.gs.path = "/usr/bin/gs" })
+	svc.runner = &cpuReportingRunner{outputWritingRunner: outputWritingRunner{output: []byte("%PDF-1.4\n% optimized\n")}, cpu: 250 * time.Millisecond}
+	headers, err := pdftest.FileHeaders("file", pdftest.File{Name: "a.pdf", Data: pdftest.Synthetic(3, 0)})
+	if err != nil {
+		t.Fatalf("failed to build multipart files: %v", err)
+	}
+	manifest, err := svc.PrepareOptimizeJob(context.Background(), headers[0], OptimizePresetStandard, "", "", JobOptions{})
+	if err != nil {
+		t.Fatalf("PrepareOptimizeJob returned error: %v", err)
+	}
+
+	result, err := svc.RunJob(context.Background(), manifest.JobID, nil)
+	if err != nil {
+		t.Fatalf("RunJob returned error: %v", err)
+	}
+	defer result.Cleanup()
+	if result.Timings == nil || result.Timings.SubprocessCPUMs != 250 || result.Timings.StoreMs != manifest.StoreMs {
+		t.Fatalf("unexpected timings: %+v", result.Timings)
+	}
+}
@@ -0,0 +1,159 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+
+	pdfapi "github.com/pdfcpu/pdfcpu/pkg/api"
+)
+
+const (
+	// tocFilename は結合前に目次のページを書き出すファイル名です（ワークスペース直下）。
+	tocFilename = "toc.pdf"
+	// tocPages は目次のページ数です。結合できるファイルは maxUploadFiles 件までのため、1ページに収まります。
+	tocPages = 1
+
+	tocMarginLeft  = 60
+	tocMarginRight = 535
+	tocFontSize    = 11
+	tocLeading     = 24
+	// tocMaxTitleWidth は題名に使える幅（ポイント）です。ページ番号と点線のリーダーの分を空けます。
+	tocMaxTitleWidth = tocMarginRight - tocMarginLeft - 60
+)
+
+// parseTOC は merge の toc 項目を解析します。
+func parseTOC(raw string) (bool, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return false, nil
+	}
+	toc, err := strconv.ParseBool(raw)
+	if err != nil {
+		return false, newError("INVALID_INPUT", "toc には true または false を指定してください。", nil)
+	}
+	return toc, nil
+}
+
+// tocEntry は目次の1行（結合する1ファイル）です。Page は結合結果での開始ページ（1-based）です。
+type tocEntry struct {
+	Title string
+	Page  int
+}
+
+// tocEntries は結合順の入力から目次の行を作ります。開始ページは目次のページの後から数えます。
+func tocEntries(ordered []storedFile) []tocEntry {
+	entries := make([]tocEntry, 0, len(ordered))
+	page := tocPages + 1
+	for i, sf := range ordered {
+		entries = append(entries, tocEntry{Title: documentTitle(sf, i), Page: page})
+		page += sf.pages
+	}
+	return entries
+}
+
+// documentTitle は入力の文書情報の題名（/Title）を返します。無い場合は拡張子を除いたファイル名にします。
+// 題名の読み込みに失敗しても結合は続けられるため、エラーにはしません。
+func documentTitle(sf storedFile, index int) string {
+	var title string
+	if pdfCtx, err := pdfapi.ReadContextFile(sf.path); err == nil {
+		title = pdfCtx.Title
+	}
+	if title = cleanTOCTitle(title); title == "" {
+		name := filepath.Base(sf.originalName)
+		title = cleanTOCTitle(strings.TrimSuffix(name, filepath.Ext(name)))
+	}
+	if title == "" {
+		title = fmt.Sprintf("Document %d", index+1)
+	}
+	return title
+}
+
+// cleanTOCTitle は改行などの制御文字を空白にし、連続する空白を1つにまとめます。
+func cleanTOCTitle(s string) string {
+	return strings.Join(strings.FieldsFunc(s, func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsControl(r)
+	}), " ")
+}
+
+// tocTextWidth は CID フォントでの s の幅（em）の目安です。半角の文字は 0.5em、それ以外は 1em とします。
+func tocTextWidth(s string) float64 {
+	var w float64
+	for _, r := range s {
+		if (r >= 0x20 && r <= 0x7e) || (r >= 0xff61 && r <= 0xff9f) {
+			w += 0.5
+		} else {
+			w++
+		}
+	}
+	return w
+}
+
+// truncateTOCTitle は title を幅 maxEm に収めます。収まらない場合は末尾を "..." にします。
+func truncateTOCTitle(title string, maxEm float64) string {
+	if tocTextWidth(title) <= maxEm {
+		return title
+	}
+	runes := []rune(title)
+	for len(runes) > 0 && tocTextWidth(string(runes))+1.5 > maxEm {
+		runes = runes[:len(runes)-1]
+	}
+	return strings.TrimSpace(string(runes)) + "..."
+}
+
+// cidHexString は s を UniJIS-UTF16-H で使う UTF-16BE の16進文字列にします。
+func cidHexString(s string) string {
+	var b strings.Builder
+	b.WriteByte('<')
+	for _, u := range utf16.Encode([]rune(s)) {
+		fmt.Fprintf(&b, "%04X", u)
+	}
+	b.WriteByte('>')
+	return b.String()
+}
+
+// writeTOCPDF は A4 縦1ページの目次を書き出します。
+// 題名は日本語を含むことが多いため、Adobe-Japan1 の CID フォント（HeiseiMin-W3）を埋め込まずに参照し、
+// 表示側の日本語フォントで描画させます。ページ番号と点線は標準14フォントの Helvetica で書きます。
+func writeTOCPDF(path string, entries []tocEntry) error {
+	const (
+		// Helvetica の数字と "." の幅（em）
+		helveticaDigitWidth = 0.556
+		helveticaDotWidth   = 0.278
+	)
+	var content bytes.Buffer
+	fmt.Fprintf(&content, "BT /F1 18 Tf %d 780 Td %s Tj ET\n", tocMarginLeft, cidHexString("目次"))
+	y := 740
+	for _, e := range entries {
+		title := truncateTOCTitle(e.Title, float64(tocMaxTitleWidth)/tocFontSize)
+		number := strconv.Itoa(e.Page)
+		numberX := tocMarginRight - float64(len(number))*helveticaDigitWidth*tocFontSize
+		fmt.Fprintf(&content, "BT /F1 %d Tf %d %d Td %s Tj ET\n", tocFontSize, tocMarginLeft, y, cidHexString(title))
+		fmt.Fprintf(&content, "BT /F2 %d Tf %.2f %d Td (%s) Tj ET\n", tocFontSize, numberX, y, number)
+
+		// 題名の後からページ番号の前までを点線でつなぐ
+		dotsStart := tocMarginLeft + tocTextWidth(title)*tocFontSize + 6
+		if dots := int((numberX - 6 - dotsStart) / (helveticaDotWidth * tocFontSize)); dots > 0 {
+			fmt.Fprintf(&content, "q 0.5 g BT /F2 %d Tf %.2f %d Td (%s) Tj ET Q\n", tocFontSize, dotsStart, y, strings.Repeat(".", dots))
+		}
+		y -= tocLeading
+	}
+
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 595 842] /Resources << /Font << /F1 5 0 R /F2 8 0 R >> >> /Contents 4 0 R >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", content.Len(), content.String()),
+		"<< /Type /Font /Subtype /Type0 /BaseFont /HeiseiMin-W3-UniJIS-UTF16-H /Encoding /UniJIS-UTF16-H /DescendantFonts [6 0 R] >>",
+		// CID 1-95（ASCII）と 231-632（半角）は半角幅
+		"<< /Type /Font /Subtype /CIDFontType0 /BaseFont /HeiseiMin-W3 /CIDSystemInfo << /Registry (Adobe) /Ordering (Japan1) /Supplement 2 >> /FontDescriptor 7 0 R /DW 1000 /W [1 95 500 231 632 500] >>",
+		"<< /Type /FontDescriptor /FontName /HeiseiMin-W3 /Flags 6 /FontBBox [-123 -257 1001 910] /ItalicAngle 0 /Ascent 723 /Descent -241 /CapHeight 709 /StemV 69 >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+		"<< /Title (Contents) /Producer (paper-forge) >>",
+	}
+	return writePDFObjects(path, objects)
+}
@@ -0,0 +1,72 @@
+package pdf
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTOCEntriesStartAfterTOCPage(t *testing.T) {
+	dir := t.TempDir()
+	ordered := []storedFile{
+		// 題名を読めない入力はファイル名で代用する
+		{path: filepath.Join(dir, "missing-1.pdf"), originalName: "契約書\nv3.pdf", pages: 3},
+		{path: filepath.Join(dir, "missing-2.pdf"), originalName: ".pdf", pages: 2},
+		{path: filepath.Join(dir, "missing-3.pdf"), originalName: "appendix.PDF", pages: 1},
+	}
+	got := tocEntries(ordered)
+	want := []tocEntry{{"契約書 v3", 2}, {"Document 2", 5}, {"appendix", 7}}
+	if len(got) != len(want) {
+		t.Fatalf("unexpected entries: %+v", got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("entry %d: got %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestWriteTOCPDF(t *testing.T) {
+	path := filepath.Join(t.TempDir(), tocFilename)
+	long := strings.Repeat("長い題名", 30)
+	if err := writeTOCPDF(path, []tocEntry{{"契約書", 2}, {long, 12}}); err != nil {
+		t.Fatalf("writeTOCPDF: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"<59517D0466F8> Tj", "(2) Tj", "(12) Tj", "/Encoding /UniJIS-UTF16-H"} {
+		if !bytes.Contains(data, []byte(want)) {
+			t.Fatalf("toc lacks %q:\n%s", want, data)
+		}
+	}
+	// 長い題名はページ番号に重ならないよう切り詰める
+	if bytes.Contains(data, []byte(cidHexString(long))) || !bytes.Contains(data, []byte("002E002E002E> Tj")) {
+		t.Fatalf("long titles should be truncated with an ellipsis")
+	}
+	if got := truncateTOCTitle(long, float64(tocMaxTitleWidth)/tocFontSize); tocTextWidth(got) > float64(tocMaxTitleWidth)/tocFontSize {
+		t.Fatalf("truncated title is still too wide: %q", got)
+	}
+}
+
+func TestParseTOCAndCacheKey(t *testing.T) {
+	for raw, want := range map[string]bool{"": false, "true": true, " 1 ": true, "false": false} {
+		if got, err := parseTOC(raw); err != nil || got != want {
+			t.Fatalf("parseTOC(%q) = %v, %v", raw, got, err)
+		}
+	}
+	if _, err := parseTOC("yes"); !IsError(err, "INVALID_INPUT") {
+		t.Fatalf("expected INVALID_INPUT, got %v", err)
+	}
+
+	manifest := &JobManifest{Operation: OperationMerge, Files: []JobFile{{SHA256: "aaa"}, {SHA256: "bbb"}}}
+	plain, _ := resultCacheKey(manifest)
+	manifest.TOC = true
+	withTOC, _ := resultCacheKey(manifest)
+	if plain == withTOC {
+		t.Fatal("merges with and without a table of contents must not share cached results")
+	}
+}
@@ -0,0 +1,237 @@
+package pdf
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	uploadsDirName  = ".uploads"
+	uploadDataName  = "data.pdf"
+	uploadSniffSize = 4096
+)
+
+// UploadState はアップロードの受信・検証状態です。
+type UploadState string
+
+const (
+	UploadReceiving UploadState = "receiving" // 受信中（先頭部分の形式チェックは通過済み）
+	UploadValid     UploadState = "valid"     // 全量を受信し、PDFとして検証済み
+	UploadInvalid   UploadState = "invalid"   // PDFではない、またはページ数などの上限を超えている
+)
+
+// UploadStatus は分割アップロードの進捗と検証結果です。
+type UploadStatus struct {
+	UploadID      string       `json:"uploadId"`
+	Filename      string       `json:"filename"`
+	Size          int64        `json:"size"`
+	ReceivedBytes int64        `json:"receivedBytes"`
+	State         UploadState  `json:"state"`
+	Pages         int          `json:"pages,omitempty"`
+	Error         *UploadError `json:"error,omitempty"`
+	ExpiresAt     time.Time    `json:"expiresAt"`
+}
+
+// UploadError は検証に失敗した理由です。
+type UploadError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// uploadSession はサーバー上で受信中のアップロードです。ファイルは作業ディレクトリの .uploads 配下に保存します。
+type uploadSession struct {
+	mu      sync.Mutex
+	dir     string
+	status  UploadStatus
+	sniff   []byte
+	release func()
+}
+
+// CreateUpload は分割アップロードを開始します。size は宣言されたファイル全体のバイト数です。
+func (s *Service) CreateUpload(filename string, size int64) (*UploadStatus, error) {
+	if size <= 0 {
+		return nil, newError("INVALID_INPUT", "ファイルサイズを指定してください。", nil)
+	}
+	if s.cfg.MaxFileSize > 0 && size > s.cfg.MaxFileSize {
+		return nil, newError("LIMIT_EXCEEDED", fmt.Sprintf("%s のサイズが上限(%dMB)を超えています。", filename, s.cfg.MaxFileSize/(1024*1024)), nil)
+	}
+	release, err := s.usage.reserve(size)
+	if err != nil {
+		return nil, err
+	}
+
+	id := uuid.NewString()
+	dir := filepath.Join(s.tmpRoot, uploadsDirName, id)
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		release()
+		return nil, fmt.Errorf("アップロード用ディレクトリの作成に失敗しました: %w", err)
+	}
+	f, err := os.OpenFile(filepath.Join(dir, uploadDataName), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o640)
+	if err != nil {
+		release()
+		_ = removeDir(dir)
+		return nil, fmt.Errorf("アップロード用ファイルの作成に失敗しました: %w", err)
+	}
+	f.Close()
+
+	expire := time.Duration(s.cfg.JobExpireMinutes) * time.Minute
+	if expire <= 0 {
+		expire = defaultCleanupMin * time.Minute
+	}
+	session := &uploadSession{
+		dir: dir,
+		status: UploadStatus{
+			UploadID:  id,
+			Filename:  safeOriginalName(filename, 0),
+			Size:      size,
+			State:     UploadReceiving,
+			ExpiresAt: s.now().UTC().Add(expire),
+		},
+		release: release,
+	}
+
+	s.uploadsMu.Lock()
+	s.uploads[id] = session
+	s.uploadsMu.Unlock()
+
+	time.AfterFunc(expire, func() { s.discardUpload(id) })
+
+	status := session.status
+	return &status, nil
+}
+
+// UploadStatus は分割アップロードの進捗と検証結果を返します。
+func (s *Service) UploadStatus(id string) (*UploadStatus, error) {
+	session, err := s.lookupUpload(id)
+	if err != nil {
+		return nil, err
+	}
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	status := session.status
+	return &status, nil
+}
+
+// AppendUpload は offset から始まるデータを追記します。offset が受信済みのバイト数と一致しない場合は
+// UPLOAD_OFFSET_MISMATCH を返すため、クライアントは状態を取得し直して続きから再送できます。
+// 先頭部分がPDFでない場合や全量受信後の検証に失敗した場合は、状態を invalid にしてエラーを返します。
+func (s *Service) AppendUpload(id string, offset int64, r io.Reader) (*UploadStatus, error) {
+	session, err := s.lookupUpload(id)
+	if err != nil {
+		return nil, err
+	}
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	st := &session.status
+	if st.State == UploadInvalid {
+		return nil, newError(st.Error.Code, st.Error.Message, nil)
+	}
+	if st.State == UploadValid {
+		return nil, newError("INVALID_INPUT", "このアップロードは完了しています。", nil)
+	}
+	if offset != st.ReceivedBytes {
+		return nil, newError("UPLOAD_OFFSET_MISMATCH", fmt.Sprintf("受信済みのバイト数は %d です。", st.ReceivedBytes), nil)
+	}
+
+	path := filepath.Join(session.dir, uploadDataName)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o640)
+	if err != nil {
+		return nil, fmt.Errorf("アップロード用ファイルを開けませんでした: %w", err)
+	}
+	// 宣言サイズを超えるデータは受け付けない（1バイト余分に読んで超過を検出する）
+	remaining := st.Size - st.ReceivedBytes
+	written, copyErr := io.Copy(io.MultiWriter(f, &sniffWriter{session: session}), io.LimitReader(r, remaining+1))
+	if closeErr := f.Close(); copyErr == nil {
+		copyErr = closeErr
+	}
+	if written > remaining {
+		return nil, s.invalidateUpload(session, "UPLOAD_TOO_LARGE", "宣言されたファイルサイズを超えるデータを受信しました。")
+	}
+	st.ReceivedBytes += written
+	if copyErr != nil {
+		// 途中までの受信分は保持し、クライアントは受信済みのバイト数から再開できる
+		return nil, fmt.Errorf("アップロードデータの受信に失敗しました: %w", copyErr)
+	}
+
+	if len(session.sniff) >= uploadSniffSize || st.ReceivedBytes == st.Size {
+		if rejectBySniff(session.sniff, s.pdfCheckLevel()) {
+			return nil, s.invalidateUpload(session, "UNSUPPORTED_PDF", fmt.Sprintf("%s はPDF形式ではありません。", st.Filename))
+		}
+	}
+
+	if st.ReceivedBytes == st.Size {
+		pages, err := s.checkStoredPDF(path, st.Filename, sniffPDF(session.sniff, s.pdfCheckLevel()))
+		var apiErr *Error
+		if errors.As(err, &apiErr) {
+			return nil, s.invalidateUpload(session, apiErr.Code, apiErr.Message)
+		}
+		if s.cfg.MaxPages > 0 && pages > s.cfg.MaxPages {
+			return nil, s.invalidateUpload(session, "LIMIT_EXCEEDED", fmt.Sprintf("%s のページ数が上限(%dページ)を超えています。", st.Filename, s.cfg.MaxPages))
+		}
+		st.Pages = pages
+		st.State = UploadValid
+		session.release()
+	}
+
+	status := *st
+	return &status, nil
+}
+
+// invalidateUpload は検証失敗を記録し、受信済みのデータを削除します。状態は有効期限まで参照できます。
+func (s *Service) invalidateUpload(session *uploadSession, code, message string) error {
+	session.status.State = UploadInvalid
+	// 状態の取得（GET）でもそのまま返すため、保存する前に利用者向けの規則を適用する
+	message = RedactMessage(message)
+	session.status.Error = &UploadError{Code: code, Message: message}
+	_ = os.Remove(filepath.Join(session.dir, uploadDataName))
+	session.release()
+	return newError(code, message, nil)
+}
+
+func (s *Service) lookupUpload(id string) (*uploadSession, error) {
+	s.uploadsMu.Lock()
+	defer s.uploadsMu.Unlock()
+	session, ok := s.uploads[strings.TrimSpace(id)]
+	if !ok {
+		return nil, newError("UPLOAD_NOT_FOUND", "アップロードが見つかりませんでした。有効期限が切れた可能性があります。", nil)
+	}
+	return session, nil
+}
+
+func (s *Service) discardUpload(id string) {
+	s.uploadsMu.Lock()
+	session, ok := s.uploads[id]
+	delete(s.uploads, id)
+	s.uploadsMu.Unlock()
+	if !ok {
+		return
+	}
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	session.release()
+	_ = removeDir(session.dir)
+}
+
+// sniffWriter は形式判定用にファイルの先頭 uploadSniffSize バイトを保持します。
+type sniffWriter struct {
+	session *uploadSession
+}
+
+func (w *sniffWriter) Write(p []byte) (int, error) {
+	if need := uploadSniffSize - len(w.session.sniff); need > 0 {
+		if need > len(p) {
+			need = len(p)
+		}
+		w.session.sniff = append(w.session.sniff, p[:need]...)
+	}
+	return len(p), nil
+}
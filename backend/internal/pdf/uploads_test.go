@@ -0,0 +1,80 @@
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/yourusername/paper-forge/internal/config"
+)
+
+func newUploadTestService(t *testing.T) *Service {
+	t.Helper()
+	svc := NewService(&config.Config{MaxFileSize: 1 << 20, JobExpireMinutes: 1})
+	svc.tmpRoot = t.TempDir()
+	return svc
+}
+
+func uploadErrorCode(err error) string {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr.Code
+	}
+	return ""
+}
+
+func TestAppendUploadRejectsWrongOffset(t *testing.T) {
+	svc := newUploadTestService(t)
+	status, err := svc.CreateUpload("a.pdf", 10)
+	if err != nil {
+		t.Fatalf("CreateUpload returned error: %v", err)
+	}
+	if _, err := svc.AppendUpload(status.UploadID, 0, strings.NewReader("%PDF-")); err != nil {
+		t.Fatalf("AppendUpload returned error: %v", err)
+	}
+	if _, err := svc.AppendUpload(status.UploadID, 0, strings.NewReader("%PDF-")); uploadErrorCode(err) != "UPLOAD_OFFSET_MISMATCH" {
+		t.Fatalf("expected UPLOAD_OFFSET_MISMATCH, got %v", err)
+	}
+	current, err := svc.UploadStatus(status.UploadID)
+	if err != nil {
+		t.Fatalf("UploadStatus returned error: %v", err)
+	}
+	if current.ReceivedBytes != 5 || current.State != UploadReceiving {
+		t.Fatalf("unexpected status: %#v", current)
+	}
+}
+
+func TestAppendUploadFailsFastOnNonPDF(t *testing.T) {
+	svc := newUploadTestService(t)
+	status, err := svc.CreateUpload("photo.pdf", 64*1024)
+	if err != nil {
+		t.Fatalf("CreateUpload returned error: %v", err)
+	}
+	head := append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{0}, uploadSniffSize)...)
+	if _, err := svc.AppendUpload(status.UploadID, 0, bytes.NewReader(head)); uploadErrorCode(err) != "UNSUPPORTED_PDF" {
+		t.Fatalf("expected UNSUPPORTED_PDF before the upload completes, got %v", err)
+	}
+	current, _ := svc.UploadStatus(status.UploadID)
+	if current.State != UploadInvalid || current.Error == nil || current.Error.Code != "UNSUPPORTED_PDF" {
+		t.Fatalf("unexpected status: %#v", current)
+	}
+}
+
+func TestAppendUploadRejectsDataBeyondDeclaredSize(t *testing.T) {
+	svc := newUploadTestService(t)
+	status, err := svc.CreateUpload("a.pdf", 4)
+	if err != nil {
+		t.Fatalf("CreateUpload returned error: %v", err)
+	}
+	if _, err := svc.AppendUpload(status.UploadID, 0, strings.NewReader("%PDF-1.4")); uploadErrorCode(err) != "UPLOAD_TOO_LARGE" {
+		t.Fatalf("expected UPLOAD_TOO_LARGE, got %v", err)
+	}
+}
+
+func TestUploadStatusUnknownID(t *testing.T) {
+	svc := newUploadTestService(t)
+	if _, err := svc.UploadStatus("missing"); uploadErrorCode(err) != "UPLOAD_NOT_FOUND" {
+		t.Fatalf("expected UPLOAD_NOT_FOUND, got %v", err)
+	}
+}
@@ -0,0 +1,116 @@
+package pdf
+
+import (
+	"io/fs"
+	"mime/multipart"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	// usageRescanInterval は作業領域の実使用量を測り直す間隔です。
+	usageRescanInterval = 5 * time.Second
+	// usageReserveFactor はアップロードサイズに対して見込む作業領域の倍率です（入力 + 復号した入力 + 成果物）。
+	usageReserveFactor = 3
+)
+
+// workspaceUsage は作業領域（WORK_DIR）全体の使用量を管理します。
+//
+// 実使用量はディレクトリを走査して求め、走査結果は usageRescanInterval の間再利用します。
+// 受け付け途中のジョブはアップロードサイズから見積もった容量を予約し、走査結果に加算して上限と比較します。
+type workspaceUsage struct {
+	root  string
+	limit int64
+	now   func() time.Time
+
+	mu         sync.Mutex
+	reserved   int64
+	measured   int64
+	measuredAt time.Time
+}
+
+func newWorkspaceUsage(root string, limit int64) *workspaceUsage {
+	return &workspaceUsage{root: root, limit: limit, now: time.Now}
+}
+
+// reserve は n バイトの作業領域を予約します。上限を超える場合は INSUFFICIENT_STORAGE を返します。
+// 戻り値の関数で予約を解除します。
+func (u *workspaceUsage) reserve(n int64) (func(), error) {
+	if u == nil || u.limit <= 0 {
+		return func() {}, nil
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if u.measuredAt.IsZero() || u.now().Sub(u.measuredAt) >= usageRescanInterval {
+		u.measured = diskUsage(u.root)
+		u.measuredAt = u.now()
+	}
+	if u.measured+u.reserved+n > u.limit {
+		return nil, newError("INSUFFICIENT_STORAGE", "サーバーの作業領域が不足しているため、現在は新しいジョブを受け付けられません。しばらくしてから再度お試しください。", nil)
+	}
+	u.reserved += n
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			u.mu.Lock()
+			defer u.mu.Unlock()
+			u.reserved -= n
+			// 予約分は既にディスクへ書き込まれている可能性があるため、次の走査までは使用済みとして扱う
+			u.measured += n
+		})
+	}, nil
+}
+
+// snapshot は直近の実使用量と予約量を返します。
+func (u *workspaceUsage) snapshot() (used, reserved, limit int64) {
+	if u == nil {
+		return 0, 0, 0
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.measured, u.reserved, u.limit
+}
+
+// diskUsage は root 配下の通常ファイルの合計サイズを返します。走査中に削除されたファイルは無視します。
+func diskUsage(root string) int64 {
+	var total int64
+	// 共有入力ストア・結果キャッシュのハードリンクは同じ実体のため一度だけ数える
+	seen := make(map[[2]uint64]struct{})
+	_ = filepath.WalkDir(root, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.Type().IsRegular() {
+			if info, infoErr := d.Info(); infoErr == nil {
+				if key, ok := fileKey(info); ok {
+					if _, dup := seen[key]; dup {
+						return nil
+					}
+					seen[key] = struct{}{}
+				}
+				total += info.Size()
+			}
+		}
+		return nil
+	})
+	return total
+}
+
+// reserveWorkspace はアップロードされたファイルの処理に必要な作業領域を予約します。
+func (s *Service) reserveWorkspace(files ...*multipart.FileHeader) (func(), error) {
+	var total int64
+	for _, fh := range files {
+		if fh != nil && fh.Size > 0 {
+			total += fh.Size
+		}
+	}
+	return s.usage.reserve(total * usageReserveFactor)
+}
+
+// WorkspaceUsage は作業領域の直近の使用量、予約量、上限（バイト）を返します。上限が 0 の場合は無制限です。
+func (s *Service) WorkspaceUsage() (used, reserved, limit int64) {
+	return s.usage.snapshot()
+}
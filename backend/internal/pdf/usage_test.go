@@ -0,0 +1,51 @@
+package pdf
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWorkspaceUsageRejectsOverLimit(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "existing.pdf"), make([]byte, 600), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	usage := newWorkspaceUsage(root, 1000)
+
+	release, err := usage.reserve(300)
+	if err != nil {
+		t.Fatalf("reserve within limit returned error: %v", err)
+	}
+
+	_, err = usage.reserve(200)
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.Code != "INSUFFICIENT_STORAGE" {
+		t.Fatalf("expected INSUFFICIENT_STORAGE, got %v", err)
+	}
+
+	// 解除後も次の走査までは書き込まれた分として数える
+	release()
+	release()
+	if _, err := usage.reserve(200); err == nil {
+		t.Fatal("expected released reservation to count as used until rescan")
+	}
+
+	now := time.Now()
+	usage.now = func() time.Time { return now.Add(usageRescanInterval) }
+	if _, err := usage.reserve(200); err != nil {
+		t.Fatalf("reserve after rescan returned error: %v", err)
+	}
+	if used, reserved, limit := usage.snapshot(); used != 600 || reserved != 200 || limit != 1000 {
+		t.Fatalf("unexpected snapshot: used=%d reserved=%d limit=%d", used, reserved, limit)
+	}
+}
+
+func TestWorkspaceUsageUnlimited(t *testing.T) {
+	usage := newWorkspaceUsage(t.TempDir(), 0)
+	if _, err := usage.reserve(1 << 40); err != nil {
+		t.Fatalf("unlimited usage returned error: %v", err)
+	}
+}
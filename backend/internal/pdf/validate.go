@@ -0,0 +1,137 @@
+package pdf
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/gabriel-vasile/mimetype"
+	pdfapi "github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+)
+
+// アップロードされたファイルをPDFとして受け付ける基準（PDF_CHECK_LEVEL）
+const (
+	PDFCheckLenient  = "lenient"  // 先頭にヘッダーが見つからなくても pdfcpu で読み込めれば受け付ける
+	PDFCheckStandard = "standard" // 先頭 1024 バイト以内に %PDF- ヘッダーがあり、pdfcpu で読み込めること
+	PDFCheckStrict   = "strict"   // ファイルが %PDF- で始まり、pdfcpu の厳格な検証を通ること
+)
+
+// pdfCheckLevel は設定された検証レベルを返します。未設定の場合は standard です。
+func (s *Service) pdfCheckLevel() string {
+	if s.cfg == nil || s.cfg.PDFCheckLevel == "" {
+		return PDFCheckStandard
+	}
+	return s.cfg.PDFCheckLevel
+}
+
+// sniffPDF は保存前に読み取った先頭部分 head に PDF ヘッダーがあるかを検証レベルに応じて判定します。
+// 仕様上ヘッダーの前に任意のデータが付く場合があるため、strict 以外は先頭 1024 バイト以内を探します。
+func sniffPDF(head []byte, level string) bool {
+	if level == PDFCheckStrict {
+		return mimetype.Detect(head).Is("application/pdf")
+	}
+	window := head
+	if len(window) > precheckHeaderWindow {
+		window = window[:precheckHeaderWindow]
+	}
+	return bytes.Contains(window, []byte("%PDF-"))
+}
+
+// rejectBySniff は先頭部分の判定結果だけでファイルを拒否するかを返します。
+// lenient では先頭のデータが大きい PDF もあるため、保存後の pdfcpu の読み込みに判定を委ねます。
+func rejectBySniff(head []byte, level string) bool {
+	return level != PDFCheckLenient && !sniffPDF(head, level)
+}
+
+// checkStoredPDF は保存済みのファイルを pdfcpu で読み込み、ページ数を返します。
+// パスワードで保護されていて読み込めない場合は ENCRYPTED_PDF を返します。
+// それ以外で読み込めない場合、ヘッダーが見つかっていれば破損したPDF（DAMAGED_PDF）、
+// 見つかっていなければPDFではないファイル（UNSUPPORTED_PDF）として扱います。
+func (s *Service) checkStoredPDF(path, name string, headerFound bool) (int, error) {
+	pages, err := pdfapi.PageCountFile(path)
+	if err != nil {
+		if errors.Is(err, pdfcpu.ErrWrongPassword) {
+			return 0, newError("ENCRYPTED_PDF", fmt.Sprintf("%s はパスワードで保護されています。passwords[] にパスワードを指定してください。", name), err)
+		}
+		if !headerFound {
+			return 0, newError("UNSUPPORTED_PDF", fmt.Sprintf("%s はPDF形式ではありません。", name), err)
+		}
+		return 0, newError("DAMAGED_PDF", fmt.Sprintf("%s は破損しているため読み込めませんでした。", name), err)
+	}
+
+	if s.pdfCheckLevel() == PDFCheckStrict {
+		conf := model.NewDefaultConfiguration()
+		conf.ValidationMode = model.ValidationStrict
+		if err := pdfapi.ValidateFile(path, conf); err != nil {
+			return 0, newError("DAMAGED_PDF", fmt.Sprintf("%s はPDFの仕様に準拠していないため受け付けられません。", name), err)
+		}
+	}
+	return pages, nil
+}
+
+// countStoredPages は保存済みのファイルのページ数を確認し、1ファイルあたりの上限（MAX_PAGES）を判定します。
+func (s *Service) countStoredPages(path, name string, headerFound bool) (int, error) {
+	pages, err := s.checkStoredPDF(path, name, headerFound)
+	if err != nil {
+		return 0, err
+	}
+	if s.cfg.MaxPages > 0 && pages > s.cfg.MaxPages {
+		return 0, newError("LIMIT_EXCEEDED", fmt.Sprintf("%s のページ数が上限(%dページ)を超えています。", name, s.cfg.MaxPages), nil)
+	}
+	return pages, nil
+}
+
+// countDeferredPages は受付時に省いたページ数の確認（DEFER_PAGE_COUNT）を実行時に行います。
+// stored は処理に使う（復号済みの）入力で、確認したページ数を stored と manifest に反映し、マニフェストを保存し直します。
+// 上限の判定は受付時と同じで、超過した場合は LIMIT_EXCEEDED を返します。
+func (s *Service) countDeferredPages(ctx context.Context, ws workspace, manifest *JobManifest, stored []storedFile) error {
+	if !manifest.PagesDeferred {
+		return nil
+	}
+	level := s.pdfCheckLevel()
+	totalPages := 0
+	for i := range stored {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		head, err := readHead(stored[i].path, 4096)
+		if err != nil {
+			return fmt.Errorf("入力ファイルの読み取りに失敗しました(%s): %w", stored[i].originalName, err)
+		}
+		pages, err := s.countStoredPages(stored[i].path, stored[i].originalName, sniffPDF(head, level))
+		if err != nil {
+			return err
+		}
+		stored[i].pages = pages
+		manifest.Files[i].Pages = pages
+		totalPages += pages
+	}
+	if manifest.Operation == OperationMerge && s.cfg.MaxMergePages > 0 && totalPages > s.cfg.MaxMergePages {
+		return newError("LIMIT_EXCEEDED", fmt.Sprintf("結合するファイルの合計ページ数が上限(%dページ)を超えています。ファイルを分けて結合してください。", s.cfg.MaxMergePages), nil)
+	}
+	manifest.PagesDeferred = false
+	if err := writeManifest(s.fs, ws.dir, manifest); err != nil {
+		return fmt.Errorf("ジョブマニフェストの保存に失敗しました: %w", err)
+	}
+	return nil
+}
+
+// readHead はファイルの先頭 n バイトまでを読み取ります。
+func readHead(path string, n int) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	buf := make([]byte, n)
+	read, err := io.ReadFull(f, buf)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, err
+	}
+	return buf[:read], nil
+}
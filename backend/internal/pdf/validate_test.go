@@ -0,0 +1,59 @@
+package pdf
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/yourusername/paper-forge/internal/config"
+)
+
+func TestSniffPDFLevels(t *testing.T) {
+	junk := bytes.Repeat([]byte("x"), 600)
+	cases := []struct {
+		name  string
+		head  []byte
+		level string
+		want  bool
+	}{
+		{name: "header at start", head: []byte("%PDF-1.7\n"), level: PDFCheckStrict, want: true},
+		{name: "small junk standard", head: append(append([]byte{}, junk...), "%PDF-1.4\n"...), level: PDFCheckStandard, want: true},
+		{name: "small junk strict", head: append(append([]byte{}, junk...), "%PDF-1.4\n"...), level: PDFCheckStrict, want: false},
+		{name: "header beyond window", head: append(bytes.Repeat([]byte("x"), 2048), "%PDF-1.4\n"...), level: PDFCheckStandard, want: false},
+		{name: "not pdf", head: []byte("\x89PNG\r\n\x1a\n"), level: PDFCheckStandard, want: false},
+	}
+	for _, tc := range cases {
+		if got := sniffPDF(tc.head, tc.level); got != tc.want {
+			t.Errorf("%s: sniffPDF = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+
+	if rejectBySniff([]byte("\x89PNG\r\n\x1a\n"), PDFCheckLenient) {
+		t.Error("lenient should defer the decision to pdfcpu")
+	}
+	if !rejectBySniff([]byte("\x89PNG\r\n\x1a\n"), PDFCheckStandard) {
+		t.Error("standard should reject files without a header")
+	}
+}
+
+func TestCheckStoredPDFDistinguishesDamagedFromNonPDF(t *testing.T) {
+	svc := NewService(&config.Config{PDFCheckLevel: PDFCheckLenient})
+	dir := t.TempDir()
+
+	damaged := filepath.Join(dir, "damaged.pdf")
+	if err := os.WriteFile(damaged, []byte("%PDF-1.4\n1 0 obj <<\n"), 0o640); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if _, err := svc.checkStoredPDF(damaged, "damaged.pdf", true); !IsError(err, "DAMAGED_PDF") {
+		t.Fatalf("expected DAMAGED_PDF, got %v", err)
+	}
+
+	text := filepath.Join(dir, "notes.pdf")
+	if err := os.WriteFile(text, []byte("hello"), 0o640); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if _, err := svc.checkStoredPDF(text, "notes.pdf", false); !IsError(err, "UNSUPPORTED_PDF") {
+		t.Fatalf("expected UNSUPPORTED_PDF, got %v", err)
+	}
+}
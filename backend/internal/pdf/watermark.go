@@ -0,0 +1,128 @@
+package pdf
+
+import (
+	"log"
+	"path/filepath"
+	"strings"
+
+	pdfapi "github.com/pdfcpu/pdfcpu/pkg/api"
+)
+
+// DefaultWatermarkStyle は WATERMARK_STYLE が未設定の場合の透かしの書式です（pdfcpu の透かし記述）。
+// ページ下部中央に小さな灰色の文字で重ねます。
+const DefaultWatermarkStyle = "fontname:Helvetica, points:9, position:bc, offset:0 12, scalefactor:1 abs, rotation:0, opacity:0.7, fillcolor:#555555"
+
+// watermarkPolicy はデプロイ単位で成果物に自動で付ける透かし（フッター）の設定です。
+type watermarkPolicy struct {
+	text  string
+	style string
+	// ops は透かしを付ける操作です。空の場合は全操作に付けます。
+	ops map[OperationType]bool
+}
+
+// newWatermarkPolicy は WATERMARK_* の設定から透かしの設定を作成します。text が空の場合は nil を返します。
+func newWatermarkPolicy(text, style, operations string) *watermarkPolicy {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil
+	}
+	style = strings.TrimSpace(style)
+	if style == "" {
+		style = DefaultWatermarkStyle
+	}
+	ops, unknown := parseOperationList(operations)
+	if len(unknown) > 0 {
+		log.Printf("WATERMARK_OPERATIONS contains unknown operations (ignored): %s", strings.Join(unknown, ", "))
+	}
+	return &watermarkPolicy{text: text, style: style, ops: ops}
+}
+
+// appliesTo は操作 op の成果物に透かしを付けるかを返します。nil の watermarkPolicy は常に false です。
+func (p *watermarkPolicy) appliesTo(op OperationType) bool {
+	if p == nil {
+		return false
+	}
+	return len(p.ops) == 0 || p.ops[op]
+}
+
+// signature は結果キャッシュで透かしの設定の違いを見分けるための値です。透かしを付けない操作では空です。
+func (p *watermarkPolicy) signature(op OperationType) string {
+	if !p.appliesTo(op) {
+		return ""
+	}
+	return p.text + "\n" + p.style
+}
+
+// applyWatermark は処理パイプラインの最終段として、設定された透かしを成果物の PDF に付けます。
+// ZIP の成果物は同じ結果の PDF 成果物をまとめたものなので、透かしを付けた PDF から作り直します。
+func (s *Service) applyWatermark(op OperationType, result *Result) error {
+	if !s.watermark.appliesTo(op) || result == nil {
+		return nil
+	}
+
+	var pdfPaths []string
+	for i, a := range result.Artifacts {
+		if a.Kind != ResultKindPDF {
+			continue
+		}
+		if err := pdfapi.AddTextWatermarksFile(a.Path, "", nil, true, s.watermark.text, s.watermark.style, nil); err != nil {
+			return err
+		}
+		if err := refreshArtifact(result, i); err != nil {
+			return err
+		}
+		pdfPaths = append(pdfPaths, a.Path)
+	}
+	return rebuildZipArtifacts(result, pdfPaths)
+}
+
+// rebuildZipArtifacts は書き換えた PDF 成果物 pdfPaths から ZIP の成果物を作り直します。
+// 分割の結果は、各パートのサイズと SHA-256（meta.parts と ZIP のコメント）も書き換えた内容に合わせます。
+func rebuildZipArtifacts(result *Result, pdfPaths []string) error {
+	comment := ""
+	if meta, ok := result.Meta.(*SplitMeta); ok && len(pdfPaths) > 0 {
+		refreshSplitParts(meta, result.Artifacts)
+		comment = splitZipComment(meta.Parts)
+	}
+	for i, a := range result.Artifacts {
+		if a.Kind != ResultKindZIP || len(pdfPaths) == 0 {
+			continue
+		}
+		if err := createZip(a.Path, pdfPaths, comment); err != nil {
+			return err
+		}
+		if err := refreshArtifact(result, i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// refreshSplitParts は分割の各パートのサイズと SHA-256 を、同じ名前の成果物の値で更新します。
+func refreshSplitParts(meta *SplitMeta, artifacts []Artifact) {
+	byName := make(map[string]Artifact, len(artifacts))
+	for _, a := range artifacts {
+		byName[a.Name] = a
+	}
+	for i, p := range meta.Parts {
+		if a, ok := byName[p.Filename]; ok {
+			meta.Parts[i].Size = a.Size
+			meta.Parts[i].SHA256 = a.SHA256
+		}
+	}
+}
+
+// refreshArtifact は書き換えた成果物のサイズとハッシュを計算し直します。
+func refreshArtifact(result *Result, i int) error {
+	a := result.Artifacts[i]
+	updated, err := newArtifact(a.Path, a.Kind)
+	if err != nil {
+		return err
+	}
+	updated.Name = a.Name
+	result.Artifacts[i] = updated
+	if i == 0 && filepath.Clean(result.OutputPath) == filepath.Clean(a.Path) {
+		result.OutputSize = updated.Size
+	}
+	return nil
+}
@@ -0,0 +1,330 @@
+package pdf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	pdfapi "github.com/pdfcpu/pdfcpu/pkg/api"
+
+	"github.com/yourusername/paper-forge/internal/tenant"
+)
+
+const (
+	watermarkFilename = "watermarked.pdf"
+	// maxWatermarkTextLength は透かしの文言の最大文字数です。
+	maxWatermarkTextLength = 200
+)
+
+// 透かしの指定を省略した項目の既定値です。ページ中央に斜めの薄い文字で重ねます。
+const (
+	defaultWatermarkOpacity  = 0.3
+	defaultWatermarkRotation = 45
+	defaultWatermarkFontSize = 48
+	defaultWatermarkPosition = "c"
+)
+
+// watermarkPositions は指定できる透かしの位置（pdfcpu の position）です。
+var watermarkPositions = []string{"tl", "tc", "tr", "l", "c", "r", "bl", "bc", "br"}
+
+// WatermarkSpec はページに重ねる文字の透かしの指定です。
+// デプロイ単位で全成果物に付ける透かし（WATERMARK_*）とは別に、利用者が操作として指定します。
+type WatermarkSpec struct {
+	Text     string  `json:"text"`
+	Opacity  float64 `json:"opacity"`  // 不透明度（0 より大きく 1 以下）
+	Rotation float64 `json:"rotation"` // 回転角度（反時計回り、-180〜180）
+	FontSize int     `json:"fontSize"` // 文字の大きさ（ポイント）
+	Position string  `json:"position"` // tl / tc / tr / l / c / r / bl / bc / br
+}
+
+// description は pdfcpu の透かし記述を返します。
+func (w WatermarkSpec) description() string {
+	return fmt.Sprintf("fontname:Helvetica, points:%d, position:%s, rotation:%s, opacity:%s, scalefactor:1 abs, fillcolor:#808080",
+		w.FontSize, w.Position,
+		strconv.FormatFloat(w.Rotation, 'f', -1, 64),
+		strconv.FormatFloat(w.Opacity, 'f', -1, 64))
+}
+
+// validateWatermarkSpec は透かしの指定が範囲内であることを確認します。
+func validateWatermarkSpec(w WatermarkSpec) error {
+	switch {
+	case strings.TrimSpace(w.Text) == "":
+		return newError("INVALID_INPUT", "透かしの文言を指定してください。", nil)
+	case utf8.RuneCountInString(w.Text) > maxWatermarkTextLength:
+		return newError("INVALID_INPUT", fmt.Sprintf("透かしの文言は%d文字以内で指定してください。", maxWatermarkTextLength), nil)
+	case !utf8.ValidString(w.Text) || strings.IndexFunc(w.Text, unicode.IsControl) >= 0:
+		return newError("INVALID_INPUT", "透かしの文言に改行などの制御文字は使用できません。", nil)
+	case w.Opacity <= 0 || w.Opacity > 1:
+		return newError("INVALID_INPUT", "opacity は 0 より大きく 1 以下で指定してください。", nil)
+	case w.Rotation < -180 || w.Rotation > 180:
+		return newError("INVALID_INPUT", "rotation は -180〜180 の範囲で指定してください。", nil)
+	case w.FontSize < 6 || w.FontSize > 200:
+		return newError("INVALID_INPUT", "fontSize は 6〜200 の範囲で指定してください。", nil)
+	}
+	for _, p := range watermarkPositions {
+		if w.Position == p {
+			return nil
+		}
+	}
+	return newError("INVALID_INPUT", fmt.Sprintf("position は %s のいずれかで指定してください。", strings.Join(watermarkPositions, ", ")), nil)
+}
+
+// parseWatermarkSpec はフォーム項目（text, opacity, rotation, fontSize, position）から透かしの指定を作成します。
+// 省略した項目は既定値を使います。値の範囲は validateWatermarkSpec で確認します。
+func parseWatermarkSpec(values url.Values) (WatermarkSpec, error) {
+	spec := WatermarkSpec{
+		Text:     strings.TrimSpace(values.Get("text")),
+		Opacity:  defaultWatermarkOpacity,
+		Rotation: defaultWatermarkRotation,
+		FontSize: defaultWatermarkFontSize,
+		Position: defaultWatermarkPosition,
+	}
+	if raw := strings.TrimSpace(values.Get("opacity")); raw != "" {
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return WatermarkSpec{}, errors.New("opacity は数値で指定してください。")
+		}
+		spec.Opacity = v
+	}
+	if raw := strings.TrimSpace(values.Get("rotation")); raw != "" {
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return WatermarkSpec{}, errors.New("rotation は数値で指定してください。")
+		}
+		spec.Rotation = v
+	}
+	if raw := strings.TrimSpace(values.Get("fontSize")); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil {
+			return WatermarkSpec{}, errors.New("fontSize は整数で指定してください。")
+		}
+		spec.FontSize = v
+	}
+	if raw := strings.TrimSpace(values.Get("position")); raw != "" {
+		spec.Position = strings.ToLower(raw)
+	}
+	return spec, nil
+}
+
+// WatermarkMultipart は単一PDFの指定ページ（pages が空の場合は全ページ）に文字の透かしを重ねます。
+func (s *Service) WatermarkMultipart(ctx context.Context, file *multipart.FileHeader, spec WatermarkSpec, pages string) (_ *Result, err error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if file == nil {
+		return nil, newError("INVALID_INPUT", "PDFファイルを選択してください。", nil)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	state, _, err := s.prepareWatermark(ctx, file, spec, pages, JobOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = removeDir(state.ws.dir)
+		}
+	}()
+
+	result, execErr := s.executeWatermark(ctx, state, nil)
+	if execErr != nil {
+		return nil, execErr
+	}
+	return result, nil
+}
+
+type watermarkState struct {
+	ws   workspace
+	file storedFile
+	spec WatermarkSpec
+	// pagesRaw は透かしを重ねるページ範囲指定です。空の場合は全ページに重ねます。
+	pagesRaw string
+}
+
+func (s *Service) prepareWatermark(ctx context.Context, file *multipart.FileHeader, spec WatermarkSpec, pages string, opts JobOptions) (*watermarkState, *JobManifest, error) {
+	spec.Text = strings.TrimSpace(spec.Text)
+	if err := validateWatermarkSpec(spec); err != nil {
+		return nil, nil, err
+	}
+
+	release, err := s.reserveWorkspace(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer release()
+
+	ws, err := s.createWorkspace()
+	if err != nil {
+		return nil, nil, err
+	}
+	stored, err := s.storeMultipartFile(ctx, file, ws.inDir, 0, false)
+	if err != nil {
+		_ = removeDir(ws.dir)
+		return nil, nil, err
+	}
+
+	pages = strings.TrimSpace(pages)
+	if pages != "" {
+		if _, err := parsePageRanges(pages, stored.pages); err != nil {
+			_ = removeDir(ws.dir)
+			return nil, nil, err
+		}
+	}
+
+	manifest := &JobManifest{
+		JobID:     ws.jobID,
+		Tenant:    tenant.FromContext(ctx),
+		Operation: OperationWatermark,
+		Files:     toJobFiles([]storedFile{stored}),
+		Ranges:    pages,
+		Watermark: &spec,
+		Options:   opts,
+		StoreMs:   ws.elapsed().Milliseconds(),
+		CreatedAt: s.now().UTC(),
+	}
+	if err := writeManifest(s.fs, ws.dir, manifest); err != nil {
+		_ = removeDir(ws.dir)
+		return nil, nil, fmt.Errorf("ジョブマニフェストの保存に失敗しました: %w", err)
+	}
+
+	return &watermarkState{ws: ws, file: stored, spec: spec, pagesRaw: pages}, manifest, nil
+}
+
+func (s *Service) executeWatermark(ctx context.Context, state *watermarkState, progress ProgressReporter) (*Result, error) {
+	ws := state.ws
+	stored := state.file
+
+	var (
+		ranges        []PageRange
+		selectedPages []string
+	)
+	if state.pagesRaw != "" {
+		parsed, err := parsePageRanges(state.pagesRaw, stored.pages)
+		if err != nil {
+			return nil, err
+		}
+		ranges = parsed
+		for _, pr := range ranges {
+			selectedPages = append(selectedPages, buildPageSelection(pr)...)
+		}
+	}
+
+	reportProgress(progress, StageProcess, 0)
+	outputPath := filepath.Join(ws.outDir, watermarkFilename)
+	spec := state.spec
+	if err := runStep(ctx, func() error {
+		return pdfapi.AddTextWatermarksFile(stored.path, outputPath, selectedPages, true, spec.Text, spec.description(), nil)
+	}); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		return nil, newError("UNSUPPORTED_PDF", "PDFへの透かしの追加に失敗しました。ファイルが破損していないか確認してください。", err)
+	}
+	reportProgress(progress, StageWrite, 0)
+
+	sourceMeta := SourceFileMeta{
+		Name:  stored.originalName,
+		Size:  stored.size,
+		Pages: stored.pages,
+	}
+
+	meta := struct {
+		Type      OperationType  `json:"type"`
+		CreatedAt string         `json:"createdAt"`
+		Source    SourceFileMeta `json:"source"`
+		Watermark WatermarkSpec  `json:"watermark"`
+		Pages     []PageRange    `json:"pages,omitempty"`
+		Output    string         `json:"output"`
+	}{
+		Type:      OperationWatermark,
+		CreatedAt: s.now().UTC().Format(time.RFC3339),
+		Source:    sourceMeta,
+		Watermark: spec,
+		Pages:     ranges,
+		Output:    watermarkFilename,
+	}
+
+	metaPath := filepath.Join(ws.dir, "meta.json")
+	if err := writeJSON(s.fs, metaPath, meta); err != nil {
+		return nil, fmt.Errorf("メタデータの保存に失敗しました: %w", err)
+	}
+
+	s.scheduleCleanup(ws.dir)
+
+	artifact, err := newArtifact(outputPath, ResultKindPDF)
+	if err != nil {
+		return nil, fmt.Errorf("成果物の確認に失敗しました: %w", err)
+	}
+
+	reportProgress(progress, StageCompleted, 1)
+
+	return newResult(ws, OperationWatermark, s.fs, []Artifact{artifact}, &WatermarkMeta{
+		Original:  sourceMeta,
+		Watermark: spec,
+		Pages:     ranges,
+	}), nil
+}
+
+// PrepareWatermarkJob は非同期ジョブ用に入力を保存します。
+func (s *Service) PrepareWatermarkJob(ctx context.Context, file *multipart.FileHeader, spec WatermarkSpec, pages string, opts JobOptions) (*JobManifest, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	_, manifest, err := s.prepareWatermark(ctx, file, spec, pages, opts)
+	if err != nil {
+		return nil, err
+	}
+	return s.sealJob(manifest)
+}
+
+func init() {
+	registerOperation(watermarkOperation{})
+}
+
+type watermarkOperation struct{}
+
+func (watermarkOperation) Type() OperationType { return OperationWatermark }
+
+func (watermarkOperation) OutputSpec() OutputSpec {
+	return OutputSpec{Filename: watermarkFilename, Kind: ResultKindPDF}
+}
+
+func (watermarkOperation) Prepare(ctx context.Context, s *Service, req PrepareRequest) (*JobManifest, error) {
+	file, err := req.singleFile()
+	if err != nil {
+		return nil, err
+	}
+	spec, err := parseWatermarkSpec(req.Params)
+	if err != nil {
+		return nil, newError("INVALID_INPUT", err.Error(), nil)
+	}
+	return s.PrepareWatermarkJob(ctx, file, spec, req.Params.Get("pages"), req.Options)
+}
+
+func (watermarkOperation) Execute(ctx context.Context, s *Service, job *jobInput, progress ProgressReporter) (*Result, error) {
+	if job.manifest.Watermark == nil {
+		return nil, newError("INVALID_INPUT", "透かしの指定がありません。", nil)
+	}
+	state := &watermarkState{
+		ws:       job.ws,
+		file:     job.files[0],
+		spec:     *job.manifest.Watermark,
+		pagesRaw: job.manifest.Ranges,
+	}
+	return s.executeWatermark(ctx, state, progress)
+}
+
+func (watermarkOperation) Plan(s *Service, job *jobInput) (*JobPlan, error) {
+	return singleOutputPlan(watermarkFilename, job.files[0], job.manifest.Ranges)
+}
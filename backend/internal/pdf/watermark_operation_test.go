@@ -0,0 +1,80 @@
+package pdf
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"github.com/yourusername/paper-forge/internal/config"
+	"github.com/yourusername/paper-forge/internal/pdf/pdftest"
+)
+
+func TestParseWatermarkSpecAppliesDefaults(t *testing.T) {
+	spec, err := parseWatermarkSpec(url.Values{"text": {" DRAFT "}, "rotation": {"0"}, "position": {"BR"}})
+	if err != nil {
+		t.Fatalf("parseWatermarkSpec returned error: %v", err)
+	}
+	want := WatermarkSpec{Text: "DRAFT", Opacity: defaultWatermarkOpacity, Rotation: 0, FontSize: defaultWatermarkFontSize, Position: "br"}
+	if spec != want {
+		t.Fatalf("unexpected spec: %+v", spec)
+	}
+	if err := validateWatermarkSpec(spec); err != nil {
+		t.Fatalf("validateWatermarkSpec returned error: %v", err)
+	}
+	if got := spec.description(); got != "fontname:Helvetica, points:48, position:br, rotation:0, opacity:0.3, scalefactor:1 abs, fillcolor:#808080" {
+		t.Fatalf("unexpected description: %s", got)
+	}
+
+	if _, err := parseWatermarkSpec(url.Values{"text": {"DRAFT"}, "fontSize": {"large"}}); err == nil {
+		t.Fatal("expected error for non-numeric fontSize")
+	}
+}
+
+func TestValidateWatermarkSpecRejectsInvalid(t *testing.T) {
+	valid := WatermarkSpec{Text: "DRAFT", Opacity: 0.5, Rotation: 45, FontSize: 48, Position: "c"}
+	cases := map[string]func(*WatermarkSpec){
+		"empty text":    func(w *WatermarkSpec) { w.Text = " " },
+		"control char":  func(w *WatermarkSpec) { w.Text = "DRAFT\nCOPY" },
+		"zero opacity":  func(w *WatermarkSpec) { w.Opacity = 0 },
+		"opacity > 1":   func(w *WatermarkSpec) { w.Opacity = 1.5 },
+		"rotation":      func(w *WatermarkSpec) { w.Rotation = 270 },
+		"font too big":  func(w *WatermarkSpec) { w.FontSize = 500 },
+		"bad position":  func(w *WatermarkSpec) { w.Position = "middle" },
+		"text too long": func(w *WatermarkSpec) { w.Text = string(make([]rune, maxWatermarkTextLength+1)) },
+	}
+	for name, mutate := range cases {
+		spec := valid
+		mutate(&spec)
+		if err := validateWatermarkSpec(spec); !IsError(err, "INVALID_INPUT") {
+			t.Fatalf("%s: expected INVALID_INPUT, got %v", name, err)
+		}
+	}
+}
+
+func TestPrepareWatermarkJobStoresSpec(t *testing.T) {
+	svc := NewService(&config.Config{MaxFileSize: 1 << 20, MaxPages: 20})
+	svc.tmpRoot = t.TempDir()
+	headers, err := pdftest.FileHeaders("file",
+		pdftest.File{Name: "a.pdf", Data: pdftest.Synthetic(3, 0)},
+	)
+	if err != nil {
+		t.Fatalf("failed to build multipart files: %v", err)
+	}
+
+	spec := WatermarkSpec{Text: "CONFIDENTIAL", Opacity: 0.2, Rotation: -30, FontSize: 36, Position: "tc"}
+	manifest, err := svc.PrepareWatermarkJob(context.Background(), headers[0], spec, " 2-3 ", JobOptions{})
+	if err != nil {
+		t.Fatalf("PrepareWatermarkJob returned error: %v", err)
+	}
+	saved, err := loadManifest(svc.fs, svc.workspaceFor(manifest.JobID).dir)
+	if err != nil {
+		t.Fatalf("failed to load manifest: %v", err)
+	}
+	if saved.Operation != OperationWatermark || saved.Ranges != "2-3" || saved.Watermark == nil || *saved.Watermark != spec {
+		t.Fatalf("unexpected manifest: %+v", saved)
+	}
+
+	if _, err := svc.PrepareWatermarkJob(context.Background(), headers[0], spec, "4", JobOptions{}); !IsError(err, "INVALID_INPUT") {
+		t.Fatalf("expected INVALID_INPUT for pages beyond the document, got %v", err)
+	}
+}
@@ -0,0 +1,110 @@
+package pdf
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/yourusername/paper-forge/internal/config"
+	"github.com/yourusername/paper-forge/internal/pdf/pdftest"
+)
+
+func TestWatermarkPolicyAppliesTo(t *testing.T) {
+	if newWatermarkPolicy("  ", "", "") != nil {
+		t.Fatal("empty text should disable the watermark")
+	}
+	var disabled *watermarkPolicy
+	if disabled.appliesTo(OperationMerge) || disabled.signature(OperationMerge) != "" {
+		t.Fatal("nil policy should not apply")
+	}
+
+	all := newWatermarkPolicy("Processed by ACME DMS", "", "")
+	if !all.appliesTo(OperationMerge) || !all.appliesTo(OperationOptimize) {
+		t.Fatal("empty WATERMARK_OPERATIONS should apply to all operations")
+	}
+	if all.style != DefaultWatermarkStyle {
+		t.Fatalf("unexpected default style: %q", all.style)
+	}
+
+	some := newWatermarkPolicy("Processed by ACME DMS", "points:12", "merge, split, unknown")
+	if !some.appliesTo(OperationSplit) || some.appliesTo(OperationOptimize) {
+		t.Fatalf("unexpected operations: %v", some.ops)
+	}
+	if some.signature(OperationOptimize) != "" || some.signature(OperationMerge) == all.signature(OperationMerge) {
+		t.Fatal("signature should differ by style and be empty for excluded operations")
+	}
+}
+
+func TestApplyWatermarkRebuildsZip(t *testing.T) {
+	dir := t.TempDir()
+	var partPaths []string
+	for _, name := range []string{"part-01.pdf", "part-02.pdf"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, pdftest.Synthetic(1, 0), 0o640); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+		partPaths = append(partPaths, path)
+	}
+	zipPath := filepath.Join(dir, splitFilename)
+	if err := createZip(zipPath, partPaths, ""); err != nil {
+		t.Fatalf("createZip returned error: %v", err)
+	}
+
+	var artifacts []Artifact
+	for _, path := range append([]string{zipPath}, partPaths...) {
+		kind := ResultKindPDF
+		if path == zipPath {
+			kind = ResultKindZIP
+		}
+		a, err := newArtifact(path, kind)
+		if err != nil {
+			t.Fatalf("newArtifact returned error: %v", err)
+		}
+		artifacts = append(artifacts, a)
+	}
+	meta := &SplitMeta{Parts: []SplitPart{
+		{Filename: "part-01.pdf", FromPage: 1, ToPage: 1, Pages: 1, Size: artifacts[1].Size, SHA256: artifacts[1].SHA256},
+		{Filename: "part-02.pdf", FromPage: 2, ToPage: 2, Pages: 1, Size: artifacts[2].Size, SHA256: artifacts[2].SHA256},
+	}}
+	result := newResult(workspace{jobID: "job-1", dir: dir, outDir: dir}, OperationSplit, OSFS{}, append([]Artifact(nil), artifacts...), meta)
+
+	svc := NewService(&config.Config{WatermarkText: "Processed by ACME DMS", WatermarkOperations: "split"})
+	if err := svc.applyWatermark(OperationSplit, result); err != nil {
+		t.Fatalf("applyWatermark returned error: %v", err)
+	}
+
+	for i, a := range result.Artifacts {
+		if a.SHA256 == artifacts[i].SHA256 {
+			t.Fatalf("artifact %s was not rewritten", a.Name)
+		}
+	}
+	if result.OutputSize != result.Artifacts[0].Size {
+		t.Fatalf("primary output size not refreshed: %d != %d", result.OutputSize, result.Artifacts[0].Size)
+	}
+
+	zr, err := zip.OpenReader(zipPath)
+	if err != nil {
+		t.Fatalf("failed to open zip: %v", err)
+	}
+	defer zr.Close()
+	for _, f := range zr.File {
+		info, err := os.Stat(filepath.Join(dir, f.Name))
+		if err != nil {
+			t.Fatalf("unexpected zip entry %s: %v", f.Name, err)
+		}
+		if int64(f.UncompressedSize64) != info.Size() {
+			t.Fatalf("zip entry %s was not rebuilt from the watermarked part", f.Name)
+		}
+	}
+
+	// 分割の各パートの SHA-256 は透かしを付けた内容に合わせて更新する
+	for i, part := range meta.Parts {
+		if part.SHA256 != result.Artifacts[i+1].SHA256 || part.Size != result.Artifacts[i+1].Size {
+			t.Fatalf("split part %s not refreshed: %+v", part.Filename, part)
+		}
+	}
+	if zr.Comment != splitZipComment(meta.Parts) {
+		t.Fatalf("zip comment not refreshed: %q", zr.Comment)
+	}
+}
@@ -1,12 +1,25 @@
 package pdf
 
-import "path/filepath"
+import (
+	"path/filepath"
+	"time"
+)
 
 type workspace struct {
 	jobID  string
 	dir    string
 	inDir  string
 	outDir string
+	// createdAt は createWorkspace で作成した時刻です（入力の保存にかかった時間の計測用）。workspaceFor では設定しません。
+	createdAt time.Time
+}
+
+// elapsed はワークスペースを作成してからの経過時間を返します。
+func (w workspace) elapsed() time.Duration {
+	if w.createdAt.IsZero() {
+		return 0
+	}
+	return time.Since(w.createdAt)
 }
 
 func (w workspace) manifestPath() string {
@@ -0,0 +1,189 @@
+package signing
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yourusername/paper-forge/internal/config"
+)
+
+const (
+	kmsEndpoint      = "https://cloudkms.googleapis.com/v1/"
+	kmsMetadataToken = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+)
+
+// kmsSigner は Cloud KMS の非対称鍵で署名します。秘密鍵は KMS の外に出ません。
+// 先頭の鍵バージョンで署名し、残りは以前の署名の検証用に公開鍵だけを公開します。
+// 認証には Cloud Run 等のメタデータサーバーから取得するアクセストークンを使います。
+type kmsSigner struct {
+	versions []string
+	endpoint string
+	tokenURL string
+	client   *http.Client
+
+	// 鍵バージョンの公開鍵は変わらないため、一度取得したものを使い続ける
+	mu   sync.Mutex
+	keys map[string]PublicKey
+}
+
+func newKMSSigner(cfg *config.Config) *kmsSigner {
+	var versions []string
+	for _, name := range strings.Split(cfg.SigningKMSKeyVersions, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			versions = append(versions, name)
+		}
+	}
+	return &kmsSigner{
+		versions: versions,
+		endpoint: kmsEndpoint,
+		tokenURL: kmsMetadataToken,
+		client:   &http.Client{Timeout: 30 * time.Second},
+		keys:     make(map[string]PublicKey),
+	}
+}
+
+func (s *kmsSigner) Sign(ctx context.Context, payload []byte) (Signature, error) {
+	key, err := s.publicKey(ctx, s.versions[0])
+	if err != nil {
+		return Signature{}, err
+	}
+	// Ed25519 は payload そのものを、それ以外は SHA-256 のダイジェストを送る
+	body := map[string]any{"data": base64.StdEncoding.EncodeToString(payload)}
+	if key.Algorithm != AlgorithmEd25519 {
+		body = map[string]any{"digest": map[string]string{"sha256": base64.StdEncoding.EncodeToString(digest(key.Algorithm, payload))}}
+	}
+	var resp struct {
+		Signature string `json:"signature"`
+	}
+	if err := s.call(ctx, http.MethodPost, key.KeyID+":asymmetricSign", body, &resp); err != nil {
+		return Signature{}, err
+	}
+	value, err := base64.StdEncoding.DecodeString(resp.Signature)
+	if err != nil {
+		return Signature{}, fmt.Errorf("kms returned an invalid signature: %w", err)
+	}
+	return Signature{KeyID: key.KeyID, Algorithm: key.Algorithm, Value: value}, nil
+}
+
+func (s *kmsSigner) PublicKeys(ctx context.Context) ([]PublicKey, error) {
+	keys := make([]PublicKey, 0, len(s.versions))
+	for i, name := range s.versions {
+		key, err := s.publicKey(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		key.Active = i == 0
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// publicKey は鍵バージョン name の公開鍵と署名方式を返します。
+func (s *kmsSigner) publicKey(ctx context.Context, name string) (PublicKey, error) {
+	s.mu.Lock()
+	key, ok := s.keys[name]
+	s.mu.Unlock()
+	if ok {
+		return key, nil
+	}
+
+	var resp struct {
+		PEM       string `json:"pem"`
+		Algorithm string `json:"algorithm"`
+	}
+	if err := s.call(ctx, http.MethodGet, name+"/publicKey", nil, &resp); err != nil {
+		return PublicKey{}, err
+	}
+	algorithm, ok := kmsAlgorithms[resp.Algorithm]
+	if !ok {
+		return PublicKey{}, fmt.Errorf("%w: kms key algorithm %s", ErrUnsupportedKey, resp.Algorithm)
+	}
+	pub, err := parsePublicKeyPEM(resp.PEM)
+	if err != nil {
+		return PublicKey{}, err
+	}
+	key = PublicKey{KeyID: name, Algorithm: algorithm, Key: pub}
+
+	s.mu.Lock()
+	s.keys[name] = key
+	s.mu.Unlock()
+	return key, nil
+}
+
+// kmsAlgorithms は Cloud KMS の鍵のアルゴリズムと署名方式の対応です。
+var kmsAlgorithms = map[string]string{
+	"EC_SIGN_ED25519":            AlgorithmEd25519,
+	"EC_SIGN_P256_SHA256":        AlgorithmES256,
+	"RSA_SIGN_PKCS1_2048_SHA256": AlgorithmRS256,
+	"RSA_SIGN_PKCS1_3072_SHA256": AlgorithmRS256,
+	"RSA_SIGN_PKCS1_4096_SHA256": AlgorithmRS256,
+}
+
+func (s *kmsSigner) call(ctx context.Context, method, path string, body, out any) error {
+	token, err := s.accessToken(ctx)
+	if err != nil {
+		return err
+	}
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, s.endpoint+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("kms request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 2048))
+		return fmt.Errorf("kms responded with %d: %s", resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func (s *kmsSigner) accessToken(ctx context.Context) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.tokenURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch access token: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("metadata server responded with %d", resp.StatusCode)
+	}
+
+	var payload struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return "", fmt.Errorf("failed to parse access token: %w", err)
+	}
+	if payload.AccessToken == "" {
+		return "", fmt.Errorf("metadata server returned an empty access token")
+	}
+	return payload.AccessToken, nil
+}
@@ -0,0 +1,101 @@
+package signing
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sort"
+
+	"golang.org/x/crypto/pkcs12"
+
+	"github.com/yourusername/paper-forge/internal/config"
+)
+
+// localSigner はこのプロセスのメモリ上の秘密鍵で署名します。
+type localSigner struct {
+	activeID  string
+	algorithm string
+	key       crypto.Signer
+	keys      []PublicKey
+}
+
+func (s *localSigner) Sign(_ context.Context, payload []byte) (Signature, error) {
+	opts := crypto.SignerOpts(crypto.SHA256)
+	if s.algorithm == AlgorithmEd25519 {
+		opts = crypto.Hash(0)
+	}
+	value, err := s.key.Sign(rand.Reader, digest(s.algorithm, payload), opts)
+	if err != nil {
+		return Signature{}, err
+	}
+	return Signature{KeyID: s.activeID, Algorithm: s.algorithm, Value: value}, nil
+}
+
+func (s *localSigner) PublicKeys(context.Context) ([]PublicKey, error) {
+	return s.keys, nil
+}
+
+// newSeedSigner は RESULT_SIGNING_KEYS の Ed25519 のシードから Signer を作成します。
+// 先頭の鍵で署名し、残りの鍵は以前の署名の検証用に公開鍵だけを公開します。
+func newSeedSigner(cfg *config.Config) (Signer, error) {
+	activeID, seeds, err := cfg.SigningKeys()
+	if err != nil {
+		return nil, err
+	}
+	signer := &localSigner{activeID: activeID, algorithm: AlgorithmEd25519, key: ed25519.NewKeyFromSeed(seeds[activeID])}
+	for id, seed := range seeds {
+		signer.keys = append(signer.keys, PublicKey{
+			KeyID:     id,
+			Algorithm: AlgorithmEd25519,
+			Key:       ed25519.NewKeyFromSeed(seed).Public(),
+			Active:    id == activeID,
+		})
+	}
+	// 署名に使う鍵を先頭に、残りは鍵IDの順に並べる
+	sort.Slice(signer.keys, func(i, j int) bool {
+		if signer.keys[i].Active != signer.keys[j].Active {
+			return signer.keys[i].Active
+		}
+		return signer.keys[i].KeyID < signer.keys[j].KeyID
+	})
+	return signer, nil
+}
+
+// newPKCS12Signer は開発用の PKCS#12 ファイルの鍵で署名する Signer を作成します。
+// 鍵IDは公開鍵（SubjectPublicKeyInfo）の SHA-256 の先頭16桁です。
+func newPKCS12Signer(path, password string) (Signer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read SIGNING_PKCS12_FILE: %w", err)
+	}
+	key, _, err := pkcs12.Decode(data, password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode SIGNING_PKCS12_FILE: %w", err)
+	}
+	privateKey, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T", ErrUnsupportedKey, key)
+	}
+	algorithm, err := algorithmFor(privateKey.Public())
+	if err != nil {
+		return nil, err
+	}
+	der, err := x509.MarshalPKIXPublicKey(privateKey.Public())
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(der)
+	id := "pkcs12:" + hex.EncodeToString(sum[:8])
+	return &localSigner{
+		activeID:  id,
+		algorithm: algorithm,
+		key:       privateKey,
+		keys:      []PublicKey{{KeyID: id, Algorithm: algorithm, Key: privateKey.Public(), Active: true}},
+	}, nil
+}
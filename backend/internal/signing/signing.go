@@ -0,0 +1,135 @@
+// Package signing は成果物のマニフェストなどへの署名を提供します。
+// 秘密鍵は Cloud KMS や HashiCorp Vault の中に置いたまま署名でき、開発環境では PKCS#12 ファイルや環境変数の鍵も使えます。
+package signing
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+
+	"github.com/yourusername/paper-forge/internal/config"
+)
+
+// 署名方式（JWS の alg と同じ名前）
+const (
+	// AlgorithmEd25519 は Ed25519 です。payload そのものに署名します。
+	AlgorithmEd25519 = "Ed25519"
+	// AlgorithmES256 は P-256 の ECDSA と SHA-256 です。署名は ASN.1 DER 形式です。
+	AlgorithmES256 = "ES256"
+	// AlgorithmRS256 は RSASSA-PKCS1-v1_5 と SHA-256 です。
+	AlgorithmRS256 = "RS256"
+)
+
+// ErrUnsupportedKey は署名に使えない種類の鍵が設定されている場合のエラーです。
+var ErrUnsupportedKey = errors.New("unsupported signing key")
+
+// Signature は署名と、署名に使った鍵の情報です。
+type Signature struct {
+	KeyID     string
+	Algorithm string
+	Value     []byte
+}
+
+// PublicKey は署名の検証に使う公開鍵です。
+type PublicKey struct {
+	KeyID     string
+	Algorithm string
+	Key       crypto.PublicKey
+	// Active は現在の署名に使っている鍵の場合に true です。
+	Active bool
+}
+
+// PEM は公開鍵を SubjectPublicKeyInfo の PEM にします。
+func (k PublicKey) PEM() (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(k.Key)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})), nil
+}
+
+// Signer は payload に署名します。
+type Signer interface {
+	// Sign は現在の鍵で payload に署名します。
+	Sign(ctx context.Context, payload []byte) (Signature, error)
+	// PublicKeys は検証に使う公開鍵を、署名に使う鍵を先頭にして返します。
+	PublicKeys(ctx context.Context) ([]PublicKey, error)
+}
+
+// New は設定（SIGNING_PROVIDER）の Signer を作成します。署名しない設定の場合は nil を返します。
+// KMS や Vault への接続は最初の署名・公開鍵の取得まで行いません。
+func New(cfg *config.Config) (Signer, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	provider, err := cfg.SigningBackend()
+	if err != nil {
+		return nil, err
+	}
+	switch provider {
+	case config.SigningProviderKeys:
+		return newSeedSigner(cfg)
+	case config.SigningProviderGCPKMS:
+		return newKMSSigner(cfg), nil
+	case config.SigningProviderVault:
+		return newVaultSigner(cfg), nil
+	case config.SigningProviderPKCS12:
+		return newPKCS12Signer(cfg.SigningPKCS12File, cfg.SigningPKCS12Password)
+	default:
+		return nil, nil
+	}
+}
+
+// algorithmFor は公開鍵から署名方式を決めます。
+func algorithmFor(pub crypto.PublicKey) (string, error) {
+	switch k := pub.(type) {
+	case ed25519.PublicKey:
+		return AlgorithmEd25519, nil
+	case *ecdsa.PublicKey:
+		if k.Curve == elliptic.P256() {
+			return AlgorithmES256, nil
+		}
+	case *rsa.PublicKey:
+		return AlgorithmRS256, nil
+	}
+	return "", fmt.Errorf("%w: %T", ErrUnsupportedKey, pub)
+}
+
+// digest は署名方式で署名する値を返します。Ed25519 は payload そのもの、それ以外は SHA-256 のダイジェストです。
+func digest(algorithm string, payload []byte) []byte {
+	if algorithm == AlgorithmEd25519 {
+		return payload
+	}
+	sum := sha256.Sum256(payload)
+	return sum[:]
+}
+
+// Verify は公開鍵 key で signature を検証します。
+func Verify(key PublicKey, payload, signature []byte) bool {
+	switch k := key.Key.(type) {
+	case ed25519.PublicKey:
+		return key.Algorithm == AlgorithmEd25519 && ed25519.Verify(k, payload, signature)
+	case *ecdsa.PublicKey:
+		return key.Algorithm == AlgorithmES256 && ecdsa.VerifyASN1(k, digest(AlgorithmES256, payload), signature)
+	case *rsa.PublicKey:
+		return key.Algorithm == AlgorithmRS256 && rsa.VerifyPKCS1v15(k, crypto.SHA256, digest(AlgorithmRS256, payload), signature) == nil
+	}
+	return false
+}
+
+// parsePublicKeyPEM は SubjectPublicKeyInfo の PEM を解析します。
+func parsePublicKeyPEM(data string) (crypto.PublicKey, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil {
+		return nil, errors.New("public key is not PEM encoded")
+	}
+	return x509.ParsePKIXPublicKey(block.Bytes)
+}
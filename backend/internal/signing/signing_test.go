@@ -0,0 +1,234 @@
+package signing
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yourusername/paper-forge/internal/config"
+)
+
+func seed(b byte) string {
+	return base64.StdEncoding.EncodeToString([]byte(strings.Repeat(string(rune(b)), ed25519.SeedSize)))
+}
+
+func publicKeyPEM(t *testing.T, pub crypto.PublicKey) string {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+// assertSigns は Sign の署名が先頭の公開鍵で検証でき、改変後の payload では検証できないことを確かめます。
+func assertSigns(t *testing.T, signer Signer, wantKeyID, wantAlgorithm string) {
+	t.Helper()
+	ctx := context.Background()
+	payload := []byte(`{"jobId":"job-1"}`)
+	sig, err := signer.Sign(ctx, payload)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	keys, err := signer.PublicKeys(ctx)
+	if err != nil {
+		t.Fatalf("PublicKeys: %v", err)
+	}
+	if sig.KeyID != wantKeyID || sig.Algorithm != wantAlgorithm || keys[0].KeyID != wantKeyID || !keys[0].Active {
+		t.Fatalf("unexpected signature %s/%s with keys %+v", sig.KeyID, sig.Algorithm, keys)
+	}
+	if !Verify(keys[0], payload, sig.Value) {
+		t.Fatal("signature does not verify with the active key")
+	}
+	if Verify(keys[0], []byte(`{"jobId":"job-2"}`), sig.Value) {
+		t.Fatal("signature should not verify after the payload is altered")
+	}
+	if _, err := keys[0].PEM(); err != nil {
+		t.Fatalf("PEM: %v", err)
+	}
+}
+
+func TestNewSelectsProvider(t *testing.T) {
+	signer, err := New(&config.Config{})
+	if err != nil || signer != nil {
+		t.Fatalf("signing should be disabled without settings: %v %v", signer, err)
+	}
+	if _, err := New(&config.Config{SigningProvider: "pkcs12", SigningPKCS12File: "testdata/dev.p12", GinMode: "release"}); err == nil {
+		t.Fatal("pkcs12 should be rejected in release mode")
+	}
+	if _, err := New(&config.Config{SigningProvider: "vault", VaultAddr: "http://vault"}); err == nil {
+		t.Fatal("vault without token and key should be rejected")
+	}
+}
+
+func TestSeedSigner(t *testing.T) {
+	signer, err := New(&config.Config{ResultSigningKeys: "2026-10:" + seed('b') + ",2026-04:" + seed('a')})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertSigns(t, signer, "2026-10", AlgorithmEd25519)
+	keys, _ := signer.PublicKeys(context.Background())
+	if len(keys) != 2 || keys[1].KeyID != "2026-04" || keys[1].Active {
+		t.Fatalf("retired key should be published for verification: %+v", keys)
+	}
+}
+
+func TestPKCS12Signer(t *testing.T) {
+	signer, err := New(&config.Config{SigningProvider: "pkcs12", SigningPKCS12File: "testdata/dev.p12", SigningPKCS12Password: "dev"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, _ := signer.PublicKeys(context.Background())
+	if !strings.HasPrefix(keys[0].KeyID, "pkcs12:") {
+		t.Fatalf("unexpected key id: %s", keys[0].KeyID)
+	}
+	assertSigns(t, signer, keys[0].KeyID, AlgorithmES256)
+
+	if _, err := New(&config.Config{SigningProvider: "pkcs12", SigningPKCS12File: "testdata/dev.p12", SigningPKCS12Password: "wrong"}); err == nil {
+		t.Fatal("wrong password should fail")
+	}
+}
+
+func TestKMSSigner(t *testing.T) {
+	const (
+		active  = "projects/p/locations/global/keyRings/r/cryptoKeys/manifest/cryptoKeyVersions/2"
+		retired = "projects/p/locations/global/keyRings/r/cryptoKeys/manifest/cryptoKeyVersions/1"
+	)
+	keys := map[string]*ecdsa.PrivateKey{}
+	for _, name := range []string{active, retired} {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys[name] = key
+	}
+	publicKeyFetches := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/token":
+			if r.Header.Get("Metadata-Flavor") != "Google" {
+				http.Error(w, "missing header", http.StatusForbidden)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"access_token": "token-1"})
+			return
+		case r.Header.Get("Authorization") != "Bearer token-1":
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		case strings.HasSuffix(r.URL.Path, "/publicKey"):
+			publicKeyFetches++
+			key := keys[strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/v1/"), "/publicKey")]
+			_ = json.NewEncoder(w).Encode(map[string]any{"pem": publicKeyPEM(t, key.Public()), "algorithm": "EC_SIGN_P256_SHA256"})
+		case strings.HasSuffix(r.URL.Path, ":asymmetricSign"):
+			var req struct {
+				Digest struct {
+					SHA256 string `json:"sha256"`
+				} `json:"digest"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			digest, _ := base64.StdEncoding.DecodeString(req.Digest.SHA256)
+			sig, _ := ecdsa.SignASN1(rand.Reader, keys[strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/v1/"), ":asymmetricSign")], digest)
+			_ = json.NewEncoder(w).Encode(map[string]any{"signature": base64.StdEncoding.EncodeToString(sig)})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	signer := newKMSSigner(&config.Config{SigningKMSKeyVersions: active + ", " + retired})
+	signer.endpoint = server.URL + "/v1/"
+	signer.tokenURL = server.URL + "/token"
+	assertSigns(t, signer, active, AlgorithmES256)
+
+	published, err := signer.PublicKeys(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(published) != 2 || published[1].KeyID != retired || published[1].Active {
+		t.Fatalf("unexpected keys: %+v", published)
+	}
+	if publicKeyFetches != 2 {
+		t.Fatalf("public keys should be fetched once per version, got %d fetches", publicKeyFetches)
+	}
+}
+
+func TestVaultSigner(t *testing.T) {
+	v1, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v2, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFetches := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "s.token" || r.Header.Get("X-Vault-Namespace") != "team" {
+			http.Error(w, `{"errors":["permission denied"]}`, http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/pki-transit/keys/manifest":
+			keyFetches++
+			_ = json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{
+				"type":           "rsa-2048",
+				"latest_version": 2,
+				"keys": map[string]any{
+					"1": map[string]string{"public_key": publicKeyPEM(t, v1.Public())},
+					"2": map[string]string{"public_key": publicKeyPEM(t, v2.Public())},
+				},
+			}})
+		case "/v1/pki-transit/sign/manifest":
+			var req map[string]string
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			if req["hash_algorithm"] != "sha2-256" || req["signature_algorithm"] != "pkcs1v15" {
+				http.Error(w, "unexpected parameters", http.StatusBadRequest)
+				return
+			}
+			input, _ := base64.StdEncoding.DecodeString(req["input"])
+			sig, _ := rsa.SignPKCS1v15(rand.Reader, v2, crypto.SHA256, digest(AlgorithmRS256, input))
+			_ = json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{
+				"signature":   "vault:v2:" + base64.StdEncoding.EncodeToString(sig),
+				"key_version": 2,
+			}})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	now := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	signer := newVaultSigner(&config.Config{VaultAddr: server.URL + "/", VaultToken: "s.token", VaultNamespace: "team", SigningVaultMount: "/pki-transit/", SigningVaultKey: "manifest"})
+	signer.now = func() time.Time { return now }
+	assertSigns(t, signer, "vault:manifest:v2", AlgorithmRS256)
+
+	keys, _ := signer.PublicKeys(context.Background())
+	if len(keys) != 2 || keys[1].KeyID != "vault:manifest:v1" || keys[1].Active {
+		t.Fatalf("unexpected keys: %+v", keys)
+	}
+	if keyFetches != 1 {
+		t.Fatalf("key info should be cached, got %d fetches", keyFetches)
+	}
+	now = now.Add(vaultKeysTTL)
+	if _, err := signer.PublicKeys(context.Background()); err != nil || keyFetches != 2 {
+		t.Fatalf("key info should be refreshed after the TTL: %v (%d fetches)", err, keyFetches)
+	}
+
+	signer.token = "expired"
+	if _, err := signer.Sign(context.Background(), []byte("x")); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Fatalf("vault errors should be reported, got %v", err)
+	}
+}
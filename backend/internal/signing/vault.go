@@ -0,0 +1,205 @@
+package signing
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yourusername/paper-forge/internal/config"
+)
+
+// vaultKeysTTL は Transit の鍵の情報（公開鍵と最新バージョン）を取得し直すまでの時間です。鍵のローテーションを反映するために使います。
+const vaultKeysTTL = 5 * time.Minute
+
+// vaultSigner は HashiCorp Vault の Transit シークレットエンジンの鍵で署名します。秘密鍵は Vault の外に出ません。
+// 鍵のバージョンは Vault が管理し、最新バージョンで署名します。以前のバージョンも検証用に公開します。
+type vaultSigner struct {
+	addr      string
+	token     string
+	namespace string
+	mount     string
+	key       string
+	client    *http.Client
+	now       func() time.Time
+
+	mu        sync.Mutex
+	keys      []PublicKey
+	fetchedAt time.Time
+}
+
+func newVaultSigner(cfg *config.Config) *vaultSigner {
+	mount := strings.Trim(strings.TrimSpace(cfg.SigningVaultMount), "/")
+	if mount == "" {
+		mount = "transit"
+	}
+	return &vaultSigner{
+		addr:      strings.TrimRight(strings.TrimSpace(cfg.VaultAddr), "/"),
+		token:     strings.TrimSpace(cfg.VaultToken),
+		namespace: strings.TrimSpace(cfg.VaultNamespace),
+		mount:     mount,
+		key:       strings.TrimSpace(cfg.SigningVaultKey),
+		client:    &http.Client{Timeout: 30 * time.Second},
+		now:       time.Now,
+	}
+}
+
+func (s *vaultSigner) Sign(ctx context.Context, payload []byte) (Signature, error) {
+	keys, err := s.PublicKeys(ctx)
+	if err != nil {
+		return Signature{}, err
+	}
+	algorithm := keys[0].Algorithm
+	body := map[string]any{"input": base64.StdEncoding.EncodeToString(payload)}
+	switch algorithm {
+	case AlgorithmES256:
+		body["hash_algorithm"] = "sha2-256"
+		body["marshaling_algorithm"] = "asn1"
+	case AlgorithmRS256:
+		body["hash_algorithm"] = "sha2-256"
+		body["signature_algorithm"] = "pkcs1v15"
+	}
+	var resp struct {
+		Data struct {
+			Signature string `json:"signature"`
+		} `json:"data"`
+	}
+	if err := s.call(ctx, http.MethodPost, "sign/"+url.PathEscape(s.key), body, &resp); err != nil {
+		return Signature{}, err
+	}
+	// 署名は "vault:v<バージョン>:<base64>" の形式
+	parts := strings.SplitN(resp.Data.Signature, ":", 3)
+	if len(parts) != 3 || parts[0] != "vault" {
+		return Signature{}, fmt.Errorf("vault returned an invalid signature")
+	}
+	value, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Signature{}, fmt.Errorf("vault returned an invalid signature: %w", err)
+	}
+	version, err := strconv.Atoi(strings.TrimPrefix(parts[1], "v"))
+	if err != nil {
+		return Signature{}, fmt.Errorf("vault returned an invalid key version %q", parts[1])
+	}
+	return Signature{KeyID: s.keyID(version), Algorithm: algorithm, Value: value}, nil
+}
+
+func (s *vaultSigner) PublicKeys(ctx context.Context) ([]PublicKey, error) {
+	s.mu.Lock()
+	if s.keys != nil && s.now().Sub(s.fetchedAt) < vaultKeysTTL {
+		keys := s.keys
+		s.mu.Unlock()
+		return keys, nil
+	}
+	s.mu.Unlock()
+
+	var resp struct {
+		Data struct {
+			Type          string `json:"type"`
+			LatestVersion int    `json:"latest_version"`
+			Keys          map[string]struct {
+				PublicKey string `json:"public_key"`
+			} `json:"keys"`
+		} `json:"data"`
+	}
+	if err := s.call(ctx, http.MethodGet, "keys/"+url.PathEscape(s.key), nil, &resp); err != nil {
+		return nil, err
+	}
+	algorithm, ok := vaultAlgorithms[resp.Data.Type]
+	if !ok {
+		return nil, fmt.Errorf("%w: vault key type %s", ErrUnsupportedKey, resp.Data.Type)
+	}
+	versions := make([]int, 0, len(resp.Data.Keys))
+	for v := range resp.Data.Keys {
+		version, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("vault returned an invalid key version %q", v)
+		}
+		versions = append(versions, version)
+	}
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("vault key %s has no versions", s.key)
+	}
+	// 署名に使う最新バージョンを先頭に、残りは新しい順に並べる
+	sort.Sort(sort.Reverse(sort.IntSlice(versions)))
+	keys := make([]PublicKey, 0, len(versions))
+	for _, version := range versions {
+		pub, err := parseVaultPublicKey(algorithm, resp.Data.Keys[strconv.Itoa(version)].PublicKey)
+		if err != nil {
+			return nil, fmt.Errorf("vault key version %d: %w", version, err)
+		}
+		keys = append(keys, PublicKey{KeyID: s.keyID(version), Algorithm: algorithm, Key: pub, Active: version == resp.Data.LatestVersion})
+	}
+
+	s.mu.Lock()
+	s.keys, s.fetchedAt = keys, s.now()
+	s.mu.Unlock()
+	return keys, nil
+}
+
+// keyID は Transit の鍵バージョンの鍵IDです（例: vault:manifests:v3）。
+func (s *vaultSigner) keyID(version int) string {
+	return fmt.Sprintf("vault:%s:v%d", s.key, version)
+}
+
+// vaultAlgorithms は Transit の鍵の種類と署名方式の対応です。
+var vaultAlgorithms = map[string]string{
+	"ed25519":    AlgorithmEd25519,
+	"ecdsa-p256": AlgorithmES256,
+	"rsa-2048":   AlgorithmRS256,
+	"rsa-3072":   AlgorithmRS256,
+	"rsa-4096":   AlgorithmRS256,
+}
+
+// parseVaultPublicKey は Transit の公開鍵を解析します。Ed25519 は base64 の鍵、それ以外は PEM です。
+func parseVaultPublicKey(algorithm, raw string) (any, error) {
+	if algorithm == AlgorithmEd25519 {
+		key, err := base64.StdEncoding.DecodeString(raw)
+		if err != nil || len(key) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid ed25519 public key")
+		}
+		return ed25519.PublicKey(key), nil
+	}
+	return parsePublicKeyPEM(raw)
+}
+
+func (s *vaultSigner) call(ctx context.Context, method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, s.addr+"/v1/"+s.mount+"/"+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Vault-Token", s.token)
+	if s.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", s.namespace)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("vault request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 2048))
+		return fmt.Errorf("vault responded with %d: %s", resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -22,7 +22,8 @@ package storage
 // - 自動削除: ジョブ完了時 or 10分経過後
 //
 // GCSストレージ実装（今後）:
-// - 保存先: gs://<bucket>/jobs/<jobID>/in|out/
+// - 保存先: gs://<bucket>/<prefix>/jobs/<jobID>/in|out/
+//   （bucket と prefix は config.StorageLocation() で DATA_REGION に対応する保存先を使う）
 // - 署名付きURL: PUT用（アップロード）、GET用（ダウンロード）
 // - ライフサイクル: 短期自動削除（例: 1時間）
 //
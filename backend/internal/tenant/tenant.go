@@ -0,0 +1,49 @@
+// Package tenant は1つのデプロイを複数の部署で使い分けるためのテナント識別子を扱います。
+// 認証ミドルウェアが解決したテナントをリクエストのコンテキストに載せ、ジョブの投入や参照で利用します。
+package tenant
+
+import (
+	"context"
+	"regexp"
+	"strings"
+)
+
+// Default はログインセッション（APP_USERNAME）など、テナントを指定しない利用者のテナントです。
+const Default = "default"
+
+// Header は API キーで認証する際にテナントを指定するヘッダーです。
+const Header = "X-Tenant"
+
+var idPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,31}$`)
+
+type contextKey struct{}
+
+// Valid はテナントIDの形式（英小文字・数字・ハイフン、32文字まで）を満たすかを返します。
+// テナントIDはストレージのプレフィックスにも使うため、パスとして安全な文字に限定します。
+func Valid(id string) bool {
+	return idPattern.MatchString(id)
+}
+
+// Normalize は空のテナントIDを Default に置き換えます。テナント導入前のジョブは Default として扱います。
+func Normalize(id string) string {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return Default
+	}
+	return id
+}
+
+// WithID は ctx にテナントIDを設定します。
+func WithID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, Normalize(id))
+}
+
+// FromContext は ctx のテナントIDを返します。設定されていない場合は Default です。
+func FromContext(ctx context.Context) string {
+	if ctx != nil {
+		if id, ok := ctx.Value(contextKey{}).(string); ok && id != "" {
+			return id
+		}
+	}
+	return Default
+}
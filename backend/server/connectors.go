@@ -0,0 +1,306 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"strings"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+
+	"github.com/yourusername/paper-forge/internal/connectors"
+	"github.com/yourusername/paper-forge/internal/pdf"
+)
+
+// remoteFilesField は連携先のファイルを入力に加える multipart のフィールドです。
+const remoteFilesField = "remoteFiles"
+
+// remoteFile は remoteFiles の1件です。
+type remoteFile struct {
+	Provider string `json:"provider"`
+	FileID   string `json:"fileId"`
+}
+
+// connectorSessionKey は接続のIDを保存するセッションのキーです。
+func connectorSessionKey(p connectors.Provider) string {
+	return "connector:" + string(p)
+}
+
+// connectorNonceCookie は認可を始めたブラウザに渡す nonce のクッキー名です。
+// 連携先からの戻り（サイト間のトップレベルの遷移）でも届くよう、セッションと異なり SameSite=Lax にします。
+func connectorNonceCookie(p connectors.Provider) string {
+	return "pf_connector_" + string(p)
+}
+
+// connectorNonceMaxAge は nonce のクッキーの有効期間（秒）です。state の有効期限に合わせます。
+const connectorNonceMaxAge = 10 * 60
+
+func sessionConnection(c *gin.Context, p connectors.Provider) string {
+	id, _ := sessions.Default(c).Get(connectorSessionKey(p)).(string)
+	return id
+}
+
+// connectorProvider はパスの :provider を解釈します。未対応・未設定の場合はレスポンスを書いて false を返します。
+func connectorProvider(c *gin.Context, mgr *connectors.Manager) (connectors.Provider, bool) {
+	p, ok := connectors.ParseProvider(c.Param("provider"))
+	if !ok || !mgr.Enabled(p) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    "CONNECTOR_NOT_CONFIGURED",
+			"message": "指定された連携先は設定されていません。",
+		})
+		return "", false
+	}
+	return p, true
+}
+
+// connectorListHandler は GET /api/connectors のハンドラーです。設定済みの連携先と接続状態を返します。
+func connectorListHandler(mgr *connectors.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		items := make([]gin.H, 0)
+		for _, p := range mgr.Providers() {
+			items = append(items, gin.H{
+				"provider":  p,
+				"connected": mgr.Connected(p, sessionConnection(c, p)),
+			})
+		}
+		c.JSON(http.StatusOK, gin.H{"connectors": items, "maxFiles": mgr.MaxFiles()})
+	}
+}
+
+// connectorAuthorizeHandler は GET /api/connectors/:provider/authorize のハンドラーです。
+// 新しい接続のIDをセッションに、nonce を短命のクッキーに保存し、連携先の認可画面へリダイレクトします。
+func connectorAuthorizeHandler(mgr *connectors.Manager, secureCookie bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		p, ok := connectorProvider(c, mgr)
+		if !ok {
+			return
+		}
+		connectionID, authURL, nonce, err := mgr.Begin(p)
+		if err != nil {
+			log.Printf("failed to begin connector authorization provider=%s: %v", p, err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code":    "INTERNAL_ERROR",
+				"message": "連携先への接続を開始できませんでした。",
+			})
+			return
+		}
+		session := sessions.Default(c)
+		// 以前の接続は使わなくなるため破棄する
+		if previous, _ := session.Get(connectorSessionKey(p)).(string); previous != "" {
+			mgr.Disconnect(previous)
+		}
+		session.Set(connectorSessionKey(p), connectionID)
+		if err := session.Save(); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code":    "INTERNAL_ERROR",
+				"message": "セッションの保存に失敗しました。",
+			})
+			return
+		}
+		c.SetSameSite(http.SameSiteLaxMode)
+		c.SetCookie(connectorNonceCookie(p), nonce, connectorNonceMaxAge, "/", "", secureCookie, true)
+		c.Redirect(http.StatusFound, authURL)
+	}
+}
+
+// connectorCallbackHandler は GET /api/connectors/:provider/callback のハンドラーです。
+// 連携先からのリダイレクトはサイト間の遷移のためセッションのクッキーが届かず、接続は署名した state から特定します。
+// 他人が始めた認可の state で戻らされた場合に備え、認可を始めたブラウザの nonce のクッキーと state を照合します。
+func connectorCallbackHandler(mgr *connectors.Manager, secureCookie bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		p, ok := connectorProvider(c, mgr)
+		if !ok {
+			return
+		}
+		nonce, _ := c.Cookie(connectorNonceCookie(p))
+		// nonce は1回の認可にだけ使う
+		c.SetSameSite(http.SameSiteLaxMode)
+		c.SetCookie(connectorNonceCookie(p), "", -1, "/", "", secureCookie, true)
+		if c.Query("error") != "" {
+			// 利用者が同意画面で拒否した
+			c.Redirect(http.StatusFound, mgr.ReturnURL(p, "denied"))
+			return
+		}
+		if err := mgr.Complete(c.Request.Context(), p, c.Query("state"), nonce, c.Query("code")); err != nil {
+			log.Printf("failed to complete connector authorization provider=%s: %v", p, err)
+			c.Redirect(http.StatusFound, mgr.ReturnURL(p, "error"))
+			return
+		}
+		c.Redirect(http.StatusFound, mgr.ReturnURL(p, "connected"))
+	}
+}
+
+// connectorDisconnectHandler は DELETE /api/connectors/:provider のハンドラーです。
+func connectorDisconnectHandler(mgr *connectors.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		p, ok := connectorProvider(c, mgr)
+		if !ok {
+			return
+		}
+		session := sessions.Default(c)
+		if id, _ := session.Get(connectorSessionKey(p)).(string); id != "" {
+			mgr.Disconnect(id)
+		}
+		session.Delete(connectorSessionKey(p))
+		if err := session.Save(); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code":    "INTERNAL_ERROR",
+				"message": "セッションの保存に失敗しました。",
+			})
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// connectorImportMiddleware は PDF 処理のリクエストに連携先のファイルと接続を加えるミドルウェアです。
+//
+//   - remoteFiles（[{"provider":"gdrive","fileId":"..."}] の JSON）のファイルをサーバーでダウンロードし、
+//     アップロードされたファイルと同じように入力の末尾へ加えます。
+//   - deliverTo が連携先の場合、セッションの接続を deliverConnection に設定します。
+//     利用者が送った deliverConnection は常に無視し、他人の接続への書き込みを防ぎます。
+func connectorImportMiddleware(mgr *connectors.Manager, maxFileSize int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !strings.HasPrefix(c.ContentType(), "multipart/form-data") {
+			c.Next()
+			return
+		}
+		form, err := c.MultipartForm()
+		if err != nil {
+			// 形式の誤りは各ハンドラーが返す
+			c.Next()
+			return
+		}
+
+		if p, ok := connectors.ParseProvider(c.PostForm("deliverTo")); ok {
+			setFormValue(c, form, pdf.DeliverConnectionField, sessionConnection(c, p))
+		} else {
+			setFormValue(c, form, pdf.DeliverConnectionField, "")
+		}
+
+		raw := strings.TrimSpace(c.PostForm(remoteFilesField))
+		if raw == "" {
+			c.Next()
+			return
+		}
+		var files []remoteFile
+		if err := json.Unmarshal([]byte(raw), &files); err != nil {
+			abortConnector(c, http.StatusBadRequest, "INVALID_INPUT", "remoteFiles は provider と fileId の配列（JSON）で指定してください。")
+			return
+		}
+		if len(files) > mgr.MaxFiles() {
+			abortConnector(c, http.StatusRequestEntityTooLarge, "LIMIT_EXCEEDED",
+				fmt.Sprintf("連携先から一度に取り込めるファイルは%d件までです。", mgr.MaxFiles()))
+			return
+		}
+
+		field := uploadFileField(form)
+		var spooled []*multipart.Form
+		defer func() {
+			for _, f := range spooled {
+				_ = f.RemoveAll()
+			}
+		}()
+		for _, item := range files {
+			p, ok := connectors.ParseProvider(item.Provider)
+			if !ok || !mgr.Enabled(p) || strings.TrimSpace(item.FileID) == "" {
+				abortConnector(c, http.StatusBadRequest, "INVALID_INPUT", "remoteFiles の provider または fileId が正しくありません。")
+				return
+			}
+			connectionID := sessionConnection(c, p)
+			if !mgr.Connected(p, connectionID) {
+				abortConnector(c, http.StatusConflict, "CONNECTOR_NOT_CONNECTED", "連携先に接続していないか、接続の有効期限が切れています。再度接続してください。")
+				return
+			}
+			header, tmp, err := downloadRemoteFile(c, mgr, p, connectionID, item.FileID, maxFileSize)
+			if tmp != nil {
+				spooled = append(spooled, tmp)
+			}
+			if err != nil {
+				abortRemoteFileError(c, p, err)
+				return
+			}
+			form.File[field] = append(form.File[field], header)
+		}
+		c.Next()
+	}
+}
+
+// downloadRemoteFile は連携先のファイルを一時ファイルへ保存し、アップロードされたファイルと同じ FileHeader として返します。
+func downloadRemoteFile(c *gin.Context, mgr *connectors.Manager, p connectors.Provider, connectionID, fileID string, maxFileSize int64) (*multipart.FileHeader, *multipart.Form, error) {
+	file, body, err := mgr.Open(c.Request.Context(), p, connectionID, fileID, maxFileSize)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer body.Close()
+
+	// ファイルの大きさは連携先の申告のため、実際に読み込む量も上限で打ち切る
+	var src io.Reader = body
+	if maxFileSize > 0 {
+		src = io.LimitReader(body, maxFileSize+1)
+	}
+	name := file.Name
+	if name == "" {
+		name = fileID + ".pdf"
+	}
+	form, err := pdf.SpoolFormFile("file", name, "application/pdf", src)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to download %s: %w", name, err)
+	}
+	headers := form.File["file"]
+	if maxFileSize > 0 && headers[0].Size > maxFileSize {
+		return nil, form, fmt.Errorf("%w: %s", connectors.ErrFileTooLarge, file.Name)
+	}
+	return headers[0], form, nil
+}
+
+// uploadFileField は取り込んだファイルを加えるフィールドです。ファイルが送られていればそのフィールドに続けます。
+func uploadFileField(form *multipart.Form) string {
+	for _, field := range []string{"files[]", "files", "file", "file[]"} {
+		if len(form.File[field]) > 0 {
+			return field
+		}
+	}
+	return "files[]"
+}
+
+// setFormValue は解析済みのフォームの値を置き換えます（value が空の場合は削除）。
+// c.PostForm は Request.PostForm を参照するため、form.Value と合わせて更新します。
+func setFormValue(c *gin.Context, form *multipart.Form, key, value string) {
+	targets := []map[string][]string{form.Value, c.Request.PostForm, c.Request.Form}
+	for _, values := range targets {
+		if values == nil {
+			continue
+		}
+		if value == "" {
+			delete(values, key)
+		} else {
+			values[key] = []string{value}
+		}
+	}
+}
+
+func abortRemoteFileError(c *gin.Context, p connectors.Provider, err error) {
+	switch {
+	case errors.Is(err, connectors.ErrNotPDF):
+		abortConnector(c, http.StatusBadRequest, "UNSUPPORTED_PDF", "連携先のファイルがPDFではありません。")
+	case errors.Is(err, connectors.ErrFileTooLarge):
+		abortConnector(c, http.StatusRequestEntityTooLarge, "LIMIT_EXCEEDED", "連携先のファイルがサイズの上限を超えています。")
+	case errors.Is(err, connectors.ErrFileNotFound):
+		abortConnector(c, http.StatusNotFound, "REMOTE_FILE_NOT_FOUND", "連携先のファイルが見つからないか、アクセスが許可されていません。")
+	case errors.Is(err, connectors.ErrNotConnected):
+		abortConnector(c, http.StatusConflict, "CONNECTOR_NOT_CONNECTED", "連携先に接続していないか、接続の有効期限が切れています。再度接続してください。")
+	default:
+		log.Printf("failed to import remote file provider=%s: %v", p, err)
+		abortConnector(c, http.StatusBadGateway, "CONNECTOR_UNAVAILABLE", "連携先からファイルを取得できませんでした。")
+	}
+}
+
+func abortConnector(c *gin.Context, status int, code, message string) {
+	c.AbortWithStatusJSON(status, gin.H{"code": code, "message": message})
+}
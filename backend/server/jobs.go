@@ -0,0 +1,839 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yourusername/paper-forge/internal/config"
+	"github.com/yourusername/paper-forge/internal/connectors"
+	"github.com/yourusername/paper-forge/internal/delivery"
+	"github.com/yourusername/paper-forge/internal/jobs"
+	"github.com/yourusername/paper-forge/internal/metrics"
+	"github.com/yourusername/paper-forge/internal/pdf"
+	"github.com/yourusername/paper-forge/internal/tenant"
+)
+
+const (
+	defaultChangesLimit = 100
+	maxChangesLimit     = 500
+
+	// maxJobWait はロングポーリングで保留する最大時間です（LB のアイドルタイムアウトより短くする）。
+	maxJobWait = 60 * time.Second
+)
+
+type pdfJobScheduler struct {
+	manager *jobs.Manager
+}
+
+func (s *pdfJobScheduler) Schedule(ctx context.Context, op pdf.OperationType, jobID string) (string, error) {
+	if s == nil || s.manager == nil {
+		return "", fmt.Errorf("asynchronous job processing is disabled")
+	}
+	tenantID := tenant.FromContext(ctx)
+	existing, duplicate, err := s.manager.ClaimDedup(ctx, jobID, tenantID)
+	if err != nil {
+		// 重複判定は二重送信の抑止が目的のため、判定できなくてもジョブは受け付ける
+		log.Printf("failed to check duplicate job job=%s: %v", jobID, err)
+	}
+	if duplicate {
+		return existing, nil
+	}
+
+	_, err = s.manager.Enqueue(ctx, &jobs.TaskPayload{
+		JobID:     jobID,
+		Operation: op,
+		Tenant:    tenantID,
+	})
+	if err != nil {
+		// 投入できなかったジョブを、同じ内容の後続のジョブの重複先として残さない
+		if releaseErr := s.manager.ReleaseDedup(ctx, jobID, tenantID); releaseErr != nil {
+			log.Printf("failed to release duplicate check job=%s: %v", jobID, releaseErr)
+		}
+	}
+	if errors.Is(err, jobs.ErrTenantQuotaExceeded) {
+		return "", &pdf.Error{
+			Code:    "TENANT_QUOTA_EXCEEDED",
+			Message: "同時に実行できるジョブ数の上限に達しています。実行中のジョブの完了後に再度お試しください。",
+			Err:     err,
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+	return jobID, nil
+}
+
+// SetupJobs は Redis に接続して非同期ジョブの Manager を作成します。
+// Redis（と待避先）に接続できない場合は nil を返し、非同期ジョブ機能を無効にします。
+func SetupJobs(cfg *config.Config, pdfService *pdf.Service) (*jobs.Manager, error) {
+	redisClient, err := jobs.NewRedisClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	// LAZY_INIT の場合、接続確認と待避先の設定は最初のリクエストで行う（startJobs）
+	var pingErr error
+	if !cfg.LazyInit {
+		pingErr = redisClient.Ping(context.Background()).Err()
+	}
+	if pingErr != nil && cfg.QueueFailoverRedisURL == "" {
+		log.Printf("[WARN] Redis に接続できないため、非同期ジョブ機能を無効化します: %v", pingErr)
+		_ = redisClient.Close()
+		return nil, nil
+	}
+	ttlMinutes := cfg.JobExpireMinutes
+	if ttlMinutes <= 0 {
+		ttlMinutes = 10
+	}
+	store := jobs.NewStore(redisClient, time.Duration(ttlMinutes)*time.Minute)
+	manager, err := jobs.NewManager(cfg, pdfService, store, log.Default())
+	if err != nil {
+		return nil, err
+	}
+	if cfg.QueueFailoverRedisURL != "" && !cfg.LazyInit {
+		if err := manager.EnableFailover(context.Background()); err != nil {
+			if pingErr != nil {
+				log.Printf("[WARN] Redis と待避先の Redis のどちらにも接続できないため、非同期ジョブ機能を無効化します: %v", err)
+				_ = manager.Shutdown(context.Background())
+				_ = redisClient.Close()
+				return nil, nil
+			}
+			// 待避先が無くても主 Redis で処理できるため、起動は続ける
+			log.Printf("[WARN] 待避先の Redis を利用できません: %v", err)
+		} else if pingErr != nil {
+			log.Printf("[WARN] Redis に接続できないため、新しいジョブは待避先の Redis へ投入します: %v", pingErr)
+		}
+	}
+	return manager, nil
+}
+
+// startJobs はワーカーを起動し、キューの待ち状況をメトリクスに登録します。
+// LAZY_INIT の場合は最初のリクエストで呼ばれ、SetupJobs で省いた待避先の設定もここで行います。
+func startJobs(cfg *config.Config, manager *jobs.Manager) {
+	if cfg.LazyInit && cfg.QueueFailoverRedisURL != "" {
+		if err := manager.EnableFailover(context.Background()); err != nil {
+			log.Printf("[WARN] 待避先の Redis を利用できません: %v", err)
+		}
+	}
+	manager.StartWorkers()
+	metrics.RegisterQueueStats(func() (any, error) { return manager.QueueStats() })
+}
+
+func jobsUnavailableHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"code":    "JOBS_DISABLED",
+			"message": "非同期ジョブ機能が無効化されています。Redis を起動してサーバーを再起動してください。",
+		})
+	}
+}
+
+// queueMetricsHandler はジョブキューの待ち状況（queue_latency_seconds 等）を返します。
+func queueMetricsHandler(manager *jobs.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		stats, err := manager.QueueStats()
+		if err != nil {
+			log.Printf("failed to inspect job queue: %v", err)
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"code":    "QUEUE_UNAVAILABLE",
+				"message": "ジョブキューの状態を取得できませんでした。",
+			})
+			return
+		}
+		c.Header("Cache-Control", "no-store")
+		c.JSON(http.StatusOK, stats)
+	}
+}
+
+func jobStatusHandler(manager *jobs.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		jobID := c.Param("id")
+		if strings.TrimSpace(jobID) == "" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    "INVALID_INPUT",
+				"message": "jobId を指定してください。",
+			})
+			return
+		}
+
+		wait, err := parseWait(c.Query("wait"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    "INVALID_INPUT",
+				"message": err.Error(),
+			})
+			return
+		}
+
+		tenantID := tenant.FromContext(c.Request.Context())
+		var record *jobs.Record
+		if wait > 0 {
+			// 他のテナントのジョブでレスポンスを保留しないよう、待つ前に参照できるかを確認する
+			visible, visErr := manager.JobVisible(c.Request.Context(), jobID, tenantID)
+			if visErr == nil && visible {
+				// ロングポーリング: 終了状態になるか wait が経過するまでレスポンスを保留する
+				record, err = manager.WaitForTerminal(c.Request.Context(), jobID, wait)
+			}
+			err = errors.Join(visErr, err)
+		} else {
+			record, err = manager.GetRecord(c.Request.Context(), jobID)
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code":    "INTERNAL_ERROR",
+				"message": "ジョブ情報の取得に失敗しました。",
+			})
+			return
+		}
+		if !record.VisibleTo(tenantID) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"code":    "JOB_NOT_FOUND",
+				"message": "指定されたジョブは存在しません。",
+			})
+			return
+		}
+
+		payload := jobRecordPayload(record)
+		etag, err := payloadETag(payload)
+		if err == nil {
+			// 頻繁にポーリングするクライアント向けに、変化がなければ 304 を返してボディの転送を省く
+			c.Header("ETag", etag)
+			c.Header("Last-Modified", record.UpdatedAt.UTC().Format(http.TimeFormat))
+			c.Header("Cache-Control", "private, no-cache")
+			if notModified(c.Request, etag, record.UpdatedAt) {
+				c.Status(http.StatusNotModified)
+				return
+			}
+		}
+
+		c.JSON(http.StatusOK, payload)
+	}
+}
+
+// parseWait は wait クエリ（"30s" 形式または秒数）を解釈し、上限で丸めます。
+func parseWait(raw string) (time.Duration, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return 0, nil
+	}
+	wait, err := time.ParseDuration(raw)
+	if err != nil {
+		seconds, convErr := strconv.Atoi(raw)
+		if convErr != nil {
+			return 0, errors.New("wait は 30s のような時間、または秒数で指定してください。")
+		}
+		wait = time.Duration(seconds) * time.Second
+	}
+	if wait < 0 {
+		return 0, errors.New("wait に負の値は指定できません。")
+	}
+	return min(wait, maxJobWait), nil
+}
+
+// payloadETag はレスポンスボディから弱い ETag を生成します。
+func payloadETag(payload gin.H) (string, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return `W/"` + hex.EncodeToString(sum[:16]) + `"`, nil
+}
+
+// notModified は条件付きリクエストに対して 304 を返せるかを判定します。
+// If-None-Match がある場合は If-Modified-Since より優先します（RFC 9110）。
+func notModified(r *http.Request, etag string, updatedAt time.Time) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		for _, candidate := range strings.Split(inm, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		}
+		return false
+	}
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" {
+		since, err := http.ParseTime(ims)
+		if err != nil {
+			return false
+		}
+		// HTTP-date は秒精度のため切り捨てて比較する
+		return !updatedAt.Truncate(time.Second).After(since)
+	}
+	return false
+}
+
+// jobChangesHandler は GET /api/jobs/changes のハンドラーです。
+// ポーリングしかできない連携基盤向けに、cursor 以降にステータスが変化したジョブを返します。
+func jobChangesHandler(manager *jobs.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var cursor int64
+		if raw := strings.TrimSpace(c.Query("since")); raw != "" {
+			parsed, err := strconv.ParseInt(raw, 10, 64)
+			if err != nil || parsed < 0 {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"code":    "INVALID_INPUT",
+					"message": "since には前回レスポンスの cursor を指定してください。",
+				})
+				return
+			}
+			cursor = parsed
+		}
+
+		limit := defaultChangesLimit
+		if raw := strings.TrimSpace(c.Query("limit")); raw != "" {
+			parsed, err := strconv.Atoi(raw)
+			if err != nil || parsed <= 0 {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"code":    "INVALID_INPUT",
+					"message": "limit には正の整数を指定してください。",
+				})
+				return
+			}
+			limit = min(parsed, maxChangesLimit)
+		}
+
+		records, next, hasMore, err := manager.ListChanges(c.Request.Context(), cursor, limit)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code":    "INTERNAL_ERROR",
+				"message": "ジョブ変更履歴の取得に失敗しました。",
+			})
+			return
+		}
+
+		// cursor は全テナント共通のため、他のテナントのジョブは除いて cursor だけ進める
+		tenantID := tenant.FromContext(c.Request.Context())
+		items := make([]gin.H, 0, len(records))
+		for _, record := range records {
+			if record.VisibleTo(tenantID) {
+				items = append(items, jobRecordPayload(record))
+			}
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"jobs":    items,
+			"cursor":  strconv.FormatInt(next, 10),
+			"hasMore": hasMore,
+		})
+	}
+}
+
+func jobRecordPayload(record *jobs.Record) gin.H {
+	payload := gin.H{
+		"jobId":     record.JobID,
+		"operation": record.Operation,
+		"status":    record.Status,
+		"progress": gin.H{
+			"percent": record.Progress.Percent,
+			"stage":   record.Progress.Stage,
+			"message": record.Progress.Message,
+		},
+		"updatedAt": record.UpdatedAt,
+	}
+	if record.Tenant != "" {
+		payload["tenant"] = record.Tenant
+	}
+	if record.Label != "" {
+		payload["label"] = record.Label
+	}
+	if len(record.ClientContext) > 0 {
+		payload["clientContext"] = record.ClientContext
+	}
+	if record.DownloadURL != "" {
+		payload["downloadUrl"] = record.DownloadURL
+	}
+	if record.OutputSize > 0 {
+		payload["outputSize"] = record.OutputSize
+	}
+	if record.OutputSHA256 != "" {
+		payload["outputSha256"] = record.OutputSHA256
+	}
+	if len(record.Artifacts) > 0 {
+		payload["artifacts"] = record.Artifacts
+	}
+	if len(record.Inputs) > 0 {
+		payload["inputs"] = record.Inputs
+	}
+	if record.Meta != nil {
+		payload["meta"] = record.Meta
+	}
+	if record.Error != nil {
+		payload["error"] = record.Error
+	}
+	if record.Delivery != nil {
+		payload["delivery"] = record.Delivery
+	}
+	if record.Hold != nil {
+		payload["hold"] = record.Hold
+	}
+	if !record.QueuedAt.IsZero() {
+		// キュー待ちの間は時間とともに増えるため、秒単位に丸めて ETag の変化を1秒ごとに抑える
+		payload["queuedForSeconds"] = int64(record.QueuedFor(time.Now()) / time.Second)
+	}
+	if record.SLABreached {
+		payload["slaBreached"] = true
+	}
+	return payload
+}
+
+type jobHoldRequest struct {
+	Reason string `json:"reason"`
+}
+
+// jobHoldHandler は POST /api/admin/jobs/:id/hold のハンドラーです。
+// 訴訟などで保全が必要になったジョブを、解除されるまで有効期限による削除の対象外にします。
+func jobHoldHandler(manager *jobs.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req jobHoldRequest
+		if c.Request.ContentLength > 0 {
+			if err := c.ShouldBindJSON(&req); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"code":    "INVALID_INPUT",
+					"message": "reason は文字列で指定してください。",
+				})
+				return
+			}
+		}
+
+		record, err := manager.HoldJob(c.Request.Context(), c.Param("id"), req.Reason)
+		if err != nil {
+			respondJobAdminError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, jobRecordPayload(record))
+	}
+}
+
+// jobReleaseHandler は DELETE /api/admin/jobs/:id/hold のハンドラーです。
+func jobReleaseHandler(manager *jobs.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		record, err := manager.ReleaseJob(c.Request.Context(), c.Param("id"))
+		if err != nil {
+			respondJobAdminError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, jobRecordPayload(record))
+	}
+}
+
+// jobPurgeHandler は DELETE /api/jobs/:id のハンドラーです。
+// ジョブの入力・成果物・マニフェスト・ジョブ情報を即時に削除し、削除の証跡を返します。
+func jobPurgeHandler(manager *jobs.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		jobID := c.Param("id")
+		if strings.TrimSpace(jobID) == "" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    "INVALID_INPUT",
+				"message": "jobId を指定してください。",
+			})
+			return
+		}
+
+		visible, err := manager.JobVisible(c.Request.Context(), jobID, tenant.FromContext(c.Request.Context()))
+		if err != nil {
+			respondJobAdminError(c, err)
+			return
+		}
+		if !visible {
+			respondJobAdminError(c, jobs.ErrJobNotFound)
+			return
+		}
+
+		receipt, err := manager.PurgeJob(c.Request.Context(), jobID)
+		if err != nil {
+			respondJobAdminError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, receipt)
+	}
+}
+
+type userPurgeRequest struct {
+	Username string `json:"username"`
+}
+
+// userPurgeHandler は POST /api/admin/purge-user のハンドラーです。
+// ログインはデプロイ単位の単一アカウントのため、そのユーザー名を指定すると保存データ全体を削除します。
+// クラウドドライブ連携のトークンもこのプロセスのメモリ上にあるため、ここで破棄します。
+func userPurgeHandler(manager *jobs.Manager, drives *connectors.Manager, appUsername string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req userPurgeRequest
+		if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Username) == "" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    "INVALID_INPUT",
+				"message": "username を指定してください。",
+			})
+			return
+		}
+		if appUsername == "" || req.Username != appUsername {
+			c.JSON(http.StatusNotFound, gin.H{
+				"code":    "USER_NOT_FOUND",
+				"message": "指定されたユーザーは存在しません。",
+			})
+			return
+		}
+
+		receipt, err := manager.PurgeAll(c.Request.Context(), req.Username)
+		if err != nil {
+			respondJobAdminError(c, err)
+			return
+		}
+		receipt.Connections = drives.DisconnectAll()
+		c.JSON(http.StatusOK, receipt)
+	}
+}
+
+type jobArchiveRequest struct {
+	OlderThanDays *int `json:"olderThanDays"`
+}
+
+// jobArchiveHandler は POST /api/admin/jobs/archive のハンドラーです。
+// 終了したジョブ情報（入力・成果物のファイルは含まない）を JSON Lines でストレージへ書き出します。
+func jobArchiveHandler(manager *jobs.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req jobArchiveRequest
+		if err := c.ShouldBindJSON(&req); err != nil || req.OlderThanDays == nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    "INVALID_INPUT",
+				"message": "olderThanDays を指定してください。",
+			})
+			return
+		}
+
+		receipt, err := manager.ArchiveJobs(c.Request.Context(), *req.OlderThanDays)
+		switch {
+		case errors.Is(err, jobs.ErrInvalidArchiveAge):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    "INVALID_INPUT",
+				"message": "olderThanDays は 0〜3650 の範囲で指定してください。",
+			})
+		case errors.Is(err, delivery.ErrNotConfigured):
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"code":    "STORAGE_NOT_CONFIGURED",
+				"message": "アーカイブの保存先（GCS_BUCKET）が設定されていません。",
+			})
+		case err != nil:
+			log.Printf("failed to archive job records: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code":    "INTERNAL_ERROR",
+				"message": "ジョブ情報のアーカイブに失敗しました。",
+			})
+		default:
+			c.JSON(http.StatusOK, receipt)
+		}
+	}
+}
+
+// gcReportHandler は GET /api/admin/gc のハンドラーです。
+// このインスタンスの作業領域に残っているワークスペースと、ジョブ情報との不整合（孤立した項目）を返します。
+func gcReportHandler(manager *jobs.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		report, err := manager.GCReport(c.Request.Context())
+		if err != nil {
+			log.Printf("failed to build gc report: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code":    "INTERNAL_ERROR",
+				"message": "作業領域の状態を取得できませんでした。",
+			})
+			return
+		}
+		c.JSON(http.StatusOK, report)
+	}
+}
+
+type gcCleanRequest struct {
+	Workspaces []string `json:"workspaces"`
+	Records    []string `json:"records"`
+}
+
+// gcCleanHandler は POST /api/admin/gc のハンドラーです。
+// 選択したワークスペースとジョブ情報を強制的に削除し、削除の証跡を返します。
+func gcCleanHandler(manager *jobs.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req gcCleanRequest
+		if err := c.ShouldBindJSON(&req); err != nil || len(req.Workspaces)+len(req.Records) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    "INVALID_INPUT",
+				"message": "workspaces または records に削除するジョブIDを指定してください。",
+			})
+			return
+		}
+
+		receipt, err := manager.CleanGarbage(c.Request.Context(), req.Workspaces, req.Records)
+		if err != nil {
+			respondJobAdminError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, receipt)
+	}
+}
+
+// usageCSVHeader は GET /api/admin/usage?format=csv の列です。
+var usageCSVHeader = []string{"month", "tenant", "jobs", "failedJobs", "pages", "bytes", "cpuSeconds", "units", "budget"}
+
+// usageExportHandler は GET /api/admin/usage のハンドラーです。
+// 部署ごとの費用按分のため、指定月（既定は当月、UTC）のテナントごとの利用量を JSON または CSV で返します。
+func usageExportHandler(manager *jobs.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		month := strings.TrimSpace(c.Query("month"))
+		if month == "" {
+			month = time.Now().UTC().Format(jobs.UsageMonthLayout)
+		}
+		if _, err := time.Parse(jobs.UsageMonthLayout, month); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    "INVALID_INPUT",
+				"message": "month は YYYY-MM 形式で指定してください。",
+			})
+			return
+		}
+		format := strings.ToLower(strings.TrimSpace(c.DefaultQuery("format", "json")))
+		if format != "json" && format != "csv" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    "INVALID_INPUT",
+				"message": "format には json または csv を指定してください。",
+			})
+			return
+		}
+
+		rows, err := manager.Usage(c.Request.Context(), month)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code":    "INTERNAL_ERROR",
+				"message": "利用量の取得に失敗しました。",
+			})
+			return
+		}
+
+		if format == "json" {
+			c.JSON(http.StatusOK, gin.H{"month": month, "usage": rows})
+			return
+		}
+
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"paper-forge-usage-%s.csv\"", month))
+		c.Status(http.StatusOK)
+		w := csv.NewWriter(c.Writer)
+		_ = w.Write(usageCSVHeader)
+		for _, row := range rows {
+			_ = w.Write([]string{
+				row.Month,
+				row.Tenant,
+				strconv.FormatInt(row.Jobs, 10),
+				strconv.FormatInt(row.FailedJobs, 10),
+				strconv.FormatInt(row.Pages, 10),
+				strconv.FormatInt(row.Bytes, 10),
+				strconv.FormatFloat(row.CPUSeconds, 'f', 3, 64),
+				strconv.FormatFloat(row.Units, 'f', 3, 64),
+				strconv.FormatFloat(row.Budget, 'f', -1, 64),
+			})
+		}
+		w.Flush()
+		if err := w.Error(); err != nil {
+			log.Printf("failed to write usage csv: %v", err)
+		}
+	}
+}
+
+// respondJobAdminError はリーガルホールド・削除の操作で発生したエラーを応答します。
+func respondJobAdminError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, jobs.ErrJobNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    "JOB_NOT_FOUND",
+			"message": "指定されたジョブは存在しません。",
+		})
+	case errors.Is(err, jobs.ErrJobNotFinished):
+		c.JSON(http.StatusConflict, gin.H{
+			"code":    "JOB_NOT_FINISHED",
+			"message": "処理中のジョブは操作できません。完了後に再度実行してください。",
+		})
+	case errors.Is(err, jobs.ErrJobOnHold):
+		c.JSON(http.StatusConflict, gin.H{
+			"code":    "JOB_ON_HOLD",
+			"message": "リーガルホールド中のジョブは削除できません。",
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    "INTERNAL_ERROR",
+			"message": "ジョブの更新に失敗しました。",
+		})
+	}
+}
+
+func jobDownloadHandler(pdfService *pdf.Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		jobID := c.Param("id")
+		if strings.TrimSpace(jobID) == "" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    "INVALID_INPUT",
+				"message": "jobId を指定してください。",
+			})
+			return
+		}
+
+		serveJobResult(c, pdfService, jobID)
+	}
+}
+
+// requireJobTenant はジョブIDを含むルートで、リクエストのテナントから参照できないジョブを 404 にするミドルウェアです。
+func requireJobTenant(manager *jobs.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		visible, err := manager.JobVisible(c.Request.Context(), c.Param("id"), tenant.FromContext(c.Request.Context()))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"code":    "INTERNAL_ERROR",
+				"message": "ジョブ情報の取得に失敗しました。",
+			})
+			return
+		}
+		if !visible {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"code":    "JOB_NOT_FOUND",
+				"message": "指定されたジョブは存在しません。",
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// tenantJobResults は一括ダウンロードの対象を、リクエストのテナントから参照できるジョブに限定します。
+type tenantJobResults struct {
+	manager *jobs.Manager
+	pdf.JobResultsService
+}
+
+func (t *tenantJobResults) OpenJobResults(ctx context.Context, jobIDs []string) (*pdf.JobResults, error) {
+	tenantID := tenant.FromContext(ctx)
+	for _, id := range jobIDs {
+		visible, err := t.manager.JobVisible(ctx, strings.TrimSpace(id), tenantID)
+		if err != nil {
+			return nil, err
+		}
+		if !visible {
+			// 他のテナントのジョブの存在を明かさないよう、見つからない場合と同じエラーにする
+			return nil, &pdf.Error{
+				Code:    "JOB_RESULT_NOT_FOUND",
+				Message: fmt.Sprintf("ジョブ %s の成果物が見つかりませんでした。完了前か、保存期間を過ぎています。", id),
+			}
+		}
+	}
+	return t.JobResultsService.OpenJobResults(ctx, jobIDs)
+}
+
+// jobInputDownloadHandler は keepInputs=true で保持した入力ファイルを返します。
+func jobInputDownloadHandler(pdfService *pdf.Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		jobID := c.Param("id")
+		if strings.TrimSpace(jobID) == "" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    "INVALID_INPUT",
+				"message": "jobId を指定してください。",
+			})
+			return
+		}
+
+		input, file, err := pdfService.OpenInput(jobID, c.Param("name"))
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				c.JSON(http.StatusNotFound, gin.H{
+					"code":    "JOB_INPUT_NOT_FOUND",
+					"message": "入力ファイルが見つかりませんでした。keepInputs=true を指定したジョブのみ、保持期間中に取得できます。",
+				})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code":    "INTERNAL_ERROR",
+				"message": "入力ファイルの取得に失敗しました。",
+			})
+			return
+		}
+		defer file.Close()
+
+		// 入力のファイル名は利用者が付けたもののため、引用符や改行を含んでもヘッダーを壊さないよう mime で組み立てる
+		c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": input.Name}))
+		c.Header("Cache-Control", "no-store")
+		c.Header("X-Job-Id", jobID)
+		c.DataFromReader(http.StatusOK, input.Size, "application/pdf", file, nil)
+	}
+}
+
+// jobSharedDownloadHandler は署名付きリンクによるログイン不要のダウンロードを提供します。
+func jobSharedDownloadHandler(manager *jobs.Manager, pdfService *pdf.Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		jobID := c.Param("id")
+		if strings.TrimSpace(jobID) == "" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    "INVALID_INPUT",
+				"message": "jobId を指定してください。",
+			})
+			return
+		}
+
+		if err := manager.VerifySharedLink(jobID, c.Query("expires"), c.Query("signature")); err != nil {
+			if errors.Is(err, jobs.ErrLinkExpired) {
+				c.JSON(http.StatusGone, gin.H{
+					"code":    "LINK_EXPIRED",
+					"message": "ダウンロードリンクの有効期限が切れています。",
+				})
+				return
+			}
+			c.JSON(http.StatusForbidden, gin.H{
+				"code":    "LINK_INVALID",
+				"message": "ダウンロードリンクが不正です。",
+			})
+			return
+		}
+
+		serveJobResult(c, pdfService, jobID)
+	}
+}
+
+// serveJobResult は成果物を返します。artifact クエリで主成果物以外の成果物を指定できます。
+func serveJobResult(c *gin.Context, pdfService *pdf.Service, jobID string) {
+	result, file, err := pdfService.OpenArtifact(jobID, c.Query("artifact"))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"code":    "JOB_RESULT_NOT_FOUND",
+				"message": "ジョブの成果物が見つかりませんでした。",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    "INTERNAL_ERROR",
+			"message": "ジョブの成果物取得に失敗しました。",
+		})
+		return
+	}
+	defer file.Close()
+
+	contentType := "application/octet-stream"
+	switch result.ResultKind {
+	case pdf.ResultKindPDF:
+		contentType = "application/pdf"
+	case pdf.ResultKindZIP:
+		contentType = "application/zip"
+	}
+
+	encodedName := url.PathEscape(result.OutputFilename)
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"; filename*=UTF-8''%s", result.OutputFilename, encodedName))
+	c.Header("Cache-Control", "no-store")
+	c.Header("X-Job-Id", result.JobID)
+	c.DataFromReader(http.StatusOK, result.OutputSize, contentType, file, nil)
+}
@@ -0,0 +1,323 @@
+// Package server は API サーバーの Gin エンジンを組み立てます。
+// cmd/api のほか、独自の認証やテレメトリを追加して組み込む場合にも使います。
+package server
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-contrib/cors"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-contrib/sessions/cookie"
+	"github.com/gin-gonic/gin"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+
+	"github.com/yourusername/paper-forge/internal/auth"
+	"github.com/yourusername/paper-forge/internal/buildinfo"
+	"github.com/yourusername/paper-forge/internal/config"
+	"github.com/yourusername/paper-forge/internal/connectors"
+	"github.com/yourusername/paper-forge/internal/envelope"
+	"github.com/yourusername/paper-forge/internal/jobs"
+	"github.com/yourusername/paper-forge/internal/metrics"
+	"github.com/yourusername/paper-forge/internal/pdf"
+	"github.com/yourusername/paper-forge/internal/tenant"
+)
+
+// Deps は New に渡す依存関係と拡張です。
+type Deps struct {
+	// PDF は PDF 操作のサービスです。nil の場合は cfg から作成します。
+	PDF *pdf.Service
+	// Jobs は非同期ジョブの Manager です（SetupJobs で作成）。nil の場合は非同期ジョブ機能を無効にし、
+	// 渡した場合はワーカーも起動します（LAZY_INIT の場合は最初のリクエスト時）。
+	Jobs *jobs.Manager
+	// Middleware はリクエストID・セッション・CORS の後、すべてのルートの前に実行するミドルウェアです。
+	Middleware []gin.HandlerFunc
+	// Routes は標準のルートを登録した後に呼び出し、独自のルートを追加します。
+	Routes []RouteRegistrar
+}
+
+// Routes は RouteRegistrar に渡すルートの登録先です。
+type Routes struct {
+	// Engine は /health などと同じ階層です。
+	Engine *gin.Engine
+	// API は /api 配下の認証不要のグループです。
+	API *gin.RouterGroup
+	// Protected は /api 配下のログイン・CSRF 検証・テナントのレート制限を通過したリクエストのグループです。
+	Protected *gin.RouterGroup
+}
+
+// RouteRegistrar は独自のルートを登録する関数です。
+type RouteRegistrar func(r Routes)
+
+// New は cfg と deps から API サーバーの Gin エンジンを組み立てます。
+func New(cfg *config.Config, deps Deps) *gin.Engine {
+	// Ginルーターの初期化（デフォルトミドルウェア: Logger, Recovery）
+	router := gin.Default()
+
+	// リクエストIDの付与と、SDK 向けエンベロープ形式のネゴシエーション
+	router.Use(envelope.Middleware())
+
+	// セッションストアの設定（クッキー署名鍵は必須）
+	store := cookie.NewStore([]byte(cfg.SessionSecret))
+	store.Options(sessions.Options{
+		Path:     "/",
+		MaxAge:   auth.SessionMaxAgeSeconds(),
+		HttpOnly: true,
+		Secure:   cfg.GinMode == gin.ReleaseMode,
+		SameSite: http.SameSiteStrictMode,
+	})
+	router.Use(sessions.Sessions(auth.SessionCookieName, store))
+
+	// CORSミドルウェアの設定
+	corsConfig := cors.DefaultConfig()
+	// CORS許可オリジンを設定（カンマ区切りの文字列を配列に変換）
+	origins := strings.Split(cfg.CORSAllowedOrigins, ",")
+	corsConfig.AllowOrigins = origins
+	corsConfig.AllowCredentials = true
+	corsConfig.AllowHeaders = []string{
+		"Origin",
+		"Content-Type",
+		"Accept",
+		"Authorization",
+		"X-CSRF-Token", // CSRF保護用ヘッダー
+		envelope.RequestIDHeader,
+		"Upload-Offset",         // 分割アップロードの開始位置
+		pdf.OverrideTokenHeader, // ソフトリミットの上書きトークン
+		tenant.Header,           // API キー認証時のテナント
+	}
+	// フロントエンドがレスポンスヘッダーから CSRF トークンを読み取れるように公開
+	corsConfig.ExposeHeaders = []string{"X-CSRF-Token", envelope.RequestIDHeader, "Upload-Offset"}
+	router.Use(cors.New(corsConfig))
+
+	pdfService := deps.PDF
+	if pdfService == nil {
+		pdfService = pdf.NewService(cfg)
+	}
+	if deps.Jobs != nil {
+		if cfg.LazyInit {
+			router.Use(onFirstRequest(func() { startJobs(cfg, deps.Jobs) }))
+		} else {
+			startJobs(cfg, deps.Jobs)
+		}
+	}
+	router.Use(deps.Middleware...)
+
+	// ルーティングの設定
+	setupRoutes(router, cfg, pdfService, deps.Jobs, deps.Routes)
+	return router
+}
+
+// onFirstRequest は最初のリクエストで init を1回だけ実行するミドルウェアです（LAZY_INIT）。
+// init が終わるまでは、同時に届いた他のリクエストも待たせます。
+func onFirstRequest(init func()) gin.HandlerFunc {
+	var once sync.Once
+	return func(c *gin.Context) {
+		once.Do(init)
+		c.Next()
+	}
+}
+
+// handleHealth はヘルスチェックエンドポイントのハンドラーです。
+func handleHealth(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "ok",
+		"service": "paper-forge-api",
+		"version": buildinfo.Get().Version,
+	})
+}
+
+// versionHandler は GET /api/version のハンドラーです。ビルドの情報と、起動時に確認した外部ツールのバージョンを返します。
+// Ghostscript は起動時に確認できなかった場合（LAZY_INIT や未インストール）は空です。
+func versionHandler(pdfService *pdf.Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, versionResponse{
+			Info:        buildinfo.Get(),
+			PDFCPU:      model.VersionStr,
+			Ghostscript: pdfService.GhostscriptVersion(),
+		})
+	}
+}
+
+type versionResponse struct {
+	buildinfo.Info
+	PDFCPU      string `json:"pdfcpu"`
+	Ghostscript string `json:"ghostscript"`
+}
+
+// setupRoutes は API グループと認証周りの配線を行い、最後に registrars のルートを登録します。
+func setupRoutes(router *gin.Engine, cfg *config.Config, pdfService *pdf.Service, jobManager *jobs.Manager, registrars []RouteRegistrar) {
+	// まずは誰でも叩けるヘルスチェックを登録
+	router.GET("/health", handleHealth)
+	// 操作種別・テナントごとの件数を含むため、管理用トークンを持つ運用者だけに公開する（未設定時はルート自体を登録しない）
+	if cfg.AdminAPIToken != "" {
+		metricsRoutes := router.Group("/metrics", auth.RequireAdminToken(cfg.AdminAPIToken))
+		metricsRoutes.GET("", metrics.Handler())
+		if jobManager != nil {
+			// ワーカーのオートスケーラー（KEDA の metrics-api スケーラー等）が管理用トークンを付けて参照する
+			metricsRoutes.GET("/queue", queueMetricsHandler(jobManager))
+		}
+	}
+
+	authManager := auth.NewManager(cfg)
+
+	// 大きなPDFを扱うため、multipartの読み込み上限を設定値に合わせて引き上げる
+	router.MaxMultipartMemory = pdf.MaxUploadTotalBytes + cfg.MaxFileSize
+
+	api := router.Group("/api")
+	{
+		// 環境ごとの差異の調査用。/health と同じくログイン不要
+		api.GET("/version", versionHandler(pdfService))
+
+		authRoutes := api.Group("/auth")
+		{
+			// ログイン時はセッション未生成なので CSRF 検証は不要
+			authRoutes.POST("/login", authManager.Login)
+			authRoutes.POST("/logout",
+				authManager.RequireLogin(),
+				authManager.VerifyCSRF(),
+				authManager.Logout,
+			)
+		}
+
+		// クラウドドライブ連携の接続。利用者データの一括削除（/admin/purge-user）でもトークンを破棄する
+		drives := connectors.NewManager(cfg)
+
+		// 上書きトークンはセッション署名鍵で署名する。発行は管理用トークンを持つ運用者のみ
+		overrideTokens := pdf.NewOverrideTokens(cfg.SessionSecret)
+		if cfg.AdminAPIToken != "" {
+			adminRoutes := api.Group("/admin")
+			adminRoutes.Use(auth.RequireAdminToken(cfg.AdminAPIToken))
+			{
+				if overrideTokens != nil {
+					adminRoutes.POST("/override-tokens", pdf.OverrideTokenHandler(overrideTokens))
+				}
+				if jobManager != nil {
+					adminRoutes.POST("/jobs/:id/hold", jobHoldHandler(jobManager))
+					adminRoutes.DELETE("/jobs/:id/hold", jobReleaseHandler(jobManager))
+					adminRoutes.POST("/purge-user", userPurgeHandler(jobManager, drives, cfg.AppUsername))
+					adminRoutes.GET("/usage", usageExportHandler(jobManager))
+					adminRoutes.POST("/jobs/archive", jobArchiveHandler(jobManager))
+					adminRoutes.GET("/gc", gcReportHandler(jobManager))
+					adminRoutes.POST("/gc", gcCleanHandler(jobManager))
+				}
+			}
+		}
+
+		// 連携先の認可画面からの戻り先。サイト間の遷移のためセッションのクッキーは届かず、state の署名で接続を特定する
+		api.GET("/connectors/:provider/callback", connectorCallbackHandler(drives, cfg.GinMode == gin.ReleaseMode))
+
+		// 署名付きリンクはメール受信者などセッションを持たない利用者向けのため、ログイン不要
+		if jobManager != nil {
+			api.GET("/jobs/:id/shared", jobSharedDownloadHandler(jobManager, pdfService))
+		}
+		// 成果物を受け取った側がマニフェストの署名を検証するための公開鍵。ログイン不要
+		api.GET("/manifest-keys", pdf.ManifestKeysHandler(pdfService))
+
+		// 今後追加する API はここにぶら下げる
+		protected := api.Group("")
+		protected.Use(
+			authManager.RequireLogin(),
+			authManager.VerifyCSRF(),
+			auth.NewTenantRateLimiter(cfg.TenantRateLimitPerMinute).Middleware(),
+		)
+		{
+			var scheduler pdf.JobScheduler
+			var usage pdf.UsageRecorder
+			if jobManager != nil {
+				scheduler = &pdfJobScheduler{manager: jobManager}
+				usage = jobManager
+			}
+			handlerOpts := pdf.HandlerOptions{
+				Scheduler:           scheduler,
+				AsyncThresholdBytes: cfg.AsyncThresholdBytes,
+				AsyncThresholdPages: cfg.AsyncThresholdPages,
+				Admission:           pdf.NewAdmission(cfg.MaxConcurrentSync, time.Duration(cfg.SyncRetryAfterSec)*time.Second),
+				SoftLimits:          pdf.NewSoftLimits(cfg.SoftLimitBytes, cfg.SoftLimitPages, overrideTokens),
+				Usage:               usage,
+				Delivery:            pdf.NewDeliveryPolicy(cfg),
+			}
+
+			protected.GET("/capabilities", pdf.CapabilitiesHandler(pdfService))
+
+			connectorRoutes := protected.Group("/connectors")
+			{
+				connectorRoutes.GET("", connectorListHandler(drives))
+				connectorRoutes.GET("/:provider/authorize", connectorAuthorizeHandler(drives, cfg.GinMode == gin.ReleaseMode))
+				connectorRoutes.DELETE("/:provider", connectorDisconnectHandler(drives))
+			}
+
+			uploadRoutes := protected.Group("/uploads")
+			{
+				uploadRoutes.POST("", pdf.UploadCreateHandler(pdfService))
+				uploadRoutes.PATCH("/:id", pdf.UploadChunkHandler(pdfService))
+				uploadRoutes.GET("/:id/status", pdf.UploadStatusHandler(pdfService))
+			}
+
+			pdfRoutes := protected.Group("/pdf")
+			if len(drives.Providers()) > 0 {
+				pdfRoutes.Use(connectorImportMiddleware(drives, cfg.MaxFileSize))
+			}
+			{
+				pdfRoutes.POST("/inspect", pdf.InspectHandler(pdfService))
+				pdfRoutes.POST("/precheck", pdf.PrecheckHandler())
+				pdfRoutes.POST("/thumbnails", pdf.ThumbnailsHandler(pdfService, handlerOpts.Admission))
+				pdfRoutes.POST("/merge", pdf.RequireOperation(pdfService, pdf.OperationMerge), pdf.MergeHandler(pdfService, handlerOpts))
+				pdfRoutes.POST("/reorder", pdf.RequireOperation(pdfService, pdf.OperationReorder), pdf.ReorderHandler(pdfService, handlerOpts))
+				pdfRoutes.POST("/split", pdf.RequireOperation(pdfService, pdf.OperationSplit), pdf.SplitHandler(pdfService, handlerOpts))
+				pdfRoutes.POST("/optimize", pdf.RequireOperation(pdfService, pdf.OperationOptimize), pdf.OptimizeHandler(pdfService, handlerOpts))
+				pdfRoutes.POST("/rotate", pdf.RequireOperation(pdfService, pdf.OperationRotate), pdf.RotateHandler(pdfService, handlerOpts))
+				pdfRoutes.POST("/watermark", pdf.RequireOperation(pdfService, pdf.OperationWatermark), pdf.WatermarkHandler(pdfService, handlerOpts))
+				pdfRoutes.POST("/stamp", pdf.RequireOperation(pdfService, pdf.OperationStamp), pdf.StampHandler(pdfService, handlerOpts))
+
+				// 専用ハンドラーを持たない登録済みの操作は汎用ハンドラーで公開する
+				dedicated := map[pdf.OperationType]bool{
+					pdf.OperationMerge:     true,
+					pdf.OperationReorder:   true,
+					pdf.OperationSplit:     true,
+					pdf.OperationOptimize:  true,
+					pdf.OperationRotate:    true,
+					pdf.OperationWatermark: true,
+					pdf.OperationStamp:     true,
+				}
+				for _, op := range pdf.Operations() {
+					if dedicated[op] {
+						continue
+					}
+					pdfRoutes.POST("/"+string(op), pdf.RequireOperation(pdfService, op), pdf.OperationHandler(pdfService, op, handlerOpts))
+				}
+			}
+
+			// 共有メニュー・ショートカット向けの簡易 API（本文に PDF をそのまま送る）
+			quickRoutes := protected.Group("/quick")
+			for _, op := range pdf.Operations() {
+				quickRoutes.POST("/"+string(op), pdf.RequireOperation(pdfService, op), pdf.QuickHandler(pdfService, op, handlerOpts))
+			}
+
+			if jobManager != nil {
+				protected.GET("/jobs/changes", jobChangesHandler(jobManager))
+				protected.POST("/jobs/download", pdf.JobResultsDownloadHandler(&tenantJobResults{manager: jobManager, JobResultsService: pdfService}))
+				protected.GET("/jobs/:id", jobStatusHandler(jobManager))
+				protected.DELETE("/jobs/:id", jobPurgeHandler(jobManager))
+				protected.GET("/jobs/:id/download", requireJobTenant(jobManager), jobDownloadHandler(pdfService))
+				protected.GET("/jobs/:id/preview", requireJobTenant(jobManager), pdf.JobPreviewHandler(pdfService))
+				protected.GET("/jobs/:id/inputs/:name", requireJobTenant(jobManager), jobInputDownloadHandler(pdfService))
+				protected.GET("/jobs/:id/manifest", requireJobTenant(jobManager), pdf.JobManifestHandler(pdfService))
+			} else {
+				protected.GET("/jobs/changes", jobsUnavailableHandler())
+				protected.POST("/jobs/download", jobsUnavailableHandler())
+				protected.GET("/jobs/:id", jobsUnavailableHandler())
+				protected.DELETE("/jobs/:id", jobsUnavailableHandler())
+				protected.GET("/jobs/:id/download", jobsUnavailableHandler())
+				protected.GET("/jobs/:id/preview", jobsUnavailableHandler())
+				protected.GET("/jobs/:id/inputs/:name", jobsUnavailableHandler())
+				protected.GET("/jobs/:id/manifest", jobsUnavailableHandler())
+			}
+		}
+
+		for _, register := range registrars {
+			register(Routes{Engine: router, API: api, Protected: protected})
+		}
+	}
+}
@@ -0,0 +1,484 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-contrib/sessions/cookie"
+	"github.com/gin-gonic/gin"
+
+	"github.com/yourusername/paper-forge/internal/buildinfo"
+	"github.com/yourusername/paper-forge/internal/config"
+	"github.com/yourusername/paper-forge/internal/connectors"
+	"github.com/yourusername/paper-forge/internal/jobs"
+	"github.com/yourusername/paper-forge/internal/pdf"
+	"github.com/yourusername/paper-forge/internal/pdf/pdftest"
+	"github.com/yourusername/paper-forge/internal/tenant"
+)
+
+const testAPIKey = "0123456789abcdef0123456789abcdef"
+
+func newTestRouter(t *testing.T, deps Deps) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{
+		SessionSecret:      "test-session-secret-0123456789abcdef",
+		CORSAllowedOrigins: "http://localhost:5173",
+		TenantAPIKeys:      "sales:" + testAPIKey,
+		WorkDir:            t.TempDir(),
+	}
+	return New(cfg, deps)
+}
+
+func TestNewAppliesMiddlewareAndRegistrars(t *testing.T) {
+	router := newTestRouter(t, Deps{
+		Middleware: []gin.HandlerFunc{func(c *gin.Context) {
+			c.Header("X-Embedder", "telemetry")
+			c.Next()
+		}},
+		Routes: []RouteRegistrar{func(r Routes) {
+			r.Protected.GET("/embedded", func(c *gin.Context) {
+				c.String(http.StatusOK, tenant.FromContext(c.Request.Context()))
+			})
+		}},
+	})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("X-Embedder") != "telemetry" {
+		t.Fatalf("middleware should run for built-in routes: %d %v", rec.Code, rec.Header())
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/embedded", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("registered protected route should require login, got %d", rec.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/embedded", nil)
+	req.Header.Set("Authorization", "Bearer "+testAPIKey)
+	req.Header.Set(tenant.Header, "sales")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Body.String() != "sales" {
+		t.Fatalf("unexpected response: %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestNewWithoutJobsReportsJobsDisabled(t *testing.T) {
+	router := newTestRouter(t, Deps{})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/jobs/abc", nil)
+	req.Header.Set("Authorization", "Bearer "+testAPIKey)
+	req.Header.Set(tenant.Header, "sales")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 without a job manager, got %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestVersionEndpointReportsBuildInfo(t *testing.T) {
+	router := newTestRouter(t, Deps{})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/version", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("version endpoint should not require login, got %d", rec.Code)
+	}
+	var body map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if body["version"] != buildinfo.Version || body["pdfcpu"] == "" || body["goVersion"] == "" {
+		t.Fatalf("unexpected version response: %v", body)
+	}
+	if _, ok := body["ghostscript"]; !ok {
+		t.Fatalf("ghostscript version should be reported even when not detected: %v", body)
+	}
+}
+
+func TestJobRecordPayloadIncludesOutputDigest(t *testing.T) {
+	payload := jobRecordPayload(&jobs.Record{
+		JobID:        "job-1",
+		Status:       jobs.StatusSucceeded,
+		DownloadURL:  "/api/jobs/job-1/download",
+		OutputSize:   2048,
+		OutputSHA256: "abc123",
+	})
+	if payload["outputSize"] != int64(2048) || payload["outputSha256"] != "abc123" {
+		t.Fatalf("output size and hash should be returned: %v", payload)
+	}
+
+	pending := jobRecordPayload(&jobs.Record{JobID: "job-2", Status: jobs.StatusQueued})
+	if _, ok := pending["outputSize"]; ok {
+		t.Fatalf("unfinished jobs should not report an output size: %v", pending)
+	}
+}
+
+func TestJobRecordPayloadIncludesQueueWait(t *testing.T) {
+	queuedAt := time.Now().Add(-time.Minute)
+	payload := jobRecordPayload(&jobs.Record{
+		JobID:       "job-1",
+		Status:      jobs.StatusRunning,
+		QueuedAt:    queuedAt,
+		StartedAt:   queuedAt.Add(45 * time.Second),
+		SLABreached: true,
+	})
+	if payload["queuedForSeconds"] != int64(45) || payload["slaBreached"] != true {
+		t.Fatalf("queue wait and SLA flag should be returned: %v", payload)
+	}
+
+	legacy := jobRecordPayload(&jobs.Record{JobID: "job-2", Status: jobs.StatusQueued})
+	if _, ok := legacy["queuedForSeconds"]; ok {
+		t.Fatalf("records without an enqueue time should omit the wait: %v", legacy)
+	}
+}
+
+func newConnectorTestRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	return New(&config.Config{
+		SessionSecret:            "test-session-secret-0123456789abcdef",
+		CORSAllowedOrigins:       "http://localhost:5173",
+		TenantAPIKeys:            "sales:" + testAPIKey,
+		WorkDir:                  t.TempDir(),
+		GoogleDriveClientID:      "google-client",
+		GoogleDriveClientSecret:  "google-secret",
+		ConnectorRedirectBaseURL: "https://forge.example.com",
+		ConnectorReturnURL:       "https://forge.example.com/app",
+		ConnectorMaxFiles:        2,
+	}, Deps{})
+}
+
+func multipartRequest(t *testing.T, target string, fields map[string]string) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for key, value := range fields {
+		if err := writer.WriteField(key, value); err != nil {
+			t.Fatalf("failed to write field: %v", err)
+		}
+	}
+	writer.Close()
+	req := httptest.NewRequest(http.MethodPost, target, &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+testAPIKey)
+	req.Header.Set(tenant.Header, "sales")
+	return req
+}
+
+func TestRemoteFilesRequireConnection(t *testing.T) {
+	router := newConnectorTestRouter(t)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, multipartRequest(t, "/api/pdf/merge", map[string]string{
+		"remoteFiles": `[{"provider":"gdrive","fileId":"abc"}]`,
+	}))
+	if rec.Code != http.StatusConflict || !strings.Contains(rec.Body.String(), "CONNECTOR_NOT_CONNECTED") {
+		t.Fatalf("expected 409 without a connection, got %d %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, multipartRequest(t, "/api/pdf/merge", map[string]string{
+		"remoteFiles": `[{"provider":"gdrive","fileId":"a"},{"provider":"gdrive","fileId":"b"},{"provider":"gdrive","fileId":"c"}]`,
+	}))
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected 413 above CONNECTOR_MAX_FILES, got %d %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, multipartRequest(t, "/api/pdf/merge", map[string]string{
+		"remoteFiles": `[{"provider":"onedrive","fileId":"a"}]`,
+	}))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an unconfigured provider, got %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestConnectorCallbackRedirectsWithoutSession(t *testing.T) {
+	router := newConnectorTestRouter(t)
+
+	cases := map[string]string{
+		"/api/connectors/gdrive/callback?error=access_denied":         "status=denied",
+		"/api/connectors/gdrive/callback?state=forged.1.sig&code=abc": "status=error",
+	}
+	for target, status := range cases {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		location := rec.Header().Get("Location")
+		if rec.Code != http.StatusFound || !strings.HasPrefix(location, "https://forge.example.com/app?") || !strings.Contains(location, status) {
+			t.Fatalf("%s: unexpected redirect %d %q", target, rec.Code, location)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/connectors/onedrive/callback?code=abc", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("unconfigured provider should be 404, got %d", rec.Code)
+	}
+}
+
+func TestConnectorCallbackRejectsStateFromAnotherSession(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(sessions.Sessions("test", cookie.NewStore([]byte("test-session-secret"))))
+	mgr := connectors.NewManager(&config.Config{
+		SessionSecret:            "test-session-secret",
+		GoogleDriveClientID:      "google-client",
+		ConnectorRedirectBaseURL: "https://forge.example.com",
+		ConnectorReturnURL:       "https://forge.example.com/app",
+	})
+	router.GET("/connectors/:provider/authorize", connectorAuthorizeHandler(mgr, false))
+	router.GET("/connectors/:provider/callback", connectorCallbackHandler(mgr, false))
+
+	authorize := func() (*http.Cookie, string) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/connectors/gdrive/authorize", nil))
+		location, err := url.Parse(rec.Header().Get("Location"))
+		if rec.Code != http.StatusFound || err != nil {
+			t.Fatalf("authorize: unexpected response %d %q", rec.Code, rec.Header().Get("Location"))
+		}
+		for _, c := range rec.Result().Cookies() {
+			if c.Name == connectorNonceCookie(connectors.GoogleDrive) {
+				if c.SameSite != http.SameSiteLaxMode || !c.HttpOnly {
+					t.Fatalf("nonce cookie should be HttpOnly and SameSite=Lax: %+v", c)
+				}
+				return c, location.Query().Get("state")
+			}
+		}
+		t.Fatal("authorize should set the nonce cookie")
+		return nil, ""
+	}
+	victimNonce, _ := authorize()
+	_, attackerState := authorize()
+
+	// 攻撃者が始めた認可の state で、被害者のブラウザを戻させる
+	req := httptest.NewRequest(http.MethodGet, "/connectors/gdrive/callback?code=abc&state="+url.QueryEscape(attackerState), nil)
+	req.AddCookie(victimNonce)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if location := rec.Header().Get("Location"); rec.Code != http.StatusFound || !strings.Contains(location, "status=error") {
+		t.Fatalf("callback from another session should fail, got %d %q", rec.Code, location)
+	}
+	cleared := false
+	for _, c := range rec.Result().Cookies() {
+		cleared = cleared || (c.Name == victimNonce.Name && c.MaxAge < 0)
+	}
+	if !cleared {
+		t.Fatal("callback should clear the nonce cookie")
+	}
+}
+
+func TestConnectorImportIgnoresClientConnection(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(sessions.Sessions("test", cookie.NewStore([]byte("test-session-secret"))))
+	mgr := connectors.NewManager(&config.Config{GoogleDriveClientID: "google-client", ConnectorMaxFiles: 1})
+	router.POST("/pdf", connectorImportMiddleware(mgr, 0), func(c *gin.Context) {
+		form, _ := c.MultipartForm()
+		c.String(http.StatusOK, "%s|%d", c.PostForm(pdf.DeliverConnectionField), len(form.Value[pdf.DeliverConnectionField]))
+	})
+
+	for _, deliverTo := range []string{"gdrive", "email"} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, multipartRequest(t, "/pdf", map[string]string{
+			"deliverTo":                deliverTo,
+			pdf.DeliverConnectionField: "someone-elses-connection",
+		}))
+		if rec.Body.String() != "|0" {
+			t.Fatalf("deliverTo=%s: client-supplied connection should be dropped, got %q", deliverTo, rec.Body.String())
+		}
+	}
+}
+
+func TestJobChangesHandlerRejectsInvalidCursor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	// 入力の検証で弾かれるリクエストはジョブ管理に到達しない
+	router.GET("/jobs/changes", jobChangesHandler(nil))
+
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{name: "non-numeric cursor", query: "since=abc", want: "since"},
+		{name: "negative cursor", query: "since=-1", want: "since"},
+		{name: "zero limit", query: "limit=0", want: "limit"},
+		{name: "non-numeric limit", query: "since=10&limit=ten", want: "limit"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/jobs/changes?"+tt.query, nil))
+			var body map[string]string
+			_ = json.Unmarshal(rec.Body.Bytes(), &body)
+			if rec.Code != http.StatusBadRequest || body["code"] != "INVALID_INPUT" || !strings.Contains(body["message"], tt.want) {
+				t.Fatalf("unexpected response: %d %s", rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestNotModified(t *testing.T) {
+	updatedAt := time.Date(2026, 3, 1, 9, 30, 15, 500_000_000, time.UTC)
+	etag, err := payloadETag(gin.H{"jobId": "job-1", "status": "running"})
+	if err != nil {
+		t.Fatalf("payloadETag returned error: %v", err)
+	}
+	if !strings.HasPrefix(etag, `W/"`) {
+		t.Fatalf("ETag should be weak: %s", etag)
+	}
+	if other, _ := payloadETag(gin.H{"jobId": "job-1", "status": "done"}); other == etag {
+		t.Fatal("a different payload should produce a different ETag")
+	}
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    bool
+	}{
+		{name: "no conditions"},
+		{name: "matching ETag", headers: map[string]string{"If-None-Match": etag}, want: true},
+		{name: "strong form of the ETag", headers: map[string]string{"If-None-Match": strings.TrimPrefix(etag, "W/")}, want: true},
+		{name: "one of several ETags", headers: map[string]string{"If-None-Match": `W/"other", ` + etag}, want: true},
+		{name: "wildcard", headers: map[string]string{"If-None-Match": "*"}, want: true},
+		{name: "stale ETag", headers: map[string]string{"If-None-Match": `W/"other"`}},
+		{name: "ETag wins over date", headers: map[string]string{"If-None-Match": `W/"other"`, "If-Modified-Since": updatedAt.Add(time.Hour).Format(http.TimeFormat)}},
+		{name: "same second", headers: map[string]string{"If-Modified-Since": updatedAt.Format(http.TimeFormat)}, want: true},
+		{name: "updated since", headers: map[string]string{"If-Modified-Since": updatedAt.Add(-time.Second).Format(http.TimeFormat)}},
+		{name: "invalid date", headers: map[string]string{"If-Modified-Since": "yesterday"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/jobs/job-1", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			if got := notModified(req, etag, updatedAt); got != tt.want {
+				t.Fatalf("notModified = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseWait(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    time.Duration
+		wantErr bool
+	}{
+		{raw: ""},
+		{raw: "0"},
+		{raw: "30s", want: 30 * time.Second},
+		{raw: " 15 ", want: 15 * time.Second},
+		{raw: "1500ms", want: 1500 * time.Millisecond},
+		{raw: "10m", want: maxJobWait},
+		{raw: "3600", want: maxJobWait},
+		{raw: "-5s", wantErr: true},
+		{raw: "-1", wantErr: true},
+		{raw: "soon", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseWait(tt.raw)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseWait(%q) = %s, %v; want %s, error %v", tt.raw, got, err, tt.want, tt.wantErr)
+		}
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/jobs/:id", jobStatusHandler(nil))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/jobs/job-1?wait=soon", nil))
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "INVALID_INPUT") {
+		t.Fatalf("invalid wait should be rejected before looking up the job: %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestMetricsRequiresAdminToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{
+		SessionSecret:      "test-session-secret-0123456789abcdef",
+		CORSAllowedOrigins: "http://localhost:5173",
+		WorkDir:            t.TempDir(),
+		AdminAPIToken:      "admin-token",
+	}
+	router := New(cfg, Deps{})
+
+	tests := []struct {
+		name   string
+		header string
+		want   int
+	}{
+		{name: "no token", want: http.StatusUnauthorized},
+		{name: "wrong token", header: "Bearer other", want: http.StatusUnauthorized},
+		{name: "admin token", header: "Bearer admin-token", want: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Fatalf("unexpected status: %d %s", rec.Code, rec.Body.String())
+			}
+		})
+	}
+
+	// 管理用トークンが無いデプロイでは公開しない
+	rec := httptest.NewRecorder()
+	newTestRouter(t, Deps{}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("/metrics should not be served without ADMIN_API_TOKEN, got %d", rec.Code)
+	}
+}
+
+func TestJobInputDownloadHandlerQuotesFilename(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	svc := pdf.NewService(&config.Config{MaxFileSize: 1 << 20, MaxPages: 20, WorkDir: t.TempDir()})
+	name := `見積 "最終"; filename=evil.exe.pdf`
+	headers, err := pdftest.FileHeaders("files[]",
+		pdftest.File{Name: name, Data: pdftest.Synthetic(1, 0)},
+		pdftest.File{Name: "b.pdf", Data: pdftest.Synthetic(1, 0)},
+	)
+	if err != nil {
+		t.Fatalf("failed to build multipart files: %v", err)
+	}
+	manifest, err := svc.PrepareJob(context.Background(), pdf.OperationMerge, pdf.PrepareRequest{
+		Files:   headers,
+		Options: pdf.JobOptions{KeepInputs: true},
+	})
+	if err != nil {
+		t.Fatalf("PrepareJob returned error: %v", err)
+	}
+
+	router := gin.New()
+	router.GET("/jobs/:id/inputs/:name", jobInputDownloadHandler(svc))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/jobs/"+manifest.JobID+"/inputs/"+url.PathEscape(name), nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d %s", rec.Code, rec.Body.String())
+	}
+	disposition, params, err := mime.ParseMediaType(rec.Header().Get("Content-Disposition"))
+	if err != nil {
+		t.Fatalf("Content-Disposition should parse: %v", err)
+	}
+	if disposition != "attachment" || params["filename"] != name {
+		t.Fatalf("unexpected Content-Disposition: %s %v", disposition, params)
+	}
+}
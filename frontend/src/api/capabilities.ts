@@ -0,0 +1,42 @@
+import { apiClient, toApiError } from './httpClient';
+
+export interface OperationCapability {
+  type: string;
+  enabled: boolean;
+}
+
+export interface CapabilityLimits {
+  maxFileSize: number;
+  maxTotalSize: number;
+  maxFiles: number;
+  maxPages: number;
+  maxMergePages: number;
+  softLimitBytes?: number;
+  softLimitPages?: number;
+}
+
+export interface Capabilities {
+  operations: OperationCapability[];
+  limits: CapabilityLimits;
+  acceptedMimeTypes: string[];
+  optimizePresets: string[];
+  ocrLanguages: string[];
+}
+
+// サーバーから取得できるまでの間、または取得に失敗した場合に使う既定の上限
+export const DEFAULT_LIMITS: CapabilityLimits = {
+  maxFileSize: 100 * 1024 * 1024,
+  maxTotalSize: 300 * 1024 * 1024,
+  maxFiles: 20,
+  maxPages: 200,
+  maxMergePages: 1000,
+};
+
+export const getCapabilities = async (): Promise<Capabilities> => {
+  try {
+    const response = await apiClient.get<Capabilities>('/capabilities');
+    return response.data;
+  } catch (error) {
+    throw toApiError(error);
+  }
+};
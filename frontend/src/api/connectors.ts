@@ -0,0 +1,46 @@
+import { apiClient, toApiError } from './httpClient';
+
+export type ConnectorProvider = 'gdrive' | 'onedrive';
+
+export interface ConnectorStatus {
+  provider: ConnectorProvider;
+  connected: boolean;
+}
+
+export interface Connectors {
+  connectors: ConnectorStatus[];
+  maxFiles: number;
+}
+
+// PDF 処理の remoteFiles に渡す、連携先のファイル
+export interface RemoteFile {
+  provider: ConnectorProvider;
+  fileId: string;
+}
+
+export const getConnectors = async (): Promise<Connectors> => {
+  try {
+    const response = await apiClient.get<Connectors>('/connectors');
+    return response.data;
+  } catch (error) {
+    throw toApiError(error);
+  }
+};
+
+// 連携先の同意画面へはブラウザごと遷移する（戻り先は CONNECTOR_RETURN_URL）
+export const connectorAuthorizeUrl = (provider: ConnectorProvider): string =>
+  `${apiClient.defaults.baseURL ?? '/api'}/connectors/${provider}/authorize`;
+
+export const disconnectConnector = async (provider: ConnectorProvider): Promise<void> => {
+  try {
+    await apiClient.delete(`/connectors/${provider}`);
+  } catch (error) {
+    throw toApiError(error);
+  }
+};
+
+export const appendRemoteFiles = (formData: FormData, files: RemoteFile[]): void => {
+  if (files.length > 0) {
+    formData.append('remoteFiles', JSON.stringify(files));
+  }
+};
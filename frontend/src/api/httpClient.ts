@@ -46,6 +46,7 @@ export interface ApiErrorPayload {
   message: string;
   remainingAttempts?: number;
   details?: Record<string, unknown>;
+  warnings?: string[];
 }
 
 export class ApiError extends Error {
@@ -32,6 +32,40 @@ export const downloadJobResult = async (jobId: string): Promise<JobDownloadResul
   }
 };
 
+// ダウンロード前の確認用に、成果物の指定ページをPNGで取得する
+export const getJobResultPreview = async (
+  jobId: string,
+  options: { page?: number; width?: number; artifact?: string } = {}
+): Promise<Blob> => {
+  try {
+    const response = await apiClient.get<ArrayBuffer>(`/jobs/${jobId}/preview`, {
+      params: options,
+      responseType: 'arraybuffer',
+    });
+    return new Blob([response.data], { type: 'image/png' });
+  } catch (error) {
+    throw toApiError(error);
+  }
+};
+
+// 複数ジョブの成果物を1つのZIPとしてまとめて取得する
+export const downloadJobResults = async (jobIds: string[]): Promise<JobDownloadResult> => {
+  try {
+    const response = await apiClient.post<ArrayBuffer>(
+      '/jobs/download',
+      { jobIds },
+      { responseType: 'arraybuffer' }
+    );
+    const contentType = (response.headers['content-type'] as string | undefined) ?? 'application/zip';
+    const disposition = response.headers['content-disposition'] as string | undefined;
+    const filename = parseContentDispositionFilename(disposition) ?? 'paper-forge-results.zip';
+    const blob = new Blob([response.data], { type: contentType });
+    return { blob, filename, contentType };
+  } catch (error) {
+    throw toApiError(error);
+  }
+};
+
 export interface AsyncJobEnqueueResponse {
   jobId: string;
 }
@@ -61,7 +61,16 @@ const postPdfOperation = async ({ endpoint, formData, defaultFilename, resultKin
       resultKind,
     };
   } catch (error) {
-    throw toApiError(error);
+    const apiError = toApiError(error);
+    // ソフトリミットを超えた大きなジョブは、利用者の確認後に confirm=true を付けて一度だけ再送信する
+    if (apiError.code === 'CONFIRMATION_REQUIRED' && !formData.has('confirm')) {
+      const warnings = apiError.payload?.warnings ?? [];
+      if (window.confirm([apiError.message, ...warnings].join('\n'))) {
+        formData.set('confirm', 'true');
+        return postPdfOperation({ endpoint, formData, defaultFilename, resultKind });
+      }
+    }
+    throw apiError;
   }
 };
 
@@ -75,6 +84,8 @@ export const mergePdfs = async (request: MergeRequest): Promise<PdfOperationResu
   request.files.forEach((file) => {
     formData.append('files[]', file);
   });
+  // 送信途中で切れたファイルをサーバー側で検出できるよう、各ファイルのサイズを宣言する
+  formData.append('sizes', JSON.stringify(request.files.map((file) => file.size)));
   if (request.order && request.order.length > 0) {
     formData.append('order', JSON.stringify(request.order));
   }
@@ -143,6 +154,7 @@ export interface SplitPartMeta {
   toPage: number;
   pages: number;
   size: number;
+  sha256: string;
 }
 
 export interface SplitMeta {
@@ -162,8 +174,32 @@ export interface OptimizeMeta {
   source: SourceFileMeta;
 }
 
+export interface DocumentInfo {
+  title?: string;
+  author?: string;
+  subject?: string;
+  keywords?: string;
+  creator?: string;
+  producer?: string;
+  createdAt?: string;
+  modifiedAt?: string;
+  version: string;
+  encrypted: boolean;
+  linearized: boolean;
+}
+
+/** ページの大きさ（ポイント、回転前）と時計回りの回転角度。90 / 270 では表示上の縦横が入れ替わる */
+export interface PageInfo {
+  number: number;
+  width: number;
+  height: number;
+  rotation: 0 | 90 | 180 | 270;
+}
+
 export interface InspectResponse {
   source: SourceFileMeta;
+  document: DocumentInfo;
+  pages: PageInfo[];
 }
 
 export const inspectPdf = async (file: File): Promise<InspectResponse> => {
@@ -176,3 +212,37 @@ export const inspectPdf = async (file: File): Promise<InspectResponse> => {
     throw toApiError(error);
   }
 };
+
+/** ページのサムネイル（PNG）。width / height は回転を反映した表示上の大きさ（ピクセル）、data は base64 */
+export interface PageThumbnail {
+  page: number;
+  width: number;
+  height: number;
+  data: string;
+}
+
+export interface ThumbnailsResponse {
+  source: SourceFileMeta;
+  thumbnails: PageThumbnail[];
+}
+
+export const fetchThumbnails = async (
+  file: File,
+  options: { pages?: string; width?: number } = {}
+): Promise<ThumbnailsResponse> => {
+  const formData = new FormData();
+  formData.append('file', file);
+  formData.append('format', 'json');
+  if (options.pages) {
+    formData.append('pages', options.pages);
+  }
+  if (options.width) {
+    formData.append('width', String(options.width));
+  }
+  try {
+    const response = await apiClient.post<ThumbnailsResponse>('/pdf/thumbnails', formData);
+    return response.data;
+  } catch (error) {
+    throw toApiError(error);
+  }
+};
@@ -17,6 +17,7 @@ import { ApiError } from '@/api/httpClient';
 import { ErrorModal } from '@/components/modals/ErrorModal';
 import { ProcessingModal } from '@/components/modals/ProcessingModal';
 import { SuccessModal } from '@/components/modals/SuccessModal';
+import { formatMegabytes, useUploadLimits } from '@/hooks/useCapabilities';
 import { useJobPolling } from '@/hooks/useJobPolling';
 import { jobStageToLabel } from '@/utils/jobProgress';
 import { useWorkspaceStore } from '@/stores/workspaceStore';
@@ -30,9 +31,6 @@ interface UploadedFile {
 
 type ProgressStep = 'idle' | 'load' | 'process' | 'write' | 'complete';
 
-const MAX_FILE_SIZE = 100 * 1024 * 1024; // 100MB
-const MAX_TOTAL_SIZE = 300 * 1024 * 1024; // 300MB
-const MAX_FILES = 20;
 const PROGRESS_LABELS: Record<ProgressStep, string> = {
   idle: '',
   load: 'ファイルを読み込んでいます',
@@ -49,6 +47,8 @@ const getFriendlyApiMessage = (error: ApiError): string => {
       return '容量またはページ数の上限を超えています。ファイルを減らすかサイズを調整してください。';
     case 'UNSUPPORTED_PDF':
       return '処理できないPDFでした。別のファイルでお試しください。';
+    case 'DAMAGED_PDF':
+      return 'PDFが破損しているため読み込めませんでした。元のファイルを保存し直してからお試しください。';
     case 'TOO_MANY_ATTEMPTS':
       return error.message;
     default:
@@ -96,6 +96,7 @@ export const MergeTab = () => {
   const saveWorkspaceResult = useWorkspaceStore((state) => state.saveResult);
 
   const jobQuery = useJobPolling({ jobId, enabled: isPollingJob });
+  const limits = useUploadLimits();
 
   const validateFile = (file: File): string | null => {
     if (!file.name.toLowerCase().endsWith('.pdf')) {
@@ -104,8 +105,8 @@ export const MergeTab = () => {
     if (file.type !== 'application/pdf') {
       return 'PDFファイルではありません';
     }
-    if (file.size > MAX_FILE_SIZE) {
-      return `ファイルサイズが100MBを超えています（${(file.size / 1024 / 1024).toFixed(1)}MB）`;
+    if (limits.maxFileSize > 0 && file.size > limits.maxFileSize) {
+      return `ファイルサイズが${formatMegabytes(limits.maxFileSize)}を超えています（${(file.size / 1024 / 1024).toFixed(1)}MB）`;
     }
     return null;
   };
@@ -115,8 +116,8 @@ export const MergeTab = () => {
 
     setErrorMessage(null);
 
-    if (files.length + selectedFiles.length > MAX_FILES) {
-      setErrorMessage(`ファイル数は${MAX_FILES}個以下にしてください`);
+    if (files.length + selectedFiles.length > limits.maxFiles) {
+      setErrorMessage(`ファイル数は${limits.maxFiles}個以下にしてください`);
       return;
     }
 
@@ -130,8 +131,8 @@ export const MergeTab = () => {
     setFiles(combined);
 
     const totalSize = combined.reduce((sum, f) => sum + f.file.size, 0);
-    if (totalSize > MAX_TOTAL_SIZE) {
-      setErrorMessage(`合計サイズが300MBを超えています（${(totalSize / 1024 / 1024).toFixed(1)}MB）`);
+    if (totalSize > limits.maxTotalSize) {
+      setErrorMessage(`合計サイズが${formatMegabytes(limits.maxTotalSize)}を超えています（${(totalSize / 1024 / 1024).toFixed(1)}MB）`);
     }
   };
 
@@ -402,7 +403,7 @@ export const MergeTab = () => {
   const validFiles = useMemo(() => files.filter((file) => !file.error), [files]);
   const hasErrors = files.some((file) => file.error);
   const totalSize = files.reduce((sum, file) => sum + file.file.size, 0);
-  const canExecute = validFiles.length >= 2 && !hasErrors && totalSize <= MAX_TOTAL_SIZE;
+  const canExecute = validFiles.length >= 2 && !hasErrors && totalSize <= limits.maxTotalSize;
 
   const executeMerge = () => {
     if (!canExecute || mergeMutation.isPending || isPollingJob) return;
@@ -442,7 +443,7 @@ export const MergeTab = () => {
         <p className="mt-2 text-sm text-gray-600">
           <span className="font-medium">クリックしてファイルを選択</span> または ドラッグ&ドロップ
         </p>
-        <p className="text-xs text-gray-500 mt-1">PDF形式のみ（最大20ファイル、各100MB以下）</p>
+        <p className="text-xs text-gray-500 mt-1">PDF形式のみ（最大{limits.maxFiles}ファイル、各{formatMegabytes(limits.maxFileSize)}以下）</p>
         <input
           type="file"
           id="merge-file-input"
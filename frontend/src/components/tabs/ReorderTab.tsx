@@ -19,6 +19,7 @@ import { ApiError } from '@/api/httpClient';
 import { ErrorModal } from '@/components/modals/ErrorModal';
 import { ProcessingModal } from '@/components/modals/ProcessingModal';
 import { SuccessModal } from '@/components/modals/SuccessModal';
+import { formatMegabytes, useUploadLimits } from '@/hooks/useCapabilities';
 import { useJobPolling } from '@/hooks/useJobPolling';
 import { jobStageToLabel } from '@/utils/jobProgress';
 import { useWorkspaceStore } from '@/stores/workspaceStore';
@@ -80,6 +81,8 @@ const getFriendlyApiMessage = (error: ApiError): string => {
       return 'ファイルサイズまたはページ数の上限を超えています。';
     case 'UNSUPPORTED_PDF':
       return '処理できないPDFでした。別のファイルでお試しください。';
+    case 'DAMAGED_PDF':
+      return 'PDFが破損しているため読み込めませんでした。元のファイルを保存し直してからお試しください。';
     default:
       return error.message || '予期しないエラーが発生しました。時間を置いて再度お試しください。';
   }
@@ -151,9 +154,6 @@ interface MergeItem {
   error?: string;
 }
 
-const MERGE_MAX_FILE_SIZE = 100 * 1024 * 1024; // 100MB
-const MERGE_MAX_TOTAL_SIZE = 300 * 1024 * 1024; // 300MB
-const MERGE_MAX_FILES = 20;
 
 export const ReorderTab = () => {
   const [file, setFile] = useState<File | null>(null);
@@ -206,6 +206,8 @@ export const ReorderTab = () => {
   }, []);
 
   const jobQuery = useJobPolling({ jobId, enabled: isPollingJob });
+  const limits = useUploadLimits();
+  const totalSizeLabel = formatMegabytes(limits.maxTotalSize);
 
   const selectedCount = useMemo(() => pages.filter((page) => page.selected).length, [pages]);
   const isOriginalOrder = useMemo(() => pages.every((page, index) => page.originalIndex === index), [pages]);
@@ -380,12 +382,12 @@ export const ReorderTab = () => {
       setMergeError(null);
       return;
     }
-    if (mergeTotalSize > MERGE_MAX_TOTAL_SIZE) {
-      setMergeError(`合計サイズが300MBを超えています（${(mergeTotalSize / 1024 / 1024).toFixed(1)}MB）`);
-    } else if (mergeError && mergeError.includes('300MB')) {
+    if (mergeTotalSize > limits.maxTotalSize) {
+      setMergeError(`合計サイズが${totalSizeLabel}を超えています（${(mergeTotalSize / 1024 / 1024).toFixed(1)}MB）`);
+    } else if (mergeError && mergeError.includes(`合計サイズが${totalSizeLabel}`)) {
       setMergeError(null);
     }
-  }, [mergeFiles, mergeTotalSize, mergeError]);
+  }, [mergeFiles, mergeTotalSize, mergeError, limits.maxTotalSize, totalSizeLabel]);
 
   const loadFile = useCallback(
     async (selectedFile: File) => {
@@ -477,8 +479,8 @@ export const ReorderTab = () => {
     if (mergeFile.type && mergeFile.type !== 'application/pdf') {
       return 'PDFファイルではありません';
     }
-    if (mergeFile.size > MERGE_MAX_FILE_SIZE) {
-      return `ファイルサイズが100MBを超えています（${(mergeFile.size / 1024 / 1024).toFixed(1)}MB）`;
+    if (limits.maxFileSize > 0 && mergeFile.size > limits.maxFileSize) {
+      return `ファイルサイズが${formatMegabytes(limits.maxFileSize)}を超えています（${(mergeFile.size / 1024 / 1024).toFixed(1)}MB）`;
     }
     return null;
   };
@@ -490,8 +492,8 @@ export const ReorderTab = () => {
 
     setMergeError(null);
 
-    if (mergeFiles.length + selectedFiles.length > MERGE_MAX_FILES) {
-      setMergeError(`ファイル数は${MERGE_MAX_FILES}個以下にしてください`);
+    if (mergeFiles.length + selectedFiles.length > limits.maxFiles) {
+      setMergeError(`ファイル数は${limits.maxFiles}個以下にしてください`);
       return;
     }
 
@@ -503,8 +505,8 @@ export const ReorderTab = () => {
 
     const combined = [...mergeFiles, ...newItems];
     const totalSize = combined.reduce((sum, current) => sum + current.file.size, 0);
-    if (totalSize > MERGE_MAX_TOTAL_SIZE) {
-      setMergeError(`合計サイズが300MBを超えています（${(totalSize / 1024 / 1024).toFixed(1)}MB）`);
+    if (totalSize > limits.maxTotalSize) {
+      setMergeError(`合計サイズが${totalSizeLabel}を超えています（${(totalSize / 1024 / 1024).toFixed(1)}MB）`);
       return;
     }
 
@@ -36,6 +36,8 @@ const getFriendlyApiMessage = (error: ApiError): string => {
       return 'ファイルサイズまたはページ数の上限を超えています。';
     case 'UNSUPPORTED_PDF':
       return '処理できないPDFでした。別のファイルでお試しください。';
+    case 'DAMAGED_PDF':
+      return 'PDFが破損しているため読み込めませんでした。元のファイルを保存し直してからお試しください。';
     default:
       return error.message || '予期しないエラーが発生しました。時間を置いて再実行してください。';
   }
@@ -0,0 +1,22 @@
+import { useQuery } from '@tanstack/react-query';
+
+import { DEFAULT_LIMITS, getCapabilities, type Capabilities, type CapabilityLimits } from '@/api/capabilities';
+
+/**
+ * サーバーの提供機能と上限を取得する。設定はデプロイ単位でしか変わらないため、セッション中は再取得しない。
+ */
+export const useCapabilities = () =>
+  useQuery<Capabilities, Error>({
+    queryKey: ['capabilities'],
+    queryFn: getCapabilities,
+    staleTime: Infinity,
+    retry: 1,
+  });
+
+/** 取得済みの上限を返す。未取得・取得失敗時は既定値を返す。 */
+export const useUploadLimits = (): CapabilityLimits => {
+  const { data } = useCapabilities();
+  return data?.limits ?? DEFAULT_LIMITS;
+};
+
+export const formatMegabytes = (bytes: number): string => `${Math.round(bytes / 1024 / 1024)}MB`;
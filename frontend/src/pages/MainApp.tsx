@@ -13,6 +13,7 @@ import { MergeTab } from '@/components/tabs/MergeTab';
 import { OptimizeTab } from '@/components/tabs/OptimizeTab';
 import { ReorderTab } from '@/components/tabs/ReorderTab';
 import { SplitTab } from '@/components/tabs/SplitTab';
+import { useCapabilities } from '@/hooks/useCapabilities';
 import { useAuthStore } from '@/stores/authStore';
 import { useWorkspaceStore } from '@/stores/workspaceStore';
 
@@ -33,6 +34,12 @@ export const MainApp = () => {
 
   const [activeTab, setActiveTab] = useState<TabType>(initialTab);
   const [isLoggingOut, setIsLoggingOut] = useState(false);
+  // サーバーで無効化された操作のタブは表示しない（取得に失敗した場合は全て表示する）
+  const { data: capabilities } = useCapabilities();
+  const disabledTabs = useMemo(
+    () => new Set((capabilities?.operations ?? []).filter((op) => !op.enabled).map((op) => op.type)),
+    [capabilities],
+  );
   const user = useAuthStore((current) => current.user);
   const logout = useAuthStore((current) => current.logout);
   const lastResult = useWorkspaceStore((current) => current.lastResult);
@@ -62,7 +69,7 @@ export const MainApp = () => {
     }
   };
 
-  const tabs: { id: TabType; label: string; icon: ReactNode }[] = [
+  const allTabs: { id: TabType; label: string; icon: ReactNode }[] = [
     {
       id: 'merge',
       label: TAB_LABELS.merge,
@@ -105,6 +112,13 @@ export const MainApp = () => {
       ),
     },
   ];
+  const tabs = allTabs.filter((tab) => !disabledTabs.has(tab.id));
+
+  useEffect(() => {
+    if (disabledTabs.has(activeTab) && tabs.length > 0) {
+      setActiveTab(tabs[0].id);
+    }
+  }, [activeTab, disabledTabs, tabs]);
 
   return (
     <div className="min-h-full bg-gradient-to-br from-blue-50 to-indigo-100">
@@ -165,10 +179,10 @@ export const MainApp = () => {
         </div>
 
         <div>
-          {activeTab === 'merge' && <MergeTab />}
-          {activeTab === 'reorder' && <ReorderTab />}
-          {activeTab === 'split' && <SplitTab />}
-          {activeTab === 'optimize' && <OptimizeTab />}
+          {activeTab === 'merge' && !disabledTabs.has('merge') && <MergeTab />}
+          {activeTab === 'reorder' && !disabledTabs.has('reorder') && <ReorderTab />}
+          {activeTab === 'split' && !disabledTabs.has('split') && <SplitTab />}
+          {activeTab === 'optimize' && !disabledTabs.has('optimize') && <OptimizeTab />}
         </div>
       </div>
     </div>
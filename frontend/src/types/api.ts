@@ -23,10 +23,21 @@ export interface JobProgress {
 export interface JobInfo {
   jobId: string;
   operation: string;
+  tenant?: string;
+  label?: string;
+  clientContext?: unknown;
   status: JobStatus;
   progress: JobProgress;
   updatedAt: string;
   downloadUrl?: string;
+  inputs?: JobInputInfo[];
   meta?: unknown;
   error?: ApiError;
 }
+
+// keepInputs=true で保持している入力ファイル
+export interface JobInputInfo {
+  name: string;
+  size: number;
+  downloadUrl: string;
+}